@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler 账户数据导出与删除处理器
+type AccountHandler struct {
+	accountService service.AccountService
+	sessionService service.SessionService
+}
+
+// NewAccountHandler 创建AccountHandler实例
+func NewAccountHandler(accountService service.AccountService, sessionService service.SessionService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		sessionService: sessionService,
+	}
+}
+
+// ExportHandler 以JSON附件形式下载用户的全部个人数据
+// 导出要求用户在ACCOUNT_MAX_AUTH_AGE_SECONDS（默认300秒）内重新登录过
+func (h *AccountHandler) ExportHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+	if time.Since(session.AuthTime) > accountMaxAuthAge() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "导出数据前请重新登录", "reauthentication_required": true})
+		return
+	}
+
+	export, err := h.accountService.ExportData(c.Request.Context(), session.UserID)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("account-export-%d-%s.json", session.UserID, export.ExportedAt.Format("20060102150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// ScheduleDeletionHandler 申请删除账户，宽限期结束后账户及其数据被永久删除，所有设备上的登录立即失效
+// 申请要求用户在ACCOUNT_MAX_AUTH_AGE_SECONDS（默认300秒）内重新登录过
+func (h *AccountHandler) ScheduleDeletionHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+	if time.Since(session.AuthTime) > accountMaxAuthAge() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "删除账户前请重新登录", "reauthentication_required": true})
+		return
+	}
+
+	deletionAt, err := h.accountService.ScheduleDeletion(c.Request.Context(), session.UserID)
+	if err != nil {
+		writeAccountError(c, err)
+		return
+	}
+
+	// 会话已被删除，同时清除浏览器中的会话Cookie
+	clearSessionCookie(c)
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":               "账户将在宽限期结束后删除，在此之前重新登录后可以取消",
+		"deletion_scheduled_at": deletionAt,
+	})
+}
+
+// CancelDeletionHandler 在宽限期内取消删除账户
+func (h *AccountHandler) CancelDeletionHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	if err := h.accountService.CancelDeletion(c.Request.Context(), session.UserID); err != nil {
+		writeAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已取消删除账户"})
+}
+
+// writeAccountError 将账户服务错误转换为HTTP响应
+func writeAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+	case errors.Is(err, service.ErrDeletionNotScheduled):
+		c.JSON(http.StatusConflict, gin.H{"error": "账户没有计划删除"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+	}
+}
+
+// accountMaxAuthAge 导出数据和删除账户时登录会话的最长认证时长
+func accountMaxAuthAge() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("ACCOUNT_MAX_AUTH_AGE_SECONDS")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Minute
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler 安全审计事件查询处理器
+type AuditHandler struct {
+	auditService   service.AuditService
+	sessionService service.SessionService
+}
+
+// NewAuditHandler 创建AuditHandler实例
+func NewAuditHandler(auditService service.AuditService, sessionService service.SessionService) *AuditHandler {
+	return &AuditHandler{
+		auditService:   auditService,
+		sessionService: sessionService,
+	}
+}
+
+// ListEventsHandler 管理员按用户、客户端、事件类型和时间范围查询审计事件
+// 查询参数：user_id、client_id、type、since、until（RFC 3339）、limit（默认100，最多1000）
+func (h *AuditHandler) ListEventsHandler(c *gin.Context) {
+	filter := model.AuditEventFilter{
+		ClientID: c.Query("client_id"),
+		Type:     c.Query("type"),
+	}
+
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil || userID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		filter.UserID = uint(userID)
+	}
+	var ok bool
+	if filter.Since, ok = parseAuditTime(c, "since"); !ok {
+		return
+	}
+	if filter.Until, ok = parseAuditTime(c, "until"); !ok {
+		return
+	}
+	if filter.Limit, ok = parseAuditLimit(c); !ok {
+		return
+	}
+
+	events, err := h.auditService.Query(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query audit events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// ListActivityHandler 用户查看自己最近的安全活动，limit默认20，最多100
+func (h *AuditHandler) ListActivityHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+	limit, ok := parseAuditLimit(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.auditService.ListUserActivity(c.Request.Context(), session.UserID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取安全活动失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// parseAuditTime 解析RFC 3339格式的时间查询参数，未提供时返回零值，格式错误时写入响应并返回false
+func parseAuditTime(c *gin.Context, name string) (time.Time, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, true
+	}
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + ", expected RFC 3339 time"})
+		return time.Time{}, false
+	}
+	return value, true
+}
+
+// parseAuditLimit 解析limit查询参数，未提供时返回0由服务层使用默认值，格式错误时写入响应并返回false
+func parseAuditLimit(c *gin.Context) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return 0, false
+	}
+	return limit, true
+}
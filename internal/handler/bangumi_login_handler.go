@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// BangumiLoginHandler 使用Bangumi账号登录的处理器
+type BangumiLoginHandler struct {
+	bangumiLoginService service.BangumiLoginService
+	userService         service.UserService
+	sessionService      service.SessionService
+	mfaService          service.MFAService
+	loginGuardService   service.LoginGuardService
+}
+
+// NewBangumiLoginHandler 创建BangumiLoginHandler实例
+func NewBangumiLoginHandler(bangumiLoginService service.BangumiLoginService, userService service.UserService, sessionService service.SessionService, mfaService service.MFAService, loginGuardService service.LoginGuardService) *BangumiLoginHandler {
+	return &BangumiLoginHandler{
+		bangumiLoginService: bangumiLoginService,
+		userService:         userService,
+		sessionService:      sessionService,
+		mfaService:          mfaService,
+		loginGuardService:   loginGuardService,
+	}
+}
+
+// BangumiCreateAccountRequest 使用Bangumi账号创建本地用户请求结构体
+type BangumiCreateAccountRequest struct {
+	PendingToken string `json:"pending_token" binding:"required"`
+	Username     string `json:"username" binding:"required,min=3,max=30"`
+	Email        string `json:"email" binding:"required,email"`
+}
+
+// BangumiLinkAccountRequest 将Bangumi账号关联到已有用户请求结构体
+type BangumiLinkAccountRequest struct {
+	PendingToken string `json:"pending_token" binding:"required"`
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password" binding:"required"`
+}
+
+// LoginHandler 跳转到Bangumi授权页面
+// return_to通常为登录页收到的原始授权请求地址，登录完成后跳转回该地址继续授权
+func (h *BangumiLoginHandler) LoginHandler(c *gin.Context) {
+	authURL, binding, err := h.bangumiLoginService.StartLogin(c.Request.Context(), c.Query("return_to"))
+	if err != nil {
+		writeBangumiLoginError(c, err)
+		return
+	}
+
+	setFederationCookie(c, binding)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// CallbackHandler 处理Bangumi登录回调，回调必须携带发起登录时写入的浏览器绑定Cookie，已登录用户关联Bangumi账号时沿用当前登录会话
+// 已绑定的Bangumi账号直接登录：有return_to时建立会话并跳转，否则返回与用户登录接口相同的令牌；
+// 用户启用了两步验证时先完成第二步验证；未绑定时跳转到登录页，由用户选择创建新账户或关联已有账户
+func (h *BangumiLoginHandler) CallbackHandler(c *gin.Context) {
+	binding := takeFederationCookie(c)
+	if errorParam := c.Query("error"); errorParam != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bangumi拒绝了登录请求", "error_description": c.Query("error_description")})
+		return
+	}
+
+	result, err := h.bangumiLoginService.CompleteLogin(c.Request.Context(), c.Query("code"), c.Query("state"), binding, currentSessionUserID(c, h.sessionService))
+	if err != nil {
+		writeBangumiLoginError(c, err)
+		return
+	}
+
+	if result.Linked {
+		writeIdentityLinked(c, result.Identity, result.ReturnTo)
+		return
+	}
+
+	if result.User == nil {
+		c.Redirect(http.StatusFound, appendQuery(loginPageURL(), url.Values{"bangumi_pending": {result.PendingToken}}))
+		return
+	}
+
+	amr := []string{model.AMRFederated}
+	challenge, ok := startSecondFactor(c, h.mfaService, result.User.ID, amr)
+	if !ok {
+		return
+	}
+	if challenge != nil {
+		if result.ReturnTo != "" {
+			redirectToMFA(c, challenge, result.ReturnTo)
+		} else {
+			writeMFARequired(c, h.mfaService, challenge)
+		}
+		return
+	}
+
+	if result.ReturnTo != "" {
+		session, err := h.sessionService.CreateSession(c.Request.Context(), result.User.ID, amr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "会话创建失败"})
+			return
+		}
+		setSessionCookie(c, session)
+		c.Redirect(http.StatusFound, result.ReturnTo)
+		return
+	}
+
+	writeLoginResponse(c, h.userService, h.sessionService, result.User, amr, bangumiLoginExtra(result))
+}
+
+// GetPendingLoginHandler 获取待完成的Bangumi登录，供登录页展示Bangumi账号信息
+func (h *BangumiLoginHandler) GetPendingLoginHandler(c *gin.Context) {
+	pendingLogin, err := h.bangumiLoginService.GetPendingLogin(c.Request.Context(), c.Query("pending_token"))
+	if err != nil {
+		writeBangumiLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bangumi_user": map[string]interface{}{
+			"id":       pendingLogin.BangumiUserID,
+			"username": pendingLogin.BangumiUsername,
+			"nickname": pendingLogin.Nickname,
+			"avatar":   pendingLogin.AvatarURL,
+		},
+		"expires_in": int(time.Until(pendingLogin.ExpiresAt).Seconds()),
+	})
+}
+
+// CreateAccountHandler 使用Bangumi账号创建本地用户并登录
+func (h *BangumiLoginHandler) CreateAccountHandler(c *gin.Context) {
+	var req BangumiCreateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.bangumiLoginService.CreateAccount(c.Request.Context(), req.PendingToken, req.Username, req.Email)
+	if err != nil {
+		writeBangumiLoginError(c, err)
+		return
+	}
+
+	writeLoginResponse(c, h.userService, h.sessionService, result.User, []string{model.AMRFederated}, bangumiLoginExtra(result))
+}
+
+// LinkAccountHandler 验证已有用户的密码后关联Bangumi账号并登录
+// 启用了两步验证的用户须先登录，再通过关联账号接口关联Bangumi账号
+func (h *BangumiLoginHandler) LinkAccountHandler(c *gin.Context) {
+	var req BangumiLinkAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := authenticatePassword(c, h.userService, h.loginGuardService, req.Username, req.Password)
+	if !ok {
+		return
+	}
+	amr := []string{model.AMRPassword}
+	if h.mfaService.RequiresSecondFactor(c.Request.Context(), user.ID, amr) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "该账户已启用两步验证，请登录后在账户设置中关联Bangumi账号"})
+		return
+	}
+
+	result, err := h.bangumiLoginService.LinkAccount(c.Request.Context(), req.PendingToken, user)
+	if err != nil {
+		writeBangumiLoginError(c, err)
+		return
+	}
+
+	writeLoginResponse(c, h.userService, h.sessionService, result.User, amr, bangumiLoginExtra(result))
+}
+
+// bangumiLoginExtra Bangumi登录响应中附加的字段
+func bangumiLoginExtra(result *service.BangumiLoginResult) gin.H {
+	extra := gin.H{
+		"created": result.Created,
+		"bangumi_user": map[string]interface{}{
+			"id":       result.BangumiUser.ID,
+			"username": result.BangumiUser.Username,
+			"nickname": result.BangumiUser.Nickname,
+		},
+	}
+	if result.ReturnTo != "" {
+		extra["return_to"] = result.ReturnTo
+	}
+	return extra
+}
+
+// writeBangumiLoginError 将Bangumi登录错误转换为HTTP响应
+func writeBangumiLoginError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidFederationState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "登录请求无效或已过期，请重新登录"})
+	case errors.Is(err, service.ErrFederationStateMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "登录请求不是由当前浏览器发起的，请重新登录"})
+	case errors.Is(err, service.ErrLinkSessionMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": "请使用发起关联的账户登录后重新关联"})
+	case errors.Is(err, service.ErrInvalidReturnTo):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "return_to必须指向本服务"})
+	case errors.Is(err, service.ErrInvalidBangumiPendingLogin):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bangumi登录已失效，请重新登录"})
+	case errors.Is(err, service.ErrFederatedUserInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": "关联的本地账户未激活"})
+	case errors.Is(err, service.ErrBangumiAccountAlreadyBound):
+		c.JSON(http.StatusConflict, gin.H{"error": "该Bangumi账号已绑定其他用户"})
+	case errors.Is(err, service.ErrUserHasOtherBangumiAccount):
+		c.JSON(http.StatusConflict, gin.H{"error": "该用户已绑定其他Bangumi账号"})
+	case errors.Is(err, service.ErrUsernameTaken), errors.Is(err, service.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Bangumi登录失败: " + err.Error()})
+	}
+}
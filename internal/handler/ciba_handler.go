@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	
+	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+// CIBAHandler 客户端发起的后端通道认证处理器
+type CIBAHandler struct {
+	oauthService   service.OAuthService
+	cibaService    service.CIBAService
+	sessionService service.SessionService
+	scopeService   service.ScopeService
+}
+
+// NewCIBAHandler 创建CIBAHandler实例
+func NewCIBAHandler(oauthService service.OAuthService, cibaService service.CIBAService, sessionService service.SessionService, scopeService service.ScopeService) *CIBAHandler {
+	return &CIBAHandler{
+		oauthService:   oauthService,
+		cibaService:    cibaService,
+		sessionService: sessionService,
+		scopeService:   scopeService,
+	}
+}
+
+// BackchannelAuthenticationHandler 处理后端通道认证请求 (OpenID CIBA Core §7)
+func (h *CIBAHandler) BackchannelAuthenticationHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	
+	// 认证客户端
+	clientID, clientSecret, ok := parseClientCredentials(c)
+	if !ok {
+		writeTokenError(c, service.ErrInvalidClient("malformed Basic authorization header"))
+		return
+	}
+	if clientID == "" {
+		writeTokenError(c, service.ErrInvalidClient("missing client credentials"))
+		return
+	}
+	client, err := h.oauthService.ValidateClient(c.Request.Context(), clientID, clientSecret, "")
+	if err != nil {
+		writeTokenError(c, service.AsOAuthError(err))
+		return
+	}
+	
+	req := &service.BackchannelAuthenticationRequest{
+		Scopes:                  strings.Fields(c.PostForm("scope")),
+		LoginHint:               c.PostForm("login_hint"),
+		IDTokenHint:             c.PostForm("id_token_hint"),
+		BindingMessage:          c.PostForm("binding_message"),
+		ClientNotificationToken: c.PostForm("client_notification_token"),
+	}
+	if c.PostForm("login_hint_token") != "" || c.PostForm("user_code") != "" {
+		writeTokenError(c, service.ErrInvalidRequest("login_hint_token and user_code are not supported"))
+		return
+	}
+	if rawExpiry := c.PostForm("requested_expiry"); rawExpiry != "" {
+		expiry, err := strconv.Atoi(rawExpiry)
+		if err != nil {
+			writeTokenError(c, service.ErrInvalidRequest("requested_expiry must be a positive integer"))
+			return
+		}
+		req.RequestedExpiry = &expiry
+	}
+	
+	resp, err := h.cibaService.StartAuthentication(c.Request.Context(), client, req)
+	if err != nil {
+		writeTokenError(c, service.AsOAuthError(err))
+		return
+	}
+	
+	c.JSON(http.StatusOK, resp)
+}
+
+// BackchannelRequestInfoHandler 返回批准页面展示所需的认证请求信息，仅目标用户本人可见
+func (h *CIBAHandler) BackchannelRequestInfoHandler(c *gin.Context) {
+	authReq, _, ok := h.pendingRequest(c)
+	if !ok {
+		return
+	}
+	
+	clientName := authReq.ClientID
+	if client, err := h.oauthService.GetClientByClientID(c.Request.Context(), authReq.ClientID); err == nil && client.Name != "" {
+		clientName = client.Name
+	}
+	
+	// 附带scope注册表中的说明，供批准页面展示
+	scopes := []gin.H{}
+	for _, scope := range h.scopeService.DescribeScopes(c.Request.Context(), strings.Fields(authReq.Scopes)) {
+		scopes = append(scopes, gin.H{
+			"name":        scope.Name,
+			"description": scope.Description,
+		})
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"auth_req_id":     authReq.AuthReqID,
+		"client_id":       authReq.ClientID,
+		"client_name":     clientName,
+		"binding_message": authReq.BindingMessage,
+		"scopes":          scopes,
+		"expires_at":      authReq.ExpiresAt,
+	})
+}
+
+// BackchannelDecisionHandler 处理用户对认证请求的批准或拒绝（decision=approve|deny）
+func (h *CIBAHandler) BackchannelDecisionHandler(c *gin.Context) {
+	authReq, session, ok := h.pendingRequest(c)
+	if !ok {
+		return
+	}
+	
+	decision := c.PostForm("decision")
+	if decision != "approve" && decision != "deny" {
+		c.JSON(http.StatusBadRequest, service.ErrInvalidRequest("decision must be approve or deny"))
+		return
+	}
+	
+	if err := h.cibaService.CompleteAuthentication(c.Request.Context(), authReq.AuthReqID, authReq.UserID, session.AuthMethods(), decision == "approve"); err != nil {
+		// 会话未完成两步验证时，批准页面须先引导用户完成第二步验证
+		if oauthErr := service.AsOAuthError(err); oauthErr.Code == service.ErrCodeInteractionRequired {
+			c.JSON(http.StatusForbidden, oauthErr)
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "decision recorded", "decision": decision})
+}
+
+// pendingRequest 根据登录会话获取当前用户待批准的认证请求及该会话，失败时写入错误响应
+func (h *CIBAHandler) pendingRequest(c *gin.Context) (*model.BackchannelAuthRequest, *model.Session, bool) {
+	var session *model.Session
+	if h.sessionService != nil {
+		session, _ = h.sessionService.GetActiveSession(c.Request.Context(), sessionIDFromCookie(c))
+	}
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, service.ErrLoginRequired("the end-user is not authenticated"))
+		return nil, nil, false
+	}
+	
+	authReq, err := h.cibaService.GetPendingRequest(c.Request.Context(), c.Param("auth_req_id"), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get authentication request"})
+		return nil, nil, false
+	}
+	if authReq == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "authentication request not found or no longer pending"})
+		return nil, nil, false
+	}
+	
+	return authReq, session, true
+}
@@ -3,8 +3,10 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	
 	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
 	"github.com/Full-finger/OIDC/internal/service"
 )
 
@@ -44,12 +46,17 @@ func (h *CollectionHandler) AddToCollectionHandler(c *gin.Context) {
 	}
 	
 	// 将用户ID字符串转换为uint
-	userID, err := strconv.ParseUint(userIDStr.(string), 10, 32)
+	userID, err := parseSubjectUserID(userIDStr.(string))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
 		return
 	}
 	
+	if !allowsCollectionAccess(c, model.CollectionActionAdd, req.Status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "operation not permitted by authorization_details"})
+		return
+	}
+	
 	collection, err := h.collectionService.AddToCollection(
 		c.Request.Context(),
 		uint(userID),
@@ -76,19 +83,24 @@ func (h *CollectionHandler) ListUserCollectionsHandler(c *gin.Context) {
 	}
 	
 	// 将用户ID字符串转换为uint
-	userID, err := strconv.ParseUint(userIDStr.(string), 10, 32)
+	userID, err := parseSubjectUserID(userIDStr.(string))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
 		return
 	}
 	
+	if !allowsCollectionAccess(c, model.CollectionActionRead, "") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "operation not permitted by authorization_details"})
+		return
+	}
+	
 	collections, err := h.collectionService.ListUserCollections(c.Request.Context(), uint(userID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	
-	c.JSON(http.StatusOK, collections)
+	c.JSON(http.StatusOK, filterReadableCollections(c, collections))
 }
 
 // ListUserCollectionsByStatusHandler 根据状态列出用户的收藏
@@ -107,12 +119,17 @@ func (h *CollectionHandler) ListUserCollectionsByStatusHandler(c *gin.Context) {
 	}
 	
 	// 将用户ID字符串转换为uint
-	userID, err := strconv.ParseUint(userIDStr.(string), 10, 32)
+	userID, err := parseSubjectUserID(userIDStr.(string))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
 		return
 	}
 	
+	if !allowsCollectionAccess(c, model.CollectionActionRead, status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "operation not permitted by authorization_details"})
+		return
+	}
+	
 	collections, err := h.collectionService.ListUserCollectionsByStatus(
 		c.Request.Context(),
 		uint(userID),
@@ -137,19 +154,24 @@ func (h *CollectionHandler) ListUserFavoritesHandler(c *gin.Context) {
 	}
 	
 	// 将用户ID字符串转换为uint
-	userID, err := strconv.ParseUint(userIDStr.(string), 10, 32)
+	userID, err := parseSubjectUserID(userIDStr.(string))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
 		return
 	}
 	
+	if !allowsCollectionAccess(c, model.CollectionActionRead, "") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "operation not permitted by authorization_details"})
+		return
+	}
+	
 	collections, err := h.collectionService.ListUserFavorites(c.Request.Context(), uint(userID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list user favorites"})
 		return
 	}
 	
-	c.JSON(http.StatusOK, collections)
+	c.JSON(http.StatusOK, filterReadableCollections(c, collections))
 }
 
 // GetCollectionHandler 获取用户的番剧收藏
@@ -169,7 +191,7 @@ func (h *CollectionHandler) GetCollectionHandler(c *gin.Context) {
 	}
 	
 	// 将用户ID字符串转换为uint
-	userID, err := strconv.ParseUint(userIDStr.(string), 10, 32)
+	userID, err := parseSubjectUserID(userIDStr.(string))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
 		return
@@ -186,6 +208,11 @@ func (h *CollectionHandler) GetCollectionHandler(c *gin.Context) {
 		return
 	}
 	
+	if !allowsCollectionAccess(c, model.CollectionActionRead, collection.Status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "operation not permitted by authorization_details"})
+		return
+	}
+	
 	c.JSON(http.StatusOK, collection)
 }
 
@@ -220,7 +247,7 @@ func (h *CollectionHandler) UpdateCollectionHandler(c *gin.Context) {
 	}
 	
 	// 将用户ID字符串转换为uint
-	userID, err := strconv.ParseUint(userIDStr.(string), 10, 32)
+	userID, err := parseSubjectUserID(userIDStr.(string))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
 		return
@@ -238,6 +265,13 @@ func (h *CollectionHandler) UpdateCollectionHandler(c *gin.Context) {
 		return
 	}
 	
+	// 修改状态时，原状态和新状态都必须在authorization_details允许的范围内
+	if !allowsCollectionAccess(c, model.CollectionActionUpdate, collection.Status) ||
+		(req.Status != "" && !allowsCollectionAccess(c, model.CollectionActionUpdate, req.Status)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "operation not permitted by authorization_details"})
+		return
+	}
+	
 	// 更新收藏记录的字段
 	if req.Status != "" {
 		collection.Status = req.Status
@@ -282,12 +316,23 @@ func (h *CollectionHandler) RemoveFromCollectionHandler(c *gin.Context) {
 	}
 	
 	// 将用户ID字符串转换为uint
-	userID, err := strconv.ParseUint(userIDStr.(string), 10, 32)
+	userID, err := parseSubjectUserID(userIDStr.(string))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
 		return
 	}
 	
+	collection, err := h.collectionService.GetCollection(c.Request.Context(), uint(userID), uint(animeID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get collection"})
+		return
+	}
+	
+	if collection != nil && !allowsCollectionAccess(c, model.CollectionActionRemove, collection.Status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "operation not permitted by authorization_details"})
+		return
+	}
+	
 	err = h.collectionService.RemoveFromCollection(c.Request.Context(), uint(userID), uint(animeID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -296,3 +341,119 @@ func (h *CollectionHandler) RemoveFromCollectionHandler(c *gin.Context) {
 	
 	c.JSON(http.StatusOK, gin.H{"message": "collection removed successfully"})
 }
+
+// UpdateProgressRequest 更新观看进度请求
+type UpdateProgressRequest struct {
+	Progress *int `json:"progress" binding:"required"`
+}
+
+// UpdateProgressHandler 更新用户收藏的观看进度
+func (h *CollectionHandler) UpdateProgressHandler(c *gin.Context) {
+	animeIDStr := c.Param("anime_id")
+	animeID, err := strconv.ParseUint(animeIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid anime id"})
+		return
+	}
+	
+	var req UpdateProgressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.Progress < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "progress must not be negative"})
+		return
+	}
+	
+	// 从上下文中获取用户ID
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	
+	// 将用户ID字符串转换为uint
+	userID, err := parseSubjectUserID(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+	
+	collection, err := h.collectionService.GetCollection(c.Request.Context(), uint(userID), uint(animeID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get collection"})
+		return
+	}
+	
+	if collection == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "collection not found"})
+		return
+	}
+	
+	// update操作包含update_progress
+	if !allowsCollectionAccess(c, model.CollectionActionUpdateProgress, collection.Status) &&
+		!allowsCollectionAccess(c, model.CollectionActionUpdate, collection.Status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "operation not permitted by authorization_details"})
+		return
+	}
+	
+	collection, err = h.collectionService.UpdateProgress(c.Request.Context(), uint(userID), uint(animeID), *req.Progress)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, collection)
+}
+
+// parseSubjectUserID 解析访问令牌subject中的用户ID，兼容第一方登录令牌的"N"与OAuth令牌的"user:N"
+func parseSubjectUserID(subject string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimPrefix(subject, "user:"), 10, 32)
+}
+
+// collectionAccessDetails 获取访问令牌中collection_access类型的authorization_details
+func collectionAccessDetails(c *gin.Context) []model.AuthorizationDetail {
+	value, exists := c.Get("authorization_details")
+	if !exists {
+		return nil
+	}
+	details, _ := value.([]model.AuthorizationDetail)
+
+	var collectionDetails []model.AuthorizationDetail
+	for _, detail := range details {
+		if detail.Type == model.AuthorizationDetailTypeCollectionAccess {
+			collectionDetails = append(collectionDetails, detail)
+		}
+	}
+	return collectionDetails
+}
+
+// allowsCollectionAccess 判断authorization_details是否允许对指定状态的收藏执行操作，status为空表示不限定状态
+// 令牌未携带collection_access时只受scope约束
+func allowsCollectionAccess(c *gin.Context, action, status string) bool {
+	details := collectionAccessDetails(c)
+	if len(details) == 0 {
+		return true
+	}
+	for i := range details {
+		if details[i].Allows(action, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterReadableCollections 过滤掉authorization_details不允许读取的收藏
+func filterReadableCollections(c *gin.Context, collections []*model.Collection) []*model.Collection {
+	if len(collectionAccessDetails(c)) == 0 {
+		return collections
+	}
+	readable := make([]*model.Collection, 0, len(collections))
+	for _, collection := range collections {
+		if allowsCollectionAccess(c, model.CollectionActionRead, collection.Status) {
+			readable = append(readable, collection)
+		}
+	}
+	return readable
+}
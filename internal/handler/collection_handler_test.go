@@ -0,0 +1,203 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/handler"
+	"github.com/Full-finger/OIDC/internal/middleware"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// memoryCollectionService 内存中的收藏服务，收藏按番剧ID保存
+type memoryCollectionService struct {
+	collections map[uint]*model.Collection
+}
+
+func (s *memoryCollectionService) AddToCollection(ctx context.Context, userID, animeID uint, status string, rating *float64, comment string) (*model.Collection, error) {
+	collection := &model.Collection{UserID: userID, AnimeID: animeID, Status: status, Rating: rating, Comment: comment}
+	s.collections[animeID] = collection
+	return collection, nil
+}
+
+func (s *memoryCollectionService) GetCollection(ctx context.Context, userID, animeID uint) (*model.Collection, error) {
+	return s.collections[animeID], nil
+}
+
+func (s *memoryCollectionService) UpdateCollection(ctx context.Context, collection *model.Collection) error {
+	s.collections[collection.AnimeID] = collection
+	return nil
+}
+
+func (s *memoryCollectionService) RemoveFromCollection(ctx context.Context, userID, animeID uint) error {
+	delete(s.collections, animeID)
+	return nil
+}
+
+func (s *memoryCollectionService) ListUserCollections(ctx context.Context, userID uint) ([]*model.Collection, error) {
+	var collections []*model.Collection
+	for _, collection := range s.collections {
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+func (s *memoryCollectionService) ListUserCollectionsByStatus(ctx context.Context, userID uint, status string) ([]*model.Collection, error) {
+	var collections []*model.Collection
+	for _, collection := range s.collections {
+		if collection.Status == status {
+			collections = append(collections, collection)
+		}
+	}
+	return collections, nil
+}
+
+func (s *memoryCollectionService) ListUserFavorites(ctx context.Context, userID uint) ([]*model.Collection, error) {
+	return nil, nil
+}
+
+func (s *memoryCollectionService) UpdateProgress(ctx context.Context, userID, animeID uint, progress int) (*model.Collection, error) {
+	collection := s.collections[animeID]
+	if collection == nil {
+		return nil, errors.New("collection not found")
+	}
+	collection.Progress = progress
+	return collection, nil
+}
+
+// newCollectionRouter 创建收藏接口路由，用户1收藏了番剧1（在看）和番剧2（看过）
+func newCollectionRouter(t *testing.T) (*gin.Engine, util.JWTUtil) {
+	t.Helper()
+	t.Setenv("ISSUER_URL", testIssuer)
+	jwtUtil, err := util.NewEphemeralJWTUtil()
+	if err != nil {
+		t.Fatalf("create jwt util: %v", err)
+	}
+
+	collections := &memoryCollectionService{collections: map[uint]*model.Collection{
+		1: {UserID: 1, AnimeID: 1, Status: "watching"},
+		2: {UserID: 1, AnimeID: 2, Status: "completed"},
+	}}
+	collectionHandler := handler.NewCollectionHandler(collections)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.IssuerMiddleware())
+	collection := router.Group("/api/v1/collection", middleware.JWTAuthMiddleware(jwtUtil, nil))
+	collection.POST("/", collectionHandler.AddToCollectionHandler)
+	collection.GET("/:anime_id", collectionHandler.GetCollectionHandler)
+	collection.PUT("/:anime_id", collectionHandler.UpdateCollectionHandler)
+	collection.PUT("/:anime_id/progress", collectionHandler.UpdateProgressHandler)
+	collection.DELETE("/:anime_id", collectionHandler.RemoveFromCollectionHandler)
+	collection.GET("/", collectionHandler.ListUserCollectionsHandler)
+	return router, jwtUtil
+}
+
+// collectionToken 签发携带指定authorization_details的访问令牌
+func collectionToken(t *testing.T, jwtUtil util.JWTUtil, details []model.AuthorizationDetail) string {
+	t.Helper()
+	token, err := jwtUtil.GenerateAccessToken(&util.AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   "user:1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		Scope:                "collection:read collection:write",
+		AuthorizationDetails: details,
+	})
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+	return token
+}
+
+// callCollectionAPI 以访问令牌调用收藏接口
+func callCollectionAPI(router *gin.Engine, method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCollectionAccessEnforcesAuthorizationDetails(t *testing.T) {
+	progressWatching := []model.AuthorizationDetail{{
+		Type:     model.AuthorizationDetailTypeCollectionAccess,
+		Actions:  []string{model.CollectionActionRead, model.CollectionActionUpdateProgress},
+		Statuses: []string{"watching"},
+	}}
+	updateWatching := []model.AuthorizationDetail{{
+		Type:     model.AuthorizationDetailTypeCollectionAccess,
+		Actions:  []string{model.CollectionActionUpdate},
+		Statuses: []string{"watching"},
+	}}
+	addAny := []model.AuthorizationDetail{{
+		Type:    model.AuthorizationDetailTypeCollectionAccess,
+		Actions: []string{model.CollectionActionAdd},
+	}}
+
+	tests := []struct {
+		name    string
+		details []model.AuthorizationDetail
+		method  string
+		path    string
+		body    string
+		want    int
+	}{
+		{"no details is limited only by scope", nil, http.MethodDelete, "/api/v1/collection/2", "", http.StatusOK},
+		{"progress on allowed status", progressWatching, http.MethodPut, "/api/v1/collection/1/progress", `{"progress": 3}`, http.StatusOK},
+		{"progress on other status", progressWatching, http.MethodPut, "/api/v1/collection/2/progress", `{"progress": 3}`, http.StatusForbidden},
+		{"read allowed status", progressWatching, http.MethodGet, "/api/v1/collection/1", "", http.StatusOK},
+		{"read other status", progressWatching, http.MethodGet, "/api/v1/collection/2", "", http.StatusForbidden},
+		{"update_progress does not grant update", progressWatching, http.MethodPut, "/api/v1/collection/1", `{"comment": "great"}`, http.StatusForbidden},
+		{"remove not granted", progressWatching, http.MethodDelete, "/api/v1/collection/1", "", http.StatusForbidden},
+		{"update includes update_progress", updateWatching, http.MethodPut, "/api/v1/collection/1/progress", `{"progress": 3}`, http.StatusOK},
+		{"update within allowed status", updateWatching, http.MethodPut, "/api/v1/collection/1", `{"comment": "great"}`, http.StatusOK},
+		{"update to disallowed status", updateWatching, http.MethodPut, "/api/v1/collection/1", `{"status": "completed"}`, http.StatusForbidden},
+		{"update does not grant read", updateWatching, http.MethodGet, "/api/v1/collection/1", "", http.StatusForbidden},
+		{"add without status restriction", addAny, http.MethodPost, "/api/v1/collection/", `{"anime_id": 3, "status": "plan_to_watch"}`, http.StatusOK},
+		{"add restricted to status", updateWatching, http.MethodPost, "/api/v1/collection/", `{"anime_id": 3, "status": "watching"}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, jwtUtil := newCollectionRouter(t)
+			w := callCollectionAPI(router, tt.method, tt.path, tt.body, collectionToken(t, jwtUtil, tt.details))
+			if w.Code != tt.want {
+				t.Fatalf("%s %s: expected %d, got %d: %s", tt.method, tt.path, tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCollectionAccessFiltersReadableCollections(t *testing.T) {
+	router, jwtUtil := newCollectionRouter(t)
+	token := collectionToken(t, jwtUtil, []model.AuthorizationDetail{{
+		Type:     model.AuthorizationDetailTypeCollectionAccess,
+		Actions:  []string{model.CollectionActionRead},
+		Statuses: []string{"watching"},
+	}})
+
+	w := callCollectionAPI(router, http.MethodGet, "/api/v1/collection/", "", token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var collections []model.Collection
+	if err := json.Unmarshal(w.Body.Bytes(), &collections); err != nil {
+		t.Fatalf("decode collections: %v", err)
+	}
+	if len(collections) != 1 || collections[0].AnimeID != 1 {
+		t.Fatalf("only the watching collection should be listed, got %+v", collections)
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// EmailChangeHandler 邮箱变更处理器
+type EmailChangeHandler struct {
+	emailChangeService service.EmailChangeService
+	sessionService     service.SessionService
+}
+
+// NewEmailChangeHandler 创建EmailChangeHandler实例
+func NewEmailChangeHandler(emailChangeService service.EmailChangeService, sessionService service.SessionService) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		emailChangeService: emailChangeService,
+		sessionService:     sessionService,
+	}
+}
+
+// EmailChangeRequest 申请更改邮箱请求结构体
+type EmailChangeRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+}
+
+// EmailChangeTokenRequest 确认或撤销邮箱变更请求结构体
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RequestChangeHandler 申请更改邮箱，新邮箱确认前账户邮箱保持不变
+// 申请要求用户在EMAIL_CHANGE_MAX_AUTH_AGE_SECONDS（默认300秒）内重新登录过
+func (h *EmailChangeHandler) RequestChangeHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+	if time.Since(session.AuthTime) > emailChangeMaxAuthAge() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "更改邮箱前请重新登录", "reauthentication_required": true})
+		return
+	}
+
+	var req EmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.emailChangeService.RequestChange(c.Request.Context(), session.UserID, req.NewEmail)
+	if err != nil {
+		writeEmailChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "确认邮件已发送到新邮箱，确认后邮箱变更生效",
+		"new_email":  request.NewEmail,
+		"expires_at": request.ExpiresAt,
+	})
+}
+
+// ConfirmChangeHandler 使用新邮箱收到的令牌确认邮箱变更
+func (h *EmailChangeHandler) ConfirmChangeHandler(c *gin.Context) {
+	var req EmailChangeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.emailChangeService.ConfirmChange(c.Request.Context(), req.Token)
+	if err != nil {
+		writeEmailChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "邮箱已更改",
+		"email":   user.Email,
+	})
+}
+
+// RevertChangeHandler 使用原邮箱收到的令牌撤销邮箱变更，成功后用户需在所有设备上重新登录
+func (h *EmailChangeHandler) RevertChangeHandler(c *gin.Context) {
+	var req EmailChangeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.emailChangeService.RevertChange(c.Request.Context(), req.Token); err != nil {
+		if errors.Is(err, service.ErrEmailInUse) {
+			// 令牌和会话已撤销，只是原邮箱无法恢复
+			c.JSON(http.StatusConflict, gin.H{"error": "原邮箱已被其他账户注册，无法恢复；已退出所有设备，请联系管理员"})
+			return
+		}
+		writeEmailChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "邮箱变更已撤销，请重新登录并及时修改密码",
+	})
+}
+
+// writeEmailChangeError 将邮箱变更错误转换为HTTP响应
+func writeEmailChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidEmailChangeToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "链接无效或已过期"})
+	case errors.Is(err, service.ErrEmailUnchanged):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "新邮箱与当前邮箱相同"})
+	case errors.Is(err, service.ErrEmailInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "邮箱已被注册"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更改邮箱失败"})
+	}
+}
+
+// emailChangeMaxAuthAge 申请更改邮箱时登录会话的最长认证时长
+func emailChangeMaxAuthAge() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("EMAIL_CHANGE_MAX_AUTH_AGE_SECONDS")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Minute
+}
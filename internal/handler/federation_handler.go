@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// FederationHandler 上游OpenID Connect登录处理器
+type FederationHandler struct {
+	federationService service.FederationService
+	sessionService    service.SessionService
+	mfaService        service.MFAService
+}
+
+// NewFederationHandler 创建FederationHandler实例
+func NewFederationHandler(federationService service.FederationService, sessionService service.SessionService, mfaService service.MFAService) *FederationHandler {
+	return &FederationHandler{
+		federationService: federationService,
+		sessionService:    sessionService,
+		mfaService:        mfaService,
+	}
+}
+
+// ListProvidersHandler 获取可用于登录的上游身份提供方，供登录页展示
+func (h *FederationHandler) ListProvidersHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": h.federationService.ListProviders(c.Request.Context()),
+	})
+}
+
+// LoginHandler 跳转到上游授权端点
+// return_to通常为登录页收到的原始授权请求地址，上游登录完成后跳转回该地址继续授权
+func (h *FederationHandler) LoginHandler(c *gin.Context) {
+	authURL, binding, err := h.federationService.StartLogin(c.Request.Context(), c.Param("provider"), c.Query("return_to"))
+	if err != nil {
+		writeFederationError(c, err)
+		return
+	}
+
+	setFederationCookie(c, binding)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// CallbackHandler 处理上游回调，登录成功后创建会话并跳转回原始请求
+// 回调必须携带发起登录时写入的浏览器绑定Cookie；
+// 用户启用了两步验证时跳转到登录页输入验证码，完成后由登录页跳转回原始请求
+func (h *FederationHandler) CallbackHandler(c *gin.Context) {
+	binding := takeFederationCookie(c)
+	if upstreamError := c.Query("error"); upstreamError != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "上游身份提供方拒绝了登录请求",
+			"upstream_error":    upstreamError,
+			"error_description": c.Query("error_description"),
+		})
+		return
+	}
+
+	result, err := h.federationService.CompleteLogin(c.Request.Context(), c.Param("provider"), c.Query("code"), c.Query("state"), binding, currentSessionUserID(c, h.sessionService))
+	if err != nil {
+		writeFederationError(c, err)
+		return
+	}
+
+	// 关联账号沿用当前登录会话，服务层已确认会话用户即发起关联的用户
+	if result.Linked {
+		writeIdentityLinked(c, result.Identity, result.ReturnTo)
+		return
+	}
+
+	amr := []string{model.AMRFederated}
+	challenge, ok := startSecondFactor(c, h.mfaService, result.User.ID, amr)
+	if !ok {
+		return
+	}
+	if challenge != nil {
+		redirectToMFA(c, challenge, result.ReturnTo)
+		return
+	}
+
+	session, err := h.sessionService.CreateSession(c.Request.Context(), result.User.ID, amr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "会话创建失败"})
+		return
+	}
+	setSessionCookie(c, session)
+
+	returnTo := result.ReturnTo
+	if returnTo == "" {
+		returnTo = loginPageURL()
+	}
+	c.Redirect(http.StatusFound, returnTo)
+}
+
+// writeIdentityLinked 关联账号完成后跳转到return_to，未指定时返回关联的外部身份
+func writeIdentityLinked(c *gin.Context, identity *model.ExternalIdentity, returnTo string) {
+	if returnTo != "" {
+		c.Redirect(http.StatusFound, returnTo)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "关联成功",
+		"identity": identity,
+	})
+}
+
+// writeFederationError 将上游登录错误转换为HTTP响应
+func writeFederationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrIdentityProviderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "身份提供方不存在"})
+	case errors.Is(err, service.ErrInvalidFederationState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "登录请求无效或已过期，请重新登录"})
+	case errors.Is(err, service.ErrFederationStateMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "登录请求不是由当前浏览器发起的，请重新登录"})
+	case errors.Is(err, service.ErrLinkSessionMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": "请使用发起关联的账户登录后重新关联"})
+	case errors.Is(err, service.ErrInvalidReturnTo):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "return_to必须指向本服务"})
+	case errors.Is(err, service.ErrFederatedEmailMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "身份提供方未返回邮箱地址"})
+	case errors.Is(err, service.ErrFederatedEmailConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "该邮箱已被本地账户使用，请使用本地账户登录后再关联"})
+	case errors.Is(err, service.ErrIdentityLinkedToOther):
+		c.JSON(http.StatusConflict, gin.H{"error": "该账号已关联其他用户"})
+	case errors.Is(err, service.ErrFederatedUserInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": "关联的本地账户未激活"})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "上游登录失败: " + err.Error()})
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// IdentityHandler 外部身份关联处理器，通过登录会话识别用户
+type IdentityHandler struct {
+	identityService     service.IdentityService
+	federationService   service.FederationService
+	bangumiLoginService service.BangumiLoginService
+	sessionService      service.SessionService
+}
+
+// NewIdentityHandler 创建IdentityHandler实例
+func NewIdentityHandler(identityService service.IdentityService, federationService service.FederationService, bangumiLoginService service.BangumiLoginService, sessionService service.SessionService) *IdentityHandler {
+	return &IdentityHandler{
+		identityService:     identityService,
+		federationService:   federationService,
+		bangumiLoginService: bangumiLoginService,
+		sessionService:      sessionService,
+	}
+}
+
+// ListIdentitiesHandler 获取当前用户关联的外部身份
+func (h *IdentityHandler) ListIdentitiesHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	identities, err := h.identityService.ListIdentities(c.Request.Context(), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取关联账号失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identities": identities,
+	})
+}
+
+// LinkIdentityHandler 跳转到上游身份提供方，为当前用户关联账号
+// 关联要求用户在IDENTITY_LINK_MAX_AUTH_AGE_SECONDS（默认300秒）内重新登录过
+func (h *IdentityHandler) LinkIdentityHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+	if time.Since(session.AuthTime) > identityLinkMaxAuthAge() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "关联账号前请重新登录", "reauthentication_required": true})
+		return
+	}
+
+	var authURL, binding string
+	var err error
+	provider := c.Param("provider")
+	if provider == service.BangumiProviderName {
+		authURL, binding, err = h.bangumiLoginService.StartLink(c.Request.Context(), session.UserID, c.Query("return_to"))
+	} else {
+		authURL, binding, err = h.federationService.StartLink(c.Request.Context(), provider, session.UserID, c.Query("return_to"))
+	}
+	if err != nil {
+		writeFederationError(c, err)
+		return
+	}
+
+	setFederationCookie(c, binding)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// UnlinkIdentityHandler 解除当前用户的外部身份关联
+func (h *IdentityHandler) UnlinkIdentityHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	identityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的关联账号ID"})
+		return
+	}
+
+	if err := h.identityService.UnlinkIdentity(c.Request.Context(), session.UserID, uint(identityID)); err != nil {
+		switch {
+		case errors.Is(err, service.ErrExternalIdentityNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "关联账号不存在"})
+		case errors.Is(err, service.ErrLastLoginMethod):
+			c.JSON(http.StatusConflict, gin.H{"error": "不能解除账户唯一的登录方式，请先设置密码或关联其他账号"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "解除关联失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已解除关联",
+	})
+}
+
+// identityLinkMaxAuthAge 关联外部身份时登录会话的最长认证时长
+func identityLinkMaxAuthAge() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("IDENTITY_LINK_MAX_AUTH_AGE_SECONDS")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Minute
+}
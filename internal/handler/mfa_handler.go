@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// MFAHandler 两步验证处理器，账户设置接口通过登录会话识别用户
+type MFAHandler struct {
+	mfaService     service.MFAService
+	userService    service.UserService
+	sessionService service.SessionService
+}
+
+// NewMFAHandler 创建MFAHandler实例
+func NewMFAHandler(mfaService service.MFAService, userService service.UserService, sessionService service.SessionService) *MFAHandler {
+	return &MFAHandler{
+		mfaService:     mfaService,
+		userService:    userService,
+		sessionService: sessionService,
+	}
+}
+
+// MFALoginRequest 第二步验证请求结构体，code可以是TOTP验证码或恢复码
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFACodeRequest 需要验证TOTP验证码或恢复码的操作请求结构体
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// VerifyLoginHandler 完成第二步验证并登录，返回与用户登录接口相同的令牌
+func (h *MFAHandler) VerifyLoginHandler(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.mfaService.CompleteChallenge(c.Request.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(result.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	// 授权流程中补充第二步验证时，以新会话替换未完成两步验证的旧会话
+	_ = h.sessionService.DeleteSession(c.Request.Context(), sessionIDFromCookie(c))
+
+	writeLoginResponse(c, h.userService, h.sessionService, user, result.AMR, gin.H{
+		"recovery_code_used":       result.RecoveryCodeUsed,
+		"recovery_codes_remaining": result.RecoveryCodesRemaining,
+	})
+}
+
+// GetStatusHandler 获取当前用户的两步验证状态
+func (h *MFAHandler) GetStatusHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfaService.GetStatus(c.Request.Context(), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取两步验证状态失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// BeginTOTPEnrollmentHandler 生成TOTP共享密钥和otpauth地址，前端据此展示二维码
+// 启用两步验证要求用户在MFA_ENROLL_MAX_AUTH_AGE_SECONDS（默认300秒）内重新登录过
+func (h *MFAHandler) BeginTOTPEnrollmentHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+	if time.Since(session.AuthTime) > mfaEnrollMaxAuthAge() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "启用两步验证前请重新登录", "reauthentication_required": true})
+		return
+	}
+
+	enrollment, err := h.mfaService.BeginTOTPEnrollment(c.Request.Context(), session.UserID)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTPEnrollmentHandler 输入认证器生成的验证码启用TOTP，恢复码只在此时返回一次
+func (h *MFAHandler) ConfirmTOTPEnrollmentHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := h.mfaService.ConfirmTOTPEnrollment(c.Request.Context(), session.UserID, req.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "两步验证已启用，请妥善保存恢复码",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableTOTPHandler 验证TOTP验证码或恢复码后停用两步验证
+func (h *MFAHandler) DisableTOTPHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.mfaService.DisableTOTP(c.Request.Context(), session.UserID, req.Code); err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "两步验证已停用",
+	})
+}
+
+// RegenerateRecoveryCodesHandler 验证TOTP验证码或恢复码后重新生成恢复码
+func (h *MFAHandler) RegenerateRecoveryCodesHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recoveryCodes, err := h.mfaService.RegenerateRecoveryCodes(c.Request.Context(), session.UserID, req.Code)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// ResetUserMFAHandler 管理员重置用户的两步验证，用于认证器和恢复码都已丢失的情况
+func (h *MFAHandler) ResetUserMFAHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	if err := h.mfaService.ResetMFA(c.Request.Context(), uint(userID)); err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "两步验证已重置",
+	})
+}
+
+// startSecondFactor 用户启用了两步验证时创建第二步验证挑战，无需第二步时返回nil；创建失败时写入500响应
+func startSecondFactor(c *gin.Context, mfaService service.MFAService, userID uint, amr []string) (*model.MFAChallenge, bool) {
+	challenge, err := mfaService.BeginChallenge(c.Request.Context(), userID, amr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "两步验证创建失败"})
+		return nil, false
+	}
+	return challenge, true
+}
+
+// writeMFARequired 返回需要第二步验证的登录响应，登录页据此要求用户输入验证码或使用通行密钥
+func writeMFARequired(c *gin.Context, mfaService service.MFAService, challenge *model.MFAChallenge) {
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required": true,
+		"mfa_token":    challenge.Token,
+		"methods":      mfaService.Methods(c.Request.Context(), challenge.UserID),
+		"expires_in":   int(time.Until(challenge.ExpiresAt).Seconds()),
+	})
+}
+
+// redirectToMFA 跳转到登录页输入第二步验证码，完成后由登录页跳转回return_to
+func redirectToMFA(c *gin.Context, challenge *model.MFAChallenge, returnTo string) {
+	params := url.Values{}
+	params.Set("mfa_token", challenge.Token)
+	if returnTo != "" {
+		params.Set("return_to", returnTo)
+	}
+	c.Redirect(http.StatusFound, appendQuery(loginPageURL(), params))
+}
+
+// writeMFAError 将两步验证错误转换为HTTP响应
+func writeMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFAChallenge):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "两步验证已失效，请重新登录"})
+	case errors.Is(err, service.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "两步验证已启用"})
+	case errors.Is(err, service.ErrTOTPNotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先获取TOTP密钥"})
+	case errors.Is(err, service.ErrTOTPNotEnabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": "未启用两步验证"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "两步验证操作失败"})
+	}
+}
+
+// mfaEnrollMaxAuthAge 启用两步验证时登录会话的最长认证时长
+func mfaEnrollMaxAuthAge() time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv("MFA_ENROLL_MAX_AUTH_AGE_SECONDS")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 5 * time.Minute
+}
@@ -1,21 +1,35 @@
 package handler
 
 import (
+	"encoding/base64"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
 	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
 )
 
 // OAuthHandler OAuth处理器
 type OAuthHandler struct {
-	oauthService service.OAuthService
+	oauthService   service.OAuthService
+	sessionService service.SessionService
+	scopeService   service.ScopeService
+	detailsService service.AuthorizationDetailsService
+	mfaService     service.MFAService
 }
 
 // NewOAuthHandler 创建OAuthHandler实例
-func NewOAuthHandler(oauthService service.OAuthService) *OAuthHandler {
+func NewOAuthHandler(oauthService service.OAuthService, sessionService service.SessionService, scopeService service.ScopeService, detailsService service.AuthorizationDetailsService, mfaService service.MFAService) *OAuthHandler {
 	return &OAuthHandler{
-		oauthService: oauthService,
+		oauthService:   oauthService,
+		sessionService: sessionService,
+		scopeService:   scopeService,
+		detailsService: detailsService,
+		mfaService:     mfaService,
 	}
 }
 
@@ -30,28 +44,40 @@ func (h *OAuthHandler) DiscoveryHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, config)
 }
 
+// AuthorizationServerMetadataHandler 处理OAuth 2.0授权服务器元数据端点请求 (RFC 8414)
+func (h *OAuthHandler) AuthorizationServerMetadataHandler(c *gin.Context) {
+	metadata, err := h.oauthService.GetAuthorizationServerMetadata(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get metadata"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, metadata)
+}
+
 // UserInfoHandler 处理用户信息端点请求
 func (h *OAuthHandler) UserInfoHandler(c *gin.Context) {
 	// 从Authorization头获取访问令牌
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
+		// 未携带凭据时仅返回质询，不附带错误码 (RFC 6750 §3.1)
+		h.writeBearerError(c, &service.OAuthError{StatusCode: http.StatusUnauthorized})
 		return
 	}
 	
 	// 解析Bearer令牌
 	tokenString := ""
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+	if len(authHeader) > 7 && strings.EqualFold(authHeader[:7], "Bearer ") {
 		tokenString = authHeader[7:]
 	} else {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
+		h.writeBearerError(c, service.ErrInvalidRequest("authorization header must use the Bearer scheme"))
 		return
 	}
 	
 	// 获取用户信息
 	userInfo, err := h.oauthService.GetUserInfo(c.Request.Context(), tokenString)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid access token"})
+		h.writeBearerError(c, service.AsOAuthError(err))
 		return
 	}
 	
@@ -60,112 +86,449 @@ func (h *OAuthHandler) UserInfoHandler(c *gin.Context) {
 
 // JWKSHandler 处理JWKS端点请求
 func (h *OAuthHandler) JWKSHandler(c *gin.Context) {
-	// 返回空的JWKS结构，实际应该包含公钥信息
-	jwks := map[string]interface{}{
-		"keys": []interface{}{},
+	jwks, err := h.oauthService.GetJWKS(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get jwks"})
+		return
 	}
 	
 	c.JSON(http.StatusOK, jwks)
 }
 
-// AuthorizeHandler 处理授权请求
+// AuthorizeHandler 处理授权请求，支持GET查询参数与POST表单两种方式
 func (h *OAuthHandler) AuthorizeHandler(c *gin.Context) {
-	// 获取查询参数
-	clientID := c.Query("client_id")
-	redirectURI := c.Query("redirect_uri")
-	scope := c.Query("scope")
-	responseType := c.Query("response_type")
-	state := c.Query("state")
-	codeChallenge := c.Query("code_challenge")
-	codeChallengeMethod := c.Query("code_challenge_method")
+	values := c.Request.URL.Query()
+	if c.Request.Method == http.MethodPost {
+		if err := c.Request.ParseForm(); err != nil {
+			c.JSON(http.StatusBadRequest, service.ErrInvalidRequest("malformed form body"))
+			return
+		}
+		values = c.Request.PostForm
+	}
 
-	// 验证必需参数
-	if clientID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing client_id"})
+	h.authorize(c, values)
+}
+
+// ConsentInfoHandler 返回同意页面展示所需的客户端与scope信息
+func (h *OAuthHandler) ConsentInfoHandler(c *gin.Context) {
+	values := c.Request.URL.Query()
+
+	client, err := h.oauthService.ValidateAuthorizationRequest(c.Request.Context(), values.Get("client_id"), values.Get("redirect_uri"))
+	if err != nil {
+		oauthErr := service.AsOAuthError(err)
+		c.JSON(oauthErr.StatusCode, oauthErr)
 		return
 	}
 
-	if redirectURI == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing redirect_uri"})
+	if h.currentSession(c) == nil {
+		c.JSON(http.StatusUnauthorized, service.ErrLoginRequired("the end-user is not authenticated"))
 		return
 	}
 
-	if responseType == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing response_type"})
+	// 附带scope注册表中的说明，供同意页面展示
+	scopes := []gin.H{}
+	for _, scope := range h.scopeService.DescribeScopes(c.Request.Context(), h.parseScopes(values.Get("scope"))) {
+		scopes = append(scopes, gin.H{
+			"name":             scope.Name,
+			"description":      scope.Description,
+			"requires_consent": scope.RequiresConsent,
+		})
+	}
+
+	// 逐项展示authorization_details及其类型说明
+	details, err := model.ParseAuthorizationDetails(values.Get("authorization_details"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, service.ErrInvalidAuthorizationDetails("authorization_details must be a JSON array of objects"))
 		return
 	}
+	authorizationDetails := []gin.H{}
+	for _, detail := range details {
+		entry := gin.H{
+			"type":     detail.Type,
+			"actions":  detail.Actions,
+			"statuses": detail.Statuses,
+		}
+		if detailType := h.detailsService.GetType(c.Request.Context(), detail.Type); detailType != nil {
+			entry["description"] = detailType.Description
+		}
+		authorizationDetails = append(authorizationDetails, entry)
+	}
 
-	// 验证response_type是否为code
-	if responseType != "code" {
-		c.Redirect(http.StatusFound, redirectURI+"?error=unsupported_response_type&state="+state)
+	c.JSON(http.StatusOK, gin.H{
+		"client_id":             client.ClientID,
+		"client_name":           client.Name,
+		"description":           client.Description,
+		"redirect_uri":          values.Get("redirect_uri"),
+		"scopes":                scopes,
+		"authorization_details": authorizationDetails,
+	})
+}
+
+// ConsentHandler 处理同意页面提交，表单包含原始授权请求参数及decision=approve|deny
+func (h *OAuthHandler) ConsentHandler(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, service.ErrInvalidRequest("malformed form body"))
 		return
 	}
+	values := c.Request.PostForm
+	clientID := values.Get("client_id")
+	redirectURI := values.Get("redirect_uri")
+	state := values.Get("state")
 
-	// TODO: 验证用户是否已登录
-	// 在实际实现中，应该检查用户是否已通过身份验证
-	// 如果未登录，应该重定向到登录页面
-	userID := "1" // 模拟用户ID
+	if _, err := h.oauthService.ValidateAuthorizationRequest(c.Request.Context(), clientID, redirectURI); err != nil {
+		oauthErr := service.AsOAuthError(err)
+		c.JSON(oauthErr.StatusCode, oauthErr)
+		return
+	}
 
-	// 解析scopes
-	scopes := h.parseScopes(scope)
+	session := h.currentSession(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, service.ErrLoginRequired("the end-user is not authenticated"))
+		return
+	}
 
-	// 处理授权请求
-	var codeChallengePtr *string
-	var codeChallengeMethodPtr *string
-	
-	if codeChallenge != "" {
-		codeChallengePtr = &codeChallenge
+	if values.Get("decision") != "approve" {
+		h.oauthService.DenyConsent(c.Request.Context(), session.UserID, clientID, h.parseScopes(values.Get("scope")))
+		h.redirectWithError(c, redirectURI, state, service.ErrAccessDenied("the end-user denied the authorization request"))
+		return
 	}
-	
-	if codeChallengeMethod != "" {
-		codeChallengeMethodPtr = &codeChallengeMethod
+
+	scopes := h.parseScopes(values.Get("scope"))
+	details, err := model.ParseAuthorizationDetails(values.Get("authorization_details"))
+	if err != nil {
+		h.redirectWithError(c, redirectURI, state, service.ErrInvalidAuthorizationDetails("authorization_details must be a JSON array of objects"))
+		return
+	}
+	if err := h.oauthService.GrantConsent(c.Request.Context(), session.UserID, clientID, scopes, details); err != nil {
+		h.redirectWithError(c, redirectURI, state, service.AsOAuthError(err))
+		return
+	}
+
+	// 同意已记录，去掉prompt=consent后继续原授权流程
+	values = withoutPrompt(values, "consent")
+	values.Del("decision")
+	h.authorize(c, values)
+}
+
+// authorize 执行授权流程：校验请求、检查登录会话与用户同意，最后签发授权码
+func (h *OAuthHandler) authorize(c *gin.Context, values url.Values) {
+	// 使用PAR时先取回推送的授权请求参数，此时redirect_uri尚未验证，错误直接展示给用户
+	if values.Get("request_uri") != "" {
+		resolved, oauthErr := h.resolveRequestURI(c, values)
+		if oauthErr != nil {
+			c.JSON(oauthErr.StatusCode, oauthErr)
+			return
+		}
+		values = resolved
+	}
+
+	clientID := values.Get("client_id")
+	redirectURI := values.Get("redirect_uri")
+	state := values.Get("state")
+
+	// 先验证client_id与redirect_uri，失败时直接向用户展示错误，不得重定向
+	if _, err := h.oauthService.ValidateAuthorizationRequest(c.Request.Context(), clientID, redirectURI); err != nil {
+		oauthErr := service.AsOAuthError(err)
+		c.JSON(oauthErr.StatusCode, oauthErr)
+		return
+	}
+
+	// 重定向URI已验证，此后的错误均通过重定向返回给客户端
+	req, oauthErr := h.parseAuthorizationRequest(values)
+	if oauthErr != nil {
+		h.redirectWithError(c, redirectURI, state, oauthErr)
+		return
 	}
 
 	// 调用服务层处理授权请求
-	authCode, err := h.oauthService.HandleAuthorizationRequest(
-		c.Request.Context(),
-		clientID,
-		userID,
-		redirectURI,
-		scopes,
-		codeChallengePtr,
-		codeChallengeMethodPtr,
-	)
-	
+	session := h.currentSession(c)
+	authCode, err := h.oauthService.HandleAuthorizationRequest(c.Request.Context(), req, session)
 	if err != nil {
-		c.Redirect(http.StatusFound, redirectURI+"?error=invalid_request&state="+state)
+		oauthErr := service.AsOAuthError(err)
+		// prompt=none时不得与用户交互，直接将错误返回给客户端
+		if !req.HasPrompt("none") {
+			switch oauthErr.Code {
+			case service.ErrCodeLoginRequired, service.ErrCodeAccountSelectionRequired:
+				h.redirectToLogin(c, values, req)
+				return
+			case service.ErrCodeInteractionRequired:
+				h.redirectToSecondFactor(c, values, session)
+				return
+			case service.ErrCodeConsentRequired:
+				c.Redirect(http.StatusFound, appendQuery(consentPageURL(), values))
+				return
+			}
+		}
+		h.redirectWithError(c, redirectURI, state, oauthErr)
 		return
 	}
 
 	// 重定向回客户端，携带授权码
-	redirectURL := redirectURI + "?code=" + authCode.Code
+	params := url.Values{}
+	params.Set("code", authCode.Code)
 	if state != "" {
-		redirectURL += "&state=" + state
+		params.Set("state", state)
 	}
 	
-	c.Redirect(http.StatusFound, redirectURL)
+	c.Redirect(http.StatusFound, appendQuery(redirectURI, params))
+}
+
+// parseAuthorizationRequest 解析授权请求参数
+func (h *OAuthHandler) parseAuthorizationRequest(values url.Values) (*service.AuthorizationRequest, *service.OAuthError) {
+	req := &service.AuthorizationRequest{
+		ClientID:     values.Get("client_id"),
+		RedirectURI:  values.Get("redirect_uri"),
+		ResponseType: values.Get("response_type"),
+		Scopes:       h.parseScopes(values.Get("scope")),
+		State:        values.Get("state"),
+		Nonce:        values.Get("nonce"),
+		Prompt:       strings.Fields(values.Get("prompt")),
+		LoginHint:    values.Get("login_hint"),
+		IDTokenHint:  values.Get("id_token_hint"),
+	}
+
+	if req.ResponseType == "" {
+		return nil, service.ErrInvalidRequest("missing response_type")
+	}
+
+	// 验证response_type是否为code
+	if req.ResponseType != "code" {
+		return nil, service.ErrUnsupportedResponseType("only response_type=code is supported")
+	}
+
+	if codeChallenge := values.Get("code_challenge"); codeChallenge != "" {
+		req.CodeChallenge = &codeChallenge
+	}
+	if codeChallengeMethod := values.Get("code_challenge_method"); codeChallengeMethod != "" {
+		req.CodeChallengeMethod = &codeChallengeMethod
+	}
+
+	if rawMaxAge := values.Get("max_age"); rawMaxAge != "" {
+		maxAge, err := strconv.Atoi(rawMaxAge)
+		if err != nil || maxAge < 0 {
+			return nil, service.ErrInvalidRequest("max_age must be a non-negative integer")
+		}
+		req.MaxAge = &maxAge
+	}
+
+	details, err := model.ParseAuthorizationDetails(values.Get("authorization_details"))
+	if err != nil {
+		return nil, service.ErrInvalidAuthorizationDetails("authorization_details must be a JSON array of objects")
+	}
+	req.AuthorizationDetails = details
+
+	return req, nil
+}
+
+// resolveRequestURI 根据request_uri取回推送的授权请求参数 (RFC 9126 §4)
+// 推送的参数取代查询参数中除client_id外的全部参数
+func (h *OAuthHandler) resolveRequestURI(c *gin.Context, values url.Values) (url.Values, *service.OAuthError) {
+	clientID := values.Get("client_id")
+	if clientID == "" {
+		return nil, service.ErrInvalidRequest("missing client_id")
+	}
+
+	parameters, err := h.oauthService.ResolveRequestURI(c.Request.Context(), clientID, values.Get("request_uri"))
+	if err != nil {
+		return nil, service.AsOAuthError(err)
+	}
+
+	resolved, err := url.ParseQuery(parameters)
+	if err != nil {
+		return nil, service.ErrServerError("failed to load pushed authorization request")
+	}
+	resolved.Set("client_id", clientID)
+	return resolved, nil
+}
+
+// PushedAuthorizationRequestHandler 处理推送授权请求 (RFC 9126)
+// 客户端认证后提交完整的授权请求参数，换取在授权端点使用的request_uri
+func (h *OAuthHandler) PushedAuthorizationRequestHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	// 解析并验证客户端凭据
+	clientID, clientSecret, ok := parseClientCredentials(c)
+	if !ok {
+		writeTokenError(c, service.ErrInvalidClient("malformed Basic authorization header"))
+		return
+	}
+	if clientID == "" {
+		writeTokenError(c, service.ErrInvalidClient("missing client credentials"))
+		return
+	}
+	client, err := h.oauthService.ValidateClient(c.Request.Context(), clientID, clientSecret, "")
+	if err != nil {
+		writeTokenError(c, service.AsOAuthError(err))
+		return
+	}
+
+	// 复制授权请求参数，去掉客户端凭据
+	values := url.Values{}
+	for key, vals := range c.Request.PostForm {
+		values[key] = append([]string(nil), vals...)
+	}
+	values.Del("client_secret")
+	if formClientID := values.Get("client_id"); formClientID != "" && formClientID != client.ClientID {
+		c.JSON(http.StatusBadRequest, service.ErrInvalidRequest("client_id does not match the authenticated client"))
+		return
+	}
+	values.Set("client_id", client.ClientID)
+	if values.Get("request_uri") != "" {
+		c.JSON(http.StatusBadRequest, service.ErrInvalidRequest("request_uri must not be used at the pushed authorization request endpoint"))
+		return
+	}
+
+	// 与授权端点执行相同的校验，尽早向客户端报告错误
+	if _, err := h.oauthService.ValidateAuthorizationRequest(c.Request.Context(), client.ClientID, values.Get("redirect_uri")); err != nil {
+		oauthErr := service.AsOAuthError(err)
+		c.JSON(oauthErr.StatusCode, oauthErr)
+		return
+	}
+	req, oauthErr := h.parseAuthorizationRequest(values)
+	if oauthErr != nil {
+		c.JSON(oauthErr.StatusCode, oauthErr)
+		return
+	}
+
+	response, err := h.oauthService.PushAuthorizationRequest(c.Request.Context(), client, req, values.Encode())
+	if err != nil {
+		oauthErr := service.AsOAuthError(err)
+		c.JSON(oauthErr.StatusCode, oauthErr)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// redirectToLogin 将用户引导至登录页面，登录完成后由登录页跳转回return_to
+func (h *OAuthHandler) redirectToLogin(c *gin.Context, values url.Values, req *service.AuthorizationRequest) {
+	// 回跳时去掉已满足的交互要求，避免循环重定向
+	returnValues := withoutPrompt(values, "login", "select_account")
+	returnValues.Del("id_token_hint")
+	// max_age=0等同于prompt=login (OIDC Core §3.1.2.1)，重新登录后的会话也会超出该时限
+	if returnValues.Get("max_age") == "0" {
+		returnValues.Del("max_age")
+	}
+	returnTo := util.IssuerFromContext(c.Request.Context()) + "/oauth/authorize?" + returnValues.Encode()
+
+	params := url.Values{}
+	params.Set("return_to", returnTo)
+	if req.LoginHint != "" {
+		params.Set("login_hint", req.LoginHint)
+	}
+	if req.HasPrompt("select_account") {
+		params.Set("prompt", "select_account")
+	}
+
+	c.Redirect(http.StatusFound, appendQuery(loginPageURL(), params))
+}
+
+// redirectToSecondFactor 会话尚未完成两步验证时创建挑战，引导用户在登录页输入验证码后回到授权请求
+func (h *OAuthHandler) redirectToSecondFactor(c *gin.Context, values url.Values, session *model.Session) {
+	challenge, err := h.mfaService.BeginChallenge(c.Request.Context(), session.UserID, session.AuthMethods())
+	if err != nil || challenge == nil {
+		oauthErr := service.ErrServerError("failed to start second factor authentication")
+		c.JSON(oauthErr.StatusCode, oauthErr)
+		return
+	}
+
+	returnTo := util.IssuerFromContext(c.Request.Context()) + "/oauth/authorize?" + values.Encode()
+	redirectToMFA(c, challenge, returnTo)
+}
+
+// currentSession 获取当前浏览器的有效登录会话
+func (h *OAuthHandler) currentSession(c *gin.Context) *model.Session {
+	if h.sessionService == nil {
+		return nil
+	}
+	session, _ := h.sessionService.GetActiveSession(c.Request.Context(), sessionIDFromCookie(c))
+	return session
+}
+
+// withoutPrompt 复制请求参数并从prompt中移除指定值
+func withoutPrompt(values url.Values, drop ...string) url.Values {
+	copied := url.Values{}
+	for key, vals := range values {
+		copied[key] = append([]string(nil), vals...)
+	}
+
+	var kept []string
+	for _, prompt := range strings.Fields(values.Get("prompt")) {
+		keep := true
+		for _, d := range drop {
+			if prompt == d {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, prompt)
+		}
+	}
+
+	if len(kept) == 0 {
+		copied.Del("prompt")
+	} else {
+		copied.Set("prompt", strings.Join(kept, " "))
+	}
+	return copied
+}
+
+// loginPageURL 获取登录页面地址
+func loginPageURL() string {
+	if pageURL := os.Getenv("LOGIN_PAGE_URL"); pageURL != "" {
+		return pageURL
+	}
+	return "http://localhost:3000/login"
+}
+
+// consentPageURL 获取同意页面地址
+func consentPageURL() string {
+	if pageURL := os.Getenv("CONSENT_PAGE_URL"); pageURL != "" {
+		return pageURL
+	}
+	return "http://localhost:3000/consent"
 }
 
 // TokenHandler 处理令牌请求
 func (h *OAuthHandler) TokenHandler(c *gin.Context) {
+	// 令牌响应与错误响应均不得被缓存 (RFC 6749 §5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
 	// 解析客户端凭据
-	clientID, clientSecret := h.parseClientCredentials(c)
+	clientID, clientSecret, ok := parseClientCredentials(c)
+	if !ok {
+		writeTokenError(c, service.ErrInvalidClient("malformed Basic authorization header"))
+		return
+	}
 
 	// 获取表单参数
 	grantType := c.PostForm("grant_type")
 	code := c.PostForm("code")
+	switch grantType {
+	case "refresh_token":
+		code = c.PostForm("refresh_token")
+	case service.CIBAGrantType:
+		code = c.PostForm("auth_req_id")
+	}
 	redirectURI := c.PostForm("redirect_uri")
 	codeVerifier := c.PostForm("code_verifier")
+	authorizationDetails, err := model.ParseAuthorizationDetails(c.PostForm("authorization_details"))
+	if err != nil {
+		writeTokenError(c, service.ErrInvalidAuthorizationDetails("authorization_details must be a JSON array of objects"))
+		return
+	}
 
 	// 验证必需参数
 	if grantType == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing grant_type"})
+		writeTokenError(c, service.ErrInvalidRequest("missing grant_type"))
 		return
 	}
 
 	// 验证客户端凭据
-	if clientID == "" || clientSecret == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+	if clientID == "" {
+		writeTokenError(c, service.ErrInvalidClient("missing client credentials"))
 		return
 	}
 
@@ -178,10 +541,11 @@ func (h *OAuthHandler) TokenHandler(c *gin.Context) {
 		clientSecret,
 		redirectURI,
 		&codeVerifier,
+		authorizationDetails,
 	)
 	
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeTokenError(c, service.AsOAuthError(err))
 		return
 	}
 
@@ -189,25 +553,120 @@ func (h *OAuthHandler) TokenHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, tokenResponse)
 }
 
+// IntrospectionHandler 处理令牌内省请求 (RFC 7662)
+func (h *OAuthHandler) IntrospectionHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	// 解析客户端凭据
+	clientID, clientSecret, ok := parseClientCredentials(c)
+	if !ok {
+		writeTokenError(c, service.ErrInvalidClient("malformed Basic authorization header"))
+		return
+	}
+	if clientID == "" {
+		writeTokenError(c, service.ErrInvalidClient("missing client credentials"))
+		return
+	}
+
+	response, err := h.oauthService.IntrospectToken(c.Request.Context(), c.PostForm("token"), clientID, clientSecret)
+	if err != nil {
+		writeTokenError(c, service.AsOAuthError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // parseClientCredentials 解析客户端凭据
-func (h *OAuthHandler) parseClientCredentials(c *gin.Context) (clientID, clientSecret string) {
+// ok为false表示Basic认证头格式错误
+func parseClientCredentials(c *gin.Context) (clientID, clientSecret string, ok bool) {
 	// 首先尝试从Authorization头解析
 	authHeader := c.GetHeader("Authorization")
-	if strings.HasPrefix(authHeader, "Basic ") {
-		// TODO: 解析Basic认证头
-		// 这里应该实现Base64解码逻辑
+	if len(authHeader) > 6 && strings.EqualFold(authHeader[:6], "Basic ") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(authHeader[6:]))
+		if err != nil {
+			return "", "", false
+		}
+		rawID, rawSecret, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return "", "", false
+		}
+		// 凭据在Base64编码前经过form-urlencoded编码 (RFC 6749 §2.3.1)
+		if clientID, err = url.QueryUnescape(rawID); err != nil {
+			return "", "", false
+		}
+		if clientSecret, err = url.QueryUnescape(rawSecret); err != nil {
+			return "", "", false
+		}
+		return clientID, clientSecret, true
 	}
 
 	// 如果Authorization头中没有凭据，则从表单参数中获取
-	if clientID == "" {
-		clientID = c.PostForm("client_id")
+	clientID = c.PostForm("client_id")
+	clientSecret = c.PostForm("client_secret")
+
+	return clientID, clientSecret, true
+}
+
+// writeTokenError 写入令牌端点错误响应
+// invalid_client错误返回401并携带WWW-Authenticate质询 (RFC 6749 §5.2)
+func writeTokenError(c *gin.Context, oauthErr *service.OAuthError) {
+	if oauthErr.Code == service.ErrCodeInvalidClient {
+		c.Header("WWW-Authenticate", oauthErr.WWWAuthenticate("Basic", challengeRealm(c)))
+		c.JSON(http.StatusUnauthorized, oauthErr)
+		return
 	}
-	
-	if clientSecret == "" {
-		clientSecret = c.PostForm("client_secret")
+	c.JSON(oauthErr.StatusCode, oauthErr)
+}
+
+// writeBearerError 写入受保护资源的错误响应，附带Bearer质询 (RFC 6750 §3)
+func (h *OAuthHandler) writeBearerError(c *gin.Context, oauthErr *service.OAuthError) {
+	c.Header("WWW-Authenticate", oauthErr.WWWAuthenticate("Bearer", challengeRealm(c)))
+	if oauthErr.Code == "" {
+		c.Status(oauthErr.StatusCode)
+		return
+	}
+	c.JSON(oauthErr.StatusCode, oauthErr)
+}
+
+// redirectWithError 将授权错误通过已验证的重定向URI返回给客户端
+func (h *OAuthHandler) redirectWithError(c *gin.Context, redirectURI, state string, oauthErr *service.OAuthError) {
+	params := url.Values{}
+	params.Set("error", oauthErr.Code)
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+	if oauthErr.URI != "" {
+		params.Set("error_uri", oauthErr.URI)
+	}
+	if state != "" {
+		params.Set("state", state)
 	}
+	c.Redirect(http.StatusFound, appendQuery(redirectURI, params))
+}
 
-	return clientID, clientSecret
+// appendQuery 在保留重定向URI原有查询参数的前提下追加参数
+func appendQuery(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// challengeRealm 获取WWW-Authenticate质询中的realm参数
+func challengeRealm(c *gin.Context) string {
+	if value, exists := c.Get("realm"); exists {
+		if realm, ok := value.(*model.Realm); ok && realm != nil {
+			return realm.Name
+		}
+	}
+	return "oauth"
 }
 
 // parseScopes 解析scopes字符串
@@ -0,0 +1,519 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/handler"
+	"github.com/Full-finger/OIDC/internal/middleware"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试使用的issuer、登录页、同意页与客户端回调地址
+const (
+	testIssuer      = "http://id.test"
+	testLoginPage   = "http://login.test/login"
+	testConsentPage = "http://login.test/consent"
+	testRedirectURI = "https://app.example.com/callback"
+)
+
+// oauthDeps 单个realm的OAuth处理器及其内存依赖
+type oauthDeps struct {
+	handler  *handler.OAuthHandler
+	sessions service.SessionService
+	repo     repository.SessionRepository
+}
+
+// newOAuthDeps 按生产环境的方式为realm组装OAuth处理器，注册客户端app（密钥为app-secret）
+// 构造参数变化时只需修改此处
+func newOAuthDeps(t *testing.T, realm *model.Realm) *oauthDeps {
+	t.Helper()
+	ctx := context.Background()
+	jwtUtil, err := util.NewEphemeralJWTUtil()
+	if err != nil {
+		t.Fatalf("create jwt util: %v", err)
+	}
+	secretHash, err := bcrypt.GenerateFromPassword([]byte("app-secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash secret: %v", err)
+	}
+	clients := repository.NewClientRepository(realm.ID)
+	if err := clients.Create(ctx, &model.Client{ClientID: "app", Name: "App", SecretHash: string(secretHash), RedirectURI: testRedirectURI, Scopes: "openid profile"}); err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	scopeService := service.NewScopeService(repository.NewScopeRepository(realm.ID))
+	if err := scopeService.SeedScopes(ctx, nil); err != nil {
+		t.Fatalf("seed scopes: %v", err)
+	}
+
+	rbacService := service.NewRBACService(repository.NewRoleRepository(realm.ID), repository.NewGroupRepository(realm.ID), repository.NewUserAssignmentRepository())
+	detailsService := service.NewAuthorizationDetailsService()
+	mfaService := service.NewMFAService(repository.NewTOTPCredentialRepository(), repository.NewRecoveryCodeRepository(), repository.NewMFAChallengeRepository(), repository.NewWebAuthnCredentialRepository(), repository.NewUserRepository(nil), nil, realm)
+	oauthService := service.NewOAuthService(jwtUtil, clients, repository.NewAuthorizationCodeRepository(), repository.NewRefreshTokenRepository(), repository.NewConsentRepository(), repository.NewPushedAuthorizationRequestRepository(), scopeService, detailsService, rbacService, nil, mfaService, repository.NewUserRepository(nil), nil, nil, realm)
+	sessionRepo := repository.NewSessionRepository(realm.ID)
+	sessionService := service.NewSessionService(sessionRepo, nil)
+	return &oauthDeps{
+		handler:  handler.NewOAuthHandler(oauthService, sessionService, scopeService, detailsService, mfaService),
+		sessions: sessionService,
+		repo:     sessionRepo,
+	}
+}
+
+// newTokenRouter 创建注册了令牌端点和用户信息端点的路由，realm为anime
+func newTokenRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	t.Setenv("ISSUER_URL", testIssuer)
+	t.Setenv("OAUTH_ERROR_URI_BASE", "")
+	gin.SetMode(gin.TestMode)
+
+	realm := &model.Realm{ID: 2, Name: "anime"}
+	deps := newOAuthDeps(t, realm)
+	router := gin.New()
+	router.Use(middleware.IssuerMiddleware())
+	group := router.Group(realm.PathPrefix(), middleware.RealmMiddleware(realm))
+	group.POST("/oauth/token", deps.handler.TokenHandler)
+	group.GET("/oauth/userinfo", deps.handler.UserInfoHandler)
+	return router
+}
+
+// decodeOAuthError 解析OAuth错误响应体
+func decodeOAuthError(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error body %q: %v", w.Body.String(), err)
+	}
+	return body
+}
+
+func TestTokenEndpointInvalidClientReturnsBasicChallenge(t *testing.T) {
+	router := newTokenRouter(t)
+
+	tests := []struct {
+		name      string
+		form      url.Values
+		basicAuth string
+	}{
+		{"missing credentials", url.Values{"grant_type": {"authorization_code"}, "code": {"x"}}, ""},
+		{"wrong secret", url.Values{"grant_type": {"authorization_code"}, "code": {"x"}, "client_id": {"app"}, "client_secret": {"wrong"}}, ""},
+		{"unknown client via basic", url.Values{"grant_type": {"authorization_code"}, "code": {"x"}}, "Basic dW5rbm93bjpzZWNyZXQ="},
+		{"malformed basic header", url.Values{"grant_type": {"authorization_code"}, "code": {"x"}}, "Basic not-base64!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/realms/anime/oauth/token", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.basicAuth != "" {
+				req.Header.Set("Authorization", tt.basicAuth)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401: %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != `Basic realm="anime"` {
+				t.Errorf("WWW-Authenticate = %q, want Basic challenge for realm anime", got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+			body := decodeOAuthError(t, w)
+			if body["error"] != service.ErrCodeInvalidClient || body["error_description"] == "" {
+				t.Errorf("body = %v, want invalid_client with a description", body)
+			}
+		})
+	}
+}
+
+func TestTokenEndpointGrantErrorsHaveNoChallenge(t *testing.T) {
+	router := newTokenRouter(t)
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		wantCode string
+	}{
+		{"missing grant_type", url.Values{"client_id": {"app"}, "client_secret": {"app-secret"}}, service.ErrCodeInvalidRequest},
+		{"unsupported grant_type", url.Values{"grant_type": {"password"}, "client_id": {"app"}, "client_secret": {"app-secret"}}, service.ErrCodeUnsupportedGrantType},
+		{"missing code", url.Values{"grant_type": {"authorization_code"}, "redirect_uri": {testRedirectURI}, "client_id": {"app"}, "client_secret": {"app-secret"}}, service.ErrCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/realms/anime/oauth/token", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != "" {
+				t.Errorf("WWW-Authenticate = %q, want none for %s", got, tt.wantCode)
+			}
+			if body := decodeOAuthError(t, w); body["error"] != tt.wantCode {
+				t.Errorf("error = %q, want %q", body["error"], tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUserInfoReturnsBearerChallenges(t *testing.T) {
+	router := newTokenRouter(t)
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantChallenge string
+		wantError     string
+	}{
+		{"missing token", "", http.StatusUnauthorized, `Bearer realm="anime"`, ""},
+		{"invalid token", "Bearer not-a-jwt", http.StatusUnauthorized, `Bearer realm="anime", error="invalid_token"`, service.ErrCodeInvalidToken},
+		{"wrong scheme", "Basic YXBwOmFwcC1zZWNyZXQ=", http.StatusBadRequest, `Bearer realm="anime", error="invalid_request"`, service.ErrCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/realms/anime/oauth/userinfo", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, tt.wantChallenge) {
+				t.Errorf("WWW-Authenticate = %q, want prefix %q", got, tt.wantChallenge)
+			}
+			if tt.wantError == "" {
+				if w.Body.Len() != 0 {
+					t.Errorf("body = %q, want empty body without error code", w.Body.String())
+				}
+				return
+			}
+			if body := decodeOAuthError(t, w); body["error"] != tt.wantError {
+				t.Errorf("error = %q, want %q", body["error"], tt.wantError)
+			}
+		})
+	}
+}
+
+// authorizeFixture 授权端点测试使用的单个realm的路由与内存依赖
+type authorizeFixture struct {
+	router   *gin.Engine
+	prefix   string
+	cookie   string
+	sessions service.SessionService
+	repo     repository.SessionRepository
+}
+
+// newAuthorizeFixture 创建只注册了默认realm授权端点的路由
+func newAuthorizeFixture(t *testing.T) *authorizeFixture {
+	t.Helper()
+	return mountAuthorize(t, newAuthorizeRouter(t), &model.Realm{ID: 1, Name: "default", IsDefault: true})
+}
+
+// newAuthorizeRouter 创建挂载了issuer中间件的空路由
+func newAuthorizeRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	t.Setenv("ISSUER_URL", testIssuer)
+	t.Setenv("LOGIN_PAGE_URL", testLoginPage)
+	t.Setenv("CONSENT_PAGE_URL", testConsentPage)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.IssuerMiddleware())
+	return router
+}
+
+// mountAuthorize 按生产环境的方式为realm挂载授权端点，每个realm拥有独立的客户端与会话存储
+func mountAuthorize(t *testing.T, router *gin.Engine, realm *model.Realm) *authorizeFixture {
+	t.Helper()
+	deps := newOAuthDeps(t, realm)
+	router.Group(realm.PathPrefix(), middleware.RealmMiddleware(realm)).GET("/oauth/authorize", deps.handler.AuthorizeHandler)
+
+	cookie := "oidc_session"
+	if !realm.IsDefault {
+		cookie += "_" + realm.Name
+	}
+	return &authorizeFixture{router: router, prefix: realm.PathPrefix(), cookie: cookie, sessions: deps.sessions, repo: deps.repo}
+}
+
+// login 创建登录会话，authAge为距离认证完成的时间
+func (f *authorizeFixture) login(t *testing.T, authAge time.Duration) *model.Session {
+	t.Helper()
+	session, err := f.sessions.CreateSession(context.Background(), 1, []string{model.AMRPassword})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if authAge > 0 {
+		session.AuthTime = session.AuthTime.Add(-authAge)
+		if err := f.repo.Create(context.Background(), session); err != nil {
+			t.Fatalf("update session: %v", err)
+		}
+	}
+	return session
+}
+
+// authorize 携带该realm的会话Cookie请求授权端点，返回跳转地址
+func (f *authorizeFixture) authorize(t *testing.T, target string, session *model.Session) *url.URL {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if session != nil {
+		req.AddCookie(&http.Cookie{Name: f.cookie, Value: session.ID})
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected a redirect from %s, got %d: %s", target, w.Code, w.Body.String())
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("parse location: %v", err)
+	}
+	return location
+}
+
+// authorizeURL 构造该realm的授权请求地址
+func (f *authorizeFixture) authorizeURL(extra url.Values) string {
+	values := url.Values{
+		"client_id":     {"app"},
+		"redirect_uri":  {testRedirectURI},
+		"response_type": {"code"},
+		"scope":         {"openid"},
+		"state":         {"xyz"},
+	}
+	for key, vals := range extra {
+		values[key] = vals
+	}
+	return f.prefix + "/oauth/authorize?" + values.Encode()
+}
+
+// requireLoginRedirect 断言跳转到登录页，返回登录后回跳的授权请求地址
+func requireLoginRedirect(t *testing.T, location *url.URL) string {
+	t.Helper()
+	if location.Scheme+"://"+location.Host+location.Path != testLoginPage {
+		t.Fatalf("expected a redirect to the login page, got %s", location)
+	}
+	returnTo := location.Query().Get("return_to")
+	if !strings.HasPrefix(returnTo, testIssuer+"/") {
+		t.Fatalf("unexpected return_to %q", returnTo)
+	}
+	return strings.TrimPrefix(returnTo, testIssuer)
+}
+
+// requireClientRedirect 断言跳转回客户端，返回查询参数
+func requireClientRedirect(t *testing.T, location *url.URL) url.Values {
+	t.Helper()
+	if location.Scheme+"://"+location.Host+location.Path != testRedirectURI {
+		t.Fatalf("expected a redirect to the client, got %s", location)
+	}
+	if location.Query().Get("state") != "xyz" {
+		t.Fatalf("state should be returned to the client, got %s", location)
+	}
+	return location.Query()
+}
+
+func TestAuthorizeWithoutSessionRedirectsToLogin(t *testing.T) {
+	f := newAuthorizeFixture(t)
+
+	returnTo := requireLoginRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"login_hint": {"alice"}}), nil))
+	returned, _ := url.Parse(returnTo)
+	if returned.Query().Get("client_id") != "app" || returned.Query().Get("state") != "xyz" {
+		t.Fatalf("return_to should keep the authorization request, got %s", returnTo)
+	}
+
+	session := f.login(t, 0)
+	query := requireClientRedirect(t, f.authorize(t, returnTo, session))
+	if query.Get("code") == "" {
+		t.Fatalf("expected an authorization code after login, got %v", query)
+	}
+}
+
+func TestAuthorizePromptNone(t *testing.T) {
+	f := newAuthorizeFixture(t)
+
+	query := requireClientRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"prompt": {"none"}}), nil))
+	if query.Get("error") != service.ErrCodeLoginRequired {
+		t.Fatalf("prompt=none without a session should return login_required, got %v", query)
+	}
+
+	// 需要同意时prompt=none同样不跳转到同意页
+	session := f.login(t, 0)
+	query = requireClientRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"prompt": {"none"}, "scope": {"openid profile"}}), session))
+	if query.Get("error") != service.ErrCodeConsentRequired {
+		t.Fatalf("prompt=none without consent should return consent_required, got %v", query)
+	}
+
+	query = requireClientRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"prompt": {"none"}}), session))
+	if query.Get("code") == "" {
+		t.Fatalf("prompt=none with a session should issue a code, got %v", query)
+	}
+
+	query = requireClientRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"prompt": {"none login"}}), session))
+	if query.Get("error") != service.ErrCodeInvalidRequest {
+		t.Fatalf("prompt=none combined with other values should be rejected, got %v", query)
+	}
+}
+
+func TestAuthorizePromptLoginDoesNotLoop(t *testing.T) {
+	f := newAuthorizeFixture(t)
+	session := f.login(t, 0)
+
+	// 已登录时prompt=login仍要求重新登录
+	returnTo := requireLoginRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"prompt": {"login"}, "id_token_hint": {"stale"}}), session))
+	returned, _ := url.Parse(returnTo)
+	if _, ok := returned.Query()["prompt"]; ok {
+		t.Fatalf("prompt=login should be stripped from return_to, got %s", returnTo)
+	}
+	if _, ok := returned.Query()["id_token_hint"]; ok {
+		t.Fatalf("id_token_hint should be stripped from return_to, got %s", returnTo)
+	}
+
+	// 重新登录后回到授权请求直接签发授权码，不会再次跳转到登录页
+	query := requireClientRedirect(t, f.authorize(t, returnTo, f.login(t, 0)))
+	if query.Get("code") == "" {
+		t.Fatalf("expected an authorization code after re-authentication, got %v", query)
+	}
+}
+
+func TestAuthorizePromptLoginKeepsConsent(t *testing.T) {
+	f := newAuthorizeFixture(t)
+	session := f.login(t, 0)
+
+	returnTo := requireLoginRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"prompt": {"login consent"}}), session))
+	returned, _ := url.Parse(returnTo)
+	if returned.Query().Get("prompt") != "consent" {
+		t.Fatalf("only prompt=login should be stripped, got %s", returnTo)
+	}
+
+	location := f.authorize(t, returnTo, f.login(t, 0))
+	if location.Scheme+"://"+location.Host+location.Path != testConsentPage {
+		t.Fatalf("prompt=consent should continue to the consent page, got %s", location)
+	}
+}
+
+func TestAuthorizePromptSelectAccount(t *testing.T) {
+	f := newAuthorizeFixture(t)
+	session := f.login(t, 0)
+
+	location := f.authorize(t, f.authorizeURL(url.Values{"prompt": {"select_account"}}), session)
+	returnTo := requireLoginRedirect(t, location)
+	if location.Query().Get("prompt") != "select_account" {
+		t.Fatalf("the login page should be asked to show the account chooser, got %s", location)
+	}
+	returned, _ := url.Parse(returnTo)
+	if _, ok := returned.Query()["prompt"]; ok {
+		t.Fatalf("prompt=select_account should be stripped from return_to, got %s", returnTo)
+	}
+
+	query := requireClientRedirect(t, f.authorize(t, returnTo, session))
+	if query.Get("code") == "" {
+		t.Fatalf("expected an authorization code after selecting the account, got %v", query)
+	}
+}
+
+func TestAuthorizePromptConsent(t *testing.T) {
+	f := newAuthorizeFixture(t)
+	session := f.login(t, 0)
+
+	// openid无需同意，但prompt=consent仍要求用户确认
+	location := f.authorize(t, f.authorizeURL(url.Values{"prompt": {"consent"}}), session)
+	if location.Scheme+"://"+location.Host+location.Path != testConsentPage {
+		t.Fatalf("prompt=consent should redirect to the consent page, got %s", location)
+	}
+	if location.Query().Get("client_id") != "app" || location.Query().Get("prompt") != "consent" {
+		t.Fatalf("the consent page should receive the authorization request, got %s", location)
+	}
+}
+
+func TestAuthorizeMaxAge(t *testing.T) {
+	f := newAuthorizeFixture(t)
+	session := f.login(t, 10*time.Minute)
+
+	// 会话认证时间在max_age内
+	query := requireClientRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"max_age": {"3600"}}), session))
+	if query.Get("code") == "" {
+		t.Fatalf("a session within max_age should issue a code, got %v", query)
+	}
+
+	// 超出max_age时要求重新登录，回跳地址保留max_age
+	returnTo := requireLoginRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"max_age": {"60"}}), session))
+	returned, _ := url.Parse(returnTo)
+	if returned.Query().Get("max_age") != "60" {
+		t.Fatalf("max_age should be kept in return_to, got %s", returnTo)
+	}
+	query = requireClientRedirect(t, f.authorize(t, returnTo, f.login(t, 0)))
+	if query.Get("code") == "" {
+		t.Fatalf("a fresh session should satisfy max_age, got %v", query)
+	}
+
+	// prompt=none时不跳转到登录页
+	query = requireClientRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"max_age": {"60"}, "prompt": {"none"}}), session))
+	if query.Get("error") != service.ErrCodeLoginRequired {
+		t.Fatalf("prompt=none with a stale session should return login_required, got %v", query)
+	}
+
+	query = requireClientRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"max_age": {"-1"}}), session))
+	if query.Get("error") != service.ErrCodeInvalidRequest {
+		t.Fatalf("negative max_age should be rejected, got %v", query)
+	}
+}
+
+func TestAuthorizeMaxAgeZeroDoesNotLoop(t *testing.T) {
+	f := newAuthorizeFixture(t)
+	session := f.login(t, 0)
+
+	// max_age=0等同于prompt=login
+	returnTo := requireLoginRedirect(t, f.authorize(t, f.authorizeURL(url.Values{"max_age": {"0"}}), session))
+	returned, _ := url.Parse(returnTo)
+	if _, ok := returned.Query()["max_age"]; ok {
+		t.Fatalf("max_age=0 should be stripped from return_to, got %s", returnTo)
+	}
+
+	query := requireClientRedirect(t, f.authorize(t, returnTo, f.login(t, 0)))
+	if query.Get("code") == "" {
+		t.Fatalf("expected an authorization code after re-authentication, got %v", query)
+	}
+}
+
+func TestAuthorizeRejectsSessionFromAnotherRealm(t *testing.T) {
+	router := newAuthorizeRouter(t)
+	realmA := mountAuthorize(t, router, &model.Realm{ID: 1, Name: "default", IsDefault: true})
+	realmB := mountAuthorize(t, router, &model.Realm{ID: 2, Name: "anime"})
+	session := realmA.login(t, 0)
+
+	// realm A的会话ID无论放在哪个Cookie中，realm B都不承认
+	requireLoginRedirect(t, realmB.authorize(t, realmB.authorizeURL(nil), session))
+
+	req := httptest.NewRequest(http.MethodGet, realmB.authorizeURL(nil), nil)
+	req.AddCookie(&http.Cookie{Name: realmA.cookie, Value: session.ID})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("parse location: %v", err)
+	}
+	requireLoginRedirect(t, location)
+
+	// realm A自身仍可使用该会话
+	query := requireClientRedirect(t, realmA.authorize(t, realmA.authorizeURL(nil), session))
+	if query.Get("code") == "" {
+		t.Fatalf("expected an authorization code in the session's own realm, got %v", query)
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordResetHandler 忘记密码、修改密码及密码策略处理器
+type PasswordResetHandler struct {
+	passwordResetService  service.PasswordResetService
+	passwordPolicyService service.PasswordPolicyService
+	userService           service.UserService
+	sessionService        service.SessionService
+}
+
+// NewPasswordResetHandler 创建PasswordResetHandler实例
+func NewPasswordResetHandler(passwordResetService service.PasswordResetService, passwordPolicyService service.PasswordPolicyService, userService service.UserService, sessionService service.SessionService) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		passwordResetService:  passwordResetService,
+		passwordPolicyService: passwordPolicyService,
+		userService:           userService,
+		sessionService:        sessionService,
+	}
+}
+
+// ForgotPasswordRequest 申请重置密码请求结构体
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest 重置密码请求结构体
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ChangePasswordRequest 修改密码请求结构体
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ForgotPasswordHandler 申请重置密码，无论邮箱是否注册都返回相同的响应
+func (h *PasswordResetHandler) ForgotPasswordHandler(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 只有已注册的邮箱才可能出错，返回错误会暴露邮箱是否存在，因此仅记录日志
+	if err := h.passwordResetService.RequestReset(c.Request.Context(), req.Email); err != nil {
+		fmt.Printf("警告: 无法发送重置密码邮件: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "如果该邮箱已注册，重置密码邮件已发送，请检查邮箱",
+	})
+}
+
+// ResetPasswordHandler 使用邮件中的令牌设置新密码，成功后用户需在所有设备上重新登录
+func (h *PasswordResetHandler) ResetPasswordHandler(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordResetService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrInvalidPasswordResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "重置链接无效或已过期"})
+			return
+		}
+		if writePasswordPolicyError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重置密码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "密码已重置，请使用新密码登录",
+	})
+}
+
+// ChangePasswordHandler 已登录用户校验当前密码后修改密码
+func (h *PasswordResetHandler) ChangePasswordHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.ChangePassword(session.UserID, req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrWrongPassword) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if writePasswordPolicyError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "修改密码失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "密码已修改",
+	})
+}
+
+// GetPolicyHandler 获取realm的密码策略，供注册和修改密码页面展示要求
+func (h *PasswordResetHandler) GetPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, h.passwordPolicyService.Policy())
+}
+
+// writePasswordPolicyError 密码不符合密码策略时返回400及错误码，返回值表示是否已写入响应
+func writePasswordPolicyError(c *gin.Context, err error) bool {
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":      policyErr.Message,
+		"error_code": policyErr.Code,
+	})
+	return true
+}
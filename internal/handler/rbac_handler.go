@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	
+	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+// RBACHandler 角色与用户组管理处理器
+type RBACHandler struct {
+	rbacService service.RBACService
+	userService service.UserService
+}
+
+// NewRBACHandler 创建RBACHandler实例
+func NewRBACHandler(rbacService service.RBACService, userService service.UserService) *RBACHandler {
+	return &RBACHandler{
+		rbacService: rbacService,
+		userService: userService,
+	}
+}
+
+// RoleRequest 创建角色请求结构体
+type RoleRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// GroupRequest 创建或更新用户组请求结构体
+type GroupRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Roles       []string `json:"roles"`
+}
+
+// UserRolesRequest 设置用户角色请求结构体
+type UserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// UserGroupsRequest 设置用户所属用户组请求结构体
+type UserGroupsRequest struct {
+	Groups []string `json:"groups"`
+}
+
+// toGroup 将请求转换为用户组实体
+func (r *GroupRequest) toGroup() *model.Group {
+	return &model.Group{
+		Name:        r.Name,
+		Description: r.Description,
+		Roles:       strings.Join(r.Roles, " "),
+	}
+}
+
+// ListRolesHandler 列出所有角色
+func (h *RBACHandler) ListRolesHandler(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list roles"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, roles)
+}
+
+// CreateRoleHandler 创建角色
+func (h *RBACHandler) CreateRoleHandler(c *gin.Context) {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	role := &model.Role{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := h.rbacService.CreateRole(c.Request.Context(), role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, role)
+}
+
+// DeleteRoleHandler 删除角色
+func (h *RBACHandler) DeleteRoleHandler(c *gin.Context) {
+	if err := h.rbacService.DeleteRole(c.Request.Context(), c.Param("name")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "role deleted successfully"})
+}
+
+// ListGroupsHandler 列出所有用户组
+func (h *RBACHandler) ListGroupsHandler(c *gin.Context) {
+	groups, err := h.rbacService.ListGroups(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list groups"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, groups)
+}
+
+// GetGroupHandler 获取用户组详情
+func (h *RBACHandler) GetGroupHandler(c *gin.Context) {
+	group, err := h.rbacService.GetGroup(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get group"})
+		return
+	}
+	
+	if group == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, group)
+}
+
+// CreateGroupHandler 创建用户组
+func (h *RBACHandler) CreateGroupHandler(c *gin.Context) {
+	var req GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	group := req.toGroup()
+	if err := h.rbacService.CreateGroup(c.Request.Context(), group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, group)
+}
+
+// UpdateGroupHandler 更新用户组
+func (h *RBACHandler) UpdateGroupHandler(c *gin.Context) {
+	var req GroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	group, err := h.rbacService.UpdateGroup(c.Request.Context(), c.Param("name"), req.toGroup())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if group == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, group)
+}
+
+// DeleteGroupHandler 删除用户组
+func (h *RBACHandler) DeleteGroupHandler(c *gin.Context) {
+	if err := h.rbacService.DeleteGroup(c.Request.Context(), c.Param("name")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "group deleted successfully"})
+}
+
+// GetUserAccessHandler 获取用户的角色、用户组及有效角色
+func (h *RBACHandler) GetUserAccessHandler(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+	
+	access, err := h.rbacService.GetUserAccess(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user access"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, access)
+}
+
+// SetUserRolesHandler 设置用户直接分配的角色
+func (h *RBACHandler) SetUserRolesHandler(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+	
+	var req UserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := h.rbacService.SetUserRoles(c.Request.Context(), userID, req.Roles); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	h.GetUserAccessHandler(c)
+}
+
+// SetUserGroupsHandler 设置用户所属的用户组
+func (h *RBACHandler) SetUserGroupsHandler(c *gin.Context) {
+	userID, ok := h.parseUserID(c)
+	if !ok {
+		return
+	}
+	
+	var req UserGroupsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if err := h.rbacService.SetUserGroups(c.Request.Context(), userID, req.Groups); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	h.GetUserAccessHandler(c)
+}
+
+// parseUserID 解析路径中的用户ID并确认用户存在，失败时写入错误响应
+func (h *RBACHandler) parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return 0, false
+	}
+	
+	if _, err := h.userService.GetUserByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return 0, false
+	}
+	
+	return uint(id), true
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// RealmHandler realm信息处理器
+type RealmHandler struct {
+	realm *model.Realm
+}
+
+// NewRealmHandler 创建RealmHandler实例
+func NewRealmHandler(realm *model.Realm) *RealmHandler {
+	return &RealmHandler{
+		realm: realm,
+	}
+}
+
+// GetRealmInfoHandler 获取realm的公开信息，供登录页渲染品牌样式
+func (h *RealmHandler) GetRealmInfoHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"realm":        h.realm.Name,
+		"display_name": h.realm.DisplayName,
+		"issuer":       util.IssuerFromContext(c.Request.Context()),
+		"branding":     h.realm.Branding,
+	})
+}
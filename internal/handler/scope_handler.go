@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+	
+	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+// ScopeHandler scope注册表管理处理器
+type ScopeHandler struct {
+	scopeService service.ScopeService
+}
+
+// NewScopeHandler 创建ScopeHandler实例
+func NewScopeHandler(scopeService service.ScopeService) *ScopeHandler {
+	return &ScopeHandler{
+		scopeService: scopeService,
+	}
+}
+
+// ScopeRequest 创建或更新scope请求结构体
+type ScopeRequest struct {
+	Name            string `json:"name"`
+	Description     string `json:"description" binding:"required"`
+	Claims          string `json:"claims"`
+	RequiresConsent *bool  `json:"requires_consent"`
+}
+
+// toScope 将请求转换为scope实体，未指定requires_consent时默认需要同意
+func (r *ScopeRequest) toScope() *model.Scope {
+	requiresConsent := true
+	if r.RequiresConsent != nil {
+		requiresConsent = *r.RequiresConsent
+	}
+	return &model.Scope{
+		Name:            r.Name,
+		Description:     r.Description,
+		Claims:          r.Claims,
+		RequiresConsent: requiresConsent,
+	}
+}
+
+// ListScopesHandler 列出所有scope
+func (h *ScopeHandler) ListScopesHandler(c *gin.Context) {
+	scopes, err := h.scopeService.ListScopes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list scopes"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, scopes)
+}
+
+// GetScopeHandler 获取scope详情
+func (h *ScopeHandler) GetScopeHandler(c *gin.Context) {
+	scope, err := h.scopeService.GetScope(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get scope"})
+		return
+	}
+	
+	if scope == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "scope not found"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, scope)
+}
+
+// CreateScopeHandler 注册新scope
+func (h *ScopeHandler) CreateScopeHandler(c *gin.Context) {
+	var req ScopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	scope := req.toScope()
+	if err := h.scopeService.CreateScope(c.Request.Context(), scope); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, scope)
+}
+
+// UpdateScopeHandler 更新scope
+func (h *ScopeHandler) UpdateScopeHandler(c *gin.Context) {
+	var req ScopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	scope, err := h.scopeService.UpdateScope(c.Request.Context(), c.Param("name"), req.toScope())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	if scope == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "scope not found"})
+		return
+	}
+	
+	c.JSON(http.StatusOK, scope)
+}
+
+// DeleteScopeHandler 删除scope
+func (h *ScopeHandler) DeleteScopeHandler(c *gin.Context) {
+	if err := h.scopeService.DeleteScope(c.Request.Context(), c.Param("name")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "scope deleted successfully"})
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// sessionCookiePrefix 登录会话Cookie名前缀，每个realm使用独立的Cookie
+const sessionCookiePrefix = "oidc_session"
+
+// federationCookiePrefix 上游登录浏览器绑定Cookie名前缀，每个realm使用独立的Cookie
+const federationCookiePrefix = "oidc_federation"
+
+// sessionCookieName 获取当前realm的会话Cookie名
+func sessionCookieName(c *gin.Context) string {
+	return realmCookieName(c, sessionCookiePrefix)
+}
+
+// realmCookieName 在Cookie名前缀后附加非默认realm的名称
+func realmCookieName(c *gin.Context, prefix string) string {
+	if value, exists := c.Get("realm"); exists {
+		if realm, ok := value.(*model.Realm); ok && realm != nil && !realm.IsDefault {
+			return prefix + "_" + realm.Name
+		}
+	}
+	return prefix
+}
+
+// sessionIDFromCookie 从Cookie中读取会话ID
+func sessionIDFromCookie(c *gin.Context) string {
+	sessionID, err := c.Cookie(sessionCookieName(c))
+	if err != nil {
+		return ""
+	}
+	return sessionID
+}
+
+// requireSession 获取当前登录会话，未登录时写入401响应
+func requireSession(c *gin.Context, sessionService service.SessionService) (*model.Session, bool) {
+	session, err := sessionService.GetActiveSession(c.Request.Context(), sessionIDFromCookie(c))
+	if err != nil || session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录"})
+		return nil, false
+	}
+	return session, true
+}
+
+// currentSessionUserID 获取当前登录会话的用户ID，未登录时返回0
+func currentSessionUserID(c *gin.Context, sessionService service.SessionService) uint {
+	session, err := sessionService.GetActiveSession(c.Request.Context(), sessionIDFromCookie(c))
+	if err != nil || session == nil {
+		return 0
+	}
+	return session.UserID
+}
+
+// setSessionCookie 写入会话Cookie
+// SameSite=Lax阻止跨站POST携带会话，作为同意提交等表单请求的CSRF防护
+func setSessionCookie(c *gin.Context, session *model.Session) {
+	maxAge := int(time.Until(session.ExpiresAt).Seconds())
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookieName(c), session.ID, maxAge, "/", "", isSecureIssuer(c), true)
+}
+
+// clearSessionCookie 清除会话Cookie
+func clearSessionCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookieName(c), "", -1, "/", "", isSecureIssuer(c), true)
+}
+
+// setFederationCookie 写入上游登录的浏览器绑定值，浏览器关闭后失效
+// 上游回调是顶级跳转，SameSite=Lax的Cookie会随回调请求发送
+func setFederationCookie(c *gin.Context, binding string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(realmCookieName(c, federationCookiePrefix), binding, 0, "/", "", isSecureIssuer(c), true)
+}
+
+// takeFederationCookie 读取并清除上游登录的浏览器绑定值
+func takeFederationCookie(c *gin.Context) string {
+	binding, err := c.Cookie(realmCookieName(c, federationCookiePrefix))
+	if err != nil {
+		return ""
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(realmCookieName(c, federationCookiePrefix), "", -1, "/", "", isSecureIssuer(c), true)
+	return binding
+}
+
+// isSecureIssuer 判断issuer是否使用HTTPS，决定Cookie是否设置Secure
+func isSecureIssuer(c *gin.Context) bool {
+	return strings.HasPrefix(util.IssuerFromContext(c.Request.Context()), "https://")
+}
@@ -1,27 +1,40 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
 	"github.com/Full-finger/OIDC/internal/service"
 )
 
 // UserHandler 用户处理器
 type UserHandler struct {
-	userService service.UserService
+	userService       service.UserService
+	sessionService    service.SessionService
+	mfaService        service.MFAService
+	loginGuardService service.LoginGuardService
+	profileService    service.ProfileService
 }
 
 // NewUserHandler 创建UserHandler实例
-func NewUserHandler(userService service.UserService) *UserHandler {
+func NewUserHandler(userService service.UserService, sessionService service.SessionService, mfaService service.MFAService, loginGuardService service.LoginGuardService, profileService service.ProfileService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:       userService,
+		sessionService:    sessionService,
+		mfaService:        mfaService,
+		loginGuardService: loginGuardService,
+		profileService:    profileService,
 	}
 }
 
 // RegisterRequest 用户注册请求结构体
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=30"`
-	Password string `json:"password" binding:"required,min=6,max=128"`
+	Password string `json:"password" binding:"required"` // 长度等要求由realm的密码策略校验
 	Email    string `json:"email" binding:"required,email"`
 	Nickname string `json:"nickname" binding:"required,max=50"`
 }
@@ -47,6 +60,9 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 	// 调用服务层注册用户
 	if err := h.userService.RegisterUser(req.Username, req.Password, req.Email, req.Nickname); err != nil {
+		if writePasswordPolicyError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -56,7 +72,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 	})
 }
 
-// Login 用户登录接口
+// Login 用户登录接口，启用了两步验证的用户返回mfa_token，需通过第二步验证接口完成登录
 func (h *UserHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -65,27 +81,82 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	// 调用服务层认证用户
-	user, err := h.userService.AuthenticateUser(req.Username, req.Password)
+	user, ok := authenticatePassword(c, h.userService, h.loginGuardService, req.Username, req.Password)
+	if !ok {
+		return
+	}
+
+	amr := []string{model.AMRPassword}
+	challenge, ok := startSecondFactor(c, h.mfaService, user.ID, amr)
+	if !ok {
+		return
+	}
+	if challenge != nil {
+		writeMFARequired(c, h.mfaService, challenge)
+		return
+	}
+
+	writeLoginResponse(c, h.userService, h.sessionService, user, amr, nil)
+}
+
+// authenticatePassword 校验用户名和密码，失败次数过多时拒绝尝试；用户名不存在和密码错误返回相同的响应。
+// 认证失败时写入响应并返回false
+func authenticatePassword(c *gin.Context, userService service.UserService, loginGuardService service.LoginGuardService, username, password string) (*model.User, bool) {
+	ctx := c.Request.Context()
+	if retryAfter, err := loginGuardService.Check(ctx, username, c.ClientIP()); err != nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "登录失败次数过多，请稍后再试",
+			"retry_after": int(math.Ceil(retryAfter.Seconds())),
+		})
+		return nil, false
+	}
+
+	user, err := userService.AuthenticateUser(username, password)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			_ = loginGuardService.RecordFailure(ctx, username, c.ClientIP())
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	_ = loginGuardService.RecordSuccess(ctx, username)
+	return user, true
+}
+
+// writeLoginResponse 签发令牌、创建登录会话并返回登录结果，amr为用户完成的认证方式，extra中的字段会合并到响应中
+func writeLoginResponse(c *gin.Context, userService service.UserService, sessionService service.SessionService, user *model.User, amr []string, extra gin.H) {
+	// 按用户的角色确定令牌的scopes
+	scopes, err := userService.FirstPartyScopes(c.Request.Context(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "令牌生成失败"})
 		return
 	}
 
 	// 生成访问令牌
-	accessToken, err := h.userService.GenerateAccessToken(user.ID, []string{"openid", "profile", "email"})
+	accessToken, err := userService.GenerateAccessToken(c.Request.Context(), user.ID, scopes, amr)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "令牌生成失败"})
 		return
 	}
 
 	// 生成刷新令牌
-	refreshToken, err := h.userService.GenerateRefreshToken(user.ID, []string{"openid", "profile", "email"})
+	refreshToken, err := userService.GenerateRefreshToken(user.ID, scopes)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "刷新令牌生成失败"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	// 创建登录会话，供授权端点识别已登录用户
+	session, err := sessionService.CreateSession(c.Request.Context(), user.ID, amr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "会话创建失败"})
+		return
+	}
+	setSessionCookie(c, session)
+
+	response := gin.H{
 		"message": "登录成功",
 		"user": map[string]interface{}{
 			"id":       user.ID,
@@ -96,7 +167,24 @@ func (h *UserHandler) Login(c *gin.Context) {
 		"access_token":  accessToken,
 		"refresh_token": refreshToken,
 		"token_type":    "Bearer",
-		"expires_in":    3600, // 1小时
+		"expires_in":    int(userService.AccessTokenLifetime().Seconds()),
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout 用户登出接口，结束当前登录会话
+func (h *UserHandler) Logout(c *gin.Context) {
+	if err := h.sessionService.DeleteSession(c.Request.Context(), sessionIDFromCookie(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登出失败"})
+		return
+	}
+	clearSessionCookie(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已登出",
 	})
 }
 
@@ -119,14 +207,168 @@ func (h *UserHandler) ResendVerificationEmail(c *gin.Context) {
 	})
 }
 
-// GetProfile 获取用户资料接口
+// UpdateProfileRequest 更新用户资料请求结构体，未提供的字段保持不变
+type UpdateProfileRequest struct {
+	Nickname *string `json:"nickname"`
+	Bio      *string `json:"bio"`
+}
+
+// GetProfile 获取访问令牌对应用户的资料
 func (h *UserHandler) GetProfile(c *gin.Context) {
-	// TODO: 实现获取用户资料逻辑
-	c.JSON(http.StatusOK, gin.H{"message": "GetProfile endpoint"})
+	userID, ok := tokenUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		writeProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
 }
 
-// UpdateProfile 更新用户资料接口
+// UpdateProfile 更新访问令牌对应用户的昵称和个人简介
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
-	// TODO: 实现更新用户资料逻辑
-	c.JSON(http.StatusOK, gin.H{"message": "UpdateProfile endpoint"})
-}
\ No newline at end of file
+	userID, ok := tokenUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.profileService.UpdateProfile(c.Request.Context(), userID, service.ProfileUpdate{
+		Nickname: req.Nickname,
+		Bio:      req.Bio,
+	})
+	if err != nil {
+		writeProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// UploadAvatarHandler 上传头像，multipart表单字段名为avatar
+func (h *UserHandler) UploadAvatarHandler(c *gin.Context) {
+	userID, ok := tokenUserID(c)
+	if !ok {
+		return
+	}
+
+	// 限制请求体大小，为multipart边界和表单头预留空间
+	maxBytes := service.AvatarMaxBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64<<10)
+	file, _, err := c.Request.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrAvatarTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请通过avatar字段上传图片"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取头像失败"})
+		return
+	}
+
+	profile, err := h.profileService.UploadAvatar(c.Request.Context(), userID, data)
+	if err != nil {
+		writeProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteAvatarHandler 删除上传的头像，恢复默认头像
+func (h *UserHandler) DeleteAvatarHandler(c *gin.Context) {
+	userID, ok := tokenUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.DeleteAvatar(c.Request.Context(), userID)
+	if err != nil {
+		writeProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// IdenticonHandler 返回用户的默认identicon头像
+func (h *UserHandler) IdenticonHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	image, err := h.profileService.Identicon(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成头像失败"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// UnlockUserHandler 管理员解除用户因多次登录失败而被临时锁定的状态
+func (h *UserHandler) UnlockUserHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	if err := h.loginGuardService.Unlock(c.Request.Context(), uint(userID)); err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解除锁定失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "用户已解除锁定",
+	})
+}
+
+// tokenUserID 获取访问令牌subject中的用户ID，解析失败时返回401
+func tokenUserID(c *gin.Context) (uint, bool) {
+	subject, _ := c.Get("user_id")
+	subjectStr, _ := subject.(string)
+	userID, err := parseSubjectUserID(subjectStr)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// writeProfileError 将用户资料服务的错误转换为HTTP响应
+func writeProfileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+	case errors.Is(err, service.ErrInvalidNickname), errors.Is(err, service.ErrBioTooLong), errors.Is(err, service.ErrInvalidAvatar):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAvatarTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户资料失败"})
+	}
+}
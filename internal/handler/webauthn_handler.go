@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// WebAuthnHandler 通行密钥处理器，负责无用户名登录、第二步验证和通行密钥管理
+type WebAuthnHandler struct {
+	webAuthnService service.WebAuthnService
+	mfaService      service.MFAService
+	userService     service.UserService
+	sessionService  service.SessionService
+}
+
+// NewWebAuthnHandler 创建WebAuthnHandler实例
+func NewWebAuthnHandler(webAuthnService service.WebAuthnService, mfaService service.MFAService, userService service.UserService, sessionService service.SessionService) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		webAuthnService: webAuthnService,
+		mfaService:      mfaService,
+		userService:     userService,
+		sessionService:  sessionService,
+	}
+}
+
+// WebAuthnRegistrationRequest 注册通行密钥请求结构体
+type WebAuthnRegistrationRequest struct {
+	Name       string                             `json:"name"`
+	Credential service.WebAuthnCredentialResponse `json:"credential"`
+}
+
+// WebAuthnLoginRequest 通行密钥登录请求结构体
+type WebAuthnLoginRequest struct {
+	Credential service.WebAuthnCredentialResponse `json:"credential"`
+}
+
+// WebAuthnMFARequest 以通行密钥完成第二步验证的请求结构体，获取仪式参数时不需要credential
+type WebAuthnMFARequest struct {
+	MFAToken   string                             `json:"mfa_token" binding:"required"`
+	Credential service.WebAuthnCredentialResponse `json:"credential"`
+}
+
+// WebAuthnRenameRequest 重命名通行密钥请求结构体
+type WebAuthnRenameRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// BeginLoginHandler 获取无用户名登录的仪式参数
+func (h *WebAuthnHandler) BeginLoginHandler(c *gin.Context) {
+	options, err := h.webAuthnService.BeginLogin(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通行密钥登录初始化失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishLoginHandler 校验通行密钥断言并登录，返回与用户登录接口相同的令牌
+func (h *WebAuthnHandler) FinishLoginHandler(c *gin.Context) {
+	var req WebAuthnLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.webAuthnService.FinishLogin(c.Request.Context(), &req.Credential)
+	if err != nil {
+		writeWebAuthnAuthenticationError(c, err)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(result.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	writeLoginResponse(c, h.userService, h.sessionService, user, result.AMR, nil)
+}
+
+// BeginSecondFactorHandler 获取以通行密钥完成第二步验证的仪式参数
+func (h *WebAuthnHandler) BeginSecondFactorHandler(c *gin.Context) {
+	var req WebAuthnMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	challenge, err := h.mfaService.GetChallenge(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	options, err := h.webAuthnService.BeginSecondFactor(c.Request.Context(), challenge.UserID)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishSecondFactorHandler 校验通行密钥断言完成第二步验证并登录
+func (h *WebAuthnHandler) FinishSecondFactorHandler(c *gin.Context) {
+	var req WebAuthnMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	challenge, err := h.mfaService.GetChallenge(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	if _, err := h.webAuthnService.FinishSecondFactor(c.Request.Context(), challenge.UserID, &req.Credential); err != nil {
+		writeWebAuthnAuthenticationError(c, err)
+		return
+	}
+
+	result, err := h.mfaService.FinishChallenge(c.Request.Context(), challenge, model.AMRHWK)
+	if err != nil {
+		writeMFAError(c, err)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(result.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	// 授权流程中补充第二步验证时，以新会话替换未完成两步验证的旧会话
+	_ = h.sessionService.DeleteSession(c.Request.Context(), sessionIDFromCookie(c))
+
+	writeLoginResponse(c, h.userService, h.sessionService, user, result.AMR, nil)
+}
+
+// ListCredentialsHandler 获取当前用户的通行密钥
+func (h *WebAuthnHandler) ListCredentialsHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	credentials, err := h.webAuthnService.ListCredentials(c.Request.Context(), session.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取通行密钥失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"credentials": credentials,
+	})
+}
+
+// BeginRegistrationHandler 获取注册通行密钥的仪式参数
+// 注册要求用户在MFA_ENROLL_MAX_AUTH_AGE_SECONDS（默认300秒）内重新登录过
+func (h *WebAuthnHandler) BeginRegistrationHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+	if time.Since(session.AuthTime) > mfaEnrollMaxAuthAge() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "注册通行密钥前请重新登录", "reauthentication_required": true})
+		return
+	}
+
+	options, err := h.webAuthnService.BeginRegistration(c.Request.Context(), session.UserID)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishRegistrationHandler 校验认证器的注册响应并保存通行密钥
+func (h *WebAuthnHandler) FinishRegistrationHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	var req WebAuthnRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := h.webAuthnService.FinishRegistration(c.Request.Context(), session.UserID, req.Name, &req.Credential)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, credential)
+}
+
+// RenameCredentialHandler 重命名当前用户的通行密钥
+func (h *WebAuthnHandler) RenameCredentialHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的通行密钥ID"})
+		return
+	}
+
+	var req WebAuthnRenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := h.webAuthnService.RenameCredential(c.Request.Context(), session.UserID, uint(credentialID), req.Name)
+	if err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+// DeleteCredentialHandler 删除当前用户的通行密钥
+func (h *WebAuthnHandler) DeleteCredentialHandler(c *gin.Context) {
+	session, ok := requireSession(c, h.sessionService)
+	if !ok {
+		return
+	}
+
+	credentialID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的通行密钥ID"})
+		return
+	}
+
+	if err := h.webAuthnService.DeleteCredential(c.Request.Context(), session.UserID, uint(credentialID)); err != nil {
+		writeWebAuthnError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "通行密钥已删除",
+	})
+}
+
+// writeWebAuthnError 将通行密钥管理错误转换为HTTP响应
+func writeWebAuthnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWebAuthnCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "通行密钥不存在"})
+	case errors.Is(err, service.ErrWebAuthnCredentialExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "通行密钥已注册"})
+	case errors.Is(err, service.ErrInvalidWebAuthnCeremony):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通行密钥验证已过期，请重试"})
+	case errors.Is(err, service.ErrInvalidWebAuthnResponse), errors.Is(err, service.ErrWebAuthnSignCount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "通行密钥验证失败", "error_description": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通行密钥操作失败"})
+	}
+}
+
+// writeWebAuthnAuthenticationError 通行密钥认证失败时统一返回401，不区分凭据是否存在
+func writeWebAuthnAuthenticationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWebAuthnCredentialNotFound),
+		errors.Is(err, service.ErrInvalidWebAuthnCeremony),
+		errors.Is(err, service.ErrInvalidWebAuthnResponse),
+		errors.Is(err, service.ErrWebAuthnSignCount):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "通行密钥验证失败"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通行密钥验证失败"})
+	}
+}
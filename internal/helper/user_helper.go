@@ -15,6 +15,9 @@ type UserHelper interface {
 	// CheckPassword 验证密码
 	CheckPassword(hashedPassword, password string) bool
 
-	// GenerateAvatarURL 生成头像URL
-	GenerateAvatarURL(userID uint) string
+	// GenerateAvatarURL 生成未上传头像时使用的默认identicon头像URL，issuer为用户所属realm的issuer
+	GenerateAvatarURL(issuer string, userID uint) string
+
+	// GenerateIdenticon 生成用户的默认identicon头像（PNG）
+	GenerateIdenticon(userID uint) ([]byte, error)
 }
\ No newline at end of file
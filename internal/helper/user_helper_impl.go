@@ -1,17 +1,21 @@
 package helper
 
 import (
+	"fmt"
 	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/util"
 )
 
 // userHelper 用户助手实现
 type userHelper struct {
-	// 可以添加依赖项
+	passwordHasher util.PasswordHasher
 }
 
-// NewUserHelper 创建UserHelper实例
-func NewUserHelper() UserHelper {
-	return &userHelper{}
+// NewUserHelper 创建UserHelper实例，密码哈希与用户服务使用同一个PasswordHasher
+func NewUserHelper(passwordHasher util.PasswordHasher) UserHelper {
+	return &userHelper{
+		passwordHasher: passwordHasher,
+	}
 }
 
 // ValidateUser 验证用户数据
@@ -22,18 +26,24 @@ func (h *userHelper) ValidateUser(user *model.User) error {
 
 // HashPassword 对密码进行哈希处理
 func (h *userHelper) HashPassword(password string) (string, error) {
-	// TODO: 实现密码哈希逻辑
-	return "", nil
+	return h.passwordHasher.Hash(password)
 }
 
 // CheckPassword 验证密码
 func (h *userHelper) CheckPassword(hashedPassword, password string) bool {
-	// TODO: 实现密码验证逻辑
-	return false
+	ok, _ := h.passwordHasher.Verify(hashedPassword, password)
+	return ok
 }
 
-// GenerateAvatarURL 生成头像URL
-func (h *userHelper) GenerateAvatarURL(userID uint) string {
-	// TODO: 实现头像URL生成逻辑
-	return ""
+// identiconSize 默认头像的边长（像素）
+const identiconSize = 256
+
+// GenerateAvatarURL 生成默认identicon头像URL，由realm的 /avatars/identicon/:id 端点提供
+func (h *userHelper) GenerateAvatarURL(issuer string, userID uint) string {
+	return fmt.Sprintf("%s/avatars/identicon/%d", issuer, userID)
+}
+
+// GenerateIdenticon 生成用户的默认identicon头像
+func (h *userHelper) GenerateIdenticon(userID uint) ([]byte, error) {
+	return util.GenerateIdenticon(fmt.Sprintf("user:%d", userID), identiconSize)
 }
\ No newline at end of file
@@ -0,0 +1,14 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// AuditEventMapper 安全审计事件映射器接口，审计事件只追加，不提供更新和删除
+type AuditEventMapper interface {
+	// Insert 追加审计事件
+	Insert(event *model.AuditEvent) error
+
+	// Query 按条件查询审计事件，按时间倒序
+	Query(filter model.AuditEventFilter) ([]*model.AuditEvent, error)
+}
@@ -0,0 +1,52 @@
+package mapper
+
+import (
+	"gorm.io/gorm"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// auditEventMapper 安全审计事件映射器实现，所有查询都限定在所属realm内
+type auditEventMapper struct {
+	db      *gorm.DB
+	realmID uint
+}
+
+// NewAuditEventMapper 创建realm范围内的AuditEventMapper实例
+func NewAuditEventMapper(db *gorm.DB, realmID uint) AuditEventMapper {
+	return &auditEventMapper{db: db, realmID: realmID}
+}
+
+// Insert 追加审计事件
+func (m *auditEventMapper) Insert(event *model.AuditEvent) error {
+	event.RealmID = m.realmID
+	return m.db.Create(event).Error
+}
+
+// Query 按条件查询审计事件，按时间倒序
+func (m *auditEventMapper) Query(filter model.AuditEventFilter) ([]*model.AuditEvent, error) {
+	query := m.db.Where("realm_id = ?", m.realmID)
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ClientID != "" {
+		query = query.Where("client_id = ?", filter.ClientID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at <= ?", filter.Until)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var events []*model.AuditEvent
+	if err := query.Order("created_at DESC, id DESC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
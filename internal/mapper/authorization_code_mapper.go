@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// AuthorizationCodeMapper 授权码映射器接口
+type AuthorizationCodeMapper interface {
+	BaseMapper
+	
+	// GetByCode 根据授权码获取记录
+	GetByCode(code string) (*model.AuthorizationCode, error)
+	
+	// ConsumeByCode 根据授权码获取记录并删除，授权码只能被取出一次
+	ConsumeByCode(code string) (*model.AuthorizationCode, error)
+}
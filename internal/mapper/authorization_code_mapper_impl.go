@@ -0,0 +1,141 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+	
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// authorizationCodeMapper 授权码映射器实现
+type authorizationCodeMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu     sync.RWMutex
+	codes  map[uint]*model.AuthorizationCode
+	nextID uint
+}
+
+// NewAuthorizationCodeMapper 创建AuthorizationCodeMapper实例
+func NewAuthorizationCodeMapper() AuthorizationCodeMapper {
+	return &authorizationCodeMapper{
+		codes:  make(map[uint]*model.AuthorizationCode),
+		nextID: 1,
+	}
+}
+
+// Save 保存授权码
+func (m *authorizationCodeMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	authCode, ok := entity.(*model.AuthorizationCode)
+	if !ok {
+		return errors.New("invalid authorization code entity")
+	}
+	
+	for id, existing := range m.codes {
+		if existing.Code == authCode.Code && id != authCode.ID {
+			return errors.New("authorization code already exists")
+		}
+	}
+	
+	// 如果是新授权码，分配ID
+	if authCode.ID == 0 {
+		authCode.ID = m.nextID
+		m.nextID++
+		authCode.CreatedAt = time.Now()
+	}
+	
+	m.codes[authCode.ID] = authCode
+	
+	return nil
+}
+
+// DeleteByID 根据ID删除授权码
+func (m *authorizationCodeMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	codeID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid authorization code id")
+	}
+	
+	delete(m.codes, codeID)
+	return nil
+}
+
+// GetByID 根据ID获取授权码
+func (m *authorizationCodeMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	codeID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid authorization code id")
+	}
+	
+	authCode, exists := m.codes[codeID]
+	if !exists {
+		return nil, errors.New("authorization code not found")
+	}
+	
+	return authCode, nil
+}
+
+// GetAll 获取所有授权码
+func (m *authorizationCodeMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	codes := make([]interface{}, 0, len(m.codes))
+	for _, authCode := range m.codes {
+		codes = append(codes, authCode)
+	}
+	
+	return codes, nil
+}
+
+// Update 更新授权码
+func (m *authorizationCodeMapper) Update(entity interface{}) error {
+	authCode, ok := entity.(*model.AuthorizationCode)
+	if !ok {
+		return errors.New("invalid authorization code entity")
+	}
+	
+	if authCode.ID == 0 {
+		return errors.New("authorization code id is required")
+	}
+	
+	return m.Save(authCode)
+}
+
+// GetByCode 根据授权码获取记录
+func (m *authorizationCodeMapper) GetByCode(code string) (*model.AuthorizationCode, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	for _, authCode := range m.codes {
+		if authCode.Code == code {
+			return authCode, nil
+		}
+	}
+	
+	return nil, errors.New("authorization code not found")
+}
+
+// ConsumeByCode 根据授权码获取记录并删除，查找与删除在同一把锁内完成，并发兑换时只有一方能取到授权码
+func (m *authorizationCodeMapper) ConsumeByCode(code string) (*model.AuthorizationCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	for id, authCode := range m.codes {
+		if authCode.Code == code {
+			delete(m.codes, id)
+			return authCode, nil
+		}
+	}
+	
+	return nil, errors.New("authorization code not found")
+}
@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// BackchannelAuthRequestMapper CIBA认证请求映射器接口
+type BackchannelAuthRequestMapper interface {
+	BaseMapper
+
+	// GetByAuthReqID 根据auth_req_id获取认证请求
+	GetByAuthReqID(authReqID string) (*model.BackchannelAuthRequest, error)
+
+	// ConsumeByID 根据ID删除认证请求，返回是否确实删除了记录
+	ConsumeByID(id uint) (bool, error)
+}
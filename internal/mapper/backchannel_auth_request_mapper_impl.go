@@ -0,0 +1,139 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// backchannelAuthRequestMapper CIBA认证请求映射器实现
+type backchannelAuthRequestMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu       sync.RWMutex
+	requests map[uint]*model.BackchannelAuthRequest
+	nextID   uint
+}
+
+// NewBackchannelAuthRequestMapper 创建BackchannelAuthRequestMapper实例
+func NewBackchannelAuthRequestMapper() BackchannelAuthRequestMapper {
+	return &backchannelAuthRequestMapper{
+		requests: make(map[uint]*model.BackchannelAuthRequest),
+		nextID:   1,
+	}
+}
+
+// Save 保存CIBA认证请求
+func (m *backchannelAuthRequestMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	authReq, ok := entity.(*model.BackchannelAuthRequest)
+	if !ok {
+		return errors.New("invalid backchannel auth request entity")
+	}
+
+	for id, existing := range m.requests {
+		if existing.AuthReqID == authReq.AuthReqID && id != authReq.ID {
+			return errors.New("backchannel auth request already exists")
+		}
+	}
+
+	// 如果是新认证请求，分配ID
+	if authReq.ID == 0 {
+		authReq.ID = m.nextID
+		m.nextID++
+		authReq.CreatedAt = time.Now()
+	}
+
+	m.requests[authReq.ID] = authReq
+
+	return nil
+}
+
+// DeleteByID 根据ID删除CIBA认证请求
+func (m *backchannelAuthRequestMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reqID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid backchannel auth request id")
+	}
+
+	delete(m.requests, reqID)
+	return nil
+}
+
+// GetByID 根据ID获取CIBA认证请求
+func (m *backchannelAuthRequestMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reqID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid backchannel auth request id")
+	}
+
+	authReq, exists := m.requests[reqID]
+	if !exists {
+		return nil, errors.New("backchannel auth request not found")
+	}
+
+	return authReq, nil
+}
+
+// GetAll 获取所有CIBA认证请求
+func (m *backchannelAuthRequestMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	requests := make([]interface{}, 0, len(m.requests))
+	for _, authReq := range m.requests {
+		requests = append(requests, authReq)
+	}
+
+	return requests, nil
+}
+
+// Update 更新CIBA认证请求
+func (m *backchannelAuthRequestMapper) Update(entity interface{}) error {
+	authReq, ok := entity.(*model.BackchannelAuthRequest)
+	if !ok {
+		return errors.New("invalid backchannel auth request entity")
+	}
+
+	if authReq.ID == 0 {
+		return errors.New("backchannel auth request id is required")
+	}
+
+	return m.Save(authReq)
+}
+
+// GetByAuthReqID 根据auth_req_id获取认证请求
+func (m *backchannelAuthRequestMapper) GetByAuthReqID(authReqID string) (*model.BackchannelAuthRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, authReq := range m.requests {
+		if authReq.AuthReqID == authReqID {
+			return authReq, nil
+		}
+	}
+
+	return nil, errors.New("backchannel auth request not found")
+}
+
+// ConsumeByID 根据ID删除认证请求，返回是否确实删除了记录，同一请求被并发兑换时只有一方返回true
+func (m *backchannelAuthRequestMapper) ConsumeByID(id uint) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.requests[id]; !exists {
+		return false, nil
+	}
+
+	delete(m.requests, id)
+	return true, nil
+}
@@ -1,57 +1,147 @@
 package mapper
 
 import (
+	"errors"
+	"sync"
+	"time"
+
 	"github.com/Full-finger/OIDC/internal/model"
 )
 
 // bangumiMapper Bangumi映射器实现
 type bangumiMapper struct {
-	// 可以添加数据库连接等依赖
+	// 使用内存存储，每个realm持有独立实例
+	mu       sync.RWMutex
+	accounts map[uint]*model.BangumiAccount
+	nextID   uint
 }
 
 // NewBangumiMapper 创建BangumiMapper实例
 func NewBangumiMapper() BangumiMapper {
-	return &bangumiMapper{}
+	return &bangumiMapper{
+		accounts: make(map[uint]*model.BangumiAccount),
+		nextID:   1,
+	}
 }
 
-// Save 保存Bangumi账号绑定记录
+// Save 保存Bangumi账号绑定记录，一个用户只能绑定一个Bangumi账号，一个Bangumi账号也只能绑定一个用户
 func (m *bangumiMapper) Save(entity interface{}) error {
-	// TODO: 实现保存Bangumi账号绑定记录逻辑
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	account, ok := entity.(*model.BangumiAccount)
+	if !ok {
+		return errors.New("invalid bangumi account entity")
+	}
+
+	for id, existing := range m.accounts {
+		if id == account.ID {
+			continue
+		}
+		if existing.UserID == account.UserID {
+			return errors.New("user already has a bound bangumi account")
+		}
+		if existing.BangumiUserID == account.BangumiUserID {
+			return errors.New("bangumi account is already bound to another user")
+		}
+	}
+
+	// 如果是新记录，分配ID
+	if account.ID == 0 {
+		account.ID = m.nextID
+		m.nextID++
+		account.CreatedAt = time.Now()
+	}
+	account.UpdatedAt = time.Now()
+
+	m.accounts[account.ID] = account
+
 	return nil
 }
 
 // DeleteByID 根据ID删除Bangumi账号绑定记录
 func (m *bangumiMapper) DeleteByID(id interface{}) error {
-	// TODO: 实现根据ID删除Bangumi账号绑定记录逻辑
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	accountID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid bangumi account id")
+	}
+
+	delete(m.accounts, accountID)
 	return nil
 }
 
 // GetByID 根据ID获取Bangumi账号绑定记录
 func (m *bangumiMapper) GetByID(id interface{}) (interface{}, error) {
-	// TODO: 实现根据ID获取Bangumi账号绑定记录逻辑
-	return nil, nil
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accountID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid bangumi account id")
+	}
+
+	account, exists := m.accounts[accountID]
+	if !exists {
+		return nil, errors.New("bangumi account not found")
+	}
+
+	return account, nil
 }
 
 // GetAll 获取所有Bangumi账号绑定记录
 func (m *bangumiMapper) GetAll() ([]interface{}, error) {
-	// TODO: 实现获取所有Bangumi账号绑定记录逻辑
-	return nil, nil
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accounts := make([]interface{}, 0, len(m.accounts))
+	for _, account := range m.accounts {
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
 }
 
 // Update 更新Bangumi账号绑定记录
 func (m *bangumiMapper) Update(entity interface{}) error {
-	// TODO: 实现更新Bangumi账号绑定记录逻辑
-	return nil
+	account, ok := entity.(*model.BangumiAccount)
+	if !ok {
+		return errors.New("invalid bangumi account entity")
+	}
+
+	if account.ID == 0 {
+		return errors.New("bangumi account id is required")
+	}
+
+	return m.Save(account)
 }
 
-// GetByUserID 根据用户ID获取Bangumi账号绑定记录
+// GetByUserID 根据用户ID获取Bangumi账号绑定记录，未绑定时返回nil
 func (m *bangumiMapper) GetByUserID(userID uint) (*model.BangumiAccount, error) {
-	// TODO: 实现根据用户ID获取Bangumi账号绑定记录逻辑
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, account := range m.accounts {
+		if account.UserID == userID {
+			return account, nil
+		}
+	}
+
 	return nil, nil
 }
 
-// GetByBangumiUserID 根据Bangumi用户ID获取Bangumi账号绑定记录
+// GetByBangumiUserID 根据Bangumi用户ID获取Bangumi账号绑定记录，未绑定时返回nil
 func (m *bangumiMapper) GetByBangumiUserID(bangumiUserID uint) (*model.BangumiAccount, error) {
-	// TODO: 实现根据Bangumi用户ID获取Bangumi账号绑定记录逻辑
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, account := range m.accounts {
+		if account.BangumiUserID == bangumiUserID {
+			return account, nil
+		}
+	}
+
 	return nil, nil
-}
\ No newline at end of file
+}
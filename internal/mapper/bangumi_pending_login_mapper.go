@@ -0,0 +1,13 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// BangumiPendingLoginMapper 待完成的Bangumi登录映射器接口
+type BangumiPendingLoginMapper interface {
+	BaseMapper
+
+	// GetByToken 根据token获取待完成的Bangumi登录
+	GetByToken(token string) (*model.BangumiPendingLogin, error)
+}
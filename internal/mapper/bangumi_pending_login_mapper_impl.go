@@ -0,0 +1,126 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// bangumiPendingLoginMapper 待完成的Bangumi登录映射器实现
+type bangumiPendingLoginMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu     sync.RWMutex
+	logins map[uint]*model.BangumiPendingLogin
+	nextID uint
+}
+
+// NewBangumiPendingLoginMapper 创建BangumiPendingLoginMapper实例
+func NewBangumiPendingLoginMapper() BangumiPendingLoginMapper {
+	return &bangumiPendingLoginMapper{
+		logins: make(map[uint]*model.BangumiPendingLogin),
+		nextID: 1,
+	}
+}
+
+// Save 保存待完成的Bangumi登录
+func (m *bangumiPendingLoginMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pendingLogin, ok := entity.(*model.BangumiPendingLogin)
+	if !ok {
+		return errors.New("invalid bangumi pending login entity")
+	}
+
+	for id, existing := range m.logins {
+		if existing.Token == pendingLogin.Token && id != pendingLogin.ID {
+			return errors.New("bangumi pending login already exists")
+		}
+	}
+
+	// 如果是新请求，分配ID
+	if pendingLogin.ID == 0 {
+		pendingLogin.ID = m.nextID
+		m.nextID++
+		pendingLogin.CreatedAt = time.Now()
+	}
+
+	m.logins[pendingLogin.ID] = pendingLogin
+
+	return nil
+}
+
+// DeleteByID 根据ID删除待完成的Bangumi登录
+func (m *bangumiPendingLoginMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	loginID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid bangumi pending login id")
+	}
+
+	delete(m.logins, loginID)
+	return nil
+}
+
+// GetByID 根据ID获取待完成的Bangumi登录
+func (m *bangumiPendingLoginMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	loginID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid bangumi pending login id")
+	}
+
+	pendingLogin, exists := m.logins[loginID]
+	if !exists {
+		return nil, errors.New("bangumi pending login not found")
+	}
+
+	return pendingLogin, nil
+}
+
+// GetAll 获取所有待完成的Bangumi登录
+func (m *bangumiPendingLoginMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	logins := make([]interface{}, 0, len(m.logins))
+	for _, pendingLogin := range m.logins {
+		logins = append(logins, pendingLogin)
+	}
+
+	return logins, nil
+}
+
+// Update 更新待完成的Bangumi登录
+func (m *bangumiPendingLoginMapper) Update(entity interface{}) error {
+	pendingLogin, ok := entity.(*model.BangumiPendingLogin)
+	if !ok {
+		return errors.New("invalid bangumi pending login entity")
+	}
+
+	if pendingLogin.ID == 0 {
+		return errors.New("bangumi pending login id is required")
+	}
+
+	return m.Save(pendingLogin)
+}
+
+// GetByToken 根据token获取待完成的Bangumi登录
+func (m *bangumiPendingLoginMapper) GetByToken(token string) (*model.BangumiPendingLogin, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, pendingLogin := range m.logins {
+		if pendingLogin.Token == token {
+			return pendingLogin, nil
+		}
+	}
+
+	return nil, errors.New("bangumi pending login not found")
+}
@@ -0,0 +1,13 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ClientMapper OAuth客户端映射器接口
+type ClientMapper interface {
+	BaseMapper
+	
+	// GetByClientID 根据client_id获取客户端
+	GetByClientID(clientID string) (*model.Client, error)
+}
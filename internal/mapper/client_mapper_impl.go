@@ -0,0 +1,124 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// clientMapper OAuth客户端映射器实现
+type clientMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu      sync.RWMutex
+	clients map[uint]*model.Client
+	nextID  uint
+}
+
+// NewClientMapper 创建ClientMapper实例
+func NewClientMapper() ClientMapper {
+	return &clientMapper{
+		clients: make(map[uint]*model.Client),
+		nextID:  1,
+	}
+}
+
+// Save 保存客户端
+func (m *clientMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	client, ok := entity.(*model.Client)
+	if !ok {
+		return errors.New("invalid client entity")
+	}
+	
+	for id, existing := range m.clients {
+		if existing.ClientID == client.ClientID && id != client.ID {
+			return errors.New("client already exists")
+		}
+	}
+	
+	// 如果是新客户端，分配ID
+	if client.ID == 0 {
+		client.ID = m.nextID
+		m.nextID++
+	}
+	
+	m.clients[client.ID] = client
+	
+	return nil
+}
+
+// DeleteByID 根据ID删除客户端
+func (m *clientMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	clientID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid client id")
+	}
+	
+	delete(m.clients, clientID)
+	return nil
+}
+
+// GetByID 根据ID获取客户端
+func (m *clientMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	clientID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid client id")
+	}
+	
+	client, exists := m.clients[clientID]
+	if !exists {
+		return nil, errors.New("client not found")
+	}
+	
+	return client, nil
+}
+
+// GetAll 获取所有客户端
+func (m *clientMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	clients := make([]interface{}, 0, len(m.clients))
+	for _, client := range m.clients {
+		clients = append(clients, client)
+	}
+	
+	return clients, nil
+}
+
+// Update 更新客户端
+func (m *clientMapper) Update(entity interface{}) error {
+	client, ok := entity.(*model.Client)
+	if !ok {
+		return errors.New("invalid client entity")
+	}
+	
+	if client.ID == 0 {
+		return errors.New("client id is required")
+	}
+	
+	return m.Save(client)
+}
+
+// GetByClientID 根据client_id获取客户端
+func (m *clientMapper) GetByClientID(clientID string) (*model.Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	for _, client := range m.clients {
+		if client.ClientID == clientID {
+			return client, nil
+		}
+	}
+	
+	return nil, errors.New("client not found")
+}
@@ -0,0 +1,19 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ConsentMapper 授权同意记录映射器接口
+type ConsentMapper interface {
+	BaseMapper
+	
+	// GetByUserAndClient 获取用户对某个客户端的授权同意记录
+	GetByUserAndClient(userID uint, clientID string) (*model.Consent, error)
+	
+	// ListByUserID 获取用户的全部授权同意记录
+	ListByUserID(userID uint) ([]*model.Consent, error)
+	
+	// DeleteByUserID 删除用户的全部授权同意记录
+	DeleteByUserID(userID uint) error
+}
@@ -0,0 +1,149 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+	
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// consentMapper 授权同意记录映射器实现
+type consentMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu       sync.RWMutex
+	consents map[uint]*model.Consent
+	nextID   uint
+}
+
+// NewConsentMapper 创建ConsentMapper实例
+func NewConsentMapper() ConsentMapper {
+	return &consentMapper{
+		consents: make(map[uint]*model.Consent),
+		nextID:   1,
+	}
+}
+
+// Save 保存授权同意记录，同一用户与客户端只保留一条记录
+func (m *consentMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	consent, ok := entity.(*model.Consent)
+	if !ok {
+		return errors.New("invalid consent entity")
+	}
+	
+	for id, existing := range m.consents {
+		if existing.UserID == consent.UserID && existing.ClientID == consent.ClientID && id != consent.ID {
+			consent.ID = id
+			consent.CreatedAt = existing.CreatedAt
+			break
+		}
+	}
+	
+	// 如果是新记录，分配ID
+	if consent.ID == 0 {
+		consent.ID = m.nextID
+		m.nextID++
+		consent.CreatedAt = time.Now()
+	}
+	consent.UpdatedAt = time.Now()
+	
+	m.consents[consent.ID] = consent
+	
+	return nil
+}
+
+// DeleteByID 根据ID删除授权同意记录
+func (m *consentMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	consentID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid consent id")
+	}
+	
+	delete(m.consents, consentID)
+	return nil
+}
+
+// GetByID 根据ID获取授权同意记录
+func (m *consentMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	consentID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid consent id")
+	}
+	
+	consent, exists := m.consents[consentID]
+	if !exists {
+		return nil, errors.New("consent not found")
+	}
+	
+	return consent, nil
+}
+
+// GetAll 获取所有授权同意记录
+func (m *consentMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	consents := make([]interface{}, 0, len(m.consents))
+	for _, consent := range m.consents {
+		consents = append(consents, consent)
+	}
+	
+	return consents, nil
+}
+
+// Update 更新授权同意记录
+func (m *consentMapper) Update(entity interface{}) error {
+	return m.Save(entity)
+}
+
+// GetByUserAndClient 获取用户对某个客户端的授权同意记录
+func (m *consentMapper) GetByUserAndClient(userID uint, clientID string) (*model.Consent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	for _, consent := range m.consents {
+		if consent.UserID == userID && consent.ClientID == clientID {
+			return consent, nil
+		}
+	}
+	
+	return nil, errors.New("consent not found")
+}
+
+// ListByUserID 获取用户的全部授权同意记录
+func (m *consentMapper) ListByUserID(userID uint) ([]*model.Consent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	consents := make([]*model.Consent, 0)
+	for _, consent := range m.consents {
+		if consent.UserID == userID {
+			consents = append(consents, consent)
+		}
+	}
+	
+	return consents, nil
+}
+
+// DeleteByUserID 删除用户的全部授权同意记录
+func (m *consentMapper) DeleteByUserID(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	for id, consent := range m.consents {
+		if consent.UserID == userID {
+			delete(m.consents, id)
+		}
+	}
+	
+	return nil
+}
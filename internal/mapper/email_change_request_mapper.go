@@ -0,0 +1,22 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// EmailChangeRequestMapper 邮箱变更请求映射器接口
+type EmailChangeRequestMapper interface {
+	BaseMapper
+
+	// GetByTokenHash 根据确认令牌哈希获取邮箱变更请求
+	GetByTokenHash(tokenHash string) (*model.EmailChangeRequest, error)
+
+	// GetByRevertTokenHash 根据撤销令牌哈希获取邮箱变更请求
+	GetByRevertTokenHash(revertTokenHash string) (*model.EmailChangeRequest, error)
+
+	// DeletePendingByUserID 删除用户尚未确认的邮箱变更请求
+	DeletePendingByUserID(userID uint) error
+
+	// DeleteByUserID 删除用户的全部邮箱变更请求
+	DeleteByUserID(userID uint) error
+}
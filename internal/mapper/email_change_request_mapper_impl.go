@@ -0,0 +1,168 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// emailChangeRequestMapper 邮箱变更请求映射器实现
+type emailChangeRequestMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu       sync.RWMutex
+	requests map[uint]*model.EmailChangeRequest
+	nextID   uint
+}
+
+// NewEmailChangeRequestMapper 创建EmailChangeRequestMapper实例
+func NewEmailChangeRequestMapper() EmailChangeRequestMapper {
+	return &emailChangeRequestMapper{
+		requests: make(map[uint]*model.EmailChangeRequest),
+		nextID:   1,
+	}
+}
+
+// Save 保存邮箱变更请求
+func (m *emailChangeRequestMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	request, ok := entity.(*model.EmailChangeRequest)
+	if !ok {
+		return errors.New("invalid email change request entity")
+	}
+
+	for id, existing := range m.requests {
+		if (existing.TokenHash == request.TokenHash || existing.RevertTokenHash == request.RevertTokenHash) && id != request.ID {
+			return errors.New("email change request already exists")
+		}
+	}
+
+	// 如果是新请求，分配ID
+	if request.ID == 0 {
+		request.ID = m.nextID
+		m.nextID++
+		request.CreatedAt = time.Now()
+	}
+
+	m.requests[request.ID] = request
+
+	return nil
+}
+
+// DeleteByID 根据ID删除邮箱变更请求
+func (m *emailChangeRequestMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requestID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid email change request id")
+	}
+
+	delete(m.requests, requestID)
+	return nil
+}
+
+// GetByID 根据ID获取邮箱变更请求
+func (m *emailChangeRequestMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	requestID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid email change request id")
+	}
+
+	request, exists := m.requests[requestID]
+	if !exists {
+		return nil, errors.New("email change request not found")
+	}
+
+	return request, nil
+}
+
+// GetAll 获取所有邮箱变更请求
+func (m *emailChangeRequestMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	requests := make([]interface{}, 0, len(m.requests))
+	for _, request := range m.requests {
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+// Update 更新邮箱变更请求
+func (m *emailChangeRequestMapper) Update(entity interface{}) error {
+	request, ok := entity.(*model.EmailChangeRequest)
+	if !ok {
+		return errors.New("invalid email change request entity")
+	}
+
+	if request.ID == 0 {
+		return errors.New("email change request id is required")
+	}
+
+	return m.Save(request)
+}
+
+// GetByTokenHash 根据确认令牌哈希获取邮箱变更请求
+func (m *emailChangeRequestMapper) GetByTokenHash(tokenHash string) (*model.EmailChangeRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, request := range m.requests {
+		if request.TokenHash == tokenHash {
+			return request, nil
+		}
+	}
+
+	return nil, errors.New("email change request not found")
+}
+
+// GetByRevertTokenHash 根据撤销令牌哈希获取邮箱变更请求
+func (m *emailChangeRequestMapper) GetByRevertTokenHash(revertTokenHash string) (*model.EmailChangeRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, request := range m.requests {
+		if request.RevertTokenHash == revertTokenHash {
+			return request, nil
+		}
+	}
+
+	return nil, errors.New("email change request not found")
+}
+
+// DeletePendingByUserID 删除用户尚未确认的邮箱变更请求，已确认的请求保留以便原邮箱撤销
+func (m *emailChangeRequestMapper) DeletePendingByUserID(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, request := range m.requests {
+		if request.UserID == userID && !request.IsConfirmed() {
+			delete(m.requests, id)
+		}
+	}
+
+	return nil
+}
+
+// DeleteByUserID 删除用户的全部邮箱变更请求
+func (m *emailChangeRequestMapper) DeleteByUserID(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, request := range m.requests {
+		if request.UserID == userID {
+			delete(m.requests, id)
+		}
+	}
+
+	return nil
+}
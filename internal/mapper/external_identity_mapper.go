@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ExternalIdentityMapper 外部身份映射器接口
+type ExternalIdentityMapper interface {
+	BaseMapper
+
+	// GetByProviderSubject 根据上游身份提供方和sub获取外部身份
+	GetByProviderSubject(provider, subject string) (*model.ExternalIdentity, error)
+
+	// ListByUserID 获取用户关联的全部外部身份
+	ListByUserID(userID uint) ([]*model.ExternalIdentity, error)
+}
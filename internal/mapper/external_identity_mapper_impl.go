@@ -0,0 +1,146 @@
+package mapper
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// externalIdentityMapper 外部身份映射器实现
+type externalIdentityMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu         sync.RWMutex
+	identities map[uint]*model.ExternalIdentity
+	nextID     uint
+}
+
+// NewExternalIdentityMapper 创建ExternalIdentityMapper实例
+func NewExternalIdentityMapper() ExternalIdentityMapper {
+	return &externalIdentityMapper{
+		identities: make(map[uint]*model.ExternalIdentity),
+		nextID:     1,
+	}
+}
+
+// Save 保存外部身份
+func (m *externalIdentityMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identity, ok := entity.(*model.ExternalIdentity)
+	if !ok {
+		return errors.New("invalid external identity entity")
+	}
+
+	for id, existing := range m.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject && id != identity.ID {
+			return errors.New("external identity already exists")
+		}
+	}
+
+	// 如果是新请求，分配ID
+	if identity.ID == 0 {
+		identity.ID = m.nextID
+		m.nextID++
+		identity.CreatedAt = time.Now()
+	}
+	identity.UpdatedAt = time.Now()
+
+	m.identities[identity.ID] = identity
+
+	return nil
+}
+
+// DeleteByID 根据ID删除外部身份
+func (m *externalIdentityMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	identityID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid external identity id")
+	}
+
+	delete(m.identities, identityID)
+	return nil
+}
+
+// GetByID 根据ID获取外部身份
+func (m *externalIdentityMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	identityID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid external identity id")
+	}
+
+	identity, exists := m.identities[identityID]
+	if !exists {
+		return nil, errors.New("external identity not found")
+	}
+
+	return identity, nil
+}
+
+// GetAll 获取所有外部身份
+func (m *externalIdentityMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	identities := make([]interface{}, 0, len(m.identities))
+	for _, identity := range m.identities {
+		identities = append(identities, identity)
+	}
+
+	return identities, nil
+}
+
+// Update 更新外部身份
+func (m *externalIdentityMapper) Update(entity interface{}) error {
+	identity, ok := entity.(*model.ExternalIdentity)
+	if !ok {
+		return errors.New("invalid external identity entity")
+	}
+
+	if identity.ID == 0 {
+		return errors.New("external identity id is required")
+	}
+
+	return m.Save(identity)
+}
+
+// GetByProviderSubject 根据上游身份提供方和sub获取外部身份
+func (m *externalIdentityMapper) GetByProviderSubject(provider, subject string) (*model.ExternalIdentity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+
+	return nil, errors.New("external identity not found")
+}
+
+// ListByUserID 获取用户关联的全部外部身份，按关联时间排序
+func (m *externalIdentityMapper) ListByUserID(userID uint) ([]*model.ExternalIdentity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	identities := make([]*model.ExternalIdentity, 0)
+	for _, identity := range m.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].ID < identities[j].ID
+	})
+
+	return identities, nil
+}
@@ -0,0 +1,13 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// FederatedLoginStateMapper 上游登录状态映射器接口
+type FederatedLoginStateMapper interface {
+	BaseMapper
+
+	// GetByState 根据state获取上游登录状态
+	GetByState(state string) (*model.FederatedLoginState, error)
+}
@@ -0,0 +1,126 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// federatedLoginStateMapper 上游登录状态映射器实现
+type federatedLoginStateMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu     sync.RWMutex
+	states map[uint]*model.FederatedLoginState
+	nextID uint
+}
+
+// NewFederatedLoginStateMapper 创建FederatedLoginStateMapper实例
+func NewFederatedLoginStateMapper() FederatedLoginStateMapper {
+	return &federatedLoginStateMapper{
+		states: make(map[uint]*model.FederatedLoginState),
+		nextID: 1,
+	}
+}
+
+// Save 保存上游登录状态
+func (m *federatedLoginStateMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	loginState, ok := entity.(*model.FederatedLoginState)
+	if !ok {
+		return errors.New("invalid federated login state entity")
+	}
+
+	for id, existing := range m.states {
+		if existing.State == loginState.State && id != loginState.ID {
+			return errors.New("federated login state already exists")
+		}
+	}
+
+	// 如果是新请求，分配ID
+	if loginState.ID == 0 {
+		loginState.ID = m.nextID
+		m.nextID++
+		loginState.CreatedAt = time.Now()
+	}
+
+	m.states[loginState.ID] = loginState
+
+	return nil
+}
+
+// DeleteByID 根据ID删除上游登录状态
+func (m *federatedLoginStateMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stateID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid federated login state id")
+	}
+
+	delete(m.states, stateID)
+	return nil
+}
+
+// GetByID 根据ID获取上游登录状态
+func (m *federatedLoginStateMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stateID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid federated login state id")
+	}
+
+	loginState, exists := m.states[stateID]
+	if !exists {
+		return nil, errors.New("federated login state not found")
+	}
+
+	return loginState, nil
+}
+
+// GetAll 获取所有上游登录状态
+func (m *federatedLoginStateMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	states := make([]interface{}, 0, len(m.states))
+	for _, loginState := range m.states {
+		states = append(states, loginState)
+	}
+
+	return states, nil
+}
+
+// Update 更新上游登录状态
+func (m *federatedLoginStateMapper) Update(entity interface{}) error {
+	loginState, ok := entity.(*model.FederatedLoginState)
+	if !ok {
+		return errors.New("invalid federated login state entity")
+	}
+
+	if loginState.ID == 0 {
+		return errors.New("federated login state id is required")
+	}
+
+	return m.Save(loginState)
+}
+
+// GetByState 根据state获取上游登录状态
+func (m *federatedLoginStateMapper) GetByState(state string) (*model.FederatedLoginState, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, loginState := range m.states {
+		if loginState.State == state {
+			return loginState, nil
+		}
+	}
+
+	return nil, errors.New("federated login state not found")
+}
@@ -0,0 +1,13 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// GroupMapper 用户组映射器接口
+type GroupMapper interface {
+	BaseMapper
+	
+	// GetByName 根据名称获取用户组
+	GetByName(name string) (*model.Group, error)
+}
@@ -0,0 +1,134 @@
+package mapper
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// groupMapper 用户组映射器实现
+type groupMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu     sync.RWMutex
+	groups map[uint]*model.Group
+	nextID uint
+}
+
+// NewGroupMapper 创建GroupMapper实例
+func NewGroupMapper() GroupMapper {
+	return &groupMapper{
+		groups: make(map[uint]*model.Group),
+		nextID: 1,
+	}
+}
+
+// Save 保存用户组
+func (m *groupMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, ok := entity.(*model.Group)
+	if !ok {
+		return errors.New("invalid group entity")
+	}
+
+	for id, existing := range m.groups {
+		if existing.Name == group.Name && id != group.ID {
+			return errors.New("group already exists")
+		}
+	}
+
+	// 如果是新用户组，分配ID
+	if group.ID == 0 {
+		group.ID = m.nextID
+		m.nextID++
+		group.CreatedAt = time.Now()
+	}
+	group.UpdatedAt = time.Now()
+
+	m.groups[group.ID] = group
+
+	return nil
+}
+
+// DeleteByID 根据ID删除用户组
+func (m *groupMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groupID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid group id")
+	}
+
+	delete(m.groups, groupID)
+	return nil
+}
+
+// GetByID 根据ID获取用户组
+func (m *groupMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	groupID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid group id")
+	}
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return nil, errors.New("group not found")
+	}
+
+	return group, nil
+}
+
+// GetAll 获取所有用户组，按ID排序保证输出稳定
+func (m *groupMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]uint, 0, len(m.groups))
+	for id := range m.groups {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	groups := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		groups = append(groups, m.groups[id])
+	}
+
+	return groups, nil
+}
+
+// Update 更新用户组
+func (m *groupMapper) Update(entity interface{}) error {
+	group, ok := entity.(*model.Group)
+	if !ok {
+		return errors.New("invalid group entity")
+	}
+
+	if group.ID == 0 {
+		return errors.New("group id is required")
+	}
+
+	return m.Save(group)
+}
+
+// GetByName 根据名称获取用户组
+func (m *groupMapper) GetByName(name string) (*model.Group, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, group := range m.groups {
+		if group.Name == name {
+			return group, nil
+		}
+	}
+
+	return nil, errors.New("group not found")
+}
@@ -0,0 +1,13 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// MFAChallengeMapper 两步验证挑战映射器接口
+type MFAChallengeMapper interface {
+	BaseMapper
+
+	// GetByToken 根据令牌获取两步验证挑战
+	GetByToken(token string) (*model.MFAChallenge, error)
+}
@@ -0,0 +1,126 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// mfaChallengeMapper 两步验证挑战映射器实现
+type mfaChallengeMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu         sync.RWMutex
+	challenges map[uint]*model.MFAChallenge
+	nextID     uint
+}
+
+// NewMFAChallengeMapper 创建MFAChallengeMapper实例
+func NewMFAChallengeMapper() MFAChallengeMapper {
+	return &mfaChallengeMapper{
+		challenges: make(map[uint]*model.MFAChallenge),
+		nextID:     1,
+	}
+}
+
+// Save 保存两步验证挑战
+func (m *mfaChallengeMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	challenge, ok := entity.(*model.MFAChallenge)
+	if !ok {
+		return errors.New("invalid mfa challenge entity")
+	}
+
+	for id, existing := range m.challenges {
+		if existing.Token == challenge.Token && id != challenge.ID {
+			return errors.New("mfa challenge already exists")
+		}
+	}
+
+	// 如果是新挑战，分配ID
+	if challenge.ID == 0 {
+		challenge.ID = m.nextID
+		m.nextID++
+		challenge.CreatedAt = time.Now()
+	}
+
+	m.challenges[challenge.ID] = challenge
+
+	return nil
+}
+
+// DeleteByID 根据ID删除两步验证挑战
+func (m *mfaChallengeMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	challengeID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid mfa challenge id")
+	}
+
+	delete(m.challenges, challengeID)
+	return nil
+}
+
+// GetByID 根据ID获取两步验证挑战
+func (m *mfaChallengeMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	challengeID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid mfa challenge id")
+	}
+
+	challenge, exists := m.challenges[challengeID]
+	if !exists {
+		return nil, errors.New("mfa challenge not found")
+	}
+
+	return challenge, nil
+}
+
+// GetAll 获取所有两步验证挑战
+func (m *mfaChallengeMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	challenges := make([]interface{}, 0, len(m.challenges))
+	for _, challenge := range m.challenges {
+		challenges = append(challenges, challenge)
+	}
+
+	return challenges, nil
+}
+
+// Update 更新两步验证挑战
+func (m *mfaChallengeMapper) Update(entity interface{}) error {
+	challenge, ok := entity.(*model.MFAChallenge)
+	if !ok {
+		return errors.New("invalid mfa challenge entity")
+	}
+
+	if challenge.ID == 0 {
+		return errors.New("mfa challenge id is required")
+	}
+
+	return m.Save(challenge)
+}
+
+// GetByToken 根据令牌获取两步验证挑战
+func (m *mfaChallengeMapper) GetByToken(token string) (*model.MFAChallenge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, challenge := range m.challenges {
+		if challenge.Token == token {
+			return challenge, nil
+		}
+	}
+
+	return nil, errors.New("mfa challenge not found")
+}
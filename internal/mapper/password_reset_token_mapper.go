@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// PasswordResetTokenMapper 重置密码令牌映射器接口
+type PasswordResetTokenMapper interface {
+	BaseMapper
+
+	// GetByTokenHash 根据令牌哈希获取重置密码令牌
+	GetByTokenHash(tokenHash string) (*model.PasswordResetToken, error)
+
+	// DeleteByUserID 删除用户的全部重置密码令牌
+	DeleteByUserID(userID uint) error
+}
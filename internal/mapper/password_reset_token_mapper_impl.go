@@ -0,0 +1,140 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// passwordResetTokenMapper 重置密码令牌映射器实现
+type passwordResetTokenMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu     sync.RWMutex
+	tokens map[uint]*model.PasswordResetToken
+	nextID uint
+}
+
+// NewPasswordResetTokenMapper 创建PasswordResetTokenMapper实例
+func NewPasswordResetTokenMapper() PasswordResetTokenMapper {
+	return &passwordResetTokenMapper{
+		tokens: make(map[uint]*model.PasswordResetToken),
+		nextID: 1,
+	}
+}
+
+// Save 保存重置密码令牌
+func (m *passwordResetTokenMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := entity.(*model.PasswordResetToken)
+	if !ok {
+		return errors.New("invalid password reset token entity")
+	}
+
+	for id, existing := range m.tokens {
+		if existing.TokenHash == token.TokenHash && id != token.ID {
+			return errors.New("password reset token already exists")
+		}
+	}
+
+	// 如果是新令牌，分配ID
+	if token.ID == 0 {
+		token.ID = m.nextID
+		m.nextID++
+		token.CreatedAt = time.Now()
+	}
+
+	m.tokens[token.ID] = token
+
+	return nil
+}
+
+// DeleteByID 根据ID删除重置密码令牌
+func (m *passwordResetTokenMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokenID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid password reset token id")
+	}
+
+	delete(m.tokens, tokenID)
+	return nil
+}
+
+// GetByID 根据ID获取重置密码令牌
+func (m *passwordResetTokenMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokenID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid password reset token id")
+	}
+
+	token, exists := m.tokens[tokenID]
+	if !exists {
+		return nil, errors.New("password reset token not found")
+	}
+
+	return token, nil
+}
+
+// GetAll 获取所有重置密码令牌
+func (m *passwordResetTokenMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokens := make([]interface{}, 0, len(m.tokens))
+	for _, token := range m.tokens {
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// Update 更新重置密码令牌
+func (m *passwordResetTokenMapper) Update(entity interface{}) error {
+	token, ok := entity.(*model.PasswordResetToken)
+	if !ok {
+		return errors.New("invalid password reset token entity")
+	}
+
+	if token.ID == 0 {
+		return errors.New("password reset token id is required")
+	}
+
+	return m.Save(token)
+}
+
+// GetByTokenHash 根据令牌哈希获取重置密码令牌
+func (m *passwordResetTokenMapper) GetByTokenHash(tokenHash string) (*model.PasswordResetToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return nil, errors.New("password reset token not found")
+}
+
+// DeleteByUserID 删除用户的全部重置密码令牌
+func (m *passwordResetTokenMapper) DeleteByUserID(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, token := range m.tokens {
+		if token.UserID == userID {
+			delete(m.tokens, id)
+		}
+	}
+
+	return nil
+}
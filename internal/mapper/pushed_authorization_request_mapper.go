@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// PushedAuthorizationRequestMapper 推送的授权请求映射器接口
+type PushedAuthorizationRequestMapper interface {
+	BaseMapper
+
+	// GetByRequestURI 根据request_uri获取推送的授权请求
+	GetByRequestURI(requestURI string) (*model.PushedAuthorizationRequest, error)
+
+	// ConsumeByRequestURI 根据request_uri获取推送的授权请求并删除，request_uri只能被取出一次
+	ConsumeByRequestURI(requestURI string) (*model.PushedAuthorizationRequest, error)
+}
@@ -0,0 +1,141 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// pushedAuthorizationRequestMapper 推送的授权请求映射器实现
+type pushedAuthorizationRequestMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu       sync.RWMutex
+	requests map[uint]*model.PushedAuthorizationRequest
+	nextID   uint
+}
+
+// NewPushedAuthorizationRequestMapper 创建PushedAuthorizationRequestMapper实例
+func NewPushedAuthorizationRequestMapper() PushedAuthorizationRequestMapper {
+	return &pushedAuthorizationRequestMapper{
+		requests: make(map[uint]*model.PushedAuthorizationRequest),
+		nextID:   1,
+	}
+}
+
+// Save 保存推送的授权请求
+func (m *pushedAuthorizationRequestMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	parReq, ok := entity.(*model.PushedAuthorizationRequest)
+	if !ok {
+		return errors.New("invalid pushed authorization request entity")
+	}
+
+	for id, existing := range m.requests {
+		if existing.RequestURI == parReq.RequestURI && id != parReq.ID {
+			return errors.New("pushed authorization request already exists")
+		}
+	}
+
+	// 如果是新请求，分配ID
+	if parReq.ID == 0 {
+		parReq.ID = m.nextID
+		m.nextID++
+		parReq.CreatedAt = time.Now()
+	}
+
+	m.requests[parReq.ID] = parReq
+
+	return nil
+}
+
+// DeleteByID 根据ID删除推送的授权请求
+func (m *pushedAuthorizationRequestMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reqID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid pushed authorization request id")
+	}
+
+	delete(m.requests, reqID)
+	return nil
+}
+
+// GetByID 根据ID获取推送的授权请求
+func (m *pushedAuthorizationRequestMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reqID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid pushed authorization request id")
+	}
+
+	parReq, exists := m.requests[reqID]
+	if !exists {
+		return nil, errors.New("pushed authorization request not found")
+	}
+
+	return parReq, nil
+}
+
+// GetAll 获取所有推送的授权请求
+func (m *pushedAuthorizationRequestMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	requests := make([]interface{}, 0, len(m.requests))
+	for _, parReq := range m.requests {
+		requests = append(requests, parReq)
+	}
+
+	return requests, nil
+}
+
+// Update 更新推送的授权请求
+func (m *pushedAuthorizationRequestMapper) Update(entity interface{}) error {
+	parReq, ok := entity.(*model.PushedAuthorizationRequest)
+	if !ok {
+		return errors.New("invalid pushed authorization request entity")
+	}
+
+	if parReq.ID == 0 {
+		return errors.New("pushed authorization request id is required")
+	}
+
+	return m.Save(parReq)
+}
+
+// GetByRequestURI 根据request_uri获取推送的授权请求
+func (m *pushedAuthorizationRequestMapper) GetByRequestURI(requestURI string) (*model.PushedAuthorizationRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, parReq := range m.requests {
+		if parReq.RequestURI == requestURI {
+			return parReq, nil
+		}
+	}
+
+	return nil, errors.New("pushed authorization request not found")
+}
+
+// ConsumeByRequestURI 根据request_uri获取推送的授权请求并删除，查找与删除在同一把锁内完成
+func (m *pushedAuthorizationRequestMapper) ConsumeByRequestURI(requestURI string) (*model.PushedAuthorizationRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, parReq := range m.requests {
+		if parReq.RequestURI == requestURI {
+			delete(m.requests, id)
+			return parReq, nil
+		}
+	}
+
+	return nil, errors.New("pushed authorization request not found")
+}
@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// RealmMapper realm映射器接口
+type RealmMapper interface {
+	BaseMapper
+
+	// GetByName 根据名称获取realm
+	GetByName(name string) (*model.Realm, error)
+
+	// GetDefault 获取默认realm
+	GetDefault() (*model.Realm, error)
+}
@@ -0,0 +1,234 @@
+package mapper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// defaultRealmID 未在配置中定义默认realm时使用的ID
+const defaultRealmID uint = 1
+
+// realmMapper realm映射器实现
+type realmMapper struct {
+	// 使用内存存储，启动时从配置文件加载
+	mu      sync.RWMutex
+	realms  map[uint]*model.Realm
+	loadErr error // 配置加载失败时记录错误，由GetAll返回以阻止服务启动
+}
+
+// NewRealmMapper 创建RealmMapper实例
+func NewRealmMapper() RealmMapper {
+	mapper := &realmMapper{
+		realms: make(map[uint]*model.Realm),
+	}
+
+	// 从配置文件加载realm定义
+	mapper.loadErr = mapper.initializeRealms()
+
+	return mapper
+}
+
+// Save 保存realm
+func (m *realmMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	realm, ok := entity.(*model.Realm)
+	if !ok {
+		return errors.New("invalid realm entity")
+	}
+
+	// realm ID被用户等数据引用，必须由配置显式给出，不能依赖加载顺序分配
+	if realm.ID == 0 {
+		return errors.New("realm id is required")
+	}
+	if !model.IsValidRealmName(realm.Name) {
+		return fmt.Errorf("invalid realm name %q: must match [a-z0-9-] and be at most 64 characters", realm.Name)
+	}
+
+	for id, existing := range m.realms {
+		if existing.Name == realm.Name && id != realm.ID {
+			return errors.New("realm already exists")
+		}
+	}
+
+	m.realms[realm.ID] = realm
+
+	return nil
+}
+
+// DeleteByID 根据ID删除realm
+func (m *realmMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	realmID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid realm id")
+	}
+
+	delete(m.realms, realmID)
+	return nil
+}
+
+// GetByID 根据ID获取realm
+func (m *realmMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	realmID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid realm id")
+	}
+
+	realm, exists := m.realms[realmID]
+	if !exists {
+		return nil, errors.New("realm not found")
+	}
+
+	return realm, nil
+}
+
+// GetAll 获取所有realm（按ID排序）
+func (m *realmMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.loadErr != nil {
+		return nil, m.loadErr
+	}
+
+	ids := make([]int, 0, len(m.realms))
+	for id := range m.realms {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	realms := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		realms = append(realms, m.realms[uint(id)])
+	}
+
+	return realms, nil
+}
+
+// Update 更新realm
+func (m *realmMapper) Update(entity interface{}) error {
+	realm, ok := entity.(*model.Realm)
+	if !ok {
+		return errors.New("invalid realm entity")
+	}
+
+	if realm.ID == 0 {
+		return errors.New("realm id is required")
+	}
+
+	return m.Save(realm)
+}
+
+// GetByName 根据名称获取realm
+func (m *realmMapper) GetByName(name string) (*model.Realm, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, realm := range m.realms {
+		if realm.Name == name {
+			return realm, nil
+		}
+	}
+
+	return nil, errors.New("realm not found")
+}
+
+// GetDefault 获取默认realm
+func (m *realmMapper) GetDefault() (*model.Realm, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, realm := range m.realms {
+		if realm.IsDefault {
+			return realm, nil
+		}
+	}
+
+	return nil, errors.New("default realm not found")
+}
+
+// initializeRealms 从REALMS_CONFIG_PATH加载realm定义
+// 配置文件不存在时只创建默认realm（ID为1），并保留原有的测试客户端；
+// 配置无法解析、缺少ID、ID或名称重复、名称不合法时返回错误
+func (m *realmMapper) initializeRealms() error {
+	defaultName := os.Getenv("DEFAULT_REALM")
+	if defaultName == "" {
+		defaultName = "default"
+	}
+	if !model.IsValidRealmName(defaultName) {
+		return fmt.Errorf("invalid DEFAULT_REALM %q: must match [a-z0-9-] and be at most 64 characters", defaultName)
+	}
+
+	var realms []*model.Realm
+	configPath := os.Getenv("REALMS_CONFIG_PATH")
+	if configPath == "" {
+		configPath = "config/realms.json"
+	}
+
+	data, err := os.ReadFile(configPath)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &realms); err != nil {
+			return fmt.Errorf("无法解析realm配置文件 %s: %w", configPath, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("无法读取realm配置文件 %s: %w", configPath, err)
+	}
+
+	// 确保默认realm存在，并使用全局的JWT密钥配置
+	hasDefault := false
+	for _, realm := range realms {
+		if realm == nil {
+			return fmt.Errorf("realm配置文件 %s 包含空的realm定义", configPath)
+		}
+		if realm.Name == defaultName {
+			realm.IsDefault = true
+			hasDefault = true
+		} else {
+			realm.IsDefault = false
+		}
+	}
+
+	if !hasDefault {
+		realms = append([]*model.Realm{{
+			ID:          defaultRealmID,
+			Name:        defaultName,
+			DisplayName: "OIDC",
+			IsDefault:   true,
+			Clients: []model.Client{
+				{
+					ClientID:                "test_client",
+					Name:                    "测试客户端",
+					Description:             "用于测试的客户端（公开客户端，需使用PKCE）",
+					RedirectURI:             "http://localhost:3000/callback",
+					Scopes:                  "openid profile email anime:read collection:read collection:write bangumi:sync",
+					TokenEndpointAuthMethod: model.TokenEndpointAuthNone,
+				},
+			},
+		}}, realms...)
+	}
+
+	for _, realm := range realms {
+		if _, exists := m.realms[realm.ID]; exists {
+			return fmt.Errorf("realm %s: duplicate realm id %d", realm.Name, realm.ID)
+		}
+		if err := m.Save(realm); err != nil {
+			return fmt.Errorf("realm %s: %w", realm.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// RecoveryCodeMapper 恢复码映射器接口
+type RecoveryCodeMapper interface {
+	BaseMapper
+
+	// ListByUserID 获取用户的全部恢复码
+	ListByUserID(userID uint) ([]*model.RecoveryCode, error)
+
+	// DeleteByUserID 删除用户的全部恢复码
+	DeleteByUserID(userID uint) error
+}
@@ -0,0 +1,137 @@
+package mapper
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// recoveryCodeMapper 恢复码映射器实现
+type recoveryCodeMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu     sync.RWMutex
+	codes  map[uint]*model.RecoveryCode
+	nextID uint
+}
+
+// NewRecoveryCodeMapper 创建RecoveryCodeMapper实例
+func NewRecoveryCodeMapper() RecoveryCodeMapper {
+	return &recoveryCodeMapper{
+		codes:  make(map[uint]*model.RecoveryCode),
+		nextID: 1,
+	}
+}
+
+// Save 保存恢复码
+func (m *recoveryCodeMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code, ok := entity.(*model.RecoveryCode)
+	if !ok {
+		return errors.New("invalid recovery code entity")
+	}
+
+	// 如果是新恢复码，分配ID
+	if code.ID == 0 {
+		code.ID = m.nextID
+		m.nextID++
+		code.CreatedAt = time.Now()
+	}
+
+	m.codes[code.ID] = code
+
+	return nil
+}
+
+// DeleteByID 根据ID删除恢复码
+func (m *recoveryCodeMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	codeID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid recovery code id")
+	}
+
+	delete(m.codes, codeID)
+	return nil
+}
+
+// GetByID 根据ID获取恢复码
+func (m *recoveryCodeMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	codeID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid recovery code id")
+	}
+
+	code, exists := m.codes[codeID]
+	if !exists {
+		return nil, errors.New("recovery code not found")
+	}
+
+	return code, nil
+}
+
+// GetAll 获取所有恢复码
+func (m *recoveryCodeMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	codes := make([]interface{}, 0, len(m.codes))
+	for _, code := range m.codes {
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// Update 更新恢复码
+func (m *recoveryCodeMapper) Update(entity interface{}) error {
+	code, ok := entity.(*model.RecoveryCode)
+	if !ok {
+		return errors.New("invalid recovery code entity")
+	}
+
+	if code.ID == 0 {
+		return errors.New("recovery code id is required")
+	}
+
+	return m.Save(code)
+}
+
+// ListByUserID 获取用户的全部恢复码，按ID排序
+func (m *recoveryCodeMapper) ListByUserID(userID uint) ([]*model.RecoveryCode, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	codes := make([]*model.RecoveryCode, 0)
+	for _, code := range m.codes {
+		if code.UserID == userID {
+			codes = append(codes, code)
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].ID < codes[j].ID })
+
+	return codes, nil
+}
+
+// DeleteByUserID 删除用户的全部恢复码
+func (m *recoveryCodeMapper) DeleteByUserID(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, code := range m.codes {
+		if code.UserID == userID {
+			delete(m.codes, id)
+		}
+	}
+
+	return nil
+}
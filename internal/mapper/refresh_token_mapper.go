@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// RefreshTokenMapper 刷新令牌映射器接口
+type RefreshTokenMapper interface {
+	BaseMapper
+
+	// GetByTokenHash 根据令牌哈希获取刷新令牌
+	GetByTokenHash(tokenHash string) (*model.RefreshToken, error)
+
+	// ListByUserID 获取用户的全部刷新令牌
+	ListByUserID(userID uint) ([]*model.RefreshToken, error)
+}
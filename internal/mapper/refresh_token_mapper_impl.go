@@ -0,0 +1,141 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// refreshTokenMapper 刷新令牌映射器实现
+type refreshTokenMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu     sync.RWMutex
+	tokens map[uint]*model.RefreshToken
+	nextID uint
+}
+
+// NewRefreshTokenMapper 创建RefreshTokenMapper实例
+func NewRefreshTokenMapper() RefreshTokenMapper {
+	return &refreshTokenMapper{
+		tokens: make(map[uint]*model.RefreshToken),
+		nextID: 1,
+	}
+}
+
+// Save 保存刷新令牌
+func (m *refreshTokenMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := entity.(*model.RefreshToken)
+	if !ok {
+		return errors.New("invalid refresh token entity")
+	}
+
+	for id, existing := range m.tokens {
+		if existing.TokenHash == token.TokenHash && id != token.ID {
+			return errors.New("refresh token already exists")
+		}
+	}
+
+	// 如果是新请求，分配ID
+	if token.ID == 0 {
+		token.ID = m.nextID
+		m.nextID++
+		token.CreatedAt = time.Now()
+	}
+
+	m.tokens[token.ID] = token
+
+	return nil
+}
+
+// DeleteByID 根据ID删除刷新令牌
+func (m *refreshTokenMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokenID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid refresh token id")
+	}
+
+	delete(m.tokens, tokenID)
+	return nil
+}
+
+// GetByID 根据ID获取刷新令牌
+func (m *refreshTokenMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokenID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid refresh token id")
+	}
+
+	token, exists := m.tokens[tokenID]
+	if !exists {
+		return nil, errors.New("refresh token not found")
+	}
+
+	return token, nil
+}
+
+// GetAll 获取所有刷新令牌
+func (m *refreshTokenMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokens := make([]interface{}, 0, len(m.tokens))
+	for _, token := range m.tokens {
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// Update 更新刷新令牌
+func (m *refreshTokenMapper) Update(entity interface{}) error {
+	token, ok := entity.(*model.RefreshToken)
+	if !ok {
+		return errors.New("invalid refresh token entity")
+	}
+
+	if token.ID == 0 {
+		return errors.New("refresh token id is required")
+	}
+
+	return m.Save(token)
+}
+
+// GetByTokenHash 根据令牌哈希获取刷新令牌
+func (m *refreshTokenMapper) GetByTokenHash(tokenHash string) (*model.RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+
+	return nil, errors.New("refresh token not found")
+}
+
+// ListByUserID 获取用户的全部刷新令牌
+func (m *refreshTokenMapper) ListByUserID(userID uint) ([]*model.RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokens := make([]*model.RefreshToken, 0)
+	for _, token := range m.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
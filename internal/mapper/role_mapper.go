@@ -0,0 +1,13 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// RoleMapper 角色映射器接口
+type RoleMapper interface {
+	BaseMapper
+	
+	// GetByName 根据名称获取角色
+	GetByName(name string) (*model.Role, error)
+}
@@ -0,0 +1,134 @@
+package mapper
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// roleMapper 角色映射器实现
+type roleMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu     sync.RWMutex
+	roles  map[uint]*model.Role
+	nextID uint
+}
+
+// NewRoleMapper 创建RoleMapper实例
+func NewRoleMapper() RoleMapper {
+	return &roleMapper{
+		roles:  make(map[uint]*model.Role),
+		nextID: 1,
+	}
+}
+
+// Save 保存角色
+func (m *roleMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	role, ok := entity.(*model.Role)
+	if !ok {
+		return errors.New("invalid role entity")
+	}
+
+	for id, existing := range m.roles {
+		if existing.Name == role.Name && id != role.ID {
+			return errors.New("role already exists")
+		}
+	}
+
+	// 如果是新角色，分配ID
+	if role.ID == 0 {
+		role.ID = m.nextID
+		m.nextID++
+		role.CreatedAt = time.Now()
+	}
+	role.UpdatedAt = time.Now()
+
+	m.roles[role.ID] = role
+
+	return nil
+}
+
+// DeleteByID 根据ID删除角色
+func (m *roleMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	roleID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid role id")
+	}
+
+	delete(m.roles, roleID)
+	return nil
+}
+
+// GetByID 根据ID获取角色
+func (m *roleMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	roleID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid role id")
+	}
+
+	role, exists := m.roles[roleID]
+	if !exists {
+		return nil, errors.New("role not found")
+	}
+
+	return role, nil
+}
+
+// GetAll 获取所有角色，按ID排序保证输出稳定
+func (m *roleMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]uint, 0, len(m.roles))
+	for id := range m.roles {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	roles := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		roles = append(roles, m.roles[id])
+	}
+
+	return roles, nil
+}
+
+// Update 更新角色
+func (m *roleMapper) Update(entity interface{}) error {
+	role, ok := entity.(*model.Role)
+	if !ok {
+		return errors.New("invalid role entity")
+	}
+
+	if role.ID == 0 {
+		return errors.New("role id is required")
+	}
+
+	return m.Save(role)
+}
+
+// GetByName 根据名称获取角色
+func (m *roleMapper) GetByName(name string) (*model.Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, role := range m.roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+
+	return nil, errors.New("role not found")
+}
@@ -0,0 +1,18 @@
+package mapper
+
+import (
+	"errors"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ErrScopeNotFound scope不存在
+var ErrScopeNotFound = errors.New("scope not found")
+
+// ScopeMapper scope映射器接口
+type ScopeMapper interface {
+	BaseMapper
+	
+	// GetByName 根据名称获取scope
+	GetByName(name string) (*model.Scope, error)
+}
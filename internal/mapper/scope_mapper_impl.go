@@ -0,0 +1,134 @@
+package mapper
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// scopeMapper scope映射器实现
+type scopeMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu     sync.RWMutex
+	scopes map[uint]*model.Scope
+	nextID uint
+}
+
+// NewScopeMapper 创建ScopeMapper实例
+func NewScopeMapper() ScopeMapper {
+	return &scopeMapper{
+		scopes: make(map[uint]*model.Scope),
+		nextID: 1,
+	}
+}
+
+// Save 保存scope
+func (m *scopeMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	scope, ok := entity.(*model.Scope)
+	if !ok {
+		return errors.New("invalid scope entity")
+	}
+	
+	for id, existing := range m.scopes {
+		if existing.Name == scope.Name && id != scope.ID {
+			return errors.New("scope already exists")
+		}
+	}
+	
+	// 如果是新scope，分配ID
+	if scope.ID == 0 {
+		scope.ID = m.nextID
+		m.nextID++
+		scope.CreatedAt = time.Now()
+	}
+	scope.UpdatedAt = time.Now()
+	
+	m.scopes[scope.ID] = scope
+	
+	return nil
+}
+
+// DeleteByID 根据ID删除scope
+func (m *scopeMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	scopeID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid scope id")
+	}
+	
+	delete(m.scopes, scopeID)
+	return nil
+}
+
+// GetByID 根据ID获取scope
+func (m *scopeMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	scopeID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid scope id")
+	}
+	
+	scope, exists := m.scopes[scopeID]
+	if !exists {
+		return nil, errors.New("scope not found")
+	}
+	
+	return scope, nil
+}
+
+// GetAll 获取所有scope，按ID排序保证输出稳定
+func (m *scopeMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	ids := make([]uint, 0, len(m.scopes))
+	for id := range m.scopes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	
+	scopes := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		scopes = append(scopes, m.scopes[id])
+	}
+	
+	return scopes, nil
+}
+
+// Update 更新scope
+func (m *scopeMapper) Update(entity interface{}) error {
+	scope, ok := entity.(*model.Scope)
+	if !ok {
+		return errors.New("invalid scope entity")
+	}
+	
+	if scope.ID == 0 {
+		return errors.New("scope id is required")
+	}
+	
+	return m.Save(scope)
+}
+
+// GetByName 根据名称获取scope
+func (m *scopeMapper) GetByName(name string) (*model.Scope, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	for _, scope := range m.scopes {
+		if scope.Name == name {
+			return scope, nil
+		}
+	}
+	
+	return nil, ErrScopeNotFound
+}
@@ -0,0 +1,16 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// SessionMapper 登录会话映射器接口
+type SessionMapper interface {
+	BaseMapper
+	
+	// DeleteByUserID 删除用户的所有会话
+	DeleteByUserID(userID uint) error
+	
+	// GetByUserID 获取用户的所有会话
+	GetByUserID(userID uint) ([]*model.Session, error)
+}
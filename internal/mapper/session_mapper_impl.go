@@ -0,0 +1,125 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+	
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// sessionMapper 登录会话映射器实现
+type sessionMapper struct {
+	// 使用内存存储，以会话ID为键
+	mu       sync.RWMutex
+	sessions map[string]*model.Session
+}
+
+// NewSessionMapper 创建SessionMapper实例
+func NewSessionMapper() SessionMapper {
+	return &sessionMapper{
+		sessions: make(map[string]*model.Session),
+	}
+}
+
+// Save 保存会话
+func (m *sessionMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	session, ok := entity.(*model.Session)
+	if !ok {
+		return errors.New("invalid session entity")
+	}
+	
+	if session.ID == "" {
+		return errors.New("session id is required")
+	}
+	
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	
+	m.sessions[session.ID] = session
+	
+	return nil
+}
+
+// DeleteByID 根据ID删除会话
+func (m *sessionMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	sessionID, ok := id.(string)
+	if !ok {
+		return errors.New("invalid session id")
+	}
+	
+	delete(m.sessions, sessionID)
+	return nil
+}
+
+// GetByID 根据ID获取会话
+func (m *sessionMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	sessionID, ok := id.(string)
+	if !ok {
+		return nil, errors.New("invalid session id")
+	}
+	
+	session, exists := m.sessions[sessionID]
+	if !exists {
+		return nil, errors.New("session not found")
+	}
+	
+	return session, nil
+}
+
+// GetAll 获取所有会话
+func (m *sessionMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	sessions := make([]interface{}, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	
+	return sessions, nil
+}
+
+// Update 更新会话
+func (m *sessionMapper) Update(entity interface{}) error {
+	return m.Save(entity)
+}
+
+// DeleteByUserID 删除用户的所有会话
+func (m *sessionMapper) DeleteByUserID(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	for id, session := range m.sessions {
+		if session.UserID == userID {
+			delete(m.sessions, id)
+		}
+	}
+	
+	return nil
+}
+
+// GetByUserID 获取用户的所有会话
+func (m *sessionMapper) GetByUserID(userID uint) ([]*model.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	var sessions []*model.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	
+	return sessions, nil
+}
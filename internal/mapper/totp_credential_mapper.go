@@ -0,0 +1,13 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// TOTPCredentialMapper TOTP认证器映射器接口
+type TOTPCredentialMapper interface {
+	BaseMapper
+
+	// GetByUserID 根据用户ID获取TOTP认证器
+	GetByUserID(userID uint) (*model.TOTPCredential, error)
+}
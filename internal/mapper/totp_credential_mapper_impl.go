@@ -0,0 +1,128 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// totpCredentialMapper TOTP认证器映射器实现
+type totpCredentialMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu          sync.RWMutex
+	credentials map[uint]*model.TOTPCredential
+	nextID      uint
+}
+
+// NewTOTPCredentialMapper 创建TOTPCredentialMapper实例
+func NewTOTPCredentialMapper() TOTPCredentialMapper {
+	return &totpCredentialMapper{
+		credentials: make(map[uint]*model.TOTPCredential),
+		nextID:      1,
+	}
+}
+
+// Save 保存TOTP认证器，每个用户只能有一个认证器
+func (m *totpCredentialMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential, ok := entity.(*model.TOTPCredential)
+	if !ok {
+		return errors.New("invalid totp credential entity")
+	}
+
+	for id, existing := range m.credentials {
+		if existing.UserID == credential.UserID && id != credential.ID {
+			return errors.New("totp credential already exists")
+		}
+	}
+
+	// 如果是新认证器，分配ID
+	now := time.Now()
+	if credential.ID == 0 {
+		credential.ID = m.nextID
+		m.nextID++
+		credential.CreatedAt = now
+	}
+	credential.UpdatedAt = now
+
+	m.credentials[credential.ID] = credential
+
+	return nil
+}
+
+// DeleteByID 根据ID删除TOTP认证器
+func (m *totpCredentialMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credentialID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid totp credential id")
+	}
+
+	delete(m.credentials, credentialID)
+	return nil
+}
+
+// GetByID 根据ID获取TOTP认证器
+func (m *totpCredentialMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credentialID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid totp credential id")
+	}
+
+	credential, exists := m.credentials[credentialID]
+	if !exists {
+		return nil, errors.New("totp credential not found")
+	}
+
+	return credential, nil
+}
+
+// GetAll 获取所有TOTP认证器
+func (m *totpCredentialMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credentials := make([]interface{}, 0, len(m.credentials))
+	for _, credential := range m.credentials {
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, nil
+}
+
+// Update 更新TOTP认证器
+func (m *totpCredentialMapper) Update(entity interface{}) error {
+	credential, ok := entity.(*model.TOTPCredential)
+	if !ok {
+		return errors.New("invalid totp credential entity")
+	}
+
+	if credential.ID == 0 {
+		return errors.New("totp credential id is required")
+	}
+
+	return m.Save(credential)
+}
+
+// GetByUserID 根据用户ID获取TOTP认证器
+func (m *totpCredentialMapper) GetByUserID(userID uint) (*model.TOTPCredential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, credential := range m.credentials {
+		if credential.UserID == userID {
+			return credential, nil
+		}
+	}
+
+	return nil, errors.New("totp credential not found")
+}
@@ -0,0 +1,19 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// UserAssignmentMapper 用户角色/用户组分配映射器接口
+type UserAssignmentMapper interface {
+	BaseMapper
+	
+	// GetByUserID 获取用户的全部分配
+	GetByUserID(userID uint) ([]*model.UserAssignment, error)
+	
+	// DeleteByUserAndKind 删除用户某一类型的全部分配
+	DeleteByUserAndKind(userID uint, kind string) error
+	
+	// DeleteByKindAndName 删除指定角色或用户组的全部分配
+	DeleteByKindAndName(kind, name string) error
+}
@@ -0,0 +1,145 @@
+package mapper
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// userAssignmentMapper 用户角色/用户组分配映射器实现
+type userAssignmentMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu          sync.RWMutex
+	assignments map[uint]*model.UserAssignment
+	nextID      uint
+}
+
+// NewUserAssignmentMapper 创建UserAssignmentMapper实例
+func NewUserAssignmentMapper() UserAssignmentMapper {
+	return &userAssignmentMapper{
+		assignments: make(map[uint]*model.UserAssignment),
+		nextID:      1,
+	}
+}
+
+// Save 保存分配，重复分配视为成功
+func (m *userAssignmentMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	assignment, ok := entity.(*model.UserAssignment)
+	if !ok {
+		return errors.New("invalid user assignment entity")
+	}
+	
+	for id, existing := range m.assignments {
+		if existing.UserID == assignment.UserID && existing.Kind == assignment.Kind && existing.Name == assignment.Name {
+			assignment.ID = id
+			return nil
+		}
+	}
+	
+	assignment.ID = m.nextID
+	m.nextID++
+	assignment.CreatedAt = time.Now()
+	m.assignments[assignment.ID] = assignment
+	
+	return nil
+}
+
+// DeleteByID 根据ID删除分配
+func (m *userAssignmentMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	assignmentID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid user assignment id")
+	}
+	
+	delete(m.assignments, assignmentID)
+	return nil
+}
+
+// GetByID 根据ID获取分配
+func (m *userAssignmentMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	assignmentID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid user assignment id")
+	}
+	
+	assignment, exists := m.assignments[assignmentID]
+	if !exists {
+		return nil, errors.New("user assignment not found")
+	}
+	
+	return assignment, nil
+}
+
+// GetAll 获取所有分配
+func (m *userAssignmentMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	assignments := make([]interface{}, 0, len(m.assignments))
+	for _, assignment := range m.assignments {
+		assignments = append(assignments, assignment)
+	}
+	
+	return assignments, nil
+}
+
+// Update 更新分配
+func (m *userAssignmentMapper) Update(entity interface{}) error {
+	return m.Save(entity)
+}
+
+// GetByUserID 获取用户的全部分配，按ID排序保证输出稳定
+func (m *userAssignmentMapper) GetByUserID(userID uint) ([]*model.UserAssignment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	var assignments []*model.UserAssignment
+	for _, assignment := range m.assignments {
+		if assignment.UserID == userID {
+			assignments = append(assignments, assignment)
+		}
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].ID < assignments[j].ID })
+	
+	return assignments, nil
+}
+
+// DeleteByUserAndKind 删除用户某一类型的全部分配
+func (m *userAssignmentMapper) DeleteByUserAndKind(userID uint, kind string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	for id, assignment := range m.assignments {
+		if assignment.UserID == userID && assignment.Kind == kind {
+			delete(m.assignments, id)
+		}
+	}
+	
+	return nil
+}
+
+// DeleteByKindAndName 删除指定角色或用户组的全部分配
+func (m *userAssignmentMapper) DeleteByKindAndName(kind, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	for id, assignment := range m.assignments {
+		if assignment.Kind == kind && assignment.Name == name {
+			delete(m.assignments, id)
+		}
+	}
+	
+	return nil
+}
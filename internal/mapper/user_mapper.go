@@ -1,6 +1,8 @@
 package mapper
 
 import (
+	"time"
+
 	"github.com/Full-finger/OIDC/internal/model"
 )
 
@@ -16,5 +18,8 @@ type UserMapper interface {
 
 	// UpdateActivationStatus 更新用户激活状态
 	UpdateActivationStatus(id uint, isActive bool) error
+
+	// ListDeletionDue 获取计划删除时间不晚于before的用户
+	ListDeletionDue(before time.Time) ([]*model.User, error)
 	
 }
\ No newline at end of file
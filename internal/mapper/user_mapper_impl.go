@@ -1,34 +1,40 @@
 package mapper
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 	"github.com/Full-finger/OIDC/internal/model"
 )
 
-// userMapper 用户映射器实现
+// userMapper 用户映射器实现，所有查询都限定在所属realm内
 type userMapper struct {
-	db *gorm.DB
+	db      *gorm.DB
+	realmID uint
 }
 
-// NewUserMapper 创建UserMapper实例
-func NewUserMapper(db *gorm.DB) UserMapper {
-	return &userMapper{db: db}
+// NewUserMapper 创建realm范围内的UserMapper实例
+func NewUserMapper(db *gorm.DB, realmID uint) UserMapper {
+	return &userMapper{db: db, realmID: realmID}
 }
 
 // Save 保存用户
 func (m *userMapper) Save(entity interface{}) error {
+	if user, ok := entity.(*model.User); ok {
+		user.RealmID = m.realmID
+	}
 	return m.db.Save(entity).Error
 }
 
 // DeleteByID 根据ID删除用户
 func (m *userMapper) DeleteByID(id interface{}) error {
-	return m.db.Delete(&model.User{}, id).Error
+	return m.db.Where("realm_id = ?", m.realmID).Delete(&model.User{}, id).Error
 }
 
 // GetByID 根据ID获取用户 (实现BaseMapper接口)
 func (m *userMapper) GetByID(id interface{}) (interface{}, error) {
 	var user model.User
-	if err := m.db.Where("id = ?", id).First(&user).Error; err != nil {
+	if err := m.db.Where("realm_id = ? AND id = ?", m.realmID, id).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -37,7 +43,7 @@ func (m *userMapper) GetByID(id interface{}) (interface{}, error) {
 // GetAll 获取所有用户
 func (m *userMapper) GetAll() ([]interface{}, error) {
 	var users []model.User
-	if err := m.db.Find(&users).Error; err != nil {
+	if err := m.db.Where("realm_id = ?", m.realmID).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	
@@ -51,13 +57,13 @@ func (m *userMapper) GetAll() ([]interface{}, error) {
 
 // Update 更新用户
 func (m *userMapper) Update(entity interface{}) error {
-	return m.db.Save(entity).Error
+	return m.Save(entity)
 }
 
 // GetByUsername 根据用户名获取用户
 func (m *userMapper) GetByUsername(username string) (*model.User, error) {
 	var user model.User
-	if err := m.db.Where("username = ?", username).First(&user).Error; err != nil {
+	if err := m.db.Where("realm_id = ? AND username = ?", m.realmID, username).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -66,7 +72,7 @@ func (m *userMapper) GetByUsername(username string) (*model.User, error) {
 // GetByEmail 根据邮箱获取用户
 func (m *userMapper) GetByEmail(email string) (*model.User, error) {
 	var user model.User
-	if err := m.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := m.db.Where("realm_id = ? AND email = ?", m.realmID, email).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -74,5 +80,14 @@ func (m *userMapper) GetByEmail(email string) (*model.User, error) {
 
 // UpdateActivationStatus 更新用户激活状态
 func (m *userMapper) UpdateActivationStatus(id uint, isActive bool) error {
-	return m.db.Model(&model.User{}).Where("id = ?", id).Update("is_active", isActive).Error
+	return m.db.Model(&model.User{}).Where("realm_id = ? AND id = ?", m.realmID, id).Update("is_active", isActive).Error
+}
+
+// ListDeletionDue 获取计划删除时间不晚于before的用户
+func (m *userMapper) ListDeletionDue(before time.Time) ([]*model.User, error) {
+	var users []*model.User
+	if err := m.db.Where("realm_id = ? AND deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", m.realmID, before).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// WebAuthnCredentialMapper WebAuthn凭据映射器接口
+type WebAuthnCredentialMapper interface {
+	BaseMapper
+
+	// GetByCredentialID 根据凭据ID获取WebAuthn凭据
+	GetByCredentialID(credentialID string) (*model.WebAuthnCredential, error)
+
+	// ListByUserID 获取用户的全部WebAuthn凭据
+	ListByUserID(userID uint) ([]*model.WebAuthnCredential, error)
+
+	// DeleteByUserID 删除用户的全部WebAuthn凭据
+	DeleteByUserID(userID uint) error
+}
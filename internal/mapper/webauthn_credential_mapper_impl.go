@@ -0,0 +1,159 @@
+package mapper
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// webAuthnCredentialMapper WebAuthn凭据映射器实现
+type webAuthnCredentialMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu          sync.RWMutex
+	credentials map[uint]*model.WebAuthnCredential
+	nextID      uint
+}
+
+// NewWebAuthnCredentialMapper 创建WebAuthnCredentialMapper实例
+func NewWebAuthnCredentialMapper() WebAuthnCredentialMapper {
+	return &webAuthnCredentialMapper{
+		credentials: make(map[uint]*model.WebAuthnCredential),
+		nextID:      1,
+	}
+}
+
+// Save 保存WebAuthn凭据
+func (m *webAuthnCredentialMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential, ok := entity.(*model.WebAuthnCredential)
+	if !ok {
+		return errors.New("invalid webauthn credential entity")
+	}
+
+	// 同一凭据ID只能注册一次
+	for id, existing := range m.credentials {
+		if existing.CredentialID == credential.CredentialID && id != credential.ID {
+			return errors.New("webauthn credential already exists")
+		}
+	}
+
+	// 如果是新凭据，分配ID
+	if credential.ID == 0 {
+		credential.ID = m.nextID
+		m.nextID++
+		credential.CreatedAt = time.Now()
+	}
+	credential.UpdatedAt = time.Now()
+
+	m.credentials[credential.ID] = credential
+
+	return nil
+}
+
+// DeleteByID 根据ID删除WebAuthn凭据
+func (m *webAuthnCredentialMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credentialID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid webauthn credential id")
+	}
+
+	delete(m.credentials, credentialID)
+	return nil
+}
+
+// GetByID 根据ID获取WebAuthn凭据
+func (m *webAuthnCredentialMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credentialID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid webauthn credential id")
+	}
+
+	credential, exists := m.credentials[credentialID]
+	if !exists {
+		return nil, errors.New("webauthn credential not found")
+	}
+
+	return credential, nil
+}
+
+// GetAll 获取所有WebAuthn凭据
+func (m *webAuthnCredentialMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credentials := make([]interface{}, 0, len(m.credentials))
+	for _, credential := range m.credentials {
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, nil
+}
+
+// Update 更新WebAuthn凭据
+func (m *webAuthnCredentialMapper) Update(entity interface{}) error {
+	credential, ok := entity.(*model.WebAuthnCredential)
+	if !ok {
+		return errors.New("invalid webauthn credential entity")
+	}
+
+	if credential.ID == 0 {
+		return errors.New("webauthn credential id is required")
+	}
+
+	return m.Save(credential)
+}
+
+// GetByCredentialID 根据凭据ID获取WebAuthn凭据
+func (m *webAuthnCredentialMapper) GetByCredentialID(credentialID string) (*model.WebAuthnCredential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, credential := range m.credentials {
+		if credential.CredentialID == credentialID {
+			return credential, nil
+		}
+	}
+
+	return nil, errors.New("webauthn credential not found")
+}
+
+// ListByUserID 获取用户的全部WebAuthn凭据，按ID排序
+func (m *webAuthnCredentialMapper) ListByUserID(userID uint) ([]*model.WebAuthnCredential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	credentials := make([]*model.WebAuthnCredential, 0)
+	for _, credential := range m.credentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, credential)
+		}
+	}
+	sort.Slice(credentials, func(i, j int) bool { return credentials[i].ID < credentials[j].ID })
+
+	return credentials, nil
+}
+
+// DeleteByUserID 删除用户的全部WebAuthn凭据
+func (m *webAuthnCredentialMapper) DeleteByUserID(userID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, credential := range m.credentials {
+		if credential.UserID == userID {
+			delete(m.credentials, id)
+		}
+	}
+
+	return nil
+}
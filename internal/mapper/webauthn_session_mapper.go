@@ -0,0 +1,13 @@
+package mapper
+
+import (
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// WebAuthnSessionMapper WebAuthn仪式映射器接口
+type WebAuthnSessionMapper interface {
+	BaseMapper
+
+	// GetByChallenge 根据挑战获取WebAuthn仪式
+	GetByChallenge(challenge string) (*model.WebAuthnSession, error)
+}
@@ -0,0 +1,126 @@
+package mapper
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// webAuthnSessionMapper WebAuthn仪式映射器实现
+type webAuthnSessionMapper struct {
+	// 使用内存存储，每个realm持有独立实例
+	mu       sync.RWMutex
+	sessions map[uint]*model.WebAuthnSession
+	nextID   uint
+}
+
+// NewWebAuthnSessionMapper 创建WebAuthnSessionMapper实例
+func NewWebAuthnSessionMapper() WebAuthnSessionMapper {
+	return &webAuthnSessionMapper{
+		sessions: make(map[uint]*model.WebAuthnSession),
+		nextID:   1,
+	}
+}
+
+// Save 保存WebAuthn仪式
+func (m *webAuthnSessionMapper) Save(entity interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := entity.(*model.WebAuthnSession)
+	if !ok {
+		return errors.New("invalid webauthn session entity")
+	}
+
+	for id, existing := range m.sessions {
+		if existing.Challenge == session.Challenge && id != session.ID {
+			return errors.New("webauthn session already exists")
+		}
+	}
+
+	// 如果是新仪式，分配ID
+	if session.ID == 0 {
+		session.ID = m.nextID
+		m.nextID++
+		session.CreatedAt = time.Now()
+	}
+
+	m.sessions[session.ID] = session
+
+	return nil
+}
+
+// DeleteByID 根据ID删除WebAuthn仪式
+func (m *webAuthnSessionMapper) DeleteByID(id interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessionID, ok := id.(uint)
+	if !ok {
+		return errors.New("invalid webauthn session id")
+	}
+
+	delete(m.sessions, sessionID)
+	return nil
+}
+
+// GetByID 根据ID获取WebAuthn仪式
+func (m *webAuthnSessionMapper) GetByID(id interface{}) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessionID, ok := id.(uint)
+	if !ok {
+		return nil, errors.New("invalid webauthn session id")
+	}
+
+	session, exists := m.sessions[sessionID]
+	if !exists {
+		return nil, errors.New("webauthn session not found")
+	}
+
+	return session, nil
+}
+
+// GetAll 获取所有WebAuthn仪式
+func (m *webAuthnSessionMapper) GetAll() ([]interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make([]interface{}, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// Update 更新WebAuthn仪式
+func (m *webAuthnSessionMapper) Update(entity interface{}) error {
+	session, ok := entity.(*model.WebAuthnSession)
+	if !ok {
+		return errors.New("invalid webauthn session entity")
+	}
+
+	if session.ID == 0 {
+		return errors.New("webauthn session id is required")
+	}
+
+	return m.Save(session)
+}
+
+// GetByChallenge 根据挑战获取WebAuthn仪式
+func (m *webAuthnSessionMapper) GetByChallenge(challenge string) (*model.WebAuthnSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, session := range m.sessions {
+		if session.Challenge == challenge {
+			return session, nil
+		}
+	}
+
+	return nil, errors.New("webauthn session not found")
+}
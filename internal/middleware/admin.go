@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// AdminRole 可访问管理接口的角色
+const AdminRole = "admin"
+
+// AdminScope 可访问管理接口的scope，只有客户端配置中允许该scope的管理客户端才能获得
+const AdminScope = "admin"
+
+// AdminAPIKeyMiddleware 管理接口认证中间件，校验X-Admin-API-Key请求头与所属realm的管理API Key一致
+// 未配置该realm的管理API Key时管理接口不可用
+func AdminAPIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authenticateAdminAPIKey(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// AdminAuthMiddleware 管理接口认证中间件，携带X-Admin-API-Key请求头时校验API Key，
+// 否则要求同时授予admin scope并持有admin角色的Bearer访问令牌；
+// 仅凭角色不足以访问，避免管理员登录第三方客户端后该客户端的令牌可以调用管理接口
+func AdminAuthMiddleware(jwtUtil util.JWTUtil, userRepo repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("X-Admin-API-Key") != "" {
+			if !authenticateAdminAPIKey(c) {
+				return
+			}
+			c.Next()
+			return
+		}
+		
+		if !authenticateBearer(c, jwtUtil, userRepo) {
+			return
+		}
+		if !hasAnyClaim(c, "scopes", []string{AdminScope}) {
+			oauthErr := service.ErrInsufficientScope("the access token does not grant the admin scope")
+			oauthErr.Scope = AdminScope
+			abortWithBearerError(c, oauthErr)
+			return
+		}
+		if !hasAnyClaim(c, "roles", []string{AdminRole}) {
+			c.AbortWithStatusJSON(http.StatusForbidden, service.ErrAccessDenied("the admin role is required"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticateAdminAPIKey 校验所属realm的管理API Key，校验失败时中止请求并返回false
+func authenticateAdminAPIKey(c *gin.Context) bool {
+	apiKey := getEnv(adminAPIKeyEnv(c), "")
+	if apiKey == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin API is disabled"})
+		return false
+	}
+	
+	provided := c.GetHeader("X-Admin-API-Key")
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin API key"})
+		return false
+	}
+	
+	return true
+}
+
+// adminAPIKeyEnv 获取请求所属realm的管理API Key配置项：默认realm使用ADMIN_API_KEY，
+// 其余realm使用ADMIN_API_KEY_{realm名称}（转为大写，连字符替换为下划线），
+// 每个realm的API Key只能管理该realm
+func adminAPIKeyEnv(c *gin.Context) string {
+	value, exists := c.Get("realm")
+	realm, _ := value.(*model.Realm)
+	if !exists || realm == nil || realm.IsDefault {
+		return "ADMIN_API_KEY"
+	}
+
+	// realm名称只包含小写字母、数字和连字符
+	return "ADMIN_API_KEY_" + strings.ToUpper(strings.ReplaceAll(realm.Name, "-", "_"))
+}
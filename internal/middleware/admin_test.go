@@ -0,0 +1,158 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/middleware"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIssuer 测试使用的issuer
+const testIssuer = "http://id.test"
+
+// newTestUserRepository 创建只有一个用户的内存用户仓库，该用户ID为1
+func newTestUserRepository(t *testing.T) (repository.UserRepository, *model.User) {
+	t.Helper()
+	userRepo := repository.NewUserRepository(nil)
+	user := &model.User{Username: "alice", Email: "alice@example.com", IsActive: true}
+	if err := userRepo.Create(user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if user.ID != 1 {
+		t.Fatalf("expected the first user to have ID 1, got %d", user.ID)
+	}
+	return userRepo, user
+}
+
+// newAdminRouter 创建只有一个管理接口的路由
+func newAdminRouter(t *testing.T) (*gin.Engine, util.JWTUtil) {
+	t.Helper()
+	t.Setenv("ISSUER_URL", testIssuer)
+	gin.SetMode(gin.TestMode)
+	jwtUtil, err := util.NewEphemeralJWTUtil()
+	if err != nil {
+		t.Fatalf("create jwt util: %v", err)
+	}
+	userRepo, _ := newTestUserRepository(t)
+	r := gin.New()
+	r.Use(middleware.IssuerMiddleware())
+	r.GET("/admin", middleware.AdminAuthMiddleware(jwtUtil, userRepo), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r, jwtUtil
+}
+
+// adminRequest 使用指定scope和角色的访问令牌调用管理接口，返回状态码
+func adminRequest(t *testing.T, r *gin.Engine, jwtUtil util.JWTUtil, scope string, roles []string) int {
+	t.Helper()
+	token, err := jwtUtil.GenerateAccessToken(&util.AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user:1",
+			Issuer:    testIssuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Audience:  []string{"third-party"},
+		},
+		Scope: scope,
+		Roles: roles,
+	})
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAdminAuthRequiresAdminScope(t *testing.T) {
+	r, jwtUtil := newAdminRouter(t)
+
+	// 管理员登录第三方客户端得到的普通令牌不能调用管理接口
+	if code := adminRequest(t, r, jwtUtil, "openid profile", []string{"admin"}); code != http.StatusForbidden {
+		t.Fatalf("openid profile token should be forbidden, got %d", code)
+	}
+	if code := adminRequest(t, r, jwtUtil, "openid admin", nil); code != http.StatusForbidden {
+		t.Fatalf("admin scope without admin role should be forbidden, got %d", code)
+	}
+	if code := adminRequest(t, r, jwtUtil, "openid admin", []string{"admin"}); code != http.StatusOK {
+		t.Fatalf("admin scope with admin role should be allowed, got %d", code)
+	}
+}
+
+// newRealmAdminRouter 创建挂载默认realm和tenant realm管理接口的路由
+func newRealmAdminRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	t.Setenv("ISSUER_URL", testIssuer)
+	gin.SetMode(gin.TestMode)
+	jwtUtil, err := util.NewEphemeralJWTUtil()
+	if err != nil {
+		t.Fatalf("create jwt util: %v", err)
+	}
+	userRepo, _ := newTestUserRepository(t)
+	r := gin.New()
+	r.Use(middleware.IssuerMiddleware())
+	for _, realm := range []*model.Realm{{Name: "default", IsDefault: true}, {Name: "tenant-a"}} {
+		group := r.Group(realm.PathPrefix())
+		group.Use(middleware.RealmMiddleware(realm))
+		group.GET("/admin", middleware.AdminAuthMiddleware(jwtUtil, userRepo), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	}
+	return r
+}
+
+// apiKeyRequest 使用管理API Key调用指定路径的管理接口，返回状态码
+func apiKeyRequest(r *gin.Engine, path, apiKey string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-Admin-API-Key", apiKey)
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAdminAPIKeyIsScopedToRealm(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "default-key")
+	t.Setenv("ADMIN_API_KEY_TENANT_A", "tenant-key")
+	r := newRealmAdminRouter(t)
+
+	tests := []struct {
+		path   string
+		apiKey string
+		status int
+	}{
+		{"/admin", "default-key", http.StatusOK},
+		{"/admin", "tenant-key", http.StatusUnauthorized},
+		{"/realms/tenant-a/admin", "tenant-key", http.StatusOK},
+		{"/realms/tenant-a/admin", "default-key", http.StatusUnauthorized},
+		{"/realms/tenant-a/admin", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if code := apiKeyRequest(r, tt.path, tt.apiKey); code != tt.status {
+			t.Fatalf("%s with %q: expected %d, got %d", tt.path, tt.apiKey, tt.status, code)
+		}
+	}
+}
+
+func TestAdminAPIKeyDisabledWithoutRealmKey(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "default-key")
+	t.Setenv("ADMIN_API_KEY_TENANT_A", "")
+	r := newRealmAdminRouter(t)
+
+	// 未配置该realm的API Key时，默认realm的API Key不会生效
+	if code := apiKeyRequest(r, "/realms/tenant-a/admin", "default-key"); code != http.StatusForbidden {
+		t.Fatalf("expected the tenant admin API key to be disabled, got %d", code)
+	}
+	if code := apiKeyRequest(r, "/admin", "default-key"); code != http.StatusOK {
+		t.Fatalf("expected the default realm API key to keep working, got %d", code)
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// IssuerMiddleware 解析当前请求对外的issuer并存入请求上下文
+// Discovery文档、令牌签发和令牌校验都从上下文读取issuer，保证三者一致
+func IssuerMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		issuer := util.ResolveIssuer(c.Request)
+		c.Request = c.Request.WithContext(util.WithIssuer(c.Request.Context(), issuer))
+		c.Set("issuer", issuer)
+		c.Next()
+	}
+}
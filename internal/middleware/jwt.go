@@ -2,57 +2,109 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
 	"github.com/Full-finger/OIDC/internal/util"
 )
 
-// JWTAuthMiddleware JWT认证中间件
-func JWTAuthMiddleware() gin.HandlerFunc {
+// JWTAuthMiddleware JWT认证中间件，使用所属realm的签名密钥校验访问令牌，
+// 并拒绝已删除或已申请删除的用户尚未过期的访问令牌
+func JWTAuthMiddleware(jwtUtil util.JWTUtil, userRepo repository.UserRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 从Authorization头获取访问令牌
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization header"})
-			c.Abort()
+		if !authenticateBearer(c, jwtUtil, userRepo) {
 			return
 		}
-		
-		// 解析Bearer令牌
-		tokenString := ""
-		if len(authHeader) > 7 && strings.HasPrefix(authHeader, "Bearer ") {
-			tokenString = authHeader[7:]
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
-			c.Abort()
-			return
-		}
-		
-		// 解析访问令牌
-		jwtUtil, err := util.NewJWTUtil()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to initialize JWT utility"})
-			c.Abort()
-			return
-		}
-		
-		claims, err := jwtUtil.ParseAccessToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid access token"})
-			c.Abort()
-			return
+		c.Next()
+	}
+}
+
+// authenticateBearer 校验Bearer访问令牌并将令牌信息写入上下文，校验失败时中止请求并返回false
+func authenticateBearer(c *gin.Context, jwtUtil util.JWTUtil, userRepo repository.UserRepository) bool {
+	// 从Authorization头获取访问令牌
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		// 未携带凭据时仅返回质询，不附带错误码 (RFC 6750 §3.1)
+		abortWithBearerError(c, &service.OAuthError{StatusCode: http.StatusUnauthorized})
+		return false
+	}
+	
+	// 解析Bearer令牌
+	tokenString := ""
+	if len(authHeader) > 7 && strings.EqualFold(authHeader[:7], "Bearer ") {
+		tokenString = authHeader[7:]
+	} else {
+		abortWithBearerError(c, service.ErrInvalidRequest("authorization header must use the Bearer scheme"))
+		return false
+	}
+	
+	// 解析访问令牌
+	if jwtUtil == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, service.ErrServerError("failed to initialize JWT utility"))
+		return false
+	}
+	
+	claims, err := jwtUtil.ParseAccessToken(tokenString)
+	if err != nil {
+		abortWithBearerError(c, service.ErrInvalidToken("the access token is invalid or expired"))
+		return false
+	}
+	
+	// 校验签发者与当前issuer一致
+	if claims.Issuer != util.IssuerFromContext(c.Request.Context()) {
+		abortWithBearerError(c, service.ErrInvalidToken("the access token was issued by a different issuer"))
+		return false
+	}
+	
+	// 从声明中提取用户ID (通过Subject字段)
+	if claims.Subject == "" {
+		abortWithBearerError(c, service.ErrInvalidToken("the access token has no subject"))
+		return false
+	}
+	
+	// 访问令牌在有效期内不会撤销，用户删除或申请删除账户后须在此拒绝
+	if userID, ok := subjectUserID(claims.Subject); ok && userRepo != nil {
+		user, err := userRepo.GetByID(userID)
+		if err != nil || user == nil || user.DeletionScheduledAt != nil {
+			abortWithBearerError(c, service.ErrInvalidToken("the user account has been deleted"))
+			return false
 		}
-		
-		// 从声明中提取用户ID (通过Subject字段)
-		if claims.Subject == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid access token: missing subject"})
-			c.Abort()
-			return
+	}
+	
+	// 将用户ID、授予的scopes、角色和用户组以及authorization_details存储到上下文中
+	c.Set("user_id", claims.Subject)
+	c.Set("scopes", strings.Fields(claims.Scope))
+	c.Set("roles", claims.Roles)
+	c.Set("groups", claims.Groups)
+	c.Set("authorization_details", claims.AuthorizationDetails)
+	return true
+}
+
+// subjectUserID 解析访问令牌subject中的用户ID，兼容第一方登录令牌的"N"与OAuth令牌的"user:N"
+func subjectUserID(subject string) (uint, bool) {
+	userID, err := strconv.ParseUint(strings.TrimPrefix(subject, "user:"), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// abortWithBearerError 中止请求并返回带Bearer质询的错误响应 (RFC 6750 §3)
+func abortWithBearerError(c *gin.Context, oauthErr *service.OAuthError) {
+	realm := "oauth"
+	if value, exists := c.Get("realm"); exists {
+		if r, ok := value.(*model.Realm); ok && r != nil {
+			realm = r.Name
 		}
-		
-		// 将用户ID存储到上下文中
-		c.Set("user_id", claims.Subject)
-		c.Next()
 	}
-}
\ No newline at end of file
+	c.Header("WWW-Authenticate", oauthErr.WWWAuthenticate("Bearer", realm))
+	if oauthErr.Code == "" {
+		c.AbortWithStatus(oauthErr.StatusCode)
+		return
+	}
+	c.AbortWithStatusJSON(oauthErr.StatusCode, oauthErr)
+}
@@ -0,0 +1,136 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/middleware"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newProtectedRouter 创建只有一个需要访问令牌的接口的路由
+func newProtectedRouter(t *testing.T, userRepo repository.UserRepository) (*gin.Engine, util.JWTUtil) {
+	t.Helper()
+	t.Setenv("ISSUER_URL", testIssuer)
+	gin.SetMode(gin.TestMode)
+	jwtUtil, err := util.NewEphemeralJWTUtil()
+	if err != nil {
+		t.Fatalf("create jwt util: %v", err)
+	}
+	r := gin.New()
+	r.Use(middleware.IssuerMiddleware())
+	r.GET("/me", middleware.JWTAuthMiddleware(jwtUtil, userRepo), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("user_id"))
+	})
+	return r, jwtUtil
+}
+
+// bearerRequest 使用指定subject的访问令牌调用接口，返回响应
+func bearerRequest(t *testing.T, r *gin.Engine, jwtUtil util.JWTUtil, subject string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := jwtUtil.GenerateAccessToken(&util.AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    testIssuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Scope: "openid profile",
+	})
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestJWTAuthMiddlewareReturnsBearerChallenges(t *testing.T) {
+	t.Setenv("ISSUER_URL", "https://id.example.com")
+	gin.SetMode(gin.TestMode)
+	jwtUtil, err := util.NewEphemeralJWTUtil()
+	if err != nil {
+		t.Fatalf("NewEphemeralJWTUtil: %v", err)
+	}
+	r := gin.New()
+	r.Use(middleware.IssuerMiddleware())
+	r.GET("/protected", middleware.JWTAuthMiddleware(jwtUtil, nil), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantChallenge string
+	}{
+		{"missing token", "", http.StatusUnauthorized, `Bearer realm="oauth"`},
+		{"invalid token", "Bearer not-a-jwt", http.StatusUnauthorized, `Bearer realm="oauth", error="invalid_token"`},
+		{"wrong scheme", "Token abc", http.StatusBadRequest, `Bearer realm="oauth", error="invalid_request"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			got := w.Header().Get("WWW-Authenticate")
+			if got != tt.wantChallenge && !strings.HasPrefix(got, tt.wantChallenge+",") {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+		})
+	}
+}
+
+func TestJWTAuthAcceptsActiveUser(t *testing.T) {
+	userRepo, _ := newTestUserRepository(t)
+	r, jwtUtil := newProtectedRouter(t, userRepo)
+
+	// 兼容OAuth令牌的"user:N"与第一方登录令牌的"N"
+	for _, subject := range []string{"user:1", "1"} {
+		if w := bearerRequest(t, r, jwtUtil, subject); w.Code != http.StatusOK || w.Body.String() != subject {
+			t.Fatalf("subject %s: expected 200, got %d %s", subject, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestJWTAuthRejectsDeletedUsers(t *testing.T) {
+	userRepo, user := newTestUserRepository(t)
+	r, jwtUtil := newProtectedRouter(t, userRepo)
+
+	// 申请删除后宽限期内的访问令牌同样失效
+	deletionAt := time.Now().Add(30 * 24 * time.Hour)
+	user.DeletionScheduledAt = &deletionAt
+	if err := userRepo.Update(user); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	w := bearerRequest(t, r, jwtUtil, "user:1")
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("pending deletion: expected 401 with a Bearer challenge, got %d", w.Code)
+	}
+
+	if err := userRepo.Delete(user.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	for _, subject := range []string{"user:1", "1", "user:2"} {
+		if w := bearerRequest(t, r, jwtUtil, subject); w.Code != http.StatusUnauthorized {
+			t.Fatalf("subject %s: expected 401 for a deleted user, got %d", subject, w.Code)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+// RequireRoles 角色授权中间件，要求访问令牌至少包含一个指定角色，需配合JWTAuthMiddleware使用
+// 令牌有效但缺少角色时返回403 access_denied，不附带Bearer质询
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasAnyClaim(c, "roles", roles) {
+			c.AbortWithStatusJSON(http.StatusForbidden, service.ErrAccessDenied("the user does not have a required role"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireGroups 用户组授权中间件，要求用户至少属于一个指定用户组，需配合JWTAuthMiddleware使用
+func RequireGroups(groups ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !hasAnyClaim(c, "groups", groups) {
+			c.AbortWithStatusJSON(http.StatusForbidden, service.ErrAccessDenied("the user is not a member of a required group"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// hasAnyClaim 判断上下文中的角色或用户组是否包含任意一个期望值
+func hasAnyClaim(c *gin.Context, key string, expected []string) bool {
+	value, exists := c.Get(key)
+	if !exists {
+		return false
+	}
+	granted, ok := value.([]string)
+	if !ok {
+		return false
+	}
+	
+	for _, g := range granted {
+		for _, e := range expected {
+			if g == e {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// RealmMiddleware 将请求绑定到指定realm
+// realm的issuer由基础issuer加上realm路由前缀构成，需在IssuerMiddleware之后使用
+func RealmMiddleware(realm *model.Realm) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		issuer := util.IssuerFromContext(c.Request.Context()) + realm.PathPrefix()
+		c.Request = c.Request.WithContext(util.WithIssuer(c.Request.Context(), issuer))
+		c.Set("issuer", issuer)
+		c.Set("realm", realm)
+		c.Next()
+	}
+}
@@ -0,0 +1,143 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Full-finger/OIDC/internal/middleware"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// realmFixture 单个realm的签名密钥和仓库
+type realmFixture struct {
+	realm   *model.Realm
+	jwtUtil util.JWTUtil
+	clients repository.ClientRepository
+	users   repository.UserRepository
+}
+
+func newRealmFixture(t *testing.T, id uint, name string, isDefault bool, jwtUtil util.JWTUtil) *realmFixture {
+	t.Helper()
+	if jwtUtil == nil {
+		var err error
+		jwtUtil, err = util.NewEphemeralJWTUtil()
+		if err != nil {
+			t.Fatalf("NewEphemeralJWTUtil: %v", err)
+		}
+	}
+	return &realmFixture{
+		realm:   &model.Realm{ID: id, Name: name, IsDefault: isDefault},
+		jwtUtil: jwtUtil,
+		clients: repository.NewClientRepository(id),
+		users:   repository.NewUserRepository(nil),
+	}
+}
+
+// newRealmRouter 按生产环境的方式挂载两个realm的受保护路由
+func newRealmRouter(realms ...*realmFixture) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.IssuerMiddleware())
+	for _, f := range realms {
+		group := r.Group(f.realm.PathPrefix(), middleware.RealmMiddleware(f.realm))
+		group.GET("/api/v1/collection/", middleware.JWTAuthMiddleware(f.jwtUtil, nil), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id")})
+		})
+	}
+	return r
+}
+
+func issueToken(t *testing.T, f *realmFixture, issuer string) string {
+	t.Helper()
+	token, err := f.jwtUtil.GenerateAccessToken(&util.AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   "1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	return token
+}
+
+func callCollection(r *gin.Engine, path, token string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRealmIsolationRejectsForeignAccessToken(t *testing.T) {
+	t.Setenv("ISSUER_URL", "https://id.example.com")
+
+	realmA := newRealmFixture(t, 1, "default", true, nil)
+	realmB := newRealmFixture(t, 2, "anime", false, nil)
+	// realmC与realmA共用签名密钥，只能依靠issuer区分
+	realmC := newRealmFixture(t, 3, "shared-key", false, realmA.jwtUtil)
+	r := newRealmRouter(realmA, realmB, realmC)
+
+	tokenA := issueToken(t, realmA, "https://id.example.com")
+
+	if code := callCollection(r, "/api/v1/collection/", tokenA); code != http.StatusOK {
+		t.Fatalf("token in its own realm: status = %d, want 200", code)
+	}
+	if code := callCollection(r, "/realms/anime/api/v1/collection/", tokenA); code != http.StatusUnauthorized {
+		t.Errorf("token from realm A in realm B: status = %d, want 401", code)
+	}
+	if code := callCollection(r, "/realms/shared-key/api/v1/collection/", tokenA); code != http.StatusUnauthorized {
+		t.Errorf("token from realm A in realm with same key: status = %d, want 401", code)
+	}
+
+	tokenB := issueToken(t, realmB, "https://id.example.com/realms/anime")
+	if code := callCollection(r, "/realms/anime/api/v1/collection/", tokenB); code != http.StatusOK {
+		t.Fatalf("token in its own realm: status = %d, want 200", code)
+	}
+	if code := callCollection(r, "/api/v1/collection/", tokenB); code != http.StatusUnauthorized {
+		t.Errorf("token from realm B in realm A: status = %d, want 401", code)
+	}
+}
+
+func TestRealmIsolationSeparatesClientsAndUsers(t *testing.T) {
+	ctx := context.Background()
+	realmA := newRealmFixture(t, 1, "default", true, nil)
+	realmB := newRealmFixture(t, 2, "anime", false, nil)
+
+	if err := realmA.clients.Create(ctx, &model.Client{ClientID: "shared_id", Name: "A"}); err != nil {
+		t.Fatalf("create client in realm A: %v", err)
+	}
+	if _, err := realmB.clients.GetByClientID(ctx, "shared_id"); err == nil {
+		t.Error("client from realm A must not be visible in realm B")
+	}
+	if err := realmB.clients.Create(ctx, &model.Client{ClientID: "shared_id", Name: "B"}); err != nil {
+		t.Fatalf("same client_id in realm B: %v", err)
+	}
+	client, err := realmA.clients.GetByClientID(ctx, "shared_id")
+	if err != nil || client.Name != "A" || client.RealmID != 1 {
+		t.Errorf("realm A client = %+v, %v; want realm A's own client", client, err)
+	}
+
+	if err := realmA.users.Create(&model.User{ID: 1, Username: "alice", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("create user in realm A: %v", err)
+	}
+	if _, err := realmB.users.GetByUsername("alice"); err == nil {
+		t.Error("user from realm A must not be visible in realm B by username")
+	}
+	if _, err := realmB.users.GetByEmail("alice@example.com"); err == nil {
+		t.Error("user from realm A must not be visible in realm B by email")
+	}
+	if _, err := realmB.users.GetByID(1); err == nil {
+		t.Error("user from realm A must not be visible in realm B by id")
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestMetadataMiddleware 将客户端IP和User-Agent存入请求上下文，服务层记录审计事件时读取
+func RequestMetadataMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		metadata := util.RequestMetadata{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+		c.Request = c.Request.WithContext(util.WithRequestMetadata(c.Request.Context(), metadata))
+		c.Next()
+	}
+}
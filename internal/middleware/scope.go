@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+// RequireScopes scope授权中间件，要求访问令牌包含全部指定scope，需配合JWTAuthMiddleware使用
+// 缺少scope时返回403 insufficient_scope (RFC 6750 §3.1)
+func RequireScopes(required ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := map[string]bool{}
+		if value, exists := c.Get("scopes"); exists {
+			if scopes, ok := value.([]string); ok {
+				for _, scope := range scopes {
+					granted[scope] = true
+				}
+			}
+		}
+		
+		for _, scope := range required {
+			if !granted[scope] {
+				oauthErr := service.ErrInsufficientScope("the access token does not grant the required scope")
+				oauthErr.Scope = strings.Join(required, " ")
+				abortWithBearerError(c, oauthErr)
+				return
+			}
+		}
+		
+		c.Next()
+	}
+}
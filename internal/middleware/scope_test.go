@@ -0,0 +1,130 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/Full-finger/OIDC/internal/middleware"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// newScopeRouter 创建要求anime:write与collection:write两个scope的受保护路由
+func newScopeRouter(t *testing.T) (*gin.Engine, util.JWTUtil) {
+	t.Helper()
+	t.Setenv("ISSUER_URL", "https://id.example.com")
+	gin.SetMode(gin.TestMode)
+	jwtUtil, err := util.NewEphemeralJWTUtil()
+	if err != nil {
+		t.Fatalf("NewEphemeralJWTUtil: %v", err)
+	}
+	r := gin.New()
+	r.Use(middleware.IssuerMiddleware())
+	r.GET("/single", middleware.JWTAuthMiddleware(jwtUtil, nil), middleware.RequireScopes("anime:write"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.GET("/multiple", middleware.JWTAuthMiddleware(jwtUtil, nil), middleware.RequireScopes("anime:write", "collection:write"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r, jwtUtil
+}
+
+func scopedToken(t *testing.T, jwtUtil util.JWTUtil, scope string) string {
+	t.Helper()
+	token, err := jwtUtil.GenerateAccessToken(&util.AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://id.example.com",
+			Subject:   "1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		Scope: scope,
+	})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	return token
+}
+
+func TestRequireScopes(t *testing.T) {
+	r, jwtUtil := newScopeRouter(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		scope      string
+		wantStatus int
+	}{
+		{"single scope granted", "/single", "openid anime:write", http.StatusOK},
+		{"single scope missing", "/single", "openid anime:read", http.StatusForbidden},
+		{"no scopes", "/single", "", http.StatusForbidden},
+		{"all scopes granted", "/multiple", "collection:write anime:write", http.StatusOK},
+		{"only first scope granted", "/multiple", "anime:write", http.StatusForbidden},
+		{"only second scope granted", "/multiple", "collection:write", http.StatusForbidden},
+		{"scope prefix is not a match", "/single", "anime:writer anime", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+scopedToken(t, jwtUtil, tt.scope))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			challenge := w.Header().Get("WWW-Authenticate")
+			if tt.wantStatus == http.StatusOK {
+				if challenge != "" {
+					t.Errorf("WWW-Authenticate = %q, want none on success", challenge)
+				}
+				return
+			}
+
+			if !strings.HasPrefix(challenge, `Bearer realm="oauth", error="insufficient_scope"`) {
+				t.Errorf("WWW-Authenticate = %q, want insufficient_scope Bearer challenge", challenge)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body["error"] != "insufficient_scope" {
+				t.Errorf("error = %q, want insufficient_scope", body["error"])
+			}
+		})
+	}
+}
+
+func TestRequireScopesAdvertisesRequiredScopes(t *testing.T) {
+	r, jwtUtil := newScopeRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/multiple", nil)
+	req.Header.Set("Authorization", "Bearer "+scopedToken(t, jwtUtil, "anime:write"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if challenge := w.Header().Get("WWW-Authenticate"); !strings.Contains(challenge, `scope="anime:write collection:write"`) {
+		t.Errorf("WWW-Authenticate = %q, want the required scopes", challenge)
+	}
+}
+
+func TestRequireScopesWithoutAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/unauthenticated", middleware.RequireScopes("anime:write"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unauthenticated", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403 when no scopes were granted", w.Code)
+	}
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"time"
+)
+
+// 安全审计事件类型
+const (
+	AuditLoginSucceeded    = "login.succeeded"            // 用户完成登录并创建会话
+	AuditLoginFailed       = "login.failed"               // 密码或两步验证错误，以及被登录防护拒绝的尝试
+	AuditLogout            = "logout"                     // 用户退出登录
+	AuditTokenIssued       = "token.issued"               // 授权码或CIBA认证请求兑换令牌
+	AuditTokenRefreshed    = "token.refreshed"            // 使用刷新令牌换取新令牌
+	AuditTokenRevoked      = "token.revoked"              // 撤销用户的全部刷新令牌和登录会话
+	AuditConsentGranted    = "consent.granted"            // 用户同意客户端的授权请求
+	AuditConsentDenied     = "consent.denied"             // 用户拒绝客户端的授权请求
+	AuditClientRegistered  = "client.registered"          // 加载realm配置中的客户端
+	AuditAccountLinked     = "account.linked"             // 绑定Bangumi账号或关联上游身份
+	AuditAccountUnlinked   = "account.unlinked"           // 解绑Bangumi账号或取消关联上游身份
+	AuditDeletionScheduled = "account.deletion_scheduled" // 申请删除账户
+	AuditDeletionCancelled = "account.deletion_cancelled" // 取消删除账户
+)
+
+// 审计事件结果
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent 安全审计事件，只追加不修改
+type AuditEvent struct {
+	ID        uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	RealmID   uint              `gorm:"not null;index" json:"realm_id"`
+	Type      string            `gorm:"not null;size:64;index" json:"type"`
+	Outcome   string            `gorm:"not null;size:16" json:"outcome"`
+	UserID    uint              `gorm:"index" json:"user_id,omitempty"` // 事件涉及的用户，无法确定用户时为0
+	Actor     string            `gorm:"size:255" json:"actor"`          // 发起者：user:{id}、client:{client_id}、system，登录失败时为尝试的用户名
+	ClientID  string            `gorm:"size:255;index" json:"client_id,omitempty"`
+	IP        string            `gorm:"size:64" json:"ip,omitempty"`
+	UserAgent string            `gorm:"type:text" json:"user_agent,omitempty"`
+	Details   map[string]string `gorm:"type:text;serializer:json" json:"details,omitempty"` // 补充信息，如grant_type、失败原因
+	CreatedAt time.Time         `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定AuditEvent表名
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// AuditEventFilter 审计事件查询条件，零值字段不参与过滤
+type AuditEventFilter struct {
+	UserID   uint
+	ClientID string
+	Type     string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// Matches 判断事件是否满足查询条件（不考虑Limit）
+func (f *AuditEventFilter) Matches(event *AuditEvent) bool {
+	if f.UserID != 0 && event.UserID != f.UserID {
+		return false
+	}
+	if f.ClientID != "" && event.ClientID != f.ClientID {
+		return false
+	}
+	if f.Type != "" && event.Type != f.Type {
+		return false
+	}
+	if !f.Since.IsZero() && event.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && event.CreatedAt.After(f.Until) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// 已注册的authorization_details类型
+const (
+	AuthorizationDetailTypeCollectionAccess = "collection_access"
+)
+
+// collection_access类型支持的操作
+const (
+	CollectionActionRead           = "read"
+	CollectionActionAdd            = "add"
+	CollectionActionUpdate         = "update"
+	CollectionActionUpdateProgress = "update_progress"
+	CollectionActionRemove         = "remove"
+)
+
+// AuthorizationDetail 细粒度授权请求中的一项 (RFC 9396 §2)
+type AuthorizationDetail struct {
+	Type       string   `json:"type"`
+	Locations  []string `json:"locations,omitempty"`
+	Actions    []string `json:"actions,omitempty"`
+	Datatypes  []string `json:"datatypes,omitempty"`
+	Identifier string   `json:"identifier,omitempty"`
+	Privileges []string `json:"privileges,omitempty"`
+	Statuses   []string `json:"statuses,omitempty"` // collection_access专用：限定可访问的收藏状态，为空表示不限
+}
+
+// Allows 判断该项是否允许对指定状态的资源执行操作，status为空表示不限定状态
+func (d *AuthorizationDetail) Allows(action, status string) bool {
+	if !containsValue(d.Actions, action) {
+		return false
+	}
+	return status == "" || len(d.Statuses) == 0 || containsValue(d.Statuses, status)
+}
+
+// Covers 判断该项授予的权限是否包含另一项请求的全部权限
+func (d *AuthorizationDetail) Covers(other *AuthorizationDetail) bool {
+	if d.Type != other.Type || d.Identifier != other.Identifier {
+		return false
+	}
+	for _, action := range other.Actions {
+		if !containsValue(d.Actions, action) {
+			return false
+		}
+	}
+	// 未限定状态的授权覆盖任意状态，限定状态的授权不能覆盖未限定状态的请求
+	if len(d.Statuses) > 0 {
+		if len(other.Statuses) == 0 {
+			return false
+		}
+		for _, status := range other.Statuses {
+			if !containsValue(d.Statuses, status) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParseAuthorizationDetails 解析authorization_details参数，必须是JSON对象数组且不允许未知字段
+func ParseAuthorizationDetails(raw string) ([]AuthorizationDetail, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewBufferString(raw))
+	decoder.DisallowUnknownFields()
+
+	var details []AuthorizationDetail
+	if err := decoder.Decode(&details); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after authorization_details")
+	}
+	return details, nil
+}
+
+// MarshalAuthorizationDetails 将authorization_details序列化为JSON字符串，空列表返回空字符串
+func MarshalAuthorizationDetails(details []AuthorizationDetail) string {
+	if len(details) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// containsValue 判断列表中是否包含指定值
+func containsValue(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
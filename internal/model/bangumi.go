@@ -23,4 +23,31 @@ type BangumiAccount struct {
 // TableName 指定BangumiAccount表名
 func (BangumiAccount) TableName() string {
 	return "bangumi_accounts"
-}
\ No newline at end of file
+}
+
+// BangumiPendingLogin 使用Bangumi登录但尚未关联本地用户的登录，用户选择创建新账户或关联已有账户后完成登录
+type BangumiPendingLogin struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Token           string    `gorm:"uniqueIndex;not null" json:"token"`               // 提交给创建或关联接口的凭据
+	BangumiUserID   uint      `gorm:"not null" json:"bangumi_user_id"`                 // Bangumi平台用户ID
+	BangumiUsername string    `gorm:"size:255" json:"bangumi_username"`                // Bangumi用户名
+	Nickname        string    `gorm:"size:255" json:"nickname"`                        // Bangumi昵称
+	AvatarURL       string    `gorm:"type:text" json:"avatar_url"`                     // Bangumi头像
+	AccessToken     string    `gorm:"not null" json:"access_token"`                    // Bangumi访问令牌
+	RefreshToken    string    `gorm:"not null" json:"refresh_token"`                   // Bangumi刷新令牌
+	TokenExpiresAt  time.Time `gorm:"not null" json:"token_expires_at"`                // Bangumi令牌过期时间
+	Scope           string    `gorm:"type:text" json:"scope"`                          // 授权范围
+	ReturnTo        string    `gorm:"type:text" json:"return_to"`                      // 登录完成后跳转的地址
+	ExpiresAt       time.Time `gorm:"not null" json:"expires_at"`                      // 过期时间
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`                // 创建时间
+}
+
+// TableName 指定BangumiPendingLogin表名
+func (BangumiPendingLogin) TableName() string {
+	return "bangumi_pending_logins"
+}
+
+// IsExpired 判断待完成的登录是否已过期
+func (p *BangumiPendingLogin) IsExpired() bool {
+	return time.Now().After(p.ExpiresAt)
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+)
+
+// CIBA认证请求状态
+const (
+	BackchannelStatusPending  = "pending"
+	BackchannelStatusApproved = "approved"
+	BackchannelStatusDenied   = "denied"
+)
+
+// CIBA令牌交付模式 (OpenID CIBA Core §5)
+const (
+	BackchannelDeliveryPoll = "poll"
+	BackchannelDeliveryPing = "ping"
+)
+
+// BackchannelAuthRequest CIBA后端通道认证请求，由客户端发起、用户在其他设备上批准
+type BackchannelAuthRequest struct {
+	ID                      uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AuthReqID               string    `gorm:"uniqueIndex;not null" json:"auth_req_id"`
+	ClientID                string    `gorm:"not null" json:"client_id"`
+	UserID                  uint      `gorm:"not null" json:"user_id"`
+	Scopes                  string    `gorm:"not null" json:"scopes"`
+	BindingMessage          string    `gorm:"type:text" json:"binding_message"`               // 同时展示在客户端设备和批准页面上，供用户核对
+	DeliveryMode            string    `gorm:"size:16;not null" json:"delivery_mode"`          // poll 或 ping
+	ClientNotificationToken string    `gorm:"type:text" json:"-"`                             // ping模式回调客户端时使用的Bearer令牌
+	Status                  string    `gorm:"size:16;not null;default:pending" json:"status"` // pending/approved/denied
+	Interval                int       `gorm:"not null" json:"interval"`                       // 最小轮询间隔（秒）
+	LastPolledAt            time.Time `json:"last_polled_at"`
+	AuthTime                time.Time `json:"auth_time"`            // 用户批准的时间
+	AMR                     string    `gorm:"type:text" json:"amr"` // 用户批准时登录会话完成的认证方式，空格分隔
+	ExpiresAt               time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt               time.Time `json:"created_at"`
+	UpdatedAt               time.Time `json:"updated_at"`
+}
+
+// TableName 指定BackchannelAuthRequest表名
+func (BackchannelAuthRequest) TableName() string {
+	return "backchannel_auth_requests"
+}
+
+// IsExpired 判断认证请求是否已过期
+func (r *BackchannelAuthRequest) IsExpired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"time"
+)
+
+// IdentityProvider 上游OpenID Connect身份提供方，用户可使用其账号登录本realm
+type IdentityProvider struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RealmID      uint      `gorm:"not null;uniqueIndex:idx_identity_providers_realm_name" json:"realm_id"`
+	Name         string    `gorm:"not null;size:64;uniqueIndex:idx_identity_providers_realm_name" json:"name"` // 路由别名 /federation/{name}
+	DisplayName  string    `gorm:"size:255" json:"display_name"`                                               // 登录页展示名称
+	Issuer       string    `gorm:"type:text;not null" json:"issuer"`                                           // 上游issuer，用于发现端点和校验ID Token
+	ClientID     string    `gorm:"not null" json:"client_id"`                                                  // 在上游登记的客户端ID
+	ClientSecret string    `gorm:"type:text" json:"client_secret,omitempty"`                                   // 在上游登记的客户端密钥
+	Scopes       string    `gorm:"type:text" json:"scopes,omitempty"`                                          // 请求的scope，空格分隔，默认openid profile email
+	LinkByEmail  bool      `gorm:"default:false" json:"link_by_email"`                                         // 上游邮箱已验证且与本地用户一致时自动关联
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 指定IdentityProvider表名
+func (IdentityProvider) TableName() string {
+	return "identity_providers"
+}
+
+// ExternalIdentity 本地用户与上游身份的关联，同一上游身份只能关联一个本地用户，一个用户可关联多个上游身份
+type ExternalIdentity struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID         uint       `gorm:"not null;index" json:"user_id"`
+	Provider       string     `gorm:"not null;size:64;uniqueIndex:idx_external_identities_provider_subject" json:"provider"` // 上游身份提供方名称，Bangumi为bangumi
+	Subject        string     `gorm:"not null;uniqueIndex:idx_external_identities_provider_subject" json:"subject"`          // 上游用户标识，如ID Token中的sub
+	Email          string     `gorm:"size:255" json:"email,omitempty"`
+	Username       string     `gorm:"size:255" json:"username,omitempty"`     // 上游用户名
+	DisplayName    string     `gorm:"size:255" json:"display_name,omitempty"` // 上游展示名称
+	AvatarURL      string     `gorm:"type:text" json:"avatar_url,omitempty"`
+	Profile        string     `gorm:"type:text" json:"profile,omitempty"` // 最近一次登录时上游返回的用户信息快照（JSON）
+	AccessToken    string     `gorm:"type:text" json:"-"`                 // 上游访问令牌
+	RefreshToken   string     `gorm:"type:text" json:"-"`                 // 上游刷新令牌
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty"`
+	Scope          string     `gorm:"type:text" json:"scope,omitempty"`
+	LastLoginAt    *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定ExternalIdentity表名
+func (ExternalIdentity) TableName() string {
+	return "external_identities"
+}
+
+// FederatedLoginState 进行中的上游登录，state只能使用一次
+type FederatedLoginState struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	State        string    `gorm:"uniqueIndex;not null" json:"state"`
+	Provider     string    `gorm:"not null;size:64" json:"provider"`
+	Nonce        string    `gorm:"not null" json:"nonce"`
+	CodeVerifier string    `gorm:"not null" json:"code_verifier"`
+	ReturnTo     string    `gorm:"type:text" json:"return_to"`    // 登录完成后跳转的地址，通常为原始授权请求
+	LinkUserID   uint      `gorm:"default:0" json:"link_user_id"` // 非0时为已登录用户关联上游身份，而不是登录
+	BindingHash  string    `gorm:"size:64" json:"-"`              // 写入发起登录的浏览器Cookie的随机值的SHA-256哈希，回调时校验以防止登录CSRF
+	ExpiresAt    time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定FederatedLoginState表名
+func (FederatedLoginState) TableName() string {
+	return "federated_login_states"
+}
+
+// IsExpired 判断上游登录是否已过期
+func (s *FederatedLoginState) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
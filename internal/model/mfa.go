@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+)
+
+// TOTPCredential 用户的TOTP认证器 (RFC 6238)，用户输入验证码确认后才启用
+type TOTPCredential struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	Secret       string     `gorm:"not null" json:"-"`      // Base32编码的共享密钥
+	LastUsedStep int64      `gorm:"default:0" json:"-"`     // 最近一次验证通过的时间步，同一验证码不能重复使用
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"` // 确认时间，未确认的认证器不参与登录
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// IsConfirmed 判断认证器是否已确认启用
+func (c *TOTPCredential) IsConfirmed() bool {
+	return c.ConfirmedAt != nil
+}
+
+// RecoveryCode 两步验证恢复码，只保存哈希，每个恢复码只能使用一次
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MFAChallenge 已完成第一步认证、等待输入第二步验证码的登录
+type MFAChallenge struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Token     string    `gorm:"uniqueIndex;not null" json:"token"`
+	UserID    uint      `gorm:"not null" json:"user_id"`
+	AMR       string    `gorm:"type:text" json:"amr"`      // 已完成的认证方式，空格分隔
+	Attempts  int       `gorm:"default:0" json:"attempts"` // 验证码错误次数
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsExpired 判断挑战是否已过期
+func (c *MFAChallenge) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
@@ -1,44 +1,128 @@
 package model
 
 import (
+	"strings"
 	"time"
 )
 
 // Client OAuth2客户端实体
 type Client struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	ClientID    string    `gorm:"uniqueIndex;not null" json:"client_id"`
-	SecretHash  string    `gorm:"not null" json:"secret_hash"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `gorm:"type:text" json:"description"`
-	RedirectURI string    `gorm:"not null" json:"redirect_uri"`
-	Scopes      string    `gorm:"not null" json:"scopes"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RealmID      uint      `gorm:"not null;uniqueIndex:idx_clients_realm_client" json:"realm_id"`
+	ClientID     string    `gorm:"not null;uniqueIndex:idx_clients_realm_client" json:"client_id"`
+	SecretHash   string    `gorm:"not null" json:"secret_hash"`
+	Name         string    `gorm:"not null" json:"name"`
+	Description  string    `gorm:"type:text" json:"description"`
+	RedirectURI  string    `gorm:"not null" json:"redirect_uri"`
+	RedirectURIs string    `gorm:"type:text" json:"redirect_uris,omitempty"` // 额外登记的重定向URI，空格分隔
+	Scopes       string    `gorm:"not null" json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// CIBA配置，未设置交付模式的客户端不能发起后端通道认证
+	BackchannelTokenDeliveryMode          string `gorm:"size:16" json:"backchannel_token_delivery_mode,omitempty"`            // poll 或 ping
+	BackchannelClientNotificationEndpoint string `gorm:"type:text" json:"backchannel_client_notification_endpoint,omitempty"` // ping模式的回调地址
+
+	// 允许请求的authorization_details类型，空格分隔，为空表示允许全部已注册类型 (RFC 9396 §10)
+	AuthorizationDetailsTypes string `gorm:"type:text" json:"authorization_details_types,omitempty"`
+
+	// 令牌端点认证方式：client_secret_basic（默认）、client_secret_post或none（公开客户端）
+	TokenEndpointAuthMethod string `gorm:"size:32" json:"token_endpoint_auth_method,omitempty"`
+	// 是否要求授权码流程必须使用PKCE，未设置时沿用全局配置，公开客户端始终要求
+	RequirePKCE *bool `json:"require_pkce,omitempty"`
+
+	// 令牌有效期策略，未设置的项沿用realm配置
+	TokenPolicy TokenLifetimePolicy `gorm:"embedded;embeddedPrefix:token_" json:"token_policy"`
+}
+
+// RegisteredRedirectURIs 获取客户端登记的全部重定向URI
+func (c *Client) RegisteredRedirectURIs() []string {
+	uris := strings.Fields(c.RedirectURIs)
+	if c.RedirectURI != "" {
+		uris = append([]string{c.RedirectURI}, uris...)
+	}
+	return uris
+}
+
+// 令牌端点认证方式 (RFC 7591 §2)
+const (
+	TokenEndpointAuthClientSecretBasic = "client_secret_basic"
+	TokenEndpointAuthClientSecretPost  = "client_secret_post"
+	TokenEndpointAuthNone              = "none"
+)
+
+// IsPublic 判断是否为公开客户端，公开客户端无法保存密钥（原生应用、单页应用），以token_endpoint_auth_method=none登记
+func (c *Client) IsPublic() bool {
+	return c.TokenEndpointAuthMethod == TokenEndpointAuthNone
 }
 
 // AuthorizationCode OAuth2授权码实体
 type AuthorizationCode struct {
-	ID                 uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Code               string    `gorm:"uniqueIndex;not null" json:"code"`
-	ClientID           string    `gorm:"not null" json:"client_id"`
-	UserID             uint      `gorm:"not null" json:"user_id"`
-	RedirectURI        string    `gorm:"not null" json:"redirect_uri"`
-	Scopes             string    `gorm:"not null" json:"scopes"`
-	CodeChallenge      string    `gorm:"type:text" json:"code_challenge"`
-	CodeChallengeMethod string   `gorm:"type:text" json:"code_challenge_method"`
-	ExpiresAt          time.Time `gorm:"not null" json:"expires_at"`
-	CreatedAt          time.Time `json:"created_at"`
+	ID                   uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code                 string    `gorm:"uniqueIndex;not null" json:"code"`
+	ClientID             string    `gorm:"not null" json:"client_id"`
+	UserID               uint      `gorm:"not null" json:"user_id"`
+	RedirectURI          string    `gorm:"not null" json:"redirect_uri"`
+	Scopes               string    `gorm:"not null" json:"scopes"`
+	CodeChallenge        string    `gorm:"type:text" json:"code_challenge"`
+	CodeChallengeMethod  string    `gorm:"type:text" json:"code_challenge_method"`
+	Nonce                string    `gorm:"type:text" json:"nonce"`
+	AuthTime             time.Time `json:"auth_time"`
+	AMR                  string    `gorm:"type:text" json:"amr"`                   // 登录会话完成的认证方式，空格分隔
+	AuthorizationDetails string    `gorm:"type:text" json:"authorization_details"` // 用户批准的authorization_details，JSON数组
+	ExpiresAt            time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt            time.Time `json:"created_at"`
 }
 
 // RefreshToken OAuth2刷新令牌实体
 type RefreshToken struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	TokenHash   string    `gorm:"uniqueIndex;not null" json:"token_hash"`
-	UserID      uint      `gorm:"not null" json:"user_id"`
-	ClientID    string    `gorm:"not null" json:"client_id"`
-	Scopes      string    `gorm:"type:text" json:"scopes"`
-	ExpiresAt   time.Time `gorm:"not null" json:"expires_at"`
-	RevokedAt   time.Time `gorm:"index" json:"revoked_at"`
-	CreatedAt   time.Time `json:"created_at"`
-}
\ No newline at end of file
+	ID                   uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	TokenHash            string    `gorm:"uniqueIndex;not null" json:"token_hash"`
+	UserID               uint      `gorm:"not null" json:"user_id"`
+	ClientID             string    `gorm:"not null" json:"client_id"`
+	Scopes               string    `gorm:"type:text" json:"scopes"`
+	AuthorizationDetails string    `gorm:"type:text" json:"authorization_details"`
+	AuthTime             time.Time `json:"auth_time"`                           // 用户最初完成认证的时间，刷新后签发的ID Token沿用
+	AMR                  string    `gorm:"type:text" json:"amr"`                // 用户最初完成认证的方式，刷新后签发的ID Token沿用
+	ExpiresAt            time.Time `gorm:"not null" json:"expires_at"`          // 当前令牌的过期时间，受空闲超时影响
+	AbsoluteExpiresAt    time.Time `gorm:"not null" json:"absolute_expires_at"` // 自首次授权起算的绝对过期时间，轮换后保持不变
+	RevokedAt            time.Time `gorm:"index" json:"revoked_at"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// Consent 用户对客户端的授权同意记录
+type Consent struct {
+	ID                   uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID               uint      `gorm:"not null;uniqueIndex:idx_consents_user_client" json:"user_id"`
+	ClientID             string    `gorm:"not null;uniqueIndex:idx_consents_user_client" json:"client_id"`
+	Scopes               string    `gorm:"type:text" json:"scopes"`
+	AuthorizationDetails string    `gorm:"type:text" json:"authorization_details"` // 已同意的authorization_details，JSON数组
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// PushedAuthorizationRequest 客户端通过PAR端点预先提交的授权请求 (RFC 9126)
+type PushedAuthorizationRequest struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RequestURI string    `gorm:"uniqueIndex;not null" json:"request_uri"`
+	ClientID   string    `gorm:"not null" json:"client_id"`
+	Parameters string    `gorm:"type:text;not null" json:"parameters"` // form-urlencoded编码的授权请求参数
+	ExpiresAt  time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定PushedAuthorizationRequest表名
+func (PushedAuthorizationRequest) TableName() string {
+	return "pushed_authorization_requests"
+}
+
+// IsActive 判断刷新令牌是否未被撤销且未过期
+func (t *RefreshToken) IsActive() bool {
+	now := time.Now()
+	return t.RevokedAt.IsZero() && now.Before(t.ExpiresAt) && now.Before(t.AbsoluteExpiresAt)
+}
+
+// IsExpired 判断推送的授权请求是否已过期
+func (r *PushedAuthorizationRequest) IsExpired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
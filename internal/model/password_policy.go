@@ -0,0 +1,11 @@
+package model
+
+// PasswordPolicy realm的密码策略，用于注册、重置密码和修改密码
+// 数值为0、布尔值为nil表示沿用全局配置
+type PasswordPolicy struct {
+	MinLength            int   `json:"min_length,omitempty"`             // 最短长度，按字符计
+	MinCharacterClasses  int   `json:"min_character_classes,omitempty"`  // 至少包含的字符类别数：小写字母、大写字母、数字、其他字符
+	BlockCommonPasswords *bool `json:"block_common_passwords,omitempty"` // 拒绝常见密码
+	BlockUserInfo        *bool `json:"block_user_info,omitempty"`        // 拒绝包含用户名或邮箱名的密码
+	CheckBreached        *bool `json:"check_breached,omitempty"`         // 拒绝出现在泄露密码库中的密码，需要配置PWNED_PASSWORDS_PATH
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// 用户授权分配类型
+const (
+	AssignmentKindRole  = "role"
+	AssignmentKindGroup = "group"
+)
+
+// Role 角色实体，决定用户在realm内的能力
+type Role struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RealmID     uint      `gorm:"not null;uniqueIndex:idx_roles_realm_name" json:"realm_id"`
+	Name        string    `gorm:"not null;size:64;uniqueIndex:idx_roles_realm_name" json:"name"` // 角色名称，如 catalog_editor
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定Role表名
+func (Role) TableName() string {
+	return "roles"
+}
+
+// Group 用户组实体，组成员自动获得组内的角色
+type Group struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RealmID     uint      `gorm:"not null;uniqueIndex:idx_groups_realm_name" json:"realm_id"`
+	Name        string    `gorm:"not null;size:64;uniqueIndex:idx_groups_realm_name" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	Roles       string    `gorm:"type:text" json:"roles"` // 组内角色，空格分隔
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定Group表名
+func (Group) TableName() string {
+	return "groups"
+}
+
+// RoleList 获取组内角色列表
+func (g *Group) RoleList() []string {
+	return strings.Fields(g.Roles)
+}
+
+// UserAssignment 用户的角色或用户组分配
+type UserAssignment struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_assignments" json:"user_id"`
+	Kind      string    `gorm:"not null;size:16;uniqueIndex:idx_user_assignments" json:"kind"` // role 或 group
+	Name      string    `gorm:"not null;size:64;uniqueIndex:idx_user_assignments" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定UserAssignment表名
+func (UserAssignment) TableName() string {
+	return "user_assignments"
+}
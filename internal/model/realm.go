@@ -0,0 +1,58 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+// realmNamePattern realm名称只允许小写字母、数字和连字符，
+// 名称会出现在URL路径、会话cookie名和环境变量名中
+var realmNamePattern = regexp.MustCompile(`^[a-z0-9-]{1,64}$`)
+
+// Realm 租户实体，每个realm拥有独立的issuer、签名密钥、客户端和用户
+type Realm struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`                                     // realm ID，由配置显式指定，users.realm_id引用该值
+	Name           string              `gorm:"uniqueIndex;not null;size:64" json:"name"`                 // realm名称，用于URL路径 /realms/{name}
+	DisplayName    string              `gorm:"size:255" json:"display_name"`                             // 显示名称
+	IsDefault      bool                `gorm:"default:false" json:"is_default"`                          // 是否为默认realm（挂载在根路径）
+	PrivateKeyPath string              `gorm:"type:text" json:"private_key_path,omitempty"`              // 签名私钥路径
+	PublicKeyPath  string              `gorm:"type:text" json:"public_key_path,omitempty"`               // 签名公钥路径
+	Branding       RealmBranding       `gorm:"embedded;embeddedPrefix:branding_" json:"branding"`        // 品牌配置
+	TokenPolicy    TokenLifetimePolicy `gorm:"embedded;embeddedPrefix:token_" json:"token_policy"`       // 令牌有效期策略，未设置的项沿用全局配置
+	PasswordPolicy PasswordPolicy      `gorm:"embedded;embeddedPrefix:password_" json:"password_policy"` // 密码策略，未设置的项沿用全局配置
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+
+	// 关联
+	Clients           []Client           `gorm:"foreignKey:RealmID" json:"clients,omitempty"`            // realm下的OAuth客户端
+	Scopes            []Scope            `gorm:"foreignKey:RealmID" json:"scopes,omitempty"`             // realm自定义的scope，覆盖同名内置scope
+	Roles             []Role             `gorm:"foreignKey:RealmID" json:"roles,omitempty"`              // realm自定义的角色
+	Groups            []Group            `gorm:"foreignKey:RealmID" json:"groups,omitempty"`             // realm预置的用户组
+	IdentityProviders []IdentityProvider `gorm:"foreignKey:RealmID" json:"identity_providers,omitempty"` // 上游身份提供方
+}
+
+// RealmBranding realm品牌配置，用于登录页和邮件展示
+type RealmBranding struct {
+	LogoURL      string `gorm:"type:text" json:"logo_url,omitempty"`    // Logo地址
+	PrimaryColor string `gorm:"size:32" json:"primary_color,omitempty"` // 主题色
+	LoginTitle   string `gorm:"size:255" json:"login_title,omitempty"`  // 登录页标题
+	FooterText   string `gorm:"type:text" json:"footer_text,omitempty"` // 页脚文字
+}
+
+// TableName 指定Realm表名
+func (Realm) TableName() string {
+	return "realms"
+}
+
+// PathPrefix 获取realm的路由前缀，默认realm挂载在根路径
+func (r *Realm) PathPrefix() string {
+	if r.IsDefault {
+		return ""
+	}
+	return "/realms/" + r.Name
+}
+
+// IsValidRealmName 检查realm名称是否只包含小写字母、数字和连字符
+func IsValidRealmName(name string) bool {
+	return realmNamePattern.MatchString(name)
+}
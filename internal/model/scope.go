@@ -0,0 +1,28 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Scope OAuth scope定义，描述scope对应的权限、可获得的声明以及是否需要用户同意
+type Scope struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RealmID         uint      `gorm:"not null;uniqueIndex:idx_scopes_realm_name" json:"realm_id"`
+	Name            string    `gorm:"not null;size:128;uniqueIndex:idx_scopes_realm_name" json:"name"` // scope名称，如 collection:write
+	Description     string    `gorm:"type:text" json:"description"`                                    // 同意页面展示的说明
+	Claims          string    `gorm:"type:text" json:"claims"`                                         // 授予的声明，空格分隔
+	RequiresConsent bool      `gorm:"default:true" json:"requires_consent"`                            // 是否需要用户同意
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName 指定Scope表名
+func (Scope) TableName() string {
+	return "scopes"
+}
+
+// ClaimList 获取scope授予的声明列表
+func (s *Scope) ClaimList() []string {
+	return strings.Fields(s.Claims)
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// 认证方式引用值 (RFC 8176)，记录在会话中并通过令牌的amr声明返回
+const (
+	AMRPassword  = "pwd" // 本地密码
+	AMROTP       = "otp" // 一次性密码，包括TOTP验证码和恢复码
+	AMRFederated = "fed" // 上游身份提供方登录，包括Bangumi
+	AMRHWK       = "hwk" // 硬件密钥持有证明，即WebAuthn通行密钥
+	AMRMFA       = "mfa" // 多因素认证，通行密钥登录时由认证器完成了用户验证
+)
+
+// Session 用户登录会话（SSO会话），通过Cookie与浏览器关联
+type Session struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	RealmID   uint      `gorm:"not null;index" json:"realm_id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	AuthTime  time.Time `gorm:"not null" json:"auth_time"`
+	AMR       string    `gorm:"type:text" json:"amr"` // 用户完成的认证方式，空格分隔
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsExpired 判断会话是否已过期
+func (s *Session) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// AuthMethods 获取会话完成的认证方式
+func (s *Session) AuthMethods() []string {
+	return strings.Fields(s.AMR)
+}
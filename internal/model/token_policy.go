@@ -0,0 +1,14 @@
+package model
+
+// TokenLifetimePolicy 令牌有效期策略，时长单位为秒
+// 数值为0、布尔值为nil表示沿用上一级配置（客户端 > realm > 全局），
+// 空闲超时取负数表示显式关闭
+type TokenLifetimePolicy struct {
+	AuthorizationCodeLifetime    int   `json:"authorization_code_lifetime,omitempty"`     // 授权码有效期
+	AccessTokenLifetime          int   `json:"access_token_lifetime,omitempty"`           // 访问令牌有效期
+	IDTokenLifetime              int   `json:"id_token_lifetime,omitempty"`               // ID Token有效期
+	RefreshTokenAbsoluteLifetime int   `json:"refresh_token_absolute_lifetime,omitempty"` // 刷新令牌自首次授权起算的绝对有效期
+	RefreshTokenIdleTimeout      int   `json:"refresh_token_idle_timeout,omitempty"`      // 刷新令牌的空闲超时，超过该时长未使用即失效
+	RefreshTokenSliding          *bool `json:"refresh_token_sliding,omitempty"`           // 滑动过期：每次刷新重新计算空闲超时
+	AlwaysIssueRefreshToken      *bool `json:"always_issue_refresh_token,omitempty"`      // 未请求offline_access时也签发刷新令牌
+}
@@ -6,14 +6,20 @@ import (
 
 // User 用户实体，包含用户基本信息
 type User struct {
-	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Username     string    `gorm:"uniqueIndex;not null" json:"username"`
-	PasswordHash string    `gorm:"not null" json:"password_hash"`
-	Email        string    `gorm:"uniqueIndex;not null" json:"email"`
-	Nickname     string    `gorm:"not null" json:"nickname"`
-	AvatarURL    string    `gorm:"type:text" json:"avatar_url"`
-	Bio          string    `gorm:"type:text" json:"bio"`
-	IsActive     bool      `gorm:"default:false" json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RealmID       uint      `gorm:"not null;uniqueIndex:idx_users_realm_username;uniqueIndex:idx_users_realm_email" json:"realm_id"`
+	Username      string    `gorm:"not null;uniqueIndex:idx_users_realm_username" json:"username"`
+	PasswordHash  string    `gorm:"not null" json:"password_hash"`
+	Email         string    `gorm:"not null;uniqueIndex:idx_users_realm_email" json:"email"`
+	EmailVerified bool      `gorm:"default:false" json:"email_verified"` // 用户是否证明了对当前邮箱的控制
+	Nickname      string    `gorm:"not null" json:"nickname"`
+	AvatarURL     string    `gorm:"type:text" json:"avatar_url"`
+	AvatarKey     string    `gorm:"type:text" json:"-"` // 上传头像在存储中的key，替换或删除头像时用于删除旧文件
+	Bio           string    `gorm:"type:text" json:"bio"`
+	IsActive      bool      `gorm:"default:false" json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// 用户申请删除账户后设置为计划删除的时间，宽限期内可以取消，到期后账户及其数据被永久删除
+	DeletionScheduledAt *time.Time `gorm:"index" json:"deletion_scheduled_at,omitempty"`
 }
\ No newline at end of file
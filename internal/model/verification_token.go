@@ -12,4 +12,38 @@ type VerificationToken struct {
 	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-}
\ No newline at end of file
+}
+
+// PasswordResetToken 重置密码令牌，只保存令牌的哈希，使用一次后删除
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	TokenHash string    `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsExpired 判断令牌是否已过期
+func (t *PasswordResetToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// EmailChangeRequest 邮箱变更请求，确认令牌发往新邮箱，撤销令牌发往原邮箱，只保存令牌的哈希
+type EmailChangeRequest struct {
+	ID               uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID           uint       `gorm:"not null;index" json:"user_id"`
+	OldEmail         string     `gorm:"not null" json:"old_email"`
+	OldEmailVerified bool       `gorm:"default:false" json:"old_email_verified"` // 撤销时恢复原邮箱的验证状态
+	NewEmail         string     `gorm:"not null" json:"new_email"`
+	TokenHash        string     `gorm:"uniqueIndex;not null" json:"-"`
+	RevertTokenHash  string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`        // 确认链接的过期时间
+	RevertExpiresAt  time.Time  `gorm:"not null" json:"revert_expires_at"` // 撤销链接的过期时间
+	ConfirmedAt      *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// IsConfirmed 判断新邮箱是否已确认
+func (r *EmailChangeRequest) IsConfirmed() bool {
+	return r.ConfirmedAt != nil
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// WebAuthn仪式类型，决定挑战可用于完成哪一种流程
+const (
+	WebAuthnCeremonyRegistration = "registration"  // 注册通行密钥
+	WebAuthnCeremonyLogin        = "login"         // 无用户名登录（可发现凭据）
+	WebAuthnCeremonySecondFactor = "second_factor" // 作为第二步验证
+)
+
+// WebAuthnCredential 用户注册的WebAuthn凭据（通行密钥）
+type WebAuthnCredential struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID         uint       `gorm:"not null;index" json:"user_id"`
+	CredentialID   string     `gorm:"uniqueIndex;not null" json:"credential_id"` // Base64URL编码的凭据ID
+	PublicKey      []byte     `gorm:"not null" json:"-"`                         // COSE格式的公钥
+	Algorithm      int64      `gorm:"not null" json:"algorithm"`                 // COSE算法标识，如-7 (ES256)
+	SignCount      uint32     `gorm:"default:0" json:"sign_count"`               // 签名计数器，用于发现克隆的认证器
+	Transports     string     `gorm:"type:text" json:"transports"`               // 认证器支持的传输方式，空格分隔
+	AAGUID         string     `json:"aaguid"`                                    // 认证器型号标识
+	Name           string     `gorm:"not null" json:"name"`                      // 用户设置的名称
+	BackupEligible bool       `gorm:"default:false" json:"backup_eligible"`      // 凭据是否可以同步备份
+	BackedUp       bool       `gorm:"default:false" json:"backed_up"`            // 凭据当前是否已备份
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TransportList 获取认证器支持的传输方式
+func (c *WebAuthnCredential) TransportList() []string {
+	return strings.Fields(c.Transports)
+}
+
+// WebAuthnSession 进行中的WebAuthn仪式，保存服务端生成的挑战，只能使用一次
+type WebAuthnSession struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Challenge string    `gorm:"uniqueIndex;not null" json:"challenge"` // Base64URL编码的挑战
+	Ceremony  string    `gorm:"not null" json:"ceremony"`
+	UserID    uint      `gorm:"default:0" json:"user_id"` // 无用户名登录时为0
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsExpired 判断仪式是否已过期
+func (s *WebAuthnSession) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
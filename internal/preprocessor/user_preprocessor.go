@@ -61,20 +61,12 @@ func validateUsername(username string) error {
 	return nil
 }
 
-// validatePassword 校验密码
+// validatePassword 校验密码，长度、字符类别等要求由service.PasswordPolicyService按realm的密码策略校验
 func validatePassword(password string) error {
 	if password == "" {
 		return errors.New("密码不能为空")
 	}
 
-	if len(password) < 6 {
-		return errors.New("密码长度不能少于6个字符")
-	}
-
-	if len(password) > 128 {
-		return errors.New("密码长度不能超过128个字符")
-	}
-
 	return nil
 }
 
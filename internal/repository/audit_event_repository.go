@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// AuditEventRepository 安全审计事件仓库接口，审计事件只追加，不提供更新和删除
+type AuditEventRepository interface {
+	// Append 追加审计事件
+	Append(ctx context.Context, event *model.AuditEvent) error
+
+	// Query 按条件查询审计事件，按时间倒序
+	Query(ctx context.Context, filter model.AuditEventFilter) ([]*model.AuditEvent, error)
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// auditEventRepository 安全审计事件仓库实现
+type auditEventRepository struct {
+	mapper mapper.AuditEventMapper
+	// 内存存储，数据库不可用时使用
+	events []*model.AuditEvent
+	nextID uint
+	mu     sync.RWMutex
+}
+
+// NewAuditEventRepository 创建AuditEventRepository实例，mapper为nil时使用内存存储
+func NewAuditEventRepository(mapper mapper.AuditEventMapper) AuditEventRepository {
+	return &auditEventRepository{
+		mapper: mapper,
+		nextID: 1,
+	}
+}
+
+// Append 追加审计事件
+func (r *auditEventRepository) Append(ctx context.Context, event *model.AuditEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if r.mapper != nil {
+		return r.mapper.Insert(event)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.ID = r.nextID
+	r.nextID++
+	// 保存副本，调用方之后修改事件不影响已记录的内容
+	stored := *event
+	r.events = append(r.events, &stored)
+	return nil
+}
+
+// Query 按条件查询审计事件，按时间倒序
+func (r *auditEventRepository) Query(ctx context.Context, filter model.AuditEventFilter) ([]*model.AuditEvent, error) {
+	if r.mapper != nil {
+		return r.mapper.Query(filter)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var events []*model.AuditEvent
+	// 事件按追加顺序保存，倒序遍历即为时间倒序
+	for i := len(r.events) - 1; i >= 0; i-- {
+		if !filter.Matches(r.events[i]) {
+			continue
+		}
+		event := *r.events[i]
+		events = append(events, &event)
+		if filter.Limit > 0 && len(events) >= filter.Limit {
+			break
+		}
+	}
+	return events, nil
+}
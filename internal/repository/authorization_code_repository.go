@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// AuthorizationCodeRepository 授权码仓库接口
+type AuthorizationCodeRepository interface {
+	// Create 保存授权码
+	Create(ctx context.Context, authCode *model.AuthorizationCode) error
+	
+	// GetByCode 根据授权码获取记录
+	GetByCode(ctx context.Context, code string) (*model.AuthorizationCode, error)
+	
+	// ConsumeByCode 根据授权码获取记录并删除，授权码只能被取出一次
+	ConsumeByCode(ctx context.Context, code string) (*model.AuthorizationCode, error)
+	
+	// DeleteByID 根据ID删除授权码
+	DeleteByID(ctx context.Context, id uint) error
+}
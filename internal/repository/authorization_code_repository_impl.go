@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// authorizationCodeRepository 授权码仓库实现
+type authorizationCodeRepository struct {
+	authCodeMapper mapper.AuthorizationCodeMapper
+}
+
+// NewAuthorizationCodeRepository 创建AuthorizationCodeRepository实例
+func NewAuthorizationCodeRepository() AuthorizationCodeRepository {
+	return &authorizationCodeRepository{
+		authCodeMapper: mapper.NewAuthorizationCodeMapper(),
+	}
+}
+
+// Create 保存授权码
+func (r *authorizationCodeRepository) Create(ctx context.Context, authCode *model.AuthorizationCode) error {
+	return r.authCodeMapper.Save(authCode)
+}
+
+// GetByCode 根据授权码获取记录
+func (r *authorizationCodeRepository) GetByCode(ctx context.Context, code string) (*model.AuthorizationCode, error) {
+	return r.authCodeMapper.GetByCode(code)
+}
+
+// ConsumeByCode 根据授权码获取记录并删除
+func (r *authorizationCodeRepository) ConsumeByCode(ctx context.Context, code string) (*model.AuthorizationCode, error) {
+	return r.authCodeMapper.ConsumeByCode(code)
+}
+
+// DeleteByID 根据ID删除授权码
+func (r *authorizationCodeRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.authCodeMapper.DeleteByID(id)
+}
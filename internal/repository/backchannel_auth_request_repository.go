@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// BackchannelAuthRequestRepository CIBA认证请求仓库接口
+type BackchannelAuthRequestRepository interface {
+	// Create 保存认证请求
+	Create(ctx context.Context, authReq *model.BackchannelAuthRequest) error
+	
+	// GetByAuthReqID 根据auth_req_id获取认证请求
+	GetByAuthReqID(ctx context.Context, authReqID string) (*model.BackchannelAuthRequest, error)
+	
+	// Update 更新认证请求
+	Update(ctx context.Context, authReq *model.BackchannelAuthRequest) error
+	
+	// DeleteByID 根据ID删除认证请求
+	DeleteByID(ctx context.Context, id uint) error
+	
+	// ConsumeByID 根据ID删除认证请求，返回是否确实删除了记录
+	ConsumeByID(ctx context.Context, id uint) (bool, error)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// backchannelAuthRequestRepository CIBA认证请求仓库实现
+type backchannelAuthRequestRepository struct {
+	authReqMapper mapper.BackchannelAuthRequestMapper
+}
+
+// NewBackchannelAuthRequestRepository 创建BackchannelAuthRequestRepository实例
+func NewBackchannelAuthRequestRepository() BackchannelAuthRequestRepository {
+	return &backchannelAuthRequestRepository{
+		authReqMapper: mapper.NewBackchannelAuthRequestMapper(),
+	}
+}
+
+// Create 保存认证请求
+func (r *backchannelAuthRequestRepository) Create(ctx context.Context, authReq *model.BackchannelAuthRequest) error {
+	return r.authReqMapper.Save(authReq)
+}
+
+// GetByAuthReqID 根据auth_req_id获取认证请求
+func (r *backchannelAuthRequestRepository) GetByAuthReqID(ctx context.Context, authReqID string) (*model.BackchannelAuthRequest, error) {
+	return r.authReqMapper.GetByAuthReqID(authReqID)
+}
+
+// Update 更新认证请求
+func (r *backchannelAuthRequestRepository) Update(ctx context.Context, authReq *model.BackchannelAuthRequest) error {
+	return r.authReqMapper.Update(authReq)
+}
+
+// DeleteByID 根据ID删除认证请求
+func (r *backchannelAuthRequestRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.authReqMapper.DeleteByID(id)
+}
+
+// ConsumeByID 根据ID删除认证请求，返回是否确实删除了记录
+func (r *backchannelAuthRequestRepository) ConsumeByID(ctx context.Context, id uint) (bool, error) {
+	return r.authReqMapper.ConsumeByID(id)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// BangumiPendingLoginRepository 待完成的Bangumi登录仓库接口
+type BangumiPendingLoginRepository interface {
+	// Create 保存待完成的Bangumi登录
+	Create(ctx context.Context, pendingLogin *model.BangumiPendingLogin) error
+	
+	// GetByToken 根据token获取待完成的Bangumi登录
+	GetByToken(ctx context.Context, token string) (*model.BangumiPendingLogin, error)
+	
+	// DeleteByID 根据ID删除待完成的Bangumi登录
+	DeleteByID(ctx context.Context, id uint) error
+}
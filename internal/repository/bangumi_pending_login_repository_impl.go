@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// bangumiPendingLoginRepository 待完成的Bangumi登录仓库实现
+type bangumiPendingLoginRepository struct {
+	pendingMapper mapper.BangumiPendingLoginMapper
+}
+
+// NewBangumiPendingLoginRepository 创建BangumiPendingLoginRepository实例
+func NewBangumiPendingLoginRepository() BangumiPendingLoginRepository {
+	return &bangumiPendingLoginRepository{
+		pendingMapper: mapper.NewBangumiPendingLoginMapper(),
+	}
+}
+
+// Create 保存待完成的Bangumi登录
+func (r *bangumiPendingLoginRepository) Create(ctx context.Context, pendingLogin *model.BangumiPendingLogin) error {
+	return r.pendingMapper.Save(pendingLogin)
+}
+
+// GetByToken 根据token获取待完成的Bangumi登录
+func (r *bangumiPendingLoginRepository) GetByToken(ctx context.Context, token string) (*model.BangumiPendingLogin, error) {
+	return r.pendingMapper.GetByToken(token)
+}
+
+// DeleteByID 根据ID删除待完成的Bangumi登录
+func (r *bangumiPendingLoginRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.pendingMapper.DeleteByID(id)
+}
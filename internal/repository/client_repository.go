@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ClientRepository OAuth客户端仓库接口
+type ClientRepository interface {
+	// Create 创建客户端
+	Create(ctx context.Context, client *model.Client) error
+	
+	// GetByClientID 根据client_id获取客户端
+	GetByClientID(ctx context.Context, clientID string) (*model.Client, error)
+	
+	// Update 更新客户端
+	Update(ctx context.Context, client *model.Client) error
+	
+	// DeleteByID 根据ID删除客户端
+	DeleteByID(ctx context.Context, id uint) error
+	
+	// ListAll 列出所有客户端
+	ListAll(ctx context.Context) ([]*model.Client, error)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// clientRepository OAuth客户端仓库实现，实例归属于单个realm
+type clientRepository struct {
+	clientMapper mapper.ClientMapper
+	realmID      uint
+}
+
+// NewClientRepository 创建realm范围内的ClientRepository实例
+func NewClientRepository(realmID uint) ClientRepository {
+	return &clientRepository{
+		clientMapper: mapper.NewClientMapper(),
+		realmID:      realmID,
+	}
+}
+
+// Create 创建客户端
+func (r *clientRepository) Create(ctx context.Context, client *model.Client) error {
+	client.RealmID = r.realmID
+	return r.clientMapper.Save(client)
+}
+
+// GetByClientID 根据client_id获取客户端
+func (r *clientRepository) GetByClientID(ctx context.Context, clientID string) (*model.Client, error) {
+	return r.clientMapper.GetByClientID(clientID)
+}
+
+// Update 更新客户端
+func (r *clientRepository) Update(ctx context.Context, client *model.Client) error {
+	client.RealmID = r.realmID
+	return r.clientMapper.Update(client)
+}
+
+// DeleteByID 根据ID删除客户端
+func (r *clientRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.clientMapper.DeleteByID(id)
+}
+
+// ListAll 列出所有客户端
+func (r *clientRepository) ListAll(ctx context.Context) ([]*model.Client, error) {
+	entities, err := r.clientMapper.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	
+	var clients []*model.Client
+	for _, entity := range entities {
+		if client, ok := entity.(*model.Client); ok {
+			clients = append(clients, client)
+		}
+	}
+	
+	return clients, nil
+}
@@ -30,4 +30,7 @@ type CollectionRepository interface {
 	
 	// ListFavorites 列出用户收藏夹
 	ListFavorites(ctx context.Context, userID uint) ([]*model.Collection, error)
+	
+	// DeleteByUserID 删除用户的全部收藏
+	DeleteByUserID(ctx context.Context, userID uint) error
 }
\ No newline at end of file
@@ -65,4 +65,18 @@ func (r *collectionRepository) ListByUserIDAndStatus(ctx context.Context, userID
 // ListFavorites 列出用户收藏夹
 func (r *collectionRepository) ListFavorites(ctx context.Context, userID uint) ([]*model.Collection, error) {
 	return r.collectionMapper.GetFavorites(userID)
+}
+
+// DeleteByUserID 删除用户的全部收藏
+func (r *collectionRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	collections, err := r.collectionMapper.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, collection := range collections {
+		if err := r.collectionMapper.DeleteByID(collection.ID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
\ No newline at end of file
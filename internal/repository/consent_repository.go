@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ConsentRepository 授权同意记录仓库接口
+type ConsentRepository interface {
+	// Save 保存授权同意记录，已存在时覆盖
+	Save(ctx context.Context, consent *model.Consent) error
+	
+	// GetByUserAndClient 获取用户对某个客户端的授权同意记录
+	GetByUserAndClient(ctx context.Context, userID uint, clientID string) (*model.Consent, error)
+	
+	// DeleteByID 根据ID删除授权同意记录
+	DeleteByID(ctx context.Context, id uint) error
+	
+	// ListByUserID 获取用户的全部授权同意记录
+	ListByUserID(ctx context.Context, userID uint) ([]*model.Consent, error)
+	
+	// DeleteByUserID 删除用户的全部授权同意记录
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
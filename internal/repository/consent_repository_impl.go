@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// consentRepository 授权同意记录仓库实现
+type consentRepository struct {
+	consentMapper mapper.ConsentMapper
+}
+
+// NewConsentRepository 创建ConsentRepository实例
+func NewConsentRepository() ConsentRepository {
+	return &consentRepository{
+		consentMapper: mapper.NewConsentMapper(),
+	}
+}
+
+// Save 保存授权同意记录，已存在时覆盖
+func (r *consentRepository) Save(ctx context.Context, consent *model.Consent) error {
+	return r.consentMapper.Save(consent)
+}
+
+// GetByUserAndClient 获取用户对某个客户端的授权同意记录
+func (r *consentRepository) GetByUserAndClient(ctx context.Context, userID uint, clientID string) (*model.Consent, error) {
+	return r.consentMapper.GetByUserAndClient(userID, clientID)
+}
+
+// DeleteByID 根据ID删除授权同意记录
+func (r *consentRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.consentMapper.DeleteByID(id)
+}
+
+// ListByUserID 获取用户的全部授权同意记录
+func (r *consentRepository) ListByUserID(ctx context.Context, userID uint) ([]*model.Consent, error) {
+	return r.consentMapper.ListByUserID(userID)
+}
+
+// DeleteByUserID 删除用户的全部授权同意记录
+func (r *consentRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.consentMapper.DeleteByUserID(userID)
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// EmailChangeRequestRepository 邮箱变更请求仓库接口
+type EmailChangeRequestRepository interface {
+	// Create 保存邮箱变更请求
+	Create(ctx context.Context, request *model.EmailChangeRequest) error
+	
+	// GetByTokenHash 根据确认令牌哈希获取邮箱变更请求
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error)
+	
+	// GetByRevertTokenHash 根据撤销令牌哈希获取邮箱变更请求
+	GetByRevertTokenHash(ctx context.Context, revertTokenHash string) (*model.EmailChangeRequest, error)
+	
+	// Update 更新邮箱变更请求
+	Update(ctx context.Context, request *model.EmailChangeRequest) error
+	
+	// DeleteByID 根据ID删除邮箱变更请求
+	DeleteByID(ctx context.Context, id uint) error
+	
+	// DeletePendingByUserID 删除用户尚未确认的邮箱变更请求，已确认的请求保留以便原邮箱撤销
+	DeletePendingByUserID(ctx context.Context, userID uint) error
+	
+	// DeleteByUserID 删除用户的全部邮箱变更请求
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// emailChangeRequestRepository 邮箱变更请求仓库实现
+type emailChangeRequestRepository struct {
+	requestMapper mapper.EmailChangeRequestMapper
+}
+
+// NewEmailChangeRequestRepository 创建EmailChangeRequestRepository实例
+func NewEmailChangeRequestRepository() EmailChangeRequestRepository {
+	return &emailChangeRequestRepository{
+		requestMapper: mapper.NewEmailChangeRequestMapper(),
+	}
+}
+
+// Create 保存邮箱变更请求
+func (r *emailChangeRequestRepository) Create(ctx context.Context, request *model.EmailChangeRequest) error {
+	return r.requestMapper.Save(request)
+}
+
+// GetByTokenHash 根据确认令牌哈希获取邮箱变更请求
+func (r *emailChangeRequestRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.EmailChangeRequest, error) {
+	return r.requestMapper.GetByTokenHash(tokenHash)
+}
+
+// GetByRevertTokenHash 根据撤销令牌哈希获取邮箱变更请求
+func (r *emailChangeRequestRepository) GetByRevertTokenHash(ctx context.Context, revertTokenHash string) (*model.EmailChangeRequest, error) {
+	return r.requestMapper.GetByRevertTokenHash(revertTokenHash)
+}
+
+// Update 更新邮箱变更请求
+func (r *emailChangeRequestRepository) Update(ctx context.Context, request *model.EmailChangeRequest) error {
+	return r.requestMapper.Update(request)
+}
+
+// DeleteByID 根据ID删除邮箱变更请求
+func (r *emailChangeRequestRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.requestMapper.DeleteByID(id)
+}
+
+// DeletePendingByUserID 删除用户尚未确认的邮箱变更请求
+func (r *emailChangeRequestRepository) DeletePendingByUserID(ctx context.Context, userID uint) error {
+	return r.requestMapper.DeletePendingByUserID(userID)
+}
+
+// DeleteByUserID 删除用户的全部邮箱变更请求
+func (r *emailChangeRequestRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.requestMapper.DeleteByUserID(userID)
+}
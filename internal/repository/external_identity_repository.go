@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ExternalIdentityRepository 外部身份仓库接口
+type ExternalIdentityRepository interface {
+	// Create 保存外部身份
+	Create(ctx context.Context, identity *model.ExternalIdentity) error
+	
+	// GetByID 根据ID获取外部身份
+	GetByID(ctx context.Context, id uint) (*model.ExternalIdentity, error)
+	
+	// GetByProviderSubject 根据上游身份提供方和sub获取外部身份
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error)
+	
+	// ListByUserID 获取用户关联的全部外部身份
+	ListByUserID(ctx context.Context, userID uint) ([]*model.ExternalIdentity, error)
+	
+	// Update 更新外部身份
+	Update(ctx context.Context, identity *model.ExternalIdentity) error
+	
+	// DeleteByID 根据ID删除外部身份
+	DeleteByID(ctx context.Context, id uint) error
+}
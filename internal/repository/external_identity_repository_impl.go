@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// externalIdentityRepository 外部身份仓库实现
+type externalIdentityRepository struct {
+	identityMapper mapper.ExternalIdentityMapper
+}
+
+// NewExternalIdentityRepository 创建ExternalIdentityRepository实例
+func NewExternalIdentityRepository() ExternalIdentityRepository {
+	return &externalIdentityRepository{
+		identityMapper: mapper.NewExternalIdentityMapper(),
+	}
+}
+
+// Create 保存外部身份
+func (r *externalIdentityRepository) Create(ctx context.Context, identity *model.ExternalIdentity) error {
+	return r.identityMapper.Save(identity)
+}
+
+// GetByID 根据ID获取外部身份
+func (r *externalIdentityRepository) GetByID(ctx context.Context, id uint) (*model.ExternalIdentity, error) {
+	entity, err := r.identityMapper.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return entity.(*model.ExternalIdentity), nil
+}
+
+// GetByProviderSubject 根据上游身份提供方和sub获取外部身份
+func (r *externalIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*model.ExternalIdentity, error) {
+	return r.identityMapper.GetByProviderSubject(provider, subject)
+}
+
+// ListByUserID 获取用户关联的全部外部身份
+func (r *externalIdentityRepository) ListByUserID(ctx context.Context, userID uint) ([]*model.ExternalIdentity, error) {
+	return r.identityMapper.ListByUserID(userID)
+}
+
+// Update 更新外部身份
+func (r *externalIdentityRepository) Update(ctx context.Context, identity *model.ExternalIdentity) error {
+	return r.identityMapper.Update(identity)
+}
+
+// DeleteByID 根据ID删除外部身份
+func (r *externalIdentityRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.identityMapper.DeleteByID(id)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// FederatedLoginStateRepository 上游登录状态仓库接口
+type FederatedLoginStateRepository interface {
+	// Create 保存上游登录状态
+	Create(ctx context.Context, loginState *model.FederatedLoginState) error
+	
+	// GetByState 根据state获取上游登录状态
+	GetByState(ctx context.Context, state string) (*model.FederatedLoginState, error)
+	
+	// DeleteByID 根据ID删除上游登录状态
+	DeleteByID(ctx context.Context, id uint) error
+}
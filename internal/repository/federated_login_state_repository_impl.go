@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// federatedLoginStateRepository 上游登录状态仓库实现
+type federatedLoginStateRepository struct {
+	stateMapper mapper.FederatedLoginStateMapper
+}
+
+// NewFederatedLoginStateRepository 创建FederatedLoginStateRepository实例
+func NewFederatedLoginStateRepository() FederatedLoginStateRepository {
+	return &federatedLoginStateRepository{
+		stateMapper: mapper.NewFederatedLoginStateMapper(),
+	}
+}
+
+// Create 保存上游登录状态
+func (r *federatedLoginStateRepository) Create(ctx context.Context, loginState *model.FederatedLoginState) error {
+	return r.stateMapper.Save(loginState)
+}
+
+// GetByState 根据state获取上游登录状态
+func (r *federatedLoginStateRepository) GetByState(ctx context.Context, state string) (*model.FederatedLoginState, error) {
+	return r.stateMapper.GetByState(state)
+}
+
+// DeleteByID 根据ID删除上游登录状态
+func (r *federatedLoginStateRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.stateMapper.DeleteByID(id)
+}
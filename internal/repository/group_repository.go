@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// GroupRepository 用户组仓库接口
+type GroupRepository interface {
+	// Create 创建用户组
+	Create(ctx context.Context, group *model.Group) error
+	
+	// GetByName 根据名称获取用户组
+	GetByName(ctx context.Context, name string) (*model.Group, error)
+	
+	// Update 更新用户组
+	Update(ctx context.Context, group *model.Group) error
+	
+	// DeleteByID 根据ID删除用户组
+	DeleteByID(ctx context.Context, id uint) error
+	
+	// ListAll 列出所有用户组
+	ListAll(ctx context.Context) ([]*model.Group, error)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// groupRepository 用户组仓库实现，实例归属于单个realm
+type groupRepository struct {
+	groupMapper mapper.GroupMapper
+	realmID     uint
+}
+
+// NewGroupRepository 创建realm范围内的GroupRepository实例
+func NewGroupRepository(realmID uint) GroupRepository {
+	return &groupRepository{
+		groupMapper: mapper.NewGroupMapper(),
+		realmID:     realmID,
+	}
+}
+
+// Create 创建用户组
+func (r *groupRepository) Create(ctx context.Context, group *model.Group) error {
+	group.RealmID = r.realmID
+	return r.groupMapper.Save(group)
+}
+
+// GetByName 根据名称获取用户组
+func (r *groupRepository) GetByName(ctx context.Context, name string) (*model.Group, error) {
+	return r.groupMapper.GetByName(name)
+}
+
+// Update 更新用户组
+func (r *groupRepository) Update(ctx context.Context, group *model.Group) error {
+	group.RealmID = r.realmID
+	return r.groupMapper.Update(group)
+}
+
+// DeleteByID 根据ID删除用户组
+func (r *groupRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.groupMapper.DeleteByID(id)
+}
+
+// ListAll 列出所有用户组
+func (r *groupRepository) ListAll(ctx context.Context) ([]*model.Group, error) {
+	entities, err := r.groupMapper.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*model.Group
+	for _, entity := range entities {
+		if group, ok := entity.(*model.Group); ok {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// LoginAttemptRepository 登录失败计数仓库接口，计数和锁定标记都会在有效期后自动过期
+type LoginAttemptRepository interface {
+	// Increment 计数加一并返回当前值，计数第一次创建时设置有效期
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	
+	// Get 获取计数及其剩余有效期，不存在时返回0
+	Get(ctx context.Context, key string) (int64, time.Duration, error)
+	
+	// Set 设置计数并重新设置有效期
+	Set(ctx context.Context, key string, value int64, ttl time.Duration) error
+	
+	// Delete 删除计数
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// loginAttemptRepository 登录失败计数仓库实现，计数保存在Redis中以便多个实例共享
+type loginAttemptRepository struct {
+	redisClient *redis.Client
+	// 内存存储，Redis不可用时使用
+	memoryStore map[string]*loginAttemptEntry
+	mu          sync.Mutex
+}
+
+// loginAttemptEntry 内存模式下的计数
+type loginAttemptEntry struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// NewLoginAttemptRepository 创建LoginAttemptRepository实例，redisClient为nil时使用内存存储
+func NewLoginAttemptRepository(redisClient *redis.Client) LoginAttemptRepository {
+	return &loginAttemptRepository{
+		redisClient: redisClient,
+		memoryStore: make(map[string]*loginAttemptEntry),
+	}
+}
+
+// Increment 计数加一并返回当前值
+func (r *loginAttemptRepository) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if r.redisClient == nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		entry := r.memoryEntry(key)
+		if entry == nil {
+			entry = &loginAttemptEntry{expiresAt: time.Now().Add(ttl)}
+			r.memoryStore[key] = entry
+		}
+		entry.value++
+		return entry.value, nil
+	}
+
+	count, err := r.redisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// 如果是第一次设置key，设置过期时间
+	if count == 1 {
+		if err := r.redisClient.Expire(ctx, key, ttl).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// Get 获取计数及其剩余有效期
+func (r *loginAttemptRepository) Get(ctx context.Context, key string) (int64, time.Duration, error) {
+	if r.redisClient == nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		entry := r.memoryEntry(key)
+		if entry == nil {
+			return 0, 0, nil
+		}
+		return entry.value, time.Until(entry.expiresAt), nil
+	}
+
+	count, err := r.redisClient.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	ttl, err := r.redisClient.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	if ttl < 0 {
+		// 键已过期或没有设置有效期
+		ttl = 0
+	}
+	return count, ttl, nil
+}
+
+// Set 设置计数并重新设置有效期
+func (r *loginAttemptRepository) Set(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	if r.redisClient == nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.memoryStore[key] = &loginAttemptEntry{value: value, expiresAt: time.Now().Add(ttl)}
+		return nil
+	}
+
+	return r.redisClient.Set(ctx, key, value, ttl).Err()
+}
+
+// Delete 删除计数
+func (r *loginAttemptRepository) Delete(ctx context.Context, keys ...string) error {
+	if r.redisClient == nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, key := range keys {
+			delete(r.memoryStore, key)
+		}
+		return nil
+	}
+
+	return r.redisClient.Del(ctx, keys...).Err()
+}
+
+// memoryEntry 获取未过期的内存计数，已过期的计数会被删除，调用方须持有锁
+func (r *loginAttemptRepository) memoryEntry(key string) *loginAttemptEntry {
+	entry, exists := r.memoryStore[key]
+	if !exists {
+		return nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(r.memoryStore, key)
+		return nil
+	}
+	return entry
+}
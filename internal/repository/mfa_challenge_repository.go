@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// MFAChallengeRepository 两步验证挑战仓库接口
+type MFAChallengeRepository interface {
+	// Create 保存两步验证挑战
+	Create(ctx context.Context, challenge *model.MFAChallenge) error
+	
+	// GetByToken 根据令牌获取两步验证挑战
+	GetByToken(ctx context.Context, token string) (*model.MFAChallenge, error)
+	
+	// Update 更新两步验证挑战
+	Update(ctx context.Context, challenge *model.MFAChallenge) error
+	
+	// DeleteByID 根据ID删除两步验证挑战
+	DeleteByID(ctx context.Context, id uint) error
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// mfaChallengeRepository 两步验证挑战仓库实现
+type mfaChallengeRepository struct {
+	challengeMapper mapper.MFAChallengeMapper
+}
+
+// NewMFAChallengeRepository 创建MFAChallengeRepository实例
+func NewMFAChallengeRepository() MFAChallengeRepository {
+	return &mfaChallengeRepository{
+		challengeMapper: mapper.NewMFAChallengeMapper(),
+	}
+}
+
+// Create 保存两步验证挑战
+func (r *mfaChallengeRepository) Create(ctx context.Context, challenge *model.MFAChallenge) error {
+	return r.challengeMapper.Save(challenge)
+}
+
+// GetByToken 根据令牌获取两步验证挑战
+func (r *mfaChallengeRepository) GetByToken(ctx context.Context, token string) (*model.MFAChallenge, error) {
+	return r.challengeMapper.GetByToken(token)
+}
+
+// Update 更新两步验证挑战
+func (r *mfaChallengeRepository) Update(ctx context.Context, challenge *model.MFAChallenge) error {
+	return r.challengeMapper.Update(challenge)
+}
+
+// DeleteByID 根据ID删除两步验证挑战
+func (r *mfaChallengeRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.challengeMapper.DeleteByID(id)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// PasswordResetTokenRepository 重置密码令牌仓库接口
+type PasswordResetTokenRepository interface {
+	// Create 保存重置密码令牌
+	Create(ctx context.Context, token *model.PasswordResetToken) error
+	
+	// GetByTokenHash 根据令牌哈希获取重置密码令牌
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error)
+	
+	// DeleteByID 根据ID删除重置密码令牌
+	DeleteByID(ctx context.Context, id uint) error
+	
+	// DeleteByUserID 删除用户的全部重置密码令牌
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
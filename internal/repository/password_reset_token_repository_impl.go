@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// passwordResetTokenRepository 重置密码令牌仓库实现
+type passwordResetTokenRepository struct {
+	tokenMapper mapper.PasswordResetTokenMapper
+}
+
+// NewPasswordResetTokenRepository 创建PasswordResetTokenRepository实例
+func NewPasswordResetTokenRepository() PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{
+		tokenMapper: mapper.NewPasswordResetTokenMapper(),
+	}
+}
+
+// Create 保存重置密码令牌
+func (r *passwordResetTokenRepository) Create(ctx context.Context, token *model.PasswordResetToken) error {
+	return r.tokenMapper.Save(token)
+}
+
+// GetByTokenHash 根据令牌哈希获取重置密码令牌
+func (r *passwordResetTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error) {
+	return r.tokenMapper.GetByTokenHash(tokenHash)
+}
+
+// DeleteByID 根据ID删除重置密码令牌
+func (r *passwordResetTokenRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.tokenMapper.DeleteByID(id)
+}
+
+// DeleteByUserID 删除用户的全部重置密码令牌
+func (r *passwordResetTokenRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.tokenMapper.DeleteByUserID(userID)
+}
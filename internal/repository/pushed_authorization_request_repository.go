@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// PushedAuthorizationRequestRepository 推送的授权请求仓库接口
+type PushedAuthorizationRequestRepository interface {
+	// Create 保存推送的授权请求
+	Create(ctx context.Context, parReq *model.PushedAuthorizationRequest) error
+	
+	// GetByRequestURI 根据request_uri获取推送的授权请求
+	GetByRequestURI(ctx context.Context, requestURI string) (*model.PushedAuthorizationRequest, error)
+	
+	// ConsumeByRequestURI 根据request_uri获取推送的授权请求并删除，request_uri只能被取出一次
+	ConsumeByRequestURI(ctx context.Context, requestURI string) (*model.PushedAuthorizationRequest, error)
+	
+	// DeleteByID 根据ID删除推送的授权请求
+	DeleteByID(ctx context.Context, id uint) error
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// pushedAuthorizationRequestRepository 推送的授权请求仓库实现
+type pushedAuthorizationRequestRepository struct {
+	parMapper mapper.PushedAuthorizationRequestMapper
+}
+
+// NewPushedAuthorizationRequestRepository 创建PushedAuthorizationRequestRepository实例
+func NewPushedAuthorizationRequestRepository() PushedAuthorizationRequestRepository {
+	return &pushedAuthorizationRequestRepository{
+		parMapper: mapper.NewPushedAuthorizationRequestMapper(),
+	}
+}
+
+// Create 保存推送的授权请求
+func (r *pushedAuthorizationRequestRepository) Create(ctx context.Context, parReq *model.PushedAuthorizationRequest) error {
+	return r.parMapper.Save(parReq)
+}
+
+// GetByRequestURI 根据request_uri获取推送的授权请求
+func (r *pushedAuthorizationRequestRepository) GetByRequestURI(ctx context.Context, requestURI string) (*model.PushedAuthorizationRequest, error) {
+	return r.parMapper.GetByRequestURI(requestURI)
+}
+
+// ConsumeByRequestURI 根据request_uri获取推送的授权请求并删除
+func (r *pushedAuthorizationRequestRepository) ConsumeByRequestURI(ctx context.Context, requestURI string) (*model.PushedAuthorizationRequest, error) {
+	return r.parMapper.ConsumeByRequestURI(requestURI)
+}
+
+// DeleteByID 根据ID删除推送的授权请求
+func (r *pushedAuthorizationRequestRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.parMapper.DeleteByID(id)
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// RealmRepository realm仓库接口
+type RealmRepository interface {
+	// Create 创建realm
+	Create(ctx context.Context, realm *model.Realm) error
+	
+	// GetByName 根据名称获取realm
+	GetByName(ctx context.Context, name string) (*model.Realm, error)
+	
+	// GetDefault 获取默认realm
+	GetDefault(ctx context.Context) (*model.Realm, error)
+	
+	// Update 更新realm
+	Update(ctx context.Context, realm *model.Realm) error
+	
+	// ListAll 列出所有realm
+	ListAll(ctx context.Context) ([]*model.Realm, error)
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// realmRepository realm仓库实现
+type realmRepository struct {
+	realmMapper mapper.RealmMapper
+}
+
+// NewRealmRepository 创建RealmRepository实例
+func NewRealmRepository() RealmRepository {
+	return &realmRepository{
+		realmMapper: mapper.NewRealmMapper(),
+	}
+}
+
+// Create 创建realm
+func (r *realmRepository) Create(ctx context.Context, realm *model.Realm) error {
+	return r.realmMapper.Save(realm)
+}
+
+// GetByName 根据名称获取realm
+func (r *realmRepository) GetByName(ctx context.Context, name string) (*model.Realm, error) {
+	return r.realmMapper.GetByName(name)
+}
+
+// GetDefault 获取默认realm
+func (r *realmRepository) GetDefault(ctx context.Context) (*model.Realm, error) {
+	return r.realmMapper.GetDefault()
+}
+
+// Update 更新realm
+func (r *realmRepository) Update(ctx context.Context, realm *model.Realm) error {
+	return r.realmMapper.Update(realm)
+}
+
+// ListAll 列出所有realm
+func (r *realmRepository) ListAll(ctx context.Context) ([]*model.Realm, error) {
+	entities, err := r.realmMapper.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	
+	var realms []*model.Realm
+	for _, entity := range entities {
+		if realm, ok := entity.(*model.Realm); ok {
+			realms = append(realms, realm)
+		}
+	}
+	
+	return realms, nil
+}
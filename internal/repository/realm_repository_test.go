@@ -0,0 +1,88 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/repository"
+)
+
+func listRealms(t *testing.T, config string) ([]string, []uint, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "realms.json")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("REALMS_CONFIG_PATH", path)
+	t.Setenv("DEFAULT_REALM", "")
+
+	realms, err := repository.NewRealmRepository().ListAll(context.Background())
+	var names []string
+	var ids []uint
+	for _, realm := range realms {
+		names = append(names, realm.Name)
+		ids = append(ids, realm.ID)
+	}
+	return names, ids, err
+}
+
+func TestRealmConfigUsesExplicitIDs(t *testing.T) {
+	// 配置顺序与ID无关，调整顺序不会改变realm ID
+	names, ids, err := listRealms(t, `[
+		{"id": 7, "name": "anime"},
+		{"id": 3, "name": "default"},
+		{"id": 5, "name": "music"}
+	]`)
+	if err != nil {
+		t.Fatalf("ListAll: %v", err)
+	}
+	want := map[string]uint{"default": 3, "music": 5, "anime": 7}
+	if len(names) != len(want) {
+		t.Fatalf("realms = %v, want %v", names, want)
+	}
+	for i, name := range names {
+		if want[name] != ids[i] {
+			t.Errorf("realm %s id = %d, want %d", name, ids[i], want[name])
+		}
+	}
+}
+
+func TestRealmConfigDefaultRealmHasFixedID(t *testing.T) {
+	names, ids, err := listRealms(t, `[{"id": 2, "name": "anime"}]`)
+	if err != nil {
+		t.Fatalf("ListAll: %v", err)
+	}
+	if len(names) != 2 || names[0] != "default" || ids[0] != 1 {
+		t.Errorf("realms = %v ids = %v, want default realm with id 1 first", names, ids)
+	}
+}
+
+func TestRealmConfigRejectsInvalidDefinitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"missing id", `[{"name": "anime"}]`, "realm id is required"},
+		{"duplicate id", `[{"id": 2, "name": "anime"}, {"id": 2, "name": "music"}]`, "duplicate realm id"},
+		{"duplicate id with implicit default", `[{"id": 1, "name": "anime"}]`, "duplicate realm id"},
+		{"duplicate name", `[{"id": 2, "name": "anime"}, {"id": 3, "name": "anime"}]`, "realm already exists"},
+		{"uppercase name", `[{"id": 2, "name": "Anime"}]`, "invalid realm name"},
+		{"path separator", `[{"id": 2, "name": "../anime"}]`, "invalid realm name"},
+		{"cookie separator", `[{"id": 2, "name": "a;b"}]`, "invalid realm name"},
+		{"empty name", `[{"id": 2, "name": ""}]`, "invalid realm name"},
+		{"malformed json", `[{"id": 2, "name": "anime"}`, "无法解析realm配置文件"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := listRealms(t, tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ListAll error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// RecoveryCodeRepository 恢复码仓库接口
+type RecoveryCodeRepository interface {
+	// Create 保存恢复码
+	Create(ctx context.Context, code *model.RecoveryCode) error
+	
+	// ListByUserID 获取用户的全部恢复码
+	ListByUserID(ctx context.Context, userID uint) ([]*model.RecoveryCode, error)
+	
+	// Update 更新恢复码
+	Update(ctx context.Context, code *model.RecoveryCode) error
+	
+	// DeleteByUserID 删除用户的全部恢复码
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
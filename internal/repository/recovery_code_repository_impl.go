@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// recoveryCodeRepository 恢复码仓库实现
+type recoveryCodeRepository struct {
+	codeMapper mapper.RecoveryCodeMapper
+}
+
+// NewRecoveryCodeRepository 创建RecoveryCodeRepository实例
+func NewRecoveryCodeRepository() RecoveryCodeRepository {
+	return &recoveryCodeRepository{
+		codeMapper: mapper.NewRecoveryCodeMapper(),
+	}
+}
+
+// Create 保存恢复码
+func (r *recoveryCodeRepository) Create(ctx context.Context, code *model.RecoveryCode) error {
+	return r.codeMapper.Save(code)
+}
+
+// ListByUserID 获取用户的全部恢复码
+func (r *recoveryCodeRepository) ListByUserID(ctx context.Context, userID uint) ([]*model.RecoveryCode, error) {
+	return r.codeMapper.ListByUserID(userID)
+}
+
+// Update 更新恢复码
+func (r *recoveryCodeRepository) Update(ctx context.Context, code *model.RecoveryCode) error {
+	return r.codeMapper.Update(code)
+}
+
+// DeleteByUserID 删除用户的全部恢复码
+func (r *recoveryCodeRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.codeMapper.DeleteByUserID(userID)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// RefreshTokenRepository 刷新令牌仓库接口
+type RefreshTokenRepository interface {
+	// Create 保存刷新令牌
+	Create(ctx context.Context, token *model.RefreshToken) error
+	
+	// GetByTokenHash 根据令牌哈希获取刷新令牌
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	
+	// Revoke 撤销刷新令牌
+	Revoke(ctx context.Context, token *model.RefreshToken) error
+	
+	// RevokeByUserID 撤销用户所有未撤销的刷新令牌
+	RevokeByUserID(ctx context.Context, userID uint) error
+	
+	// ListByUserID 获取用户的全部刷新令牌，包括已撤销的令牌
+	ListByUserID(ctx context.Context, userID uint) ([]*model.RefreshToken, error)
+	
+	// DeleteByUserID 删除用户的全部刷新令牌
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// refreshTokenRepository 刷新令牌仓库实现
+type refreshTokenRepository struct {
+	tokenMapper mapper.RefreshTokenMapper
+}
+
+// NewRefreshTokenRepository 创建RefreshTokenRepository实例
+func NewRefreshTokenRepository() RefreshTokenRepository {
+	return &refreshTokenRepository{
+		tokenMapper: mapper.NewRefreshTokenMapper(),
+	}
+}
+
+// Create 保存刷新令牌
+func (r *refreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	return r.tokenMapper.Save(token)
+}
+
+// GetByTokenHash 根据令牌哈希获取刷新令牌
+func (r *refreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	return r.tokenMapper.GetByTokenHash(tokenHash)
+}
+
+// Revoke 撤销刷新令牌，保留记录以便识别重放
+func (r *refreshTokenRepository) Revoke(ctx context.Context, token *model.RefreshToken) error {
+	token.RevokedAt = time.Now()
+	return r.tokenMapper.Update(token)
+}
+
+// RevokeByUserID 撤销用户所有未撤销的刷新令牌
+func (r *refreshTokenRepository) RevokeByUserID(ctx context.Context, userID uint) error {
+	tokens, err := r.tokenMapper.ListByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if !token.RevokedAt.IsZero() {
+			continue
+		}
+		if err := r.Revoke(ctx, token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListByUserID 获取用户的全部刷新令牌
+func (r *refreshTokenRepository) ListByUserID(ctx context.Context, userID uint) ([]*model.RefreshToken, error) {
+	return r.tokenMapper.ListByUserID(userID)
+}
+
+// DeleteByUserID 删除用户的全部刷新令牌
+func (r *refreshTokenRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	tokens, err := r.tokenMapper.ListByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := r.tokenMapper.DeleteByID(token.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// RoleRepository 角色仓库接口
+type RoleRepository interface {
+	// Create 创建角色
+	Create(ctx context.Context, role *model.Role) error
+	
+	// GetByName 根据名称获取角色
+	GetByName(ctx context.Context, name string) (*model.Role, error)
+	
+	// Update 更新角色
+	Update(ctx context.Context, role *model.Role) error
+	
+	// DeleteByID 根据ID删除角色
+	DeleteByID(ctx context.Context, id uint) error
+	
+	// ListAll 列出所有角色
+	ListAll(ctx context.Context) ([]*model.Role, error)
+}
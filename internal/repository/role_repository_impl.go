@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// roleRepository 角色仓库实现，实例归属于单个realm
+type roleRepository struct {
+	roleMapper mapper.RoleMapper
+	realmID    uint
+}
+
+// NewRoleRepository 创建realm范围内的RoleRepository实例
+func NewRoleRepository(realmID uint) RoleRepository {
+	return &roleRepository{
+		roleMapper: mapper.NewRoleMapper(),
+		realmID:    realmID,
+	}
+}
+
+// Create 创建角色
+func (r *roleRepository) Create(ctx context.Context, role *model.Role) error {
+	role.RealmID = r.realmID
+	return r.roleMapper.Save(role)
+}
+
+// GetByName 根据名称获取角色
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*model.Role, error) {
+	return r.roleMapper.GetByName(name)
+}
+
+// Update 更新角色
+func (r *roleRepository) Update(ctx context.Context, role *model.Role) error {
+	role.RealmID = r.realmID
+	return r.roleMapper.Update(role)
+}
+
+// DeleteByID 根据ID删除角色
+func (r *roleRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.roleMapper.DeleteByID(id)
+}
+
+// ListAll 列出所有角色
+func (r *roleRepository) ListAll(ctx context.Context) ([]*model.Role, error) {
+	entities, err := r.roleMapper.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []*model.Role
+	for _, entity := range entities {
+		if role, ok := entity.(*model.Role); ok {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles, nil
+}
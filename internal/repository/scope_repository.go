@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ScopeRepository scope仓库接口
+type ScopeRepository interface {
+	// Create 创建scope
+	Create(ctx context.Context, scope *model.Scope) error
+	
+	// GetByName 根据名称获取scope，scope不存在时返回nil
+	GetByName(ctx context.Context, name string) (*model.Scope, error)
+	
+	// Update 更新scope
+	Update(ctx context.Context, scope *model.Scope) error
+	
+	// DeleteByID 根据ID删除scope
+	DeleteByID(ctx context.Context, id uint) error
+	
+	// ListAll 列出所有scope
+	ListAll(ctx context.Context) ([]*model.Scope, error)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// scopeRepository scope仓库实现，实例归属于单个realm
+type scopeRepository struct {
+	scopeMapper mapper.ScopeMapper
+	realmID     uint
+}
+
+// NewScopeRepository 创建realm范围内的ScopeRepository实例
+func NewScopeRepository(realmID uint) ScopeRepository {
+	return &scopeRepository{
+		scopeMapper: mapper.NewScopeMapper(),
+		realmID:     realmID,
+	}
+}
+
+// Create 创建scope
+func (r *scopeRepository) Create(ctx context.Context, scope *model.Scope) error {
+	scope.RealmID = r.realmID
+	return r.scopeMapper.Save(scope)
+}
+
+// GetByName 根据名称获取scope，scope不存在时返回nil
+func (r *scopeRepository) GetByName(ctx context.Context, name string) (*model.Scope, error) {
+	scope, err := r.scopeMapper.GetByName(name)
+	if errors.Is(err, mapper.ErrScopeNotFound) {
+		return nil, nil
+	}
+	return scope, err
+}
+
+// Update 更新scope
+func (r *scopeRepository) Update(ctx context.Context, scope *model.Scope) error {
+	scope.RealmID = r.realmID
+	return r.scopeMapper.Update(scope)
+}
+
+// DeleteByID 根据ID删除scope
+func (r *scopeRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.scopeMapper.DeleteByID(id)
+}
+
+// ListAll 列出所有scope
+func (r *scopeRepository) ListAll(ctx context.Context) ([]*model.Scope, error) {
+	entities, err := r.scopeMapper.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	
+	var scopes []*model.Scope
+	for _, entity := range entities {
+		if scope, ok := entity.(*model.Scope); ok {
+			scopes = append(scopes, scope)
+		}
+	}
+	
+	return scopes, nil
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// SessionRepository 登录会话仓库接口
+type SessionRepository interface {
+	// Create 创建会话
+	Create(ctx context.Context, session *model.Session) error
+	
+	// GetByID 根据ID获取会话
+	GetByID(ctx context.Context, id string) (*model.Session, error)
+	
+	// DeleteByID 根据ID删除会话
+	DeleteByID(ctx context.Context, id string) error
+	
+	// DeleteByUserID 删除用户的所有会话
+	DeleteByUserID(ctx context.Context, userID uint) error
+	
+	// ListByUserID 获取用户的所有会话
+	ListByUserID(ctx context.Context, userID uint) ([]*model.Session, error)
+}
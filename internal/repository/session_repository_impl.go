@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// sessionRepository 登录会话仓库实现，实例归属于单个realm
+type sessionRepository struct {
+	sessionMapper mapper.SessionMapper
+	realmID       uint
+}
+
+// NewSessionRepository 创建realm范围内的SessionRepository实例
+func NewSessionRepository(realmID uint) SessionRepository {
+	return &sessionRepository{
+		sessionMapper: mapper.NewSessionMapper(),
+		realmID:       realmID,
+	}
+}
+
+// Create 创建会话
+func (r *sessionRepository) Create(ctx context.Context, session *model.Session) error {
+	session.RealmID = r.realmID
+	return r.sessionMapper.Save(session)
+}
+
+// GetByID 根据ID获取会话
+func (r *sessionRepository) GetByID(ctx context.Context, id string) (*model.Session, error) {
+	entity, err := r.sessionMapper.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	
+	session, ok := entity.(*model.Session)
+	if !ok {
+		return nil, errors.New("invalid session entity")
+	}
+	
+	return session, nil
+}
+
+// DeleteByID 根据ID删除会话
+func (r *sessionRepository) DeleteByID(ctx context.Context, id string) error {
+	return r.sessionMapper.DeleteByID(id)
+}
+
+// DeleteByUserID 删除用户的所有会话
+func (r *sessionRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.sessionMapper.DeleteByUserID(userID)
+}
+
+// ListByUserID 获取用户的所有会话
+func (r *sessionRepository) ListByUserID(ctx context.Context, userID uint) ([]*model.Session, error) {
+	return r.sessionMapper.GetByUserID(userID)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// TOTPCredentialRepository TOTP认证器仓库接口
+type TOTPCredentialRepository interface {
+	// Create 保存TOTP认证器
+	Create(ctx context.Context, credential *model.TOTPCredential) error
+	
+	// GetByUserID 根据用户ID获取TOTP认证器
+	GetByUserID(ctx context.Context, userID uint) (*model.TOTPCredential, error)
+	
+	// Update 更新TOTP认证器
+	Update(ctx context.Context, credential *model.TOTPCredential) error
+	
+	// DeleteByID 根据ID删除TOTP认证器
+	DeleteByID(ctx context.Context, id uint) error
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// totpCredentialRepository TOTP认证器仓库实现
+type totpCredentialRepository struct {
+	credentialMapper mapper.TOTPCredentialMapper
+}
+
+// NewTOTPCredentialRepository 创建TOTPCredentialRepository实例
+func NewTOTPCredentialRepository() TOTPCredentialRepository {
+	return &totpCredentialRepository{
+		credentialMapper: mapper.NewTOTPCredentialMapper(),
+	}
+}
+
+// Create 保存TOTP认证器
+func (r *totpCredentialRepository) Create(ctx context.Context, credential *model.TOTPCredential) error {
+	return r.credentialMapper.Save(credential)
+}
+
+// GetByUserID 根据用户ID获取TOTP认证器
+func (r *totpCredentialRepository) GetByUserID(ctx context.Context, userID uint) (*model.TOTPCredential, error) {
+	return r.credentialMapper.GetByUserID(userID)
+}
+
+// Update 更新TOTP认证器
+func (r *totpCredentialRepository) Update(ctx context.Context, credential *model.TOTPCredential) error {
+	return r.credentialMapper.Update(credential)
+}
+
+// DeleteByID 根据ID删除TOTP认证器
+func (r *totpCredentialRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.credentialMapper.DeleteByID(id)
+}
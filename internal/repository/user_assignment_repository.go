@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+)
+
+// UserAssignmentRepository 用户角色/用户组分配仓库接口
+type UserAssignmentRepository interface {
+	// ListByUser 获取用户某一类型的分配名称
+	ListByUser(ctx context.Context, userID uint, kind string) ([]string, error)
+	
+	// ReplaceForUser 用给定名称替换用户某一类型的全部分配
+	ReplaceForUser(ctx context.Context, userID uint, kind string, names []string) error
+	
+	// DeleteByName 删除指定角色或用户组的全部分配
+	DeleteByName(ctx context.Context, kind, name string) error
+}
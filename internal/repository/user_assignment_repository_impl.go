@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// userAssignmentRepository 用户角色/用户组分配仓库实现
+type userAssignmentRepository struct {
+	assignmentMapper mapper.UserAssignmentMapper
+}
+
+// NewUserAssignmentRepository 创建UserAssignmentRepository实例
+func NewUserAssignmentRepository() UserAssignmentRepository {
+	return &userAssignmentRepository{
+		assignmentMapper: mapper.NewUserAssignmentMapper(),
+	}
+}
+
+// ListByUser 获取用户某一类型的分配名称
+func (r *userAssignmentRepository) ListByUser(ctx context.Context, userID uint, kind string) ([]string, error) {
+	assignments, err := r.assignmentMapper.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	
+	names := []string{}
+	for _, assignment := range assignments {
+		if assignment.Kind == kind {
+			names = append(names, assignment.Name)
+		}
+	}
+	
+	return names, nil
+}
+
+// ReplaceForUser 用给定名称替换用户某一类型的全部分配
+func (r *userAssignmentRepository) ReplaceForUser(ctx context.Context, userID uint, kind string, names []string) error {
+	if err := r.assignmentMapper.DeleteByUserAndKind(userID, kind); err != nil {
+		return err
+	}
+	
+	for _, name := range names {
+		assignment := &model.UserAssignment{
+			UserID: userID,
+			Kind:   kind,
+			Name:   name,
+		}
+		if err := r.assignmentMapper.Save(assignment); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
+// DeleteByName 删除指定角色或用户组的全部分配
+func (r *userAssignmentRepository) DeleteByName(ctx context.Context, kind, name string) error {
+	return r.assignmentMapper.DeleteByKindAndName(kind, name)
+}
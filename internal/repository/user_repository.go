@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/Full-finger/OIDC/internal/model"
 )
 
@@ -23,4 +25,10 @@ type UserRepository interface {
 
 	// UpdateActivationStatus 更新用户激活状态
 	UpdateActivationStatus(id uint, isActive bool) error
+
+	// Delete 删除用户
+	Delete(id uint) error
+
+	// ListDeletionDue 获取计划删除时间不晚于before的用户
+	ListDeletionDue(before time.Time) ([]*model.User, error)
 }
\ No newline at end of file
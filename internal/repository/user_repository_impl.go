@@ -3,6 +3,7 @@ package repository
 import (
 	"errors"
 	"sync"
+	"time"
 	
 	"github.com/Full-finger/OIDC/internal/mapper"
 	"github.com/Full-finger/OIDC/internal/model"
@@ -14,6 +15,7 @@ type userRepository struct {
 	mapper mapper.UserMapper
 	// 内存存储
 	memoryStore map[string]*model.User
+	nextID      uint
 	mu          sync.RWMutex
 }
 
@@ -22,6 +24,7 @@ func NewUserRepository(mapper mapper.UserMapper) UserRepository {
 	return &userRepository{
 		mapper:      mapper,
 		memoryStore: make(map[string]*model.User),
+		nextID:      1,
 	}
 }
 
@@ -31,6 +34,11 @@ func (r *userRepository) Create(user *model.User) error {
 		// 内存模式，存储用户信息
 		r.mu.Lock()
 		defer r.mu.Unlock()
+		// 分配用户ID，角色分配等按用户ID关联的数据依赖ID唯一
+		if user.ID == 0 {
+			user.ID = r.nextID
+			r.nextID++
+		}
 		r.memoryStore[user.Username] = user
 		r.memoryStore[user.Email] = user
 		return nil
@@ -119,6 +127,12 @@ func (r *userRepository) Update(user *model.User) error {
 		// 内存模式，更新用户信息
 		r.mu.Lock()
 		defer r.mu.Unlock()
+		// 邮箱变更后删除指向该用户的旧键
+		for key, existing := range r.memoryStore {
+			if existing.ID == user.ID && key != user.Username && key != user.Email {
+				delete(r.memoryStore, key)
+			}
+		}
 		r.memoryStore[user.Username] = user
 		r.memoryStore[user.Email] = user
 		return nil
@@ -132,13 +146,11 @@ func (r *userRepository) Delete(id uint) error {
 		// 内存模式，查找并删除用户
 		r.mu.Lock()
 		defer r.mu.Unlock()
-		for key, user := range r.memoryStore {
+		for _, user := range r.memoryStore {
 			if user.ID == id {
-				delete(r.memoryStore, key)
-				// 如果用户名和邮箱不同，也要删除邮箱键
-				if key != user.Email {
-					delete(r.memoryStore, user.Email)
-				}
+				// 同时删除用户名键和邮箱键
+				delete(r.memoryStore, user.Username)
+				delete(r.memoryStore, user.Email)
 				break
 			}
 		}
@@ -165,4 +177,24 @@ func (r *userRepository) UpdateActivationStatus(id uint, isActive bool) error {
 		return nil
 	}
 	return r.mapper.UpdateActivationStatus(id, isActive)
+}
+
+// ListDeletionDue 获取计划删除时间不晚于before的用户
+func (r *userRepository) ListDeletionDue(before time.Time) ([]*model.User, error) {
+	if r.mapper == nil {
+		// 内存模式，用户名键和邮箱键指向同一用户，按ID去重
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		seen := make(map[uint]bool)
+		var users []*model.User
+		for _, user := range r.memoryStore {
+			if seen[user.ID] || user.DeletionScheduledAt == nil || user.DeletionScheduledAt.After(before) {
+				continue
+			}
+			seen[user.ID] = true
+			users = append(users, user)
+		}
+		return users, nil
+	}
+	return r.mapper.ListDeletionDue(before)
 }
\ No newline at end of file
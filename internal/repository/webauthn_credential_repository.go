@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// WebAuthnCredentialRepository WebAuthn凭据仓库接口
+type WebAuthnCredentialRepository interface {
+	// Create 保存WebAuthn凭据
+	Create(ctx context.Context, credential *model.WebAuthnCredential) error
+	
+	// GetByID 根据ID获取WebAuthn凭据
+	GetByID(ctx context.Context, id uint) (*model.WebAuthnCredential, error)
+	
+	// GetByCredentialID 根据凭据ID获取WebAuthn凭据
+	GetByCredentialID(ctx context.Context, credentialID string) (*model.WebAuthnCredential, error)
+	
+	// ListByUserID 获取用户的全部WebAuthn凭据
+	ListByUserID(ctx context.Context, userID uint) ([]*model.WebAuthnCredential, error)
+	
+	// Update 更新WebAuthn凭据
+	Update(ctx context.Context, credential *model.WebAuthnCredential) error
+	
+	// DeleteByID 根据ID删除WebAuthn凭据
+	DeleteByID(ctx context.Context, id uint) error
+	
+	// DeleteByUserID 删除用户的全部WebAuthn凭据
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// webAuthnCredentialRepository WebAuthn凭据仓库实现
+type webAuthnCredentialRepository struct {
+	credentialMapper mapper.WebAuthnCredentialMapper
+}
+
+// NewWebAuthnCredentialRepository 创建WebAuthnCredentialRepository实例
+func NewWebAuthnCredentialRepository() WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{
+		credentialMapper: mapper.NewWebAuthnCredentialMapper(),
+	}
+}
+
+// Create 保存WebAuthn凭据
+func (r *webAuthnCredentialRepository) Create(ctx context.Context, credential *model.WebAuthnCredential) error {
+	return r.credentialMapper.Save(credential)
+}
+
+// GetByID 根据ID获取WebAuthn凭据
+func (r *webAuthnCredentialRepository) GetByID(ctx context.Context, id uint) (*model.WebAuthnCredential, error) {
+	entity, err := r.credentialMapper.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return entity.(*model.WebAuthnCredential), nil
+}
+
+// GetByCredentialID 根据凭据ID获取WebAuthn凭据
+func (r *webAuthnCredentialRepository) GetByCredentialID(ctx context.Context, credentialID string) (*model.WebAuthnCredential, error) {
+	return r.credentialMapper.GetByCredentialID(credentialID)
+}
+
+// ListByUserID 获取用户的全部WebAuthn凭据
+func (r *webAuthnCredentialRepository) ListByUserID(ctx context.Context, userID uint) ([]*model.WebAuthnCredential, error) {
+	return r.credentialMapper.ListByUserID(userID)
+}
+
+// Update 更新WebAuthn凭据
+func (r *webAuthnCredentialRepository) Update(ctx context.Context, credential *model.WebAuthnCredential) error {
+	return r.credentialMapper.Update(credential)
+}
+
+// DeleteByID 根据ID删除WebAuthn凭据
+func (r *webAuthnCredentialRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.credentialMapper.DeleteByID(id)
+}
+
+// DeleteByUserID 删除用户的全部WebAuthn凭据
+func (r *webAuthnCredentialRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	return r.credentialMapper.DeleteByUserID(userID)
+}
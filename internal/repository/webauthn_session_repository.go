@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// WebAuthnSessionRepository WebAuthn仪式仓库接口
+type WebAuthnSessionRepository interface {
+	// Create 保存WebAuthn仪式
+	Create(ctx context.Context, session *model.WebAuthnSession) error
+	
+	// GetByChallenge 根据挑战获取WebAuthn仪式
+	GetByChallenge(ctx context.Context, challenge string) (*model.WebAuthnSession, error)
+	
+	// DeleteByID 根据ID删除WebAuthn仪式
+	DeleteByID(ctx context.Context, id uint) error
+}
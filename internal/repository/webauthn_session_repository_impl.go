@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// webAuthnSessionRepository WebAuthn仪式仓库实现
+type webAuthnSessionRepository struct {
+	sessionMapper mapper.WebAuthnSessionMapper
+}
+
+// NewWebAuthnSessionRepository 创建WebAuthnSessionRepository实例
+func NewWebAuthnSessionRepository() WebAuthnSessionRepository {
+	return &webAuthnSessionRepository{
+		sessionMapper: mapper.NewWebAuthnSessionMapper(),
+	}
+}
+
+// Create 保存WebAuthn仪式
+func (r *webAuthnSessionRepository) Create(ctx context.Context, session *model.WebAuthnSession) error {
+	return r.sessionMapper.Save(session)
+}
+
+// GetByChallenge 根据挑战获取WebAuthn仪式
+func (r *webAuthnSessionRepository) GetByChallenge(ctx context.Context, challenge string) (*model.WebAuthnSession, error) {
+	return r.sessionMapper.GetByChallenge(challenge)
+}
+
+// DeleteByID 根据ID删除WebAuthn仪式
+func (r *webAuthnSessionRepository) DeleteByID(ctx context.Context, id uint) error {
+	return r.sessionMapper.DeleteByID(id)
+}
@@ -1,10 +1,17 @@
 package router
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	
@@ -15,11 +22,38 @@ import (
 	"github.com/Full-finger/OIDC/internal/util"
 	"github.com/Full-finger/OIDC/internal/middleware"
 	"github.com/Full-finger/OIDC/internal/mapper"
+	"github.com/Full-finger/OIDC/internal/model"
 )
 
+// sharedDependencies 所有realm共享的依赖
+type sharedDependencies struct {
+	db          *gorm.DB
+	emailQueue  util.EmailQueue
+	animeRepo   repository.AnimeRepository
+	rateLimiter *middleware.RateLimiter
+	redisClient *redis.Client // 无法连接Redis时为nil
+	notifier    util.Notifier
+
+	passwordBlocklist map[string]struct{}
+	breachedChecker   util.BreachedPasswordChecker // 未配置泄露密码库时为nil
+	passwordHasher    util.PasswordHasher
+	avatarStorage     util.AvatarStorage // 存储配置无效时为nil，此时无法上传头像
+}
+
 // SetupRouter 设置路由
 func SetupRouter() *gin.Engine {
+	// issuer只取自配置，未配置时拒绝启动，避免退回到由请求头推导
+	if err := util.ValidateIssuerConfig(); err != nil {
+		log.Fatalf("issuer配置无效: %v", err)
+	}
+
 	r := gin.Default()
+	// 只信任配置的反向代理转发的客户端IP，未配置时使用连接的来源地址，避免伪造X-Forwarded-For绕过按IP的登录限制
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatalf("TRUSTED_PROXIES配置无效: %v", err)
+	}
+	r.Use(middleware.IssuerMiddleware())
+	r.Use(middleware.RequestMetadataMiddleware())
 
 	// 初始化数据库连接
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Shanghai",
@@ -37,32 +71,192 @@ func SetupRouter() *gin.Engine {
 		db = nil
 	}
 
+	// 初始化共享依赖，番剧目录在所有realm之间共享
+	shared := &sharedDependencies{
+		db:          db,
+		emailQueue:  util.NewSimpleEmailQueue(),
+		animeRepo:   repository.NewAnimeRepository(),
+		rateLimiter: middleware.NewRateLimiter(),
+	}
+	shared.notifier = newNotifier(shared.emailQueue)
+	
+	// 登录失败计数保存在Redis中，Redis不可用时使用内存存储
+	redisClient := util.NewRedisClient()
+	pingCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	if err := redisClient.Ping(pingCtx).Err(); err != nil {
+		fmt.Printf("警告: 无法连接到Redis: %v\n", err)
+		redisClient.Close()
+	} else {
+		shared.redisClient = redisClient
+	}
+	cancel()
+
+	// 加载密码黑名单和本地泄露密码库，所有realm共享
+	blocklist, err := util.LoadPasswordBlocklist(os.Getenv("PASSWORD_BLOCKLIST_PATH"))
+	if err != nil {
+		fmt.Printf("警告: 无法加载密码黑名单: %v，将只使用内置列表\n", err)
+		blocklist, _ = util.LoadPasswordBlocklist("")
+	}
+	shared.passwordBlocklist = blocklist
+	if path := os.Getenv("PWNED_PASSWORDS_PATH"); path != "" {
+		checker, err := util.NewBreachedPasswordChecker(path)
+		if err != nil {
+			fmt.Printf("警告: 无法加载泄露密码库: %v\n", err)
+		} else {
+			shared.breachedChecker = checker
+		}
+	}
+
+	// 初始化密码哈希算法，配置无效时退回默认参数的argon2id
+	passwordHasher, err := util.NewPasswordHasherFromEnv()
+	if err != nil {
+		fmt.Printf("警告: 密码哈希配置无效: %v，将使用默认的argon2id参数\n", err)
+		passwordHasher, _ = util.NewPasswordHasher(util.PasswordHashArgon2id, util.DefaultArgon2Params(), 0)
+	}
+	shared.passwordHasher = passwordHasher
+
+	// 初始化头像存储，本地存储的头像由服务自身提供
+	avatarStorage, err := util.NewAvatarStorageFromEnv()
+	if err != nil {
+		fmt.Printf("警告: 无法初始化头像存储: %v\n", err)
+	} else {
+		shared.avatarStorage = avatarStorage
+		if os.Getenv("AVATAR_STORAGE") == "" || os.Getenv("AVATAR_STORAGE") == "local" {
+			r.Static(util.LocalAvatarPath, util.AvatarStorageDir())
+		}
+	}
+
+	// 加载realm，默认realm挂载在根路径，其余realm挂载在 /realms/{name}
+	realmRepo := repository.NewRealmRepository()
+	realms, err := realmRepo.ListAll(context.Background())
+	if err != nil {
+		log.Fatalf("无法加载realm: %v", err)
+	}
+
+	for _, realm := range realms {
+		group := r.Group(realm.PathPrefix(), middleware.RealmMiddleware(realm))
+		registerRealmRoutes(group, realm, shared)
+	}
+
+	return r
+}
+
+// registerRealmRoutes 为单个realm创建独立的仓库、服务和处理器并注册路由
+func registerRealmRoutes(r *gin.RouterGroup, realm *model.Realm, shared *sharedDependencies) {
+	// 初始化realm签名密钥
+	jwtUtil := newRealmJWTUtil(realm)
+
 	// 初始化依赖
 	var userRepo repository.UserRepository
 	var userMapper mapper.UserMapper
 	
-	if db != nil {
-		userMapper = mapper.NewUserMapper(db)
+	if shared.db != nil {
+		userMapper = mapper.NewUserMapper(shared.db, realm.ID)
 		userRepo = repository.NewUserRepository(userMapper)
 	} else {
 		// 使用内存存储
 		userRepo = repository.NewUserRepository(nil)
 	}
 	
-	userHelper := helper.NewUserHelper()
+	// 初始化安全审计，审计事件只追加，数据库不可用时保存在内存中
+	var auditRepo repository.AuditEventRepository
+	if shared.db != nil {
+		auditRepo = repository.NewAuditEventRepository(mapper.NewAuditEventMapper(shared.db, realm.ID))
+	} else {
+		auditRepo = repository.NewAuditEventRepository(nil)
+	}
+	auditService := service.NewAuditService(auditRepo)
+	
+	userHelper := helper.NewUserHelper(shared.passwordHasher)
 	tokenRepo := repository.NewVerificationTokenRepository()
-	emailQueue := util.NewSimpleEmailQueue()
 	
-	userService := service.NewUserService(userRepo, userHelper, tokenRepo, emailQueue)
-	userHandler := handler.NewUserHandler(userService)
+	// 初始化角色与用户组
+	rbacService := service.NewRBACService(
+		repository.NewRoleRepository(realm.ID),
+		repository.NewGroupRepository(realm.ID),
+		repository.NewUserAssignmentRepository(),
+	)
+	if err := rbacService.SeedRBAC(context.Background(), realm.Roles, realm.Groups); err != nil {
+		fmt.Printf("警告: realm %s 无法加载角色和用户组: %v\n", realm.Name, err)
+	}
+	
+	passwordPolicyService := service.NewPasswordPolicyService(realm, shared.passwordBlocklist, shared.breachedChecker)
+	userService := service.NewUserService(userRepo, userHelper, tokenRepo, shared.emailQueue, jwtUtil, realm, rbacService, passwordPolicyService, shared.passwordHasher)
+	sessionRepo := repository.NewSessionRepository(realm.ID)
+	sessionService := service.NewSessionService(sessionRepo, auditService)
+	webAuthnCredentialRepo := repository.NewWebAuthnCredentialRepository()
+	mfaService := service.NewMFAService(
+		repository.NewTOTPCredentialRepository(),
+		repository.NewRecoveryCodeRepository(),
+		repository.NewMFAChallengeRepository(),
+		webAuthnCredentialRepo,
+		userRepo,
+		auditService,
+		realm,
+	)
+	loginGuardService := service.NewLoginGuardService(repository.NewLoginAttemptRepository(shared.redisClient), userRepo, shared.emailQueue, auditService, realm)
+	profileService := service.NewProfileService(userRepo, userHelper, shared.avatarStorage, realm)
+	userHandler := handler.NewUserHandler(userService, sessionService, mfaService, loginGuardService, profileService)
+	mfaHandler := handler.NewMFAHandler(mfaService, userService, sessionService)
+	webAuthnService := service.NewWebAuthnService(webAuthnCredentialRepo, repository.NewWebAuthnSessionRepository(), userRepo, realm)
+	webAuthnHandler := handler.NewWebAuthnHandler(webAuthnService, mfaService, userService, sessionService)
 	verificationHandler := handler.NewVerificationHandler(userService)
+	refreshRepo := repository.NewRefreshTokenRepository()
+	passwordResetRepo := repository.NewPasswordResetTokenRepository()
+	passwordResetService := service.NewPasswordResetService(passwordResetRepo, userRepo, refreshRepo, sessionService, shared.emailQueue, passwordPolicyService, shared.passwordHasher, auditService, realm)
+	passwordResetHandler := handler.NewPasswordResetHandler(passwordResetService, passwordPolicyService, userService, sessionService)
+	emailChangeRepo := repository.NewEmailChangeRequestRepository()
+	emailChangeService := service.NewEmailChangeService(emailChangeRepo, userRepo, refreshRepo, sessionService, shared.emailQueue, auditService, realm)
+	emailChangeHandler := handler.NewEmailChangeHandler(emailChangeService, sessionService)
 
 	// 初始化OAuth依赖
-	oauthService := service.NewOAuthService()
-	oauthHandler := handler.NewOAuthHandler(oauthService)
+	clientRepo := repository.NewClientRepository(realm.ID)
+	for i := range realm.Clients {
+		clientID := realm.Clients[i].ClientID
+		if err := service.ValidateClientRegistration(&realm.Clients[i]); err != nil {
+			fmt.Printf("警告: realm %s 忽略客户端 %s: %v\n", realm.Name, clientID, err)
+			recordClientRegistration(auditService, clientID, err)
+			continue
+		}
+		if err := clientRepo.Create(context.Background(), &realm.Clients[i]); err != nil {
+			fmt.Printf("警告: realm %s 无法加载客户端 %s: %v\n", realm.Name, clientID, err)
+			recordClientRegistration(auditService, clientID, err)
+			continue
+		}
+		recordClientRegistration(auditService, clientID, nil)
+	}
+	scopeService := service.NewScopeService(repository.NewScopeRepository(realm.ID))
+	if err := scopeService.SeedScopes(context.Background(), realm.Scopes); err != nil {
+		fmt.Printf("警告: realm %s 无法加载scope: %v\n", realm.Name, err)
+	}
+	authCodeRepo := repository.NewAuthorizationCodeRepository()
+	consentRepo := repository.NewConsentRepository()
+	cibaService := service.NewCIBAService(repository.NewBackchannelAuthRequestRepository(), userRepo, clientRepo, scopeService, jwtUtil, shared.notifier, mfaService, realm)
+	parRepo := repository.NewPushedAuthorizationRequestRepository()
+	detailsService := service.NewAuthorizationDetailsService()
+	oauthService := service.NewOAuthService(jwtUtil, clientRepo, authCodeRepo, refreshRepo, consentRepo, parRepo, scopeService, detailsService, rbacService, cibaService, mfaService, userRepo, userHelper, auditService, realm)
+	oauthHandler := handler.NewOAuthHandler(oauthService, sessionService, scopeService, detailsService, mfaService)
+	cibaHandler := handler.NewCIBAHandler(oauthService, cibaService, sessionService, scopeService)
+	scopeHandler := handler.NewScopeHandler(scopeService)
+	rbacHandler := handler.NewRBACHandler(rbacService, userService)
+	realmHandler := handler.NewRealmHandler(realm)
+
+	// 初始化上游身份提供方登录
+	identityProviders := make([]model.IdentityProvider, 0, len(realm.IdentityProviders))
+	for _, provider := range realm.IdentityProviders {
+		if err := service.ValidateIdentityProvider(&provider); err != nil {
+			fmt.Printf("警告: realm %s 忽略身份提供方 %s: %v\n", realm.Name, provider.Name, err)
+			continue
+		}
+		identityProviders = append(identityProviders, provider)
+	}
+	identityRepo := repository.NewExternalIdentityRepository()
+	federationStateRepo := repository.NewFederatedLoginStateRepository()
+	federationService := service.NewFederationService(identityProviders, identityRepo, federationStateRepo, userRepo, auditService)
+	federationHandler := handler.NewFederationHandler(federationService, sessionService, mfaService)
 
 	// 初始化番剧收藏依赖
-	animeRepo := repository.NewAnimeRepository()
+	animeRepo := shared.animeRepo
 	animeService := service.NewAnimeService(animeRepo)
 	animeHandler := handler.NewAnimeHandler(animeService)
 
@@ -73,11 +267,24 @@ func SetupRouter() *gin.Engine {
 
 	// 初始化Bangumi依赖
 	bangumiRepo := repository.NewBangumiRepository()
-	bangumiService := service.NewBangumiService(bangumiRepo, animeRepo, collectionRepo)
+	bangumiService := service.NewBangumiService(bangumiRepo, animeRepo, collectionRepo, identityRepo, userRepo, auditService)
 	bangumiHandler := handler.NewBangumiHandler(bangumiService)
+	bangumiLoginService := service.NewBangumiLoginService(bangumiService, bangumiRepo, userRepo, federationStateRepo, repository.NewBangumiPendingLoginRepository(), identityRepo)
+	bangumiLoginHandler := handler.NewBangumiLoginHandler(bangumiLoginService, userService, sessionService, mfaService, loginGuardService)
+
+	// 初始化外部身份关联依赖
+	identityService := service.NewIdentityService(identityRepo, userRepo, bangumiService, auditService)
+	identityHandler := handler.NewIdentityHandler(identityService, federationService, bangumiLoginService, sessionService)
+
+	// 初始化账户数据导出与删除依赖，宽限期结束的账户由后台任务定期删除
+	accountService := service.NewAccountService(userRepo, profileService, mfaService, rbacService, collectionRepo, bangumiRepo, identityRepo, consentRepo, refreshRepo, sessionRepo, passwordResetRepo, emailChangeRepo, tokenRepo, shared.emailQueue, auditService, realm)
+	accountHandler := handler.NewAccountHandler(accountService, sessionService)
+	auditHandler := handler.NewAuditHandler(auditService, sessionService)
+	startAccountPurge(realm, accountService)
 
 	// 初始化中间件
-	rateLimiter := middleware.NewRateLimiter()
+	rateLimiter := shared.rateLimiter
+	authMiddleware := middleware.JWTAuthMiddleware(jwtUtil, userRepo)
 
 	// API v1 路由组
 	v1 := r.Group("/api/v1")
@@ -86,8 +293,76 @@ func SetupRouter() *gin.Engine {
 		v1.POST("/register", rateLimiter.LimitByIP(), userHandler.Register)
 		v1.POST("/resend-verification", rateLimiter.LimitByUser(), userHandler.ResendVerificationEmail)
 		v1.POST("/login", userHandler.Login)
+		v1.POST("/login/mfa", mfaHandler.VerifyLoginHandler)
+		// 通行密钥：无用户名登录，以及作为第二步验证
+		v1.POST("/login/webauthn/options", webAuthnHandler.BeginLoginHandler)
+		v1.POST("/login/webauthn", webAuthnHandler.FinishLoginHandler)
+		v1.POST("/login/mfa/webauthn/options", webAuthnHandler.BeginSecondFactorHandler)
+		v1.POST("/login/mfa/webauthn", webAuthnHandler.FinishSecondFactorHandler)
+		v1.POST("/logout", userHandler.Logout)
+		v1.POST("/password/forgot", rateLimiter.LimitByIP(), passwordResetHandler.ForgotPasswordHandler)
+		v1.POST("/password/reset", rateLimiter.LimitByIP(), passwordResetHandler.ResetPasswordHandler)
+		// 修改密码需要登录会话并校验当前密码
+		v1.POST("/password/change", rateLimiter.LimitByIP(), passwordResetHandler.ChangePasswordHandler)
+		v1.GET("/password/policy", passwordResetHandler.GetPolicyHandler)
+		// 使用Bangumi登录：未绑定的Bangumi账号创建新用户或关联已有用户
+		v1.GET("/login/bangumi/pending", bangumiLoginHandler.GetPendingLoginHandler)
+		v1.POST("/login/bangumi/create", rateLimiter.LimitByIP(), bangumiLoginHandler.CreateAccountHandler)
+		v1.POST("/login/bangumi/link", bangumiLoginHandler.LinkAccountHandler)
 		// 邮箱验证路由
 		v1.GET("/verify", verificationHandler.VerifyEmail)
+		// 邮箱变更：申请需要登录会话，确认和撤销使用邮件中的令牌
+		v1.POST("/email/change", rateLimiter.LimitByIP(), emailChangeHandler.RequestChangeHandler)
+		v1.POST("/email/change/confirm", emailChangeHandler.ConfirmChangeHandler)
+		v1.POST("/email/change/revert", emailChangeHandler.RevertChangeHandler)
+		
+		// 用户资料路由，通过访问令牌识别用户
+		me := v1.Group("/me")
+		{
+			me.Use(authMiddleware, middleware.RequireScopes("profile"))
+			me.GET("", userHandler.GetProfile)
+			me.PATCH("", userHandler.UpdateProfile)
+			me.PUT("/avatar", userHandler.UploadAvatarHandler)
+			me.DELETE("/avatar", userHandler.DeleteAvatarHandler)
+		}
+		
+		// 账户数据导出与删除路由，通过登录会话识别用户，导出和申请删除前须重新登录
+		account := v1.Group("/account")
+		{
+			account.GET("/export", accountHandler.ExportHandler)
+			account.POST("/deletion", accountHandler.ScheduleDeletionHandler)
+			account.DELETE("/deletion", accountHandler.CancelDeletionHandler)
+			// 最近的登录、令牌、授权同意和账号绑定等安全活动
+			account.GET("/activity", auditHandler.ListActivityHandler)
+		}
+		
+		// 外部身份关联路由，通过登录会话识别用户，关联前须重新登录
+		identities := v1.Group("/identities")
+		{
+			identities.GET("", identityHandler.ListIdentitiesHandler)
+			identities.GET("/:provider/link", identityHandler.LinkIdentityHandler)
+			identities.DELETE("/:id", identityHandler.UnlinkIdentityHandler)
+		}
+		
+		// 两步验证设置路由，通过登录会话识别用户
+		mfa := v1.Group("/mfa")
+		{
+			mfa.GET("", mfaHandler.GetStatusHandler)
+			mfa.POST("/totp", mfaHandler.BeginTOTPEnrollmentHandler)
+			mfa.POST("/totp/confirm", mfaHandler.ConfirmTOTPEnrollmentHandler)
+			mfa.DELETE("/totp", mfaHandler.DisableTOTPHandler)
+			mfa.POST("/recovery-codes", mfaHandler.RegenerateRecoveryCodesHandler)
+		}
+		
+		// 通行密钥管理路由，通过登录会话识别用户
+		webauthn := v1.Group("/webauthn/credentials")
+		{
+			webauthn.GET("", webAuthnHandler.ListCredentialsHandler)
+			webauthn.POST("/options", webAuthnHandler.BeginRegistrationHandler)
+			webauthn.POST("", webAuthnHandler.FinishRegistrationHandler)
+			webauthn.PATCH("/:id", webAuthnHandler.RenameCredentialHandler)
+			webauthn.DELETE("/:id", webAuthnHandler.DeleteCredentialHandler)
+		}
 		
 		// 番剧收藏路由
 		anime := v1.Group("/anime")
@@ -96,28 +371,60 @@ func SetupRouter() *gin.Engine {
 			anime.GET("/search", animeHandler.SearchAnimesHandler)
 			anime.GET("/list", animeHandler.ListAnimesHandler)
 			anime.GET("/status", animeHandler.ListAnimesByStatusHandler)
-			// 添加创建、更新和删除番剧的路由
-			anime.POST("/", animeHandler.CreateAnimeHandler)
-			anime.PUT("/:id", animeHandler.UpdateAnimeHandler)
-			anime.DELETE("/:id", animeHandler.DeleteAnimeHandler)
+			// 创建、更新和删除番剧需要anime:write权限以及相应角色：
+			// 目录编辑可创建和修改，版主可修改和删除，管理员拥有全部权限
+			animeWrite := middleware.RequireScopes("anime:write")
+			anime.POST("/", authMiddleware, animeWrite, middleware.RequireRoles("catalog_editor", "admin"), animeHandler.CreateAnimeHandler)
+			anime.PUT("/:id", authMiddleware, animeWrite, middleware.RequireRoles("catalog_editor", "moderator", "admin"), animeHandler.UpdateAnimeHandler)
+			anime.DELETE("/:id", authMiddleware, animeWrite, middleware.RequireRoles("moderator", "admin"), animeHandler.DeleteAnimeHandler)
 		}
 		
 		collection := v1.Group("/collection")
 		{
-			collection.Use(middleware.JWTAuthMiddleware())
-			collection.POST("/", collectionHandler.AddToCollectionHandler)
-			collection.GET("/:anime_id", collectionHandler.GetCollectionHandler)
-			collection.PUT("/:anime_id", collectionHandler.UpdateCollectionHandler)
-			collection.DELETE("/:anime_id", collectionHandler.RemoveFromCollectionHandler)
-			collection.GET("/", collectionHandler.ListUserCollectionsHandler)
-			collection.GET("/status", collectionHandler.ListUserCollectionsByStatusHandler)
-			collection.GET("/favorites", collectionHandler.ListUserFavoritesHandler)
+			collection.Use(authMiddleware)
+			collectionRead := middleware.RequireScopes("collection:read")
+			collectionWrite := middleware.RequireScopes("collection:write")
+			collection.POST("/", collectionWrite, collectionHandler.AddToCollectionHandler)
+			collection.GET("/:anime_id", collectionRead, collectionHandler.GetCollectionHandler)
+			collection.PUT("/:anime_id", collectionWrite, collectionHandler.UpdateCollectionHandler)
+			collection.PUT("/:anime_id/progress", collectionWrite, collectionHandler.UpdateProgressHandler)
+			collection.DELETE("/:anime_id", collectionWrite, collectionHandler.RemoveFromCollectionHandler)
+			collection.GET("/", collectionRead, collectionHandler.ListUserCollectionsHandler)
+			collection.GET("/status", collectionRead, collectionHandler.ListUserCollectionsByStatusHandler)
+			collection.GET("/favorites", collectionRead, collectionHandler.ListUserFavoritesHandler)
+		}
+		
+		// 管理接口路由
+		admin := v1.Group("/admin")
+		{
+			admin.Use(middleware.AdminAuthMiddleware(jwtUtil, userRepo))
+			admin.GET("/scopes", scopeHandler.ListScopesHandler)
+			admin.POST("/scopes", scopeHandler.CreateScopeHandler)
+			admin.GET("/scopes/:name", scopeHandler.GetScopeHandler)
+			admin.PUT("/scopes/:name", scopeHandler.UpdateScopeHandler)
+			admin.DELETE("/scopes/:name", scopeHandler.DeleteScopeHandler)
+			// 角色与用户组管理
+			admin.GET("/roles", rbacHandler.ListRolesHandler)
+			admin.POST("/roles", rbacHandler.CreateRoleHandler)
+			admin.DELETE("/roles/:name", rbacHandler.DeleteRoleHandler)
+			admin.GET("/groups", rbacHandler.ListGroupsHandler)
+			admin.POST("/groups", rbacHandler.CreateGroupHandler)
+			admin.GET("/groups/:name", rbacHandler.GetGroupHandler)
+			admin.PUT("/groups/:name", rbacHandler.UpdateGroupHandler)
+			admin.DELETE("/groups/:name", rbacHandler.DeleteGroupHandler)
+			admin.GET("/users/:id/access", rbacHandler.GetUserAccessHandler)
+			admin.PUT("/users/:id/roles", rbacHandler.SetUserRolesHandler)
+			admin.PUT("/users/:id/groups", rbacHandler.SetUserGroupsHandler)
+			admin.DELETE("/users/:id/mfa", mfaHandler.ResetUserMFAHandler)
+			admin.DELETE("/users/:id/lockout", userHandler.UnlockUserHandler)
+			// 安全审计事件查询
+			admin.GET("/audit-events", auditHandler.ListEventsHandler)
 		}
 		
 		// Bangumi绑定路由
 		bangumi := v1.Group("/bangumi")
 		{
-			bangumi.Use(middleware.JWTAuthMiddleware())
+			bangumi.Use(authMiddleware, middleware.RequireScopes("bangumi:sync"))
 			bangumi.GET("/authorize", bangumiHandler.AuthorizeHandler)
 			bangumi.GET("/callback", bangumiHandler.CallbackHandler)
 			bangumi.DELETE("/unbind", bangumiHandler.UnbindHandler)
@@ -126,20 +433,141 @@ func SetupRouter() *gin.Engine {
 		}
 	}
 
+	// realm公开信息（品牌配置）
+	r.GET("/branding", realmHandler.GetRealmInfoHandler)
+
+	// 未上传头像的用户使用的默认头像
+	r.GET("/avatars/identicon/:id", userHandler.IdenticonHandler)
+
 	// OIDC Discovery端点
 	r.GET("/.well-known/openid-configuration", oauthHandler.DiscoveryHandler)
+	r.GET("/.well-known/oauth-authorization-server", oauthHandler.AuthorizationServerMetadataHandler)
+	r.GET("/.well-known/jwks.json", oauthHandler.JWKSHandler)
 	r.GET("/jwks.json", oauthHandler.JWKSHandler)
 
+	// 上游身份提供方登录路由
+	federation := r.Group("/federation")
+	{
+		federation.GET("/providers", federationHandler.ListProvidersHandler)
+		federation.GET("/:provider/login", federationHandler.LoginHandler)
+		federation.GET("/:provider/callback", federationHandler.CallbackHandler)
+	}
+
+	// Bangumi登录路由
+	r.GET("/bangumi/login", bangumiLoginHandler.LoginHandler)
+	r.GET("/bangumi/login/callback", bangumiLoginHandler.CallbackHandler)
+
 	// OAuth 2.0 路由
 	oauth := r.Group("/oauth")
 	{
 		// 授权端点
 		oauth.GET("/authorize", oauthHandler.AuthorizeHandler)
+		oauth.POST("/authorize", oauthHandler.AuthorizeHandler)
+		// 用户同意端点
+		oauth.GET("/consent", oauthHandler.ConsentInfoHandler)
+		oauth.POST("/consent", oauthHandler.ConsentHandler)
+		// 推送授权请求端点 (PAR)
+		oauth.POST("/par", oauthHandler.PushedAuthorizationRequestHandler)
 		// 令牌端点
 		oauth.POST("/token", oauthHandler.TokenHandler)
+		// 令牌内省端点
+		oauth.POST("/introspect", oauthHandler.IntrospectionHandler)
+		// CIBA后端通道认证端点及用户批准接口
+		oauth.POST("/bc-authorize", cibaHandler.BackchannelAuthenticationHandler)
+		oauth.GET("/bc-authorize/:auth_req_id", cibaHandler.BackchannelRequestInfoHandler)
+		oauth.POST("/bc-authorize/:auth_req_id", cibaHandler.BackchannelDecisionHandler)
 		// 用户信息端点
 		oauth.GET("/userinfo", oauthHandler.UserInfoHandler)
 	}
+}
 
-	return r
-}
\ No newline at end of file
+// startAccountPurge 启动后台任务，每隔ACCOUNT_PURGE_INTERVAL_SECONDS（默认3600秒）删除宽限期已结束的账户
+func startAccountPurge(realm *model.Realm, accountService service.AccountService) {
+	interval := time.Hour
+	if seconds, err := strconv.Atoi(os.Getenv("ACCOUNT_PURGE_INTERVAL_SECONDS")); err == nil && seconds > 0 {
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := accountService.PurgeDueAccounts(context.Background())
+			if err != nil {
+				fmt.Printf("警告: realm %s 删除到期账户失败: %v\n", realm.Name, err)
+			}
+			if purged > 0 {
+				fmt.Printf("realm %s 已删除 %d 个到期账户\n", realm.Name, purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// recordClientRegistration 记录加载realm配置中的客户端，发起者为system，加载失败时记录失败原因
+func recordClientRegistration(auditService service.AuditService, clientID string, err error) {
+	event := &model.AuditEvent{
+		Type:     model.AuditClientRegistered,
+		Outcome:  model.AuditOutcomeSuccess,
+		Actor:    "system",
+		ClientID: clientID,
+	}
+	if err != nil {
+		event.Outcome = model.AuditOutcomeFailure
+		event.Details = map[string]string{"error": err.Error()}
+	}
+	auditService.Record(context.Background(), event)
+}
+
+// newNotifier 根据CIBA_NOTIFIER创建CIBA认证请求通知器：email（默认）通过邮件队列投递，memory仅保存在内存中
+func newNotifier(emailQueue util.EmailQueue) util.Notifier {
+	switch os.Getenv("CIBA_NOTIFIER") {
+	case "memory":
+		return util.NewMemoryNotifier()
+	default:
+		return util.NewEmailNotifier(emailQueue)
+	}
+}
+
+// trustedProxies 读取TRUSTED_PROXIES（逗号分隔的IP或CIDR），未配置时返回nil，不信任任何代理
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// newRealmJWTUtil 加载realm的签名密钥
+// 默认realm沿用JWT_PRIVATE_KEY_PATH/JWT_PUBLIC_KEY_PATH，其余realm默认读取config/realms/{name}/下的密钥，
+// 密钥不可用时退回临时密钥
+func newRealmJWTUtil(realm *model.Realm) util.JWTUtil {
+	var jwtUtil util.JWTUtil
+	var err error
+	
+	switch {
+	case realm.PrivateKeyPath != "":
+		jwtUtil, err = util.NewJWTUtilWithKeyFiles(realm.PrivateKeyPath, realm.PublicKeyPath)
+	case realm.IsDefault:
+		jwtUtil, err = util.NewJWTUtil()
+	default:
+		keyDir := filepath.Join("config", "realms", realm.Name)
+		jwtUtil, err = util.NewJWTUtilWithKeyFiles(
+			filepath.Join(keyDir, "private_key.pem"),
+			filepath.Join(keyDir, "public_key.pem"),
+		)
+	}
+	
+	if err != nil {
+		fmt.Printf("警告: realm %s 无法加载签名密钥: %v，将使用临时密钥\n", realm.Name, err)
+		jwtUtil, err = util.NewEphemeralJWTUtil()
+		if err != nil {
+			fmt.Printf("警告: realm %s 无法生成临时密钥: %v\n", realm.Name, err)
+			return nil
+		}
+	}
+	
+	return jwtUtil
+}
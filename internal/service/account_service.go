@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ErrDeletionNotScheduled 用户没有申请删除账户，无法取消
+var ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+
+// AccountExport 用户个人数据导出，包含服务保存的全部用户数据，不包含密码哈希、令牌和密钥
+type AccountExport struct {
+	ExportedAt         time.Time                 `json:"exported_at"`
+	Realm              string                    `json:"realm"`
+	Profile            *Profile                  `json:"profile"`
+	Collections        []ExportedCollection      `json:"collections"`
+	Bangumi            *ExportedBangumiAccount   `json:"bangumi,omitempty"` // 未绑定Bangumi时省略
+	ExternalIdentities []*model.ExternalIdentity `json:"external_identities"`
+	Grants             []ExportedGrant           `json:"grants"`
+	RefreshTokens      []ExportedRefreshToken    `json:"refresh_tokens"`
+	Sessions           []ExportedSession         `json:"sessions"`
+	MFA                *MFAStatus                `json:"mfa"`
+	Access             *UserAccess               `json:"access"`
+	SecurityEvents     []*model.AuditEvent       `json:"security_events"` // 最近的安全审计事件，最多1000条
+}
+
+// ExportedCollection 导出的番剧收藏
+type ExportedCollection struct {
+	AnimeID   uint      `json:"anime_id"`
+	Status    string    `json:"status"`
+	Rating    *float64  `json:"rating,omitempty"`
+	Progress  int       `json:"progress"`
+	Comment   string    `json:"comment"`
+	Favorite  bool      `json:"favorite"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExportedBangumiAccount 导出的Bangumi绑定信息，不包含Bangumi访问令牌和刷新令牌
+type ExportedBangumiAccount struct {
+	BangumiUserID  uint      `json:"bangumi_user_id"`
+	Scope          string    `json:"scope"`
+	TokenExpiresAt time.Time `json:"token_expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ExportedGrant 导出的客户端授权同意记录
+type ExportedGrant struct {
+	ClientID             string    `json:"client_id"`
+	Scopes               string    `json:"scopes"`
+	AuthorizationDetails string    `json:"authorization_details,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// ExportedRefreshToken 导出的刷新令牌记录，不包含令牌哈希
+type ExportedRefreshToken struct {
+	ClientID  string     `json:"client_id"`
+	Scopes    string     `json:"scopes"`
+	AuthTime  time.Time  `json:"auth_time"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ExportedSession 导出的登录会话，不包含会话ID
+type ExportedSession struct {
+	AuthTime  time.Time `json:"auth_time"`
+	AMR       string    `json:"amr"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountService 账户数据导出与删除服务接口
+type AccountService interface {
+	// ExportData 导出用户的全部个人数据
+	ExportData(ctx context.Context, userID uint) (*AccountExport, error)
+
+	// ScheduleDeletion 申请删除账户：宽限期结束后永久删除，立即撤销全部刷新令牌和登录会话，
+	// 并通知用户；已申请删除时返回原计划删除时间
+	ScheduleDeletion(ctx context.Context, userID uint) (time.Time, error)
+
+	// CancelDeletion 在宽限期内取消删除账户，未申请删除时返回ErrDeletionNotScheduled
+	CancelDeletion(ctx context.Context, userID uint) error
+
+	// PurgeDueAccounts 永久删除宽限期已结束的账户，返回删除的账户数量
+	PurgeDueAccounts(ctx context.Context) (int, error)
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// defaultAccountDeletionGrace 申请删除账户后的默认宽限期
+const defaultAccountDeletionGrace = 30 * 24 * time.Hour
+
+// accountService 账户数据导出与删除服务实现
+type accountService struct {
+	userRepo          repository.UserRepository
+	profileService    ProfileService
+	mfaService        MFAService
+	rbacService       RBACService
+	collectionRepo    repository.CollectionRepository
+	bangumiRepo       repository.BangumiRepository
+	identityRepo      repository.ExternalIdentityRepository
+	consentRepo       repository.ConsentRepository
+	refreshRepo       repository.RefreshTokenRepository
+	sessionRepo       repository.SessionRepository
+	passwordResetRepo repository.PasswordResetTokenRepository
+	emailChangeRepo   repository.EmailChangeRequestRepository
+	verificationRepo  repository.VerificationTokenRepository
+	emailQueue        util.EmailQueue
+	auditService      AuditService
+	realm             *model.Realm
+	gracePeriod       time.Duration
+}
+
+// NewAccountService 创建realm范围内的AccountService实例，删除账户的宽限期由ACCOUNT_DELETION_GRACE_SECONDS配置
+func NewAccountService(userRepo repository.UserRepository, profileService ProfileService, mfaService MFAService, rbacService RBACService, collectionRepo repository.CollectionRepository, bangumiRepo repository.BangumiRepository, identityRepo repository.ExternalIdentityRepository, consentRepo repository.ConsentRepository, refreshRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, passwordResetRepo repository.PasswordResetTokenRepository, emailChangeRepo repository.EmailChangeRequestRepository, verificationRepo repository.VerificationTokenRepository, emailQueue util.EmailQueue, auditService AuditService, realm *model.Realm) AccountService {
+	return &accountService{
+		userRepo:          userRepo,
+		profileService:    profileService,
+		mfaService:        mfaService,
+		rbacService:       rbacService,
+		collectionRepo:    collectionRepo,
+		bangumiRepo:       bangumiRepo,
+		identityRepo:      identityRepo,
+		consentRepo:       consentRepo,
+		refreshRepo:       refreshRepo,
+		sessionRepo:       sessionRepo,
+		passwordResetRepo: passwordResetRepo,
+		emailChangeRepo:   emailChangeRepo,
+		verificationRepo:  verificationRepo,
+		emailQueue:        emailQueue,
+		auditService:      auditService,
+		realm:             realm,
+		gracePeriod:       envSeconds("ACCOUNT_DELETION_GRACE_SECONDS", defaultAccountDeletionGrace),
+	}
+}
+
+// ExportData 汇总用户资料、收藏、Bangumi绑定、外部身份、授权同意、刷新令牌、登录会话、两步验证和角色信息
+func (s *accountService) ExportData(ctx context.Context, userID uint) (*AccountExport, error) {
+	profile, err := s.profileService.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &AccountExport{
+		ExportedAt:         time.Now(),
+		Realm:              s.realm.Name,
+		Profile:            profile,
+		Collections:        []ExportedCollection{},
+		ExternalIdentities: []*model.ExternalIdentity{},
+		Grants:             []ExportedGrant{},
+		RefreshTokens:      []ExportedRefreshToken{},
+		Sessions:           []ExportedSession{},
+		SecurityEvents:     []*model.AuditEvent{},
+	}
+
+	collections, err := s.collectionRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	for _, collection := range collections {
+		export.Collections = append(export.Collections, ExportedCollection{
+			AnimeID:   collection.AnimeID,
+			Status:    collection.Status,
+			Rating:    collection.Rating,
+			Progress:  collection.Progress,
+			Comment:   collection.Comment,
+			Favorite:  collection.Favorite,
+			CreatedAt: collection.CreatedAt,
+			UpdatedAt: collection.UpdatedAt,
+		})
+	}
+
+	if account, err := s.bangumiRepo.GetByUserID(ctx, userID); err == nil && account != nil {
+		export.Bangumi = &ExportedBangumiAccount{
+			BangumiUserID:  account.BangumiUserID,
+			Scope:          account.Scope,
+			TokenExpiresAt: account.TokenExpiresAt,
+			CreatedAt:      account.CreatedAt,
+			UpdatedAt:      account.UpdatedAt,
+		}
+	}
+
+	identities, err := s.identityRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list external identities: %w", err)
+	}
+	export.ExternalIdentities = append(export.ExternalIdentities, identities...)
+
+	consents, err := s.consentRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consents: %w", err)
+	}
+	for _, consent := range consents {
+		export.Grants = append(export.Grants, ExportedGrant{
+			ClientID:             consent.ClientID,
+			Scopes:               consent.Scopes,
+			AuthorizationDetails: consent.AuthorizationDetails,
+			CreatedAt:            consent.CreatedAt,
+			UpdatedAt:            consent.UpdatedAt,
+		})
+	}
+
+	tokens, err := s.refreshRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+	for _, token := range tokens {
+		exported := ExportedRefreshToken{
+			ClientID:  token.ClientID,
+			Scopes:    token.Scopes,
+			AuthTime:  token.AuthTime,
+			ExpiresAt: token.ExpiresAt,
+			CreatedAt: token.CreatedAt,
+		}
+		if !token.RevokedAt.IsZero() {
+			revokedAt := token.RevokedAt
+			exported.RevokedAt = &revokedAt
+		}
+		export.RefreshTokens = append(export.RefreshTokens, exported)
+	}
+
+	sessions, err := s.sessionRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, ExportedSession{
+			AuthTime:  session.AuthTime,
+			AMR:       session.AMR,
+			ExpiresAt: session.ExpiresAt,
+			CreatedAt: session.CreatedAt,
+		})
+	}
+
+	if export.MFA, err = s.mfaService.GetStatus(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get mfa status: %w", err)
+	}
+	if export.Access, err = s.rbacService.GetUserAccess(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user access: %w", err)
+	}
+
+	if s.auditService != nil {
+		events, err := s.auditService.Query(ctx, model.AuditEventFilter{UserID: userID, Limit: maxAuditQueryLimit})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list security events: %w", err)
+		}
+		export.SecurityEvents = append(export.SecurityEvents, events...)
+	}
+
+	return export, nil
+}
+
+// ScheduleDeletion 记录计划删除时间，撤销全部刷新令牌和登录会话，并将通知邮件加入队列
+func (s *accountService) ScheduleDeletion(ctx context.Context, userID uint) (time.Time, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return time.Time{}, ErrUserNotFound
+	}
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+
+	deletionAt := time.Now().Add(s.gracePeriod)
+	user.DeletionScheduledAt = &deletionAt
+	if err := s.userRepo.Update(user); err != nil {
+		return time.Time{}, fmt.Errorf("failed to save user: %w", err)
+	}
+
+	// 申请删除后所有设备都需要重新登录，客户端持有的刷新令牌立即失效
+	if err := s.refreshRepo.RevokeByUserID(ctx, userID); err != nil {
+		return time.Time{}, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := s.sessionRepo.DeleteByUserID(ctx, userID); err != nil {
+		return time.Time{}, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:    model.AuditTokenRevoked,
+		UserID:  userID,
+		Details: map[string]string{"reason": "account_deletion"},
+	})
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:    model.AuditDeletionScheduled,
+		UserID:  userID,
+		Details: map[string]string{"deletion_scheduled_at": deletionAt.Format(time.RFC3339)},
+	})
+
+	if err := s.emailQueue.Enqueue(util.EmailQueueItem{
+		Email:               user.Email,
+		BasePath:            s.realm.PathPrefix(),
+		Type:                util.EmailTypeAccountDeletion,
+		DeletionScheduledAt: deletionAt,
+	}); err != nil {
+		// 通知失败不影响删除申请，用户仍可在宽限期内取消
+		fmt.Printf("警告: 无法发送账户删除通知: %v\n", err)
+	}
+
+	return deletionAt, nil
+}
+
+// CancelDeletion 清除计划删除时间
+func (s *accountService) CancelDeletion(ctx context.Context, userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.DeletionScheduledAt == nil {
+		return ErrDeletionNotScheduled
+	}
+
+	user.DeletionScheduledAt = nil
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to save user: %w", err)
+	}
+	recordAudit(ctx, s.auditService, &model.AuditEvent{Type: model.AuditDeletionCancelled, UserID: userID})
+	return nil
+}
+
+// PurgeDueAccounts 逐个删除宽限期已结束的账户，单个账户删除失败时继续处理其余账户，下次执行时重试
+func (s *accountService) PurgeDueAccounts(ctx context.Context) (int, error) {
+	users, err := s.userRepo.ListDeletionDue(time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list accounts due for deletion: %w", err)
+	}
+
+	purged := 0
+	var errs []error
+	for _, user := range users {
+		if err := s.purgeAccount(ctx, user); err != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", user.ID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+// purgeAccount 删除用户的全部关联数据，最后删除用户本身，失败时用户保留以便重试。
+// 安全审计事件只追加不删除，账户删除后仍保留
+func (s *accountService) purgeAccount(ctx context.Context, user *model.User) error {
+	if err := s.collectionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete collections: %w", err)
+	}
+	if account, err := s.bangumiRepo.GetByUserID(ctx, user.ID); err == nil && account != nil {
+		if err := s.bangumiRepo.DeleteByID(ctx, account.ID); err != nil {
+			return fmt.Errorf("failed to delete bangumi account: %w", err)
+		}
+	}
+	identities, err := s.identityRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list external identities: %w", err)
+	}
+	for _, identity := range identities {
+		if err := s.identityRepo.DeleteByID(ctx, identity.ID); err != nil {
+			return fmt.Errorf("failed to delete external identity: %w", err)
+		}
+	}
+
+	// 授权同意、刷新令牌和登录会话
+	if err := s.consentRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete consents: %w", err)
+	}
+	if err := s.refreshRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete refresh tokens: %w", err)
+	}
+	if err := s.sessionRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	// 两步验证凭据、角色和用户组
+	if err := s.mfaService.ResetMFA(ctx, user.ID); err != nil && !errors.Is(err, ErrTOTPNotEnabled) {
+		return fmt.Errorf("failed to delete mfa credentials: %w", err)
+	}
+	if err := s.rbacService.SetUserRoles(ctx, user.ID, nil); err != nil {
+		return fmt.Errorf("failed to delete role assignments: %w", err)
+	}
+	if err := s.rbacService.SetUserGroups(ctx, user.ID, nil); err != nil {
+		return fmt.Errorf("failed to delete group assignments: %w", err)
+	}
+
+	// 尚未使用的各类邮件令牌
+	if err := s.passwordResetRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+	if err := s.emailChangeRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete email change requests: %w", err)
+	}
+	if token, err := s.verificationRepo.GetByUserID(user.ID); err == nil && token != nil {
+		if err := s.verificationRepo.Delete(token.ID); err != nil {
+			return fmt.Errorf("failed to delete verification token: %w", err)
+		}
+	}
+
+	if user.AvatarKey != "" {
+		if _, err := s.profileService.DeleteAvatar(ctx, user.ID); err != nil {
+			return fmt.Errorf("failed to delete avatar: %w", err)
+		}
+	}
+
+	if err := s.userRepo.Delete(user.ID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
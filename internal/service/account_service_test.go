@@ -0,0 +1,177 @@
+package service_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/helper"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// accountFixture 在共用依赖之上创建账户服务，alice已绑定Bangumi并持有授权同意、刷新令牌和登录会话
+type accountFixture struct {
+	*realmHarness
+	account     service.AccountService
+	bangumiRepo repository.BangumiRepository
+	sessionRepo repository.SessionRepository
+	emailQueue  util.EmailQueue
+}
+
+func newAccountFixture(t *testing.T) *accountFixture {
+	t.Helper()
+	f := &accountFixture{
+		realmHarness: newRealmHarness(t),
+		bangumiRepo:  repository.NewBangumiRepository(),
+		sessionRepo:  repository.NewSessionRepository(0),
+		emailQueue:   util.NewSimpleEmailQueue(),
+	}
+	f.account = service.NewAccountService(
+		f.users,
+		service.NewProfileService(f.users, helper.NewUserHelper(f.hasher), nil, f.realm),
+		f.mfa,
+		f.rbac,
+		repository.NewCollectionRepository(),
+		f.bangumiRepo,
+		repository.NewExternalIdentityRepository(),
+		f.consents,
+		f.refresh,
+		f.sessionRepo,
+		repository.NewPasswordResetTokenRepository(),
+		repository.NewEmailChangeRequestRepository(),
+		repository.NewVerificationTokenRepository(),
+		f.emailQueue,
+		f.audit,
+		f.realm,
+	)
+
+	f.alice.PasswordHash = "$argon2id$secret-hash"
+	if err := f.users.Update(f.alice); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	if err := f.bangumiRepo.Create(f.ctx, &model.BangumiAccount{UserID: f.alice.ID, BangumiUserID: 42, AccessToken: "bangumi-access-token", RefreshToken: "bangumi-refresh-token", Scope: "read"}); err != nil {
+		t.Fatalf("create bangumi account: %v", err)
+	}
+	if err := f.consents.Save(f.ctx, &model.Consent{UserID: f.alice.ID, ClientID: "web", Scopes: "openid profile"}); err != nil {
+		t.Fatalf("save consent: %v", err)
+	}
+	now := time.Now()
+	if err := f.refresh.Create(f.ctx, &model.RefreshToken{TokenHash: "refresh-token-hash", UserID: f.alice.ID, ClientID: "web", ExpiresAt: now.Add(time.Hour), AbsoluteExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+	if err := f.sessionRepo.Create(f.ctx, &model.Session{ID: "session-id", UserID: f.alice.ID, AuthTime: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	return f
+}
+
+func TestAccountExportOmitsSecrets(t *testing.T) {
+	f := newAccountFixture(t)
+
+	export, err := f.account.ExportData(f.ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("export data: %v", err)
+	}
+	if export.Profile.Username != "alice" || export.Bangumi == nil || export.Bangumi.BangumiUserID != 42 {
+		t.Fatalf("unexpected export: %+v", export)
+	}
+	if len(export.Grants) != 1 || len(export.RefreshTokens) != 1 || len(export.Sessions) != 1 {
+		t.Fatalf("expected one grant, refresh token and session, got %+v", export)
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("marshal export: %v", err)
+	}
+	for _, secret := range []string{"secret-hash", "bangumi-access-token", "bangumi-refresh-token", "refresh-token-hash", "session-id"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("export should not contain %s", secret)
+		}
+	}
+}
+
+func TestAccountDeletionScheduleAndCancel(t *testing.T) {
+	f := newAccountFixture(t)
+
+	deletionAt, err := f.account.ScheduleDeletion(f.ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("schedule deletion: %v", err)
+	}
+	if time.Until(deletionAt) < 29*24*time.Hour {
+		t.Fatalf("expected default grace period of 30 days, got %s", deletionAt)
+	}
+
+	// 申请删除后刷新令牌被撤销，所有会话失效，并通知用户
+	token, _ := f.refresh.GetByTokenHash(f.ctx, "refresh-token-hash")
+	if token == nil || token.RevokedAt.IsZero() {
+		t.Fatalf("refresh token should be revoked")
+	}
+	if sessions, _ := f.sessionRepo.ListByUserID(f.ctx, f.alice.ID); len(sessions) != 0 {
+		t.Fatalf("sessions should be deleted, got %d", len(sessions))
+	}
+	item, err := f.emailQueue.Dequeue()
+	if err != nil || item.Type != util.EmailTypeAccountDeletion || !item.DeletionScheduledAt.Equal(deletionAt) {
+		t.Fatalf("expected account deletion email, got %+v %v", item, err)
+	}
+
+	// 重复申请保留原计划删除时间
+	again, err := f.account.ScheduleDeletion(f.ctx, f.alice.ID)
+	if err != nil || !again.Equal(deletionAt) {
+		t.Fatalf("expected existing deletion time, got %s %v", again, err)
+	}
+
+	if err := f.account.CancelDeletion(f.ctx, f.alice.ID); err != nil {
+		t.Fatalf("cancel deletion: %v", err)
+	}
+	if user, _ := f.users.GetByID(f.alice.ID); user.DeletionScheduledAt != nil {
+		t.Fatalf("deletion should be cancelled")
+	}
+	if err := f.account.CancelDeletion(f.ctx, f.alice.ID); !errors.Is(err, service.ErrDeletionNotScheduled) {
+		t.Fatalf("expected ErrDeletionNotScheduled, got %v", err)
+	}
+}
+
+func TestPurgeDueAccountsCascades(t *testing.T) {
+	f := newAccountFixture(t)
+
+	// 宽限期未结束的账户不会被删除
+	if _, err := f.account.ScheduleDeletion(f.ctx, f.alice.ID); err != nil {
+		t.Fatalf("schedule deletion: %v", err)
+	}
+	if purged, err := f.account.PurgeDueAccounts(f.ctx); err != nil || purged != 0 {
+		t.Fatalf("expected no accounts to be purged, got %d %v", purged, err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	f.alice.DeletionScheduledAt = &past
+	if err := f.users.Update(f.alice); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	if purged, err := f.account.PurgeDueAccounts(f.ctx); err != nil || purged != 1 {
+		t.Fatalf("expected one account to be purged, got %d %v", purged, err)
+	}
+
+	if _, err := f.users.GetByID(f.alice.ID); err == nil {
+		t.Fatalf("user should be deleted")
+	}
+	if _, err := f.users.GetByUsername("alice"); err == nil {
+		t.Fatalf("username should be released")
+	}
+	if _, err := f.users.GetByEmail("alice@example.com"); err == nil {
+		t.Fatalf("email should be released")
+	}
+	if account, err := f.bangumiRepo.GetByUserID(f.ctx, f.alice.ID); err == nil && account != nil {
+		t.Fatalf("bangumi account should be deleted")
+	}
+	if consents, _ := f.consents.ListByUserID(f.ctx, f.alice.ID); len(consents) != 0 {
+		t.Fatalf("consents should be deleted, got %d", len(consents))
+	}
+	if tokens, _ := f.refresh.ListByUserID(f.ctx, f.alice.ID); len(tokens) != 0 {
+		t.Fatalf("refresh tokens should be deleted, got %d", len(tokens))
+	}
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// AuditService 安全审计服务接口，记录登录、令牌、授权同意、客户端和账号绑定事件
+type AuditService interface {
+	// Record 追加审计事件，IP和User-Agent取自请求上下文；记录失败只输出警告，不影响业务流程
+	Record(ctx context.Context, event *model.AuditEvent)
+
+	// Query 按用户、客户端、事件类型和时间范围查询审计事件，按时间倒序
+	Query(ctx context.Context, filter model.AuditEventFilter) ([]*model.AuditEvent, error)
+
+	// ListUserActivity 获取用户最近的安全活动，按时间倒序
+	ListUserActivity(ctx context.Context, userID uint, limit int) ([]*model.AuditEvent, error)
+}
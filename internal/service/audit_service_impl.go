@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// 审计事件查询的默认策略
+const (
+	defaultAuditQueryLimit    = 100
+	maxAuditQueryLimit        = 1000
+	defaultUserActivityLimit  = 20
+	maxUserActivityLimit      = 100
+	defaultUserActivityWindow = 90 * 24 * time.Hour
+)
+
+// auditService 安全审计服务实现
+type auditService struct {
+	auditRepo      repository.AuditEventRepository
+	activityWindow time.Duration
+}
+
+// NewAuditService 创建AuditService实例，用户可查看的安全活动范围由AUDIT_USER_ACTIVITY_SECONDS配置
+func NewAuditService(auditRepo repository.AuditEventRepository) AuditService {
+	return &auditService{
+		auditRepo:      auditRepo,
+		activityWindow: envSeconds("AUDIT_USER_ACTIVITY_SECONDS", defaultUserActivityWindow),
+	}
+}
+
+// Record 追加审计事件
+func (s *auditService) Record(ctx context.Context, event *model.AuditEvent) {
+	metadata := util.RequestMetadataFromContext(ctx)
+	if event.IP == "" {
+		event.IP = metadata.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = metadata.UserAgent
+	}
+	if event.Outcome == "" {
+		event.Outcome = model.AuditOutcomeSuccess
+	}
+	if event.Actor == "" && event.UserID != 0 {
+		event.Actor = userActor(event.UserID)
+	}
+
+	if err := s.auditRepo.Append(ctx, event); err != nil {
+		fmt.Printf("警告: 无法记录审计事件 %s: %v\n", event.Type, err)
+	}
+}
+
+// Query 按条件查询审计事件，未指定数量时返回最近100条，最多1000条
+func (s *auditService) Query(ctx context.Context, filter model.AuditEventFilter) ([]*model.AuditEvent, error) {
+	filter.Limit = clampLimit(filter.Limit, defaultAuditQueryLimit, maxAuditQueryLimit)
+	return s.auditRepo.Query(ctx, filter)
+}
+
+// ListUserActivity 获取用户在最近一段时间内的安全活动，未指定数量时返回最近20条，最多100条
+func (s *auditService) ListUserActivity(ctx context.Context, userID uint, limit int) ([]*model.AuditEvent, error) {
+	return s.auditRepo.Query(ctx, model.AuditEventFilter{
+		UserID: userID,
+		Since:  time.Now().Add(-s.activityWindow),
+		Limit:  clampLimit(limit, defaultUserActivityLimit, maxUserActivityLimit),
+	})
+}
+
+// recordAudit 记录审计事件，未配置审计服务时忽略
+func recordAudit(ctx context.Context, auditService AuditService, event *model.AuditEvent) {
+	if auditService != nil {
+		auditService.Record(ctx, event)
+	}
+}
+
+// userActor 用户作为事件发起者时的标识
+func userActor(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// clientActor 客户端作为事件发起者时的标识
+func clientActor(clientID string) string {
+	return "client:" + clientID
+}
+
+// clampLimit 未指定数量时使用默认值，超过上限时使用上限
+func clampLimit(limit, fallback, max int) int {
+	if limit <= 0 {
+		return fallback
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+func TestAuditQueryFiltersNewestFirst(t *testing.T) {
+	h := newRealmHarness(t)
+	ctx := util.WithRequestMetadata(h.ctx, util.RequestMetadata{IP: "203.0.113.7", UserAgent: "test-agent"})
+	audit := h.audit
+
+	audit.Record(ctx, &model.AuditEvent{Type: model.AuditTokenIssued, UserID: 1, ClientID: "web"})
+	audit.Record(ctx, &model.AuditEvent{Type: model.AuditTokenRefreshed, UserID: 1, ClientID: "web"})
+	audit.Record(ctx, &model.AuditEvent{Type: model.AuditTokenIssued, UserID: 2, ClientID: "cli"})
+
+	events, err := audit.Query(ctx, model.AuditEventFilter{UserID: 1})
+	if err != nil || len(events) != 2 {
+		t.Fatalf("expected two events for user 1, got %d %v", len(events), err)
+	}
+	if events[0].Type != model.AuditTokenRefreshed || events[1].Type != model.AuditTokenIssued {
+		t.Fatalf("events should be newest first, got %s %s", events[0].Type, events[1].Type)
+	}
+	// 未指定的字段由请求上下文和默认值补全
+	if events[0].IP != "203.0.113.7" || events[0].UserAgent != "test-agent" || events[0].Outcome != model.AuditOutcomeSuccess || events[0].Actor != "user:1" {
+		t.Fatalf("unexpected event metadata: %+v", events[0])
+	}
+
+	if events, _ := audit.Query(ctx, model.AuditEventFilter{ClientID: "cli"}); len(events) != 1 || events[0].UserID != 2 {
+		t.Fatalf("expected one event for client cli, got %+v", events)
+	}
+	if events, _ := audit.Query(ctx, model.AuditEventFilter{Type: model.AuditTokenIssued, Limit: 1}); len(events) != 1 || events[0].UserID != 2 {
+		t.Fatalf("expected latest token.issued event, got %+v", events)
+	}
+	if events, _ := audit.Query(ctx, model.AuditEventFilter{Since: time.Now().Add(time.Minute)}); len(events) != 0 {
+		t.Fatalf("expected no events in the future, got %d", len(events))
+	}
+
+	// 查询结果是副本，修改不影响已记录的事件
+	events[0].Type = "tampered"
+	if again, _ := audit.Query(ctx, model.AuditEventFilter{UserID: 1}); again[0].Type != model.AuditTokenRefreshed {
+		t.Fatalf("audit events should not be modifiable, got %s", again[0].Type)
+	}
+}
+
+func TestAuditRecordsLoginActivity(t *testing.T) {
+	h := newRealmHarness(t)
+	ctx, audit, user := h.ctx, h.audit, h.alice
+	guard := service.NewLoginGuardService(repository.NewLoginAttemptRepository(nil), h.users, util.NewSimpleEmailQueue(), audit, h.realm)
+	sessions := service.NewSessionService(repository.NewSessionRepository(0), audit)
+
+	if err := guard.RecordFailure(ctx, "Alice", "203.0.113.1"); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	if err := guard.RecordFailure(ctx, "mallory", "203.0.113.1"); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	session, err := sessions.CreateSession(ctx, user.ID, []string{model.AMRPassword})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if err := sessions.DeleteSession(ctx, session.ID); err != nil {
+		t.Fatalf("delete session: %v", err)
+	}
+
+	events, err := audit.ListUserActivity(ctx, user.ID, 0)
+	if err != nil || len(events) != 3 {
+		t.Fatalf("expected three events for alice, got %d %v", len(events), err)
+	}
+	if events[0].Type != model.AuditLogout || events[1].Type != model.AuditLoginSucceeded || events[2].Type != model.AuditLoginFailed {
+		t.Fatalf("unexpected activity: %s %s %s", events[0].Type, events[1].Type, events[2].Type)
+	}
+	if events[2].Outcome != model.AuditOutcomeFailure || events[2].Actor != "Alice" {
+		t.Fatalf("failed login should record the attempted username, got %+v", events[2])
+	}
+
+	// 不存在的用户名同样记录，但不关联用户
+	failures, _ := audit.Query(ctx, model.AuditEventFilter{Type: model.AuditLoginFailed})
+	if len(failures) != 2 || failures[0].Actor != "mallory" || failures[0].UserID != 0 {
+		t.Fatalf("expected failed login for unknown user, got %+v", failures)
+	}
+}
+
+func TestAuditRecordsAccountDeletion(t *testing.T) {
+	f := newAccountFixture(t)
+
+	if _, err := f.account.ScheduleDeletion(f.ctx, f.alice.ID); err != nil {
+		t.Fatalf("schedule deletion: %v", err)
+	}
+	if err := f.account.CancelDeletion(f.ctx, f.alice.ID); err != nil {
+		t.Fatalf("cancel deletion: %v", err)
+	}
+
+	// 申请删除、撤销令牌和取消删除都记录在用户的安全活动中
+	events, err := f.audit.ListUserActivity(f.ctx, f.alice.ID, 0)
+	if err != nil || len(events) != 3 {
+		t.Fatalf("expected three security events, got %d %v", len(events), err)
+	}
+	if events[0].Type != model.AuditDeletionCancelled || events[1].Type != model.AuditDeletionScheduled || events[2].Type != model.AuditTokenRevoked {
+		t.Fatalf("unexpected security events: %s %s %s", events[0].Type, events[1].Type, events[2].Type)
+	}
+}
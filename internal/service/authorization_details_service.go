@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// AuthorizationDetailType 已注册的authorization_details类型，定义允许的操作和状态过滤值 (RFC 9396)
+type AuthorizationDetailType struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description"`        // 同意页面展示的说明
+	Actions     []string `json:"actions"`            // 允许请求的操作
+	Statuses    []string `json:"statuses,omitempty"` // 允许的状态过滤值，为空表示该类型不支持状态过滤
+}
+
+// AuthorizationDetailsService authorization_details类型注册表服务接口
+type AuthorizationDetailsService interface {
+	// ListTypes 列出所有已注册的类型
+	ListTypes(ctx context.Context) []*AuthorizationDetailType
+
+	// GetType 根据名称获取类型，不存在时返回nil
+	GetType(ctx context.Context, name string) *AuthorizationDetailType
+
+	// ValidateAuthorizationDetails 校验请求的authorization_details已注册、被客户端允许且字段合法
+	ValidateAuthorizationDetails(ctx context.Context, client *model.Client, details []model.AuthorizationDetail) error
+
+	// CoversAuthorizationDetails 判断已授予的authorization_details是否包含全部请求项
+	CoversAuthorizationDetails(granted, requested []model.AuthorizationDetail) bool
+
+	// NarrowAuthorizationDetails 在令牌端点按客户端请求收窄已授予的authorization_details，未请求时原样返回
+	NarrowAuthorizationDetails(ctx context.Context, granted, requested []model.AuthorizationDetail) ([]model.AuthorizationDetail, error)
+}
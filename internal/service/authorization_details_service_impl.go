@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// authorizationDetailsService authorization_details类型注册表服务实现
+type authorizationDetailsService struct {
+	types map[string]*AuthorizationDetailType
+	order []string
+}
+
+// NewAuthorizationDetailsService 创建AuthorizationDetailsService实例，注册内置类型
+func NewAuthorizationDetailsService() AuthorizationDetailsService {
+	s := &authorizationDetailsService{
+		types: make(map[string]*AuthorizationDetailType),
+	}
+	for _, detailType := range DefaultAuthorizationDetailTypes() {
+		detailType := detailType
+		s.types[detailType.Type] = &detailType
+		s.order = append(s.order, detailType.Type)
+	}
+	return s
+}
+
+// DefaultAuthorizationDetailTypes 内置authorization_details类型
+// collection_access按操作和收藏状态限定对番剧收藏的访问，例如只允许更新"在看"列表的观看进度
+func DefaultAuthorizationDetailTypes() []AuthorizationDetailType {
+	return []AuthorizationDetailType{
+		{
+			Type:        model.AuthorizationDetailTypeCollectionAccess,
+			Description: "访问您的番剧收藏",
+			Actions: []string{
+				model.CollectionActionRead,
+				model.CollectionActionAdd,
+				model.CollectionActionUpdate,
+				model.CollectionActionUpdateProgress,
+				model.CollectionActionRemove,
+			},
+			Statuses: []string{"watching", "completed", "on_hold", "dropped", "plan_to_watch"},
+		},
+	}
+}
+
+// ListTypes 列出所有已注册的类型
+func (s *authorizationDetailsService) ListTypes(ctx context.Context) []*AuthorizationDetailType {
+	types := make([]*AuthorizationDetailType, 0, len(s.order))
+	for _, name := range s.order {
+		types = append(types, s.types[name])
+	}
+	return types
+}
+
+// GetType 根据名称获取类型，不存在时返回nil
+func (s *authorizationDetailsService) GetType(ctx context.Context, name string) *AuthorizationDetailType {
+	return s.types[name]
+}
+
+// ValidateAuthorizationDetails 校验请求的authorization_details (RFC 9396 §5)
+func (s *authorizationDetailsService) ValidateAuthorizationDetails(ctx context.Context, client *model.Client, details []model.AuthorizationDetail) error {
+	allowedTypes := strings.Fields(client.AuthorizationDetailsTypes)
+
+	for i := range details {
+		detail := &details[i]
+		if detail.Type == "" {
+			return ErrInvalidAuthorizationDetails("authorization_details entry is missing type")
+		}
+
+		detailType := s.GetType(ctx, detail.Type)
+		if detailType == nil {
+			return ErrInvalidAuthorizationDetails(fmt.Sprintf("authorization_details type %q is not supported", detail.Type))
+		}
+		if len(allowedTypes) > 0 && !containsString(allowedTypes, detail.Type) {
+			return ErrInvalidAuthorizationDetails(fmt.Sprintf("the client is not allowed to request authorization_details type %q", detail.Type))
+		}
+
+		// 内置类型只使用actions和statuses，其余通用字段没有意义
+		if len(detail.Locations) > 0 || len(detail.Datatypes) > 0 || len(detail.Privileges) > 0 || detail.Identifier != "" {
+			return ErrInvalidAuthorizationDetails(fmt.Sprintf("authorization_details type %q only supports actions and statuses", detail.Type))
+		}
+
+		if len(detail.Actions) == 0 {
+			return ErrInvalidAuthorizationDetails(fmt.Sprintf("authorization_details type %q requires actions", detail.Type))
+		}
+		for _, action := range detail.Actions {
+			if !containsString(detailType.Actions, action) {
+				return ErrInvalidAuthorizationDetails(fmt.Sprintf("unsupported action %q for authorization_details type %q", action, detail.Type))
+			}
+		}
+		for _, status := range detail.Statuses {
+			if !containsString(detailType.Statuses, status) {
+				return ErrInvalidAuthorizationDetails(fmt.Sprintf("unsupported status %q for authorization_details type %q", status, detail.Type))
+			}
+		}
+	}
+
+	return nil
+}
+
+// CoversAuthorizationDetails 判断每个请求项是否都被某个已授予项包含
+func (s *authorizationDetailsService) CoversAuthorizationDetails(granted, requested []model.AuthorizationDetail) bool {
+	for i := range requested {
+		covered := false
+		for j := range granted {
+			if granted[j].Covers(&requested[i]) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// NarrowAuthorizationDetails 令牌请求中的authorization_details只能是授权时批准内容的子集 (RFC 9396 §6.1)
+func (s *authorizationDetailsService) NarrowAuthorizationDetails(ctx context.Context, granted, requested []model.AuthorizationDetail) ([]model.AuthorizationDetail, error) {
+	if len(requested) == 0 {
+		return granted, nil
+	}
+	if !s.CoversAuthorizationDetails(granted, requested) {
+		return nil, ErrInvalidAuthorizationDetails("the requested authorization_details exceed those approved by the end-user")
+	}
+	return requested, nil
+}
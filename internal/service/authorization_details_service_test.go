@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+func TestParseAuthorizationDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{name: "empty", raw: "", want: 0},
+		{name: "single entry", raw: `[{"type":"collection_access","actions":["read"],"statuses":["watching"]}]`, want: 1},
+		{name: "multiple entries", raw: `[{"type":"collection_access","actions":["read"]},{"type":"collection_access","actions":["add"]}]`, want: 2},
+		{name: "object instead of array", raw: `{"type":"collection_access"}`, wantErr: true},
+		{name: "unknown field", raw: `[{"type":"collection_access","actions":["read"],"scope":"all"}]`, wantErr: true},
+		{name: "trailing data", raw: `[{"type":"collection_access"}] []`, wantErr: true},
+		{name: "invalid json", raw: `[{"type":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := model.ParseAuthorizationDetails(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected %q to be rejected, got %+v", tt.raw, details)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.raw, err)
+			}
+			if len(details) != tt.want {
+				t.Fatalf("expected %d entries, got %+v", tt.want, details)
+			}
+		})
+	}
+}
+
+func TestValidateAuthorizationDetails(t *testing.T) {
+	detailsService := service.NewAuthorizationDetailsService()
+	client := &model.Client{ClientID: "app"}
+	restricted := &model.Client{ClientID: "restricted", AuthorizationDetailsTypes: "payment_initiation"}
+
+	tests := []struct {
+		name    string
+		client  *model.Client
+		detail  model.AuthorizationDetail
+		wantErr bool
+	}{
+		{name: "valid", client: client, detail: model.AuthorizationDetail{Type: "collection_access", Actions: []string{"read", "update_progress"}, Statuses: []string{"watching"}}},
+		{name: "valid without statuses", client: client, detail: model.AuthorizationDetail{Type: "collection_access", Actions: []string{"add"}}},
+		{name: "missing type", client: client, detail: model.AuthorizationDetail{Actions: []string{"read"}}, wantErr: true},
+		{name: "unregistered type", client: client, detail: model.AuthorizationDetail{Type: "payment_initiation", Actions: []string{"read"}}, wantErr: true},
+		{name: "type not allowed for client", client: restricted, detail: model.AuthorizationDetail{Type: "collection_access", Actions: []string{"read"}}, wantErr: true},
+		{name: "missing actions", client: client, detail: model.AuthorizationDetail{Type: "collection_access"}, wantErr: true},
+		{name: "unsupported action", client: client, detail: model.AuthorizationDetail{Type: "collection_access", Actions: []string{"delete_account"}}, wantErr: true},
+		{name: "unsupported status", client: client, detail: model.AuthorizationDetail{Type: "collection_access", Actions: []string{"read"}, Statuses: []string{"favorite"}}, wantErr: true},
+		{name: "unused common field", client: client, detail: model.AuthorizationDetail{Type: "collection_access", Actions: []string{"read"}, Locations: []string{"https://api.example.com"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := detailsService.ValidateAuthorizationDetails(context.Background(), tt.client, []model.AuthorizationDetail{tt.detail})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("expected %+v to be valid, got %v", tt.detail, err)
+				}
+				return
+			}
+			requireOAuthErrorCode(t, err, service.ErrCodeInvalidAuthorizationDetails)
+		})
+	}
+}
+
+func TestNarrowAuthorizationDetails(t *testing.T) {
+	detailsService := service.NewAuthorizationDetailsService()
+	granted := []model.AuthorizationDetail{{Type: "collection_access", Actions: []string{"read", "update_progress"}, Statuses: []string{"watching", "on_hold"}}}
+
+	// 未请求收窄时原样返回已批准的内容
+	narrowed, err := detailsService.NarrowAuthorizationDetails(context.Background(), granted, nil)
+	if err != nil || len(narrowed) != 1 || len(narrowed[0].Actions) != 2 {
+		t.Fatalf("expected the granted details, got %+v, %v", narrowed, err)
+	}
+
+	subset := []model.AuthorizationDetail{{Type: "collection_access", Actions: []string{"update_progress"}, Statuses: []string{"watching"}}}
+	narrowed, err = detailsService.NarrowAuthorizationDetails(context.Background(), granted, subset)
+	if err != nil || len(narrowed) != 1 || len(narrowed[0].Actions) != 1 || narrowed[0].Actions[0] != "update_progress" {
+		t.Fatalf("expected the requested subset, got %+v, %v", narrowed, err)
+	}
+
+	exceeding := [][]model.AuthorizationDetail{
+		{{Type: "collection_access", Actions: []string{"remove"}, Statuses: []string{"watching"}}},
+		{{Type: "collection_access", Actions: []string{"read"}, Statuses: []string{"completed"}}},
+		{{Type: "collection_access", Actions: []string{"read"}}},
+	}
+	for _, requested := range exceeding {
+		_, err := detailsService.NarrowAuthorizationDetails(context.Background(), granted, requested)
+		requireOAuthErrorCode(t, err, service.ErrCodeInvalidAuthorizationDetails)
+	}
+}
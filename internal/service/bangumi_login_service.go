@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// Bangumi登录错误，处理器据此选择HTTP状态码
+var (
+	ErrInvalidBangumiPendingLogin = errors.New("invalid or expired bangumi login")
+	ErrUserHasOtherBangumiAccount = errors.New("the local account is already bound to a different bangumi account")
+	ErrUsernameTaken              = errors.New("用户名已存在")
+	ErrEmailTaken                 = errors.New("邮箱已被注册")
+)
+
+// BangumiLoginResult 使用Bangumi登录的结果
+type BangumiLoginResult struct {
+	User         *model.User             // 登录的本地用户，Bangumi账号尚未关联本地用户时为nil
+	Identity     *model.ExternalIdentity // Bangumi账号对应的外部身份，尚未关联本地用户时为nil
+	BangumiUser  *BangumiUser            // Bangumi用户信息
+	PendingToken string                  // 尚未关联本地用户时，用于创建或关联账户的凭据
+	Created      bool                    // 是否新建了本地用户
+	Linked       bool                    // 是否为已登录用户关联Bangumi账号
+	ReturnTo     string                  // 登录完成后跳转的地址
+}
+
+// BangumiLoginService 使用Bangumi账号登录的服务接口
+type BangumiLoginService interface {
+	// StartLogin 发起Bangumi登录，返回Bangumi授权地址和浏览器绑定值，returnTo必须指向本issuer
+	// 浏览器绑定值须写入发起登录的浏览器的Cookie，回调时原样传给CompleteLogin
+	StartLogin(ctx context.Context, returnTo string) (string, string, error)
+
+	// StartLink 为已登录用户发起Bangumi账号关联，返回值同StartLogin，调用方须确认用户刚完成认证
+	StartLink(ctx context.Context, userID uint, returnTo string) (string, string, error)
+
+	// CompleteLogin 处理Bangumi回调：校验state与浏览器绑定值，已绑定的Bangumi账号直接登录并同步令牌，否则返回待完成的登录凭据
+	// sessionUserID为回调请求所在登录会话的用户，未登录时为0；关联账号时必须与发起关联的用户一致
+	CompleteLogin(ctx context.Context, code, state, binding string, sessionUserID uint) (*BangumiLoginResult, error)
+
+	// GetPendingLogin 获取待完成的登录，供登录页展示Bangumi账号信息
+	GetPendingLogin(ctx context.Context, token string) (*model.BangumiPendingLogin, error)
+
+	// CreateAccount 为待完成的登录创建本地用户并绑定Bangumi账号
+	CreateAccount(ctx context.Context, token, username, email string) (*BangumiLoginResult, error)
+
+	// LinkAccount 将待完成登录的Bangumi账号绑定到已通过密码认证的本地用户
+	LinkAccount(ctx context.Context, token string, user *model.User) (*BangumiLoginResult, error)
+}
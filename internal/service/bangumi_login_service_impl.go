@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// defaultBangumiPendingLoginExpiry 待完成的Bangumi登录默认有效期，可通过BANGUMI_PENDING_LOGIN_EXPIRY_SECONDS覆盖
+const defaultBangumiPendingLoginExpiry = 10 * time.Minute
+
+// bangumiLoginService Bangumi登录服务实现
+type bangumiLoginService struct {
+	bangumiService BangumiService
+	bangumiRepo    repository.BangumiRepository
+	userRepo       repository.UserRepository
+	stateRepo      repository.FederatedLoginStateRepository
+	pendingRepo    repository.BangumiPendingLoginRepository
+	identityRepo   repository.ExternalIdentityRepository
+}
+
+// NewBangumiLoginService 创建BangumiLoginService实例
+func NewBangumiLoginService(bangumiService BangumiService, bangumiRepo repository.BangumiRepository, userRepo repository.UserRepository, stateRepo repository.FederatedLoginStateRepository, pendingRepo repository.BangumiPendingLoginRepository, identityRepo repository.ExternalIdentityRepository) BangumiLoginService {
+	return &bangumiLoginService{
+		bangumiService: bangumiService,
+		bangumiRepo:    bangumiRepo,
+		userRepo:       userRepo,
+		stateRepo:      stateRepo,
+		pendingRepo:    pendingRepo,
+		identityRepo:   identityRepo,
+	}
+}
+
+// StartLogin 发起Bangumi登录，返回Bangumi授权地址和浏览器绑定值，returnTo必须指向本issuer
+func (s *bangumiLoginService) StartLogin(ctx context.Context, returnTo string) (string, string, error) {
+	return s.startAuthorization(ctx, returnTo, 0)
+}
+
+// StartLink 为已登录用户发起Bangumi账号关联，返回Bangumi授权地址和浏览器绑定值
+func (s *bangumiLoginService) StartLink(ctx context.Context, userID uint, returnTo string) (string, string, error) {
+	return s.startAuthorization(ctx, returnTo, userID)
+}
+
+// startAuthorization 保存state与浏览器绑定值的哈希，返回Bangumi授权地址和浏览器绑定值
+func (s *bangumiLoginService) startAuthorization(ctx context.Context, returnTo string, linkUserID uint) (string, string, error) {
+	// 只允许跳转回本issuer下的地址，避免开放重定向
+	if returnTo != "" && !strings.HasPrefix(returnTo, util.IssuerFromContext(ctx)+"/") {
+		return "", "", ErrInvalidReturnTo
+	}
+
+	binding := randomURLToken(32)
+	loginState := &model.FederatedLoginState{
+		State:       randomURLToken(32),
+		Provider:    BangumiProviderName,
+		ReturnTo:    returnTo,
+		LinkUserID:  linkUserID,
+		BindingHash: hashStateBinding(binding),
+		ExpiresAt:   time.Now().Add(envSeconds("FEDERATION_LOGIN_EXPIRY_SECONDS", defaultFederationLoginExpiry)),
+	}
+	if err := s.stateRepo.Create(ctx, loginState); err != nil {
+		return "", "", fmt.Errorf("failed to save bangumi login state: %w", err)
+	}
+
+	return s.bangumiService.GetAuthorizationURLWithRedirect(loginState.State, s.callbackURL(ctx)), binding, nil
+}
+
+// CompleteLogin 处理Bangumi回调：校验state与浏览器绑定值，已绑定的Bangumi账号直接登录并同步令牌，否则返回待完成的登录凭据
+func (s *bangumiLoginService) CompleteLogin(ctx context.Context, code, state, binding string, sessionUserID uint) (*BangumiLoginResult, error) {
+	loginState, err := consumeFederatedLoginState(ctx, s.stateRepo, BangumiProviderName, state, binding, sessionUserID)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, ErrInvalidFederationState
+	}
+
+	tokenResponse, err := s.bangumiService.ExchangeCodeForTokenWithRedirect(ctx, code, s.callbackURL(ctx))
+	if err != nil {
+		return nil, err
+	}
+	bangumiUser, err := s.bangumiService.GetUserInfo(ctx, tokenResponse.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if tokenResponse.UserID == 0 {
+		tokenResponse.UserID = bangumiUser.ID
+	}
+	if tokenResponse.UserID == 0 || tokenResponse.UserID != bangumiUser.ID {
+		return nil, errors.New("bangumi token response does not match the bangumi user")
+	}
+
+	// 已登录用户关联Bangumi账号
+	if loginState.LinkUserID != 0 {
+		return s.linkToUser(ctx, loginState, tokenResponse, bangumiUser)
+	}
+
+	// 已绑定的Bangumi账号：直接登录，并用本次返回的令牌更新绑定记录
+	account, err := s.bangumiRepo.GetByBangumiUserID(ctx, tokenResponse.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bangumi account: %w", err)
+	}
+	if account != nil {
+		user, err := s.userRepo.GetByID(account.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load bound user: %w", err)
+		}
+		if !user.IsActive {
+			return nil, ErrFederatedUserInactive
+		}
+		if err := s.bangumiService.BindAccount(ctx, user.ID, tokenResponse); err != nil {
+			return nil, err
+		}
+		identity, err := s.recordProfile(ctx, bangumiUser)
+		if err != nil {
+			return nil, err
+		}
+		return &BangumiLoginResult{User: user, Identity: identity, BangumiUser: bangumiUser, ReturnTo: loginState.ReturnTo}, nil
+	}
+
+	// 未绑定：保存Bangumi令牌，等待用户选择创建新账户或关联已有账户
+	pendingLogin := &model.BangumiPendingLogin{
+		Token:           randomURLToken(32),
+		BangumiUserID:   tokenResponse.UserID,
+		BangumiUsername: bangumiUser.Username,
+		Nickname:        bangumiUser.Nickname,
+		AvatarURL:       bangumiUser.Avatar,
+		AccessToken:     tokenResponse.AccessToken,
+		RefreshToken:    tokenResponse.RefreshToken,
+		TokenExpiresAt:  time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second),
+		Scope:           tokenResponse.Scope,
+		ReturnTo:        loginState.ReturnTo,
+		ExpiresAt:       time.Now().Add(envSeconds("BANGUMI_PENDING_LOGIN_EXPIRY_SECONDS", defaultBangumiPendingLoginExpiry)),
+	}
+	if err := s.pendingRepo.Create(ctx, pendingLogin); err != nil {
+		return nil, fmt.Errorf("failed to save bangumi pending login: %w", err)
+	}
+
+	return &BangumiLoginResult{
+		BangumiUser:  bangumiUser,
+		PendingToken: pendingLogin.Token,
+		ReturnTo:     loginState.ReturnTo,
+	}, nil
+}
+
+// GetPendingLogin 获取待完成的登录，供登录页展示Bangumi账号信息
+func (s *bangumiLoginService) GetPendingLogin(ctx context.Context, token string) (*model.BangumiPendingLogin, error) {
+	pendingLogin, err := s.pendingRepo.GetByToken(ctx, token)
+	if err != nil || token == "" || pendingLogin.IsExpired() {
+		return nil, ErrInvalidBangumiPendingLogin
+	}
+	return pendingLogin, nil
+}
+
+// CreateAccount 为待完成的登录创建本地用户并绑定Bangumi账号
+// 新用户没有本地密码，身份已由Bangumi确认，因此无需邮箱验证即可登录
+func (s *bangumiLoginService) CreateAccount(ctx context.Context, token, username, email string) (*BangumiLoginResult, error) {
+	pendingLogin, err := s.GetPendingLogin(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByUsername(username); err == nil {
+		return nil, ErrUsernameTaken
+	}
+	if _, err := s.userRepo.GetByEmail(email); err == nil {
+		return nil, ErrEmailTaken
+	}
+
+	nickname := pendingLogin.Nickname
+	if nickname == "" {
+		nickname = username
+	}
+	user := &model.User{
+		Username:  username,
+		Email:     email,
+		Nickname:  nickname,
+		AvatarURL: pendingLogin.AvatarURL,
+		IsActive:  true,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, errors.New("用户创建失败")
+	}
+
+	result, err := s.completePendingLogin(ctx, pendingLogin, user)
+	if err != nil {
+		return nil, err
+	}
+	result.Created = true
+
+	return result, nil
+}
+
+// LinkAccount 将待完成登录的Bangumi账号绑定到已通过密码认证的本地用户
+func (s *bangumiLoginService) LinkAccount(ctx context.Context, token string, user *model.User) (*BangumiLoginResult, error) {
+	pendingLogin, err := s.GetPendingLogin(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// 不覆盖用户已绑定的其他Bangumi账号
+	account, err := s.bangumiRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bangumi account: %w", err)
+	}
+	if account != nil && account.BangumiUserID != pendingLogin.BangumiUserID {
+		return nil, ErrUserHasOtherBangumiAccount
+	}
+
+	return s.completePendingLogin(ctx, pendingLogin, user)
+}
+
+// completePendingLogin 绑定Bangumi账号并使待完成的登录失效
+func (s *bangumiLoginService) completePendingLogin(ctx context.Context, pendingLogin *model.BangumiPendingLogin, user *model.User) (*BangumiLoginResult, error) {
+	expiresIn := int(time.Until(pendingLogin.TokenExpiresAt).Seconds())
+	if expiresIn < 0 {
+		expiresIn = 0
+	}
+	tokenResponse := &BangumiTokenResponse{
+		AccessToken:  pendingLogin.AccessToken,
+		RefreshToken: pendingLogin.RefreshToken,
+		ExpiresIn:    expiresIn,
+		Scope:        pendingLogin.Scope,
+		UserID:       pendingLogin.BangumiUserID,
+	}
+	if err := s.bangumiService.BindAccount(ctx, user.ID, tokenResponse); err != nil {
+		return nil, err
+	}
+	_ = s.pendingRepo.DeleteByID(ctx, pendingLogin.ID)
+
+	bangumiUser := &BangumiUser{
+		ID:       pendingLogin.BangumiUserID,
+		Username: pendingLogin.BangumiUsername,
+		Nickname: pendingLogin.Nickname,
+		Avatar:   pendingLogin.AvatarURL,
+	}
+	identity, err := s.recordProfile(ctx, bangumiUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BangumiLoginResult{
+		User:        user,
+		Identity:    identity,
+		BangumiUser: bangumiUser,
+		ReturnTo:    pendingLogin.ReturnTo,
+	}, nil
+}
+
+// linkToUser 将Bangumi账号关联到发起关联的已登录用户
+func (s *bangumiLoginService) linkToUser(ctx context.Context, loginState *model.FederatedLoginState, tokenResponse *BangumiTokenResponse, bangumiUser *BangumiUser) (*BangumiLoginResult, error) {
+	user, err := s.userRepo.GetByID(loginState.LinkUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if !user.IsActive {
+		return nil, ErrFederatedUserInactive
+	}
+
+	// 不覆盖用户已绑定的其他Bangumi账号，已绑定其他用户的Bangumi账号由BindAccount拒绝
+	account, err := s.bangumiRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bangumi account: %w", err)
+	}
+	if account != nil && account.BangumiUserID != tokenResponse.UserID {
+		return nil, ErrUserHasOtherBangumiAccount
+	}
+	if err := s.bangumiService.BindAccount(ctx, user.ID, tokenResponse); err != nil {
+		return nil, err
+	}
+	identity, err := s.recordProfile(ctx, bangumiUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BangumiLoginResult{User: user, Identity: identity, BangumiUser: bangumiUser, Linked: true, ReturnTo: loginState.ReturnTo}, nil
+}
+
+// recordProfile 在Bangumi外部身份上保存用户信息快照
+func (s *bangumiLoginService) recordProfile(ctx context.Context, bangumiUser *BangumiUser) (*model.ExternalIdentity, error) {
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, BangumiProviderName, strconv.FormatUint(uint64(bangumiUser.ID), 10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bangumi identity: %w", err)
+	}
+
+	now := time.Now()
+	identity.Username = bangumiUser.Username
+	identity.DisplayName = bangumiUser.Nickname
+	identity.AvatarURL = bangumiUser.Avatar
+	if profile, err := json.Marshal(bangumiUser); err == nil {
+		identity.Profile = string(profile)
+	}
+	identity.LastLoginAt = &now
+
+	if err := s.identityRepo.Update(ctx, identity); err != nil {
+		return nil, fmt.Errorf("failed to update bangumi identity: %w", err)
+	}
+	return identity, nil
+}
+
+// callbackURL 在Bangumi登记的登录回调地址
+func (s *bangumiLoginService) callbackURL(ctx context.Context) string {
+	return util.IssuerFromContext(ctx) + "/bangumi/login/callback"
+}
@@ -0,0 +1,263 @@
+package service_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+// testBangumiUserID 模拟Bangumi API返回的用户ID
+const testBangumiUserID = 42
+
+// stubBangumi 模拟的Bangumi OAuth与用户信息接口
+type stubBangumi struct {
+	server *httptest.Server
+	// accessToken 令牌端点本次签发的访问令牌
+	accessToken string
+	// tokenUserID 令牌响应中的user_id
+	tokenUserID uint
+	// tokenRequests 令牌端点收到的请求数
+	tokenRequests int
+}
+
+// newStubBangumi 启动模拟的Bangumi API，并通过BANGUMI_BASE_URL指向它
+func newStubBangumi(t *testing.T) *stubBangumi {
+	t.Helper()
+	stub := &stubBangumi{accessToken: "bgm-access", tokenUserID: testBangumiUserID}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		stub.tokenRequests++
+		if r.FormValue("code") != "bgm-code" || r.FormValue("grant_type") != "authorization_code" ||
+			r.FormValue("client_id") != "bgm-client" || r.FormValue("redirect_uri") != testIssuer+"/bangumi/login/callback" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeTestJSON(w, map[string]interface{}{
+			"access_token":  stub.accessToken,
+			"refresh_token": stub.accessToken + "-refresh",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"scope":         "read",
+			"user_id":       stub.tokenUserID,
+		})
+	})
+	mux.HandleFunc("/oauth/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+stub.accessToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeTestJSON(w, service.BangumiUser{ID: testBangumiUserID, Username: "bgm_user", Nickname: "Bangumi User", Avatar: "https://lain.bgm.tv/avatar.jpg"})
+	})
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+
+	t.Setenv("BANGUMI_BASE_URL", stub.server.URL)
+	t.Setenv("BANGUMI_CLIENT_ID", "bgm-client")
+	t.Setenv("BANGUMI_CLIENT_SECRET", "bgm-secret")
+	return stub
+}
+
+// bangumiLoginFixture 连接模拟Bangumi API的Bangumi登录服务，其余依赖来自realmHarness
+type bangumiLoginFixture struct {
+	*realmHarness
+	stub         *stubBangumi
+	bangumi      service.BangumiService
+	bangumiLogin service.BangumiLoginService
+	accounts     repository.BangumiRepository
+	identities   repository.ExternalIdentityRepository
+}
+
+// newBangumiLoginFixture 创建连接模拟Bangumi API的BangumiLoginService
+func newBangumiLoginFixture(t *testing.T) *bangumiLoginFixture {
+	t.Helper()
+	h := newRealmHarness(t)
+	stub := newStubBangumi(t)
+	accounts := repository.NewBangumiRepository()
+	identities := repository.NewExternalIdentityRepository()
+	bangumi := service.NewBangumiService(accounts, repository.NewAnimeRepository(), repository.NewCollectionRepository(), identities, h.users, h.audit)
+	return &bangumiLoginFixture{
+		realmHarness: h,
+		stub:         stub,
+		bangumi:      bangumi,
+		bangumiLogin: service.NewBangumiLoginService(bangumi, accounts, h.users, repository.NewFederatedLoginStateRepository(), repository.NewBangumiPendingLoginRepository(), identities),
+		accounts:     accounts,
+		identities:   identities,
+	}
+}
+
+// start 发起Bangumi登录，返回state和浏览器绑定值
+func (f *bangumiLoginFixture) start(t *testing.T) (string, string) {
+	t.Helper()
+	authURL, binding, err := f.bangumiLogin.StartLogin(f.ctx, "")
+	if err != nil {
+		t.Fatalf("start bangumi login: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization url: %v", err)
+	}
+	state := parsed.Query().Get("state")
+	if state == "" || binding == "" {
+		t.Fatalf("expected state and browser binding, got %q %q", state, binding)
+	}
+	return state, binding
+}
+
+func TestBangumiLoginProvisionsAccount(t *testing.T) {
+	f := newBangumiLoginFixture(t)
+	state, binding := f.start(t)
+
+	result, err := f.bangumiLogin.CompleteLogin(f.ctx, "bgm-code", state, binding, 0)
+	if err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if result.User != nil || result.PendingToken == "" || result.BangumiUser.ID != testBangumiUserID {
+		t.Fatalf("unbound bangumi account should return a pending login, got %+v", result)
+	}
+
+	created, err := f.bangumiLogin.CreateAccount(f.ctx, result.PendingToken, "newbie", "newbie@example.com")
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	if !created.Created || created.User.Nickname != "Bangumi User" {
+		t.Fatalf("unexpected created account: %+v", created.User)
+	}
+
+	// 上游令牌保存在绑定记录与外部身份中
+	account, err := f.accounts.GetByUserID(f.ctx, created.User.ID)
+	if err != nil || account == nil {
+		t.Fatalf("bangumi account not bound: %v", err)
+	}
+	if account.BangumiUserID != testBangumiUserID || account.AccessToken != "bgm-access" || account.RefreshToken != "bgm-access-refresh" {
+		t.Fatalf("unexpected bangumi account: %+v", account)
+	}
+	identity, err := f.identities.GetByProviderSubject(f.ctx, service.BangumiProviderName, "42")
+	if err != nil || identity.UserID != created.User.ID || identity.AccessToken != "bgm-access" || identity.Username != "bgm_user" {
+		t.Fatalf("unexpected bangumi identity: %+v %v", identity, err)
+	}
+
+	// 待完成的登录只能使用一次
+	if _, err := f.bangumiLogin.GetPendingLogin(f.ctx, result.PendingToken); !errors.Is(err, service.ErrInvalidBangumiPendingLogin) {
+		t.Fatalf("pending login should be consumed, got %v", err)
+	}
+}
+
+func TestBangumiLoginSignsInBoundUserAndStoresTokens(t *testing.T) {
+	f := newBangumiLoginFixture(t)
+	user := f.alice
+	if err := f.bangumi.BindAccount(f.ctx, user.ID, &service.BangumiTokenResponse{AccessToken: "old-access", RefreshToken: "old-refresh", ExpiresIn: 60, UserID: testBangumiUserID}); err != nil {
+		t.Fatalf("bind account: %v", err)
+	}
+
+	f.stub.accessToken = "fresh-access"
+	state, binding := f.start(t)
+	result, err := f.bangumiLogin.CompleteLogin(f.ctx, "bgm-code", state, binding, 0)
+	if err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if result.User == nil || result.User.ID != user.ID || result.PendingToken != "" {
+		t.Fatalf("bound account should sign in directly, got %+v", result)
+	}
+
+	account, err := f.accounts.GetByUserID(f.ctx, user.ID)
+	if err != nil || account.AccessToken != "fresh-access" || account.RefreshToken != "fresh-access-refresh" {
+		t.Fatalf("bangumi tokens should be refreshed on login, got %+v %v", account, err)
+	}
+	identity, err := f.identities.GetByProviderSubject(f.ctx, service.BangumiProviderName, "42")
+	if err != nil || identity.AccessToken != "fresh-access" {
+		t.Fatalf("identity tokens should be refreshed on login, got %+v %v", identity, err)
+	}
+}
+
+func TestBangumiLoginLinksExistingAccount(t *testing.T) {
+	f := newBangumiLoginFixture(t)
+	user := f.alice
+
+	state, binding := f.start(t)
+	result, err := f.bangumiLogin.CompleteLogin(f.ctx, "bgm-code", state, binding, 0)
+	if err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	linked, err := f.bangumiLogin.LinkAccount(f.ctx, result.PendingToken, user)
+	if err != nil {
+		t.Fatalf("link account: %v", err)
+	}
+	if linked.User.ID != user.ID || linked.Created {
+		t.Fatalf("unexpected link result: %+v", linked)
+	}
+	if account, err := f.accounts.GetByBangumiUserID(f.ctx, testBangumiUserID); err != nil || account == nil || account.UserID != user.ID {
+		t.Fatalf("bangumi account should be bound to the user, got %+v %v", account, err)
+	}
+}
+
+func TestBangumiLoginRejectsMismatchedTokenUser(t *testing.T) {
+	f := newBangumiLoginFixture(t)
+	f.stub.tokenUserID = 7
+
+	state, binding := f.start(t)
+	if _, err := f.bangumiLogin.CompleteLogin(f.ctx, "bgm-code", state, binding, 0); err == nil {
+		t.Fatal("expected a token response for another bangumi user to be rejected")
+	}
+}
+
+func TestBangumiLoginRequiresBrowserBinding(t *testing.T) {
+	f := newBangumiLoginFixture(t)
+
+	for _, binding := range []string{"", "attacker-binding"} {
+		state, _ := f.start(t)
+		if _, err := f.bangumiLogin.CompleteLogin(f.ctx, "bgm-code", state, binding, 0); !errors.Is(err, service.ErrFederationStateMismatch) {
+			t.Fatalf("binding %q: expected ErrFederationStateMismatch, got %v", binding, err)
+		}
+	}
+	if f.stub.tokenRequests != 0 {
+		t.Fatalf("the code must not be exchanged without a browser binding, got %d token requests", f.stub.tokenRequests)
+	}
+}
+
+func TestBangumiLinkRequiresInitiatingSession(t *testing.T) {
+	f := newBangumiLoginFixture(t)
+	user := f.alice
+	other := &model.User{Username: "mallory", Email: "mallory@example.com", IsActive: true}
+	if err := f.users.Create(other); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	startLink := func() (string, string) {
+		authURL, binding, err := f.bangumiLogin.StartLink(f.ctx, user.ID, "")
+		if err != nil {
+			t.Fatalf("start link: %v", err)
+		}
+		parsed, err := url.Parse(authURL)
+		if err != nil {
+			t.Fatalf("parse authorization url: %v", err)
+		}
+		return parsed.Query().Get("state"), binding
+	}
+
+	// 回调所在浏览器未登录，或已切换为其他用户的会话
+	for _, sessionUserID := range []uint{0, other.ID} {
+		state, binding := startLink()
+		if _, err := f.bangumiLogin.CompleteLogin(f.ctx, "bgm-code", state, binding, sessionUserID); !errors.Is(err, service.ErrLinkSessionMismatch) {
+			t.Fatalf("session user %d: expected ErrLinkSessionMismatch, got %v", sessionUserID, err)
+		}
+	}
+	if account, _ := f.accounts.GetByBangumiUserID(f.ctx, testBangumiUserID); account != nil {
+		t.Fatalf("bangumi account should not be bound outside the initiating session, got %+v", account)
+	}
+
+	state, binding := startLink()
+	result, err := f.bangumiLogin.CompleteLogin(f.ctx, "bgm-code", state, binding, user.ID)
+	if err != nil {
+		t.Fatalf("complete link: %v", err)
+	}
+	if !result.Linked || result.User.ID != user.ID {
+		t.Fatalf("unexpected link result: %+v", result)
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"github.com/Full-finger/OIDC/internal/model"
 )
 
@@ -10,9 +11,15 @@ type BangumiService interface {
 	// GetAuthorizationURL 获取Bangumi授权URL
 	GetAuthorizationURL(state string) string
 	
+	// GetAuthorizationURLWithRedirect 使用指定回调地址获取Bangumi授权URL，用于Bangumi登录
+	GetAuthorizationURLWithRedirect(state, redirectURI string) string
+	
 	// ExchangeCodeForToken 用授权码换取访问令牌
 	ExchangeCodeForToken(ctx context.Context, code string) (*BangumiTokenResponse, error)
 	
+	// ExchangeCodeForTokenWithRedirect 使用指定回调地址兑换授权码，回调地址须与授权请求一致
+	ExchangeCodeForTokenWithRedirect(ctx context.Context, code, redirectURI string) (*BangumiTokenResponse, error)
+	
 	// RefreshToken 刷新访问令牌
 	RefreshToken(ctx context.Context, refreshToken string) (*BangumiTokenResponse, error)
 	
@@ -32,6 +39,9 @@ type BangumiService interface {
 	SyncCollection(ctx context.Context, userID uint) error
 }
 
+// ErrBangumiAccountAlreadyBound Bangumi账号已绑定到其他用户
+var ErrBangumiAccountAlreadyBound = errors.New("bangumi account is already bound to another user")
+
 // BangumiTokenResponse Bangumi令牌响应
 type BangumiTokenResponse struct {
 	AccessToken  string `json:"access_token"`
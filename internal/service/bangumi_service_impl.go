@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 	
 	"github.com/Full-finger/OIDC/internal/model"
@@ -23,29 +25,49 @@ type bangumiService struct {
 	bangumiRepo  repository.BangumiRepository
 	animeRepo    repository.AnimeRepository
 	collectionRepo repository.CollectionRepository
+	identityRepo repository.ExternalIdentityRepository
+	userRepo     repository.UserRepository
+	auditService AuditService
 }
 
 // NewBangumiService 创建BangumiService实例
-func NewBangumiService(bangumiRepo repository.BangumiRepository, animeRepo repository.AnimeRepository, collectionRepo repository.CollectionRepository) BangumiService {
+// 绑定记录同时作为provider为bangumi的外部身份保存，与其他上游身份一起展示和解除关联
+func NewBangumiService(bangumiRepo repository.BangumiRepository, animeRepo repository.AnimeRepository, collectionRepo repository.CollectionRepository, identityRepo repository.ExternalIdentityRepository, userRepo repository.UserRepository, auditService AuditService) BangumiService {
 	return &bangumiService{
 		clientID:     os.Getenv("BANGUMI_CLIENT_ID"),
 		clientSecret: os.Getenv("BANGUMI_CLIENT_SECRET"),
 		redirectURI:  os.Getenv("BANGUMI_REDIRECT_URI"),
-		baseURL:      "https://bgm.tv",
+		baseURL:      bangumiBaseURL(),
 		httpClient:   &http.Client{Timeout: 30 * time.Second},
 		bangumiRepo:  bangumiRepo,
 		animeRepo:    animeRepo,
 		collectionRepo: collectionRepo,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		auditService: auditService,
 	}
 }
 
+// bangumiBaseURL 获取Bangumi站点地址，由BANGUMI_BASE_URL配置，默认https://bgm.tv
+func bangumiBaseURL() string {
+	if baseURL := os.Getenv("BANGUMI_BASE_URL"); baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	return "https://bgm.tv"
+}
+
 // GetAuthorizationURL 获取Bangumi授权URL
 func (s *bangumiService) GetAuthorizationURL(state string) string {
+	return s.GetAuthorizationURLWithRedirect(state, s.redirectURI)
+}
+
+// GetAuthorizationURLWithRedirect 使用指定回调地址获取Bangumi授权URL，用于Bangumi登录
+func (s *bangumiService) GetAuthorizationURLWithRedirect(state, redirectURI string) string {
 	authURL := fmt.Sprintf("%s/oauth/authorize", s.baseURL)
 	
 	params := url.Values{}
 	params.Set("client_id", s.clientID)
-	params.Set("redirect_uri", s.redirectURI)
+	params.Set("redirect_uri", redirectURI)
 	params.Set("response_type", "code")
 	params.Set("state", state)
 	
@@ -54,12 +76,17 @@ func (s *bangumiService) GetAuthorizationURL(state string) string {
 
 // ExchangeCodeForToken 用授权码换取访问令牌
 func (s *bangumiService) ExchangeCodeForToken(ctx context.Context, code string) (*BangumiTokenResponse, error) {
+	return s.ExchangeCodeForTokenWithRedirect(ctx, code, s.redirectURI)
+}
+
+// ExchangeCodeForTokenWithRedirect 使用指定回调地址兑换授权码，回调地址须与授权请求一致
+func (s *bangumiService) ExchangeCodeForTokenWithRedirect(ctx context.Context, code, redirectURI string) (*BangumiTokenResponse, error) {
 	tokenURL := fmt.Sprintf("%s/oauth/access_token", s.baseURL)
 	
 	data := url.Values{}
 	data.Set("client_id", s.clientID)
 	data.Set("client_secret", s.clientSecret)
-	data.Set("redirect_uri", s.redirectURI)
+	data.Set("redirect_uri", redirectURI)
 	data.Set("grant_type", "authorization_code")
 	data.Set("code", code)
 	
@@ -141,6 +168,15 @@ func (s *bangumiService) GetUserInfo(ctx context.Context, accessToken string) (*
 
 // BindAccount 绑定Bangumi账号
 func (s *bangumiService) BindAccount(ctx context.Context, userID uint, tokenResponse *BangumiTokenResponse) error {
+	// 同一Bangumi账号只能绑定一个用户，否则无法通过Bangumi登录确定本地用户
+	boundAccount, err := s.bangumiRepo.GetByBangumiUserID(ctx, tokenResponse.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to check bangumi account binding: %w", err)
+	}
+	if boundAccount != nil && boundAccount.UserID != userID {
+		return ErrBangumiAccountAlreadyBound
+	}
+	
 	// 检查是否已经绑定
 	existingAccount, err := s.bangumiRepo.GetByUserID(ctx, userID)
 	if err != nil {
@@ -148,7 +184,10 @@ func (s *bangumiService) BindAccount(ctx context.Context, userID uint, tokenResp
 	}
 	
 	if existingAccount != nil {
-		// 如果已经绑定，更新令牌信息
+		// 如果已经绑定，更新令牌信息；换绑其他Bangumi账号时记录绑定事件
+		if existingAccount.BangumiUserID != tokenResponse.UserID {
+			s.recordBinding(ctx, model.AuditAccountLinked, userID, tokenResponse.UserID)
+		}
 		existingAccount.BangumiUserID = tokenResponse.UserID
 		existingAccount.AccessToken = tokenResponse.AccessToken
 		existingAccount.RefreshToken = tokenResponse.RefreshToken
@@ -156,7 +195,10 @@ func (s *bangumiService) BindAccount(ctx context.Context, userID uint, tokenResp
 		existingAccount.Scope = tokenResponse.Scope
 		existingAccount.UpdatedAt = time.Now()
 		
-		return s.bangumiRepo.Update(ctx, existingAccount)
+		if err := s.bangumiRepo.Update(ctx, existingAccount); err != nil {
+			return err
+		}
+		return s.syncIdentity(ctx, existingAccount)
 	}
 	
 	// 创建新的绑定记录
@@ -171,10 +213,14 @@ func (s *bangumiService) BindAccount(ctx context.Context, userID uint, tokenResp
 		UpdatedAt:       time.Now(),
 	}
 	
-	return s.bangumiRepo.Create(ctx, account)
+	if err := s.bangumiRepo.Create(ctx, account); err != nil {
+		return err
+	}
+	s.recordBinding(ctx, model.AuditAccountLinked, userID, account.BangumiUserID)
+	return s.syncIdentity(ctx, account)
 }
 
-// UnbindAccount 解绑Bangumi账号
+// UnbindAccount 解绑Bangumi账号，Bangumi是用户唯一的登录方式时不能解绑
 func (s *bangumiService) UnbindAccount(ctx context.Context, userID uint) error {
 	account, err := s.bangumiRepo.GetByUserID(ctx, userID)
 	if err != nil {
@@ -185,7 +231,70 @@ func (s *bangumiService) UnbindAccount(ctx context.Context, userID uint) error {
 		return nil // 没有绑定记录，直接返回
 	}
 	
-	return s.bangumiRepo.DeleteByID(ctx, account.ID)
+	var identityID uint
+	if identity, err := s.identityRepo.GetByProviderSubject(ctx, BangumiProviderName, strconv.FormatUint(uint64(account.BangumiUserID), 10)); err == nil {
+		identityID = identity.ID
+	}
+	if err := ensureOtherLoginMethod(ctx, s.userRepo, s.identityRepo, userID, identityID); err != nil {
+		return err
+	}
+	
+	if err := s.bangumiRepo.DeleteByID(ctx, account.ID); err != nil {
+		return err
+	}
+	s.recordBinding(ctx, model.AuditAccountUnlinked, userID, account.BangumiUserID)
+	if identityID != 0 {
+		return s.identityRepo.DeleteByID(ctx, identityID)
+	}
+	return nil
+}
+
+// recordBinding 记录Bangumi账号绑定或解绑事件
+func (s *bangumiService) recordBinding(ctx context.Context, eventType string, userID, bangumiUserID uint) {
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:    eventType,
+		UserID:  userID,
+		Details: map[string]string{"provider": BangumiProviderName, "subject": strconv.FormatUint(uint64(bangumiUserID), 10)},
+	})
+}
+
+// syncIdentity 将绑定记录中的Bangumi令牌同步到外部身份，并移除用户此前绑定的其他Bangumi账号
+func (s *bangumiService) syncIdentity(ctx context.Context, account *model.BangumiAccount) error {
+	subject := strconv.FormatUint(uint64(account.BangumiUserID), 10)
+	
+	identities, err := s.identityRepo.ListByUserID(ctx, account.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to list external identities: %w", err)
+	}
+	var identity *model.ExternalIdentity
+	for _, existing := range identities {
+		if existing.Provider != BangumiProviderName {
+			continue
+		}
+		if existing.Subject == subject {
+			identity = existing
+		} else if err := s.identityRepo.DeleteByID(ctx, existing.ID); err != nil {
+			return fmt.Errorf("failed to remove previous bangumi identity: %w", err)
+		}
+	}
+	
+	tokenExpiresAt := account.TokenExpiresAt
+	if identity == nil {
+		identity = &model.ExternalIdentity{
+			UserID:   account.UserID,
+			Provider: BangumiProviderName,
+			Subject:  subject,
+		}
+	}
+	identity.AccessToken = account.AccessToken
+	identity.RefreshToken = account.RefreshToken
+	identity.TokenExpiresAt = &tokenExpiresAt
+	identity.Scope = account.Scope
+	
+	if identity.ID == 0 {
+		return s.identityRepo.Create(ctx, identity)
+	}
+	return s.identityRepo.Update(ctx, identity)
 }
 
 // GetBoundAccount 获取已绑定的Bangumi账号
@@ -222,6 +331,9 @@ func (s *bangumiService) SyncCollection(ctx context.Context, userID uint) error
 		if err := s.bangumiRepo.Update(ctx, account); err != nil {
 			return fmt.Errorf("failed to update account tokens: %w", err)
 		}
+		if err := s.syncIdentity(ctx, account); err != nil {
+			return fmt.Errorf("failed to update account tokens: %w", err)
+		}
 		
 		accessToken = tokenResponse.AccessToken
 	}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// CIBAGrantType CIBA令牌端点使用的授权类型 (OpenID CIBA Core §10.1)
+const CIBAGrantType = "urn:openid:params:grant-type:ciba"
+
+// BackchannelAuthenticationRequest 后端通道认证请求参数 (OpenID CIBA Core §7.1)
+type BackchannelAuthenticationRequest struct {
+	Scopes                  []string
+	LoginHint               string // 用户名或邮箱
+	IDTokenHint             string // 此前签发给该用户的ID Token
+	BindingMessage          string
+	ClientNotificationToken string // ping模式必需
+	RequestedExpiry         *int   // 期望的有效期（秒）
+}
+
+// BackchannelAuthenticationResponse 后端通道认证响应 (OpenID CIBA Core §7.3)
+type BackchannelAuthenticationResponse struct {
+	AuthReqID string `json:"auth_req_id"`
+	ExpiresIn int    `json:"expires_in"`
+	Interval  int    `json:"interval,omitempty"`
+}
+
+// CIBAService 客户端发起的后端通道认证服务接口
+type CIBAService interface {
+	// StartAuthentication 为已认证的客户端创建认证请求，并通过Notifier通知用户
+	StartAuthentication(ctx context.Context, client *model.Client, req *BackchannelAuthenticationRequest) (*BackchannelAuthenticationResponse, error)
+	
+	// GetPendingRequest 获取属于该用户且待批准的认证请求，不存在时返回nil
+	GetPendingRequest(ctx context.Context, authReqID string, userID uint) (*model.BackchannelAuthRequest, error)
+	
+	// CompleteAuthentication 记录用户的批准或拒绝，ping模式下回调通知客户端
+	// amr为批准时登录会话完成的认证方式，用户启用了两步验证而会话未完成第二步时不能批准
+	CompleteAuthentication(ctx context.Context, authReqID string, userID uint, amr []string, approve bool) error
+	
+	// RedeemAuthReqID 在令牌端点兑换auth_req_id，仅在用户批准后返回认证请求，且只能兑换一次
+	RedeemAuthReqID(ctx context.Context, clientID, authReqID string) (*model.BackchannelAuthRequest, error)
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// CIBA默认配置
+const (
+	defaultBackchannelExpiry   = 300 // 认证请求默认有效期（秒）
+	defaultBackchannelInterval = 5   // 默认最小轮询间隔（秒）
+	maxBindingMessageLength    = 64  // 绑定消息最大长度（字符）
+)
+
+// cibaService 后端通道认证服务实现
+type cibaService struct {
+	authReqRepo  repository.BackchannelAuthRequestRepository
+	userRepo     repository.UserRepository
+	clientRepo   repository.ClientRepository
+	scopeService ScopeService
+	jwtUtil      util.JWTUtil
+	notifier     util.Notifier
+	mfaService   MFAService
+	realm        *model.Realm
+	httpClient   *http.Client
+	expiry       int
+	interval     int
+}
+
+// NewCIBAService 创建CIBAService实例
+// 认证请求有效期与轮询间隔分别由CIBA_REQUEST_EXPIRY_SECONDS和CIBA_POLL_INTERVAL_SECONDS配置
+func NewCIBAService(
+	authReqRepo repository.BackchannelAuthRequestRepository,
+	userRepo repository.UserRepository,
+	clientRepo repository.ClientRepository,
+	scopeService ScopeService,
+	jwtUtil util.JWTUtil,
+	notifier util.Notifier,
+	mfaService MFAService,
+	realm *model.Realm,
+) CIBAService {
+	expiry := defaultBackchannelExpiry
+	if seconds, err := strconv.Atoi(os.Getenv("CIBA_REQUEST_EXPIRY_SECONDS")); err == nil && seconds > 0 {
+		expiry = seconds
+	}
+	interval := defaultBackchannelInterval
+	if seconds, err := strconv.Atoi(os.Getenv("CIBA_POLL_INTERVAL_SECONDS")); err == nil && seconds > 0 {
+		interval = seconds
+	}
+	
+	return &cibaService{
+		authReqRepo:  authReqRepo,
+		userRepo:     userRepo,
+		clientRepo:   clientRepo,
+		scopeService: scopeService,
+		jwtUtil:      jwtUtil,
+		notifier:     notifier,
+		mfaService:   mfaService,
+		realm:        realm,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		expiry:       expiry,
+		interval:     interval,
+	}
+}
+
+// StartAuthentication 为已认证的客户端创建认证请求，并通过Notifier通知用户
+func (s *cibaService) StartAuthentication(ctx context.Context, client *model.Client, req *BackchannelAuthenticationRequest) (*BackchannelAuthenticationResponse, error) {
+	// CIBA仅适用于能够认证的机密客户端
+	if client.IsPublic() {
+		return nil, ErrUnauthorizedClient("public clients cannot use backchannel authentication")
+	}
+
+	// 客户端必须登记了CIBA交付模式
+	switch client.BackchannelTokenDeliveryMode {
+	case model.BackchannelDeliveryPoll:
+	case model.BackchannelDeliveryPing:
+		if client.BackchannelClientNotificationEndpoint == "" {
+			return nil, ErrUnauthorizedClient("the client has no backchannel_client_notification_endpoint registered")
+		}
+		if req.ClientNotificationToken == "" {
+			return nil, ErrInvalidRequest("missing client_notification_token")
+		}
+	default:
+		return nil, ErrUnauthorizedClient("the client is not registered for backchannel authentication")
+	}
+	
+	// 验证scopes：必须包含openid，且已注册并被客户端允许
+	if !containsString(req.Scopes, "openid") {
+		return nil, ErrInvalidScope("the openid scope is required")
+	}
+	if unknown, ok := s.scopeService.ValidateScopes(ctx, req.Scopes); !ok {
+		return nil, ErrInvalidScope(fmt.Sprintf("scope %q is not registered", unknown))
+	}
+	allowed := strings.Fields(client.Scopes)
+	for _, scope := range req.Scopes {
+		if !containsString(allowed, scope) {
+			return nil, ErrInvalidScope("the requested scope exceeds the scope granted to the client")
+		}
+	}
+	
+	if utf8.RuneCountInString(req.BindingMessage) > maxBindingMessageLength {
+		return nil, ErrInvalidBindingMessage(fmt.Sprintf("binding_message must not exceed %d characters", maxBindingMessageLength))
+	}
+	
+	// 根据提示识别用户
+	user, err := s.resolveUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	
+	expiresIn := s.expiry
+	if req.RequestedExpiry != nil {
+		if *req.RequestedExpiry <= 0 {
+			return nil, ErrInvalidRequest("requested_expiry must be a positive integer")
+		}
+		if *req.RequestedExpiry < expiresIn {
+			expiresIn = *req.RequestedExpiry
+		}
+	}
+	
+	authReqID, err := generateAuthReqID()
+	if err != nil {
+		return nil, ErrServerError("failed to generate auth_req_id")
+	}
+	
+	authReq := &model.BackchannelAuthRequest{
+		AuthReqID:               authReqID,
+		ClientID:                client.ClientID,
+		UserID:                  user.ID,
+		Scopes:                  strings.Join(req.Scopes, " "),
+		BindingMessage:          req.BindingMessage,
+		DeliveryMode:            client.BackchannelTokenDeliveryMode,
+		ClientNotificationToken: req.ClientNotificationToken,
+		Status:                  model.BackchannelStatusPending,
+		Interval:                s.interval,
+		ExpiresAt:               time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}
+	if err := s.authReqRepo.Create(ctx, authReq); err != nil {
+		return nil, ErrServerError("failed to save the authentication request")
+	}
+	
+	// 通知用户批准请求
+	if s.notifier != nil {
+		basePath := ""
+		if s.realm != nil {
+			basePath = s.realm.PathPrefix()
+		}
+		notification := &util.BackchannelNotification{
+			AuthReqID:      authReq.AuthReqID,
+			UserID:         user.ID,
+			Email:          user.Email,
+			ClientID:       client.ClientID,
+			ClientName:     clientDisplayName(client),
+			BindingMessage: authReq.BindingMessage,
+			Scopes:         req.Scopes,
+			BasePath:       basePath,
+			ExpiresAt:      authReq.ExpiresAt,
+		}
+		if err := s.notifier.NotifyBackchannelAuthRequest(notification); err != nil {
+			_ = s.authReqRepo.DeleteByID(ctx, authReq.ID)
+			return nil, ErrServerError("failed to notify the end-user")
+		}
+	}
+	
+	return &BackchannelAuthenticationResponse{
+		AuthReqID: authReq.AuthReqID,
+		ExpiresIn: expiresIn,
+		Interval:  authReq.Interval,
+	}, nil
+}
+
+// GetPendingRequest 获取属于该用户且待批准的认证请求
+func (s *cibaService) GetPendingRequest(ctx context.Context, authReqID string, userID uint) (*model.BackchannelAuthRequest, error) {
+	authReq, err := s.authReqRepo.GetByAuthReqID(ctx, authReqID)
+	if err != nil {
+		return nil, nil
+	}
+	
+	// 其他用户的请求视为不存在，避免泄露请求信息
+	if authReq.UserID != userID || authReq.Status != model.BackchannelStatusPending || authReq.IsExpired() {
+		return nil, nil
+	}
+	
+	return authReq, nil
+}
+
+// CompleteAuthentication 记录用户的批准或拒绝
+func (s *cibaService) CompleteAuthentication(ctx context.Context, authReqID string, userID uint, amr []string, approve bool) error {
+	authReq, _ := s.GetPendingRequest(ctx, authReqID, userID)
+	if authReq == nil {
+		return fmt.Errorf("authentication request not found or no longer pending")
+	}
+	
+	if approve {
+		// 与授权端点一致，启用了两步验证的用户须在完成第二步的会话中批准
+		if s.mfaService != nil && s.mfaService.RequiresSecondFactor(ctx, userID, amr) {
+			return ErrInteractionRequired("second factor authentication is required")
+		}
+		authReq.Status = model.BackchannelStatusApproved
+		authReq.AuthTime = time.Now()
+		authReq.AMR = strings.Join(amr, " ")
+	} else {
+		authReq.Status = model.BackchannelStatusDenied
+	}
+	
+	if err := s.authReqRepo.Update(ctx, authReq); err != nil {
+		return fmt.Errorf("failed to update authentication request: %w", err)
+	}
+	
+	// ping模式下通知客户端到令牌端点获取结果
+	if authReq.DeliveryMode == model.BackchannelDeliveryPing {
+		client, err := s.clientRepo.GetByClientID(ctx, authReq.ClientID)
+		if err == nil && client.BackchannelClientNotificationEndpoint != "" {
+			go s.pingClient(client.BackchannelClientNotificationEndpoint, authReq.ClientNotificationToken, authReq.AuthReqID)
+		}
+	}
+	
+	return nil
+}
+
+// RedeemAuthReqID 在令牌端点兑换auth_req_id (OpenID CIBA Core §10、§11)
+func (s *cibaService) RedeemAuthReqID(ctx context.Context, clientID, authReqID string) (*model.BackchannelAuthRequest, error) {
+	if authReqID == "" {
+		return nil, ErrInvalidRequest("missing auth_req_id")
+	}
+	
+	authReq, err := s.authReqRepo.GetByAuthReqID(ctx, authReqID)
+	if err != nil || authReq.ClientID != clientID {
+		return nil, ErrInvalidGrant("invalid auth_req_id")
+	}
+	
+	// 过期、已批准和已拒绝的请求都只能得到一次结果，由条件删除决定并发轮询中哪一方取得结果
+	expired := authReq.IsExpired()
+	if expired || authReq.Status != model.BackchannelStatusPending {
+		consumed, err := s.authReqRepo.ConsumeByID(ctx, authReq.ID)
+		if err != nil {
+			return nil, ErrServerError("failed to consume the authentication request")
+		}
+		if !consumed {
+			return nil, ErrInvalidGrant("invalid auth_req_id")
+		}
+	}
+	
+	if expired {
+		return nil, ErrExpiredToken("the auth_req_id has expired")
+	}
+	
+	switch authReq.Status {
+	case model.BackchannelStatusApproved:
+		return authReq, nil
+	case model.BackchannelStatusDenied:
+		return nil, NewOAuthError(ErrCodeAccessDenied, "the end-user denied the authorization request", http.StatusBadRequest)
+	}
+	
+	// 仍在等待用户批准，轮询过快时要求客户端增加间隔
+	now := time.Now()
+	tooFast := !authReq.LastPolledAt.IsZero() && now.Sub(authReq.LastPolledAt) < time.Duration(authReq.Interval)*time.Second
+	authReq.LastPolledAt = now
+	if tooFast {
+		authReq.Interval += defaultBackchannelInterval
+	}
+	if err := s.authReqRepo.Update(ctx, authReq); err != nil {
+		return nil, ErrServerError("failed to update the authentication request")
+	}
+	
+	if tooFast {
+		return nil, ErrSlowDown(fmt.Sprintf("polling too frequently, wait at least %d seconds", authReq.Interval))
+	}
+	return nil, ErrAuthorizationPending("the end-user has not yet approved the request")
+}
+
+// resolveUser 根据login_hint或id_token_hint识别用户，两者必须且只能提供一个
+func (s *cibaService) resolveUser(ctx context.Context, req *BackchannelAuthenticationRequest) (*model.User, error) {
+	if (req.LoginHint == "") == (req.IDTokenHint == "") {
+		return nil, ErrInvalidRequest("exactly one of login_hint or id_token_hint is required")
+	}
+	
+	var user *model.User
+	var err error
+	if req.IDTokenHint != "" {
+		if s.jwtUtil == nil {
+			return nil, ErrServerError("JWT utility not available")
+		}
+		claims, parseErr := s.jwtUtil.ParseIDTokenHint(req.IDTokenHint)
+		if parseErr != nil || claims.Issuer != util.IssuerFromContext(ctx) {
+			return nil, ErrInvalidRequest("invalid id_token_hint")
+		}
+		var userID uint
+		if _, scanErr := fmt.Sscanf(claims.Subject, "user:%d", &userID); scanErr != nil {
+			return nil, ErrInvalidRequest("invalid id_token_hint")
+		}
+		user, err = s.userRepo.GetByID(userID)
+	} else if strings.Contains(req.LoginHint, "@") {
+		user, err = s.userRepo.GetByEmail(req.LoginHint)
+	} else {
+		user, err = s.userRepo.GetByUsername(req.LoginHint)
+	}
+	
+	if err != nil || user == nil || !user.IsActive {
+		return nil, ErrUnknownUserID("the end-user could not be identified")
+	}
+	
+	return user, nil
+}
+
+// pingClient 回调客户端的通知端点 (OpenID CIBA Core §10.2)
+func (s *cibaService) pingClient(endpoint, notificationToken, authReqID string) {
+	body, _ := json.Marshal(map[string]string{"auth_req_id": authReqID})
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("CIBA回调请求创建失败: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+notificationToken)
+	
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		log.Printf("CIBA回调客户端失败: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode/100 != 2 {
+		log.Printf("CIBA回调客户端返回状态码 %d", resp.StatusCode)
+	}
+}
+
+// generateAuthReqID 生成auth_req_id，至少128位熵 (OpenID CIBA Core §7.3)
+func generateAuthReqID() (string, error) {
+	idBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(idBytes), nil
+}
+
+// clientDisplayName 获取客户端展示名称
+func clientDisplayName(client *model.Client) string {
+	if client.Name != "" {
+		return client.Name
+	}
+	return client.ClientID
+}
+
+// containsString 判断列表是否包含指定值
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+// startBackchannel 为poll模式客户端发起CIBA认证请求，并确认用户收到了通知
+func startBackchannel(t *testing.T, h *realmHarness) (*model.Client, string) {
+	t.Helper()
+	client := h.addClient(t, &model.Client{
+		ClientID:                     "ciba-client",
+		Name:                         "CIBA Client",
+		RedirectURI:                  "https://ciba.example.com/callback",
+		Scopes:                       "openid profile",
+		BackchannelTokenDeliveryMode: model.BackchannelDeliveryPoll,
+	})
+
+	response, err := h.ciba.StartAuthentication(h.ctx, client, &service.BackchannelAuthenticationRequest{
+		Scopes:         []string{"openid", "profile"},
+		LoginHint:      h.alice.Username,
+		BindingMessage: "W4SCT",
+	})
+	if err != nil {
+		t.Fatalf("start authentication: %v", err)
+	}
+	if response.AuthReqID == "" || response.Interval <= 0 {
+		t.Fatalf("unexpected backchannel response: %+v", response)
+	}
+
+	notifications := h.notifier.Notifications()
+	if len(notifications) != 1 || notifications[0].AuthReqID != response.AuthReqID || notifications[0].UserID != h.alice.ID || notifications[0].BindingMessage != "W4SCT" {
+		t.Fatalf("the user should be notified of the request, got %+v", notifications)
+	}
+	return client, response.AuthReqID
+}
+
+// pollBackchannel 以客户端身份在令牌端点轮询
+func pollBackchannel(h *realmHarness, client *model.Client, authReqID string) (*service.TokenResponse, error) {
+	return h.oauth.ExchangeBackchannelAuthRequest(h.ctx, authReqID, client.ClientID, testClientSecret)
+}
+
+// requireOAuthErrorCode 断言错误为指定错误码的OAuthError
+func requireOAuthErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != code {
+		t.Fatalf("expected %s, got %v", code, err)
+	}
+}
+
+// rewindLastPoll 将上次轮询时间提前，模拟客户端按间隔等待后再轮询
+func rewindLastPoll(t *testing.T, h *realmHarness, authReqID string, d time.Duration) {
+	t.Helper()
+	authReq, err := h.authReqs.GetByAuthReqID(h.ctx, authReqID)
+	if err != nil {
+		t.Fatalf("get auth request: %v", err)
+	}
+	authReq.LastPolledAt = authReq.LastPolledAt.Add(-d)
+	if err := h.authReqs.Update(h.ctx, authReq); err != nil {
+		t.Fatalf("update auth request: %v", err)
+	}
+}
+
+// concurrently 并发执行n次fn，返回成功的次数
+func concurrently(n int, fn func() error) int {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if fn() == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return succeeded
+}
+
+func TestCIBAPendingAndSlowDown(t *testing.T) {
+	h := newRealmHarness(t)
+	client, authReqID := startBackchannel(t, h)
+
+	_, err := pollBackchannel(h, client, authReqID)
+	requireOAuthErrorCode(t, err, service.ErrCodeAuthorizationPending)
+
+	// 未等待interval就再次轮询
+	_, err = pollBackchannel(h, client, authReqID)
+	requireOAuthErrorCode(t, err, service.ErrCodeSlowDown)
+
+	// slow_down后间隔增加5秒，按原间隔轮询仍然过快
+	authReq, err := h.authReqs.GetByAuthReqID(h.ctx, authReqID)
+	if err != nil {
+		t.Fatalf("get auth request: %v", err)
+	}
+	interval := time.Duration(authReq.Interval) * time.Second
+	rewindLastPoll(t, h, authReqID, interval-5*time.Second)
+	_, err = pollBackchannel(h, client, authReqID)
+	requireOAuthErrorCode(t, err, service.ErrCodeSlowDown)
+
+	// 按增加后的间隔等待后恢复为authorization_pending
+	authReq, _ = h.authReqs.GetByAuthReqID(h.ctx, authReqID)
+	rewindLastPoll(t, h, authReqID, time.Duration(authReq.Interval)*time.Second)
+	_, err = pollBackchannel(h, client, authReqID)
+	requireOAuthErrorCode(t, err, service.ErrCodeAuthorizationPending)
+}
+
+func TestCIBADenied(t *testing.T) {
+	h := newRealmHarness(t)
+	client, authReqID := startBackchannel(t, h)
+
+	if err := h.ciba.CompleteAuthentication(h.ctx, authReqID, h.alice.ID, []string{model.AMRPassword}, false); err != nil {
+		t.Fatalf("deny authentication: %v", err)
+	}
+	_, err := pollBackchannel(h, client, authReqID)
+	requireOAuthErrorCode(t, err, service.ErrCodeAccessDenied)
+
+	// 拒绝结果只返回一次
+	_, err = pollBackchannel(h, client, authReqID)
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidGrant)
+}
+
+func TestCIBAExpired(t *testing.T) {
+	h := newRealmHarness(t)
+	client, authReqID := startBackchannel(t, h)
+
+	authReq, err := h.authReqs.GetByAuthReqID(h.ctx, authReqID)
+	if err != nil {
+		t.Fatalf("get auth request: %v", err)
+	}
+	authReq.ExpiresAt = time.Now().Add(-time.Second)
+	if err := h.authReqs.Update(h.ctx, authReq); err != nil {
+		t.Fatalf("update auth request: %v", err)
+	}
+
+	// 过期的请求不能再批准
+	if err := h.ciba.CompleteAuthentication(h.ctx, authReqID, h.alice.ID, []string{model.AMRPassword}, true); err == nil {
+		t.Fatal("expected approving an expired request to fail")
+	}
+	_, err = pollBackchannel(h, client, authReqID)
+	requireOAuthErrorCode(t, err, service.ErrCodeExpiredToken)
+}
+
+func TestCIBAApprovedIssuesTokens(t *testing.T) {
+	h := newRealmHarness(t)
+	client, authReqID := startBackchannel(t, h)
+
+	// 其他用户不能批准该请求
+	if err := h.ciba.CompleteAuthentication(h.ctx, authReqID, h.alice.ID+1, []string{model.AMRPassword}, true); err == nil {
+		t.Fatal("expected another user to be unable to approve the request")
+	}
+	if err := h.ciba.CompleteAuthentication(h.ctx, authReqID, h.alice.ID, []string{model.AMRPassword}, true); err != nil {
+		t.Fatalf("approve authentication: %v", err)
+	}
+
+	response, err := pollBackchannel(h, client, authReqID)
+	if err != nil {
+		t.Fatalf("redeem auth_req_id: %v", err)
+	}
+	if response.AccessToken == "" || response.IDToken == "" || response.Scope != "openid profile" {
+		t.Fatalf("unexpected token response: %+v", response)
+	}
+	claims, err := h.jwtUtil.ParseAccessToken(response.AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	if claims.Subject == "" || claims.Scope != "openid profile" {
+		t.Fatalf("unexpected access token claims: %+v", claims)
+	}
+
+	// auth_req_id只能兑换一次
+	_, err = pollBackchannel(h, client, authReqID)
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidGrant)
+}
+
+func TestCIBAApprovalRequiresSecondFactor(t *testing.T) {
+	f, _ := newMFAFixture(t)
+	client, authReqID := startBackchannel(t, f.realmHarness)
+
+	// 启用了两步验证的用户不能在只完成密码登录的会话中批准
+	err := f.ciba.CompleteAuthentication(f.ctx, authReqID, f.alice.ID, []string{model.AMRPassword}, true)
+	requireOAuthErrorCode(t, err, service.ErrCodeInteractionRequired)
+	_, err = pollBackchannel(f.realmHarness, client, authReqID)
+	requireOAuthErrorCode(t, err, service.ErrCodeAuthorizationPending)
+
+	amr := []string{model.AMRPassword, model.AMROTP}
+	if err := f.ciba.CompleteAuthentication(f.ctx, authReqID, f.alice.ID, amr, true); err != nil {
+		t.Fatalf("approve authentication: %v", err)
+	}
+	rewindLastPoll(t, f.realmHarness, authReqID, time.Minute)
+	response, err := pollBackchannel(f.realmHarness, client, authReqID)
+	if err != nil {
+		t.Fatalf("redeem auth_req_id: %v", err)
+	}
+
+	// ID Token的amr来自批准时的登录会话
+	claims, err := f.jwtUtil.ParseIDToken(response.IDToken)
+	if err != nil {
+		t.Fatalf("parse id token: %v", err)
+	}
+	if strings.Join(claims.AMR, " ") != "pwd otp" {
+		t.Fatalf("expected amr [pwd otp], got %v", claims.AMR)
+	}
+}
+
+func TestCIBAConcurrentRedeemIssuesTokensOnce(t *testing.T) {
+	h := newRealmHarness(t)
+	client, authReqID := startBackchannel(t, h)
+	if err := h.ciba.CompleteAuthentication(h.ctx, authReqID, h.alice.ID, []string{model.AMRPassword}, true); err != nil {
+		t.Fatalf("approve authentication: %v", err)
+	}
+
+	succeeded := concurrently(8, func() error {
+		_, err := pollBackchannel(h, client, authReqID)
+		return err
+	})
+	if succeeded != 1 {
+		t.Fatalf("an approved auth_req_id should be redeemed exactly once, got %d", succeeded)
+	}
+}
+
+func TestAuthorizationCodeConcurrentExchangeSucceedsOnce(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid profile"})
+	code, err := h.oauth.GenerateAuthorizationCode(h.ctx, client, h.alice.ID, client.RedirectURI, []string{"openid"}, nil, nil)
+	if err != nil {
+		t.Fatalf("generate authorization code: %v", err)
+	}
+
+	succeeded := concurrently(8, func() error {
+		_, err := h.oauth.ExchangeAuthorizationCode(h.ctx, code, client.ClientID, testClientSecret, client.RedirectURI, nil, nil)
+		return err
+	})
+	if succeeded != 1 {
+		t.Fatalf("an authorization code should be exchanged exactly once, got %d", succeeded)
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ValidateClientRegistration 校验客户端登记信息：重定向URI (RFC 6749 §3.1.2，RFC 8252 §7) 与令牌端点认证方式 (RFC 7591 §2)
+func ValidateClientRegistration(client *model.Client) error {
+	uris := client.RegisteredRedirectURIs()
+	if len(uris) == 0 {
+		return errors.New("at least one redirect_uri must be registered")
+	}
+	for _, uri := range uris {
+		if err := validateRedirectURI(uri); err != nil {
+			return fmt.Errorf("invalid redirect_uri %q: %w", uri, err)
+		}
+	}
+
+	switch client.TokenEndpointAuthMethod {
+	case "", model.TokenEndpointAuthClientSecretBasic, model.TokenEndpointAuthClientSecretPost:
+		if client.SecretHash == "" {
+			return errors.New("confidential clients must register a client secret")
+		}
+	case model.TokenEndpointAuthNone:
+		if client.SecretHash != "" {
+			return errors.New("public clients must not register a client secret")
+		}
+		// CIBA要求客户端能够在令牌端点完成认证
+		if client.BackchannelTokenDeliveryMode != "" {
+			return errors.New("public clients cannot use backchannel authentication")
+		}
+	default:
+		return fmt.Errorf("unsupported token_endpoint_auth_method %q", client.TokenEndpointAuthMethod)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// 邮箱变更错误，处理器据此选择HTTP状态码
+var (
+	ErrInvalidEmailChangeToken = errors.New("invalid or expired email change token")
+	ErrEmailUnchanged          = errors.New("new email is the same as the current email")
+	ErrEmailInUse              = errors.New("email is already registered")
+)
+
+// EmailChangeService 邮箱变更服务接口
+type EmailChangeService interface {
+	// RequestChange 申请更改邮箱，向新邮箱发送确认链接，并向原邮箱发送附带撤销链接的通知；
+	// 用户之前未确认的申请失效
+	RequestChange(ctx context.Context, userID uint, newEmail string) (*model.EmailChangeRequest, error)
+
+	// ConfirmChange 校验新邮箱收到的确认令牌，将用户邮箱替换为已验证的新邮箱
+	ConfirmChange(ctx context.Context, token string) (*model.User, error)
+
+	// RevertChange 校验原邮箱收到的撤销令牌，取消未确认的申请或恢复原邮箱，
+	// 并撤销用户的全部刷新令牌和登录会话；原邮箱已被其他用户注册时仍撤销令牌和会话，返回ErrEmailInUse
+	RevertChange(ctx context.Context, token string) error
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// 邮箱变更链接的默认有效期
+const (
+	defaultEmailChangeExpiry       = 24 * time.Hour
+	defaultEmailChangeRevertExpiry = 7 * 24 * time.Hour
+)
+
+// emailChangeService 邮箱变更服务实现
+type emailChangeService struct {
+	requestRepo    repository.EmailChangeRequestRepository
+	userRepo       repository.UserRepository
+	refreshRepo    repository.RefreshTokenRepository
+	sessionService SessionService
+	emailQueue     util.EmailQueue
+	auditService   AuditService
+	realm          *model.Realm
+	tokenTTL       time.Duration
+	revertTTL      time.Duration
+}
+
+// NewEmailChangeService 创建realm范围内的EmailChangeService实例，确认链接和撤销链接的有效期分别由
+// EMAIL_CHANGE_TOKEN_EXPIRY_SECONDS和EMAIL_CHANGE_REVERT_EXPIRY_SECONDS配置
+func NewEmailChangeService(requestRepo repository.EmailChangeRequestRepository, userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, sessionService SessionService, emailQueue util.EmailQueue, auditService AuditService, realm *model.Realm) EmailChangeService {
+	return &emailChangeService{
+		requestRepo:    requestRepo,
+		userRepo:       userRepo,
+		refreshRepo:    refreshRepo,
+		sessionService: sessionService,
+		emailQueue:     emailQueue,
+		auditService:   auditService,
+		realm:          realm,
+		tokenTTL:       envSeconds("EMAIL_CHANGE_TOKEN_EXPIRY_SECONDS", defaultEmailChangeExpiry),
+		revertTTL:      envSeconds("EMAIL_CHANGE_REVERT_EXPIRY_SECONDS", defaultEmailChangeRevertExpiry),
+	}
+}
+
+// RequestChange 保存邮箱变更请求，并将确认邮件和通知邮件加入队列，用户邮箱在确认前保持不变
+func (s *emailChangeService) RequestChange(ctx context.Context, userID uint, newEmail string) (*model.EmailChangeRequest, error) {
+	newEmail = strings.TrimSpace(newEmail)
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return nil, ErrEmailUnchanged
+	}
+	if _, err := s.userRepo.GetByEmail(newEmail); err == nil {
+		return nil, ErrEmailInUse
+	}
+
+	if err := s.requestRepo.DeletePendingByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete pending email change requests: %w", err)
+	}
+
+	token := randomURLToken(32)
+	revertToken := randomURLToken(32)
+	now := time.Now()
+	request := &model.EmailChangeRequest{
+		UserID:           userID,
+		OldEmail:         user.Email,
+		OldEmailVerified: user.EmailVerified,
+		NewEmail:         newEmail,
+		TokenHash:        hashEmailChangeToken(token),
+		RevertTokenHash:  hashEmailChangeToken(revertToken),
+		ExpiresAt:        now.Add(s.tokenTTL),
+		RevertExpiresAt:  now.Add(s.revertTTL),
+	}
+	if err := s.requestRepo.Create(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to save email change request: %w", err)
+	}
+
+	if err := s.emailQueue.Enqueue(util.EmailQueueItem{
+		Email:    newEmail,
+		Token:    token,
+		BasePath: s.realm.PathPrefix(),
+		Type:     util.EmailTypeEmailChange,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to enqueue email change confirmation: %w", err)
+	}
+	if err := s.emailQueue.Enqueue(util.EmailQueueItem{
+		Email:    user.Email,
+		Token:    revertToken,
+		BasePath: s.realm.PathPrefix(),
+		Type:     util.EmailTypeEmailChangeNotice,
+		NewEmail: newEmail,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to enqueue email change notice: %w", err)
+	}
+
+	return request, nil
+}
+
+// ConfirmChange 确认新邮箱，确认令牌只能使用一次，撤销令牌在有效期内仍然可用
+func (s *emailChangeService) ConfirmChange(ctx context.Context, token string) (*model.User, error) {
+	request, err := s.requestRepo.GetByTokenHash(ctx, hashEmailChangeToken(token))
+	if err != nil || request.IsConfirmed() {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	if time.Now().After(request.ExpiresAt) {
+		_ = s.requestRepo.DeleteByID(ctx, request.ID)
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	user, err := s.userRepo.GetByID(request.UserID)
+	if err != nil || user.Email != request.OldEmail {
+		// 申请之后邮箱已经变化，该申请不再有效
+		_ = s.requestRepo.DeleteByID(ctx, request.ID)
+		return nil, ErrInvalidEmailChangeToken
+	}
+	if existing, err := s.userRepo.GetByEmail(request.NewEmail); err == nil && existing.ID != user.ID {
+		_ = s.requestRepo.DeleteByID(ctx, request.ID)
+		return nil, ErrEmailInUse
+	}
+
+	user.Email = request.NewEmail
+	user.EmailVerified = true
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+
+	confirmedAt := time.Now()
+	request.ConfirmedAt = &confirmedAt
+	if err := s.requestRepo.Update(ctx, request); err != nil {
+		return nil, fmt.Errorf("failed to save email change request: %w", err)
+	}
+
+	return user, nil
+}
+
+// RevertChange 撤销邮箱变更，撤销令牌只能使用一次
+func (s *emailChangeService) RevertChange(ctx context.Context, token string) error {
+	request, err := s.requestRepo.GetByRevertTokenHash(ctx, hashEmailChangeToken(token))
+	if err != nil {
+		return ErrInvalidEmailChangeToken
+	}
+	_ = s.requestRepo.DeleteByID(ctx, request.ID)
+	if time.Now().After(request.RevertExpiresAt) {
+		return ErrInvalidEmailChangeToken
+	}
+
+	user, err := s.userRepo.GetByID(request.UserID)
+	if err != nil {
+		return ErrInvalidEmailChangeToken
+	}
+
+	if err := s.requestRepo.DeletePendingByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete pending email change requests: %w", err)
+	}
+
+	var restoreErr error
+	if user.Email != request.OldEmail {
+		if existing, err := s.userRepo.GetByEmail(request.OldEmail); err == nil && existing.ID != user.ID {
+			// 原邮箱在变更后被其他用户注册时无法恢复，但仍需让可能盗用账户的人下线
+			restoreErr = ErrEmailInUse
+		} else {
+			user.Email = request.OldEmail
+			user.EmailVerified = request.OldEmailVerified
+			if err := s.userRepo.Update(user); err != nil {
+				return fmt.Errorf("failed to save user: %w", err)
+			}
+		}
+	}
+
+	// 变更可能是他人盗用账户发起的，让用户在所有设备和客户端上重新登录
+	if err := s.refreshRepo.RevokeByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := s.sessionService.DeleteUserSessions(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:    model.AuditTokenRevoked,
+		UserID:  user.ID,
+		Details: map[string]string{"reason": "email_change_reverted"},
+	})
+	return restoreErr
+}
+
+// hashEmailChangeToken 计算邮箱变更令牌的SHA-256哈希，数据库中只保存哈希
+func hashEmailChangeToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
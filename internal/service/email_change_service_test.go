@@ -0,0 +1,237 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// emailChangeFixture 邮箱变更服务及其内存依赖
+type emailChangeFixture struct {
+	ctx         context.Context
+	emailChange service.EmailChangeService
+	requests    repository.EmailChangeRequestRepository
+	users       repository.UserRepository
+	refreshRepo repository.RefreshTokenRepository
+	sessionRepo repository.SessionRepository
+	emailQueue  util.EmailQueue
+	user        *model.User
+}
+
+// newEmailChangeFixture 创建邮箱变更服务，并为邮箱已验证的用户准备刷新令牌和登录会话
+func newEmailChangeFixture(t *testing.T) *emailChangeFixture {
+	t.Helper()
+	f := &emailChangeFixture{
+		ctx:         context.Background(),
+		requests:    repository.NewEmailChangeRequestRepository(),
+		users:       repository.NewUserRepository(nil),
+		refreshRepo: repository.NewRefreshTokenRepository(),
+		sessionRepo: repository.NewSessionRepository(0),
+		emailQueue:  util.NewSimpleEmailQueue(),
+	}
+	f.emailChange = service.NewEmailChangeService(f.requests, f.users, f.refreshRepo, service.NewSessionService(f.sessionRepo, nil), f.emailQueue, nil, &model.Realm{Name: "default"})
+
+	f.user = &model.User{Username: "alice", Email: "alice@example.com", EmailVerified: true, IsActive: true}
+	if err := f.users.Create(f.user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	now := time.Now()
+	if err := f.refreshRepo.Create(f.ctx, &model.RefreshToken{TokenHash: "refresh-token-hash", UserID: f.user.ID, ClientID: "web", ExpiresAt: now.Add(time.Hour), AbsoluteExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+	if err := f.sessionRepo.Create(f.ctx, &model.Session{ID: "session-id", UserID: f.user.ID, AuthTime: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	return f
+}
+
+// requestChange 申请更改邮箱，返回新邮箱收到的确认令牌和原邮箱收到的撤销令牌
+func (f *emailChangeFixture) requestChange(t *testing.T, newEmail string) (string, string) {
+	t.Helper()
+	if _, err := f.emailChange.RequestChange(f.ctx, f.user.ID, newEmail); err != nil {
+		t.Fatalf("request change: %v", err)
+	}
+	confirmation, err := f.emailQueue.Dequeue()
+	if err != nil || confirmation.Type != util.EmailTypeEmailChange || confirmation.Email != newEmail {
+		t.Fatalf("expected a confirmation email to %s, got %+v %v", newEmail, confirmation, err)
+	}
+	notice, err := f.emailQueue.Dequeue()
+	if err != nil || notice.Type != util.EmailTypeEmailChangeNotice || notice.NewEmail != newEmail {
+		t.Fatalf("expected a notice email to the old address, got %+v %v", notice, err)
+	}
+	return confirmation.Token, notice.Token
+}
+
+// expire 按撤销令牌找到申请，将确认链接(revert为false)或撤销链接的过期时间设为过去
+func (f *emailChangeFixture) expire(t *testing.T, revertToken string, revert bool) {
+	t.Helper()
+	hash := sha256.Sum256([]byte(revertToken))
+	request, err := f.requests.GetByRevertTokenHash(f.ctx, hex.EncodeToString(hash[:]))
+	if err != nil {
+		t.Fatalf("get email change request: %v", err)
+	}
+	if revert {
+		request.RevertExpiresAt = time.Now().Add(-time.Second)
+	} else {
+		request.ExpiresAt = time.Now().Add(-time.Second)
+	}
+	if err := f.requests.Update(f.ctx, request); err != nil {
+		t.Fatalf("update email change request: %v", err)
+	}
+}
+
+// requireEmail 断言用户当前的邮箱及验证状态
+func (f *emailChangeFixture) requireEmail(t *testing.T, email string, verified bool) {
+	t.Helper()
+	user, err := f.users.GetByID(f.user.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if user.Email != email || user.EmailVerified != verified {
+		t.Fatalf("expected email %s (verified=%v), got %s (verified=%v)", email, verified, user.Email, user.EmailVerified)
+	}
+}
+
+// requireSignedOut 断言用户的刷新令牌已撤销且会话已删除
+func (f *emailChangeFixture) requireSignedOut(t *testing.T, signedOut bool) {
+	t.Helper()
+	token, _ := f.refreshRepo.GetByTokenHash(f.ctx, "refresh-token-hash")
+	if token == nil || token.RevokedAt.IsZero() != !signedOut {
+		t.Fatalf("expected refresh token revoked=%v, got %+v", signedOut, token)
+	}
+	if _, err := f.sessionRepo.GetByID(f.ctx, "session-id"); (err != nil) != signedOut {
+		t.Fatalf("expected sessions deleted=%v, got %v", signedOut, err)
+	}
+}
+
+func TestEmailChangeConfirmOnlyOnce(t *testing.T) {
+	f := newEmailChangeFixture(t)
+	token, _ := f.requestChange(t, "alice@new.example.com")
+
+	// 确认前邮箱保持不变
+	f.requireEmail(t, "alice@example.com", true)
+	user, err := f.emailChange.ConfirmChange(f.ctx, token)
+	if err != nil {
+		t.Fatalf("confirm change: %v", err)
+	}
+	if user.Email != "alice@new.example.com" || !user.EmailVerified {
+		t.Fatalf("unexpected user after confirmation: %+v", user)
+	}
+
+	if _, err := f.emailChange.ConfirmChange(f.ctx, token); !errors.Is(err, service.ErrInvalidEmailChangeToken) {
+		t.Fatalf("expected a second confirmation to be rejected, got %v", err)
+	}
+	f.requireEmail(t, "alice@new.example.com", true)
+	f.requireSignedOut(t, false)
+}
+
+func TestEmailChangeConfirmRejectsSupersededAndTakenEmail(t *testing.T) {
+	f := newEmailChangeFixture(t)
+	first, _ := f.requestChange(t, "first@example.com")
+	second, _ := f.requestChange(t, "second@example.com")
+
+	// 新申请使之前未确认的申请失效
+	if _, err := f.emailChange.ConfirmChange(f.ctx, first); !errors.Is(err, service.ErrInvalidEmailChangeToken) {
+		t.Fatalf("expected a superseded request to be rejected, got %v", err)
+	}
+
+	// 确认前新邮箱已被其他用户注册
+	if err := f.users.Create(&model.User{Username: "bob", Email: "second@example.com", IsActive: true}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if _, err := f.emailChange.ConfirmChange(f.ctx, second); !errors.Is(err, service.ErrEmailInUse) {
+		t.Fatalf("expected ErrEmailInUse, got %v", err)
+	}
+	f.requireEmail(t, "alice@example.com", true)
+}
+
+func TestEmailChangeConfirmExpiry(t *testing.T) {
+	f := newEmailChangeFixture(t)
+	token, revertToken := f.requestChange(t, "alice@new.example.com")
+
+	f.expire(t, revertToken, false)
+	if _, err := f.emailChange.ConfirmChange(f.ctx, token); !errors.Is(err, service.ErrInvalidEmailChangeToken) {
+		t.Fatalf("expected an expired confirmation to be rejected, got %v", err)
+	}
+	f.requireEmail(t, "alice@example.com", true)
+}
+
+func TestEmailChangeRevertBeforeConfirm(t *testing.T) {
+	f := newEmailChangeFixture(t)
+	token, revertToken := f.requestChange(t, "alice@new.example.com")
+
+	if err := f.emailChange.RevertChange(f.ctx, revertToken); err != nil {
+		t.Fatalf("revert change: %v", err)
+	}
+	if _, err := f.emailChange.ConfirmChange(f.ctx, token); !errors.Is(err, service.ErrInvalidEmailChangeToken) {
+		t.Fatalf("expected a reverted request to be unconfirmable, got %v", err)
+	}
+	f.requireEmail(t, "alice@example.com", true)
+	f.requireSignedOut(t, true)
+}
+
+func TestEmailChangeRevertAfterConfirmRestoresVerification(t *testing.T) {
+	for _, verified := range []bool{true, false} {
+		f := newEmailChangeFixture(t)
+		f.user.EmailVerified = verified
+		if err := f.users.Update(f.user); err != nil {
+			t.Fatalf("update user: %v", err)
+		}
+		token, revertToken := f.requestChange(t, "alice@new.example.com")
+		if _, err := f.emailChange.ConfirmChange(f.ctx, token); err != nil {
+			t.Fatalf("confirm change: %v", err)
+		}
+
+		if err := f.emailChange.RevertChange(f.ctx, revertToken); err != nil {
+			t.Fatalf("revert change: %v", err)
+		}
+		f.requireEmail(t, "alice@example.com", verified)
+		f.requireSignedOut(t, true)
+
+		// 撤销令牌只能使用一次
+		if err := f.emailChange.RevertChange(f.ctx, revertToken); !errors.Is(err, service.ErrInvalidEmailChangeToken) {
+			t.Fatalf("expected a second revert to be rejected, got %v", err)
+		}
+	}
+}
+
+func TestEmailChangeRevertWhenOldEmailTaken(t *testing.T) {
+	f := newEmailChangeFixture(t)
+	token, revertToken := f.requestChange(t, "alice@new.example.com")
+	if _, err := f.emailChange.ConfirmChange(f.ctx, token); err != nil {
+		t.Fatalf("confirm change: %v", err)
+	}
+	if err := f.users.Create(&model.User{Username: "mallory", Email: "alice@example.com", IsActive: true}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// 原邮箱无法恢复，但刷新令牌和会话仍被撤销
+	if err := f.emailChange.RevertChange(f.ctx, revertToken); !errors.Is(err, service.ErrEmailInUse) {
+		t.Fatalf("expected ErrEmailInUse, got %v", err)
+	}
+	f.requireEmail(t, "alice@new.example.com", true)
+	f.requireSignedOut(t, true)
+}
+
+func TestEmailChangeRevertExpiry(t *testing.T) {
+	f := newEmailChangeFixture(t)
+	token, revertToken := f.requestChange(t, "alice@new.example.com")
+	if _, err := f.emailChange.ConfirmChange(f.ctx, token); err != nil {
+		t.Fatalf("confirm change: %v", err)
+	}
+
+	f.expire(t, revertToken, true)
+	if err := f.emailChange.RevertChange(f.ctx, revertToken); !errors.Is(err, service.ErrInvalidEmailChangeToken) {
+		t.Fatalf("expected an expired revert to be rejected, got %v", err)
+	}
+	f.requireEmail(t, "alice@new.example.com", true)
+	f.requireSignedOut(t, false)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// 上游登录错误，处理器据此选择HTTP状态码
+var (
+	ErrIdentityProviderNotFound = errors.New("identity provider not found")
+	ErrInvalidFederationState   = errors.New("invalid or expired federation state")
+	ErrFederationStateMismatch  = errors.New("federation state was not started by this browser")
+	ErrLinkSessionMismatch      = errors.New("identity link must be completed by the user who started it")
+	ErrInvalidReturnTo          = errors.New("return_to must point to this issuer")
+	ErrFederatedEmailConflict   = errors.New("a local account with this email already exists")
+	ErrFederatedEmailMissing    = errors.New("the identity provider did not return an email address")
+	ErrFederatedUserInactive    = errors.New("the linked local account is not active")
+)
+
+// FederationProvider 登录页展示的上游身份提供方
+type FederationProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"`
+}
+
+// FederatedLoginResult 上游登录完成后的结果
+type FederatedLoginResult struct {
+	User     *model.User
+	Identity *model.ExternalIdentity
+	Created  bool   // 是否新建了本地用户
+	Linked   bool   // 是否为已登录用户关联上游身份
+	ReturnTo string // 登录完成后跳转的地址
+}
+
+// FederationService 上游OpenID Connect登录服务接口
+type FederationService interface {
+	// ListProviders 获取realm配置的上游身份提供方
+	ListProviders(ctx context.Context) []FederationProvider
+
+	// StartLogin 发起上游登录，返回上游授权端点地址和浏览器绑定值，returnTo必须指向本issuer
+	// 浏览器绑定值须写入发起登录的浏览器的Cookie，回调时原样传给CompleteLogin
+	StartLogin(ctx context.Context, providerName, returnTo string) (string, string, error)
+
+	// StartLink 为已登录用户发起上游身份关联，返回值同StartLogin，调用方须确认用户刚完成认证
+	StartLink(ctx context.Context, providerName string, userID uint, returnTo string) (string, string, error)
+
+	// CompleteLogin 处理上游回调：校验state与浏览器绑定值，兑换授权码、校验ID Token，并关联或创建本地用户
+	// sessionUserID为回调请求所在登录会话的用户，未登录时为0；关联账号时必须与发起关联的用户一致
+	CompleteLogin(ctx context.Context, providerName, code, state, binding string, sessionUserID uint) (*FederatedLoginResult, error)
+}
@@ -0,0 +1,678 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// defaultFederationLoginExpiry 上游登录默认有效期，可通过FEDERATION_LOGIN_EXPIRY_SECONDS覆盖
+const defaultFederationLoginExpiry = 10 * time.Minute
+
+// upstreamMetadata 上游身份提供方的发现文档中本服务使用的字段
+type upstreamMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// upstreamTokenResponse 上游令牌端点响应
+type upstreamTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Scope            string `json:"scope"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// upstreamIDTokenClaims 上游ID Token声明
+type upstreamIDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string      `json:"nonce"`
+	Azp               string      `json:"azp,omitempty"`
+	Email             string      `json:"email,omitempty"`
+	EmailVerified     interface{} `json:"email_verified,omitempty"` // 部分提供方以字符串形式返回
+	PreferredUsername string      `json:"preferred_username,omitempty"`
+	Name              string      `json:"name,omitempty"`
+	Picture           string      `json:"picture,omitempty"`
+}
+
+// isEmailVerified 判断上游是否声明邮箱已验证
+func (c *upstreamIDTokenClaims) isEmailVerified() bool {
+	switch verified := c.EmailVerified.(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	default:
+		return false
+	}
+}
+
+// federationService 上游OpenID Connect登录服务实现
+type federationService struct {
+	providers    map[string]*model.IdentityProvider
+	order        []string
+	identityRepo repository.ExternalIdentityRepository
+	stateRepo    repository.FederatedLoginStateRepository
+	userRepo     repository.UserRepository
+	auditService AuditService
+	httpClient   *http.Client
+
+	// 发现文档与签名公钥缓存，按提供方名称索引
+	mu       sync.Mutex
+	metadata map[string]*upstreamMetadata
+	keys     map[string]map[string]*rsa.PublicKey
+}
+
+// NewFederationService 创建FederationService实例，providers为realm配置的上游身份提供方
+func NewFederationService(providers []model.IdentityProvider, identityRepo repository.ExternalIdentityRepository, stateRepo repository.FederatedLoginStateRepository, userRepo repository.UserRepository, auditService AuditService) FederationService {
+	s := &federationService{
+		providers:    make(map[string]*model.IdentityProvider),
+		identityRepo: identityRepo,
+		stateRepo:    stateRepo,
+		userRepo:     userRepo,
+		auditService: auditService,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		metadata:     make(map[string]*upstreamMetadata),
+		keys:         make(map[string]map[string]*rsa.PublicKey),
+	}
+	for i := range providers {
+		provider := providers[i]
+		if _, exists := s.providers[provider.Name]; !exists {
+			s.order = append(s.order, provider.Name)
+		}
+		s.providers[provider.Name] = &provider
+	}
+	return s
+}
+
+// ValidateIdentityProvider 校验上游身份提供方配置
+func ValidateIdentityProvider(provider *model.IdentityProvider) error {
+	if provider.Name == "" || strings.ContainsAny(provider.Name, "/?#") {
+		return errors.New("name must be a non-empty path segment")
+	}
+	issuer, err := url.Parse(provider.Issuer)
+	if err != nil || (issuer.Scheme != "https" && issuer.Scheme != "http") || issuer.Host == "" {
+		return errors.New("issuer must be an absolute http(s) URL")
+	}
+	if provider.ClientID == "" {
+		return errors.New("client_id is required")
+	}
+	if provider.Name == BangumiProviderName {
+		return errors.New("name bangumi is reserved for sign in with Bangumi")
+	}
+	return nil
+}
+
+// ListProviders 获取realm配置的上游身份提供方
+func (s *federationService) ListProviders(ctx context.Context) []FederationProvider {
+	issuer := util.IssuerFromContext(ctx)
+
+	providers := make([]FederationProvider, 0, len(s.order))
+	for _, name := range s.order {
+		provider := s.providers[name]
+		displayName := provider.DisplayName
+		if displayName == "" {
+			displayName = provider.Name
+		}
+		providers = append(providers, FederationProvider{
+			Name:        provider.Name,
+			DisplayName: displayName,
+			LoginURL:    issuer + "/federation/" + provider.Name + "/login",
+		})
+	}
+	return providers
+}
+
+// StartLogin 发起上游登录，返回上游授权端点地址和浏览器绑定值
+func (s *federationService) StartLogin(ctx context.Context, providerName, returnTo string) (string, string, error) {
+	return s.startAuthorization(ctx, providerName, returnTo, 0)
+}
+
+// StartLink 为已登录用户发起上游身份关联，返回上游授权端点地址和浏览器绑定值
+func (s *federationService) StartLink(ctx context.Context, providerName string, userID uint, returnTo string) (string, string, error) {
+	return s.startAuthorization(ctx, providerName, returnTo, userID)
+}
+
+// startAuthorization 保存state、nonce、PKCE code_verifier与浏览器绑定值的哈希，返回上游授权端点地址和浏览器绑定值
+func (s *federationService) startAuthorization(ctx context.Context, providerName, returnTo string, linkUserID uint) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrIdentityProviderNotFound
+	}
+
+	// 只允许跳转回本issuer下的地址，避免开放重定向
+	if returnTo != "" && !strings.HasPrefix(returnTo, util.IssuerFromContext(ctx)+"/") {
+		return "", "", ErrInvalidReturnTo
+	}
+
+	metadata, err := s.discover(ctx, provider)
+	if err != nil {
+		return "", "", err
+	}
+
+	binding := randomURLToken(32)
+	loginState := &model.FederatedLoginState{
+		State:        randomURLToken(32),
+		Provider:     provider.Name,
+		Nonce:        randomURLToken(32),
+		CodeVerifier: randomURLToken(32),
+		ReturnTo:     returnTo,
+		LinkUserID:   linkUserID,
+		BindingHash:  hashStateBinding(binding),
+		ExpiresAt:    time.Now().Add(envSeconds("FEDERATION_LOGIN_EXPIRY_SECONDS", defaultFederationLoginExpiry)),
+	}
+	if err := s.stateRepo.Create(ctx, loginState); err != nil {
+		return "", "", fmt.Errorf("failed to save federation state: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(loginState.CodeVerifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", provider.ClientID)
+	params.Set("redirect_uri", s.callbackURL(ctx, provider))
+	params.Set("scope", providerScopes(provider))
+	params.Set("state", loginState.State)
+	params.Set("nonce", loginState.Nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid upstream authorization endpoint: %w", err)
+	}
+	query := authURL.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), binding, nil
+}
+
+// CompleteLogin 处理上游回调：校验state与浏览器绑定值，兑换授权码、校验ID Token，并关联或创建本地用户
+func (s *federationService) CompleteLogin(ctx context.Context, providerName, code, state, binding string, sessionUserID uint) (*FederatedLoginResult, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrIdentityProviderNotFound
+	}
+
+	loginState, err := consumeFederatedLoginState(ctx, s.stateRepo, provider.Name, state, binding, sessionUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.discover(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenResponse, err := s.exchangeCode(ctx, provider, metadata, code, loginState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.verifyIDToken(ctx, provider, metadata, tokenResponse.IDToken, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *FederatedLoginResult
+	if loginState.LinkUserID != 0 {
+		result, err = s.linkToUser(ctx, provider, claims, loginState.LinkUserID)
+	} else {
+		result, err = s.resolveUser(ctx, provider, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+	recordIdentityLogin(result.Identity, tokenResponse, claims)
+	if err := s.identityRepo.Update(ctx, result.Identity); err != nil {
+		return nil, fmt.Errorf("failed to update external identity: %w", err)
+	}
+	result.ReturnTo = loginState.ReturnTo
+
+	return result, nil
+}
+
+// consumeFederatedLoginState 取出并删除指定提供方的state（只能使用一次），校验有效期与浏览器绑定值
+// 回调必须来自发起登录的浏览器，否则攻击者可以诱导受害者完成攻击者发起的登录 (RFC 6749 §10.12)；
+// 关联账号时回调所在会话的用户还必须是发起关联的用户，避免上游身份被关联到会话已切换的其他用户
+func consumeFederatedLoginState(ctx context.Context, stateRepo repository.FederatedLoginStateRepository, providerName, state, binding string, sessionUserID uint) (*model.FederatedLoginState, error) {
+	loginState, err := stateRepo.GetByState(ctx, state)
+	if err != nil || state == "" {
+		return nil, ErrInvalidFederationState
+	}
+	_ = stateRepo.DeleteByID(ctx, loginState.ID)
+	if loginState.IsExpired() || loginState.Provider != providerName {
+		return nil, ErrInvalidFederationState
+	}
+	if binding == "" || subtle.ConstantTimeCompare([]byte(hashStateBinding(binding)), []byte(loginState.BindingHash)) != 1 {
+		return nil, ErrFederationStateMismatch
+	}
+	if loginState.LinkUserID != 0 && loginState.LinkUserID != sessionUserID {
+		return nil, ErrLinkSessionMismatch
+	}
+	return loginState, nil
+}
+
+// hashStateBinding 计算浏览器绑定值的SHA-256哈希，state中只保存哈希
+func hashStateBinding(binding string) string {
+	hash := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(hash[:])
+}
+
+// resolveUser 根据上游身份查找已关联的本地用户；未关联时按邮箱关联或创建新用户
+func (s *federationService) resolveUser(ctx context.Context, provider *model.IdentityProvider, claims *upstreamIDTokenClaims) (*FederatedLoginResult, error) {
+	// 已关联的上游身份
+	if identity, err := s.identityRepo.GetByProviderSubject(ctx, provider.Name, claims.Subject); err == nil {
+		user, err := s.userRepo.GetByID(identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load linked user: %w", err)
+		}
+		if !user.IsActive {
+			return nil, ErrFederatedUserInactive
+		}
+		return &FederatedLoginResult{User: user, Identity: identity}, nil
+	}
+
+	if claims.Email == "" {
+		return nil, ErrFederatedEmailMissing
+	}
+
+	// 邮箱已被本地用户使用：仅在配置允许且上游已验证邮箱时自动关联，否则需要用户登录后手动关联
+	if existing, err := s.userRepo.GetByEmail(claims.Email); err == nil {
+		if !provider.LinkByEmail || !claims.isEmailVerified() {
+			return nil, ErrFederatedEmailConflict
+		}
+		if !existing.IsActive {
+			return nil, ErrFederatedUserInactive
+		}
+		identity, err := s.linkIdentity(ctx, provider, claims, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &FederatedLoginResult{User: existing, Identity: identity}, nil
+	}
+
+	// 创建本地用户，没有本地密码，只能通过上游登录
+	nickname := claims.Name
+	if nickname == "" {
+		nickname = claims.PreferredUsername
+	}
+	user := &model.User{
+		Username:      s.uniqueUsername(provider, claims),
+		Email:         claims.Email,
+		EmailVerified: claims.isEmailVerified(),
+		Nickname:      nickname,
+		AvatarURL:     claims.Picture,
+		IsActive:      true,
+	}
+	if user.Nickname == "" {
+		user.Nickname = user.Username
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	identity, err := s.linkIdentity(ctx, provider, claims, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &FederatedLoginResult{User: user, Identity: identity, Created: true}, nil
+}
+
+// linkToUser 将上游身份关联到发起关联的已登录用户，已关联其他用户的上游身份不能再关联
+func (s *federationService) linkToUser(ctx context.Context, provider *model.IdentityProvider, claims *upstreamIDTokenClaims, userID uint) (*FederatedLoginResult, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if !user.IsActive {
+		return nil, ErrFederatedUserInactive
+	}
+
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, provider.Name, claims.Subject)
+	if err == nil {
+		if identity.UserID != user.ID {
+			return nil, ErrIdentityLinkedToOther
+		}
+	} else if identity, err = s.linkIdentity(ctx, provider, claims, user.ID); err != nil {
+		return nil, err
+	}
+
+	return &FederatedLoginResult{User: user, Identity: identity, Linked: true}, nil
+}
+
+// recordIdentityLogin 保存上游返回的令牌与用户信息快照
+func recordIdentityLogin(identity *model.ExternalIdentity, tokenResponse *upstreamTokenResponse, claims *upstreamIDTokenClaims) {
+	now := time.Now()
+	if claims.Email != "" {
+		identity.Email = claims.Email
+	}
+	identity.Username = claims.PreferredUsername
+	identity.DisplayName = claims.Name
+	identity.AvatarURL = claims.Picture
+	if profile, err := json.Marshal(claims); err == nil {
+		identity.Profile = string(profile)
+	}
+	identity.AccessToken = tokenResponse.AccessToken
+	identity.RefreshToken = tokenResponse.RefreshToken
+	identity.TokenExpiresAt = nil
+	if tokenResponse.ExpiresIn > 0 {
+		expiresAt := now.Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+		identity.TokenExpiresAt = &expiresAt
+	}
+	identity.Scope = tokenResponse.Scope
+	identity.LastLoginAt = &now
+}
+
+// linkIdentity 将上游身份关联到本地用户
+func (s *federationService) linkIdentity(ctx context.Context, provider *model.IdentityProvider, claims *upstreamIDTokenClaims, userID uint) (*model.ExternalIdentity, error) {
+	identity := &model.ExternalIdentity{
+		UserID:   userID,
+		Provider: provider.Name,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	if err := s.identityRepo.Create(ctx, identity); err != nil {
+		return nil, fmt.Errorf("failed to link external identity: %w", err)
+	}
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:    model.AuditAccountLinked,
+		UserID:  userID,
+		Details: map[string]string{"provider": provider.Name, "subject": claims.Subject},
+	})
+	return identity, nil
+}
+
+// uniqueUsername 根据上游声明生成未被占用的用户名
+func (s *federationService) uniqueUsername(provider *model.IdentityProvider, claims *upstreamIDTokenClaims) string {
+	base := sanitizeUsername(claims.PreferredUsername)
+	if base == "" {
+		base = sanitizeUsername(strings.SplitN(claims.Email, "@", 2)[0])
+	}
+	if base == "" {
+		base = sanitizeUsername(provider.Name + "_user")
+	}
+
+	candidate := base
+	for i := 2; i <= 100; i++ {
+		if _, err := s.userRepo.GetByUsername(candidate); err != nil {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d", base, i)
+	}
+	return base + "_" + randomURLToken(6)
+}
+
+// sanitizeUsername 只保留字母、数字和 _ . -，并限制长度
+func sanitizeUsername(raw string) string {
+	var builder strings.Builder
+	for _, ch := range raw {
+		if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') || ch == '_' || ch == '.' || ch == '-' {
+			builder.WriteRune(ch)
+		}
+		if builder.Len() >= 40 {
+			break
+		}
+	}
+	return builder.String()
+}
+
+// discover 获取并缓存上游发现文档，文档中的issuer必须与配置一致 (OIDC Discovery §4.3)
+func (s *federationService) discover(ctx context.Context, provider *model.IdentityProvider) (*upstreamMetadata, error) {
+	s.mu.Lock()
+	cached, ok := s.metadata[provider.Name]
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var metadata upstreamMetadata
+	discoveryURL := strings.TrimSuffix(provider.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := s.getJSON(ctx, discoveryURL, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to load upstream discovery document: %w", err)
+	}
+	if metadata.Issuer != provider.Issuer {
+		return nil, fmt.Errorf("upstream discovery document issuer %q does not match %q", metadata.Issuer, provider.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JwksURI == "" {
+		return nil, errors.New("upstream discovery document is missing required endpoints")
+	}
+
+	s.mu.Lock()
+	s.metadata[provider.Name] = &metadata
+	s.mu.Unlock()
+
+	return &metadata, nil
+}
+
+// exchangeCode 在上游令牌端点兑换授权码，携带PKCE code_verifier
+func (s *federationService) exchangeCode(ctx context.Context, provider *model.IdentityProvider, metadata *upstreamMetadata, code, codeVerifier string) (*upstreamTokenResponse, error) {
+	if code == "" {
+		return nil, ErrInvalidFederationState
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.callbackURL(ctx, provider))
+	form.Set("code_verifier", codeVerifier)
+
+	// 默认使用client_secret_basic，上游仅支持client_secret_post时改为表单传递
+	useBasic := provider.ClientSecret != "" && !onlySupportsSecretPost(metadata.TokenEndpointAuthMethodsSupported)
+	if !useBasic {
+		form.Set("client_id", provider.ClientID)
+		if provider.ClientSecret != "" {
+			form.Set("client_secret", provider.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upstream token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		// 凭据在Base64编码前需要form-urlencoded编码 (RFC 6749 §2.3.1)
+		req.SetBasicAuth(url.QueryEscape(provider.ClientID), url.QueryEscape(provider.ClientSecret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange upstream authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokenResponse upstreamTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode upstream token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream token endpoint returned %d: %s %s", resp.StatusCode, tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, errors.New("upstream token response does not contain an id_token")
+	}
+
+	return &tokenResponse, nil
+}
+
+// verifyIDToken 校验上游ID Token的签名、iss、aud、exp、azp与nonce (OIDC Core §3.1.3.7)
+func (s *federationService) verifyIDToken(ctx context.Context, provider *model.IdentityProvider, metadata *upstreamMetadata, idToken, nonce string) (*upstreamIDTokenClaims, error) {
+	claims := &upstreamIDTokenClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.signingKey(ctx, provider, metadata, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(provider.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream id_token: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("invalid upstream id_token: missing sub")
+	}
+	if len(claims.Audience) > 1 && claims.Azp != provider.ClientID {
+		return nil, errors.New("invalid upstream id_token: azp does not match client_id")
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("invalid upstream id_token: nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// signingKey 根据kid获取上游签名公钥，未知kid时重新拉取JWKS以支持密钥轮换
+func (s *federationService) signingKey(ctx context.Context, provider *model.IdentityProvider, metadata *upstreamMetadata, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	key := lookupKey(s.keys[provider.Name], kid)
+	s.mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+
+	var jwks util.JWKS
+	if err := s.getJSON(ctx, metadata.JwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to load upstream JWKS: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		if publicKey, err := parseRSAJWK(jwk); err == nil {
+			keys[jwk.Kid] = publicKey
+		}
+	}
+
+	s.mu.Lock()
+	s.keys[provider.Name] = keys
+	s.mu.Unlock()
+
+	if key := lookupKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no upstream signing key found for kid %q", kid)
+}
+
+// lookupKey 按kid查找公钥，ID Token未携带kid且只有一把密钥时使用该密钥
+func lookupKey(keys map[string]*rsa.PublicKey, kid string) *rsa.PublicKey {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// parseRSAJWK 将JWK转换为RSA公钥
+func parseRSAJWK(jwk util.JWK) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+// getJSON 请求上游地址并解析JSON响应
+func (s *federationService) getJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// callbackURL 本服务在上游登记的回调地址
+func (s *federationService) callbackURL(ctx context.Context, provider *model.IdentityProvider) string {
+	return util.IssuerFromContext(ctx) + "/federation/" + provider.Name + "/callback"
+}
+
+// providerScopes 获取向上游请求的scope，必须包含openid
+func providerScopes(provider *model.IdentityProvider) string {
+	scopes := strings.Fields(provider.Scopes)
+	if len(scopes) == 0 {
+		return "openid profile email"
+	}
+	for _, scope := range scopes {
+		if scope == "openid" {
+			return strings.Join(scopes, " ")
+		}
+	}
+	return strings.Join(append([]string{"openid"}, scopes...), " ")
+}
+
+// onlySupportsSecretPost 判断上游令牌端点是否只支持client_secret_post
+func onlySupportsSecretPost(methods []string) bool {
+	supportsPost := false
+	for _, method := range methods {
+		switch method {
+		case "client_secret_basic":
+			return false
+		case "client_secret_post":
+			supportsPost = true
+		}
+	}
+	return supportsPost
+}
+
+// randomURLToken 生成URL安全的随机字符串
+func randomURLToken(size int) string {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
@@ -0,0 +1,380 @@
+package service_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testUpstreamClientID 本服务在模拟上游登记的客户端ID
+const testUpstreamClientID = "oidc-test"
+
+// mockIdP 模拟的上游OpenID Connect身份提供方
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// issuer 发现文档中声明的issuer，默认与服务地址一致
+	issuer string
+	// idToken 令牌端点返回的ID Token
+	idToken string
+}
+
+// newMockIdP 启动模拟的上游身份提供方
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	idp := &mockIdP{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]interface{}{
+			"issuer":                 idp.issuer,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, util.JWKS{Keys: []util.JWK{{
+			Kty: "RSA",
+			Use: "sig",
+			Kid: "upstream",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "upstream-code" || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeTestJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeTestJSON(w, map[string]interface{}{
+			"access_token":  "upstream-access-token",
+			"refresh_token": "upstream-refresh-token",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"id_token":      idp.idToken,
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	idp.issuer = idp.server.URL
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// claims 生成对nonce有效的ID Token声明
+func (idp *mockIdP) claims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"sub":                "upstream-user",
+		"aud":                testUpstreamClientID,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"nonce":              nonce,
+		"email":              "carol@example.com",
+		"email_verified":     true,
+		"preferred_username": "carol",
+	}
+}
+
+// sign 使用上游密钥签名ID Token
+func (idp *mockIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "upstream"
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatalf("sign id token: %v", err)
+	}
+	return signed
+}
+
+// writeTestJSON 写入JSON响应
+func writeTestJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// federationFixture 连接模拟上游的上游登录服务，其余依赖来自realmHarness
+type federationFixture struct {
+	*realmHarness
+	idp        *mockIdP
+	federation service.FederationService
+	identities repository.ExternalIdentityRepository
+}
+
+// newFederationFixture 创建连接模拟上游的FederationService
+func newFederationFixture(t *testing.T) *federationFixture {
+	t.Helper()
+	h := newRealmHarness(t)
+	idp := newMockIdP(t)
+	identities := repository.NewExternalIdentityRepository()
+	providers := []model.IdentityProvider{{
+		Name:         "upstream",
+		Issuer:       idp.server.URL,
+		ClientID:     testUpstreamClientID,
+		ClientSecret: "upstream-secret",
+	}}
+	return &federationFixture{
+		realmHarness: h,
+		idp:          idp,
+		federation:   service.NewFederationService(providers, identities, repository.NewFederatedLoginStateRepository(), h.users, h.audit),
+		identities:   identities,
+	}
+}
+
+// pendingFederatedLogin 已发起、等待上游回调的登录
+type pendingFederatedLogin struct {
+	state   string
+	nonce   string
+	binding string
+	// sessionUserID 回调请求所在登录会话的用户，未登录时为0
+	sessionUserID uint
+}
+
+// parseFederatedLogin 从上游授权地址中取出state和nonce
+func parseFederatedLogin(t *testing.T, authURL, binding string) *pendingFederatedLogin {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization url: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("state") == "" || query.Get("nonce") == "" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("authorization url is missing state, nonce or PKCE: %s", authURL)
+	}
+	if binding == "" {
+		t.Fatal("expected a browser binding value")
+	}
+	return &pendingFederatedLogin{state: query.Get("state"), nonce: query.Get("nonce"), binding: binding}
+}
+
+// startLogin 发起上游登录
+func (f *federationFixture) startLogin(t *testing.T) *pendingFederatedLogin {
+	t.Helper()
+	authURL, binding, err := f.federation.StartLogin(f.ctx, "upstream", "")
+	if err != nil {
+		t.Fatalf("start login: %v", err)
+	}
+	return parseFederatedLogin(t, authURL, binding)
+}
+
+// complete 使用指定的ID Token完成回调
+func (f *federationFixture) complete(login *pendingFederatedLogin, idToken string) (*service.FederatedLoginResult, error) {
+	f.idp.idToken = idToken
+	return f.federation.CompleteLogin(f.ctx, "upstream", "upstream-code", login.state, login.binding, login.sessionUserID)
+}
+
+func TestFederationDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	f := newFederationFixture(t)
+	f.idp.issuer = "https://evil.example.com"
+
+	if _, _, err := f.federation.StartLogin(f.ctx, "upstream", ""); err == nil {
+		t.Fatal("expected discovery with a mismatched issuer to fail")
+	}
+}
+
+func TestFederationCallbackProvisionsUser(t *testing.T) {
+	f := newFederationFixture(t)
+	login := f.startLogin(t)
+
+	result, err := f.complete(login, f.idp.sign(t, f.idp.claims(login.nonce)))
+	if err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if !result.Created || result.Linked {
+		t.Fatalf("expected a new local user, got created=%v linked=%v", result.Created, result.Linked)
+	}
+	if result.User.Email != "carol@example.com" || result.User.Username != "carol" {
+		t.Fatalf("unexpected provisioned user: %+v", result.User)
+	}
+
+	identity, err := f.identities.GetByProviderSubject(f.ctx, "upstream", "upstream-user")
+	if err != nil {
+		t.Fatalf("identity not linked: %v", err)
+	}
+	if identity.UserID != result.User.ID || identity.AccessToken != "upstream-access-token" || identity.RefreshToken != "upstream-refresh-token" {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+
+	// 再次登录使用已关联的本地用户
+	again := f.startLogin(t)
+	second, err := f.complete(again, f.idp.sign(t, f.idp.claims(again.nonce)))
+	if err != nil {
+		t.Fatalf("complete second login: %v", err)
+	}
+	if second.Created || second.User.ID != result.User.ID {
+		t.Fatalf("expected the linked user to sign in, got created=%v user=%d", second.Created, second.User.ID)
+	}
+}
+
+func TestFederationCallbackLinksIdentity(t *testing.T) {
+	f := newFederationFixture(t)
+	user := f.alice
+
+	authURL, binding, err := f.federation.StartLink(f.ctx, "upstream", user.ID, testIssuer+"/account")
+	if err != nil {
+		t.Fatalf("start link: %v", err)
+	}
+	login := parseFederatedLogin(t, authURL, binding)
+	login.sessionUserID = user.ID
+
+	result, err := f.complete(login, f.idp.sign(t, f.idp.claims(login.nonce)))
+	if err != nil {
+		t.Fatalf("complete link: %v", err)
+	}
+	if !result.Linked || result.Created || result.User.ID != user.ID || result.ReturnTo != testIssuer+"/account" {
+		t.Fatalf("unexpected link result: %+v", result)
+	}
+	identity, err := f.identities.GetByProviderSubject(f.ctx, "upstream", "upstream-user")
+	if err != nil || identity.UserID != user.ID {
+		t.Fatalf("identity should be linked to the user, got %+v %v", identity, err)
+	}
+}
+
+func TestFederationCallbackRequiresBrowserBinding(t *testing.T) {
+	f := newFederationFixture(t)
+
+	for _, binding := range []string{"", "attacker-binding"} {
+		login := f.startLogin(t)
+		idToken := f.idp.sign(t, f.idp.claims(login.nonce))
+		correct := login.binding
+
+		login.binding = binding
+		if _, err := f.complete(login, idToken); !errors.Is(err, service.ErrFederationStateMismatch) {
+			t.Fatalf("binding %q: expected ErrFederationStateMismatch, got %v", binding, err)
+		}
+
+		// 绑定值不匹配的回调同样会消耗state
+		login.binding = correct
+		if _, err := f.complete(login, idToken); !errors.Is(err, service.ErrInvalidFederationState) {
+			t.Fatalf("binding %q: expected the state to be consumed, got %v", binding, err)
+		}
+	}
+
+	if _, err := f.users.GetByEmail("carol@example.com"); err == nil {
+		t.Fatal("no user should be provisioned without a browser binding")
+	}
+}
+
+func TestFederationRejectsInvalidIDToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		idToken func(f *federationFixture, t *testing.T, nonce string) string
+	}{
+		{"wrong issuer", func(f *federationFixture, t *testing.T, nonce string) string {
+			claims := f.idp.claims(nonce)
+			claims["iss"] = "https://evil.example.com"
+			return f.idp.sign(t, claims)
+		}},
+		{"wrong audience", func(f *federationFixture, t *testing.T, nonce string) string {
+			claims := f.idp.claims(nonce)
+			claims["aud"] = "another-client"
+			return f.idp.sign(t, claims)
+		}},
+		{"wrong nonce", func(f *federationFixture, t *testing.T, nonce string) string {
+			return f.idp.sign(t, f.idp.claims("replayed-nonce"))
+		}},
+		{"azp mismatch", func(f *federationFixture, t *testing.T, nonce string) string {
+			claims := f.idp.claims(nonce)
+			claims["aud"] = []string{testUpstreamClientID, "another-client"}
+			claims["azp"] = "another-client"
+			return f.idp.sign(t, claims)
+		}},
+		{"expired", func(f *federationFixture, t *testing.T, nonce string) string {
+			claims := f.idp.claims(nonce)
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			return f.idp.sign(t, claims)
+		}},
+		{"missing exp", func(f *federationFixture, t *testing.T, nonce string) string {
+			claims := f.idp.claims(nonce)
+			delete(claims, "exp")
+			return f.idp.sign(t, claims)
+		}},
+		{"hmac alg", func(f *federationFixture, t *testing.T, nonce string) string {
+			signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, f.idp.claims(nonce)).SignedString([]byte(testUpstreamClientID))
+			if err != nil {
+				t.Fatalf("sign id token: %v", err)
+			}
+			return signed
+		}},
+		{"none alg", func(f *federationFixture, t *testing.T, nonce string) string {
+			signed, err := jwt.NewWithClaims(jwt.SigningMethodNone, f.idp.claims(nonce)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			if err != nil {
+				t.Fatalf("sign id token: %v", err)
+			}
+			return signed
+		}},
+		{"foreign key", func(f *federationFixture, t *testing.T, nonce string) string {
+			other, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("generate rsa key: %v", err)
+			}
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, f.idp.claims(nonce))
+			token.Header["kid"] = "upstream"
+			signed, err := token.SignedString(other)
+			if err != nil {
+				t.Fatalf("sign id token: %v", err)
+			}
+			return signed
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFederationFixture(t)
+			login := f.startLogin(t)
+
+			if _, err := f.complete(login, tt.idToken(f, t, login.nonce)); err == nil {
+				t.Fatal("expected the id_token to be rejected")
+			}
+			if _, err := f.identities.GetByProviderSubject(f.ctx, "upstream", "upstream-user"); err == nil {
+				t.Fatal("no identity should be linked for a rejected id_token")
+			}
+		})
+	}
+}
+
+func TestFederationLinkRequiresInitiatingSession(t *testing.T) {
+	f := newFederationFixture(t)
+	user := f.alice
+	other := &model.User{Username: "mallory", Email: "mallory@example.com", IsActive: true}
+	if err := f.users.Create(other); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// 回调所在浏览器未登录，或已切换为其他用户的会话
+	for _, sessionUserID := range []uint{0, other.ID} {
+		authURL, binding, err := f.federation.StartLink(f.ctx, "upstream", user.ID, "")
+		if err != nil {
+			t.Fatalf("start link: %v", err)
+		}
+		login := parseFederatedLogin(t, authURL, binding)
+		login.sessionUserID = sessionUserID
+
+		if _, err := f.complete(login, f.idp.sign(t, f.idp.claims(login.nonce))); !errors.Is(err, service.ErrLinkSessionMismatch) {
+			t.Fatalf("session user %d: expected ErrLinkSessionMismatch, got %v", sessionUserID, err)
+		}
+	}
+	if _, err := f.identities.GetByProviderSubject(f.ctx, "upstream", "upstream-user"); err == nil {
+		t.Fatal("no identity should be linked outside the initiating session")
+	}
+}
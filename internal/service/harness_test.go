@@ -0,0 +1,139 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/helper"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testIssuer 服务测试使用的issuer
+const testIssuer = "http://id.test"
+
+// testClientSecret 测试机密客户端的密钥
+const testClientSecret = "client-secret"
+
+// realmHarness 单个realm的内存依赖与服务，服务测试共用，用户alice已创建
+// 服务构造参数变化时只需修改newRealmHarness
+type realmHarness struct {
+	ctx       context.Context
+	realm     *model.Realm
+	jwtUtil   util.JWTUtil
+	users     repository.UserRepository
+	clients   repository.ClientRepository
+	scopes    service.ScopeService
+	details   service.AuthorizationDetailsService
+	rbac      service.RBACService
+	passwords service.PasswordPolicyService
+	hasher    util.PasswordHasher
+	blocklist map[string]struct{}
+	oauth     service.OAuthService
+	user      service.UserService
+	ciba      service.CIBAService
+	mfa       service.MFAService
+	audit     service.AuditService
+	notifier  *util.MemoryNotifier
+	authCode  repository.AuthorizationCodeRepository
+	refresh   repository.RefreshTokenRepository
+	consents  repository.ConsentRepository
+	authReqs  repository.BackchannelAuthRequestRepository
+	pars      repository.PushedAuthorizationRequestRepository
+	totp      repository.TOTPCredentialRepository
+	webAuthn  service.WebAuthnService
+	passkeys  repository.WebAuthnCredentialRepository
+	alice     *model.User
+}
+
+// newRealmHarness 按生产环境的方式组装默认realm的服务
+func newRealmHarness(t *testing.T) *realmHarness {
+	t.Helper()
+	t.Setenv("ISSUER_URL", testIssuer)
+	ctx := context.Background()
+
+	h := &realmHarness{
+		ctx:      issuerContext(),
+		realm:    &model.Realm{ID: 1, Name: "default", IsDefault: true},
+		users:    repository.NewUserRepository(nil),
+		authCode: repository.NewAuthorizationCodeRepository(),
+		refresh:  repository.NewRefreshTokenRepository(),
+		consents: repository.NewConsentRepository(),
+		authReqs: repository.NewBackchannelAuthRequestRepository(),
+		pars:     repository.NewPushedAuthorizationRequestRepository(),
+		totp:     repository.NewTOTPCredentialRepository(),
+		passkeys: repository.NewWebAuthnCredentialRepository(),
+		details:  service.NewAuthorizationDetailsService(),
+		audit:    service.NewAuditService(repository.NewAuditEventRepository(nil)),
+		notifier: util.NewMemoryNotifier(),
+	}
+
+	var err error
+	if h.jwtUtil, err = util.NewEphemeralJWTUtil(); err != nil {
+		t.Fatalf("create jwt util: %v", err)
+	}
+	h.alice = &model.User{Username: "alice", Email: "alice@example.com", Nickname: "Alice", IsActive: true}
+	if err := h.users.Create(h.alice); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	h.clients = repository.NewClientRepository(h.realm.ID)
+	h.scopes = service.NewScopeService(repository.NewScopeRepository(h.realm.ID))
+	if err := h.scopes.SeedScopes(ctx, nil); err != nil {
+		t.Fatalf("seed scopes: %v", err)
+	}
+	if h.hasher, err = util.NewPasswordHasher(util.PasswordHashArgon2id, testArgon2Params, 0); err != nil {
+		t.Fatalf("create password hasher: %v", err)
+	}
+	if h.blocklist, err = util.LoadPasswordBlocklist(""); err != nil {
+		t.Fatalf("load password blocklist: %v", err)
+	}
+	h.passwords = service.NewPasswordPolicyService(h.realm, h.blocklist, nil)
+	h.rbac = service.NewRBACService(repository.NewRoleRepository(h.realm.ID), repository.NewGroupRepository(h.realm.ID), repository.NewUserAssignmentRepository())
+	if err := h.rbac.SeedRBAC(ctx, nil, nil); err != nil {
+		t.Fatalf("seed rbac: %v", err)
+	}
+
+	h.mfa = service.NewMFAService(h.totp, repository.NewRecoveryCodeRepository(), repository.NewMFAChallengeRepository(), h.passkeys, h.users, h.audit, h.realm)
+	h.webAuthn = service.NewWebAuthnService(h.passkeys, repository.NewWebAuthnSessionRepository(), h.users, h.realm)
+	h.ciba = service.NewCIBAService(h.authReqs, h.users, h.clients, h.scopes, h.jwtUtil, h.notifier, h.mfa, h.realm)
+	h.oauth = service.NewOAuthService(h.jwtUtil, h.clients, h.authCode, h.refresh, h.consents, h.pars, h.scopes, h.details, h.rbac, h.ciba, h.mfa, h.users, helper.NewUserHelper(h.hasher), h.audit, h.realm)
+	h.user = service.NewUserService(h.users, helper.NewUserHelper(h.hasher), repository.NewVerificationTokenRepository(), util.NewSimpleEmailQueue(), h.jwtUtil, h.realm, h.rbac, h.passwords, h.hasher)
+	return h
+}
+
+// issuerContext 返回携带测试issuer的上下文，与IssuerMiddleware写入的一致
+func issuerContext() context.Context {
+	return util.WithIssuer(context.Background(), testIssuer)
+}
+
+// addClient 登记客户端，机密客户端使用testClientSecret
+func (h *realmHarness) addClient(t *testing.T, client *model.Client) *model.Client {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testClientSecret), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash client secret: %v", err)
+	}
+	client.SecretHash = string(hash)
+	if err := h.clients.Create(h.ctx, client); err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	return client
+}
+
+// exchangeCode 为alice签发授权码并立即兑换
+func (h *realmHarness) exchangeCode(t *testing.T, client *model.Client, scopes []string) *service.TokenResponse {
+	t.Helper()
+	code, err := h.oauth.GenerateAuthorizationCode(h.ctx, client, h.alice.ID, client.RedirectURI, scopes, nil, nil)
+	if err != nil {
+		t.Fatalf("generate authorization code: %v", err)
+	}
+	response, err := h.oauth.ExchangeAuthorizationCode(h.ctx, code, client.ClientID, testClientSecret, client.RedirectURI, nil, nil)
+	if err != nil {
+		t.Fatalf("exchange authorization code: %v", err)
+	}
+	return response
+}
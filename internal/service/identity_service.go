@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// 外部身份关联错误，处理器据此选择HTTP状态码
+var (
+	ErrExternalIdentityNotFound = errors.New("external identity not found")
+	ErrIdentityLinkedToOther    = errors.New("the external identity is already linked to another user")
+	ErrLastLoginMethod          = errors.New("cannot remove the last login method of the account")
+)
+
+// BangumiProviderName Bangumi在外部身份中使用的提供方名称
+const BangumiProviderName = "bangumi"
+
+// IdentityService 外部身份关联服务接口
+type IdentityService interface {
+	// ListIdentities 获取用户关联的全部外部身份
+	ListIdentities(ctx context.Context, userID uint) ([]*model.ExternalIdentity, error)
+
+	// UnlinkIdentity 解除外部身份关联，不能解除用户最后一种登录方式
+	UnlinkIdentity(ctx context.Context, userID, identityID uint) error
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+)
+
+// identityService 外部身份关联服务实现
+type identityService struct {
+	identityRepo   repository.ExternalIdentityRepository
+	userRepo       repository.UserRepository
+	bangumiService BangumiService
+	auditService   AuditService
+}
+
+// NewIdentityService 创建IdentityService实例
+func NewIdentityService(identityRepo repository.ExternalIdentityRepository, userRepo repository.UserRepository, bangumiService BangumiService, auditService AuditService) IdentityService {
+	return &identityService{
+		identityRepo:   identityRepo,
+		userRepo:       userRepo,
+		bangumiService: bangumiService,
+		auditService:   auditService,
+	}
+}
+
+// ListIdentities 获取用户关联的全部外部身份
+func (s *identityService) ListIdentities(ctx context.Context, userID uint) ([]*model.ExternalIdentity, error) {
+	return s.identityRepo.ListByUserID(ctx, userID)
+}
+
+// UnlinkIdentity 解除外部身份关联，不能解除用户最后一种登录方式
+func (s *identityService) UnlinkIdentity(ctx context.Context, userID, identityID uint) error {
+	identity, err := s.identityRepo.GetByID(ctx, identityID)
+	if err != nil || identity.UserID != userID {
+		return ErrExternalIdentityNotFound
+	}
+
+	// Bangumi账号同时解除收藏同步使用的绑定记录
+	if identity.Provider == BangumiProviderName {
+		return s.bangumiService.UnbindAccount(ctx, userID)
+	}
+
+	if err := ensureOtherLoginMethod(ctx, s.userRepo, s.identityRepo, userID, identity.ID); err != nil {
+		return err
+	}
+	if err := s.identityRepo.DeleteByID(ctx, identity.ID); err != nil {
+		return fmt.Errorf("failed to unlink external identity: %w", err)
+	}
+
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:    model.AuditAccountUnlinked,
+		UserID:  userID,
+		Details: map[string]string{"provider": identity.Provider, "subject": identity.Subject},
+	})
+	return nil
+}
+
+// ensureOtherLoginMethod 确认解除指定外部身份后用户仍能登录：设置了本地密码，或还关联了其他外部身份
+func ensureOtherLoginMethod(ctx context.Context, userRepo repository.UserRepository, identityRepo repository.ExternalIdentityRepository, userID, identityID uint) error {
+	user, err := userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	if user.PasswordHash != "" {
+		return nil
+	}
+
+	identities, err := identityRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list external identities: %w", err)
+	}
+	for _, identity := range identities {
+		if identity.ID != identityID {
+			return nil
+		}
+	}
+
+	return ErrLastLoginMethod
+}
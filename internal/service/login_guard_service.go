@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLoginThrottled 登录失败次数过多，账户被临时锁定或需要等待后重试
+var ErrLoginThrottled = errors.New("too many failed login attempts")
+
+// LoginGuardService 登录暴力破解防护服务接口
+// 按账户和来源IP统计失败次数：账户连续失败后逐步延长下次允许尝试的等待时间，
+// 达到上限后临时锁定并邮件通知用户，同一IP失败过多时暂时拒绝该IP的登录
+type LoginGuardService interface {
+	// Check 检查账户和来源IP当前是否允许尝试登录，不允许时返回ErrLoginThrottled和需要等待的时长
+	Check(ctx context.Context, username, ip string) (time.Duration, error)
+
+	// RecordFailure 记录一次失败的登录，无论用户名是否存在都同样计数
+	RecordFailure(ctx context.Context, username, ip string) error
+
+	// RecordSuccess 登录成功后清除账户的失败记录
+	RecordSuccess(ctx context.Context, username string) error
+
+	// Unlock 管理员解除用户的锁定并清除失败记录
+	Unlock(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+	"strings"
+	"time"
+)
+
+// 登录防护的默认策略
+const (
+	defaultLoginFailureWindow      = 15 * time.Minute
+	defaultLoginDelayAfterFailures = 3
+	defaultLoginMaxDelay           = time.Minute
+	defaultLoginMaxFailures        = 10
+	defaultLoginLockout            = 15 * time.Minute
+	defaultLoginMaxLockout         = 24 * time.Hour
+	defaultLoginMaxFailuresPerIP   = 50
+
+	// loginLockoutHistoryTTL 连续锁定次数的保留时间，决定锁定时长是否继续加倍
+	loginLockoutHistoryTTL = 24 * time.Hour
+)
+
+// loginGuardService 登录暴力破解防护服务实现
+type loginGuardService struct {
+	attemptRepo      repository.LoginAttemptRepository
+	userRepo         repository.UserRepository
+	emailQueue       util.EmailQueue
+	auditService     AuditService
+	realm            *model.Realm
+	failureWindow    time.Duration
+	delayAfter       int64
+	maxDelay         time.Duration
+	maxFailures      int64
+	lockout          time.Duration
+	maxLockout       time.Duration
+	maxFailuresPerIP int64
+}
+
+// NewLoginGuardService 创建realm范围内的LoginGuardService实例，策略由LOGIN_*环境变量配置
+func NewLoginGuardService(attemptRepo repository.LoginAttemptRepository, userRepo repository.UserRepository, emailQueue util.EmailQueue, auditService AuditService, realm *model.Realm) LoginGuardService {
+	return &loginGuardService{
+		attemptRepo:      attemptRepo,
+		userRepo:         userRepo,
+		emailQueue:       emailQueue,
+		auditService:     auditService,
+		realm:            realm,
+		failureWindow:    envSeconds("LOGIN_FAILURE_WINDOW_SECONDS", defaultLoginFailureWindow),
+		delayAfter:       int64(envInt("LOGIN_DELAY_AFTER_FAILURES", defaultLoginDelayAfterFailures)),
+		maxDelay:         envSeconds("LOGIN_MAX_DELAY_SECONDS", defaultLoginMaxDelay),
+		maxFailures:      int64(envInt("LOGIN_MAX_FAILURES", defaultLoginMaxFailures)),
+		lockout:          envSeconds("LOGIN_LOCKOUT_SECONDS", defaultLoginLockout),
+		maxLockout:       envSeconds("LOGIN_MAX_LOCKOUT_SECONDS", defaultLoginMaxLockout),
+		maxFailuresPerIP: int64(envInt("LOGIN_MAX_FAILURES_PER_IP", defaultLoginMaxFailuresPerIP)),
+	}
+}
+
+// Check 依次检查账户锁定、逐步延迟和来源IP的失败次数
+func (s *loginGuardService) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	account := normalizeLoginUsername(username)
+
+	for _, key := range []string{s.key("lock", account), s.key("delay", account)} {
+		blocked, ttl, err := s.attemptRepo.Get(ctx, key)
+		if err != nil {
+			// 计数存储不可用时允许登录，避免Redis故障导致无法登录
+			log.Printf("登录防护计数读取失败，暂不限制登录: %v", err)
+			return 0, nil
+		}
+		if blocked > 0 && ttl > 0 {
+			s.recordFailure(ctx, username, "throttled")
+			return ttl, ErrLoginThrottled
+		}
+	}
+
+	failures, ttl, err := s.attemptRepo.Get(ctx, s.key("ip", ip))
+	if err == nil && failures >= s.maxFailuresPerIP && ttl > 0 {
+		s.recordFailure(ctx, username, "ip_throttled")
+		return ttl, ErrLoginThrottled
+	}
+
+	return 0, nil
+}
+
+// RecordFailure 记录失败次数，达到阈值后设置逐步延迟，达到上限后锁定账户，连续锁定时锁定时长加倍
+func (s *loginGuardService) RecordFailure(ctx context.Context, username, ip string) error {
+	account := normalizeLoginUsername(username)
+	s.recordFailure(ctx, username, "invalid_credentials")
+
+	if _, err := s.attemptRepo.Increment(ctx, s.key("ip", ip), s.failureWindow); err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+	failures, err := s.attemptRepo.Increment(ctx, s.key("fail", account), s.failureWindow)
+	if err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	if failures >= s.maxFailures {
+		return s.lock(ctx, account, username)
+	}
+
+	if failures >= s.delayAfter {
+		// 第delayAfter次失败后等待1秒，之后每次失败加倍
+		delay := s.maxDelay
+		if shift := failures - s.delayAfter; shift < 16 {
+			if d := time.Second << uint(shift); d < delay {
+				delay = d
+			}
+		}
+		if err := s.attemptRepo.Set(ctx, s.key("delay", account), 1, delay); err != nil {
+			return fmt.Errorf("failed to delay login: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess 清除账户的失败次数、延迟和连续锁定记录，来源IP的失败次数保留
+func (s *loginGuardService) RecordSuccess(ctx context.Context, username string) error {
+	account := normalizeLoginUsername(username)
+	return s.attemptRepo.Delete(ctx, s.key("fail", account), s.key("delay", account), s.key("lockouts", account))
+}
+
+// Unlock 解除用户的锁定
+func (s *loginGuardService) Unlock(ctx context.Context, userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	account := normalizeLoginUsername(user.Username)
+	return s.attemptRepo.Delete(ctx, s.key("lock", account), s.key("fail", account), s.key("delay", account), s.key("lockouts", account))
+}
+
+// lock 锁定账户并通知用户，用户名不存在时同样锁定，避免通过锁定行为判断用户名是否注册
+func (s *loginGuardService) lock(ctx context.Context, account, username string) error {
+	lockouts, err := s.attemptRepo.Increment(ctx, s.key("lockouts", account), loginLockoutHistoryTTL)
+	if err != nil {
+		return fmt.Errorf("failed to lock account: %w", err)
+	}
+	duration := s.maxLockout
+	if lockouts < 16 {
+		if d := s.lockout << uint(lockouts-1); d > 0 && d < duration {
+			duration = d
+		}
+	}
+
+	if err := s.attemptRepo.Set(ctx, s.key("lock", account), 1, duration); err != nil {
+		return fmt.Errorf("failed to lock account: %w", err)
+	}
+	s.recordFailure(ctx, username, "locked")
+	if err := s.attemptRepo.Delete(ctx, s.key("fail", account), s.key("delay", account)); err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+
+	user := s.findUser(username)
+	if user == nil {
+		return nil
+	}
+	if err := s.emailQueue.Enqueue(util.EmailQueueItem{
+		Email:       user.Email,
+		BasePath:    s.realm.PathPrefix(),
+		Type:        util.EmailTypeAccountLocked,
+		LockedUntil: time.Now().Add(duration),
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue account locked email: %w", err)
+	}
+
+	return nil
+}
+
+// findUser 按尝试登录的用户名查找用户，用户名不存在时返回nil
+func (s *loginGuardService) findUser(username string) *model.User {
+	user, err := s.userRepo.GetByUsername(strings.TrimSpace(username))
+	if err != nil {
+		if user, err = s.userRepo.GetByUsername(normalizeLoginUsername(username)); err != nil {
+			return nil
+		}
+	}
+	return user
+}
+
+// recordFailure 记录登录失败审计事件，发起者为尝试的用户名，用户名存在时关联到该用户
+func (s *loginGuardService) recordFailure(ctx context.Context, username, reason string) {
+	if s.auditService == nil {
+		return
+	}
+	event := &model.AuditEvent{
+		Type:    model.AuditLoginFailed,
+		Outcome: model.AuditOutcomeFailure,
+		Actor:   strings.TrimSpace(username),
+		Details: map[string]string{"reason": reason},
+	}
+	if user := s.findUser(username); user != nil {
+		event.UserID = user.ID
+	}
+	s.auditService.Record(ctx, event)
+}
+
+// key 生成realm范围内的计数键，多个realm共用同一个Redis
+func (s *loginGuardService) key(kind, subject string) string {
+	return fmt.Sprintf("login_guard:%s:%s:%s", s.realm.Name, kind, subject)
+}
+
+// normalizeLoginUsername 规范化用户名，避免通过改变大小写绕过账户的失败计数
+func normalizeLoginUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
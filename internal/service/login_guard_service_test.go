@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// loginGuardFixture 在共用依赖之上使用内存计数的登录防护服务
+type loginGuardFixture struct {
+	*realmHarness
+	guard      service.LoginGuardService
+	emailQueue util.EmailQueue
+}
+
+// newLoginGuardFixture 创建登录防护服务：第2次失败起逐步延迟，第4次失败锁定
+func newLoginGuardFixture(t *testing.T) *loginGuardFixture {
+	t.Helper()
+	t.Setenv("LOGIN_DELAY_AFTER_FAILURES", "2")
+	t.Setenv("LOGIN_MAX_FAILURES", "4")
+	t.Setenv("LOGIN_LOCKOUT_SECONDS", "600")
+	t.Setenv("LOGIN_MAX_FAILURES_PER_IP", "10")
+
+	f := &loginGuardFixture{realmHarness: newRealmHarness(t), emailQueue: util.NewSimpleEmailQueue()}
+	f.guard = service.NewLoginGuardService(repository.NewLoginAttemptRepository(nil), f.users, f.emailQueue, f.audit, f.realm)
+	return f
+}
+
+func TestLoginGuardProgressiveDelayAndLockout(t *testing.T) {
+	f := newLoginGuardFixture(t)
+
+	if _, err := f.guard.Check(f.ctx, "alice", "203.0.113.1"); err != nil {
+		t.Fatalf("first attempt should be allowed: %v", err)
+	}
+
+	// 第1次失败不延迟，第2、3次失败分别延迟1秒和2秒
+	expectedDelays := []time.Duration{0, time.Second, 2 * time.Second}
+	for i, expected := range expectedDelays {
+		if err := f.guard.RecordFailure(f.ctx, "alice", "203.0.113.1"); err != nil {
+			t.Fatalf("record failure %d: %v", i+1, err)
+		}
+		retryAfter, err := f.guard.Check(f.ctx, "alice", "203.0.113.1")
+		if expected == 0 {
+			if err != nil {
+				t.Fatalf("failure %d: expected no delay, got %v", i+1, err)
+			}
+			continue
+		}
+		if !errors.Is(err, service.ErrLoginThrottled) || retryAfter <= expected-100*time.Millisecond || retryAfter > expected {
+			t.Fatalf("failure %d: expected delay of %v, got %v (%v)", i+1, expected, retryAfter, err)
+		}
+	}
+
+	// 第4次失败锁定账户，大小写不同的用户名共用计数，并通知用户
+	if err := f.guard.RecordFailure(f.ctx, "ALICE", "203.0.113.1"); err != nil {
+		t.Fatalf("record failure 4: %v", err)
+	}
+	retryAfter, err := f.guard.Check(f.ctx, "alice", "198.51.100.7")
+	if !errors.Is(err, service.ErrLoginThrottled) || retryAfter <= 9*time.Minute {
+		t.Fatalf("expected a 10 minute lockout, got %v (%v)", retryAfter, err)
+	}
+	item, err := f.emailQueue.Dequeue()
+	if err != nil || item.Type != util.EmailTypeAccountLocked || item.Email != "alice@example.com" {
+		t.Fatalf("expected a lockout notification, got %+v (%v)", item, err)
+	}
+
+	// 管理员解除锁定
+	if err := f.guard.Unlock(f.ctx, f.alice.ID); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if _, err := f.guard.Check(f.ctx, "alice", "203.0.113.1"); err != nil {
+		t.Fatalf("expected unlocked account, got %v", err)
+	}
+	if err := f.guard.Unlock(f.ctx, 999); !errors.Is(err, service.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestLoginGuardUnknownUsernameIsTreatedAlike(t *testing.T) {
+	f := newLoginGuardFixture(t)
+
+	for i := 0; i < 4; i++ {
+		if err := f.guard.RecordFailure(f.ctx, "nobody", "203.0.113.2"); err != nil {
+			t.Fatalf("record failure: %v", err)
+		}
+	}
+	if _, err := f.guard.Check(f.ctx, "nobody", "203.0.113.3"); !errors.Is(err, service.ErrLoginThrottled) {
+		t.Fatalf("unknown usernames should be locked like real ones, got %v", err)
+	}
+	if item, err := f.emailQueue.Dequeue(); err == nil {
+		t.Fatalf("no email should be sent for an unknown username, got %+v", item)
+	}
+}
+
+func TestLoginGuardSuccessResetsAccountButNotIP(t *testing.T) {
+	f := newLoginGuardFixture(t)
+
+	if err := f.guard.RecordFailure(f.ctx, "alice", "203.0.113.4"); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	if err := f.guard.RecordSuccess(f.ctx, "alice"); err != nil {
+		t.Fatalf("record success: %v", err)
+	}
+	// 成功登录后失败计数重新开始，第1次失败不延迟
+	if err := f.guard.RecordFailure(f.ctx, "alice", "203.0.113.4"); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	if _, err := f.guard.Check(f.ctx, "alice", "203.0.113.4"); err != nil {
+		t.Fatalf("expected no delay after a successful login, got %v", err)
+	}
+
+	// 同一IP对不同账户的失败累计到上限后拒绝该IP
+	for i := 0; i < 8; i++ {
+		if err := f.guard.RecordFailure(f.ctx, "user"+string(rune('a'+i)), "203.0.113.4"); err != nil {
+			t.Fatalf("record failure: %v", err)
+		}
+	}
+	if _, err := f.guard.Check(f.ctx, "someone", "203.0.113.4"); !errors.Is(err, service.ErrLoginThrottled) {
+		t.Fatalf("expected the IP to be throttled, got %v", err)
+	}
+	if _, err := f.guard.Check(f.ctx, "someone", "203.0.113.5"); err != nil {
+		t.Fatalf("other IPs should not be throttled, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// 两步验证错误，处理器据此选择HTTP状态码
+var (
+	ErrTOTPAlreadyEnabled  = errors.New("totp is already enabled")
+	ErrTOTPNotEnabled      = errors.New("totp is not enabled")
+	ErrTOTPNotEnrolled     = errors.New("totp enrollment has not been started")
+	ErrInvalidMFACode      = errors.New("invalid verification code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired mfa challenge")
+)
+
+// TOTPEnrollment 待确认的TOTP认证器，前端将ProvisioningURI展示为二维码
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFAStatus 用户的两步验证状态
+type MFAStatus struct {
+	TOTPEnabled            bool `json:"totp_enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+	WebAuthnCredentials    int  `json:"webauthn_credentials"` // 可用于第二步验证的通行密钥数量
+}
+
+// MFAChallengeResult 第二步验证通过后的结果
+type MFAChallengeResult struct {
+	UserID                 uint
+	AMR                    []string // 包含第二步在内的全部认证方式
+	RecoveryCodeUsed       bool
+	RecoveryCodesRemaining int
+}
+
+// MFAService 两步验证服务接口
+type MFAService interface {
+	// GetStatus 获取用户的两步验证状态
+	GetStatus(ctx context.Context, userID uint) (*MFAStatus, error)
+
+	// BeginTOTPEnrollment 生成新的TOTP共享密钥，确认前不会启用；已启用时需先停用
+	BeginTOTPEnrollment(ctx context.Context, userID uint) (*TOTPEnrollment, error)
+
+	// ConfirmTOTPEnrollment 用认证器生成的验证码确认并启用TOTP，返回新生成的恢复码
+	ConfirmTOTPEnrollment(ctx context.Context, userID uint, code string) ([]string, error)
+
+	// DisableTOTP 验证TOTP验证码或恢复码后停用两步验证，同时删除恢复码
+	DisableTOTP(ctx context.Context, userID uint, code string) error
+
+	// RegenerateRecoveryCodes 验证TOTP验证码或恢复码后重新生成恢复码，旧恢复码全部失效
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error)
+
+	// ResetMFA 管理员重置用户的两步验证，删除TOTP认证器、恢复码和通行密钥，用于它们都已丢失的情况
+	ResetMFA(ctx context.Context, userID uint) error
+
+	// RequiresSecondFactor 判断用户启用了TOTP或注册了通行密钥，且amr中尚未包含第二步
+	RequiresSecondFactor(ctx context.Context, userID uint, amr []string) bool
+
+	// Methods 获取用户可用的第二步验证方式：totp、recovery_code、webauthn
+	Methods(ctx context.Context, userID uint) []string
+
+	// BeginChallenge 用户完成第一步认证后创建第二步验证挑战，无需第二步时返回nil
+	BeginChallenge(ctx context.Context, userID uint, amr []string) (*model.MFAChallenge, error)
+
+	// CompleteChallenge 校验TOTP验证码或恢复码，通过后挑战失效
+	CompleteChallenge(ctx context.Context, token, code string) (*MFAChallengeResult, error)
+
+	// GetChallenge 获取未过期的第二步验证挑战，用于以通行密钥完成第二步
+	GetChallenge(ctx context.Context, token string) (*model.MFAChallenge, error)
+
+	// FinishChallenge 调用方已用其他方式（如通行密钥）完成第二步后结束挑战，method为追加的amr值
+	FinishChallenge(ctx context.Context, challenge *model.MFAChallenge, method string) (*MFAChallengeResult, error)
+}
@@ -0,0 +1,417 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+const (
+	// defaultMFAChallengeExpiry 第二步验证挑战的默认有效期
+	defaultMFAChallengeExpiry = 5 * time.Minute
+
+	// maxMFAChallengeAttempts 单个挑战允许输错验证码的次数，超过后须重新完成第一步
+	maxMFAChallengeAttempts = 5
+
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+)
+
+// recoveryCodeEncoding 恢复码使用小写Base32字符，避免易混淆的0/1/8
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// mfaService 两步验证服务实现
+type mfaService struct {
+	credentialRepo repository.TOTPCredentialRepository
+	recoveryRepo   repository.RecoveryCodeRepository
+	challengeRepo  repository.MFAChallengeRepository
+	webAuthnRepo   repository.WebAuthnCredentialRepository
+	userRepo       repository.UserRepository
+	auditService   AuditService
+	realm          *model.Realm
+	challengeTTL   time.Duration
+}
+
+// NewMFAService 创建realm范围内的MFAService实例，挑战有效期由MFA_CHALLENGE_EXPIRY_SECONDS配置
+func NewMFAService(credentialRepo repository.TOTPCredentialRepository, recoveryRepo repository.RecoveryCodeRepository, challengeRepo repository.MFAChallengeRepository, webAuthnRepo repository.WebAuthnCredentialRepository, userRepo repository.UserRepository, auditService AuditService, realm *model.Realm) MFAService {
+	return &mfaService{
+		credentialRepo: credentialRepo,
+		recoveryRepo:   recoveryRepo,
+		challengeRepo:  challengeRepo,
+		webAuthnRepo:   webAuthnRepo,
+		userRepo:       userRepo,
+		auditService:   auditService,
+		realm:          realm,
+		challengeTTL:   envSeconds("MFA_CHALLENGE_EXPIRY_SECONDS", defaultMFAChallengeExpiry),
+	}
+}
+
+// GetStatus 获取用户的两步验证状态
+func (s *mfaService) GetStatus(ctx context.Context, userID uint) (*MFAStatus, error) {
+	status := &MFAStatus{}
+	if credential, err := s.credentialRepo.GetByUserID(ctx, userID); err == nil && credential.IsConfirmed() {
+		status.TOTPEnabled = true
+	}
+
+	remaining, err := s.remainingRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status.RecoveryCodesRemaining = remaining
+
+	credentials, err := s.webAuthnRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webauthn credentials: %w", err)
+	}
+	status.WebAuthnCredentials = len(credentials)
+
+	return status, nil
+}
+
+// BeginTOTPEnrollment 生成新的TOTP共享密钥，重复调用会替换尚未确认的密钥
+func (s *mfaService) BeginTOTPEnrollment(ctx context.Context, userID uint) (*TOTPEnrollment, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.credentialRepo.GetByUserID(ctx, userID)
+	if err == nil {
+		if credential.IsConfirmed() {
+			return nil, ErrTOTPAlreadyEnabled
+		}
+		credential.Secret = secret
+		credential.LastUsedStep = 0
+		if err := s.credentialRepo.Update(ctx, credential); err != nil {
+			return nil, fmt.Errorf("failed to save totp credential: %w", err)
+		}
+	} else {
+		credential = &model.TOTPCredential{
+			UserID: userID,
+			Secret: secret,
+		}
+		if err := s.credentialRepo.Create(ctx, credential); err != nil {
+			return nil, fmt.Errorf("failed to save totp credential: %w", err)
+		}
+	}
+
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: util.TOTPProvisioningURI(s.issuerName(), user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment 用认证器生成的验证码确认并启用TOTP
+func (s *mfaService) ConfirmTOTPEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	credential, err := s.credentialRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if credential.IsConfirmed() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	step, ok := util.ValidateTOTP(credential.Secret, strings.TrimSpace(code), time.Now(), credential.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	now := time.Now()
+	credential.ConfirmedAt = &now
+	credential.LastUsedStep = step
+	if err := s.credentialRepo.Update(ctx, credential); err != nil {
+		return nil, fmt.Errorf("failed to save totp credential: %w", err)
+	}
+
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// DisableTOTP 验证TOTP验证码或恢复码后停用TOTP，已注册的通行密钥不受影响
+func (s *mfaService) DisableTOTP(ctx context.Context, userID uint, code string) error {
+	if _, err := s.verifyCode(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.removeTOTP(ctx, userID)
+}
+
+// RegenerateRecoveryCodes 验证TOTP验证码或恢复码后重新生成恢复码
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	if _, err := s.verifyCode(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// ResetMFA 删除用户的TOTP认证器、恢复码和通行密钥
+func (s *mfaService) ResetMFA(ctx context.Context, userID uint) error {
+	credentials, err := s.webAuthnRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load webauthn credentials: %w", err)
+	}
+	if err := s.webAuthnRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete webauthn credentials: %w", err)
+	}
+
+	err = s.removeTOTP(ctx, userID)
+	if errors.Is(err, ErrTOTPNotEnabled) && len(credentials) > 0 {
+		return nil
+	}
+	return err
+}
+
+// removeTOTP 删除用户的TOTP认证器和恢复码
+func (s *mfaService) removeTOTP(ctx context.Context, userID uint) error {
+	credential, err := s.credentialRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return ErrTOTPNotEnabled
+	}
+
+	if err := s.credentialRepo.DeleteByID(ctx, credential.ID); err != nil {
+		return fmt.Errorf("failed to delete totp credential: %w", err)
+	}
+	if err := s.recoveryRepo.DeleteByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// RequiresSecondFactor 判断用户启用了TOTP或注册了通行密钥，且amr中尚未包含第二步
+func (s *mfaService) RequiresSecondFactor(ctx context.Context, userID uint, amr []string) bool {
+	for _, method := range amr {
+		if method == model.AMROTP || method == model.AMRHWK {
+			return false
+		}
+	}
+
+	return len(s.Methods(ctx, userID)) > 0
+}
+
+// Methods 获取用户可用的第二步验证方式
+func (s *mfaService) Methods(ctx context.Context, userID uint) []string {
+	methods := make([]string, 0, 3)
+	if credential, err := s.credentialRepo.GetByUserID(ctx, userID); err == nil && credential.IsConfirmed() {
+		methods = append(methods, "totp", "recovery_code")
+	}
+	if credentials, err := s.webAuthnRepo.ListByUserID(ctx, userID); err == nil && len(credentials) > 0 {
+		methods = append(methods, "webauthn")
+	}
+	return methods
+}
+
+// BeginChallenge 用户完成第一步认证后创建第二步验证挑战
+func (s *mfaService) BeginChallenge(ctx context.Context, userID uint, amr []string) (*model.MFAChallenge, error) {
+	if !s.RequiresSecondFactor(ctx, userID, amr) {
+		return nil, nil
+	}
+
+	challenge := &model.MFAChallenge{
+		Token:     randomURLToken(32),
+		UserID:    userID,
+		AMR:       strings.Join(amr, " "),
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	}
+	if err := s.challengeRepo.Create(ctx, challenge); err != nil {
+		return nil, fmt.Errorf("failed to save mfa challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// CompleteChallenge 校验TOTP验证码或恢复码，输错次数过多时挑战失效
+func (s *mfaService) CompleteChallenge(ctx context.Context, token, code string) (*MFAChallengeResult, error) {
+	challenge, err := s.GetChallenge(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodeUsed, err := s.verifyCode(ctx, challenge.UserID, code)
+	if err != nil {
+		if errors.Is(err, ErrTOTPNotEnabled) && s.RequiresSecondFactor(ctx, challenge.UserID, nil) {
+			// 只注册了通行密钥的用户输入验证码，按验证码错误计数
+			err = ErrInvalidMFACode
+		}
+		if !errors.Is(err, ErrInvalidMFACode) {
+			// 两步验证已被停用或重置，挑战随之失效
+			_ = s.challengeRepo.DeleteByID(ctx, challenge.ID)
+			return nil, ErrInvalidMFAChallenge
+		}
+		recordAudit(ctx, s.auditService, &model.AuditEvent{
+			Type:    model.AuditLoginFailed,
+			Outcome: model.AuditOutcomeFailure,
+			UserID:  challenge.UserID,
+			Details: map[string]string{"reason": "invalid_mfa_code"},
+		})
+		challenge.Attempts++
+		if challenge.Attempts >= maxMFAChallengeAttempts {
+			_ = s.challengeRepo.DeleteByID(ctx, challenge.ID)
+			return nil, ErrInvalidMFAChallenge
+		}
+		if err := s.challengeRepo.Update(ctx, challenge); err != nil {
+			return nil, fmt.Errorf("failed to save mfa challenge: %w", err)
+		}
+		return nil, err
+	}
+
+	result, err := s.FinishChallenge(ctx, challenge, model.AMROTP)
+	if err != nil {
+		return nil, err
+	}
+	result.RecoveryCodeUsed = recoveryCodeUsed
+
+	return result, nil
+}
+
+// GetChallenge 获取未过期的第二步验证挑战
+func (s *mfaService) GetChallenge(ctx context.Context, token string) (*model.MFAChallenge, error) {
+	challenge, err := s.challengeRepo.GetByToken(ctx, token)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if challenge.IsExpired() {
+		_ = s.challengeRepo.DeleteByID(ctx, challenge.ID)
+		return nil, ErrInvalidMFAChallenge
+	}
+	return challenge, nil
+}
+
+// FinishChallenge 结束第二步验证挑战，挑战只能使用一次
+func (s *mfaService) FinishChallenge(ctx context.Context, challenge *model.MFAChallenge, method string) (*MFAChallengeResult, error) {
+	if _, err := s.challengeRepo.GetByToken(ctx, challenge.Token); err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	_ = s.challengeRepo.DeleteByID(ctx, challenge.ID)
+
+	remaining, err := s.remainingRecoveryCodes(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAChallengeResult{
+		UserID:                 challenge.UserID,
+		AMR:                    append(strings.Fields(challenge.AMR), method),
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// verifyCode 校验TOTP验证码或恢复码，6位数字按TOTP校验，其余按恢复码校验；返回是否使用了恢复码
+func (s *mfaService) verifyCode(ctx context.Context, userID uint, code string) (bool, error) {
+	credential, err := s.credentialRepo.GetByUserID(ctx, userID)
+	if err != nil || !credential.IsConfirmed() {
+		return false, ErrTOTPNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == util.TOTPDigits && isDigits(code) {
+		step, ok := util.ValidateTOTP(credential.Secret, code, time.Now(), credential.LastUsedStep)
+		if !ok {
+			return false, ErrInvalidMFACode
+		}
+		credential.LastUsedStep = step
+		if err := s.credentialRepo.Update(ctx, credential); err != nil {
+			return false, fmt.Errorf("failed to save totp credential: %w", err)
+		}
+		return false, nil
+	}
+
+	codes, err := s.recoveryRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load recovery codes: %w", err)
+	}
+	hash := hashRecoveryCode(code)
+	for _, recoveryCode := range codes {
+		if recoveryCode.UsedAt != nil || recoveryCode.CodeHash != hash {
+			continue
+		}
+		now := time.Now()
+		recoveryCode.UsedAt = &now
+		if err := s.recoveryRepo.Update(ctx, recoveryCode); err != nil {
+			return false, fmt.Errorf("failed to save recovery code: %w", err)
+		}
+		return true, nil
+	}
+
+	return false, ErrInvalidMFACode
+}
+
+// replaceRecoveryCodes 生成新的恢复码并替换旧恢复码，明文只在此时返回一次
+func (s *mfaService) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	if err := s.recoveryRepo.DeleteByUserID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		bytes := make([]byte, 5)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := recoveryCodeEncoding.EncodeToString(bytes)
+		code := raw[:4] + "-" + raw[4:]
+
+		if err := s.recoveryRepo.Create(ctx, &model.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)}); err != nil {
+			return nil, fmt.Errorf("failed to save recovery code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// remainingRecoveryCodes 统计用户未使用的恢复码
+func (s *mfaService) remainingRecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	codes, err := s.recoveryRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load recovery codes: %w", err)
+	}
+
+	remaining := 0
+	for _, code := range codes {
+		if code.UsedAt == nil {
+			remaining++
+		}
+	}
+	return remaining, nil
+}
+
+// issuerName 认证器应用中显示的服务名称
+func (s *mfaService) issuerName() string {
+	if s.realm != nil && s.realm.DisplayName != "" {
+		return s.realm.DisplayName
+	}
+	if s.realm != nil && s.realm.Name != "" {
+		return s.realm.Name
+	}
+	return "OIDC"
+}
+
+// hashRecoveryCode 哈希恢复码，忽略大小写、空格和分隔符
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+// isDigits 判断字符串是否只包含数字
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
@@ -0,0 +1,162 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// mfaFixture 已为alice启用TOTP的realmHarness
+type mfaFixture struct {
+	*realmHarness
+	secret string
+}
+
+// newMFAFixture 创建realmHarness并为alice启用TOTP，返回启用时生成的恢复码
+func newMFAFixture(t *testing.T) (*mfaFixture, []string) {
+	t.Helper()
+	f := &mfaFixture{realmHarness: newRealmHarness(t)}
+
+	enrollment, err := f.mfa.BeginTOTPEnrollment(f.ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("begin enrollment: %v", err)
+	}
+	f.secret = enrollment.Secret
+
+	// 错误的验证码不能启用TOTP
+	if _, err := f.mfa.ConfirmTOTPEnrollment(f.ctx, f.alice.ID, "000000x"); !errors.Is(err, service.ErrInvalidMFACode) {
+		t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+	}
+	recoveryCodes, err := f.mfa.ConfirmTOTPEnrollment(f.ctx, f.alice.ID, f.code(t, 0))
+	if err != nil {
+		t.Fatalf("confirm enrollment: %v", err)
+	}
+	return f, recoveryCodes
+}
+
+// code 生成相对当前时间步偏移offset的验证码
+func (f *mfaFixture) code(t *testing.T, offset int64) string {
+	t.Helper()
+	code, err := util.TOTPCode(f.secret, util.TOTPStep(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	return code
+}
+
+// challenge 模拟完成密码登录后创建第二步验证挑战
+func (f *mfaFixture) challenge(t *testing.T) string {
+	t.Helper()
+	challenge, err := f.mfa.BeginChallenge(f.ctx, f.alice.ID, []string{model.AMRPassword})
+	if err != nil || challenge == nil {
+		t.Fatalf("begin challenge: %+v %v", challenge, err)
+	}
+	return challenge.Token
+}
+
+func TestMFATOTPCodeCannotBeReused(t *testing.T) {
+	f, _ := newMFAFixture(t)
+
+	// 启用时使用过的验证码不能再用于登录
+	enrolled, err := f.totp.GetByUserID(f.ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("get credential: %v", err)
+	}
+	used, err := util.TOTPCode(f.secret, enrolled.LastUsedStep)
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	token := f.challenge(t)
+	if _, err := f.mfa.CompleteChallenge(f.ctx, token, used); !errors.Is(err, service.ErrInvalidMFACode) {
+		t.Fatalf("expected the enrollment code to be rejected, got %v", err)
+	}
+
+	// 下一时间步的验证码在偏差范围内
+	next, err := util.TOTPCode(f.secret, enrolled.LastUsedStep+1)
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+	result, err := f.mfa.CompleteChallenge(f.ctx, token, next)
+	if err != nil {
+		t.Fatalf("complete challenge: %v", err)
+	}
+	if result.UserID != f.alice.ID || result.RecoveryCodeUsed || len(result.AMR) != 2 || result.AMR[1] != model.AMROTP {
+		t.Fatalf("unexpected challenge result: %+v", result)
+	}
+
+	// 挑战只能使用一次，同一验证码也不能在新挑战中再次使用
+	if _, err := f.mfa.CompleteChallenge(f.ctx, token, next); !errors.Is(err, service.ErrInvalidMFAChallenge) {
+		t.Fatalf("expected the challenge to be consumed, got %v", err)
+	}
+	if _, err := f.mfa.CompleteChallenge(f.ctx, f.challenge(t), next); !errors.Is(err, service.ErrInvalidMFACode) {
+		t.Fatalf("expected a reused code to be rejected, got %v", err)
+	}
+}
+
+func TestMFATOTPSkewWindow(t *testing.T) {
+	f, _ := newMFAFixture(t)
+
+	// 超出前后一个时间步的验证码被拒绝
+	if _, err := f.mfa.CompleteChallenge(f.ctx, f.challenge(t), f.code(t, 3)); !errors.Is(err, service.ErrInvalidMFACode) {
+		t.Fatalf("expected a code outside the skew window to be rejected, got %v", err)
+	}
+	if _, err := f.mfa.CompleteChallenge(f.ctx, f.challenge(t), f.code(t, 1)); err != nil {
+		t.Fatalf("expected a code one step ahead to be accepted, got %v", err)
+	}
+}
+
+func TestMFARecoveryCodesAreSingleUse(t *testing.T) {
+	f, recoveryCodes := newMFAFixture(t)
+	if len(recoveryCodes) != 10 {
+		t.Fatalf("expected 10 recovery codes, got %d", len(recoveryCodes))
+	}
+
+	result, err := f.mfa.CompleteChallenge(f.ctx, f.challenge(t), recoveryCodes[0])
+	if err != nil {
+		t.Fatalf("complete challenge with recovery code: %v", err)
+	}
+	if !result.RecoveryCodeUsed || result.RecoveryCodesRemaining != 9 {
+		t.Fatalf("unexpected challenge result: %+v", result)
+	}
+
+	if _, err := f.mfa.CompleteChallenge(f.ctx, f.challenge(t), recoveryCodes[0]); !errors.Is(err, service.ErrInvalidMFACode) {
+		t.Fatalf("expected a used recovery code to be rejected, got %v", err)
+	}
+	status, err := f.mfa.GetStatus(f.ctx, f.alice.ID)
+	if err != nil || !status.TOTPEnabled || status.RecoveryCodesRemaining != 9 {
+		t.Fatalf("unexpected mfa status: %+v %v", status, err)
+	}
+
+	// 重新生成后旧恢复码全部失效
+	regenerated, err := f.mfa.RegenerateRecoveryCodes(f.ctx, f.alice.ID, recoveryCodes[1])
+	if err != nil {
+		t.Fatalf("regenerate recovery codes: %v", err)
+	}
+	if _, err := f.mfa.CompleteChallenge(f.ctx, f.challenge(t), recoveryCodes[2]); !errors.Is(err, service.ErrInvalidMFACode) {
+		t.Fatalf("expected an old recovery code to be rejected, got %v", err)
+	}
+	if _, err := f.mfa.CompleteChallenge(f.ctx, f.challenge(t), regenerated[0]); err != nil {
+		t.Fatalf("expected a new recovery code to be accepted, got %v", err)
+	}
+}
+
+func TestMFAChallengeAttemptLimit(t *testing.T) {
+	f, _ := newMFAFixture(t)
+	token := f.challenge(t)
+
+	for i := 1; i < 5; i++ {
+		if _, err := f.mfa.CompleteChallenge(f.ctx, token, "wrong-code"); !errors.Is(err, service.ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: expected ErrInvalidMFACode, got %v", i, err)
+		}
+	}
+	if _, err := f.mfa.CompleteChallenge(f.ctx, token, "wrong-code"); !errors.Is(err, service.ErrInvalidMFAChallenge) {
+		t.Fatalf("expected the challenge to be invalidated, got %v", err)
+	}
+	if _, err := f.mfa.CompleteChallenge(f.ctx, token, f.code(t, 1)); !errors.Is(err, service.ErrInvalidMFAChallenge) {
+		t.Fatalf("expected the invalidated challenge to stay unusable, got %v", err)
+	}
+}
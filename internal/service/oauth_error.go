@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// OAuth 2.0 / OIDC 错误码 (RFC 6749 §4.1.2.1、§5.2，RFC 6750 §3.1，OIDC Core §3.1.2.6，OpenID CIBA Core §11、§13，RFC 9396 §5，RFC 9101 §6.2)
+const (
+	ErrCodeInvalidRequest              = "invalid_request"
+	ErrCodeInvalidClient               = "invalid_client"
+	ErrCodeInvalidGrant                = "invalid_grant"
+	ErrCodeUnauthorizedClient          = "unauthorized_client"
+	ErrCodeUnsupportedGrantType        = "unsupported_grant_type"
+	ErrCodeUnsupportedResponseType     = "unsupported_response_type"
+	ErrCodeInvalidScope                = "invalid_scope"
+	ErrCodeAccessDenied                = "access_denied"
+	ErrCodeServerError                 = "server_error"
+	ErrCodeTemporarilyUnavailable      = "temporarily_unavailable"
+	ErrCodeInvalidToken                = "invalid_token"
+	ErrCodeInsufficientScope           = "insufficient_scope"
+	ErrCodeInteractionRequired         = "interaction_required"
+	ErrCodeLoginRequired               = "login_required"
+	ErrCodeAccountSelectionRequired    = "account_selection_required"
+	ErrCodeConsentRequired             = "consent_required"
+	ErrCodeAuthorizationPending        = "authorization_pending"
+	ErrCodeSlowDown                    = "slow_down"
+	ErrCodeExpiredToken                = "expired_token"
+	ErrCodeUnknownUserID               = "unknown_user_id"
+	ErrCodeInvalidBindingMessage       = "invalid_binding_message"
+	ErrCodeInvalidAuthorizationDetails = "invalid_authorization_details"
+	ErrCodeInvalidRequestURI           = "invalid_request_uri"
+)
+
+// OAuthError 符合规范的OAuth错误
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	URI         string `json:"error_uri,omitempty"`
+	StatusCode  int    `json:"-"`
+	// Scope 访问资源所需的scope，仅用于Bearer质询 (RFC 6750 §3)
+	Scope string `json:"-"`
+}
+
+// Error 实现error接口
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// WWWAuthenticate 生成WWW-Authenticate响应头的值
+// scheme为Basic（客户端认证失败）或Bearer（RFC 6750资源访问失败）
+func (e *OAuthError) WWWAuthenticate(scheme, realm string) string {
+	params := []string{fmt.Sprintf("realm=%q", realm)}
+	// Bearer请求未携带令牌时不应返回错误码 (RFC 6750 §3.1)
+	if scheme == "Bearer" && e.Code != "" {
+		params = append(params, fmt.Sprintf("error=%q", e.Code))
+		if e.Description != "" {
+			params = append(params, fmt.Sprintf("error_description=%q", e.Description))
+		}
+		if e.URI != "" {
+			params = append(params, fmt.Sprintf("error_uri=%q", e.URI))
+		}
+		if e.Scope != "" {
+			params = append(params, fmt.Sprintf("scope=%q", e.Scope))
+		}
+	}
+	return scheme + " " + strings.Join(params, ", ")
+}
+
+// NewOAuthError 创建OAuth错误，error_uri由OAUTH_ERROR_URI_BASE配置派生
+func NewOAuthError(code, description string, statusCode int) *OAuthError {
+	oauthErr := &OAuthError{
+		Code:        code,
+		Description: description,
+		StatusCode:  statusCode,
+	}
+	if base := os.Getenv("OAUTH_ERROR_URI_BASE"); base != "" {
+		oauthErr.URI = strings.TrimRight(base, "#") + "#" + code
+	}
+	return oauthErr
+}
+
+// ErrInvalidRequest 请求缺少参数或参数格式错误
+func ErrInvalidRequest(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInvalidRequest, description, http.StatusBadRequest)
+}
+
+// ErrInvalidClient 客户端认证失败
+func ErrInvalidClient(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInvalidClient, description, http.StatusUnauthorized)
+}
+
+// ErrInvalidGrant 授权码或刷新令牌无效、过期、已撤销或不匹配
+func ErrInvalidGrant(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInvalidGrant, description, http.StatusBadRequest)
+}
+
+// ErrUnauthorizedClient 客户端无权使用该授权类型
+func ErrUnauthorizedClient(description string) *OAuthError {
+	return NewOAuthError(ErrCodeUnauthorizedClient, description, http.StatusBadRequest)
+}
+
+// ErrUnsupportedGrantType 不支持的授权类型
+func ErrUnsupportedGrantType(description string) *OAuthError {
+	return NewOAuthError(ErrCodeUnsupportedGrantType, description, http.StatusBadRequest)
+}
+
+// ErrUnsupportedResponseType 不支持的响应类型
+func ErrUnsupportedResponseType(description string) *OAuthError {
+	return NewOAuthError(ErrCodeUnsupportedResponseType, description, http.StatusBadRequest)
+}
+
+// ErrInvalidScope 请求的scope无效或超出客户端允许范围
+func ErrInvalidScope(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInvalidScope, description, http.StatusBadRequest)
+}
+
+// ErrAccessDenied 资源所有者或授权服务器拒绝请求
+func ErrAccessDenied(description string) *OAuthError {
+	return NewOAuthError(ErrCodeAccessDenied, description, http.StatusForbidden)
+}
+
+// ErrServerError 服务器内部错误
+func ErrServerError(description string) *OAuthError {
+	return NewOAuthError(ErrCodeServerError, description, http.StatusInternalServerError)
+}
+
+// ErrInvalidToken 访问令牌缺失、过期、已撤销或格式错误
+func ErrInvalidToken(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInvalidToken, description, http.StatusUnauthorized)
+}
+
+// ErrInsufficientScope 访问令牌权限不足
+func ErrInsufficientScope(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInsufficientScope, description, http.StatusForbidden)
+}
+
+// ErrLoginRequired 需要用户登录或重新认证
+func ErrLoginRequired(description string) *OAuthError {
+	return NewOAuthError(ErrCodeLoginRequired, description, http.StatusBadRequest)
+}
+
+// ErrAccountSelectionRequired 需要用户选择账户
+func ErrAccountSelectionRequired(description string) *OAuthError {
+	return NewOAuthError(ErrCodeAccountSelectionRequired, description, http.StatusBadRequest)
+}
+
+// ErrConsentRequired 需要用户同意授权
+func ErrConsentRequired(description string) *OAuthError {
+	return NewOAuthError(ErrCodeConsentRequired, description, http.StatusBadRequest)
+}
+
+// ErrInteractionRequired 需要用户交互才能完成授权
+func ErrInteractionRequired(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInteractionRequired, description, http.StatusBadRequest)
+}
+
+// ErrAuthorizationPending CIBA认证请求尚未被用户批准
+func ErrAuthorizationPending(description string) *OAuthError {
+	return NewOAuthError(ErrCodeAuthorizationPending, description, http.StatusBadRequest)
+}
+
+// ErrSlowDown 客户端轮询过于频繁
+func ErrSlowDown(description string) *OAuthError {
+	return NewOAuthError(ErrCodeSlowDown, description, http.StatusBadRequest)
+}
+
+// ErrExpiredToken CIBA认证请求已过期
+func ErrExpiredToken(description string) *OAuthError {
+	return NewOAuthError(ErrCodeExpiredToken, description, http.StatusBadRequest)
+}
+
+// ErrUnknownUserID 无法根据提示识别用户
+func ErrUnknownUserID(description string) *OAuthError {
+	return NewOAuthError(ErrCodeUnknownUserID, description, http.StatusBadRequest)
+}
+
+// ErrInvalidBindingMessage 绑定消息无效或无法展示
+func ErrInvalidBindingMessage(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInvalidBindingMessage, description, http.StatusBadRequest)
+}
+
+// ErrInvalidAuthorizationDetails authorization_details格式错误、类型未注册或超出允许范围
+func ErrInvalidAuthorizationDetails(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInvalidAuthorizationDetails, description, http.StatusBadRequest)
+}
+
+// ErrInvalidRequestURI request_uri无效、已过期或已被使用
+func ErrInvalidRequestURI(description string) *OAuthError {
+	return NewOAuthError(ErrCodeInvalidRequestURI, description, http.StatusBadRequest)
+}
+
+// AsOAuthError 将任意错误转换为OAuthError，非OAuth错误视为server_error
+func AsOAuthError(err error) *OAuthError {
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr
+	}
+	return ErrServerError("internal server error")
+}
@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+func TestOAuthErrorWWWAuthenticate(t *testing.T) {
+	t.Setenv("OAUTH_ERROR_URI_BASE", "https://docs.example.com/errors#")
+
+	tests := []struct {
+		name       string
+		err        *service.OAuthError
+		scheme     string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "invalid_client uses Basic without error params",
+			err:        service.ErrInvalidClient("client authentication failed"),
+			scheme:     "Basic",
+			wantStatus: http.StatusUnauthorized,
+			want:       `Basic realm="anime"`,
+		},
+		{
+			name:       "invalid_token",
+			err:        service.ErrInvalidToken("the access token expired"),
+			scheme:     "Bearer",
+			wantStatus: http.StatusUnauthorized,
+			want:       `Bearer realm="anime", error="invalid_token", error_description="the access token expired", error_uri="https://docs.example.com/errors#invalid_token"`,
+		},
+		{
+			name:       "insufficient_scope",
+			err:        service.ErrInsufficientScope("requires scope admin"),
+			scheme:     "Bearer",
+			wantStatus: http.StatusForbidden,
+			want:       `Bearer realm="anime", error="insufficient_scope", error_description="requires scope admin", error_uri="https://docs.example.com/errors#insufficient_scope"`,
+		},
+		{
+			name:       "missing credentials has no error code",
+			err:        &service.OAuthError{StatusCode: http.StatusUnauthorized},
+			scheme:     "Bearer",
+			wantStatus: http.StatusUnauthorized,
+			want:       `Bearer realm="anime"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", tt.err.StatusCode, tt.wantStatus)
+			}
+			if got := tt.err.WWWAuthenticate(tt.scheme, "anime"); got != tt.want {
+				t.Errorf("WWWAuthenticate = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsOAuthErrorHidesInternalErrors(t *testing.T) {
+	wrapped := fmt.Errorf("exchange failed: %w", service.ErrInvalidGrant("authorization code expired"))
+	if got := service.AsOAuthError(wrapped); got.Code != service.ErrCodeInvalidGrant {
+		t.Errorf("wrapped error code = %q, want invalid_grant", got.Code)
+	}
+
+	got := service.AsOAuthError(errors.New("pq: connection refused"))
+	if got.Code != service.ErrCodeServerError || got.StatusCode != http.StatusInternalServerError {
+		t.Errorf("internal error = %+v, want server_error 500", got)
+	}
+	if got.Description == "pq: connection refused" {
+		t.Error("internal error details must not leak into error_description")
+	}
+}
@@ -3,19 +3,47 @@ package service
 import (
 	"context"
 	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/util"
 )
 
 // OAuthService OAuth服务接口
 type OAuthService interface {
-	// HandleAuthorizationRequest 处理授权请求
-	HandleAuthorizationRequest(ctx context.Context, clientID, userID, redirectURI string, scopes []string, codeChallenge, codeChallengeMethod *string) (*model.AuthorizationCode, error)
+	// HandleAuthorizationRequest 根据登录会话处理授权请求，session为nil表示用户未登录
+	HandleAuthorizationRequest(ctx context.Context, req *AuthorizationRequest, session *model.Session) (*model.AuthorizationCode, error)
 	
-	// HandleTokenRequest 处理令牌请求
-	HandleTokenRequest(ctx context.Context, grantType, code, clientID, clientSecret, redirectURI string, codeVerifier *string) (*TokenResponse, error)
+	// HasConsent 判断用户是否已同意向客户端授予请求的scopes和authorization_details
+	HasConsent(ctx context.Context, userID uint, clientID string, scopes []string, details []model.AuthorizationDetail) (bool, error)
+	
+	// GrantConsent 记录用户对客户端的授权同意
+	GrantConsent(ctx context.Context, userID uint, clientID string, scopes []string, details []model.AuthorizationDetail) error
+	
+	// DenyConsent 记录用户拒绝客户端的授权请求，已有的授权同意保持不变
+	DenyConsent(ctx context.Context, userID uint, clientID string, scopes []string)
+	
+	// ValidateAuthorizationRequest 验证授权请求的客户端与重定向URI
+	ValidateAuthorizationRequest(ctx context.Context, clientID, redirectURI string) (*model.Client, error)
+	
+	// PushAuthorizationRequest 校验并保存客户端推送的授权请求，返回request_uri (RFC 9126)
+	PushAuthorizationRequest(ctx context.Context, client *model.Client, req *AuthorizationRequest, parameters string) (*PushedAuthorizationResponse, error)
+	
+	// ResolveRequestURI 取出request_uri对应的授权请求参数，request_uri只能使用一次
+	ResolveRequestURI(ctx context.Context, clientID, requestURI string) (string, error)
+	
+	// HandleTokenRequest 处理令牌请求，authorizationDetails用于收窄授权码已批准的authorization_details
+	HandleTokenRequest(ctx context.Context, grantType, code, clientID, clientSecret, redirectURI string, codeVerifier *string, authorizationDetails []model.AuthorizationDetail) (*TokenResponse, error)
+	
+	// IntrospectToken 令牌内省 (RFC 7662)
+	IntrospectToken(ctx context.Context, token, clientID, clientSecret string) (*IntrospectionResponse, error)
 	
 	// GetOpenIDConfiguration 获取OpenID配置信息
 	GetOpenIDConfiguration(ctx context.Context) (*OpenIDConfiguration, error)
 	
+	// GetAuthorizationServerMetadata 获取OAuth 2.0授权服务器元数据 (RFC 8414)
+	GetAuthorizationServerMetadata(ctx context.Context) (*AuthorizationServerMetadata, error)
+	
+	// GetJWKS 获取JSON Web Key Set
+	GetJWKS(ctx context.Context) (*util.JWKS, error)
+	
 	// GetUserInfo 获取用户信息
 	GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error)
 	
@@ -26,7 +54,7 @@ type OAuthService interface {
 	GenerateAuthorizationCode(ctx context.Context, client *model.Client, userID uint, redirectURI string, scopes []string, codeChallenge, codeChallengeMethod *string) (string, error)
 	
 	// ExchangeAuthorizationCode 兑换授权码获取访问令牌
-	ExchangeAuthorizationCode(ctx context.Context, code, clientID, clientSecret, redirectURI string, codeVerifier *string) (*TokenResponse, error)
+	ExchangeAuthorizationCode(ctx context.Context, code, clientID, clientSecret, redirectURI string, codeVerifier *string, authorizationDetails []model.AuthorizationDetail) (*TokenResponse, error)
 	
 	// ValidateAuthorizationCode 验证授权码
 	ValidateAuthorizationCode(ctx context.Context, code, clientID, redirectURI string) (*model.AuthorizationCode, error)
@@ -34,6 +62,9 @@ type OAuthService interface {
 	// CreateRefreshToken 创建刷新令牌
 	CreateRefreshToken(ctx context.Context, userID uint, clientID string, scopes []string) (*model.RefreshToken, error)
 	
+	// ExchangeBackchannelAuthRequest 兑换CIBA认证请求获取令牌
+	ExchangeBackchannelAuthRequest(ctx context.Context, authReqID, clientID, clientSecret string) (*TokenResponse, error)
+	
 	// RefreshAccessToken 刷新访问令牌
 	RefreshAccessToken(ctx context.Context, refreshToken, clientID, clientSecret string) (*TokenResponse, error)
 	
@@ -6,10 +6,19 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"github.com/Full-finger/OIDC/internal/helper"
 	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
 	"github.com/Full-finger/OIDC/internal/util"
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // TokenResponse 令牌响应
@@ -20,76 +29,256 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token,omitempty"`
 	Scope        string `json:"scope,omitempty"`
 	IDToken      string `json:"id_token,omitempty"`
+
+	// AuthorizationDetails 令牌实际授予的authorization_details (RFC 9396 §7)
+	AuthorizationDetails []model.AuthorizationDetail `json:"authorization_details,omitempty"`
+}
+
+// PushedAuthorizationResponse PAR端点响应 (RFC 9126 §2.2)
+type PushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// IntrospectionResponse 令牌内省响应 (RFC 7662 §2.2)，令牌无效时仅返回active=false
+type IntrospectionResponse struct {
+	Active               bool                        `json:"active"`
+	Scope                string                      `json:"scope,omitempty"`
+	ClientID             string                      `json:"client_id,omitempty"`
+	TokenType            string                      `json:"token_type,omitempty"`
+	Exp                  int64                       `json:"exp,omitempty"`
+	Iat                  int64                       `json:"iat,omitempty"`
+	Sub                  string                      `json:"sub,omitempty"`
+	Aud                  []string                    `json:"aud,omitempty"`
+	Iss                  string                      `json:"iss,omitempty"`
+	Roles                []string                    `json:"roles,omitempty"`
+	Groups               []string                    `json:"groups,omitempty"`
+	AuthorizationDetails []model.AuthorizationDetail `json:"authorization_details,omitempty"`
+}
+
+// AuthorizationRequest 授权端点请求参数
+type AuthorizationRequest struct {
+	ClientID             string
+	RedirectURI          string
+	ResponseType         string
+	Scopes               []string
+	State                string
+	Nonce                string
+	CodeChallenge        *string
+	CodeChallengeMethod  *string
+	Prompt               []string
+	MaxAge               *int
+	LoginHint            string
+	IDTokenHint          string
+	AuthorizationDetails []model.AuthorizationDetail
+}
+
+// HasPrompt 判断请求的prompt参数是否包含指定值
+func (r *AuthorizationRequest) HasPrompt(value string) bool {
+	for _, prompt := range r.Prompt {
+		if prompt == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationServerMetadata OAuth 2.0授权服务器元数据 (RFC 8414)
+type AuthorizationServerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	ResponseModesSupported            []string `json:"response_modes_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ServiceDocumentation              string   `json:"service_documentation,omitempty"`
+	UILocalesSupported                []string `json:"ui_locales_supported,omitempty"`
+	OpPolicyURI                       string   `json:"op_policy_uri,omitempty"`
+	OpTosURI                          string   `json:"op_tos_uri,omitempty"`
+
+	// PAR、令牌内省与细粒度授权元数据 (RFC 9126 §5，RFC 7662，RFC 9396 §10)
+	PushedAuthorizationRequestEndpoint        string   `json:"pushed_authorization_request_endpoint"`
+	RequirePushedAuthorizationRequests        bool     `json:"require_pushed_authorization_requests"`
+	IntrospectionEndpoint                     string   `json:"introspection_endpoint"`
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported"`
+	AuthorizationDetailsTypesSupported        []string `json:"authorization_details_types_supported"`
 }
 
 // OpenIDConfiguration OpenID配置信息
 type OpenIDConfiguration struct {
-	Issuer                            string   `json:"issuer"`
-	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
-	TokenEndpoint                    string   `json:"token_endpoint"`
+	AuthorizationServerMetadata
 	UserinfoEndpoint                 string   `json:"userinfo_endpoint"`
-	JwksURI                          string   `json:"jwks_uri"`
-	ScopesSupported              []string `json:"scopes_supported"`
-	ResponseTypesSupported       []string `json:"response_types_supported"`
-	GrantTypesSupported          []string `json:"grant_types_supported"`
-	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
-	SubjectTypesSupported        []string `json:"subject_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
 	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
-	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
-	ClaimsSupported              []string `json:"claims_supported"`
+	ClaimsSupported                  []string `json:"claims_supported"`
+
+	// CIBA元数据 (OpenID CIBA Core §4)
+	BackchannelAuthenticationEndpoint      string   `json:"backchannel_authentication_endpoint"`
+	BackchannelTokenDeliveryModesSupported []string `json:"backchannel_token_delivery_modes_supported"`
+	BackchannelUserCodeParameterSupported  bool     `json:"backchannel_user_code_parameter_supported"`
 }
 
 // UserInfo 用户信息
 type UserInfo struct {
-	Sub           string `json:"sub"`
-	Name          string `json:"name,omitempty"`
-	Nickname      string `json:"nickname,omitempty"`
-	Profile       string `json:"profile,omitempty"`
-	Picture       string `json:"picture,omitempty"`
-	Email         string `json:"email,omitempty"`
-	EmailVerified bool   `json:"email_verified,omitempty"`
+	Sub           string   `json:"sub"`
+	Name          string   `json:"name,omitempty"`
+	Nickname      string   `json:"nickname,omitempty"`
+	Profile       string   `json:"profile,omitempty"`
+	Picture       string   `json:"picture,omitempty"`
+	Email         string   `json:"email,omitempty"`
+	EmailVerified *bool    `json:"email_verified,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
 }
 
 // oauthService OAuth服务实现
 type oauthService struct {
-	jwtUtil util.JWTUtil
+	jwtUtil        util.JWTUtil
+	clientRepo     repository.ClientRepository
+	authCodeRepo   repository.AuthorizationCodeRepository
+	refreshRepo    repository.RefreshTokenRepository
+	consentRepo    repository.ConsentRepository
+	parRepo        repository.PushedAuthorizationRequestRepository
+	scopeService   ScopeService
+	detailsService AuthorizationDetailsService
+	rbacService    RBACService
+	cibaService    CIBAService
+	mfaService     MFAService
+	userRepo       repository.UserRepository
+	userHelper     helper.UserHelper
+	auditService   AuditService
+	realm          *model.Realm
 }
 
-// NewOAuthService 创建OAuth服务实例
-func NewOAuthService() OAuthService {
-	// 初始化JWT工具
-	jwtUtil, err := util.NewJWTUtil()
-	if err != nil {
-		// 如果JWT工具初始化失败，记录日志但继续运行
-		fmt.Printf("Warning: failed to initialize JWT utility: %v\n", err)
-	}
-	
+// NewOAuthService 创建OAuth服务实例，所有依赖均归属于同一realm，令牌有效期策略取自realm及客户端配置
+func NewOAuthService(jwtUtil util.JWTUtil, clientRepo repository.ClientRepository, authCodeRepo repository.AuthorizationCodeRepository, refreshRepo repository.RefreshTokenRepository, consentRepo repository.ConsentRepository, parRepo repository.PushedAuthorizationRequestRepository, scopeService ScopeService, detailsService AuthorizationDetailsService, rbacService RBACService, cibaService CIBAService, mfaService MFAService, userRepo repository.UserRepository, userHelper helper.UserHelper, auditService AuditService, realm *model.Realm) OAuthService {
 	return &oauthService{
-		jwtUtil: jwtUtil,
+		jwtUtil:        jwtUtil,
+		clientRepo:     clientRepo,
+		authCodeRepo:   authCodeRepo,
+		refreshRepo:    refreshRepo,
+		consentRepo:    consentRepo,
+		parRepo:        parRepo,
+		scopeService:   scopeService,
+		detailsService: detailsService,
+		rbacService:    rbacService,
+		cibaService:    cibaService,
+		mfaService:     mfaService,
+		userRepo:       userRepo,
+		userHelper:     userHelper,
+		auditService:   auditService,
+		realm:          realm,
 	}
 }
 
+// GetAuthorizationServerMetadata 获取授权服务器元数据
+func (s *oauthService) GetAuthorizationServerMetadata(ctx context.Context) (*AuthorizationServerMetadata, error) {
+	// 所有端点均由同一个issuer派生，保证与令牌中的iss一致
+	issuer := util.IssuerFromContext(ctx)
+
+	metadata := &AuthorizationServerMetadata{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		JwksURI:                           issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   s.supportedScopes(ctx),
+		ResponseTypesSupported:            []string{"code"},
+		ResponseModesSupported:            []string{"query"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", CIBAGrantType},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256", "plain"},
+		ServiceDocumentation:              os.Getenv("SERVICE_DOCUMENTATION_URL"),
+		OpPolicyURI:                       os.Getenv("OP_POLICY_URI"),
+		OpTosURI:                          os.Getenv("OP_TOS_URI"),
+
+		PushedAuthorizationRequestEndpoint:        issuer + "/oauth/par",
+		RequirePushedAuthorizationRequests:        false,
+		IntrospectionEndpoint:                     issuer + "/oauth/introspect",
+		IntrospectionEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+		AuthorizationDetailsTypesSupported:        s.supportedAuthorizationDetailsTypes(ctx),
+	}
+
+	if locales := os.Getenv("UI_LOCALES_SUPPORTED"); locales != "" {
+		metadata.UILocalesSupported = strings.Fields(strings.ReplaceAll(locales, ",", " "))
+	}
+
+	return metadata, nil
+}
+
 // GetOpenIDConfiguration 获取OpenID配置信息
 func (s *oauthService) GetOpenIDConfiguration(ctx context.Context) (*OpenIDConfiguration, error) {
+	metadata, err := s.GetAuthorizationServerMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	config := &OpenIDConfiguration{
-		Issuer:                           "http://localhost:8080",
-		AuthorizationEndpoint:           "http://localhost:8080/oauth/authorize",
-		TokenEndpoint:                   "http://localhost:8080/oauth/token",
-		UserinfoEndpoint:                "http://localhost:8080/oauth/userinfo",
-		JwksURI:                         "http://localhost:8080/.well-known/jwks.json",
-		ScopesSupported:                 []string{"openid", "profile", "email"},
-		ResponseTypesSupported:          []string{"code"},
-		GrantTypesSupported:             []string{"authorization_code", "refresh_token"},
-		CodeChallengeMethodsSupported:   []string{"S256", "plain"},
-		SubjectTypesSupported:           []string{"public"},
+		AuthorizationServerMetadata:      *metadata,
+		UserinfoEndpoint:                 metadata.Issuer + "/oauth/userinfo",
+		SubjectTypesSupported:            []string{"public"},
 		IDTokenSigningAlgValuesSupported: []string{"RS256"},
-		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
-		ClaimsSupported:                 []string{"sub", "name", "nickname", "profile", "picture", "email", "email_verified"},
+		ClaimsSupported:                  s.supportedClaims(ctx),
+
+		BackchannelAuthenticationEndpoint:      metadata.Issuer + "/oauth/bc-authorize",
+		BackchannelTokenDeliveryModesSupported: []string{model.BackchannelDeliveryPoll, model.BackchannelDeliveryPing},
+		BackchannelUserCodeParameterSupported:  false,
 	}
 	
 	return config, nil
 }
 
+// supportedScopes 获取scope注册表中的全部scope名称
+func (s *oauthService) supportedScopes(ctx context.Context) []string {
+	scopes, err := s.scopeService.ListScopes(ctx)
+	if err != nil {
+		return []string{}
+	}
+
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, scope.Name)
+	}
+	return names
+}
+
+// supportedAuthorizationDetailsTypes 获取已注册的authorization_details类型名称
+func (s *oauthService) supportedAuthorizationDetailsTypes(ctx context.Context) []string {
+	names := []string{}
+	for _, detailType := range s.detailsService.ListTypes(ctx) {
+		names = append(names, detailType.Type)
+	}
+	return names
+}
+
+// supportedClaims 获取标准声明以及scope注册表映射的全部声明
+func (s *oauthService) supportedClaims(ctx context.Context) []string {
+	claims := []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "amr"}
+
+	// scope映射的声明按名称排序后追加在标准声明之后
+	var mapped []string
+	for claim := range s.scopeService.ClaimsForScopes(ctx, s.supportedScopes(ctx)) {
+		if !s.containsScope(claims, claim) {
+			mapped = append(mapped, claim)
+		}
+	}
+	sort.Strings(mapped)
+
+	return append(claims, mapped...)
+}
+
+// GetJWKS 获取用于验证令牌签名的公钥集合
+func (s *oauthService) GetJWKS(ctx context.Context) (*util.JWKS, error) {
+	if s.jwtUtil == nil {
+		return nil, fmt.Errorf("JWT utility not available")
+	}
+
+	return &util.JWKS{Keys: []util.JWK{s.jwtUtil.PublicJWK()}}, nil
+}
+
 // GetUserInfo 获取用户信息
 func (s *oauthService) GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
 	// 解析访问令牌
@@ -99,7 +288,10 @@ func (s *oauthService) GetUserInfo(ctx context.Context, accessToken string) (*Us
 	if s.jwtUtil != nil {
 		claims, err = s.jwtUtil.ParseAccessToken(accessToken)
 		if err != nil {
-			return nil, fmt.Errorf("invalid access token: %w", err)
+			return nil, ErrInvalidToken("the access token is invalid or expired")
+		}
+		if claims.Issuer != util.IssuerFromContext(ctx) {
+			return nil, ErrInvalidToken("the access token was issued by a different issuer")
 		}
 	} else {
 		// JWT工具不可用时的简化实现
@@ -111,90 +303,411 @@ func (s *oauthService) GetUserInfo(ctx context.Context, accessToken string) (*Us
 		}
 	}
 	
-	// 根据scope决定返回哪些用户信息
-	// 这里简化处理，实际应该从数据库获取真实用户信息
+	// 根据scope注册表的声明映射决定返回哪些用户信息
 	userInfo := &UserInfo{
 		Sub: claims.Subject,
 	}
+	allowed := s.scopeService.ClaimsForScopes(ctx, s.stringToScopes(claims.Scope))
+	var userID uint
+	fmt.Sscanf(claims.Subject, "user:%d", &userID)
 	
-	// 检查scope中是否包含profile
-	if containsScope(claims.Scope, "profile") || containsScope(claims.Scope, "openid") {
-		userInfo.Name = "示例用户"
-		userInfo.Nickname = "示例昵称"
-		userInfo.Profile = "https://example.com/profile"
-		userInfo.Picture = "https://example.com/avatar.jpg"
+	if allowed["name"] || allowed["nickname"] || allowed["picture"] || allowed["profile"] {
+		profile := s.userProfileClaims(ctx, userID)
+		if allowed["name"] {
+			userInfo.Name = profile.Name
+		}
+		if allowed["nickname"] {
+			userInfo.Nickname = profile.Nickname
+		}
+		if allowed["picture"] {
+			userInfo.Picture = profile.Picture
+		}
+		if allowed["profile"] {
+			userInfo.Profile = profile.Profile
+		}
 	}
-	
-	// 检查scope中是否包含email
-	if containsScope(claims.Scope, "email") {
-		userInfo.Email = "user@example.com"
-		userInfo.EmailVerified = true
+	if allowed["email"] || allowed["email_verified"] {
+		email, emailVerified := s.userEmailClaims(userID)
+		if allowed["email"] {
+			userInfo.Email = email
+		}
+		if allowed["email_verified"] {
+			userInfo.EmailVerified = emailVerified
+		}
+	}
+	if (allowed["roles"] || allowed["groups"]) && userID != 0 {
+		roles, groups := s.userAccessClaims(ctx, userID)
+		if allowed["roles"] {
+			userInfo.Roles = roles
+		}
+		if allowed["groups"] {
+			userInfo.Groups = groups
+		}
 	}
 	
 	return userInfo, nil
 }
 
 // HandleAuthorizationRequest 处理授权请求
-func (s *oauthService) HandleAuthorizationRequest(ctx context.Context, clientID, userID, redirectURI string, scopes []string, codeChallenge, codeChallengeMethod *string) (*model.AuthorizationCode, error) {
-	// 查找客户端
-	client, err := s.GetClientByClientID(ctx, clientID)
+// 根据当前登录会话判断是否需要登录、选择账户或用户同意，满足条件时签发授权码。
+// 返回的login_required/account_selection_required/interaction_required/consent_required错误由调用方决定
+// 是引导用户交互，还是在prompt=none时直接返回给客户端
+func (s *oauthService) HandleAuthorizationRequest(ctx context.Context, req *AuthorizationRequest, session *model.Session) (*model.AuthorizationCode, error) {
+	// 查找客户端并验证重定向URI
+	client, err := s.ValidateAuthorizationRequest(ctx, req.ClientID, req.RedirectURI)
 	if err != nil {
-		return nil, fmt.Errorf("invalid client: %w", err)
+		return nil, err
 	}
 
-	// 验证重定向URI
-	if !s.isValidRedirectURI(redirectURI, client.RedirectURI) {
-		return nil, fmt.Errorf("invalid redirect URI")
+	// 验证prompt、scopes与authorization_details
+	if err := s.validateRequestedAccess(ctx, client, req); err != nil {
+		return nil, err
+	}
+
+	// 检查登录状态
+	if session == nil {
+		return nil, ErrLoginRequired("the end-user is not authenticated")
+	}
+	if req.HasPrompt("login") {
+		return nil, ErrLoginRequired("re-authentication requested by prompt=login")
+	}
+	if req.HasPrompt("select_account") {
+		return nil, ErrAccountSelectionRequired("account selection requested by prompt=select_account")
+	}
+	if req.MaxAge != nil && time.Since(session.AuthTime) > time.Duration(*req.MaxAge)*time.Second {
+		return nil, ErrLoginRequired("authentication is older than max_age")
+	}
+	if req.IDTokenHint != "" {
+		hintUserID, err := s.parseIDTokenHint(ctx, req.IDTokenHint)
+		if err != nil {
+			return nil, err
+		}
+		if hintUserID != session.UserID {
+			return nil, ErrLoginRequired("the authenticated end-user does not match id_token_hint")
+		}
+	}
+	// 用户启用了两步验证但会话未完成第二步（如启用前建立的会话），由调用方引导用户输入验证码
+	if s.mfaService != nil && s.mfaService.RequiresSecondFactor(ctx, session.UserID, session.AuthMethods()) {
+		return nil, ErrInteractionRequired("second factor authentication is required")
+	}
+
+	// 检查用户同意
+	if req.HasPrompt("consent") {
+		return nil, ErrConsentRequired("consent requested by prompt=consent")
+	}
+	granted, err := s.HasConsent(ctx, session.UserID, client.ClientID, req.Scopes, req.AuthorizationDetails)
+	if err != nil {
+		return nil, ErrServerError("failed to load consent")
+	}
+	if !granted {
+		return nil, ErrConsentRequired("the end-user has not granted the requested scopes or authorization_details")
+	}
+
+	// 签发授权码
+	authCode, err := s.createAuthorizationCode(ctx, client, session.UserID, req, session.AuthTime, session.AMR)
+	if err != nil {
+		return nil, ErrServerError("failed to issue authorization code")
+	}
+
+	return authCode, nil
+}
+
+// HasConsent 判断用户是否已同意向客户端授予全部请求的scopes和authorization_details，无需同意的scope不参与判断
+// authorization_details总是需要用户同意，已同意的内容必须包含本次请求的全部权限
+func (s *oauthService) HasConsent(ctx context.Context, userID uint, clientID string, scopes []string, details []model.AuthorizationDetail) (bool, error) {
+	scopes = s.scopeService.ConsentRequiredScopes(ctx, scopes)
+	if len(scopes) == 0 && len(details) == 0 {
+		return true, nil
+	}
+
+	consent, err := s.consentRepo.GetByUserAndClient(ctx, userID, clientID)
+	if err != nil {
+		return false, nil
+	}
+
+	granted := s.stringToScopes(consent.Scopes)
+	for _, scope := range scopes {
+		if !s.containsScope(granted, scope) {
+			return false, nil
+		}
+	}
+
+	if len(details) > 0 {
+		grantedDetails, err := model.ParseAuthorizationDetails(consent.AuthorizationDetails)
+		if err != nil || !s.detailsService.CoversAuthorizationDetails(grantedDetails, details) {
+			return false, nil
+		}
 	}
 
-	// 验证请求的scopes是否被客户端允许
+	return true, nil
+}
+
+// GrantConsent 记录用户对客户端的授权同意，与已同意的scopes和authorization_details合并
+func (s *oauthService) GrantConsent(ctx context.Context, userID uint, clientID string, scopes []string, details []model.AuthorizationDetail) error {
+	client, err := s.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		return ErrInvalidClient("unknown client")
+	}
 	if !s.areScopesAllowed(scopes, client.Scopes) {
-		return nil, fmt.Errorf("invalid scopes")
+		return ErrInvalidScope("the requested scope exceeds the scope granted to the client")
+	}
+	if err := s.detailsService.ValidateAuthorizationDetails(ctx, client, details); err != nil {
+		return err
+	}
+
+	granted := []string{}
+	grantedDetails := []model.AuthorizationDetail{}
+	if consent, err := s.consentRepo.GetByUserAndClient(ctx, userID, clientID); err == nil {
+		granted = s.stringToScopes(consent.Scopes)
+		if previous, err := model.ParseAuthorizationDetails(consent.AuthorizationDetails); err == nil {
+			grantedDetails = append(grantedDetails, previous...)
+		}
+	}
+	for _, scope := range scopes {
+		if !s.containsScope(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	for _, detail := range details {
+		if !s.detailsService.CoversAuthorizationDetails(grantedDetails, []model.AuthorizationDetail{detail}) {
+			grantedDetails = append(grantedDetails, detail)
+		}
 	}
 
-	// 生成随机授权码
-	code := s.generateRandomCode(64)
+	if err := s.consentRepo.Save(ctx, &model.Consent{
+		UserID:               userID,
+		ClientID:             clientID,
+		Scopes:               s.scopesToString(granted),
+		AuthorizationDetails: model.MarshalAuthorizationDetails(grantedDetails),
+	}); err != nil {
+		return err
+	}
 
-	// 解析用户ID
-	var parsedUserID uint
-	// 这里应该有实际的用户ID解析逻辑
-	// 为简化示例，我们假设传入的userID是有效的
-	fmt.Sscanf(userID, "%d", &parsedUserID)
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:     model.AuditConsentGranted,
+		UserID:   userID,
+		ClientID: clientID,
+		Details:  map[string]string{"scope": s.scopesToString(scopes)},
+	})
+	return nil
+}
 
-	// 创建授权码实体
-	authCode := &model.AuthorizationCode{
-		Code:                code,
-		ClientID:            clientID,
-		UserID:              parsedUserID,
-		RedirectURI:         redirectURI,
-		Scopes:              s.scopesToString(scopes),
-		ExpiresAt:           time.Now().Add(10 * time.Minute), // 10分钟有效期
-		CodeChallenge:       s.getStringValue(codeChallenge),
-		CodeChallengeMethod: s.getStringValue(codeChallengeMethod),
+// DenyConsent 记录用户拒绝客户端的授权请求
+func (s *oauthService) DenyConsent(ctx context.Context, userID uint, clientID string, scopes []string) {
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:     model.AuditConsentDenied,
+		Outcome:  model.AuditOutcomeFailure,
+		UserID:   userID,
+		ClientID: clientID,
+		Details:  map[string]string{"scope": s.scopesToString(scopes)},
+	})
+}
+
+// PushAuthorizationRequest 校验并保存客户端推送的授权请求 (RFC 9126 §2)
+// 客户端已在PAR端点完成认证，授权端点只需凭request_uri取回参数
+func (s *oauthService) PushAuthorizationRequest(ctx context.Context, client *model.Client, req *AuthorizationRequest, parameters string) (*PushedAuthorizationResponse, error) {
+	if err := s.validateRequestedAccess(ctx, client, req); err != nil {
+		return nil, err
 	}
 
-	// TODO: 保存到数据库
-	// 这里应该调用repository来保存授权码
-	// err = s.authorizationCodeRepo.Create(ctx, authCode)
-	// if err != nil {
-	//     return nil, fmt.Errorf("failed to save authorization code: %w", err)
-	// }
+	expiresIn := parRequestURIExpiry()
+	parReq := &model.PushedAuthorizationRequest{
+		RequestURI: "urn:ietf:params:oauth:request_uri:" + s.generateRandomCode(32),
+		ClientID:   client.ClientID,
+		Parameters: parameters,
+		ExpiresAt:  time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}
+	if err := s.parRepo.Create(ctx, parReq); err != nil {
+		return nil, ErrServerError("failed to save pushed authorization request")
+	}
 
-	return authCode, nil
+	return &PushedAuthorizationResponse{
+		RequestURI: parReq.RequestURI,
+		ExpiresIn:  expiresIn,
+	}, nil
+}
+
+// ResolveRequestURI 取出request_uri对应的授权请求参数，取出后立即失效
+func (s *oauthService) ResolveRequestURI(ctx context.Context, clientID, requestURI string) (string, error) {
+	// 查找与消费一次完成，并发使用同一request_uri时只有一方成功
+	parReq, err := s.parRepo.ConsumeByRequestURI(ctx, requestURI)
+	if err != nil {
+		return "", ErrInvalidRequestURI("request_uri is invalid or has already been used")
+	}
+
+	if parReq.IsExpired() {
+		return "", ErrInvalidRequestURI("request_uri has expired")
+	}
+	// request_uri只能由推送该请求的客户端使用 (RFC 9126 §4)
+	if parReq.ClientID != clientID {
+		return "", ErrInvalidRequestURI("request_uri was issued to another client")
+	}
+
+	return parReq.Parameters, nil
 }
 
 // HandleTokenRequest 处理令牌请求
-func (s *oauthService) HandleTokenRequest(ctx context.Context, grantType, code, clientID, clientSecret, redirectURI string, codeVerifier *string) (*TokenResponse, error) {
+func (s *oauthService) HandleTokenRequest(ctx context.Context, grantType, code, clientID, clientSecret, redirectURI string, codeVerifier *string, authorizationDetails []model.AuthorizationDetail) (*TokenResponse, error) {
+	var response *TokenResponse
+	var err error
 	switch grantType {
 	case "authorization_code":
 		// 使用授权码换取访问令牌
-		return s.ExchangeAuthorizationCode(ctx, code, clientID, clientSecret, redirectURI, codeVerifier)
+		response, err = s.ExchangeAuthorizationCode(ctx, code, clientID, clientSecret, redirectURI, codeVerifier, authorizationDetails)
 	case "refresh_token":
 		// 使用刷新令牌获取新的访问令牌
-		return s.RefreshAccessToken(ctx, code, clientID, clientSecret)
+		response, err = s.RefreshAccessToken(ctx, code, clientID, clientSecret)
+	case CIBAGrantType:
+		// 使用auth_req_id获取CIBA认证结果
+		response, err = s.ExchangeBackchannelAuthRequest(ctx, code, clientID, clientSecret)
 	default:
-		return nil, fmt.Errorf("unsupported grant type: %s", grantType)
+		err = ErrUnsupportedGrantType(fmt.Sprintf("grant type %q is not supported", grantType))
 	}
+
+	if err != nil {
+		s.recordTokenFailure(ctx, grantType, clientID, err)
+	}
+	return response, err
+}
+
+// recordTokenFailure 记录令牌请求失败，CIBA轮询时用户尚未批准属于正常流程，不记录
+func (s *oauthService) recordTokenFailure(ctx context.Context, grantType, clientID string, err error) {
+	oauthErr := AsOAuthError(err)
+	if oauthErr.Code == ErrCodeAuthorizationPending || oauthErr.Code == ErrCodeSlowDown {
+		return
+	}
+
+	eventType := model.AuditTokenIssued
+	if grantType == "refresh_token" {
+		eventType = model.AuditTokenRefreshed
+	}
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:     eventType,
+		Outcome:  model.AuditOutcomeFailure,
+		Actor:    clientActor(clientID),
+		ClientID: clientID,
+		Details:  map[string]string{"grant_type": grantType, "error": oauthErr.Code},
+	})
+}
+
+// recordTokenSuccess 记录为用户签发令牌
+func (s *oauthService) recordTokenSuccess(ctx context.Context, eventType, grantType string, userID uint, clientID, scopes string) {
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:     eventType,
+		UserID:   userID,
+		Actor:    clientActor(clientID),
+		ClientID: clientID,
+		Details:  map[string]string{"grant_type": grantType, "scope": scopes},
+	})
+}
+
+// IntrospectToken 令牌内省，调用方必须是本realm中已认证的客户端 (RFC 7662 §2.1)
+// 本realm签发且未过期的访问令牌对任一客户端有效；刷新令牌只对持有它的客户端有效，
+// 其余令牌均返回active=false
+func (s *oauthService) IntrospectToken(ctx context.Context, token, clientID, clientSecret string) (*IntrospectionResponse, error) {
+	client, err := s.ValidateClient(ctx, clientID, clientSecret, "")
+	if err != nil {
+		return nil, err
+	}
+	// 公开客户端没有可验证的凭据，不能调用内省端点
+	if client.IsPublic() {
+		return nil, ErrInvalidClient("public clients cannot use the introspection endpoint")
+	}
+
+	if token == "" {
+		return nil, ErrInvalidRequest("missing token")
+	}
+
+	// 刷新令牌以哈希形式保存，可直接查询；其他客户端不能借内省获知刷新令牌的授权信息 (RFC 7662 §4)
+	if refresh, err := s.refreshRepo.GetByTokenHash(ctx, s.hashToken(token)); err == nil {
+		if refresh.ClientID != client.ClientID {
+			return &IntrospectionResponse{Active: false}, nil
+		}
+		return s.introspectRefreshToken(ctx, refresh), nil
+	}
+
+	if s.jwtUtil == nil {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+	claims, err := s.jwtUtil.ParseAccessToken(token)
+	if err != nil || claims.Issuer != util.IssuerFromContext(ctx) {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+	// 用户已被删除或已申请删除时，其尚未过期的访问令牌同样视为无效
+	var userID uint
+	if _, err := fmt.Sscanf(claims.Subject, "user:%d", &userID); err == nil && s.userRepo != nil {
+		if user, err := s.userRepo.GetByID(userID); err != nil || user.DeletionScheduledAt != nil {
+			return &IntrospectionResponse{Active: false}, nil
+		}
+	}
+
+	response := &IntrospectionResponse{
+		Active:               true,
+		Scope:                claims.Scope,
+		TokenType:            "Bearer",
+		Sub:                  claims.Subject,
+		Aud:                  claims.Audience,
+		Iss:                  claims.Issuer,
+		Roles:                claims.Roles,
+		Groups:               claims.Groups,
+		AuthorizationDetails: claims.AuthorizationDetails,
+	}
+	if len(claims.Audience) > 0 {
+		response.ClientID = claims.Audience[0]
+	}
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+
+	return response, nil
+}
+
+// introspectRefreshToken 构造刷新令牌的内省响应，已撤销或过期的刷新令牌视为无效
+func (s *oauthService) introspectRefreshToken(ctx context.Context, refresh *model.RefreshToken) *IntrospectionResponse {
+	if !refresh.IsActive() {
+		return &IntrospectionResponse{Active: false}
+	}
+
+	details, _ := model.ParseAuthorizationDetails(refresh.AuthorizationDetails)
+	return &IntrospectionResponse{
+		Active:               true,
+		Scope:                refresh.Scopes,
+		ClientID:             refresh.ClientID,
+		TokenType:            "refresh_token",
+		Exp:                  refresh.ExpiresAt.Unix(),
+		Iat:                  refresh.CreatedAt.Unix(),
+		Sub:                  fmt.Sprintf("user:%d", refresh.UserID),
+		Aud:                  []string{refresh.ClientID},
+		Iss:                  util.IssuerFromContext(ctx),
+		AuthorizationDetails: details,
+	}
+}
+
+// ValidateAuthorizationRequest 验证授权请求的客户端与重定向URI
+// 只有该校验通过后，后续错误才允许重定向回客户端 (RFC 6749 §4.1.2.1)
+func (s *oauthService) ValidateAuthorizationRequest(ctx context.Context, clientID, redirectURI string) (*model.Client, error) {
+	if clientID == "" {
+		return nil, ErrInvalidRequest("missing client_id")
+	}
+
+	client, err := s.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		// 授权端点直接向用户展示该错误，不涉及客户端认证，因此使用400
+		return nil, NewOAuthError(ErrCodeInvalidClient, "unknown client", http.StatusBadRequest)
+	}
+
+	if redirectURI == "" {
+		return nil, ErrInvalidRequest("missing redirect_uri")
+	}
+
+	if !s.isValidRedirectURI(redirectURI, client) {
+		return nil, ErrInvalidRequest("redirect_uri does not match a registered redirect URI")
+	}
+
+	return client, nil
 }
 
 // ValidateClient 验证客户端
@@ -202,18 +715,23 @@ func (s *oauthService) ValidateClient(ctx context.Context, clientID, clientSecre
 	// 查找客户端
 	client, err := s.GetClientByClientID(ctx, clientID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid client: %w", err)
+		return nil, ErrInvalidClient("client authentication failed")
 	}
 
-	// 验证客户端密钥
-	// 这里应该有实际的密钥验证逻辑
-	// 为简化示例，我们跳过验证
-	_ = clientSecret
+	// 公开客户端不使用密钥认证，其授权码必须由PKCE保护；机密客户端必须提供正确的密钥
+	if client.IsPublic() {
+		if clientSecret != "" {
+			return nil, ErrInvalidClient("public clients must not present a client secret")
+		}
+	} else if client.SecretHash == "" || clientSecret == "" ||
+		bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(clientSecret)) != nil {
+		return nil, ErrInvalidClient("client authentication failed")
+	}
 
 	// 验证重定向URI（仅当提供了重定向URI时才验证）
 	// 在刷新令牌流程中，通常不提供重定向URI
-	if redirectURI != "" && !s.isValidRedirectURI(redirectURI, client.RedirectURI) {
-		return nil, fmt.Errorf("invalid redirect URI")
+	if redirectURI != "" && !s.isValidRedirectURI(redirectURI, client) {
+		return nil, ErrInvalidGrant("redirect_uri does not match the authorization request")
 	}
 
 	return client, nil
@@ -221,289 +739,475 @@ func (s *oauthService) ValidateClient(ctx context.Context, clientID, clientSecre
 
 // GenerateAuthorizationCode 生成授权码
 func (s *oauthService) GenerateAuthorizationCode(ctx context.Context, client *model.Client, userID uint, redirectURI string, scopes []string, codeChallenge, codeChallengeMethod *string) (string, error) {
-	// 生成随机授权码
-	code := s.generateRandomCode(64)
-
-	// 创建授权码实体（当前仅用于演示，实际应保存到数据库）
-	_ = &model.AuthorizationCode{
-		Code:                code,
+	req := &AuthorizationRequest{
 		ClientID:            client.ClientID,
-		UserID:              userID,
 		RedirectURI:         redirectURI,
-		Scopes:              s.scopesToString(scopes),
-		ExpiresAt:           time.Now().Add(10 * time.Minute), // 10分钟有效期
-		CodeChallenge:       s.getStringValue(codeChallenge),
-		CodeChallengeMethod: s.getStringValue(codeChallengeMethod),
+		Scopes:              scopes,
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
 	}
 
-	// TODO: 保存到数据库
-	// 这里应该调用repository来保存授权码
-	// err := s.authorizationCodeRepo.Create(ctx, authCode)
-	// if err != nil {
-	//     return "", fmt.Errorf("failed to save authorization code: %w", err)
-	// }
+	authCode, err := s.createAuthorizationCode(ctx, client, userID, req, time.Now(), "")
+	if err != nil {
+		return "", err
+	}
 
-	return code, nil
+	return authCode.Code, nil
+}
+
+// createAuthorizationCode 创建并保存授权码，authTime与amr来自登录会话
+func (s *oauthService) createAuthorizationCode(ctx context.Context, client *model.Client, userID uint, req *AuthorizationRequest, authTime time.Time, amr string) (*model.AuthorizationCode, error) {
+	authCode := &model.AuthorizationCode{
+		Code:                 s.generateRandomCode(64),
+		ClientID:             client.ClientID,
+		UserID:               userID,
+		RedirectURI:          req.RedirectURI,
+		Scopes:               s.scopesToString(req.Scopes),
+		CodeChallenge:        s.getStringValue(req.CodeChallenge),
+		CodeChallengeMethod:  s.getStringValue(req.CodeChallengeMethod),
+		Nonce:                req.Nonce,
+		AuthTime:             authTime,
+		AMR:                  amr,
+		AuthorizationDetails: model.MarshalAuthorizationDetails(req.AuthorizationDetails),
+		ExpiresAt:            time.Now().Add(s.tokenPolicy(client).AuthorizationCodeLifetime),
+	}
+
+	if err := s.authCodeRepo.Create(ctx, authCode); err != nil {
+		return nil, fmt.Errorf("failed to save authorization code: %w", err)
+	}
+
+	return authCode, nil
 }
 
 // ExchangeAuthorizationCode 用授权码换取访问令牌
-func (s *oauthService) ExchangeAuthorizationCode(ctx context.Context, code, clientID, clientSecret, redirectURI string, codeVerifier *string) (*TokenResponse, error) {
+func (s *oauthService) ExchangeAuthorizationCode(ctx context.Context, code, clientID, clientSecret, redirectURI string, codeVerifier *string, authorizationDetails []model.AuthorizationDetail) (*TokenResponse, error) {
+	if code == "" {
+		return nil, ErrInvalidRequest("missing code")
+	}
+
+	// 验证客户端
+	client, err := s.ValidateClient(ctx, clientID, clientSecret, "")
+	if err != nil {
+		return nil, err
+	}
+
 	// 查找授权码
 	authCode, err := s.ValidateAuthorizationCode(ctx, code, clientID, redirectURI)
 	if err != nil {
-		return nil, fmt.Errorf("invalid authorization code: %w", err)
+		return nil, err
 	}
 
-	// 验证客户端
-	client, err := s.ValidateClient(ctx, clientID, clientSecret, redirectURI)
-	if err != nil {
-		return nil, fmt.Errorf("invalid client: %w", err)
+	// 公开客户端的授权码必须绑定S256 code_challenge，要求PKCE的客户端必须绑定code_challenge
+	if client.IsPublic() && authCode.CodeChallengeMethod != "S256" {
+		return nil, ErrInvalidGrant("public clients must use PKCE with code_challenge_method=S256")
+	}
+	if s.requiresPKCE(client) && authCode.CodeChallenge == "" {
+		return nil, ErrInvalidGrant("the authorization code was issued without a code_challenge")
 	}
 
 	// 验证PKCE（如果使用）
 	if authCode.CodeChallenge != "" {
-		if codeVerifier == nil {
-			return nil, fmt.Errorf("code verifier required")
+		if codeVerifier == nil || *codeVerifier == "" {
+			return nil, ErrInvalidRequest("missing code_verifier")
 		}
 
 		if !s.validatePKCE(authCode.CodeChallenge, *codeVerifier, authCode.CodeChallengeMethod) {
-			return nil, fmt.Errorf("invalid code verifier")
+			return nil, ErrInvalidGrant("code_verifier does not match the code_challenge")
 		}
 	}
 
-	// 生成访问令牌
-	accessToken, err := s.generateAccessToken(authCode.UserID, client.ClientID, authCode.Scopes)
+	// 令牌请求可以只申请用户已批准的部分authorization_details
+	granted, err := model.ParseAuthorizationDetails(authCode.AuthorizationDetails)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, ErrServerError("failed to load authorization_details")
 	}
-
-	// 生成刷新令牌
-	refreshTokenStr, err := s.generateRefreshToken()
+	details, err := s.detailsService.NarrowAuthorizationDetails(ctx, granted, authorizationDetails)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, err
 	}
 
-	// 创建刷新令牌实体（当前仅用于演示，实际应保存到数据库）
-	_ = &model.RefreshToken{
-		TokenHash: s.hashToken(refreshTokenStr),
-		UserID:    authCode.UserID,
-		ClientID:  client.ClientID,
-		Scopes:    authCode.Scopes,
-		ExpiresAt: time.Now().Add(24 * time.Hour * 30), // 30天有效期
-	}
+	policy := s.tokenPolicy(client)
 
-	// TODO: 保存刷新令牌到数据库
-	// err = s.refreshTokenRepo.Create(ctx, refreshTokenModel)
-	// if err != nil {
-	//     return nil, fmt.Errorf("failed to save refresh token: %w", err)
-	// }
+	// 生成访问令牌
+	accessToken, err := s.generateAccessToken(ctx, authCode.UserID, client.ClientID, authCode.Scopes, details, policy.AccessTokenLifetime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
 
 	// 构造响应
 	response := &TokenResponse{
-		AccessToken:  accessToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    3600, // 1小时
-		Scope:        authCode.Scopes,
+		AccessToken:          accessToken,
+		TokenType:            "Bearer",
+		ExpiresIn:            int(policy.AccessTokenLifetime.Seconds()),
+		Scope:                authCode.Scopes,
+		AuthorizationDetails: details,
 	}
 
-	// 如果是授权码流程，添加刷新令牌
-	response.RefreshToken = refreshTokenStr
+	// 仅在请求了offline_access或客户端策略要求时签发刷新令牌 (OIDC Core §11)
+	if policy.ShouldIssueRefreshToken(s.stringToScopes(authCode.Scopes)) {
+		now := time.Now()
+		refresh := &model.RefreshToken{
+			UserID:               authCode.UserID,
+			ClientID:             client.ClientID,
+			Scopes:               authCode.Scopes,
+			AuthorizationDetails: model.MarshalAuthorizationDetails(details),
+			AuthTime:             authCode.AuthTime,
+			AMR:                  authCode.AMR,
+			AbsoluteExpiresAt:    now.Add(policy.RefreshTokenAbsoluteLifetime),
+		}
+		refresh.ExpiresAt = policy.RefreshTokenExpiry(now, refresh.AbsoluteExpiresAt)
+		if response.RefreshToken, err = s.saveRefreshToken(ctx, refresh); err != nil {
+			return nil, err
+		}
+	}
 
 	// 检查是否包含openid scope，如果包含则生成ID Token
 	if s.containsScope(s.stringToScopes(authCode.Scopes), "openid") {
 		// 生成ID Token
-		idToken, err := s.generateIDToken(authCode.UserID, client.ClientID, authCode.Scopes)
+		idToken, err := s.generateIDToken(ctx, authCode.UserID, client.ClientID, authCode.Scopes, authCode.Nonce, authCode.AuthTime, authCode.AMR, policy.IDTokenLifetime)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate ID token: %w", err)
 		}
 		response.IDToken = idToken
 	}
 
-	// TODO: 删除已使用的授权码
-	// err = s.authorizationCodeRepo.Delete(ctx, code)
-	// if err != nil {
-	//     // 记录日志但不中断流程
-	// }
-
+	s.recordTokenSuccess(ctx, model.AuditTokenIssued, "authorization_code", authCode.UserID, client.ClientID, authCode.Scopes)
 	return response, nil
 }
 
-// ValidateAuthorizationCode 验证授权码
+// ValidateAuthorizationCode 验证授权码，授权码只能使用一次
 func (s *oauthService) ValidateAuthorizationCode(ctx context.Context, code, clientID, redirectURI string) (*model.AuthorizationCode, error) {
-	// TODO: 从数据库查找授权码
-	// authCode, err := s.authorizationCodeRepo.GetByCode(ctx, code)
-	// if err != nil {
-	//     return nil, fmt.Errorf("invalid authorization code")
-	// }
-
-	// 模拟一个授权码对象用于演示
-	authCode := &model.AuthorizationCode{
-		Code:        code,
-		ClientID:    clientID,
-		UserID:      1,
-		RedirectURI: redirectURI,
-		Scopes:      "openid profile email",
-		ExpiresAt:   time.Now().Add(10 * time.Minute),
+	// 查找与消费一次完成：无论校验结果如何授权码都会失效，并发兑换同一授权码时只有一方成功
+	authCode, err := s.authCodeRepo.ConsumeByCode(ctx, code)
+	if err != nil {
+		return nil, ErrInvalidGrant("invalid authorization code")
 	}
 
 	// 检查是否过期
 	if time.Now().After(authCode.ExpiresAt) {
-		return nil, fmt.Errorf("authorization code expired")
+		return nil, ErrInvalidGrant("authorization code expired")
 	}
 
 	// 验证客户端ID
 	if authCode.ClientID != clientID {
-		return nil, fmt.Errorf("invalid client")
+		return nil, ErrInvalidGrant("authorization code was issued to another client")
 	}
 
 	// 验证重定向URI
 	if authCode.RedirectURI != redirectURI {
-		return nil, fmt.Errorf("invalid redirect URI")
+		return nil, ErrInvalidGrant("redirect_uri does not match the authorization request")
 	}
 
 	return authCode, nil
 }
 
-// CreateRefreshToken 创建刷新令牌
+// CreateRefreshToken 按客户端的令牌有效期策略创建并保存刷新令牌
 func (s *oauthService) CreateRefreshToken(ctx context.Context, userID uint, clientID string, scopes []string) (*model.RefreshToken, error) {
-	// 生成刷新令牌
-	refreshTokenStr, err := s.generateRefreshToken()
+	client, err := s.GetClientByClientID(ctx, clientID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, err
 	}
+	policy := s.tokenPolicy(client)
 
-	// 创建刷新令牌实体
+	now := time.Now()
 	refreshToken := &model.RefreshToken{
-		TokenHash: s.hashToken(refreshTokenStr),
-		UserID:    userID,
-		ClientID:  clientID,
-		Scopes:    s.scopesToString(scopes),
-		ExpiresAt: time.Now().Add(24 * time.Hour * 30), // 30天有效期
+		UserID:            userID,
+		ClientID:          clientID,
+		Scopes:            s.scopesToString(scopes),
+		AuthTime:          now,
+		AbsoluteExpiresAt: now.Add(policy.RefreshTokenAbsoluteLifetime),
 	}
+	refreshToken.ExpiresAt = policy.RefreshTokenExpiry(now, refreshToken.AbsoluteExpiresAt)
 
-	// TODO: 保存到数据库
-	// err = s.refreshTokenRepo.Create(ctx, refreshToken)
-	// if err != nil {
-	//     return nil, fmt.Errorf("failed to save refresh token: %w", err)
-	// }
+	if _, err := s.saveRefreshToken(ctx, refreshToken); err != nil {
+		return nil, err
+	}
 
 	return refreshToken, nil
 }
 
-// RefreshAccessToken 刷新访问令牌
+// RefreshAccessToken 刷新访问令牌，每次刷新都会轮换刷新令牌，旧令牌立即失效
 func (s *oauthService) RefreshAccessToken(ctx context.Context, refreshToken, clientID, clientSecret string) (*TokenResponse, error) {
 	// 验证客户端
 	client, err := s.ValidateClient(ctx, clientID, clientSecret, "") // 重定向URI在刷新令牌流程中不验证
 	if err != nil {
-		return nil, fmt.Errorf("invalid client: %w", err)
+		return nil, err
+	}
+
+	if refreshToken == "" {
+		return nil, ErrInvalidRequest("missing refresh_token")
 	}
 
 	// 查找刷新令牌
-	// refresh, err := s.refreshTokenRepo.GetByTokenHash(ctx, s.hashToken(refreshToken))
-	// if err != nil {
-	//     return nil, fmt.Errorf("invalid refresh token")
-	// }
+	refresh, err := s.refreshRepo.GetByTokenHash(ctx, s.hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidGrant("invalid refresh token")
+	}
+	if refresh.ClientID != client.ClientID {
+		return nil, ErrInvalidGrant("refresh token was issued to another client")
+	}
+	if !refresh.RevokedAt.IsZero() {
+		return nil, ErrInvalidGrant("refresh token has been revoked")
+	}
+	if !refresh.IsActive() {
+		return nil, ErrInvalidGrant("refresh token expired")
+	}
+	
+	// 用户已删除、已申请删除或已停用时不再签发新的令牌
+	if s.userRepo != nil {
+		user, err := s.userRepo.GetByID(refresh.UserID)
+		if err != nil || user.DeletionScheduledAt != nil || !user.IsActive {
+			return nil, ErrInvalidGrant("the user account is not active")
+		}
+	}
 
-	// 模拟一个刷新令牌对象用于演示
-	refresh := &model.RefreshToken{
-		TokenHash: s.hashToken(refreshToken),
-		UserID:    1,
-		ClientID:  clientID,
-		Scopes:    "openid profile email",
-		ExpiresAt: time.Now().Add(24 * time.Hour * 30),
+	// 撤销旧的刷新令牌
+	if err := s.refreshRepo.Revoke(ctx, refresh); err != nil {
+		return nil, ErrServerError("failed to revoke refresh token")
 	}
 
-	// 检查是否过期
-	if time.Now().After(refresh.ExpiresAt) {
-		return nil, fmt.Errorf("refresh token expired")
+	policy := s.tokenPolicy(client)
+	details, err := model.ParseAuthorizationDetails(refresh.AuthorizationDetails)
+	if err != nil {
+		return nil, ErrServerError("failed to load authorization_details")
 	}
 
 	// 生成新的访问令牌
-	accessToken, err := s.generateAccessToken(refresh.UserID, client.ClientID, refresh.Scopes)
+	accessToken, err := s.generateAccessToken(ctx, refresh.UserID, client.ClientID, refresh.Scopes, details, policy.AccessTokenLifetime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	// 生成新的刷新令牌
-	newRefreshTokenStr, err := s.generateRefreshToken()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	// 轮换刷新令牌，绝对过期时间保持不变；滑动过期时重新计算空闲超时，否则沿用旧令牌的过期时间
+	rotated := &model.RefreshToken{
+		UserID:               refresh.UserID,
+		ClientID:             client.ClientID,
+		Scopes:               refresh.Scopes,
+		AuthorizationDetails: refresh.AuthorizationDetails,
+		AuthTime:             refresh.AuthTime,
+		AMR:                  refresh.AMR,
+		ExpiresAt:            refresh.ExpiresAt,
+		AbsoluteExpiresAt:    refresh.AbsoluteExpiresAt,
 	}
-
-	// 创建新的刷新令牌实体
-	_ = &model.RefreshToken{
-		TokenHash: s.hashToken(newRefreshTokenStr),
-		UserID:    refresh.UserID,
-		ClientID:  client.ClientID,
-		Scopes:    refresh.Scopes,
-		ExpiresAt: time.Now().Add(24 * time.Hour * 30), // 30天有效期
+	if policy.RefreshTokenSliding {
+		rotated.ExpiresAt = policy.RefreshTokenExpiry(time.Now(), refresh.AbsoluteExpiresAt)
+	}
+	newRefreshToken, err := s.saveRefreshToken(ctx, rotated)
+	if err != nil {
+		return nil, err
 	}
-
-	// TODO: 保存新刷新令牌到数据库
-	// err = s.refreshTokenRepo.Create(ctx, newRefreshToken)
-	// if err != nil {
-	//     return nil, fmt.Errorf("failed to save new refresh token: %w", err)
-	// }
-
-	// TODO: 撤销旧的刷新令牌
-	// err = s.refreshTokenRepo.Revoke(ctx, refresh.ID)
-	// if err != nil {
-	//     // 记录日志但不中断流程
-	// }
 
 	// 构造响应
 	response := &TokenResponse{
-		AccessToken:  accessToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    3600, // 1小时
-		RefreshToken: newRefreshTokenStr,
-		Scope:        refresh.Scopes,
+		AccessToken:          accessToken,
+		TokenType:            "Bearer",
+		ExpiresIn:            int(policy.AccessTokenLifetime.Seconds()),
+		RefreshToken:         newRefreshToken,
+		Scope:                refresh.Scopes,
+		AuthorizationDetails: details,
 	}
 
-	// 如果scope包含openid，生成ID Token
+	// 如果scope包含openid，生成ID Token，auth_time沿用最初认证的时间
 	if s.containsScope(s.stringToScopes(refresh.Scopes), "openid") {
-		idToken, err := s.generateIDToken(refresh.UserID, client.ClientID, refresh.Scopes)
+		idToken, err := s.generateIDToken(ctx, refresh.UserID, client.ClientID, refresh.Scopes, "", refresh.AuthTime, refresh.AMR, policy.IDTokenLifetime)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate ID token: %w", err)
 		}
 		response.IDToken = idToken
 	}
 
+	s.recordTokenSuccess(ctx, model.AuditTokenRefreshed, "refresh_token", refresh.UserID, client.ClientID, refresh.Scopes)
+	return response, nil
+}
+
+// ExchangeBackchannelAuthRequest 兑换CIBA认证请求获取令牌，poll与ping模式均通过令牌端点取回结果
+func (s *oauthService) ExchangeBackchannelAuthRequest(ctx context.Context, authReqID, clientID, clientSecret string) (*TokenResponse, error) {
+	// 验证客户端
+	client, err := s.ValidateClient(ctx, clientID, clientSecret, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if client.BackchannelTokenDeliveryMode == "" || s.cibaService == nil {
+		return nil, ErrUnauthorizedClient("the client is not registered for backchannel authentication")
+	}
+
+	// 用户尚未批准时返回authorization_pending/slow_down等错误
+	authReq, err := s.cibaService.RedeemAuthReqID(ctx, client.ClientID, authReqID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := s.tokenPolicy(client)
+
+	// 生成访问令牌
+	accessToken, err := s.generateAccessToken(ctx, authReq.UserID, client.ClientID, authReq.Scopes, nil, policy.AccessTokenLifetime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	// 构造响应
+	response := &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(policy.AccessTokenLifetime.Seconds()),
+		Scope:       authReq.Scopes,
+	}
+
+	// 与授权码流程一致，仅在请求了offline_access或客户端策略要求时签发刷新令牌
+	if policy.ShouldIssueRefreshToken(s.stringToScopes(authReq.Scopes)) {
+		now := time.Now()
+		refresh := &model.RefreshToken{
+			UserID:            authReq.UserID,
+			ClientID:          client.ClientID,
+			Scopes:            authReq.Scopes,
+			AuthTime:          authReq.AuthTime,
+			AMR:               authReq.AMR,
+			AbsoluteExpiresAt: now.Add(policy.RefreshTokenAbsoluteLifetime),
+		}
+		refresh.ExpiresAt = policy.RefreshTokenExpiry(now, refresh.AbsoluteExpiresAt)
+		if response.RefreshToken, err = s.saveRefreshToken(ctx, refresh); err != nil {
+			return nil, err
+		}
+	}
+
+	// CIBA请求必须包含openid scope，auth_time与amr来自用户批准时的登录会话
+	idToken, err := s.generateIDToken(ctx, authReq.UserID, client.ClientID, authReq.Scopes, "", authReq.AuthTime, authReq.AMR, policy.IDTokenLifetime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ID token: %w", err)
+	}
+	response.IDToken = idToken
+
+	s.recordTokenSuccess(ctx, model.AuditTokenIssued, CIBAGrantType, authReq.UserID, client.ClientID, authReq.Scopes)
 	return response, nil
 }
 
 // GetClientByClientID 根据客户端ID获取客户端
 func (s *oauthService) GetClientByClientID(ctx context.Context, clientID string) (*model.Client, error) {
-	// TODO: 从数据库查找客户端
-	// client, err := s.clientRepo.GetByClientID(ctx, clientID)
-	// if err != nil {
-	//     return nil, fmt.Errorf("client not found")
-	// }
-
-	// 模拟一个客户端对象用于演示
-	client := &model.Client{
-		ID:          1,
-		ClientID:    "test_client",
-		SecretHash:  "", // 实际应该存储哈希值
-		Name:        "测试客户端",
-		Description: "用于测试的客户端",
-		RedirectURI: "http://localhost:3000/callback",
-		Scopes:      "openid profile email",
-	}
-	
-	// 检查客户端ID是否匹配
-	if clientID != client.ClientID {
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
 		return nil, fmt.Errorf("client not found")
 	}
 
 	return client, nil
 }
 
-// isValidRedirectURI 验证重定向URI是否有效
-func (s *oauthService) isValidRedirectURI(requestedURI, allowedURI string) bool {
-	// 在测试环境中允许更灵活的重定向URI验证
-	// 实际生产环境中应该更严格
-	return requestedURI == allowedURI || allowedURI == "http://localhost:3000/callback"
+// validateRequestedAccess 验证授权请求的prompt、scopes与authorization_details，授权端点与PAR端点共用
+func (s *oauthService) validateRequestedAccess(ctx context.Context, client *model.Client, req *AuthorizationRequest) error {
+	// 验证prompt参数
+	if err := s.validatePrompt(req.Prompt); err != nil {
+		return err
+	}
+
+	// 验证请求的scopes已注册且被客户端允许
+	if unknown, ok := s.scopeService.ValidateScopes(ctx, req.Scopes); !ok {
+		return ErrInvalidScope(fmt.Sprintf("scope %q is not registered", unknown))
+	}
+	if !s.areScopesAllowed(req.Scopes, client.Scopes) {
+		return ErrInvalidScope("the requested scope exceeds the scope granted to the client")
+	}
+
+	// 验证PKCE参数
+	if err := s.validateCodeChallenge(client, req); err != nil {
+		return err
+	}
+
+	// 验证authorization_details类型已注册且被客户端允许
+	return s.detailsService.ValidateAuthorizationDetails(ctx, client, req.AuthorizationDetails)
+}
+
+// validateCodeChallenge 验证PKCE参数，未指定code_challenge_method时默认为plain (RFC 7636 §4.3)
+// 公开客户端必须使用S256 (RFC 8252 §8.1)
+func (s *oauthService) validateCodeChallenge(client *model.Client, req *AuthorizationRequest) error {
+	if req.CodeChallenge == nil {
+		if client.IsPublic() {
+			return ErrInvalidRequest("public clients must use PKCE with code_challenge_method=S256")
+		}
+		if s.requiresPKCE(client) {
+			return ErrInvalidRequest("code_challenge is required")
+		}
+		return nil
+	}
+
+	// code_challenge与code_verifier使用相同的字符集和长度限制 (RFC 7636 §4.2)
+	if !isValidPKCEValue(*req.CodeChallenge) {
+		return ErrInvalidRequest("code_challenge must be 43-128 characters from the unreserved character set")
+	}
+
+	method := "plain"
+	if req.CodeChallengeMethod != nil {
+		method = *req.CodeChallengeMethod
+	}
+	switch method {
+	case "S256":
+	case "plain":
+		if client.IsPublic() {
+			return ErrInvalidRequest("code_challenge_method=plain is not allowed for public clients")
+		}
+	default:
+		return ErrInvalidRequest("transform algorithm not supported")
+	}
+	req.CodeChallengeMethod = &method
+
+	return nil
+}
+
+// requiresPKCE 判断客户端的授权码流程是否必须使用PKCE：公开客户端始终要求，
+// 机密客户端由客户端的require_pkce决定，未设置时由REQUIRE_PKCE配置
+func (s *oauthService) requiresPKCE(client *model.Client) bool {
+	if client.IsPublic() {
+		return true
+	}
+	if client.RequirePKCE != nil {
+		return *client.RequirePKCE
+	}
+	return os.Getenv("REQUIRE_PKCE") == "true"
+}
+
+// validatePrompt 验证prompt参数 (OIDC Core §3.1.2.1)
+func (s *oauthService) validatePrompt(prompts []string) error {
+	for _, prompt := range prompts {
+		switch prompt {
+		case "none", "login", "consent", "select_account":
+		default:
+			return ErrInvalidRequest(fmt.Sprintf("unsupported prompt value %q", prompt))
+		}
+	}
+	// prompt=none不能与其他值同时出现
+	if len(prompts) > 1 && s.containsScope(prompts, "none") {
+		return ErrInvalidRequest("prompt=none must not be combined with other values")
+	}
+	return nil
+}
+
+// parseIDTokenHint 解析id_token_hint并返回其中的用户ID
+func (s *oauthService) parseIDTokenHint(ctx context.Context, idTokenHint string) (uint, error) {
+	if s.jwtUtil == nil {
+		return 0, ErrServerError("JWT utility not available")
+	}
+
+	claims, err := s.jwtUtil.ParseIDTokenHint(idTokenHint)
+	if err != nil || claims.Issuer != util.IssuerFromContext(ctx) {
+		return 0, ErrInvalidRequest("invalid id_token_hint")
+	}
+
+	var userID uint
+	if _, err := fmt.Sscanf(claims.Subject, "user:%d", &userID); err != nil {
+		return 0, ErrInvalidRequest("invalid id_token_hint")
+	}
+
+	return userID, nil
+}
+
+// isValidRedirectURI 验证重定向URI是否与客户端登记的任一重定向URI匹配
+func (s *oauthService) isValidRedirectURI(requestedURI string, client *model.Client) bool {
+	// 必须与登记的重定向URI一致（回环地址仅端口可变），否则错误会被重定向到攻击者控制的地址
+	for _, registeredURI := range client.RegisteredRedirectURIs() {
+		if matchRedirectURI(requestedURI, registeredURI) {
+			return true
+		}
+	}
+	return false
 }
 
 // areScopesAllowed 验证请求的scopes是否被允许
@@ -545,19 +1249,32 @@ func (s *oauthService) generateRandomCode(length int) string {
 	return base64.URLEncoding.EncodeToString(bytes)
 }
 
-// generateAccessToken 生成访问令牌
-func (s *oauthService) generateAccessToken(userID uint, clientID, scopes string) (string, error) {
+// generateAccessToken 生成访问令牌，details为用户批准的authorization_details，lifetime由令牌有效期策略决定
+func (s *oauthService) generateAccessToken(ctx context.Context, userID uint, clientID, scopes string, details []model.AuthorizationDetail, lifetime time.Duration) (string, error) {
 	// 如果JWT工具可用，则生成JWT令牌
 	if s.jwtUtil != nil {
 		claims := &util.AccessTokenClaims{
 			RegisteredClaims: jwt.RegisteredClaims{
 				Subject:   fmt.Sprintf("user:%d", userID),
-				Issuer:    "OIDC",
+				Issuer:    util.IssuerFromContext(ctx),
 				IssuedAt:  jwt.NewNumericDate(time.Now()),
-				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)), // 1小时过期
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)),
 				Audience:  []string{clientID},
 			},
-			Scope: scopes,
+			Scope:                scopes,
+			AuthorizationDetails: details,
+		}
+		// 与ID Token一致，仅在授予了映射roles/groups声明的scope时携带用户的角色和用户组，
+		// 避免未获授权的第三方客户端凭用户的角色访问受保护的接口
+		allowed := s.scopeService.ClaimsForScopes(ctx, s.stringToScopes(scopes))
+		if allowed["roles"] || allowed["groups"] {
+			roles, groups := s.userAccessClaims(ctx, userID)
+			if allowed["roles"] {
+				claims.Roles = roles
+			}
+			if allowed["groups"] {
+				claims.Groups = groups
+			}
 		}
 		
 		return s.jwtUtil.GenerateAccessToken(claims)
@@ -571,8 +1288,8 @@ func (s *oauthService) generateAccessToken(userID uint, clientID, scopes string)
 	return "access_" + base64.URLEncoding.EncodeToString(tokenBytes), nil
 }
 
-// generateIDToken 生成ID令牌
-func (s *oauthService) generateIDToken(userID uint, clientID, scopes string) (string, error) {
+// generateIDToken 生成ID令牌，nonce、auth_time与amr来自授权请求及登录会话，lifetime由令牌有效期策略决定
+func (s *oauthService) generateIDToken(ctx context.Context, userID uint, clientID, scopes, nonce string, authTime time.Time, amr string, lifetime time.Duration) (string, error) {
 	// 如果JWT工具不可用，返回错误
 	if s.jwtUtil == nil {
 		return "", fmt.Errorf("JWT utility not available")
@@ -585,27 +1302,120 @@ func (s *oauthService) generateIDToken(userID uint, clientID, scopes string) (st
 	claims := &util.IDTokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   fmt.Sprintf("user:%d", userID),
-			Issuer:    "OIDC",
+			Issuer:    util.IssuerFromContext(ctx),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)), // 1小时过期
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)),
 			Audience:  []string{clientID},
 		},
+		Nonce: nonce,
+		AMR:   strings.Fields(amr),
 	}
-	
-	// 根据scope添加额外声明
-	if s.containsScope(scopeList, "profile") {
-		claims.Profile = "https://example.com/profile"
-		claims.Name = "示例用户"
+	if !authTime.IsZero() {
+		claims.AuthTime = authTime.Unix()
 	}
 	
-	if s.containsScope(scopeList, "email") {
-		claims.Email = "user@example.com"
+	// 根据scope注册表的声明映射添加额外声明
+	allowed := s.scopeService.ClaimsForScopes(ctx, scopeList)
+	if allowed["name"] || allowed["nickname"] || allowed["picture"] || allowed["profile"] {
+		profile := s.userProfileClaims(ctx, userID)
+		if allowed["name"] {
+			claims.Name = profile.Name
+		}
+		if allowed["nickname"] {
+			claims.Nickname = profile.Nickname
+		}
+		if allowed["picture"] {
+			claims.Picture = profile.Picture
+		}
+		if allowed["profile"] {
+			claims.Profile = profile.Profile
+		}
+	}
+	if allowed["email"] || allowed["email_verified"] {
+		email, emailVerified := s.userEmailClaims(userID)
+		if allowed["email"] {
+			claims.Email = email
+		}
+		if allowed["email_verified"] {
+			claims.EmailVerified = emailVerified
+		}
+	}
+	if allowed["roles"] || allowed["groups"] {
+		roles, groups := s.userAccessClaims(ctx, userID)
+		if allowed["roles"] {
+			claims.Roles = roles
+		}
+		if allowed["groups"] {
+			claims.Groups = groups
+		}
 	}
 	
 	// 生成ID Token
 	return s.jwtUtil.GenerateIDToken(claims)
 }
 
+// profileClaims 用户资料相关的标准声明 (OIDC Core §5.1)
+type profileClaims struct {
+	Name     string
+	Nickname string
+	Picture  string
+	Profile  string
+}
+
+// userProfileClaims 按用户记录填充name、nickname、picture和profile声明：
+// 未设置昵称时name使用用户名，未上传头像时picture为默认identicon头像，profile仅在配置PROFILE_PAGE_URL时返回
+func (s *oauthService) userProfileClaims(ctx context.Context, userID uint) profileClaims {
+	var claims profileClaims
+	if s.userRepo == nil || userID == 0 {
+		return claims
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return claims
+	}
+
+	issuer := util.IssuerFromContext(ctx)
+	claims.Name = user.Nickname
+	if claims.Name == "" {
+		claims.Name = user.Username
+	}
+	claims.Nickname = user.Nickname
+	claims.Picture = user.AvatarURL
+	if claims.Picture == "" && s.userHelper != nil {
+		claims.Picture = s.userHelper.GenerateAvatarURL(issuer, user.ID)
+	}
+	if pageURL := os.Getenv("PROFILE_PAGE_URL"); pageURL != "" {
+		claims.Profile = fmt.Sprintf("%s?user=%s&issuer=%s", pageURL, url.QueryEscape(user.Username), url.QueryEscape(issuer))
+	}
+	return claims
+}
+
+// userEmailClaims 获取用户当前的邮箱及其验证状态，用于填充email/email_verified声明；
+// 邮箱变更在新邮箱确认后才生效，因此email_verified反映的是当前邮箱
+func (s *oauthService) userEmailClaims(userID uint) (string, *bool) {
+	if s.userRepo == nil || userID == 0 {
+		return "", nil
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", nil
+	}
+	emailVerified := user.EmailVerified
+	return user.Email, &emailVerified
+}
+
+// userAccessClaims 获取用户的有效角色和用户组，用于填充roles/groups声明
+func (s *oauthService) userAccessClaims(ctx context.Context, userID uint) ([]string, []string) {
+	if s.rbacService == nil {
+		return nil, nil
+	}
+	access, err := s.rbacService.GetUserAccess(ctx, userID)
+	if err != nil {
+		return nil, nil
+	}
+	return access.EffectiveRoles, access.Groups
+}
+
 // generateRefreshToken 生成刷新令牌
 func (s *oauthService) generateRefreshToken() (string, error) {
 	tokenBytes := make([]byte, 32)
@@ -615,12 +1425,16 @@ func (s *oauthService) generateRefreshToken() (string, error) {
 	return "refresh_" + base64.URLEncoding.EncodeToString(tokenBytes), nil
 }
 
-// validatePKCE 验证PKCE
+// validatePKCE 验证PKCE，code_verifier必须为43-128位非保留字符 (RFC 7636 §4.1)
 func (s *oauthService) validatePKCE(codeChallenge, codeVerifier, method string) bool {
+	if !isValidPKCEValue(codeVerifier) {
+		return false
+	}
+
 	switch method {
 	case "S256":
 		hash := sha256.Sum256([]byte(codeVerifier))
-		expectedChallenge := base64.URLEncoding.EncodeToString(hash[:])
+		expectedChallenge := base64.RawURLEncoding.EncodeToString(hash[:])
 		return expectedChallenge == codeChallenge
 	case "plain":
 		return codeChallenge == codeVerifier
@@ -629,6 +1443,22 @@ func (s *oauthService) validatePKCE(codeChallenge, codeVerifier, method string)
 	}
 }
 
+// isValidPKCEValue 判断code_verifier或code_challenge是否符合 [A-Za-z0-9-._~]{43,128}
+func isValidPKCEValue(value string) bool {
+	if len(value) < 43 || len(value) > 128 {
+		return false
+	}
+	for _, ch := range value {
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '.', ch == '_', ch == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // hashToken 哈希令牌
 func (s *oauthService) hashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
@@ -673,38 +1503,38 @@ func (s *oauthService) stringToScopes(scopes string) []string {
 	return result
 }
 
+// tokenPolicy 获取对客户端生效的令牌有效期策略
+func (s *oauthService) tokenPolicy(client *model.Client) *TokenPolicy {
+	return ResolveTokenPolicy(s.realm, client)
+}
+
+// saveRefreshToken 生成刷新令牌并保存其哈希，返回令牌明文
+func (s *oauthService) saveRefreshToken(ctx context.Context, refresh *model.RefreshToken) (string, error) {
+	refreshTokenStr, err := s.generateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	refresh.TokenHash = s.hashToken(refreshTokenStr)
+	if err := s.refreshRepo.Create(ctx, refresh); err != nil {
+		return "", ErrServerError("failed to save refresh token")
+	}
+
+	return refreshTokenStr, nil
+}
+
+// parRequestURIExpiry 获取request_uri有效期（秒），由PAR_REQUEST_URI_EXPIRY_SECONDS配置，默认60秒
+func parRequestURIExpiry() int {
+	if seconds, err := strconv.Atoi(os.Getenv("PAR_REQUEST_URI_EXPIRY_SECONDS")); err == nil && seconds > 0 {
+		return seconds
+	}
+	return 60
+}
+
 // getStringValue 获取字符串指针的值，如果指针为nil则返回空字符串
 func (s *oauthService) getStringValue(str *string) string {
 	if str == nil {
 		return ""
 	}
 	return *str
-}
-
-// containsScope 检查scope字符串中是否包含指定的scope
-func containsScope(scopes, targetScope string) bool {
-	// 按空格分割scopes
-	scopeList := []string{}
-	start := 0
-	for i, char := range scopes {
-		if char == ' ' {
-			if start < i {
-				scopeList = append(scopeList, scopes[start:i])
-			}
-			start = i + 1
-		}
-	}
-	
-	// 添加最后一个scope
-	if start < len(scopes) {
-		scopeList = append(scopeList, scopes[start:])
-	}
-	
-	// 检查是否包含目标scope
-	for _, scope := range scopeList {
-		if scope == targetScope {
-			return true
-		}
-	}
-	return false
 }
\ No newline at end of file
@@ -0,0 +1,313 @@
+package service_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAccessTokenRolesRequireRolesScope(t *testing.T) {
+	h := newRealmHarness(t)
+	if err := h.rbac.SetUserRoles(h.ctx, h.alice.ID, []string{"admin"}); err != nil {
+		t.Fatalf("assign admin role: %v", err)
+	}
+	client := h.addClient(t, &model.Client{ClientID: "third-party", Name: "Third Party", RedirectURI: "https://app.example.com/callback", Scopes: "openid profile roles"})
+
+	// 未授予roles scope时，管理员的访问令牌同样不携带角色
+	plain, err := h.jwtUtil.ParseAccessToken(h.exchangeCode(t, client, []string{"openid", "profile"}).AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	if len(plain.Roles) != 0 || len(plain.Groups) != 0 {
+		t.Fatalf("access token without roles scope should not carry roles, got %v %v", plain.Roles, plain.Groups)
+	}
+
+	withRoles, err := h.jwtUtil.ParseAccessToken(h.exchangeCode(t, client, []string{"openid", "roles"}).AccessToken)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	if len(withRoles.Roles) != 1 || withRoles.Roles[0] != "admin" {
+		t.Fatalf("access token with roles scope should carry roles, got %v", withRoles.Roles)
+	}
+}
+
+// pushRequest 以客户端身份推送授权请求，返回request_uri
+func pushRequest(t *testing.T, h *realmHarness, client *model.Client) string {
+	t.Helper()
+	response, err := h.oauth.PushAuthorizationRequest(h.ctx, client, &service.AuthorizationRequest{
+		ClientID:     client.ClientID,
+		RedirectURI:  client.RedirectURI,
+		ResponseType: "code",
+		Scopes:       []string{"openid"},
+	}, "response_type=code&scope=openid")
+	if err != nil {
+		t.Fatalf("push authorization request: %v", err)
+	}
+	if !strings.HasPrefix(response.RequestURI, "urn:ietf:params:oauth:request_uri:") || response.ExpiresIn <= 0 {
+		t.Fatalf("unexpected PAR response: %+v", response)
+	}
+	return response.RequestURI
+}
+
+func TestPushedAuthorizationRequestIsSingleUse(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid profile"})
+	requestURI := pushRequest(t, h, client)
+
+	parameters, err := h.oauth.ResolveRequestURI(h.ctx, client.ClientID, requestURI)
+	if err != nil || parameters != "response_type=code&scope=openid" {
+		t.Fatalf("resolve request_uri: %q, %v", parameters, err)
+	}
+	_, err = h.oauth.ResolveRequestURI(h.ctx, client.ClientID, requestURI)
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidRequestURI)
+
+	// 并发使用同一request_uri时只有一方成功
+	requestURI = pushRequest(t, h, client)
+	succeeded := concurrently(8, func() error {
+		_, err := h.oauth.ResolveRequestURI(h.ctx, client.ClientID, requestURI)
+		return err
+	})
+	if succeeded != 1 {
+		t.Fatalf("a request_uri should be resolved exactly once, got %d", succeeded)
+	}
+}
+
+func TestPushedAuthorizationRequestExpires(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid profile"})
+	requestURI := pushRequest(t, h, client)
+
+	parReq, err := h.pars.GetByRequestURI(h.ctx, requestURI)
+	if err != nil {
+		t.Fatalf("get pushed request: %v", err)
+	}
+	// 内存仓库返回保存的记录本身，直接修改即可模拟过期
+	parReq.ExpiresAt = time.Now().Add(-time.Second)
+
+	_, err = h.oauth.ResolveRequestURI(h.ctx, client.ClientID, requestURI)
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidRequestURI)
+}
+
+func TestPushedAuthorizationRequestIsBoundToClient(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid profile"})
+	other := h.addClient(t, &model.Client{ClientID: "other", Name: "Other", RedirectURI: "https://other.example.com/callback", Scopes: "openid profile"})
+	requestURI := pushRequest(t, h, client)
+
+	_, err := h.oauth.ResolveRequestURI(h.ctx, other.ClientID, requestURI)
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidRequestURI)
+}
+
+func TestPushedAuthorizationRequestValidatesParameters(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid profile"})
+
+	_, err := h.oauth.PushAuthorizationRequest(h.ctx, client, &service.AuthorizationRequest{
+		ClientID:    client.ClientID,
+		RedirectURI: client.RedirectURI,
+		Scopes:      []string{"openid", "admin"},
+	}, "scope=openid+admin")
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidScope)
+
+	_, err = h.oauth.PushAuthorizationRequest(h.ctx, client, &service.AuthorizationRequest{
+		ClientID:             client.ClientID,
+		RedirectURI:          client.RedirectURI,
+		Scopes:               []string{"openid"},
+		AuthorizationDetails: []model.AuthorizationDetail{{Type: "collection_access", Actions: []string{"delete_account"}}},
+	}, "scope=openid")
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidAuthorizationDetails)
+}
+
+// signAccessToken 使用jwtUtil签发alice的访问令牌
+func signAccessToken(t *testing.T, h *realmHarness, jwtUtil util.JWTUtil, issuer string, expiresIn time.Duration) string {
+	t.Helper()
+	token, err := jwtUtil.GenerateAccessToken(&util.AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   fmt.Sprintf("user:%d", h.alice.ID),
+			Audience:  jwt.ClaimStrings{"app"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+		Scope: "openid collection:read",
+	})
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+	return token
+}
+
+func TestIntrospectToken(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid profile"})
+	resourceServer := h.addClient(t, &model.Client{ClientID: "resource-server", Name: "Resource Server", RedirectURI: "https://rs.example.com/callback", Scopes: "openid"})
+	other := newRealmHarness(t)
+
+	issued := h.exchangeCode(t, client, []string{"openid", "profile"})
+
+	tests := []struct {
+		name       string
+		token      string
+		wantActive bool
+	}{
+		{name: "active access token", token: issued.AccessToken, wantActive: true},
+		{name: "malformed token", token: "not-a-token"},
+		{name: "expired access token", token: signAccessToken(t, h, h.jwtUtil, testIssuer, -time.Minute)},
+		{name: "token signed by another realm", token: signAccessToken(t, h, other.jwtUtil, testIssuer, time.Hour)},
+		{name: "token with another issuer", token: signAccessToken(t, h, h.jwtUtil, "http://other.test", time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 资源服务器以自己的凭据内省其他客户端获得的访问令牌
+			response, err := h.oauth.IntrospectToken(h.ctx, tt.token, resourceServer.ClientID, testClientSecret)
+			if err != nil {
+				t.Fatalf("introspect: %v", err)
+			}
+			if response.Active != tt.wantActive {
+				t.Fatalf("expected active=%v, got %+v", tt.wantActive, response)
+			}
+			if !tt.wantActive && (response.Sub != "" || response.Scope != "" || response.ClientID != "") {
+				t.Fatalf("inactive tokens must not disclose token data, got %+v", response)
+			}
+			if tt.wantActive && (response.ClientID != client.ClientID || response.Scope != "openid profile" || response.Iss != testIssuer) {
+				t.Fatalf("unexpected introspection response: %+v", response)
+			}
+		})
+	}
+}
+
+func TestIntrospectTokenRequiresClientAuthentication(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid profile"})
+	issued := h.exchangeCode(t, client, []string{"openid"})
+
+	_, err := h.oauth.IntrospectToken(h.ctx, issued.AccessToken, client.ClientID, "wrong-secret")
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidClient)
+
+	// 其他realm的客户端不能内省本realm的令牌
+	other := newRealmHarness(t)
+	_, err = other.oauth.IntrospectToken(other.ctx, issued.AccessToken, client.ClientID, testClientSecret)
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidClient)
+
+	_, err = h.oauth.IntrospectToken(h.ctx, "", client.ClientID, testClientSecret)
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidRequest)
+}
+
+func TestIntrospectRefreshToken(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid offline_access"})
+	other := h.addClient(t, &model.Client{ClientID: "other", Name: "Other", RedirectURI: "https://other.example.com/callback", Scopes: "openid"})
+	issued := h.exchangeCode(t, client, []string{"openid", "offline_access"})
+
+	response, err := h.oauth.IntrospectToken(h.ctx, issued.RefreshToken, client.ClientID, testClientSecret)
+	if err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	if !response.Active || response.TokenType != "refresh_token" || response.ClientID != client.ClientID || response.Scope != "openid offline_access" {
+		t.Fatalf("unexpected introspection response: %+v", response)
+	}
+
+	// 其他客户端不能内省该刷新令牌
+	response, err = h.oauth.IntrospectToken(h.ctx, issued.RefreshToken, other.ClientID, testClientSecret)
+	if err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	if response.Active || response.Scope != "" {
+		t.Fatalf("a refresh token must be inactive for other clients, got %+v", response)
+	}
+
+	// 轮换后旧令牌已撤销
+	if _, err := h.oauth.RefreshAccessToken(h.ctx, issued.RefreshToken, client.ClientID, testClientSecret); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	response, err = h.oauth.IntrospectToken(h.ctx, issued.RefreshToken, client.ClientID, testClientSecret)
+	if err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	if response.Active {
+		t.Fatalf("a revoked refresh token must be inactive, got %+v", response)
+	}
+	_, err = h.oauth.RefreshAccessToken(h.ctx, issued.RefreshToken, client.ClientID, testClientSecret)
+	requireOAuthErrorCode(t, err, service.ErrCodeInvalidGrant)
+}
+
+func TestIntrospectionRejectsDeletedUsers(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "resource-server", Name: "Resource Server", RedirectURI: "https://rs.example.com/callback", Scopes: "openid"})
+	accessToken := h.exchangeCode(t, client, []string{"openid"}).AccessToken
+
+	introspect := func() bool {
+		t.Helper()
+		response, err := h.oauth.IntrospectToken(h.ctx, accessToken, client.ClientID, testClientSecret)
+		if err != nil {
+			t.Fatalf("introspect token: %v", err)
+		}
+		return response.Active
+	}
+	if !introspect() {
+		t.Fatal("expected the access token to be active")
+	}
+
+	// 申请删除账户后，宽限期内的访问令牌同样视为无效
+	deletionAt := time.Now().Add(30 * 24 * time.Hour)
+	h.alice.DeletionScheduledAt = &deletionAt
+	if err := h.users.Update(h.alice); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	if introspect() {
+		t.Fatal("expected the access token of a user pending deletion to be inactive")
+	}
+
+	if err := h.users.Delete(h.alice.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	if introspect() {
+		t.Fatal("expected the access token of a deleted user to be inactive")
+	}
+}
+
+func TestRefreshRejectsInactiveAndDeletedUsers(t *testing.T) {
+	h := newRealmHarness(t)
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid offline_access"})
+	refresh := func(refreshToken string) error {
+		t.Helper()
+		_, err := h.oauth.RefreshAccessToken(h.ctx, refreshToken, client.ClientID, testClientSecret)
+		return err
+	}
+
+	// 停用的用户不能刷新，恢复后原刷新令牌仍然可用
+	issued := h.exchangeCode(t, client, []string{"openid", "offline_access"})
+	h.alice.IsActive = false
+	if err := h.users.Update(h.alice); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	requireOAuthErrorCode(t, refresh(issued.RefreshToken), service.ErrCodeInvalidGrant)
+	h.alice.IsActive = true
+	if err := h.users.Update(h.alice); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	if err := refresh(issued.RefreshToken); err != nil {
+		t.Fatalf("refresh for an active user: %v", err)
+	}
+
+	// 申请删除账户后宽限期内同样不能刷新
+	issued = h.exchangeCode(t, client, []string{"openid", "offline_access"})
+	deletionAt := time.Now().Add(30 * 24 * time.Hour)
+	h.alice.DeletionScheduledAt = &deletionAt
+	if err := h.users.Update(h.alice); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	requireOAuthErrorCode(t, refresh(issued.RefreshToken), service.ErrCodeInvalidGrant)
+
+	if err := h.users.Delete(h.alice.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	requireOAuthErrorCode(t, refresh(issued.RefreshToken), service.ErrCodeInvalidGrant)
+}
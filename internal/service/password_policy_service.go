@@ -0,0 +1,42 @@
+package service
+
+// 密码策略错误码，前端据此提示用户
+const (
+	PasswordErrTooShort         = "password_too_short"
+	PasswordErrTooLong          = "password_too_long"
+	PasswordErrCharacterClasses = "password_character_classes"
+	PasswordErrCommon           = "password_common"
+	PasswordErrUserInfo         = "password_contains_user_info"
+	PasswordErrBreached         = "password_breached"
+)
+
+// PasswordPolicyError 密码不符合realm的密码策略
+type PasswordPolicyError struct {
+	Code    string // 错误码，取值见PasswordErr*
+	Message string
+}
+
+// Error 返回面向用户的错误信息
+func (e *PasswordPolicyError) Error() string {
+	return e.Message
+}
+
+// PasswordPolicy 对某个realm生效的密码策略
+type PasswordPolicy struct {
+	MinLength            int  `json:"min_length"`
+	MaxLength            int  `json:"max_length"`
+	MinCharacterClasses  int  `json:"min_character_classes"`
+	BlockCommonPasswords bool `json:"block_common_passwords"`
+	BlockUserInfo        bool `json:"block_user_info"`
+	CheckBreached        bool `json:"check_breached"`
+}
+
+// PasswordPolicyService 密码策略服务接口
+type PasswordPolicyService interface {
+	// Policy 获取realm生效的密码策略，供前端展示密码要求
+	Policy() *PasswordPolicy
+
+	// Validate 按密码策略校验新密码，username和email用于拒绝与用户信息相似的密码；
+	// 不符合时返回*PasswordPolicyError
+	Validate(password, username, email string) error
+}
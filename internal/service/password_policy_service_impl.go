@@ -0,0 +1,149 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// 全局默认密码策略，最短长度和字符类别数可通过环境变量覆盖
+const (
+	defaultPasswordMinLength           = 8
+	defaultPasswordMinCharacterClasses = 1
+	passwordMaxLength                  = 128
+
+	// passwordUserInfoMinLength 用户名或邮箱名至少包含这么多字符时才检查相似度，避免过短的用户名误伤
+	passwordUserInfoMinLength = 3
+)
+
+// passwordPolicyService 密码策略服务实现
+type passwordPolicyService struct {
+	policy          *PasswordPolicy
+	blocklist       map[string]struct{}
+	breachedChecker util.BreachedPasswordChecker
+}
+
+// NewPasswordPolicyService 创建realm范围内的PasswordPolicyService实例
+// 策略按 realm`password_policy` > 环境变量 > 默认值 的优先级生效；breachedChecker为nil时不检查泄露密码
+func NewPasswordPolicyService(realm *model.Realm, blocklist map[string]struct{}, breachedChecker util.BreachedPasswordChecker) PasswordPolicyService {
+	policy := &PasswordPolicy{
+		MinLength:            envInt("PASSWORD_MIN_LENGTH", defaultPasswordMinLength),
+		MaxLength:            passwordMaxLength,
+		MinCharacterClasses:  envInt("PASSWORD_MIN_CHARACTER_CLASSES", defaultPasswordMinCharacterClasses),
+		BlockCommonPasswords: true,
+		BlockUserInfo:        true,
+		CheckBreached:        breachedChecker != nil,
+	}
+
+	if realm != nil {
+		override := &realm.PasswordPolicy
+		if override.MinLength > 0 {
+			policy.MinLength = override.MinLength
+		}
+		if override.MinCharacterClasses > 0 {
+			policy.MinCharacterClasses = override.MinCharacterClasses
+		}
+		if override.BlockCommonPasswords != nil {
+			policy.BlockCommonPasswords = *override.BlockCommonPasswords
+		}
+		if override.BlockUserInfo != nil {
+			policy.BlockUserInfo = *override.BlockUserInfo
+		}
+		if override.CheckBreached != nil {
+			policy.CheckBreached = *override.CheckBreached && breachedChecker != nil
+		}
+	}
+	if policy.MinLength > policy.MaxLength {
+		policy.MinLength = policy.MaxLength
+	}
+	if policy.MinCharacterClasses > 4 {
+		policy.MinCharacterClasses = 4
+	}
+
+	return &passwordPolicyService{
+		policy:          policy,
+		blocklist:       blocklist,
+		breachedChecker: breachedChecker,
+	}
+}
+
+// Policy 获取realm生效的密码策略
+func (s *passwordPolicyService) Policy() *PasswordPolicy {
+	policy := *s.policy
+	return &policy
+}
+
+// Validate 依次检查长度、字符类别、常见密码、用户信息和泄露密码库
+func (s *passwordPolicyService) Validate(password, username, email string) error {
+	length := utf8.RuneCountInString(password)
+	if length < s.policy.MinLength {
+		return &PasswordPolicyError{Code: PasswordErrTooShort, Message: fmt.Sprintf("密码长度不能少于%d个字符", s.policy.MinLength)}
+	}
+	if length > s.policy.MaxLength {
+		return &PasswordPolicyError{Code: PasswordErrTooLong, Message: fmt.Sprintf("密码长度不能超过%d个字符", s.policy.MaxLength)}
+	}
+
+	if passwordCharacterClasses(password) < s.policy.MinCharacterClasses {
+		return &PasswordPolicyError{
+			Code:    PasswordErrCharacterClasses,
+			Message: fmt.Sprintf("密码需要包含小写字母、大写字母、数字和其他字符中的至少%d类", s.policy.MinCharacterClasses),
+		}
+	}
+
+	lower := strings.ToLower(password)
+	if s.policy.BlockCommonPasswords {
+		if _, blocked := s.blocklist[lower]; blocked {
+			return &PasswordPolicyError{Code: PasswordErrCommon, Message: "密码过于常见，请换一个更难猜测的密码"}
+		}
+	}
+
+	if s.policy.BlockUserInfo {
+		emailName := strings.SplitN(email, "@", 2)[0]
+		for _, info := range []string{username, emailName} {
+			info = strings.ToLower(strings.TrimSpace(info))
+			if utf8.RuneCountInString(info) >= passwordUserInfoMinLength && strings.Contains(lower, info) {
+				return &PasswordPolicyError{Code: PasswordErrUserInfo, Message: "密码不能包含用户名或邮箱"}
+			}
+		}
+	}
+
+	if s.policy.CheckBreached && s.breachedChecker != nil {
+		breached, err := s.breachedChecker.IsBreached(password)
+		if err != nil {
+			// 泄露密码库不可读时跳过检查，避免因此无法注册或重置密码
+			fmt.Printf("警告: 无法检查泄露密码库: %v\n", err)
+		} else if breached {
+			return &PasswordPolicyError{Code: PasswordErrBreached, Message: "该密码已出现在公开泄露的密码库中，请换一个密码"}
+		}
+	}
+
+	return nil
+}
+
+// passwordCharacterClasses 统计密码包含的字符类别数：小写字母、大写字母、数字、其他字符
+func passwordCharacterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
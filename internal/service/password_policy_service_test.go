@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// breachedPassword 测试用泄露密码库中收录的密码
+const breachedPassword = "Correct-Horse-9"
+
+// sha1Hex 计算密码的大写十六进制SHA-1哈希
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// assertPasswordErrorCode 断言err为指定错误码的*PasswordPolicyError，code为空表示应当通过校验
+func assertPasswordErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+	if code == "" {
+		if err != nil {
+			t.Fatalf("expected password to be accepted, got %v", err)
+		}
+		return
+	}
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) || policyErr.Code != code {
+		t.Fatalf("expected error code %s, got %v", code, err)
+	}
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "10")
+	t.Setenv("PASSWORD_MIN_CHARACTER_CLASSES", "3")
+
+	h := newRealmHarness(t)
+
+	cases := []struct {
+		password string
+		code     string
+	}{
+		{"Ab1-short", service.PasswordErrTooShort},
+		{strings.Repeat("Ab1-", 33), service.PasswordErrTooLong},
+		{"lowercaseonly1", service.PasswordErrCharacterClasses},
+		{"Alice-Rocks-2024", service.PasswordErrUserInfo},
+		{"MyWonderland-77", service.PasswordErrUserInfo},
+		{"Tsukimi-Dango-42", ""},
+	}
+	for _, tc := range cases {
+		err := h.passwords.Validate(tc.password, h.alice.Username, "wonderland@example.com")
+		assertPasswordErrorCode(t, err, tc.code)
+	}
+}
+
+func TestPasswordPolicyRealmOverride(t *testing.T) {
+	h := newRealmHarness(t)
+	disabled := false
+	h.realm.PasswordPolicy = model.PasswordPolicy{
+		MinLength:     6,
+		BlockUserInfo: &disabled,
+	}
+	policyService := service.NewPasswordPolicyService(h.realm, h.blocklist, nil)
+
+	policy := policyService.Policy()
+	if policy.MinLength != 6 || policy.BlockUserInfo || !policy.BlockCommonPasswords || policy.CheckBreached {
+		t.Fatalf("unexpected resolved policy: %+v", policy)
+	}
+
+	// 常见密码检查不区分大小写
+	assertPasswordErrorCode(t, policyService.Validate("Password", "bob", "bob@example.com"), service.PasswordErrCommon)
+	assertPasswordErrorCode(t, policyService.Validate("bobby-the-cat", "bob", "bob@example.com"), "")
+}
+
+func TestPasswordPolicyBreachedCheck(t *testing.T) {
+	h := newRealmHarness(t)
+	hash := sha1Hex(breachedPassword)
+	prefix, suffix := hash[:5], hash[5:]
+
+	// 单文件格式：每行为完整哈希和出现次数
+	dir := t.TempDir()
+	rangeFile := filepath.Join(dir, "pwned.txt")
+	content := "0000000000000000000000000000000000000000:3\n" + hash + ":42\n"
+	if err := os.WriteFile(rangeFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("write breached password file: %v", err)
+	}
+
+	// 目录格式：以前缀命名的分段文件，每行为后缀和出现次数
+	rangeDir := filepath.Join(dir, "ranges")
+	if err := os.Mkdir(rangeDir, 0o700); err != nil {
+		t.Fatalf("create range dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(rangeDir, prefix), []byte(strings.ToLower(suffix)+":42\r\n"), 0o600); err != nil {
+		t.Fatalf("write range file: %v", err)
+	}
+
+	for _, path := range []string{rangeFile, rangeDir} {
+		checker, err := util.NewBreachedPasswordChecker(path)
+		if err != nil {
+			t.Fatalf("load breached password database %s: %v", path, err)
+		}
+		policyService := service.NewPasswordPolicyService(h.realm, nil, checker)
+
+		assertPasswordErrorCode(t, policyService.Validate(breachedPassword, h.alice.Username, h.alice.Email), service.PasswordErrBreached)
+		assertPasswordErrorCode(t, policyService.Validate("Not-In-The-List-7", h.alice.Username, h.alice.Email), "")
+	}
+}
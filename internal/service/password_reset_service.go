@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidPasswordResetToken 重置密码令牌无效、已使用或已过期
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+// PasswordResetService 忘记密码服务接口
+type PasswordResetService interface {
+	// RequestReset 在后台为邮箱对应的用户生成重置令牌并通过邮件发送；无论邮箱是否注册都立即返回nil，响应内容和耗时都不暴露邮箱是否存在
+	RequestReset(ctx context.Context, email string) error
+
+	// ResetPassword 校验重置令牌并设置新密码，成功后撤销用户的全部刷新令牌和登录会话
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// defaultPasswordResetExpiry 重置密码令牌的默认有效期
+const defaultPasswordResetExpiry = 30 * time.Minute
+
+// passwordResetService 忘记密码服务实现
+type passwordResetService struct {
+	tokenRepo      repository.PasswordResetTokenRepository
+	userRepo       repository.UserRepository
+	refreshRepo    repository.RefreshTokenRepository
+	sessionService SessionService
+	emailQueue     util.EmailQueue
+	passwordPolicy PasswordPolicyService
+	passwordHasher util.PasswordHasher
+	auditService   AuditService
+	realm          *model.Realm
+	tokenTTL       time.Duration
+}
+
+// NewPasswordResetService 创建realm范围内的PasswordResetService实例，令牌有效期由PASSWORD_RESET_TOKEN_EXPIRY_SECONDS配置
+func NewPasswordResetService(tokenRepo repository.PasswordResetTokenRepository, userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, sessionService SessionService, emailQueue util.EmailQueue, passwordPolicy PasswordPolicyService, passwordHasher util.PasswordHasher, auditService AuditService, realm *model.Realm) PasswordResetService {
+	return &passwordResetService{
+		tokenRepo:      tokenRepo,
+		userRepo:       userRepo,
+		refreshRepo:    refreshRepo,
+		sessionService: sessionService,
+		emailQueue:     emailQueue,
+		passwordPolicy: passwordPolicy,
+		passwordHasher: passwordHasher,
+		auditService:   auditService,
+		realm:          realm,
+		tokenTTL:       envSeconds("PASSWORD_RESET_TOKEN_EXPIRY_SECONDS", defaultPasswordResetExpiry),
+	}
+}
+
+// RequestReset 在后台生成重置令牌并加入邮件队列，立即返回，响应时间不随邮箱是否注册而变化
+func (s *passwordResetService) RequestReset(ctx context.Context, email string) error {
+	go func() {
+		if err := s.issueResetToken(context.WithoutCancel(ctx), strings.TrimSpace(email)); err != nil {
+			log.Printf("生成重置密码令牌失败: %v", err)
+		}
+	}()
+	return nil
+}
+
+// issueResetToken 为邮箱对应的用户生成重置令牌并加入邮件队列，新令牌生成后该用户之前的令牌全部失效
+func (s *passwordResetService) issueResetToken(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		// 不暴露邮箱是否注册
+		return nil
+	}
+
+	if err := s.tokenRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+
+	token := randomURLToken(32)
+	if err := s.tokenRepo.Create(ctx, &model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashPasswordResetToken(token),
+		ExpiresAt: time.Now().Add(s.tokenTTL),
+	}); err != nil {
+		return fmt.Errorf("failed to save password reset token: %w", err)
+	}
+
+	if err := s.emailQueue.Enqueue(util.EmailQueueItem{
+		Email:    user.Email,
+		Token:    token,
+		BasePath: s.realm.PathPrefix(),
+		Type:     util.EmailTypePasswordReset,
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue password reset email: %w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword 校验重置令牌并设置新密码，新密码不符合密码策略时令牌仍然可用，否则令牌只能使用一次
+func (s *passwordResetService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	resetToken, err := s.tokenRepo.GetByTokenHash(ctx, hashPasswordResetToken(token))
+	if err != nil {
+		return ErrInvalidPasswordResetToken
+	}
+	if resetToken.IsExpired() {
+		_ = s.tokenRepo.DeleteByID(ctx, resetToken.ID)
+		return ErrInvalidPasswordResetToken
+	}
+
+	user, err := s.userRepo.GetByID(resetToken.UserID)
+	if err != nil {
+		_ = s.tokenRepo.DeleteByID(ctx, resetToken.ID)
+		return ErrInvalidPasswordResetToken
+	}
+
+	// 按realm的密码策略校验新密码，用户可以用同一链接换一个密码重试
+	if err := s.passwordPolicy.Validate(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+	_ = s.tokenRepo.DeleteByID(ctx, resetToken.ID)
+
+	hashedPassword, err := s.passwordHasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to save user: %w", err)
+	}
+
+	// 旧密码可能已泄露，让用户在所有设备和客户端上重新登录
+	if err := s.tokenRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+	if err := s.refreshRepo.RevokeByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := s.sessionService.DeleteUserSessions(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:    model.AuditTokenRevoked,
+		UserID:  user.ID,
+		Details: map[string]string{"reason": "password_reset"},
+	})
+	return nil
+}
+
+// hashPasswordResetToken 计算重置令牌的SHA-256哈希，数据库中只保存哈希
+func hashPasswordResetToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
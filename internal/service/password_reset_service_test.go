@@ -0,0 +1,193 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// passwordResetFixture 忘记密码服务及其内存依赖
+type passwordResetFixture struct {
+	ctx         context.Context
+	reset       service.PasswordResetService
+	tokens      repository.PasswordResetTokenRepository
+	users       repository.UserRepository
+	refreshRepo repository.RefreshTokenRepository
+	sessionRepo repository.SessionRepository
+	sessions    service.SessionService
+	emailQueue  util.EmailQueue
+	hasher      util.PasswordHasher
+	user        *model.User
+	sessionID   string
+}
+
+// newPasswordResetFixture 创建忘记密码服务，并为用户准备刷新令牌和登录会话
+func newPasswordResetFixture(t *testing.T) *passwordResetFixture {
+	t.Helper()
+	hasher, err := util.NewPasswordHasher(util.PasswordHashArgon2id, testArgon2Params, 0)
+	if err != nil {
+		t.Fatalf("create hasher: %v", err)
+	}
+	f := &passwordResetFixture{
+		ctx:         context.Background(),
+		tokens:      repository.NewPasswordResetTokenRepository(),
+		users:       repository.NewUserRepository(nil),
+		refreshRepo: repository.NewRefreshTokenRepository(),
+		sessionRepo: repository.NewSessionRepository(0),
+		emailQueue:  util.NewSimpleEmailQueue(),
+		hasher:      hasher,
+	}
+	f.sessions = service.NewSessionService(f.sessionRepo, nil)
+	realm := &model.Realm{Name: "default"}
+	f.reset = service.NewPasswordResetService(f.tokens, f.users, f.refreshRepo, f.sessions, f.emailQueue, service.NewPasswordPolicyService(realm, nil, nil), hasher, nil, realm)
+
+	passwordHash, err := hasher.Hash("Old-Password-1")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	f.user = &model.User{Username: "alice", Email: "alice@example.com", PasswordHash: passwordHash, IsActive: true}
+	if err := f.users.Create(f.user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	now := time.Now()
+	if err := f.refreshRepo.Create(f.ctx, &model.RefreshToken{TokenHash: "refresh-token-hash", UserID: f.user.ID, ClientID: "web", ExpiresAt: now.Add(time.Hour), AbsoluteExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("create refresh token: %v", err)
+	}
+	session, err := f.sessions.CreateSession(f.ctx, f.user.ID, []string{model.AMRPassword})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	f.sessionID = session.ID
+	return f
+}
+
+// requestToken 申请重置密码，等待后台任务将邮件加入队列后返回邮件中的令牌
+func (f *passwordResetFixture) requestToken(t *testing.T) string {
+	t.Helper()
+	if err := f.reset.RequestReset(f.ctx, " alice@example.com "); err != nil {
+		t.Fatalf("request reset: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if item, err := f.emailQueue.Dequeue(); err == nil {
+			if item.Type != util.EmailTypePasswordReset || item.Email != f.user.Email || item.Token == "" {
+				t.Fatalf("unexpected password reset email: %+v", item)
+			}
+			return item.Token
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("expected a password reset email to be enqueued")
+	return ""
+}
+
+// requirePassword 断言用户当前的密码
+func (f *passwordResetFixture) requirePassword(t *testing.T, password string) {
+	t.Helper()
+	user, err := f.users.GetByID(f.user.ID)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if ok, err := f.hasher.Verify(user.PasswordHash, password); err != nil || !ok {
+		t.Fatalf("expected the password to be %q", password)
+	}
+}
+
+func TestPasswordResetRequestForUnknownEmail(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	if err := f.reset.RequestReset(f.ctx, "nobody@example.com"); err != nil {
+		t.Fatalf("unknown email should not return an error, got %v", err)
+	}
+	// 队列中只有已注册邮箱的邮件
+	token := f.requestToken(t)
+	if token == "" {
+		t.Fatal("expected a reset token")
+	}
+	if item, err := f.emailQueue.Dequeue(); err == nil {
+		t.Fatalf("unknown email should not receive a reset email, got %+v", item)
+	}
+}
+
+func TestPasswordResetSucceedsOnceAndRevokesSessions(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	token := f.requestToken(t)
+
+	if err := f.reset.ResetPassword(f.ctx, token, "Tsukimi-Dango-42"); err != nil {
+		t.Fatalf("reset password: %v", err)
+	}
+	f.requirePassword(t, "Tsukimi-Dango-42")
+
+	// 旧密码可能已泄露，刷新令牌和会话全部失效
+	refreshToken, _ := f.refreshRepo.GetByTokenHash(f.ctx, "refresh-token-hash")
+	if refreshToken == nil || refreshToken.RevokedAt.IsZero() {
+		t.Fatal("refresh tokens should be revoked")
+	}
+	if _, err := f.sessionRepo.GetByID(f.ctx, f.sessionID); err == nil {
+		t.Fatal("sessions should be deleted")
+	}
+
+	// 令牌只能使用一次
+	if err := f.reset.ResetPassword(f.ctx, token, "Another-Dango-43"); !errors.Is(err, service.ErrInvalidPasswordResetToken) {
+		t.Fatalf("expected ErrInvalidPasswordResetToken, got %v", err)
+	}
+	f.requirePassword(t, "Tsukimi-Dango-42")
+}
+
+func TestPasswordResetTokenSurvivesPolicyFailure(t *testing.T) {
+	f := newPasswordResetFixture(t)
+	token := f.requestToken(t)
+
+	var policyErr *service.PasswordPolicyError
+	if err := f.reset.ResetPassword(f.ctx, token, "short"); !errors.As(err, &policyErr) {
+		t.Fatalf("expected a password policy error, got %v", err)
+	}
+	f.requirePassword(t, "Old-Password-1")
+	if _, err := f.sessionRepo.GetByID(f.ctx, f.sessionID); err != nil {
+		t.Fatalf("sessions should be kept after a policy failure, got %v", err)
+	}
+
+	// 同一链接换一个符合策略的密码重试
+	if err := f.reset.ResetPassword(f.ctx, token, "Tsukimi-Dango-42"); err != nil {
+		t.Fatalf("retry reset password: %v", err)
+	}
+	f.requirePassword(t, "Tsukimi-Dango-42")
+	if err := f.reset.ResetPassword(f.ctx, token, "short"); !errors.Is(err, service.ErrInvalidPasswordResetToken) {
+		t.Fatalf("expected the used token to be rejected before the policy check, got %v", err)
+	}
+}
+
+func TestPasswordResetRejectsExpiredAndSupersededTokens(t *testing.T) {
+	f := newPasswordResetFixture(t)
+
+	expired := "expired-reset-token"
+	hash := sha256.Sum256([]byte(expired))
+	if err := f.tokens.Create(f.ctx, &model.PasswordResetToken{UserID: f.user.ID, TokenHash: hex.EncodeToString(hash[:]), ExpiresAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if err := f.reset.ResetPassword(f.ctx, expired, "Tsukimi-Dango-42"); !errors.Is(err, service.ErrInvalidPasswordResetToken) {
+		t.Fatalf("expected an expired token to be rejected, got %v", err)
+	}
+	if err := f.reset.ResetPassword(f.ctx, "unknown-token", "Tsukimi-Dango-42"); !errors.Is(err, service.ErrInvalidPasswordResetToken) {
+		t.Fatalf("expected an unknown token to be rejected, got %v", err)
+	}
+
+	// 新令牌生成后之前的令牌失效
+	first := f.requestToken(t)
+	second := f.requestToken(t)
+	if err := f.reset.ResetPassword(f.ctx, first, "Tsukimi-Dango-42"); !errors.Is(err, service.ErrInvalidPasswordResetToken) {
+		t.Fatalf("expected a superseded token to be rejected, got %v", err)
+	}
+	if err := f.reset.ResetPassword(f.ctx, second, "Tsukimi-Dango-42"); err != nil {
+		t.Fatalf("reset password with the latest token: %v", err)
+	}
+	f.requirePassword(t, "Tsukimi-Dango-42")
+}
@@ -0,0 +1,164 @@
+package service_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+// testCodeVerifier 符合长度与字符集要求的code_verifier (RFC 7636 附录B)
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+// s256Challenge 计算code_verifier的S256 code_challenge
+func s256Challenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// addPKCEClients 登记公开客户端、默认机密客户端和要求PKCE的机密客户端
+func addPKCEClients(t *testing.T, h *realmHarness) (public, confidential, strict *model.Client) {
+	t.Helper()
+	requirePKCE := true
+	public = h.addClient(t, &model.Client{ClientID: "native-app", Name: "Native App", RedirectURI: "http://127.0.0.1/callback", Scopes: "openid", TokenEndpointAuthMethod: model.TokenEndpointAuthNone})
+	confidential = h.addClient(t, &model.Client{ClientID: "web-app", Name: "Web App", RedirectURI: "https://web.example.com/callback", Scopes: "openid"})
+	strict = h.addClient(t, &model.Client{ClientID: "strict-app", Name: "Strict App", RedirectURI: "https://strict.example.com/callback", Scopes: "openid", RequirePKCE: &requirePKCE})
+	return public, confidential, strict
+}
+
+func TestAuthorizationRequestPKCE(t *testing.T) {
+	h := newRealmHarness(t)
+	t.Setenv("REQUIRE_PKCE", "")
+	public, confidential, strict := addPKCEClients(t, h)
+
+	validChallenge := s256Challenge(testCodeVerifier)
+	tests := []struct {
+		name      string
+		client    *model.Client
+		challenge string
+		method    string
+		valid     bool
+	}{
+		{"public without challenge", public, "", "", false},
+		{"public S256", public, validChallenge, "S256", true},
+		{"public plain", public, testCodeVerifier, "plain", false},
+		{"public default method is plain", public, testCodeVerifier, "", false},
+		{"public unsupported method", public, validChallenge, "S512", false},
+		{"confidential without challenge", confidential, "", "", true},
+		{"confidential S256", confidential, validChallenge, "S256", true},
+		{"confidential plain", confidential, testCodeVerifier, "plain", true},
+		{"require_pkce without challenge", strict, "", "", false},
+		{"require_pkce S256", strict, validChallenge, "S256", true},
+		{"challenge 42 characters", confidential, strings.Repeat("a", 42), "plain", false},
+		{"challenge 43 characters", confidential, strings.Repeat("a", 43), "plain", true},
+		{"challenge 128 characters", confidential, strings.Repeat("a", 128), "plain", true},
+		{"challenge 129 characters", confidential, strings.Repeat("a", 129), "plain", false},
+		{"challenge unreserved characters", confidential, strings.Repeat("aZ9-._~", 7), "plain", true},
+		{"challenge padding", confidential, validChallenge + "=", "S256", false},
+		{"challenge plus sign", confidential, strings.Repeat("a", 42) + "+", "plain", false},
+		{"challenge non-ascii", confidential, strings.Repeat("a", 42) + "é", "plain", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &service.AuthorizationRequest{
+				ClientID:     tt.client.ClientID,
+				RedirectURI:  tt.client.RedirectURI,
+				ResponseType: "code",
+				Scopes:       []string{"openid"},
+			}
+			if tt.challenge != "" {
+				challenge := tt.challenge
+				req.CodeChallenge = &challenge
+			}
+			if tt.method != "" {
+				method := tt.method
+				req.CodeChallengeMethod = &method
+			}
+
+			_, err := h.oauth.PushAuthorizationRequest(h.ctx, tt.client, req, "")
+			if tt.valid && err != nil {
+				t.Fatalf("expected the request to be accepted, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected the request to be rejected")
+			}
+		})
+	}
+}
+
+func TestRequirePKCEEnvironment(t *testing.T) {
+	h := newRealmHarness(t)
+	t.Setenv("REQUIRE_PKCE", "")
+	_, confidential, _ := addPKCEClients(t, h)
+	t.Setenv("REQUIRE_PKCE", "true")
+
+	req := &service.AuthorizationRequest{ClientID: confidential.ClientID, RedirectURI: confidential.RedirectURI, ResponseType: "code", Scopes: []string{"openid"}}
+	if _, err := h.oauth.PushAuthorizationRequest(h.ctx, confidential, req, ""); err == nil {
+		t.Fatal("REQUIRE_PKCE=true should require code_challenge from confidential clients")
+	}
+}
+
+func TestExchangeAuthorizationCodePKCE(t *testing.T) {
+	h := newRealmHarness(t)
+	t.Setenv("REQUIRE_PKCE", "")
+	public, confidential, strict := addPKCEClients(t, h)
+
+	s256 := "S256"
+	plain := "plain"
+	challenge := s256Challenge(testCodeVerifier)
+	shortVerifier := strings.Repeat("a", 42)
+	shortChallenge := s256Challenge(shortVerifier)
+	wrongVerifier := strings.Repeat("b", 43)
+
+	tests := []struct {
+		name      string
+		client    *model.Client
+		challenge *string
+		method    *string
+		verifier  *string
+		errCode   string
+	}{
+		{"public S256", public, &challenge, &s256, strPtr(testCodeVerifier), ""},
+		{"public mismatched verifier", public, &challenge, &s256, &wrongVerifier, service.ErrCodeInvalidGrant},
+		{"public missing verifier", public, &challenge, &s256, nil, service.ErrCodeInvalidRequest},
+		{"public code without challenge", public, nil, nil, nil, service.ErrCodeInvalidGrant},
+		{"public plain code", public, strPtr(testCodeVerifier), &plain, strPtr(testCodeVerifier), service.ErrCodeInvalidGrant},
+		{"verifier shorter than 43 characters", public, &shortChallenge, &s256, &shortVerifier, service.ErrCodeInvalidGrant},
+		{"verifier with invalid characters", public, strPtr(s256Challenge(testCodeVerifier + "+")), &s256, strPtr(testCodeVerifier + "+"), service.ErrCodeInvalidGrant},
+		{"confidential without PKCE", confidential, nil, nil, nil, ""},
+		{"confidential plain", confidential, strPtr(testCodeVerifier), &plain, strPtr(testCodeVerifier), ""},
+		{"confidential mismatched plain verifier", confidential, strPtr(testCodeVerifier), &plain, &wrongVerifier, service.ErrCodeInvalidGrant},
+		{"require_pkce code without challenge", strict, nil, nil, nil, service.ErrCodeInvalidGrant},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := h.oauth.GenerateAuthorizationCode(h.ctx, tt.client, h.alice.ID, tt.client.RedirectURI, []string{"openid"}, tt.challenge, tt.method)
+			if err != nil {
+				t.Fatalf("generate authorization code: %v", err)
+			}
+			secret := testClientSecret
+			if tt.client.IsPublic() {
+				secret = ""
+			}
+
+			response, err := h.oauth.ExchangeAuthorizationCode(h.ctx, code, tt.client.ClientID, secret, tt.client.RedirectURI, tt.verifier, nil)
+			if tt.errCode == "" {
+				if err != nil || response.AccessToken == "" {
+					t.Fatalf("expected tokens, got %v", err)
+				}
+				return
+			}
+			requireOAuthErrorCode(t, err, tt.errCode)
+		})
+	}
+}
+
+// strPtr 返回字符串指针
+func strPtr(value string) *string {
+	return &value
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 用户资料错误，处理器据此选择HTTP状态码
+var (
+	ErrInvalidNickname = errors.New("昵称长度必须在1-50个字符之间")
+	ErrBioTooLong      = errors.New("个人简介不能超过500个字符")
+	ErrInvalidAvatar   = errors.New("头像必须是JPEG、PNG或GIF图片")
+	ErrAvatarTooLarge  = errors.New("头像文件或图片尺寸过大")
+)
+
+// Profile 用户资料，avatar_url在未上传头像时为默认identicon头像
+type Profile struct {
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Nickname      string    `json:"nickname"`
+	Bio           string    `json:"bio"`
+	AvatarURL     string    `json:"avatar_url"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"` // 账户计划删除的时间，未申请删除时省略
+}
+
+// ProfileUpdate 部分更新用户资料，nil字段保持不变
+type ProfileUpdate struct {
+	Nickname *string
+	Bio      *string
+}
+
+// ProfileService 用户资料服务接口
+type ProfileService interface {
+	// GetProfile 获取用户资料
+	GetProfile(ctx context.Context, userID uint) (*Profile, error)
+
+	// UpdateProfile 更新昵称和个人简介，校验失败时返回ErrInvalidNickname或ErrBioTooLong
+	UpdateProfile(ctx context.Context, userID uint, update ProfileUpdate) (*Profile, error)
+
+	// UploadAvatar 校验上传的图片，重新编码并缩放后保存为用户头像，旧头像随之删除
+	UploadAvatar(ctx context.Context, userID uint, data []byte) (*Profile, error)
+
+	// DeleteAvatar 删除上传的头像，恢复为默认identicon头像
+	DeleteAvatar(ctx context.Context, userID uint) (*Profile, error)
+
+	// Identicon 生成用户的默认identicon头像（PNG），不校验用户是否存在
+	Identicon(userID uint) ([]byte, error)
+}
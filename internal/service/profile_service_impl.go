@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+	"github.com/Full-finger/OIDC/internal/helper"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// 用户资料限制
+const (
+	profileNicknameMaxLength = 50
+	profileBioMaxLength      = 500
+
+	defaultAvatarMaxBytes = 5 << 20
+	defaultAvatarSize     = 256
+)
+
+// profileService 用户资料服务实现
+type profileService struct {
+	userRepo      repository.UserRepository
+	userHelper    helper.UserHelper
+	avatarStorage util.AvatarStorage
+	realm         *model.Realm
+	avatarSize    int
+}
+
+// NewProfileService 创建realm范围内的ProfileService实例，头像边长由AVATAR_SIZE配置
+func NewProfileService(userRepo repository.UserRepository, userHelper helper.UserHelper, avatarStorage util.AvatarStorage, realm *model.Realm) ProfileService {
+	return &profileService{
+		userRepo:      userRepo,
+		userHelper:    userHelper,
+		avatarStorage: avatarStorage,
+		realm:         realm,
+		avatarSize:    envInt("AVATAR_SIZE", defaultAvatarSize),
+	}
+}
+
+// AvatarMaxBytes 允许上传的头像文件大小上限，由AVATAR_MAX_BYTES配置
+func AvatarMaxBytes() int64 {
+	return int64(envInt("AVATAR_MAX_BYTES", defaultAvatarMaxBytes))
+}
+
+// GetProfile 获取用户资料
+func (s *profileService) GetProfile(ctx context.Context, userID uint) (*Profile, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return s.toProfile(ctx, user), nil
+}
+
+// UpdateProfile 更新昵称和个人简介
+func (s *profileService) UpdateProfile(ctx context.Context, userID uint, update ProfileUpdate) (*Profile, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if update.Nickname != nil {
+		nickname := strings.TrimSpace(*update.Nickname)
+		if nickname == "" || utf8.RuneCountInString(nickname) > profileNicknameMaxLength {
+			return nil, ErrInvalidNickname
+		}
+		user.Nickname = nickname
+	}
+	if update.Bio != nil {
+		bio := strings.TrimSpace(*update.Bio)
+		if utf8.RuneCountInString(bio) > profileBioMaxLength {
+			return nil, ErrBioTooLong
+		}
+		user.Bio = bio
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+	return s.toProfile(ctx, user), nil
+}
+
+// UploadAvatar 保存新头像后再删除旧头像，每次上传使用新的key，避免CDN和浏览器缓存旧图片
+func (s *profileService) UploadAvatar(ctx context.Context, userID uint, data []byte) (*Profile, error) {
+	if int64(len(data)) > AvatarMaxBytes() {
+		return nil, ErrAvatarTooLarge
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if s.avatarStorage == nil {
+		return nil, errors.New("avatar storage is not configured")
+	}
+
+	avatar, err := util.ProcessAvatar(data, s.avatarSize)
+	if errors.Is(err, util.ErrImageTooLarge) {
+		return nil, ErrAvatarTooLarge
+	}
+	if errors.Is(err, util.ErrUnsupportedImage) {
+		return nil, ErrInvalidAvatar
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s/%d/%s.png", s.realm.Name, user.ID, randomURLToken(16))
+	avatarURL, err := s.avatarStorage.Put(ctx, key, "image/png", avatar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store avatar: %w", err)
+	}
+
+	oldKey := user.AvatarKey
+	user.AvatarURL = avatarURL
+	user.AvatarKey = key
+	if err := s.userRepo.Update(user); err != nil {
+		_ = s.avatarStorage.Delete(ctx, key)
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+	s.deleteStoredAvatar(ctx, oldKey)
+
+	return s.toProfile(ctx, user), nil
+}
+
+// DeleteAvatar 删除上传的头像
+func (s *profileService) DeleteAvatar(ctx context.Context, userID uint) (*Profile, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	oldKey := user.AvatarKey
+	user.AvatarURL = ""
+	user.AvatarKey = ""
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+	s.deleteStoredAvatar(ctx, oldKey)
+
+	return s.toProfile(ctx, user), nil
+}
+
+// Identicon 生成用户的默认identicon头像
+func (s *profileService) Identicon(userID uint) ([]byte, error) {
+	return s.userHelper.GenerateIdenticon(userID)
+}
+
+// deleteStoredAvatar 删除存储中的旧头像，失败时只记录日志，不影响资料更新
+func (s *profileService) deleteStoredAvatar(ctx context.Context, key string) {
+	if key == "" || s.avatarStorage == nil {
+		return
+	}
+	if err := s.avatarStorage.Delete(ctx, key); err != nil {
+		fmt.Printf("警告: 无法删除旧头像 %s: %v\n", key, err)
+	}
+}
+
+// toProfile 转换为用户资料，未上传头像（或上游身份提供方未提供头像）时使用默认identicon头像
+func (s *profileService) toProfile(ctx context.Context, user *model.User) *Profile {
+	avatarURL := user.AvatarURL
+	if avatarURL == "" {
+		avatarURL = s.userHelper.GenerateAvatarURL(util.IssuerFromContext(ctx), user.ID)
+	}
+	return &Profile{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Nickname:      user.Nickname,
+		Bio:           user.Bio,
+		AvatarURL:     avatarURL,
+		CreatedAt:     user.CreatedAt,
+
+		DeletionScheduledAt: user.DeletionScheduledAt,
+	}
+}
@@ -0,0 +1,261 @@
+package service_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/helper"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// fakeS3 S3兼容对象存储的本地替身，校验签名头并在内存中保存对象
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	payloadHash := sha256.Sum256(body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") ||
+		r.Header.Get("X-Amz-Date") == "" ||
+		r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(payloadHash[:]) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[r.URL.Path] = body
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// testImage 生成指定尺寸和格式的测试图片
+func testImage(t *testing.T, width, height int, format string) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatalf("encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// profileFixture 在共用依赖之上使用指定头像存储的用户资料服务
+type profileFixture struct {
+	*realmHarness
+	profile service.ProfileService
+}
+
+func newProfileFixture(t *testing.T, storage util.AvatarStorage) *profileFixture {
+	t.Helper()
+	h := newRealmHarness(t)
+	return &profileFixture{realmHarness: h, profile: service.NewProfileService(h.users, helper.NewUserHelper(h.hasher), storage, h.realm)}
+}
+
+func TestProfileUpdateAndDefaultAvatar(t *testing.T) {
+	f := newProfileFixture(t, nil)
+
+	profile, err := f.profile.GetProfile(f.ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("get profile: %v", err)
+	}
+	if profile.AvatarURL != fmt.Sprintf("%s/avatars/identicon/%d", testIssuer, f.alice.ID) {
+		t.Fatalf("expected default identicon avatar, got %s", profile.AvatarURL)
+	}
+
+	// 只更新提供的字段
+	bio := "  喜欢看番  "
+	profile, err = f.profile.UpdateProfile(f.ctx, f.alice.ID, service.ProfileUpdate{Bio: &bio})
+	if err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	if profile.Bio != "喜欢看番" || profile.Nickname != "Alice" {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+
+	empty := " "
+	if _, err := f.profile.UpdateProfile(f.ctx, f.alice.ID, service.ProfileUpdate{Nickname: &empty}); !errors.Is(err, service.ErrInvalidNickname) {
+		t.Fatalf("expected invalid nickname, got %v", err)
+	}
+
+	// 同一用户的identicon保持不变
+	first, err := f.profile.Identicon(f.alice.ID)
+	if err != nil {
+		t.Fatalf("generate identicon: %v", err)
+	}
+	second, _ := f.profile.Identicon(f.alice.ID)
+	other, _ := f.profile.Identicon(f.alice.ID + 1)
+	if !bytes.Equal(first, second) || bytes.Equal(first, other) {
+		t.Fatalf("identicon should be deterministic per user")
+	}
+}
+
+func TestProfileAvatarUploadToS3(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	storage, err := util.NewS3AvatarStorage(util.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "avatars",
+		AccessKeyID:     "test-key",
+		SecretAccessKey: "test-secret",
+		PublicURL:       "https://cdn.example.com",
+	})
+	if err != nil {
+		t.Fatalf("create S3 storage: %v", err)
+	}
+	f := newProfileFixture(t, storage)
+
+	if _, err := f.profile.UploadAvatar(f.ctx, f.alice.ID, []byte("not an image")); !errors.Is(err, service.ErrInvalidAvatar) {
+		t.Fatalf("expected invalid avatar, got %v", err)
+	}
+
+	profile, err := f.profile.UploadAvatar(f.ctx, f.alice.ID, testImage(t, 600, 400, "jpeg"))
+	if err != nil {
+		t.Fatalf("upload avatar: %v", err)
+	}
+	if !strings.HasPrefix(profile.AvatarURL, fmt.Sprintf("https://cdn.example.com/%s/%d/", f.realm.Name, f.alice.ID)) {
+		t.Fatalf("unexpected avatar url: %s", profile.AvatarURL)
+	}
+	objectPath := "/avatars/" + strings.TrimPrefix(profile.AvatarURL, "https://cdn.example.com/")
+	stored, ok := fake.objects[objectPath]
+	if !ok {
+		t.Fatalf("avatar was not stored at %s", objectPath)
+	}
+
+	// 重新编码为256×256的PNG
+	config, format, err := image.DecodeConfig(bytes.NewReader(stored))
+	if err != nil || format != "png" || config.Width != 256 || config.Height != 256 {
+		t.Fatalf("unexpected stored avatar: %s %dx%d (%v)", format, config.Width, config.Height, err)
+	}
+
+	// 替换头像后删除旧对象，删除头像后恢复默认头像
+	if _, err := f.profile.UploadAvatar(f.ctx, f.alice.ID, testImage(t, 64, 64, "png")); err != nil {
+		t.Fatalf("replace avatar: %v", err)
+	}
+	if _, ok := fake.objects[objectPath]; ok || len(fake.objects) != 1 {
+		t.Fatalf("old avatar should be deleted, objects: %d", len(fake.objects))
+	}
+	profile, err = f.profile.DeleteAvatar(f.ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("delete avatar: %v", err)
+	}
+	if len(fake.objects) != 0 || profile.AvatarURL != fmt.Sprintf("%s/avatars/identicon/%d", testIssuer, f.alice.ID) {
+		t.Fatalf("avatar should be reset to identicon, got %s", profile.AvatarURL)
+	}
+}
+
+func TestProfileAvatarUploadToLocalDisk(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := util.NewLocalAvatarStorage(dir, "https://id.example.com/uploads/avatars/")
+	if err != nil {
+		t.Fatalf("create local storage: %v", err)
+	}
+	f := newProfileFixture(t, storage)
+
+	profile, err := f.profile.UploadAvatar(f.ctx, f.alice.ID, testImage(t, 32, 48, "png"))
+	if err != nil {
+		t.Fatalf("upload avatar: %v", err)
+	}
+	key := strings.TrimPrefix(profile.AvatarURL, "https://id.example.com/uploads/avatars/")
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(key)))
+	if err != nil {
+		t.Fatalf("read stored avatar: %v", err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width != 256 || config.Height != 256 {
+		t.Fatalf("unexpected stored avatar: %dx%d (%v)", config.Width, config.Height, err)
+	}
+
+	if _, err := storage.Put(f.ctx, "../escape.png", "image/png", data); err == nil {
+		t.Fatalf("keys outside the storage directory should be rejected")
+	}
+}
+
+func TestUserInfoAndIDTokenProfileClaims(t *testing.T) {
+	t.Setenv("PROFILE_PAGE_URL", "https://app.example.com/profile")
+	f := newProfileFixture(t, nil)
+	client := f.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid profile email"})
+
+	// 未上传头像时picture为默认identicon头像
+	identicon := fmt.Sprintf("%s/avatars/identicon/%d", testIssuer, f.alice.ID)
+	response := f.exchangeCode(t, client, []string{"openid", "profile"})
+	userInfo, err := f.oauth.GetUserInfo(f.ctx, response.AccessToken)
+	if err != nil {
+		t.Fatalf("get userinfo: %v", err)
+	}
+	if userInfo.Name != "Alice" || userInfo.Nickname != "Alice" || userInfo.Picture != identicon ||
+		userInfo.Profile != "https://app.example.com/profile?user=alice&issuer=http%3A%2F%2Fid.test" {
+		t.Fatalf("unexpected profile claims: %+v", userInfo)
+	}
+	if userInfo.Email != "" {
+		t.Fatalf("email should not be returned without the email scope, got %s", userInfo.Email)
+	}
+	idToken, err := f.jwtUtil.ParseIDToken(response.IDToken)
+	if err != nil {
+		t.Fatalf("parse id token: %v", err)
+	}
+	if idToken.Name != "Alice" || idToken.Nickname != "Alice" || idToken.Picture != identicon || idToken.Profile != userInfo.Profile {
+		t.Fatalf("unexpected id token profile claims: %+v", idToken)
+	}
+
+	// 上传头像后picture为头像地址，清空昵称后name退回用户名
+	f.alice.Nickname = ""
+	f.alice.AvatarURL = "https://cdn.example.com/default/1/avatar.png"
+	if err := f.users.Update(f.alice); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	userInfo, err = f.oauth.GetUserInfo(f.ctx, f.exchangeCode(t, client, []string{"openid", "profile"}).AccessToken)
+	if err != nil {
+		t.Fatalf("get userinfo: %v", err)
+	}
+	if userInfo.Name != "alice" || userInfo.Nickname != "" || userInfo.Picture != f.alice.AvatarURL {
+		t.Fatalf("unexpected profile claims after update: %+v", userInfo)
+	}
+
+	// 未授予profile scope时不返回资料声明
+	userInfo, err = f.oauth.GetUserInfo(f.ctx, f.exchangeCode(t, client, []string{"openid", "email"}).AccessToken)
+	if err != nil {
+		t.Fatalf("get userinfo: %v", err)
+	}
+	if userInfo.Name != "" || userInfo.Picture != "" || userInfo.Profile != "" || userInfo.Email != "alice@example.com" {
+		t.Fatalf("unexpected claims without the profile scope: %+v", userInfo)
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// UserAccess 用户的角色与用户组
+type UserAccess struct {
+	UserID         uint     `json:"user_id"`
+	Roles          []string `json:"roles"`           // 直接分配的角色
+	Groups         []string `json:"groups"`          // 所属用户组
+	EffectiveRoles []string `json:"effective_roles"` // 直接分配及用户组继承的全部角色
+}
+
+// RBACService 角色与用户组服务接口
+type RBACService interface {
+	// SeedRBAC 写入内置角色及realm配置的角色和用户组
+	SeedRBAC(ctx context.Context, roles []model.Role, groups []model.Group) error
+	
+	// ListRoles 列出所有角色
+	ListRoles(ctx context.Context) ([]*model.Role, error)
+	
+	// CreateRole 创建角色
+	CreateRole(ctx context.Context, role *model.Role) error
+	
+	// DeleteRole 删除角色，并从用户组和用户分配中移除
+	DeleteRole(ctx context.Context, name string) error
+	
+	// ListGroups 列出所有用户组
+	ListGroups(ctx context.Context) ([]*model.Group, error)
+	
+	// GetGroup 根据名称获取用户组，不存在时返回nil
+	GetGroup(ctx context.Context, name string) (*model.Group, error)
+	
+	// CreateGroup 创建用户组
+	CreateGroup(ctx context.Context, group *model.Group) error
+	
+	// UpdateGroup 更新用户组的说明和组内角色
+	UpdateGroup(ctx context.Context, name string, update *model.Group) (*model.Group, error)
+	
+	// DeleteGroup 删除用户组，并移除其成员关系
+	DeleteGroup(ctx context.Context, name string) error
+	
+	// SetUserRoles 设置用户直接分配的角色
+	SetUserRoles(ctx context.Context, userID uint, roles []string) error
+	
+	// SetUserGroups 设置用户所属的用户组
+	SetUserGroups(ctx context.Context, userID uint, groups []string) error
+	
+	// GetUserAccess 获取用户的角色、用户组及有效角色
+	GetUserAccess(ctx context.Context, userID uint) (*UserAccess, error)
+	
+	// EntitledScopes 获取用户的有效角色所允许的scope（如anime:write、admin）
+	EntitledScopes(ctx context.Context, userID uint) ([]string, error)
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+)
+
+// rbacService 角色与用户组服务实现
+type rbacService struct {
+	roleRepo       repository.RoleRepository
+	groupRepo      repository.GroupRepository
+	assignmentRepo repository.UserAssignmentRepository
+}
+
+// NewRBACService 创建RBACService实例
+func NewRBACService(roleRepo repository.RoleRepository, groupRepo repository.GroupRepository, assignmentRepo repository.UserAssignmentRepository) RBACService {
+	return &rbacService{
+		roleRepo:       roleRepo,
+		groupRepo:      groupRepo,
+		assignmentRepo: assignmentRepo,
+	}
+}
+
+// DefaultRoles 内置角色：管理员、版主和番剧目录编辑
+func DefaultRoles() []model.Role {
+	return []model.Role{
+		{Name: "admin", Description: "管理员，拥有全部权限"},
+		{Name: "moderator", Description: "版主，可以修改和删除番剧条目"},
+		{Name: "catalog_editor", Description: "目录编辑，可以创建和修改番剧条目"},
+	}
+}
+
+// SeedRBAC 写入内置角色及realm配置的角色和用户组，配置的角色覆盖同名内置角色
+func (s *rbacService) SeedRBAC(ctx context.Context, roles []model.Role, groups []model.Group) error {
+	overrides := make(map[string]bool)
+	for i := range roles {
+		overrides[roles[i].Name] = true
+		if err := s.CreateRole(ctx, &roles[i]); err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", roles[i].Name, err)
+		}
+	}
+	
+	for _, role := range DefaultRoles() {
+		if overrides[role.Name] {
+			continue
+		}
+		role := role
+		if err := s.CreateRole(ctx, &role); err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", role.Name, err)
+		}
+	}
+	
+	for i := range groups {
+		if err := s.CreateGroup(ctx, &groups[i]); err != nil {
+			return fmt.Errorf("failed to seed group %s: %w", groups[i].Name, err)
+		}
+	}
+	
+	return nil
+}
+
+// ListRoles 列出所有角色
+func (s *rbacService) ListRoles(ctx context.Context) ([]*model.Role, error) {
+	return s.roleRepo.ListAll(ctx)
+}
+
+// getRole 根据名称获取角色，不存在时返回nil
+func (s *rbacService) getRole(ctx context.Context, name string) *model.Role {
+	role, err := s.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil
+	}
+	return role
+}
+
+// CreateRole 创建角色
+func (s *rbacService) CreateRole(ctx context.Context, role *model.Role) error {
+	if err := validateRBACName("role", role.Name); err != nil {
+		return err
+	}
+	
+	if s.getRole(ctx, role.Name) != nil {
+		return fmt.Errorf("role %s already exists", role.Name)
+	}
+	
+	return s.roleRepo.Create(ctx, role)
+}
+
+// DeleteRole 删除角色，并从用户组和用户分配中移除
+func (s *rbacService) DeleteRole(ctx context.Context, name string) error {
+	role := s.getRole(ctx, name)
+	if role == nil {
+		return fmt.Errorf("role %s not found", name)
+	}
+	
+	groups, err := s.groupRepo.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list groups: %w", err)
+	}
+	for _, group := range groups {
+		remaining := removeName(group.RoleList(), name)
+		if len(remaining) == len(group.RoleList()) {
+			continue
+		}
+		group.Roles = strings.Join(remaining, " ")
+		if err := s.groupRepo.Update(ctx, group); err != nil {
+			return fmt.Errorf("failed to update group %s: %w", group.Name, err)
+		}
+	}
+	
+	if err := s.assignmentRepo.DeleteByName(ctx, model.AssignmentKindRole, name); err != nil {
+		return fmt.Errorf("failed to remove role assignments: %w", err)
+	}
+	
+	return s.roleRepo.DeleteByID(ctx, role.ID)
+}
+
+// ListGroups 列出所有用户组
+func (s *rbacService) ListGroups(ctx context.Context) ([]*model.Group, error) {
+	return s.groupRepo.ListAll(ctx)
+}
+
+// GetGroup 根据名称获取用户组
+func (s *rbacService) GetGroup(ctx context.Context, name string) (*model.Group, error) {
+	group, err := s.groupRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, nil
+	}
+	return group, nil
+}
+
+// CreateGroup 创建用户组，组内角色必须已存在
+func (s *rbacService) CreateGroup(ctx context.Context, group *model.Group) error {
+	if err := validateRBACName("group", group.Name); err != nil {
+		return err
+	}
+	if err := s.validateRoles(ctx, group.RoleList()); err != nil {
+		return err
+	}
+	group.Roles = strings.Join(uniqueNames(group.RoleList()), " ")
+	
+	if existing, _ := s.GetGroup(ctx, group.Name); existing != nil {
+		return fmt.Errorf("group %s already exists", group.Name)
+	}
+	
+	return s.groupRepo.Create(ctx, group)
+}
+
+// UpdateGroup 更新用户组的说明和组内角色，名称不可修改
+func (s *rbacService) UpdateGroup(ctx context.Context, name string, update *model.Group) (*model.Group, error) {
+	group, _ := s.GetGroup(ctx, name)
+	if group == nil {
+		return nil, nil
+	}
+	
+	if err := s.validateRoles(ctx, update.RoleList()); err != nil {
+		return nil, err
+	}
+	
+	group.Description = update.Description
+	group.Roles = strings.Join(uniqueNames(update.RoleList()), " ")
+	
+	if err := s.groupRepo.Update(ctx, group); err != nil {
+		return nil, fmt.Errorf("failed to update group: %w", err)
+	}
+	
+	return group, nil
+}
+
+// DeleteGroup 删除用户组，并移除其成员关系
+func (s *rbacService) DeleteGroup(ctx context.Context, name string) error {
+	group, _ := s.GetGroup(ctx, name)
+	if group == nil {
+		return fmt.Errorf("group %s not found", name)
+	}
+	
+	if err := s.assignmentRepo.DeleteByName(ctx, model.AssignmentKindGroup, name); err != nil {
+		return fmt.Errorf("failed to remove group memberships: %w", err)
+	}
+	
+	return s.groupRepo.DeleteByID(ctx, group.ID)
+}
+
+// SetUserRoles 设置用户直接分配的角色，未注册的角色会被拒绝
+func (s *rbacService) SetUserRoles(ctx context.Context, userID uint, roles []string) error {
+	if err := s.validateRoles(ctx, roles); err != nil {
+		return err
+	}
+	return s.assignmentRepo.ReplaceForUser(ctx, userID, model.AssignmentKindRole, uniqueNames(roles))
+}
+
+// SetUserGroups 设置用户所属的用户组，不存在的用户组会被拒绝
+func (s *rbacService) SetUserGroups(ctx context.Context, userID uint, groups []string) error {
+	for _, name := range groups {
+		if group, _ := s.GetGroup(ctx, name); group == nil {
+			return fmt.Errorf("group %s not found", name)
+		}
+	}
+	return s.assignmentRepo.ReplaceForUser(ctx, userID, model.AssignmentKindGroup, uniqueNames(groups))
+}
+
+// GetUserAccess 获取用户的角色、用户组及有效角色
+func (s *rbacService) GetUserAccess(ctx context.Context, userID uint) (*UserAccess, error) {
+	roles, err := s.assignmentRepo.ListByUser(ctx, userID, model.AssignmentKindRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user roles: %w", err)
+	}
+	groups, err := s.assignmentRepo.ListByUser(ctx, userID, model.AssignmentKindGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user groups: %w", err)
+	}
+	
+	effective := append([]string{}, roles...)
+	for _, name := range groups {
+		if group, _ := s.GetGroup(ctx, name); group != nil {
+			effective = append(effective, group.RoleList()...)
+		}
+	}
+	
+	return &UserAccess{
+		UserID:         userID,
+		Roles:          roles,
+		Groups:         groups,
+		EffectiveRoles: uniqueNames(effective),
+	}, nil
+}
+
+// roleScopes 角色允许签发的scope，与路由上anime:write和管理接口的角色要求保持一致
+var roleScopes = []struct {
+	scope string
+	roles []string
+}{
+	{scope: "anime:write", roles: []string{"catalog_editor", "moderator", "admin"}},
+	{scope: "admin", roles: []string{"admin"}},
+}
+
+// EntitledScopes 获取用户的有效角色所允许的scope，没有相应角色的用户不会得到这些scope
+func (s *rbacService) EntitledScopes(ctx context.Context, userID uint) ([]string, error) {
+	access, err := s.GetUserAccess(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	
+	held := make(map[string]bool)
+	for _, role := range access.EffectiveRoles {
+		held[role] = true
+	}
+	
+	var scopes []string
+	for _, entry := range roleScopes {
+		for _, role := range entry.roles {
+			if held[role] {
+				scopes = append(scopes, entry.scope)
+				break
+			}
+		}
+	}
+	return scopes, nil
+}
+
+// validateRoles 校验角色均已存在
+func (s *rbacService) validateRoles(ctx context.Context, roles []string) error {
+	for _, name := range roles {
+		if s.getRole(ctx, name) == nil {
+			return fmt.Errorf("role %s not found", name)
+		}
+	}
+	return nil
+}
+
+// validateRBACName 校验角色或用户组名称，名称会出现在令牌声明中，仅允许字母、数字、下划线、连字符和冒号
+func validateRBACName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s name is required", kind)
+	}
+	for _, ch := range name {
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_' || ch == '-' || ch == ':') {
+			return fmt.Errorf("%s name %q contains invalid characters", kind, name)
+		}
+	}
+	return nil
+}
+
+// uniqueNames 去除重复名称并保持原有顺序
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// removeName 从名称列表中移除指定名称
+func removeName(names []string, name string) []string {
+	result := []string{}
+	for _, n := range names {
+		if n != name {
+			result = append(result, n)
+		}
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+func TestFirstPartyScopesFollowRoles(t *testing.T) {
+	base := "openid profile email roles collection:read collection:write bangumi:sync"
+
+	tests := []struct {
+		name   string
+		roles  []string
+		groups []string
+		want   string
+	}{
+		{"no roles", nil, nil, base},
+		{"catalog editor", []string{"catalog_editor"}, nil, base + " anime:write"},
+		{"moderator", []string{"moderator"}, nil, base + " anime:write"},
+		{"admin", []string{"admin"}, nil, base + " anime:write admin"},
+		{"admin through group", nil, []string{"ops"}, base + " anime:write admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newRealmHarness(t)
+			if err := h.rbac.CreateGroup(h.ctx, &model.Group{Name: "ops", Roles: "admin"}); err != nil {
+				t.Fatalf("create group: %v", err)
+			}
+			if err := h.rbac.SetUserRoles(h.ctx, h.alice.ID, tt.roles); err != nil {
+				t.Fatalf("set roles: %v", err)
+			}
+			if err := h.rbac.SetUserGroups(h.ctx, h.alice.ID, tt.groups); err != nil {
+				t.Fatalf("set groups: %v", err)
+			}
+
+			scopes, err := h.user.FirstPartyScopes(h.ctx, h.alice.ID)
+			if err != nil {
+				t.Fatalf("FirstPartyScopes: %v", err)
+			}
+			if got := strings.Join(scopes, " "); got != tt.want {
+				t.Errorf("scopes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
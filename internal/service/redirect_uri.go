@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// validateRedirectURI 校验单个重定向URI
+// 允许https、回环地址或localhost上的http，以及原生应用使用的反向域名形式的私有scheme
+func validateRedirectURI(raw string) error {
+	uri, err := url.Parse(raw)
+	if err != nil || uri.Scheme == "" {
+		return errors.New("must be an absolute URI")
+	}
+	if uri.Fragment != "" || strings.Contains(raw, "#") {
+		return errors.New("must not contain a fragment")
+	}
+	// 重定向URI按字面值比较，通配符不会生效，登记时直接拒绝以免误以为可以匹配多个域名
+	if strings.Contains(uri.Host, "*") {
+		return errors.New("must not contain wildcards")
+	}
+
+	switch uri.Scheme {
+	case "https":
+		if uri.Host == "" {
+			return errors.New("https redirect URIs must include a host")
+		}
+	case "http":
+		if !isLoopbackHost(uri.Hostname()) && uri.Hostname() != "localhost" {
+			return errors.New("http is only allowed for loopback redirect URIs")
+		}
+	default:
+		// 私有scheme必须是应用控制的域名的反写，如com.example.app:/oauth2redirect (RFC 8252 §7.1)
+		if !strings.Contains(uri.Scheme, ".") {
+			return errors.New("private-use URI schemes must be a reverse domain name")
+		}
+		if uri.Host != "" {
+			return errors.New("private-use URI schemes must not include an authority")
+		}
+	}
+
+	return nil
+}
+
+// matchRedirectURI 判断请求的重定向URI是否与登记值匹配
+// 除回环IP地址的端口外必须完全一致，原生应用可在运行时使用任意端口 (RFC 8252 §7.3)
+func matchRedirectURI(requestedURI, registeredURI string) bool {
+	if requestedURI == registeredURI {
+		return true
+	}
+
+	registered, err := url.Parse(registeredURI)
+	if err != nil || registered.Scheme != "http" || !isLoopbackHost(registered.Hostname()) {
+		return false
+	}
+	requested, err := url.Parse(requestedURI)
+	if err != nil || requested.Fragment != "" {
+		return false
+	}
+
+	return requested.Scheme == registered.Scheme &&
+		requested.Hostname() == registered.Hostname() &&
+		requested.User == nil && registered.User == nil &&
+		requested.EscapedPath() == registered.EscapedPath() &&
+		requested.RawQuery == registered.RawQuery
+}
+
+// isLoopbackHost 判断主机是否为回环IP地址，localhost可能被解析到其他地址，因此不视为回环地址 (RFC 8252 §8.3)
+func isLoopbackHost(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
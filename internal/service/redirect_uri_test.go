@@ -0,0 +1,96 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+func TestClientRegistrationRedirectURIs(t *testing.T) {
+	tests := []struct {
+		name  string
+		uri   string
+		valid bool
+	}{
+		{"https", "https://app.example.com/callback", true},
+		{"https with query", "https://app.example.com/callback?tenant=1", true},
+		{"loopback ipv4", "http://127.0.0.1/callback", true},
+		{"loopback ipv6", "http://[::1]:8080/callback", true},
+		{"localhost", "http://localhost:3000/callback", true},
+		{"private-use scheme", "com.example.app:/oauth2redirect", true},
+		{"relative", "/callback", false},
+		{"fragment", "https://app.example.com/callback#token", false},
+		{"empty fragment", "https://app.example.com/callback#", false},
+		{"http non-loopback", "http://app.example.com/callback", false},
+		{"http private network", "http://192.168.1.10/callback", false},
+		{"https without host", "https:///callback", false},
+		{"wildcard host", "https://*.example.com/callback", false},
+		{"private-use scheme without dot", "myapp:/callback", false},
+		{"private-use scheme with authority", "com.example.app://host/callback", false},
+		{"javascript", "javascript:alert(1)", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &model.Client{ClientID: "client", SecretHash: "hash", RedirectURI: tt.uri}
+			err := service.ValidateClientRegistration(client)
+			if tt.valid && err != nil {
+				t.Fatalf("expected %q to be accepted, got %v", tt.uri, err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatalf("expected %q to be rejected", tt.uri)
+			}
+		})
+	}
+}
+
+func TestAuthorizationRequestRedirectURIMatching(t *testing.T) {
+	h := newRealmHarness(t)
+	h.addClient(t, &model.Client{
+		ClientID:     "matcher",
+		Name:         "Matcher",
+		RedirectURI:  "https://app.example.com/callback",
+		RedirectURIs: "http://127.0.0.1/callback http://[::1]/callback?mode=native http://localhost:3000/callback com.example.app:/oauth2redirect",
+		Scopes:       "openid",
+	})
+
+	tests := []struct {
+		name    string
+		uri     string
+		matches bool
+	}{
+		{"exact https", "https://app.example.com/callback", true},
+		{"exact second uri", "http://localhost:3000/callback", true},
+		{"exact private-use scheme", "com.example.app:/oauth2redirect", true},
+		{"https different path", "https://app.example.com/callback/other", false},
+		{"https added query", "https://app.example.com/callback?next=/", false},
+		{"https different port", "https://app.example.com:8443/callback", false},
+		{"https trailing slash", "https://app.example.com/callback/", false},
+		{"https with fragment", "https://app.example.com/callback#frag", false},
+		{"https subdomain", "https://evil.app.example.com/callback", false},
+		{"loopback exact", "http://127.0.0.1/callback", true},
+		{"loopback any port", "http://127.0.0.1:51004/callback", true},
+		{"loopback ipv6 any port", "http://[::1]:8080/callback?mode=native", true},
+		{"loopback different path", "http://127.0.0.1:51004/other", false},
+		{"loopback different query", "http://[::1]:8080/callback?mode=web", false},
+		{"loopback with fragment", "http://127.0.0.1:51004/callback#frag", false},
+		{"loopback with userinfo", "http://user@127.0.0.1:51004/callback", false},
+		{"loopback https scheme", "https://127.0.0.1:51004/callback", false},
+		{"loopback other address", "http://127.0.0.2:51004/callback", false},
+		{"localhost port is not flexible", "http://localhost:3001/callback", false},
+		{"localhost for loopback ip", "http://localhost:51004/callback", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.oauth.ValidateAuthorizationRequest(h.ctx, "matcher", tt.uri)
+			if tt.matches && err != nil {
+				t.Fatalf("expected %q to match, got %v", tt.uri, err)
+			}
+			if !tt.matches && err == nil {
+				t.Fatalf("expected %q not to match", tt.uri)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// ScopeService scope注册表服务接口
+type ScopeService interface {
+	// SeedScopes 写入内置scope及realm配置的scope，配置项覆盖同名内置scope
+	SeedScopes(ctx context.Context, configured []model.Scope) error
+	
+	// ListScopes 列出所有已注册的scope
+	ListScopes(ctx context.Context) ([]*model.Scope, error)
+	
+	// GetScope 根据名称获取scope，不存在时返回nil
+	GetScope(ctx context.Context, name string) (*model.Scope, error)
+	
+	// CreateScope 注册新scope
+	CreateScope(ctx context.Context, scope *model.Scope) error
+	
+	// UpdateScope 更新scope的说明、声明映射和同意要求
+	UpdateScope(ctx context.Context, name string, update *model.Scope) (*model.Scope, error)
+	
+	// DeleteScope 删除scope
+	DeleteScope(ctx context.Context, name string) error
+	
+	// ValidateScopes 校验scope是否均已注册，返回第一个未注册的scope
+	ValidateScopes(ctx context.Context, names []string) (string, bool)
+	
+	// ClaimsForScopes 获取scopes授予的声明集合
+	ClaimsForScopes(ctx context.Context, names []string) map[string]bool
+	
+	// ConsentRequiredScopes 筛选出需要用户同意的scopes
+	ConsentRequiredScopes(ctx context.Context, names []string) []string
+	
+	// DescribeScopes 获取scopes的注册信息，用于同意页面展示，未注册的scope会被忽略
+	DescribeScopes(ctx context.Context, names []string) []*model.Scope
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+)
+
+// scopeService scope注册表服务实现
+type scopeService struct {
+	scopeRepo repository.ScopeRepository
+}
+
+// NewScopeService 创建ScopeService实例
+func NewScopeService(scopeRepo repository.ScopeRepository) ScopeService {
+	return &scopeService{
+		scopeRepo: scopeRepo,
+	}
+}
+
+// DefaultScopes 内置scope：OIDC标准scope（含offline_access）、角色声明、管理接口以及番剧、收藏、Bangumi同步相关的API权限
+func DefaultScopes() []model.Scope {
+	return []model.Scope{
+		{Name: "openid", Description: "使用您的账户登录", Claims: "sub", RequiresConsent: false},
+		{Name: "profile", Description: "访问您的基本资料（昵称、头像）", Claims: "name nickname profile picture", RequiresConsent: true},
+		{Name: "email", Description: "访问您的邮箱地址", Claims: "email email_verified", RequiresConsent: true},
+		{Name: "offline_access", Description: "在您离线时保持对账户的访问", RequiresConsent: true},
+		{Name: "roles", Description: "查看您的角色和用户组", Claims: "roles groups", RequiresConsent: true},
+		{Name: "admin", Description: "以管理员身份管理本realm", RequiresConsent: true},
+		{Name: "anime:read", Description: "读取番剧目录", RequiresConsent: false},
+		{Name: "anime:write", Description: "创建、修改和删除番剧条目", RequiresConsent: true},
+		{Name: "collection:read", Description: "查看您的番剧收藏", RequiresConsent: true},
+		{Name: "collection:write", Description: "添加、修改和删除您的番剧收藏", RequiresConsent: true},
+		{Name: "bangumi:sync", Description: "绑定Bangumi账号并同步您的收藏", RequiresConsent: true},
+	}
+}
+
+// SeedScopes 写入内置scope及realm配置的scope
+func (s *scopeService) SeedScopes(ctx context.Context, configured []model.Scope) error {
+	overrides := make(map[string]bool)
+	for i := range configured {
+		overrides[configured[i].Name] = true
+		if err := s.CreateScope(ctx, &configured[i]); err != nil {
+			return fmt.Errorf("failed to seed scope %s: %w", configured[i].Name, err)
+		}
+	}
+	
+	for _, scope := range DefaultScopes() {
+		if overrides[scope.Name] {
+			continue
+		}
+		scope := scope
+		if err := s.CreateScope(ctx, &scope); err != nil {
+			return fmt.Errorf("failed to seed scope %s: %w", scope.Name, err)
+		}
+	}
+	
+	return nil
+}
+
+// ListScopes 列出所有已注册的scope
+func (s *scopeService) ListScopes(ctx context.Context) ([]*model.Scope, error) {
+	return s.scopeRepo.ListAll(ctx)
+}
+
+// GetScope 根据名称获取scope，scope不存在时返回nil
+func (s *scopeService) GetScope(ctx context.Context, name string) (*model.Scope, error) {
+	scope, err := s.scopeRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scope %s: %w", name, err)
+	}
+	return scope, nil
+}
+
+// CreateScope 注册新scope
+func (s *scopeService) CreateScope(ctx context.Context, scope *model.Scope) error {
+	if err := validateScopeName(scope.Name); err != nil {
+		return err
+	}
+	scope.Claims = strings.Join(strings.Fields(scope.Claims), " ")
+	
+	existing, err := s.GetScope(ctx, scope.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("scope %s already exists", scope.Name)
+	}
+	
+	return s.scopeRepo.Create(ctx, scope)
+}
+
+// UpdateScope 更新scope的说明、声明映射和同意要求，名称不可修改
+func (s *scopeService) UpdateScope(ctx context.Context, name string, update *model.Scope) (*model.Scope, error) {
+	scope, err := s.GetScope(ctx, name)
+	if err != nil || scope == nil {
+		return nil, err
+	}
+	
+	scope.Description = update.Description
+	scope.Claims = strings.Join(strings.Fields(update.Claims), " ")
+	scope.RequiresConsent = update.RequiresConsent
+	
+	if err := s.scopeRepo.Update(ctx, scope); err != nil {
+		return nil, fmt.Errorf("failed to update scope: %w", err)
+	}
+	
+	return scope, nil
+}
+
+// DeleteScope 删除scope，openid为OIDC必需scope，不允许删除
+func (s *scopeService) DeleteScope(ctx context.Context, name string) error {
+	if name == "openid" {
+		return fmt.Errorf("scope openid cannot be deleted")
+	}
+	
+	scope, err := s.GetScope(ctx, name)
+	if err != nil {
+		return err
+	}
+	if scope == nil {
+		return fmt.Errorf("scope %s not found", name)
+	}
+	
+	return s.scopeRepo.DeleteByID(ctx, scope.ID)
+}
+
+// ValidateScopes 校验scope是否均已注册
+func (s *scopeService) ValidateScopes(ctx context.Context, names []string) (string, bool) {
+	for _, name := range names {
+		if scope, _ := s.GetScope(ctx, name); scope == nil {
+			return name, false
+		}
+	}
+	return "", true
+}
+
+// ClaimsForScopes 获取scopes授予的声明集合
+func (s *scopeService) ClaimsForScopes(ctx context.Context, names []string) map[string]bool {
+	claims := make(map[string]bool)
+	for _, scope := range s.DescribeScopes(ctx, names) {
+		for _, claim := range scope.ClaimList() {
+			claims[claim] = true
+		}
+	}
+	return claims
+}
+
+// ConsentRequiredScopes 筛选出需要用户同意的scopes
+func (s *scopeService) ConsentRequiredScopes(ctx context.Context, names []string) []string {
+	var required []string
+	for _, scope := range s.DescribeScopes(ctx, names) {
+		if scope.RequiresConsent {
+			required = append(required, scope.Name)
+		}
+	}
+	return required
+}
+
+// DescribeScopes 获取scopes的注册信息
+func (s *scopeService) DescribeScopes(ctx context.Context, names []string) []*model.Scope {
+	var scopes []*model.Scope
+	for _, name := range names {
+		if scope, _ := s.GetScope(ctx, name); scope != nil {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// validateScopeName 校验scope名称符合RFC 6749 §3.3的scope-token语法
+func validateScopeName(name string) error {
+	if name == "" {
+		return fmt.Errorf("scope name is required")
+	}
+	for _, ch := range name {
+		if ch < 0x21 || ch > 0x7e || ch == '"' || ch == '\\' {
+			return fmt.Errorf("scope name %q contains invalid characters", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+// failingScopeRepository 模拟存储故障的scope仓库
+type failingScopeRepository struct {
+	repository.ScopeRepository
+	err error
+}
+
+func (r *failingScopeRepository) GetByName(ctx context.Context, name string) (*model.Scope, error) {
+	return nil, r.err
+}
+
+func TestGetScopeReturnsRepositoryErrors(t *testing.T) {
+	storeErr := errors.New("connection refused")
+	scopes := service.NewScopeService(&failingScopeRepository{ScopeRepository: repository.NewScopeRepository(1), err: storeErr})
+
+	scope, err := scopes.GetScope(context.Background(), "profile")
+	if !errors.Is(err, storeErr) || scope != nil {
+		t.Fatalf("GetScope = %v, %v; want the repository error", scope, err)
+	}
+	if err := scopes.CreateScope(context.Background(), &model.Scope{Name: "custom"}); !errors.Is(err, storeErr) {
+		t.Errorf("CreateScope error = %v, want the repository error", err)
+	}
+	if _, err := scopes.UpdateScope(context.Background(), "profile", &model.Scope{}); !errors.Is(err, storeErr) {
+		t.Errorf("UpdateScope error = %v, want the repository error", err)
+	}
+	if err := scopes.DeleteScope(context.Background(), "profile"); !errors.Is(err, storeErr) {
+		t.Errorf("DeleteScope error = %v, want the repository error", err)
+	}
+}
+
+func TestGetScopeNotFound(t *testing.T) {
+	h := newRealmHarness(t)
+
+	scope, err := h.scopes.GetScope(context.Background(), "missing")
+	if err != nil || scope != nil {
+		t.Fatalf("GetScope(missing) = %v, %v; want nil, nil", scope, err)
+	}
+	if scope, err := h.scopes.GetScope(context.Background(), "profile"); err != nil || scope == nil {
+		t.Fatalf("GetScope(profile) = %v, %v; want the seeded scope", scope, err)
+	}
+}
+
+func TestDiscoveryClaimsSupported(t *testing.T) {
+	h := newRealmHarness(t)
+	ctx := issuerContext()
+	if err := h.scopes.CreateScope(ctx, &model.Scope{Name: "library", Claims: "aaa_claim sub"}); err != nil {
+		t.Fatalf("create scope: %v", err)
+	}
+
+	config, err := h.oauth.GetOpenIDConfiguration(ctx)
+	if err != nil {
+		t.Fatalf("GetOpenIDConfiguration: %v", err)
+	}
+
+	standard := []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "amr"}
+	claims := config.ClaimsSupported
+	if len(claims) <= len(standard) {
+		t.Fatalf("claims_supported = %v, want standard and mapped claims", claims)
+	}
+	for i, claim := range standard {
+		if claims[i] != claim {
+			t.Fatalf("claims_supported = %v, want standard claims first", claims)
+		}
+	}
+	mapped := claims[len(standard):]
+	if !sort.StringsAreSorted(mapped) {
+		t.Errorf("mapped claims %v are not sorted", mapped)
+	}
+	seen := make(map[string]bool)
+	for _, claim := range claims {
+		if seen[claim] {
+			t.Errorf("claim %s listed twice in %v", claim, claims)
+		}
+		seen[claim] = true
+	}
+	if !seen["aaa_claim"] || !seen["email"] {
+		t.Errorf("claims_supported = %v, want claims mapped from registered scopes", claims)
+	}
+}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// SessionService 登录会话服务接口
+type SessionService interface {
+	// CreateSession 用户完成认证后创建会话，auth_time为当前时间，amr为用户完成的认证方式
+	CreateSession(ctx context.Context, userID uint, amr []string) (*model.Session, error)
+	
+	// GetActiveSession 获取未过期的会话，会话不存在或已过期时返回nil
+	GetActiveSession(ctx context.Context, sessionID string) (*model.Session, error)
+	
+	// DeleteSession 删除会话（登出）
+	DeleteSession(ctx context.Context, sessionID string) error
+	
+	// DeleteUserSessions 删除用户的所有会话，用于重置密码等需要让用户在所有设备上重新登录的场景
+	DeleteUserSessions(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+)
+
+// defaultSessionLifetime 默认会话有效期
+const defaultSessionLifetime = 24 * time.Hour
+
+// sessionService 登录会话服务实现
+type sessionService struct {
+	sessionRepo  repository.SessionRepository
+	auditService AuditService
+	lifetime     time.Duration
+}
+
+// NewSessionService 创建SessionService实例，会话有效期由SESSION_LIFETIME_HOURS配置
+func NewSessionService(sessionRepo repository.SessionRepository, auditService AuditService) SessionService {
+	lifetime := defaultSessionLifetime
+	if hours, err := strconv.Atoi(os.Getenv("SESSION_LIFETIME_HOURS")); err == nil && hours > 0 {
+		lifetime = time.Duration(hours) * time.Hour
+	}
+	
+	return &sessionService{
+		sessionRepo:  sessionRepo,
+		auditService: auditService,
+		lifetime:     lifetime,
+	}
+}
+
+// CreateSession 用户完成认证后创建会话
+func (s *sessionService) CreateSession(ctx context.Context, userID uint, amr []string) (*model.Session, error) {
+	idBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+	
+	now := time.Now()
+	session := &model.Session{
+		ID:        base64.RawURLEncoding.EncodeToString(idBytes),
+		UserID:    userID,
+		AuthTime:  now,
+		AMR:       strings.Join(amr, " "),
+		ExpiresAt: now.Add(s.lifetime),
+	}
+	
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	
+	// 所有登录方式最终都会创建会话，在此统一记录登录成功
+	recordAudit(ctx, s.auditService, &model.AuditEvent{
+		Type:    model.AuditLoginSucceeded,
+		UserID:  userID,
+		Details: map[string]string{"amr": session.AMR},
+	})
+	
+	return session, nil
+}
+
+// GetActiveSession 获取未过期的会话
+func (s *sessionService) GetActiveSession(ctx context.Context, sessionID string) (*model.Session, error) {
+	if sessionID == "" {
+		return nil, nil
+	}
+	
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, nil
+	}
+	
+	if session.IsExpired() {
+		_ = s.sessionRepo.DeleteByID(ctx, sessionID)
+		return nil, nil
+	}
+	
+	return session, nil
+}
+
+// DeleteSession 删除会话
+func (s *sessionService) DeleteSession(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil
+	}
+	if err := s.sessionRepo.DeleteByID(ctx, sessionID); err != nil {
+		return err
+	}
+	
+	recordAudit(ctx, s.auditService, &model.AuditEvent{Type: model.AuditLogout, UserID: session.UserID})
+	return nil
+}
+
+// DeleteUserSessions 删除用户的所有会话
+func (s *sessionService) DeleteUserSessions(ctx context.Context, userID uint) error {
+	return s.sessionRepo.DeleteByUserID(ctx, userID)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"os"
+	"strconv"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// 全局默认令牌有效期，可通过环境变量覆盖
+const (
+	defaultAuthorizationCodeLifetime    = 10 * time.Minute
+	defaultAccessTokenLifetime          = time.Hour
+	defaultIDTokenLifetime              = time.Hour
+	defaultRefreshTokenAbsoluteLifetime = 30 * 24 * time.Hour
+)
+
+// TokenPolicy 对某个客户端生效的令牌有效期策略
+type TokenPolicy struct {
+	AuthorizationCodeLifetime    time.Duration
+	AccessTokenLifetime          time.Duration
+	IDTokenLifetime              time.Duration
+	RefreshTokenAbsoluteLifetime time.Duration
+	RefreshTokenIdleTimeout      time.Duration // 0表示不限制空闲时间
+	RefreshTokenSliding          bool
+	AlwaysIssueRefreshToken      bool
+}
+
+// ResolveTokenPolicy 按 客户端 > realm > 环境变量 > 默认值 的优先级合并令牌有效期策略
+func ResolveTokenPolicy(realm *model.Realm, client *model.Client) *TokenPolicy {
+	policy := &TokenPolicy{
+		AuthorizationCodeLifetime:    envSeconds("AUTHORIZATION_CODE_LIFETIME_SECONDS", defaultAuthorizationCodeLifetime),
+		AccessTokenLifetime:          envSeconds("ACCESS_TOKEN_LIFETIME_SECONDS", defaultAccessTokenLifetime),
+		IDTokenLifetime:              envSeconds("ID_TOKEN_LIFETIME_SECONDS", defaultIDTokenLifetime),
+		RefreshTokenAbsoluteLifetime: envSeconds("REFRESH_TOKEN_ABSOLUTE_LIFETIME_SECONDS", defaultRefreshTokenAbsoluteLifetime),
+		RefreshTokenIdleTimeout:      envSeconds("REFRESH_TOKEN_IDLE_TIMEOUT_SECONDS", 0),
+		RefreshTokenSliding:          os.Getenv("REFRESH_TOKEN_SLIDING") != "false",
+	}
+
+	if realm != nil {
+		policy.apply(&realm.TokenPolicy)
+	}
+	if client != nil {
+		policy.apply(&client.TokenPolicy)
+	}
+
+	return policy
+}
+
+// RefreshTokenExpiry 计算刷新令牌的过期时间，空闲超时不能超出绝对有效期
+func (p *TokenPolicy) RefreshTokenExpiry(now, absoluteExpiresAt time.Time) time.Time {
+	if p.RefreshTokenIdleTimeout > 0 {
+		if idleExpiresAt := now.Add(p.RefreshTokenIdleTimeout); idleExpiresAt.Before(absoluteExpiresAt) {
+			return idleExpiresAt
+		}
+	}
+	return absoluteExpiresAt
+}
+
+// ShouldIssueRefreshToken 判断是否签发刷新令牌：请求了offline_access或客户端策略要求始终签发
+func (p *TokenPolicy) ShouldIssueRefreshToken(scopes []string) bool {
+	if p.AlwaysIssueRefreshToken {
+		return true
+	}
+	for _, scope := range scopes {
+		if scope == "offline_access" {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 用下一级配置覆盖已设置的项
+func (p *TokenPolicy) apply(override *model.TokenLifetimePolicy) {
+	if override.AuthorizationCodeLifetime > 0 {
+		p.AuthorizationCodeLifetime = time.Duration(override.AuthorizationCodeLifetime) * time.Second
+	}
+	if override.AccessTokenLifetime > 0 {
+		p.AccessTokenLifetime = time.Duration(override.AccessTokenLifetime) * time.Second
+	}
+	if override.IDTokenLifetime > 0 {
+		p.IDTokenLifetime = time.Duration(override.IDTokenLifetime) * time.Second
+	}
+	if override.RefreshTokenAbsoluteLifetime > 0 {
+		p.RefreshTokenAbsoluteLifetime = time.Duration(override.RefreshTokenAbsoluteLifetime) * time.Second
+	}
+	if override.RefreshTokenIdleTimeout > 0 {
+		p.RefreshTokenIdleTimeout = time.Duration(override.RefreshTokenIdleTimeout) * time.Second
+	} else if override.RefreshTokenIdleTimeout < 0 {
+		p.RefreshTokenIdleTimeout = 0
+	}
+	if override.RefreshTokenSliding != nil {
+		p.RefreshTokenSliding = *override.RefreshTokenSliding
+	}
+	if override.AlwaysIssueRefreshToken != nil {
+		p.AlwaysIssueRefreshToken = *override.AlwaysIssueRefreshToken
+	}
+}
+
+// envSeconds 读取以秒为单位的环境变量，未设置或非法时返回默认值
+func envSeconds(key string, fallback time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(os.Getenv(key)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return fallback
+}
+
+// envInt 读取正整数环境变量，未设置或非法时返回默认值
+func envInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,239 @@
+package service_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+)
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+func TestResolveTokenPolicyPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		realm   model.TokenLifetimePolicy
+		client  model.TokenLifetimePolicy
+		want    service.TokenPolicy
+		noRealm bool
+	}{
+		{
+			name:    "defaults",
+			noRealm: true,
+			want: service.TokenPolicy{
+				AuthorizationCodeLifetime:    10 * time.Minute,
+				AccessTokenLifetime:          time.Hour,
+				IDTokenLifetime:              time.Hour,
+				RefreshTokenAbsoluteLifetime: 30 * 24 * time.Hour,
+				RefreshTokenSliding:          true,
+			},
+		},
+		{
+			name: "environment overrides defaults",
+			env:  map[string]string{"ACCESS_TOKEN_LIFETIME_SECONDS": "1800", "REFRESH_TOKEN_IDLE_TIMEOUT_SECONDS": "600", "REFRESH_TOKEN_SLIDING": "false"},
+			want: service.TokenPolicy{
+				AuthorizationCodeLifetime:    10 * time.Minute,
+				AccessTokenLifetime:          30 * time.Minute,
+				IDTokenLifetime:              time.Hour,
+				RefreshTokenAbsoluteLifetime: 30 * 24 * time.Hour,
+				RefreshTokenIdleTimeout:      10 * time.Minute,
+			},
+		},
+		{
+			name:  "realm overrides environment",
+			env:   map[string]string{"ACCESS_TOKEN_LIFETIME_SECONDS": "1800"},
+			realm: model.TokenLifetimePolicy{AccessTokenLifetime: 900, IDTokenLifetime: 300},
+			want: service.TokenPolicy{
+				AuthorizationCodeLifetime:    10 * time.Minute,
+				AccessTokenLifetime:          15 * time.Minute,
+				IDTokenLifetime:              5 * time.Minute,
+				RefreshTokenAbsoluteLifetime: 30 * 24 * time.Hour,
+				RefreshTokenSliding:          true,
+			},
+		},
+		{
+			name:   "client overrides realm and unset fields fall through",
+			realm:  model.TokenLifetimePolicy{AccessTokenLifetime: 900, IDTokenLifetime: 300, RefreshTokenSliding: boolPtr(false)},
+			client: model.TokenLifetimePolicy{AccessTokenLifetime: 120, AlwaysIssueRefreshToken: boolPtr(true)},
+			want: service.TokenPolicy{
+				AuthorizationCodeLifetime:    10 * time.Minute,
+				AccessTokenLifetime:          2 * time.Minute,
+				IDTokenLifetime:              5 * time.Minute,
+				RefreshTokenAbsoluteLifetime: 30 * 24 * time.Hour,
+				AlwaysIssueRefreshToken:      true,
+			},
+		},
+		{
+			name:   "client disables idle timeout set by realm",
+			realm:  model.TokenLifetimePolicy{RefreshTokenIdleTimeout: 3600},
+			client: model.TokenLifetimePolicy{RefreshTokenIdleTimeout: -1, RefreshTokenSliding: boolPtr(true)},
+			want: service.TokenPolicy{
+				AuthorizationCodeLifetime:    10 * time.Minute,
+				AccessTokenLifetime:          time.Hour,
+				IDTokenLifetime:              time.Hour,
+				RefreshTokenAbsoluteLifetime: 30 * 24 * time.Hour,
+				RefreshTokenSliding:          true,
+			},
+		},
+		{
+			name: "invalid environment values are ignored",
+			env:  map[string]string{"ACCESS_TOKEN_LIFETIME_SECONDS": "soon", "ID_TOKEN_LIFETIME_SECONDS": "-5"},
+			want: service.TokenPolicy{
+				AuthorizationCodeLifetime:    10 * time.Minute,
+				AccessTokenLifetime:          time.Hour,
+				IDTokenLifetime:              time.Hour,
+				RefreshTokenAbsoluteLifetime: 30 * 24 * time.Hour,
+				RefreshTokenSliding:          true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"AUTHORIZATION_CODE_LIFETIME_SECONDS", "ACCESS_TOKEN_LIFETIME_SECONDS", "ID_TOKEN_LIFETIME_SECONDS", "REFRESH_TOKEN_ABSOLUTE_LIFETIME_SECONDS", "REFRESH_TOKEN_IDLE_TIMEOUT_SECONDS", "REFRESH_TOKEN_SLIDING"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			var realm *model.Realm
+			if !tt.noRealm {
+				realm = &model.Realm{ID: 1, Name: "default", TokenPolicy: tt.realm}
+			}
+			got := service.ResolveTokenPolicy(realm, &model.Client{ClientID: "app", TokenPolicy: tt.client})
+			if *got != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenExpiry(t *testing.T) {
+	now := time.Now()
+	absolute := now.Add(2 * time.Hour)
+
+	noIdle := &service.TokenPolicy{}
+	if got := noIdle.RefreshTokenExpiry(now, absolute); !got.Equal(absolute) {
+		t.Fatalf("without idle timeout the absolute expiry applies, got %v", got)
+	}
+
+	idle := &service.TokenPolicy{RefreshTokenIdleTimeout: time.Hour}
+	if got := idle.RefreshTokenExpiry(now, absolute); !got.Equal(now.Add(time.Hour)) {
+		t.Fatalf("idle timeout should apply, got %v", got)
+	}
+	if got := idle.RefreshTokenExpiry(now.Add(90*time.Minute), absolute); !got.Equal(absolute) {
+		t.Fatalf("idle timeout must not extend past the absolute expiry, got %v", got)
+	}
+}
+
+func TestShouldIssueRefreshToken(t *testing.T) {
+	policy := &service.TokenPolicy{}
+	if policy.ShouldIssueRefreshToken([]string{"openid", "profile"}) {
+		t.Fatal("refresh tokens require offline_access")
+	}
+	if !policy.ShouldIssueRefreshToken([]string{"openid", "offline_access"}) {
+		t.Fatal("offline_access should issue a refresh token")
+	}
+	always := &service.TokenPolicy{AlwaysIssueRefreshToken: true}
+	if !always.ShouldIssueRefreshToken([]string{"openid"}) {
+		t.Fatal("always_issue_refresh_token should issue a refresh token")
+	}
+}
+
+// storedRefreshToken 获取刷新令牌的存储记录，内存仓库返回记录本身，修改即生效
+func storedRefreshToken(t *testing.T, h *realmHarness, token string) *model.RefreshToken {
+	t.Helper()
+	hash := sha256.Sum256([]byte(token))
+	refresh, err := h.refresh.GetByTokenHash(h.ctx, base64.URLEncoding.EncodeToString(hash[:]))
+	if err != nil {
+		t.Fatalf("get refresh token: %v", err)
+	}
+	return refresh
+}
+
+// requireNear 断言时间与期望值相差不超过一分钟
+func requireNear(t *testing.T, what string, got, want time.Time) {
+	t.Helper()
+	if diff := got.Sub(want); diff > time.Minute || diff < -time.Minute {
+		t.Fatalf("%s: expected about %v, got %v", what, want, got)
+	}
+}
+
+func TestRefreshTokenSlidingExpiry(t *testing.T) {
+	tests := []struct {
+		name     string
+		sliding  bool
+		absolute time.Duration // 刷新前剩余的绝对有效期
+		want     time.Duration // 轮换后的令牌距离现在的过期时间
+	}{
+		{name: "sliding renews the idle timeout", sliding: true, absolute: 2 * time.Hour, want: time.Hour},
+		{name: "sliding is capped by the absolute lifetime", sliding: true, absolute: 10 * time.Minute, want: 10 * time.Minute},
+		{name: "without sliding the expiry is kept", sliding: false, absolute: 2 * time.Hour, want: 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newRealmHarness(t)
+			client := h.addClient(t, &model.Client{
+				ClientID:    "app",
+				Name:        "App",
+				RedirectURI: "https://app.example.com/callback",
+				Scopes:      "openid offline_access",
+				TokenPolicy: model.TokenLifetimePolicy{RefreshTokenIdleTimeout: 3600, RefreshTokenSliding: boolPtr(tt.sliding)},
+			})
+
+			issued := h.exchangeCode(t, client, []string{"openid", "offline_access"})
+			if issued.RefreshToken == "" {
+				t.Fatal("offline_access should issue a refresh token")
+			}
+
+			// 模拟令牌空闲了55分钟
+			now := time.Now()
+			original := storedRefreshToken(t, h, issued.RefreshToken)
+			original.ExpiresAt = now.Add(5 * time.Minute)
+			original.AbsoluteExpiresAt = now.Add(tt.absolute)
+
+			refreshed, err := h.oauth.RefreshAccessToken(h.ctx, issued.RefreshToken, client.ClientID, testClientSecret)
+			if err != nil {
+				t.Fatalf("refresh: %v", err)
+			}
+			rotated := storedRefreshToken(t, h, refreshed.RefreshToken)
+			requireNear(t, "expires_at", rotated.ExpiresAt, now.Add(tt.want))
+			if !rotated.AbsoluteExpiresAt.Equal(original.AbsoluteExpiresAt) {
+				t.Fatalf("rotation must keep the absolute expiry, got %v", rotated.AbsoluteExpiresAt)
+			}
+		})
+	}
+}
+
+func TestTokenLifetimesFollowPolicy(t *testing.T) {
+	h := newRealmHarness(t)
+	h.realm.TokenPolicy = model.TokenLifetimePolicy{AccessTokenLifetime: 900}
+	client := h.addClient(t, &model.Client{ClientID: "app", Name: "App", RedirectURI: "https://app.example.com/callback", Scopes: "openid", TokenPolicy: model.TokenLifetimePolicy{AccessTokenLifetime: 300}})
+	other := h.addClient(t, &model.Client{ClientID: "other", Name: "Other", RedirectURI: "https://other.example.com/callback", Scopes: "openid"})
+
+	if issued := h.exchangeCode(t, client, []string{"openid"}); issued.ExpiresIn != 300 || issued.RefreshToken != "" {
+		t.Fatalf("client policy should apply, got expires_in=%d refresh_token=%q", issued.ExpiresIn, issued.RefreshToken)
+	}
+	if issued := h.exchangeCode(t, other, []string{"openid"}); issued.ExpiresIn != 900 {
+		t.Fatalf("realm policy should apply, got expires_in=%d", issued.ExpiresIn)
+	}
+
+	// 直接登录签发的令牌不属于任何客户端，只应用realm策略
+	if lifetime := h.user.AccessTokenLifetime(); lifetime != 15*time.Minute {
+		t.Fatalf("first-party tokens should follow the realm policy, got %v", lifetime)
+	}
+	token, err := h.user.GenerateAccessToken(h.ctx, h.alice.ID, []string{"openid"}, []string{model.AMRPassword})
+	if err != nil {
+		t.Fatalf("generate access token: %v", err)
+	}
+	claims, err := h.jwtUtil.ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	requireNear(t, "exp", claims.ExpiresAt.Time, time.Now().Add(15*time.Minute))
+}
@@ -1,9 +1,21 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/Full-finger/OIDC/internal/model"
 )
 
+// 用户错误，用户不存在和密码错误在认证时返回同一个错误，避免暴露用户名是否注册
+var (
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	ErrUserInactive       = errors.New("用户未激活，请先验证邮箱")
+	ErrUserNotFound       = errors.New("用户不存在")
+	ErrWrongPassword      = errors.New("当前密码错误")
+)
+
 // UserService 用户服务接口
 type UserService interface {
 	// RegisterUser 注册用户
@@ -18,17 +30,23 @@ type UserService interface {
 	// ResendVerificationEmail 重新发送验证邮件
 	ResendVerificationEmail(email string) error
 
-	// AuthenticateUser 用户认证
+	// AuthenticateUser 用户认证，用户名不存在或密码错误时都返回ErrInvalidCredentials
 	AuthenticateUser(username, password string) (*model.User, error)
 
 	// GetUserByID 根据ID获取用户
 	GetUserByID(id uint) (*model.User, error)
 
-	// UpdateUserProfile 更新用户资料
-	UpdateUserProfile(userID uint, nickname, avatarURL, bio string) error
+	// ChangePassword 校验当前密码后设置新密码，新密码须符合realm的密码策略
+	ChangePassword(userID uint, currentPassword, newPassword string) error
+
+	// FirstPartyScopes 获取直接登录签发的令牌所包含的scopes
+	FirstPartyScopes(ctx context.Context, userID uint) ([]string, error)
+
+	// AccessTokenLifetime 直接登录签发的访问令牌有效期，按realm的令牌有效期策略确定
+	AccessTokenLifetime() time.Duration
 
-	// GenerateAccessToken 生成访问令牌
-	GenerateAccessToken(userID uint, scopes []string) (string, error)
+	// GenerateAccessToken 生成访问令牌，amr为用户完成的认证方式
+	GenerateAccessToken(ctx context.Context, userID uint, scopes []string, amr []string) (string, error)
 
 	// GenerateRefreshToken 生成刷新令牌
 	GenerateRefreshToken(userID uint, scopes []string) (string, error)
@@ -1,12 +1,10 @@
 package service
 
 import (
+	"context"
 	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"os"
@@ -18,30 +16,50 @@ import (
 	"github.com/Full-finger/OIDC/internal/repository"
 	"github.com/Full-finger/OIDC/internal/util"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // userService 用户服务实现
 type userService struct {
-	userRepo   repository.UserRepository
-	userHelper helper.UserHelper
-	tokenRepo  repository.VerificationTokenRepository
-	emailQueue util.EmailQueue // 使用util包中的接口类型
+	userRepo       repository.UserRepository
+	userHelper     helper.UserHelper
+	tokenRepo      repository.VerificationTokenRepository
+	emailQueue     util.EmailQueue // 使用util包中的接口类型
+	jwtUtil        util.JWTUtil
+	realm          *model.Realm
+	rbacService    RBACService
+	passwordPolicy PasswordPolicyService
+	passwordHasher util.PasswordHasher
+
+	// dummyPasswordHash 用户不存在时用于比对的哈希，使响应时间与密码错误时一致
+	dummyPasswordHash string
 }
 
 
-// NewUserService 创建UserService实例
+// NewUserService 创建realm范围内的UserService实例
 func NewUserService(
 	userRepo repository.UserRepository,
 	userHelper helper.UserHelper,
 	tokenRepo repository.VerificationTokenRepository,
 	emailQueue util.EmailQueue,
+	jwtUtil util.JWTUtil,
+	realm *model.Realm,
+	rbacService RBACService,
+	passwordPolicy PasswordPolicyService,
+	passwordHasher util.PasswordHasher,
 ) UserService {
+	dummyPasswordHash, _ := passwordHasher.Hash("dummy-password")
 	return &userService{
-		userRepo:   userRepo,
-		userHelper: userHelper,
-		tokenRepo:  tokenRepo,
-		emailQueue: emailQueue,
+		userRepo:       userRepo,
+		userHelper:     userHelper,
+		tokenRepo:      tokenRepo,
+		emailQueue:     emailQueue,
+		jwtUtil:        jwtUtil,
+		realm:          realm,
+		rbacService:    rbacService,
+		passwordPolicy: passwordPolicy,
+		passwordHasher: passwordHasher,
+
+		dummyPasswordHash: dummyPasswordHash,
 	}
 }
 
@@ -68,8 +86,13 @@ func (s *userService) RegisterUser(username, password, email, nickname string) e
 		return errors.New("邮箱已被注册")
 	}
 
-	// 使用bcrypt哈希密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	// 按realm的密码策略校验密码
+	if err := s.passwordPolicy.Validate(password, username, email); err != nil {
+		return err
+	}
+
+	// 使用配置的算法哈希密码
+	hashedPassword, err := s.passwordHasher.Hash(password)
 	if err != nil {
 		return errors.New("密码加密失败")
 	}
@@ -80,7 +103,7 @@ func (s *userService) RegisterUser(username, password, email, nickname string) e
 	// 创建用户实体
 	user := &model.User{
 		Username:     username,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 		Email:        email,
 		Nickname:     nickname,
 		IsActive:     skipEmailVerification, // 如果跳过邮箱验证，则用户默认激活
@@ -116,8 +139,9 @@ func (s *userService) RegisterUser(username, password, email, nickname string) e
 
 	// 将邮件发送任务加入队列
 	emailItem := util.EmailQueueItem{
-		Email: user.Email,
-		Token: tokenString,
+		Email:    user.Email,
+		Token:    tokenString,
+		BasePath: s.realm.PathPrefix(),
 	}
 	
 	if err := s.emailQueue.Enqueue(emailItem); err != nil {
@@ -171,6 +195,7 @@ func (s *userService) VerifyEmail(token string) error {
 
 	// 激活用户
 	user.IsActive = true
+	user.EmailVerified = true
 	if err := s.userRepo.Update(user); err != nil {
 		return errors.New("更新用户状态失败")
 	}
@@ -217,8 +242,9 @@ func (s *userService) ResendVerificationEmail(email string) error {
 
 	// 将邮件发送任务加入队列
 	emailItem := util.EmailQueueItem{
-		Email: user.Email,
-		Token: tokenString,
+		Email:    user.Email,
+		Token:    tokenString,
+		BasePath: s.realm.PathPrefix(),
 	}
 	
 	if err := s.emailQueue.Enqueue(emailItem); err != nil {
@@ -228,25 +254,27 @@ func (s *userService) ResendVerificationEmail(email string) error {
 	return nil
 }
 
-// AuthenticateUser 用户认证
+// AuthenticateUser 用户认证，密码正确且哈希算法或参数弱于当前配置时按当前配置重新哈希
 func (s *userService) AuthenticateUser(username, password string) (*model.User, error) {
 	// 根据用户名查找用户
 	user, err := s.userRepo.GetByUsername(username)
 	if err != nil {
-		return nil, errors.New("用户不存在")
+		s.passwordHasher.Verify(s.dummyPasswordHash, password)
+		return nil, ErrInvalidCredentials
 	}
 
+	// 比对密码哈希
+	if ok, _ := s.passwordHasher.Verify(user.PasswordHash, password); !ok {
+		return nil, ErrInvalidCredentials
+	}
+	s.upgradePasswordHash(user, password)
+
 	// 检查是否跳过邮箱验证
 	skipEmailVerification := os.Getenv("SKIP_EMAIL_VERIFICATION") == "true"
 	
-	// 检查用户是否已激活（除非跳过邮箱验证）
+	// 检查用户是否已激活（除非跳过邮箱验证），密码正确后才提示，避免暴露用户名是否注册
 	if !skipEmailVerification && !user.IsActive {
-		return nil, errors.New("用户未激活，请先验证邮箱")
-	}
-
-	// 比对密码哈希
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return nil, errors.New("密码错误")
+		return nil, ErrUserInactive
 	}
 
 	// 认证成功，返回用户信息
@@ -258,29 +286,77 @@ func (s *userService) GetUserByID(id uint) (*model.User, error) {
 	return s.userRepo.GetByID(id)
 }
 
-// UpdateUserProfile 更新用户资料
-func (s *userService) UpdateUserProfile(userID uint, nickname, avatarURL, bio string) error {
-	// 查找用户
+// ChangePassword 修改密码
+func (s *userService) ChangePassword(userID uint, currentPassword, newPassword string) error {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return errors.New("用户不存在")
+		return ErrUserNotFound
 	}
 
-	// 更新用户资料
-	user.Nickname = nickname
-	user.AvatarURL = avatarURL
-	user.Bio = bio
+	// 校验当前密码
+	if ok, _ := s.passwordHasher.Verify(user.PasswordHash, currentPassword); !ok {
+		return ErrWrongPassword
+	}
+
+	// 按realm的密码策略校验新密码
+	if err := s.passwordPolicy.Validate(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
 
-	// 保存更新
+	hashedPassword, err := s.passwordHasher.Hash(newPassword)
+	if err != nil {
+		return errors.New("密码加密失败")
+	}
+	user.PasswordHash = hashedPassword
 	if err := s.userRepo.Update(user); err != nil {
-		return errors.New("更新用户资料失败")
+		return errors.New("更新密码失败")
 	}
 
 	return nil
 }
 
+// upgradePasswordHash 登录成功后按当前配置重新哈希旧算法或弱参数的密码，失败时只记录日志，不影响登录
+func (s *userService) upgradePasswordHash(user *model.User, password string) {
+	if !s.passwordHasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	hashedPassword, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		fmt.Printf("警告: 无法重新哈希用户 %d 的密码: %v\n", user.ID, err)
+		return
+	}
+	user.PasswordHash = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		fmt.Printf("警告: 无法保存用户 %d 重新哈希的密码: %v\n", user.ID, err)
+	}
+}
+
+// firstPartyBaseScopes 直接登录签发的令牌始终包含的scopes，可访问用户自己的资源
+var firstPartyBaseScopes = []string{"openid", "profile", "email", "roles", "collection:read", "collection:write", "bangumi:sync"}
+
+// FirstPartyScopes 获取直接登录签发的令牌所包含的scopes
+// 番剧目录写权限和管理接口的scope只签发给持有相应角色的用户
+func (s *userService) FirstPartyScopes(ctx context.Context, userID uint) ([]string, error) {
+	scopes := append([]string{}, firstPartyBaseScopes...)
+	if s.rbacService == nil {
+		return scopes, nil
+	}
+	
+	entitled, err := s.rbacService.EntitledScopes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve user scopes: %w", err)
+	}
+	return append(scopes, entitled...), nil
+}
+
+// AccessTokenLifetime 直接登录签发的访问令牌有效期，不属于任何客户端，因此只应用realm策略
+func (s *userService) AccessTokenLifetime() time.Duration {
+	return ResolveTokenPolicy(s.realm, nil).AccessTokenLifetime
+}
+
 // GenerateAccessToken 生成访问令牌
-func (s *userService) GenerateAccessToken(userID uint, scopes []string) (string, error) {
+func (s *userService) GenerateAccessToken(ctx context.Context, userID uint, scopes []string, amr []string) (string, error) {
 	// 获取用户信息
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
@@ -288,38 +364,38 @@ func (s *userService) GenerateAccessToken(userID uint, scopes []string) (string,
 	}
 
 	// 生成JWT令牌
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+	claims := jwt.MapClaims{
 		"sub":   fmt.Sprintf("%d", user.ID),
-		"iss":   "OIDC", // 应该从配置中获取
+		"iss":   util.IssuerFromContext(ctx),
 		"aud":   "test_client",
-		"exp":   time.Now().Add(time.Hour).Unix(),
+		"exp":   time.Now().Add(s.AccessTokenLifetime()).Unix(),
 		"iat":   time.Now().Unix(),
 		"scope": strings.Join(scopes, " "),
 		"preferred_username": user.Username,
 		"email":              user.Email,
 		"name":               user.Nickname,
-	})
-
-	// 获取私钥路径
-	privateKeyPath := os.Getenv("JWT_PRIVATE_KEY_PATH")
-	if privateKeyPath == "" {
-		privateKeyPath = "config/private_key.pem"
 	}
-	
-	// 读取私钥文件
-	privateKeyData, err := os.ReadFile(privateKeyPath)
-	if err != nil {
-		return "", errors.New("读取私钥文件失败")
+	if len(amr) > 0 {
+		claims["amr"] = amr
 	}
-	
-	// 解析私钥
-	privateKey, err := s.parsePrivateKey(privateKeyData)
-	if err != nil {
-		return "", errors.New("解析私钥失败")
+
+	// 携带用户的有效角色和用户组，供资源服务器授权使用
+	if s.rbacService != nil {
+		if access, err := s.rbacService.GetUserAccess(ctx, user.ID); err == nil {
+			if len(access.EffectiveRoles) > 0 {
+				claims["roles"] = access.EffectiveRoles
+			}
+			if len(access.Groups) > 0 {
+				claims["groups"] = access.Groups
+			}
+		}
 	}
 
-	// 签名令牌
-	tokenString, err := token.SignedString(privateKey)
+	// 使用realm的签名密钥签名令牌
+	if s.jwtUtil == nil {
+		return "", errors.New("签名密钥不可用")
+	}
+	tokenString, err := s.jwtUtil.SignClaims(claims)
 	if err != nil {
 		return "", errors.New("令牌签名失败")
 	}
@@ -342,29 +418,3 @@ func (s *userService) GenerateRefreshToken(userID uint, scopes []string) (string
 	
 	return refreshToken, nil
 }
-
-// parsePrivateKey 解析私钥
-func (s *userService) parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
-	block, _ := pem.Decode(data)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block containing private key")
-	}
-	
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		// 尝试解析PKCS8格式
-		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse private key: %w", err)
-		}
-		
-		rsaKey, ok := key.(*rsa.PrivateKey)
-		if !ok {
-			return nil, fmt.Errorf("not an RSA private key")
-		}
-		
-		return rsaKey, nil
-	}
-	
-	return privateKey, nil
-}
@@ -0,0 +1,116 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/helper"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2Params 测试用的低成本argon2id参数
+var testArgon2Params = util.Argon2Params{MemoryKiB: 1024, Iterations: 1, Parallelism: 1}
+
+// newTestUserService 创建使用内存存储和指定密码哈希算法的用户服务
+func newTestUserService(t *testing.T, hasher util.PasswordHasher) (service.UserService, repository.UserRepository) {
+	t.Helper()
+	realm := &model.Realm{Name: "default"}
+	userRepo := repository.NewUserRepository(nil)
+	userService := service.NewUserService(
+		userRepo,
+		helper.NewUserHelper(hasher),
+		repository.NewVerificationTokenRepository(),
+		util.NewSimpleEmailQueue(),
+		nil,
+		realm,
+		nil,
+		service.NewPasswordPolicyService(realm, nil, nil),
+		hasher,
+	)
+	return userService, userRepo
+}
+
+func TestAuthenticateUserUpgradesBcryptHash(t *testing.T) {
+	hasher, err := util.NewPasswordHasher(util.PasswordHashArgon2id, testArgon2Params, 0)
+	if err != nil {
+		t.Fatalf("create hasher: %v", err)
+	}
+	userService, userRepo := newTestUserService(t, hasher)
+
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte("Tsukimi-Dango-42"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	if err := userRepo.Create(&model.User{Username: "alice", Email: "alice@example.com", PasswordHash: string(legacyHash), IsActive: true}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// 密码错误时不重新哈希
+	if _, err := userService.AuthenticateUser("alice", "wrong-password"); !errors.Is(err, service.ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+	user, _ := userRepo.GetByUsername("alice")
+	if user.PasswordHash != string(legacyHash) {
+		t.Fatalf("hash should not change after a failed login")
+	}
+
+	// 登录成功后升级为argon2id，之后仍可使用同一密码登录
+	if _, err := userService.AuthenticateUser("alice", "Tsukimi-Dango-42"); err != nil {
+		t.Fatalf("authenticate with bcrypt hash: %v", err)
+	}
+	user, _ = userRepo.GetByUsername("alice")
+	if !strings.HasPrefix(user.PasswordHash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("expected hash to be upgraded to argon2id, got %s", user.PasswordHash)
+	}
+	upgradedHash := user.PasswordHash
+	if _, err := userService.AuthenticateUser("alice", "Tsukimi-Dango-42"); err != nil {
+		t.Fatalf("authenticate with argon2id hash: %v", err)
+	}
+	user, _ = userRepo.GetByUsername("alice")
+	if user.PasswordHash != upgradedHash {
+		t.Fatalf("hash with current parameters should not be rehashed")
+	}
+}
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	weak, err := util.NewPasswordHasher(util.PasswordHashArgon2id, testArgon2Params, 0)
+	if err != nil {
+		t.Fatalf("create hasher: %v", err)
+	}
+	strongParams := testArgon2Params
+	strongParams.Iterations = 2
+	strong, err := util.NewPasswordHasher(util.PasswordHashArgon2id, strongParams, 0)
+	if err != nil {
+		t.Fatalf("create hasher: %v", err)
+	}
+	bcryptHasher, err := util.NewPasswordHasher(util.PasswordHashBcrypt, util.Argon2Params{}, bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("create hasher: %v", err)
+	}
+
+	weakHash, err := weak.Hash("Tsukimi-Dango-42")
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	if weak.NeedsRehash(weakHash) || !strong.NeedsRehash(weakHash) || !bcryptHasher.NeedsRehash(weakHash) {
+		t.Fatalf("unexpected rehash decision for %s", weakHash)
+	}
+
+	// 任一配置都能校验其他算法和参数的哈希
+	for _, hasher := range []util.PasswordHasher{weak, strong, bcryptHasher} {
+		if ok, err := hasher.Verify(weakHash, "Tsukimi-Dango-42"); !ok || err != nil {
+			t.Fatalf("verify argon2id hash: %v %v", ok, err)
+		}
+		if ok, _ := hasher.Verify(weakHash, "tsukimi-dango-42"); ok {
+			t.Fatalf("wrong password should not verify")
+		}
+	}
+	if _, err := weak.Verify("plaintext", "plaintext"); !errors.Is(err, util.ErrUnsupportedPasswordHash) {
+		t.Fatalf("expected unsupported hash error, got %v", err)
+	}
+}
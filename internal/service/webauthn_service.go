@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Full-finger/OIDC/internal/model"
+)
+
+// WebAuthn错误，处理器据此选择HTTP状态码
+var (
+	ErrWebAuthnCredentialNotFound = errors.New("webauthn credential not found")
+	ErrWebAuthnCredentialExists   = errors.New("webauthn credential is already registered")
+	ErrInvalidWebAuthnCeremony    = errors.New("invalid or expired webauthn ceremony")
+	ErrInvalidWebAuthnResponse    = errors.New("invalid webauthn response")
+	ErrWebAuthnSignCount          = errors.New("webauthn sign count did not increase, the authenticator may be cloned")
+)
+
+// WebAuthnRelyingParty 依赖方信息
+type WebAuthnRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// WebAuthnUserEntity 注册凭据时的用户信息，ID为Base64URL编码的用户句柄
+type WebAuthnUserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// WebAuthnCredentialParameter 可接受的凭据类型和签名算法
+type WebAuthnCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// WebAuthnCredentialDescriptor 凭据描述，ID为Base64URL编码的凭据ID
+type WebAuthnCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// WebAuthnAuthenticatorSelection 对认证器的要求
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// WebAuthnCreationOptions 注册仪式参数，前端以navigator.credentials.create({publicKey})调用
+type WebAuthnCreationOptions struct {
+	Challenge              string                         `json:"challenge"`
+	RP                     WebAuthnRelyingParty           `json:"rp"`
+	User                   WebAuthnUserEntity             `json:"user"`
+	PubKeyCredParams       []WebAuthnCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                         `json:"attestation"`
+}
+
+// WebAuthnRequestOptions 认证仪式参数，前端以navigator.credentials.get({publicKey})调用
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"`
+	Timeout          int64                          `json:"timeout"`
+	RPID             string                         `json:"rpId"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+// WebAuthnCredentialResponse 前端提交的PublicKeyCredential，二进制字段均为Base64URL编码
+type WebAuthnCredentialResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject,omitempty"` // 仅注册
+		Transports        []string `json:"transports,omitempty"`        // 仅注册
+		AuthenticatorData string   `json:"authenticatorData,omitempty"` // 仅认证
+		Signature         string   `json:"signature,omitempty"`         // 仅认证
+		UserHandle        string   `json:"userHandle,omitempty"`        // 仅认证
+	} `json:"response"`
+}
+
+// WebAuthnLoginResult 通行密钥登录的结果
+type WebAuthnLoginResult struct {
+	UserID     uint
+	Credential *model.WebAuthnCredential
+	AMR        []string
+}
+
+// WebAuthnService WebAuthn通行密钥服务接口
+type WebAuthnService interface {
+	// BeginRegistration 为用户生成注册仪式参数，要求认证器创建可发现凭据并验证用户
+	BeginRegistration(ctx context.Context, userID uint) (*WebAuthnCreationOptions, error)
+
+	// FinishRegistration 校验认证器的注册响应并保存凭据，name为空时使用默认名称
+	FinishRegistration(ctx context.Context, userID uint, name string, response *WebAuthnCredentialResponse) (*model.WebAuthnCredential, error)
+
+	// BeginLogin 生成无用户名登录的认证仪式参数，由认证器选择可发现凭据
+	BeginLogin(ctx context.Context) (*WebAuthnRequestOptions, error)
+
+	// FinishLogin 校验无用户名登录的断言，返回凭据所属用户
+	FinishLogin(ctx context.Context, response *WebAuthnCredentialResponse) (*WebAuthnLoginResult, error)
+
+	// BeginSecondFactor 为已完成第一步认证的用户生成第二步验证的认证仪式参数
+	BeginSecondFactor(ctx context.Context, userID uint) (*WebAuthnRequestOptions, error)
+
+	// FinishSecondFactor 校验第二步验证的断言，凭据必须属于该用户
+	FinishSecondFactor(ctx context.Context, userID uint, response *WebAuthnCredentialResponse) (*model.WebAuthnCredential, error)
+
+	// ListCredentials 获取用户的全部通行密钥
+	ListCredentials(ctx context.Context, userID uint) ([]*model.WebAuthnCredential, error)
+
+	// RenameCredential 重命名用户的通行密钥
+	RenameCredential(ctx context.Context, userID, credentialID uint, name string) (*model.WebAuthnCredential, error)
+
+	// DeleteCredential 删除用户的通行密钥
+	DeleteCredential(ctx context.Context, userID, credentialID uint) error
+}
@@ -0,0 +1,473 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/repository"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+const (
+	// defaultWebAuthnTimeout WebAuthn仪式的默认有效期
+	defaultWebAuthnTimeout = 5 * time.Minute
+
+	// maxWebAuthnCredentialName 通行密钥名称的最大长度
+	maxWebAuthnCredentialName = 64
+
+	// defaultWebAuthnCredentialName 未指定名称时使用的默认名称
+	defaultWebAuthnCredentialName = "通行密钥"
+)
+
+// webAuthnAlgorithms 可接受的签名算法，按偏好排序
+var webAuthnAlgorithms = []int64{util.COSEAlgES256, util.COSEAlgEdDSA, util.COSEAlgRS256}
+
+// webAuthnService WebAuthn通行密钥服务实现
+type webAuthnService struct {
+	credentialRepo repository.WebAuthnCredentialRepository
+	sessionRepo    repository.WebAuthnSessionRepository
+	userRepo       repository.UserRepository
+	realm          *model.Realm
+	timeout        time.Duration
+}
+
+// NewWebAuthnService 创建realm范围内的WebAuthnService实例，仪式有效期由WEBAUTHN_TIMEOUT_SECONDS配置
+func NewWebAuthnService(credentialRepo repository.WebAuthnCredentialRepository, sessionRepo repository.WebAuthnSessionRepository, userRepo repository.UserRepository, realm *model.Realm) WebAuthnService {
+	return &webAuthnService{
+		credentialRepo: credentialRepo,
+		sessionRepo:    sessionRepo,
+		userRepo:       userRepo,
+		realm:          realm,
+		timeout:        envSeconds("WEBAUTHN_TIMEOUT_SECONDS", defaultWebAuthnTimeout),
+	}
+}
+
+// BeginRegistration 为用户生成注册仪式参数，已注册的凭据通过excludeCredentials排除
+func (s *webAuthnService) BeginRegistration(ctx context.Context, userID uint) (*WebAuthnCreationOptions, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	credentials, err := s.credentialRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webauthn credentials: %w", err)
+	}
+
+	session, err := s.createSession(ctx, model.WebAuthnCeremonyRegistration, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := user.Nickname
+	if displayName == "" {
+		displayName = user.Username
+	}
+
+	params := make([]WebAuthnCredentialParameter, 0, len(webAuthnAlgorithms))
+	for _, alg := range webAuthnAlgorithms {
+		params = append(params, WebAuthnCredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	return &WebAuthnCreationOptions{
+		Challenge: session.Challenge,
+		RP: WebAuthnRelyingParty{
+			ID:   s.rpID(ctx),
+			Name: s.rpName(),
+		},
+		User: WebAuthnUserEntity{
+			ID:          base64.RawURLEncoding.EncodeToString(webAuthnUserHandle(userID)),
+			Name:        user.Username,
+			DisplayName: displayName,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            s.timeout.Milliseconds(),
+		ExcludeCredentials: credentialDescriptors(credentials),
+		AuthenticatorSelection: WebAuthnAuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration 校验认证器的注册响应并保存凭据
+// 注册时请求none证明，不校验证明声明，也不据此限制认证器型号
+func (s *webAuthnService) FinishRegistration(ctx context.Context, userID uint, name string, response *WebAuthnCredentialResponse) (*model.WebAuthnCredential, error) {
+	clientDataJSON, err := util.DecodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid clientDataJSON encoding", ErrInvalidWebAuthnResponse)
+	}
+	if _, err := s.consumeSession(ctx, clientDataJSON, "webauthn.create", model.WebAuthnCeremonyRegistration, userID); err != nil {
+		return nil, err
+	}
+
+	attestationObject, err := util.DecodeBase64URL(response.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid attestationObject encoding", ErrInvalidWebAuthnResponse)
+	}
+	_, rawAuthData, err := util.ParseWebAuthnAttestationObject(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err)
+	}
+	authData, err := s.verifyAuthenticatorData(ctx, rawAuthData, true)
+	if err != nil {
+		return nil, err
+	}
+	if !authData.HasFlag(util.WebAuthnFlagAttestedData) {
+		return nil, fmt.Errorf("%w: missing attested credential data", ErrInvalidWebAuthnResponse)
+	}
+	if _, _, err := util.ParseCOSEPublicKey(authData.PublicKey); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err)
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(authData.CredentialID)
+	if response.RawID != "" {
+		if rawID, err := util.DecodeBase64URL(response.RawID); err != nil || !bytes.Equal(rawID, authData.CredentialID) {
+			return nil, fmt.Errorf("%w: credential id mismatch", ErrInvalidWebAuthnResponse)
+		}
+	}
+	if _, err := s.credentialRepo.GetByCredentialID(ctx, credentialID); err == nil {
+		return nil, ErrWebAuthnCredentialExists
+	}
+
+	_, alg, _ := util.ParseCOSEPublicKey(authData.PublicKey)
+	credential := &model.WebAuthnCredential{
+		UserID:         userID,
+		CredentialID:   credentialID,
+		PublicKey:      authData.PublicKey,
+		Algorithm:      alg,
+		SignCount:      authData.SignCount,
+		Transports:     strings.Join(response.Response.Transports, " "),
+		AAGUID:         formatAAGUID(authData.AAGUID),
+		Name:           normalizeCredentialName(name),
+		BackupEligible: authData.HasFlag(util.WebAuthnFlagBackupEligible),
+		BackedUp:       authData.HasFlag(util.WebAuthnFlagBackedUp),
+	}
+	if err := s.credentialRepo.Create(ctx, credential); err != nil {
+		return nil, fmt.Errorf("failed to save webauthn credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+// BeginLogin 生成无用户名登录的认证仪式参数，allowCredentials为空
+func (s *webAuthnService) BeginLogin(ctx context.Context) (*WebAuthnRequestOptions, error) {
+	session, err := s.createSession(ctx, model.WebAuthnCeremonyLogin, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnRequestOptions{
+		Challenge:        session.Challenge,
+		Timeout:          s.timeout.Milliseconds(),
+		RPID:             s.rpID(ctx),
+		AllowCredentials: []WebAuthnCredentialDescriptor{},
+		UserVerification: "required",
+	}, nil
+}
+
+// FinishLogin 校验无用户名登录的断言，认证器必须完成用户验证
+func (s *webAuthnService) FinishLogin(ctx context.Context, response *WebAuthnCredentialResponse) (*WebAuthnLoginResult, error) {
+	credential, err := s.verifyAssertion(ctx, response, model.WebAuthnCeremonyLogin, 0, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// 通行密钥本身是持有因素，认证器的PIN或生物识别构成第二个因素
+	return &WebAuthnLoginResult{
+		UserID:     credential.UserID,
+		Credential: credential,
+		AMR:        []string{model.AMRHWK, model.AMRMFA},
+	}, nil
+}
+
+// BeginSecondFactor 生成第二步验证的认证仪式参数，只允许用户已注册的凭据
+func (s *webAuthnService) BeginSecondFactor(ctx context.Context, userID uint) (*WebAuthnRequestOptions, error) {
+	credentials, err := s.credentialRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webauthn credentials: %w", err)
+	}
+	if len(credentials) == 0 {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+
+	session, err := s.createSession(ctx, model.WebAuthnCeremonySecondFactor, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnRequestOptions{
+		Challenge:        session.Challenge,
+		Timeout:          s.timeout.Milliseconds(),
+		RPID:             s.rpID(ctx),
+		AllowCredentials: credentialDescriptors(credentials),
+		UserVerification: "discouraged",
+	}, nil
+}
+
+// FinishSecondFactor 校验第二步验证的断言，只要求用户在场
+func (s *webAuthnService) FinishSecondFactor(ctx context.Context, userID uint, response *WebAuthnCredentialResponse) (*model.WebAuthnCredential, error) {
+	return s.verifyAssertion(ctx, response, model.WebAuthnCeremonySecondFactor, userID, false)
+}
+
+// ListCredentials 获取用户的全部通行密钥
+func (s *webAuthnService) ListCredentials(ctx context.Context, userID uint) ([]*model.WebAuthnCredential, error) {
+	return s.credentialRepo.ListByUserID(ctx, userID)
+}
+
+// RenameCredential 重命名用户的通行密钥
+func (s *webAuthnService) RenameCredential(ctx context.Context, userID, credentialID uint, name string) (*model.WebAuthnCredential, error) {
+	credential, err := s.credentialRepo.GetByID(ctx, credentialID)
+	if err != nil || credential.UserID != userID {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+
+	credential.Name = normalizeCredentialName(name)
+	if err := s.credentialRepo.Update(ctx, credential); err != nil {
+		return nil, fmt.Errorf("failed to save webauthn credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+// DeleteCredential 删除用户的通行密钥
+func (s *webAuthnService) DeleteCredential(ctx context.Context, userID, credentialID uint) error {
+	credential, err := s.credentialRepo.GetByID(ctx, credentialID)
+	if err != nil || credential.UserID != userID {
+		return ErrWebAuthnCredentialNotFound
+	}
+
+	if err := s.credentialRepo.DeleteByID(ctx, credential.ID); err != nil {
+		return fmt.Errorf("failed to delete webauthn credential: %w", err)
+	}
+
+	return nil
+}
+
+// verifyAssertion 校验认证断言：仪式、凭据归属、认证器数据、签名和签名计数器，通过后更新凭据
+func (s *webAuthnService) verifyAssertion(ctx context.Context, response *WebAuthnCredentialResponse, ceremony string, userID uint, requireUserVerification bool) (*model.WebAuthnCredential, error) {
+	clientDataJSON, err := util.DecodeBase64URL(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid clientDataJSON encoding", ErrInvalidWebAuthnResponse)
+	}
+	if _, err := s.consumeSession(ctx, clientDataJSON, "webauthn.get", ceremony, userID); err != nil {
+		return nil, err
+	}
+
+	rawID, err := util.DecodeBase64URL(response.RawID)
+	if err != nil || len(rawID) == 0 {
+		return nil, fmt.Errorf("%w: invalid credential id", ErrInvalidWebAuthnResponse)
+	}
+	credential, err := s.credentialRepo.GetByCredentialID(ctx, base64.RawURLEncoding.EncodeToString(rawID))
+	if err != nil {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+	if userID != 0 && credential.UserID != userID {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+
+	// 可发现凭据必须返回用户句柄，且与凭据所属用户一致
+	userHandle, err := util.DecodeBase64URL(response.Response.UserHandle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user handle", ErrInvalidWebAuthnResponse)
+	}
+	if (userID == 0 && len(userHandle) == 0) || (len(userHandle) > 0 && !bytes.Equal(userHandle, webAuthnUserHandle(credential.UserID))) {
+		return nil, fmt.Errorf("%w: user handle mismatch", ErrInvalidWebAuthnResponse)
+	}
+
+	rawAuthData, err := util.DecodeBase64URL(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid authenticatorData encoding", ErrInvalidWebAuthnResponse)
+	}
+	authData, err := s.verifyAuthenticatorData(ctx, rawAuthData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := util.DecodeBase64URL(response.Response.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidWebAuthnResponse)
+	}
+	if err := util.VerifyWebAuthnAssertion(credential.PublicKey, rawAuthData, clientDataJSON, signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err)
+	}
+
+	// 计数器为0表示认证器不支持计数；否则必须严格递增
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		return nil, ErrWebAuthnSignCount
+	}
+
+	now := time.Now()
+	credential.SignCount = authData.SignCount
+	credential.BackedUp = authData.HasFlag(util.WebAuthnFlagBackedUp)
+	credential.LastUsedAt = &now
+	if err := s.credentialRepo.Update(ctx, credential); err != nil {
+		return nil, fmt.Errorf("failed to save webauthn credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+// createSession 创建新的WebAuthn仪式并保存服务端挑战
+func (s *webAuthnService) createSession(ctx context.Context, ceremony string, userID uint) (*model.WebAuthnSession, error) {
+	session := &model.WebAuthnSession{
+		Challenge: randomURLToken(32),
+		Ceremony:  ceremony,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(s.timeout),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to save webauthn session: %w", err)
+	}
+	return session, nil
+}
+
+// consumeSession 校验clientDataJSON并取出对应的仪式，仪式无论校验结果如何都只能使用一次
+func (s *webAuthnService) consumeSession(ctx context.Context, clientDataJSON []byte, clientDataType, ceremony string, userID uint) (*model.WebAuthnSession, error) {
+	clientData, err := util.ParseWebAuthnClientData(clientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err)
+	}
+	if clientData.Type != clientDataType {
+		return nil, fmt.Errorf("%w: unexpected client data type %q", ErrInvalidWebAuthnResponse, clientData.Type)
+	}
+
+	session, err := s.sessionRepo.GetByChallenge(ctx, clientData.Challenge)
+	if err != nil {
+		return nil, ErrInvalidWebAuthnCeremony
+	}
+	_ = s.sessionRepo.DeleteByID(ctx, session.ID)
+	if session.IsExpired() || session.Ceremony != ceremony || session.UserID != userID {
+		return nil, ErrInvalidWebAuthnCeremony
+	}
+
+	if !s.isAllowedOrigin(ctx, clientData.Origin) {
+		return nil, fmt.Errorf("%w: origin %q is not allowed", ErrInvalidWebAuthnResponse, clientData.Origin)
+	}
+
+	return session, nil
+}
+
+// verifyAuthenticatorData 校验认证器数据的依赖方ID哈希和用户在场、用户验证标志
+func (s *webAuthnService) verifyAuthenticatorData(ctx context.Context, raw []byte, requireUserVerification bool) (*util.WebAuthnAuthenticatorData, error) {
+	authData, err := util.ParseWebAuthnAuthenticatorData(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, err)
+	}
+
+	rpIDHash := sha256.Sum256([]byte(s.rpID(ctx)))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: relying party id mismatch", ErrInvalidWebAuthnResponse)
+	}
+	if !authData.HasFlag(util.WebAuthnFlagUserPresent) {
+		return nil, fmt.Errorf("%w: user presence is required", ErrInvalidWebAuthnResponse)
+	}
+	if requireUserVerification && !authData.HasFlag(util.WebAuthnFlagUserVerified) {
+		return nil, fmt.Errorf("%w: user verification is required", ErrInvalidWebAuthnResponse)
+	}
+
+	return authData, nil
+}
+
+// rpID 依赖方ID，默认为issuer的主机名，可通过WEBAUTHN_RP_ID指定为其父域名
+func (s *webAuthnService) rpID(ctx context.Context) string {
+	if rpID := os.Getenv("WEBAUTHN_RP_ID"); rpID != "" {
+		return rpID
+	}
+	if issuer, err := url.Parse(util.IssuerFromContext(ctx)); err == nil {
+		return issuer.Hostname()
+	}
+	return ""
+}
+
+// isAllowedOrigin 判断发起仪式的页面来源是否可信
+// 配置WEBAUTHN_ORIGINS（逗号分隔）时只接受其中的来源，否则接受issuer和登录页的来源
+func (s *webAuthnService) isAllowedOrigin(ctx context.Context, origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	var allowed []string
+	if configured := os.Getenv("WEBAUTHN_ORIGINS"); configured != "" {
+		allowed = strings.Split(configured, ",")
+	} else {
+		allowed = []string{originOf(util.IssuerFromContext(ctx)), originOf(os.Getenv("LOGIN_PAGE_URL"))}
+	}
+
+	for _, candidate := range allowed {
+		if strings.TrimSpace(candidate) == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// rpName 认证器中显示的依赖方名称
+func (s *webAuthnService) rpName() string {
+	if s.realm != nil && s.realm.DisplayName != "" {
+		return s.realm.DisplayName
+	}
+	if s.realm != nil && s.realm.Name != "" {
+		return s.realm.Name
+	}
+	return "OIDC"
+}
+
+// webAuthnUserHandle 用户句柄，使用用户ID的8字节大端编码，不包含个人信息
+func webAuthnUserHandle(userID uint) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+// credentialDescriptors 将凭据转换为仪式参数中的凭据描述
+func credentialDescriptors(credentials []*model.WebAuthnCredential) []WebAuthnCredentialDescriptor {
+	descriptors := make([]WebAuthnCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, WebAuthnCredentialDescriptor{
+			Type:       "public-key",
+			ID:         credential.CredentialID,
+			Transports: credential.TransportList(),
+		})
+	}
+	return descriptors
+}
+
+// normalizeCredentialName 去除名称首尾空白并限制长度，为空时使用默认名称
+func normalizeCredentialName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return defaultWebAuthnCredentialName
+	}
+	if runes := []rune(name); len(runes) > maxWebAuthnCredentialName {
+		name = string(runes[:maxWebAuthnCredentialName])
+	}
+	return name
+}
+
+// formatAAGUID 将AAGUID格式化为UUID字符串
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", aaguid[0:4], aaguid[4:6], aaguid[6:8], aaguid[8:10], aaguid[10:16])
+}
+
+// originOf 获取地址的来源（scheme://host[:port]），无法解析时返回空字符串
+func originOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
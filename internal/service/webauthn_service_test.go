@@ -0,0 +1,428 @@
+package service_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/Full-finger/OIDC/internal/model"
+	"github.com/Full-finger/OIDC/internal/service"
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+const (
+	testOrigin = testIssuer
+	testRPID   = "id.test"
+)
+
+// softAuthenticator 软件实现的WebAuthn认证器，生成ES256可发现凭据并签名断言
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+// create 按注册仪式参数创建凭据，返回none证明格式的注册响应；已有凭据时重新注册同一凭据
+func (a *softAuthenticator) create(t *testing.T, options *service.WebAuthnCreationOptions, origin string) *service.WebAuthnCredentialResponse {
+	t.Helper()
+
+	if a.key == nil {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		a.key = key
+		a.credentialID = make([]byte, 16)
+		rand.Read(a.credentialID)
+	}
+	a.userHandle = decodeB64(t, options.User.ID)
+
+	coseKey := encodeCBOR(map[interface{}]interface{}{
+		int64(1):  int64(2),
+		int64(3):  int64(-7),
+		int64(-1): int64(1),
+		int64(-2): a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		int64(-3): a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+
+	authData := a.authenticatorData(options.RP.ID, 0x01|0x04|0x40)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, coseKey...)
+
+	attestationObject := encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": authData,
+	})
+
+	response := &service.WebAuthnCredentialResponse{
+		ID:    b64(a.credentialID),
+		RawID: b64(a.credentialID),
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = b64(clientDataJSON("webauthn.create", options.Challenge, origin))
+	response.Response.AttestationObject = b64(attestationObject)
+	response.Response.Transports = []string{"internal", "hybrid"}
+	return response
+}
+
+// get 按认证仪式参数生成断言，每次签名计数器加一
+func (a *softAuthenticator) get(t *testing.T, options *service.WebAuthnRequestOptions, origin string) *service.WebAuthnCredentialResponse {
+	t.Helper()
+
+	a.signCount++
+	authData := a.authenticatorData(options.RPID, 0x01|0x04)
+	clientData := clientDataJSON("webauthn.get", options.Challenge, origin)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign assertion: %v", err)
+	}
+
+	response := &service.WebAuthnCredentialResponse{
+		ID:    b64(a.credentialID),
+		RawID: b64(a.credentialID),
+		Type:  "public-key",
+	}
+	response.Response.ClientDataJSON = b64(clientData)
+	response.Response.AuthenticatorData = b64(authData)
+	response.Response.Signature = b64(signature)
+	response.Response.UserHandle = b64(a.userHandle)
+	return response
+}
+
+// authenticatorData 生成认证器数据的固定部分
+func (a *softAuthenticator) authenticatorData(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.signCount)
+}
+
+// webAuthnFixture 在共用依赖之上注册通行密钥的测试环境
+type webAuthnFixture struct {
+	*realmHarness
+}
+
+func newWebAuthnFixture(t *testing.T) *webAuthnFixture {
+	t.Helper()
+	t.Setenv("WEBAUTHN_RP_ID", "")
+	t.Setenv("WEBAUTHN_ORIGINS", "")
+	t.Setenv("LOGIN_PAGE_URL", "")
+	return &webAuthnFixture{realmHarness: newRealmHarness(t)}
+}
+
+// register 用软件认证器为用户注册通行密钥
+func (f *webAuthnFixture) register(t *testing.T, authenticator *softAuthenticator) *model.WebAuthnCredential {
+	t.Helper()
+
+	options, err := f.webAuthn.BeginRegistration(f.ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration failed: %v", err)
+	}
+	if options.RP.ID != testRPID || !options.AuthenticatorSelection.RequireResidentKey {
+		t.Fatalf("unexpected creation options: %+v", options)
+	}
+
+	credential, err := f.webAuthn.FinishRegistration(f.ctx, f.alice.ID, "  Laptop  ", authenticator.create(t, options, testOrigin))
+	if err != nil {
+		t.Fatalf("FinishRegistration failed: %v", err)
+	}
+	return credential
+}
+
+func TestWebAuthnRegistrationAndDiscoverableLogin(t *testing.T) {
+	f := newWebAuthnFixture(t)
+	authenticator := &softAuthenticator{}
+
+	credential := f.register(t, authenticator)
+	if credential.Name != "Laptop" || credential.Algorithm != util.COSEAlgES256 || credential.Transports != "internal hybrid" {
+		t.Fatalf("unexpected credential: %+v", credential)
+	}
+
+	options, err := f.webAuthn.BeginLogin(f.ctx)
+	if err != nil {
+		t.Fatalf("BeginLogin failed: %v", err)
+	}
+	if len(options.AllowCredentials) != 0 || options.UserVerification != "required" {
+		t.Fatalf("discoverable login must not list credentials: %+v", options)
+	}
+
+	assertion := authenticator.get(t, options, testOrigin)
+	result, err := f.webAuthn.FinishLogin(f.ctx, assertion)
+	if err != nil {
+		t.Fatalf("FinishLogin failed: %v", err)
+	}
+	if result.UserID != f.alice.ID {
+		t.Fatalf("expected user %d, got %d", f.alice.ID, result.UserID)
+	}
+	if got := result.AMR; len(got) != 2 || got[0] != model.AMRHWK || got[1] != model.AMRMFA {
+		t.Fatalf("unexpected amr: %v", got)
+	}
+	if result.Credential.SignCount != 1 || result.Credential.LastUsedAt == nil {
+		t.Fatalf("sign count and last use were not updated: %+v", result.Credential)
+	}
+
+	// 同一断言不能重放
+	if _, err := f.webAuthn.FinishLogin(f.ctx, assertion); !errors.Is(err, service.ErrInvalidWebAuthnCeremony) {
+		t.Fatalf("expected replay to fail with ErrInvalidWebAuthnCeremony, got %v", err)
+	}
+}
+
+func TestWebAuthnRejectsInvalidAssertions(t *testing.T) {
+	f := newWebAuthnFixture(t)
+	authenticator := &softAuthenticator{}
+	f.register(t, authenticator)
+
+	begin := func() *service.WebAuthnRequestOptions {
+		options, err := f.webAuthn.BeginLogin(f.ctx)
+		if err != nil {
+			t.Fatalf("BeginLogin failed: %v", err)
+		}
+		return options
+	}
+
+	t.Run("origin", func(t *testing.T) {
+		_, err := f.webAuthn.FinishLogin(f.ctx, authenticator.get(t, begin(), "https://evil.example.com"))
+		if !errors.Is(err, service.ErrInvalidWebAuthnResponse) {
+			t.Fatalf("expected ErrInvalidWebAuthnResponse, got %v", err)
+		}
+	})
+
+	t.Run("relying party", func(t *testing.T) {
+		options := begin()
+		options.RPID = "evil.example.com"
+		_, err := f.webAuthn.FinishLogin(f.ctx, authenticator.get(t, options, testOrigin))
+		if !errors.Is(err, service.ErrInvalidWebAuthnResponse) {
+			t.Fatalf("expected ErrInvalidWebAuthnResponse, got %v", err)
+		}
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		assertion := authenticator.get(t, begin(), testOrigin)
+		signature := decodeB64(t, assertion.Response.Signature)
+		signature[len(signature)-1] ^= 0xff
+		assertion.Response.Signature = b64(signature)
+		if _, err := f.webAuthn.FinishLogin(f.ctx, assertion); !errors.Is(err, service.ErrInvalidWebAuthnResponse) {
+			t.Fatalf("expected ErrInvalidWebAuthnResponse, got %v", err)
+		}
+	})
+
+	t.Run("cloned authenticator", func(t *testing.T) {
+		if _, err := f.webAuthn.FinishLogin(f.ctx, authenticator.get(t, begin(), testOrigin)); err != nil {
+			t.Fatalf("FinishLogin failed: %v", err)
+		}
+		clone := *authenticator
+		clone.signCount--
+		if _, err := f.webAuthn.FinishLogin(f.ctx, clone.get(t, begin(), testOrigin)); !errors.Is(err, service.ErrWebAuthnSignCount) {
+			t.Fatalf("expected ErrWebAuthnSignCount, got %v", err)
+		}
+	})
+
+	t.Run("duplicate registration", func(t *testing.T) {
+		options, err := f.webAuthn.BeginRegistration(f.ctx, f.alice.ID)
+		if err != nil {
+			t.Fatalf("BeginRegistration failed: %v", err)
+		}
+		if len(options.ExcludeCredentials) != 1 {
+			t.Fatalf("expected registered credential to be excluded, got %+v", options.ExcludeCredentials)
+		}
+		duplicate := *authenticator
+		_, err = f.webAuthn.FinishRegistration(f.ctx, f.alice.ID, "", duplicate.create(t, options, testOrigin))
+		if !errors.Is(err, service.ErrWebAuthnCredentialExists) {
+			t.Fatalf("expected ErrWebAuthnCredentialExists, got %v", err)
+		}
+	})
+}
+
+func TestWebAuthnSecondFactor(t *testing.T) {
+	f := newWebAuthnFixture(t)
+	password := []string{model.AMRPassword}
+
+	challenge, err := f.mfa.BeginChallenge(f.ctx, f.alice.ID, password)
+	if err != nil || challenge != nil {
+		t.Fatalf("second factor must not be required before a passkey is registered: %v %v", challenge, err)
+	}
+
+	authenticator := &softAuthenticator{}
+	f.register(t, authenticator)
+
+	challenge, err = f.mfa.BeginChallenge(f.ctx, f.alice.ID, password)
+	if err != nil || challenge == nil {
+		t.Fatalf("expected a second factor challenge, got %v %v", challenge, err)
+	}
+	if methods := f.mfa.Methods(f.ctx, f.alice.ID); len(methods) != 1 || methods[0] != "webauthn" {
+		t.Fatalf("unexpected methods: %v", methods)
+	}
+
+	// 只注册了通行密钥时输入验证码按错误计数，不会使挑战失效
+	if _, err := f.mfa.CompleteChallenge(f.ctx, challenge.Token, "123456"); !errors.Is(err, service.ErrInvalidMFACode) {
+		t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+	}
+
+	options, err := f.webAuthn.BeginSecondFactor(f.ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("BeginSecondFactor failed: %v", err)
+	}
+	if len(options.AllowCredentials) != 1 || options.AllowCredentials[0].ID != b64(authenticator.credentialID) {
+		t.Fatalf("unexpected allowCredentials: %+v", options.AllowCredentials)
+	}
+
+	// 第二步验证的挑战不能用于无用户名登录
+	if _, err := f.webAuthn.FinishLogin(f.ctx, authenticator.get(t, options, testOrigin)); !errors.Is(err, service.ErrInvalidWebAuthnCeremony) {
+		t.Fatalf("expected ErrInvalidWebAuthnCeremony, got %v", err)
+	}
+
+	options, _ = f.webAuthn.BeginSecondFactor(f.ctx, f.alice.ID)
+	if _, err := f.webAuthn.FinishSecondFactor(f.ctx, f.alice.ID, authenticator.get(t, options, testOrigin)); err != nil {
+		t.Fatalf("FinishSecondFactor failed: %v", err)
+	}
+
+	result, err := f.mfa.FinishChallenge(f.ctx, challenge, model.AMRHWK)
+	if err != nil {
+		t.Fatalf("FinishChallenge failed: %v", err)
+	}
+	if got := result.AMR; len(got) != 2 || got[0] != model.AMRPassword || got[1] != model.AMRHWK {
+		t.Fatalf("unexpected amr: %v", got)
+	}
+	if _, err := f.mfa.FinishChallenge(f.ctx, challenge, model.AMRHWK); !errors.Is(err, service.ErrInvalidMFAChallenge) {
+		t.Fatalf("expected challenge to be single use, got %v", err)
+	}
+	if f.mfa.RequiresSecondFactor(f.ctx, f.alice.ID, result.AMR) {
+		t.Fatal("amr containing hwk must satisfy the second factor")
+	}
+}
+
+func TestWebAuthnCredentialManagement(t *testing.T) {
+	f := newWebAuthnFixture(t)
+	first := f.register(t, &softAuthenticator{})
+	second := f.register(t, &softAuthenticator{})
+
+	credentials, err := f.webAuthn.ListCredentials(f.ctx, f.alice.ID)
+	if err != nil || len(credentials) != 2 {
+		t.Fatalf("expected 2 credentials, got %d (%v)", len(credentials), err)
+	}
+
+	renamed, err := f.webAuthn.RenameCredential(f.ctx, f.alice.ID, first.ID, "Phone")
+	if err != nil || renamed.Name != "Phone" {
+		t.Fatalf("RenameCredential failed: %v %+v", err, renamed)
+	}
+	if _, err := f.webAuthn.RenameCredential(f.ctx, f.alice.ID+1, first.ID, "Mine"); !errors.Is(err, service.ErrWebAuthnCredentialNotFound) {
+		t.Fatalf("expected other users to be rejected, got %v", err)
+	}
+
+	if err := f.webAuthn.DeleteCredential(f.ctx, f.alice.ID+1, second.ID); !errors.Is(err, service.ErrWebAuthnCredentialNotFound) {
+		t.Fatalf("expected other users to be rejected, got %v", err)
+	}
+	if err := f.webAuthn.DeleteCredential(f.ctx, f.alice.ID, second.ID); err != nil {
+		t.Fatalf("DeleteCredential failed: %v", err)
+	}
+
+	status, err := f.mfa.GetStatus(f.ctx, f.alice.ID)
+	if err != nil || status.WebAuthnCredentials != 1 {
+		t.Fatalf("expected 1 credential in mfa status, got %+v (%v)", status, err)
+	}
+
+	if err := f.mfa.ResetMFA(f.ctx, f.alice.ID); err != nil {
+		t.Fatalf("ResetMFA failed: %v", err)
+	}
+	if credentials, _ := f.webAuthn.ListCredentials(f.ctx, f.alice.ID); len(credentials) != 0 {
+		t.Fatalf("expected reset to delete passkeys, got %d", len(credentials))
+	}
+}
+
+// clientDataJSON 生成浏览器格式的clientDataJSON
+func clientDataJSON(ceremonyType, challenge, origin string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":        ceremonyType,
+		"challenge":   challenge,
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+	return data
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeB64(t *testing.T, value string) []byte {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatalf("invalid base64url %q: %v", value, err)
+	}
+	return data
+}
+
+// encodeCBOR 软件认证器使用的最小CBOR编码，映射键按CTAP2规范排序
+func encodeCBOR(value interface{}) []byte {
+	var buf bytes.Buffer
+	writeCBOR(&buf, value)
+	return buf.Bytes()
+}
+
+func writeCBOR(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case int64:
+		if v >= 0 {
+			writeCBORHead(buf, 0, uint64(v))
+		} else {
+			writeCBORHead(buf, 1, uint64(-1-v))
+		}
+	case []byte:
+		writeCBORHead(buf, 2, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeCBORHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case map[interface{}]interface{}:
+		encodedKeys := make([][]byte, 0, len(v))
+		values := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			encoded := encodeCBOR(key)
+			encodedKeys = append(encodedKeys, encoded)
+			values[string(encoded)] = item
+		}
+		sort.Slice(encodedKeys, func(i, j int) bool {
+			if len(encodedKeys[i]) != len(encodedKeys[j]) {
+				return len(encodedKeys[i]) < len(encodedKeys[j])
+			}
+			return bytes.Compare(encodedKeys[i], encodedKeys[j]) < 0
+		})
+		writeCBORHead(buf, 5, uint64(len(v)))
+		for _, key := range encodedKeys {
+			buf.Write(key)
+			writeCBOR(buf, values[string(key)])
+		}
+	default:
+		panic("unsupported cbor value")
+	}
+}
+
+func writeCBORHead(buf *bytes.Buffer, major byte, argument uint64) {
+	switch {
+	case argument < 24:
+		buf.WriteByte(major<<5 | byte(argument))
+	case argument <= 0xff:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(argument))
+	case argument <= 0xffff:
+		buf.WriteByte(major<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(argument)))
+	default:
+		buf.WriteByte(major<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(argument)))
+	}
+}
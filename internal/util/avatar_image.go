@@ -0,0 +1,141 @@
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"  // 注册GIF解码器
+	_ "image/jpeg" // 注册JPEG解码器
+	"image/png"
+)
+
+// avatarMaxDimension 解码前允许的最大图片边长，防止体积很小但解码后占用大量内存的图片
+const avatarMaxDimension = 4096
+
+var (
+	// ErrUnsupportedImage 图片格式不受支持或内容损坏
+	ErrUnsupportedImage = errors.New("unsupported or corrupt image")
+	// ErrImageTooLarge 图片尺寸超过限制
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+)
+
+// ProcessAvatar 校验上传的头像并重新编码：支持JPEG、PNG和GIF（取第一帧），
+// 居中裁剪为正方形并缩放为size×size的PNG，重新编码会去除EXIF等元数据
+func ProcessAvatar(data []byte, size int) ([]byte, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if format != "jpeg" && format != "png" && format != "gif" {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width > avatarMaxDimension || config.Height > avatarMaxDimension {
+		return nil, ErrImageTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	// 居中裁剪为正方形
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, resizeArea(src, crop, size)); err != nil {
+		return nil, fmt.Errorf("failed to encode avatar: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resizeArea 将src中rect区域缩放为size×size，每个目标像素取覆盖区域内源像素的平均值；
+// 源区域小于目标尺寸时按最近邻放大
+func resizeArea(src image.Image, rect image.Rectangle, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	side := rect.Dx()
+
+	for y := 0; y < size; y++ {
+		y0 := rect.Min.Y + y*side/size
+		y1 := rect.Min.Y + (y+1)*side/size
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < size; x++ {
+			x0 := rect.Min.X + x*side/size
+			x1 := rect.Min.X + (x+1)*side/size
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			// RGBA()返回预乘颜色，写入NRGBA时自动转换为非预乘颜色
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+// GenerateIdenticon 根据seed生成对称的5×5方块头像，同一seed始终生成相同的图片
+func GenerateIdenticon(seed string, size int) ([]byte, error) {
+	const grid = 5
+	sum := sha256.Sum256([]byte(seed))
+
+	fg := color.NRGBA{R: 64 + sum[0]%160, G: 64 + sum[1]%160, B: 64 + sum[2]%160, A: 255}
+	bg := color.NRGBA{R: 240, G: 240, B: 240, A: 255}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+
+	// 留出边距后划分格子，左侧三列由哈希决定，右侧两列镜像
+	padding := size / 10
+	cell := (size - 2*padding) / grid
+	offset := (size - cell*grid) / 2
+	for row := 0; row < grid; row++ {
+		for col := 0; col < (grid+1)/2; col++ {
+			if sum[3+row*3+col]%2 == 0 {
+				continue
+			}
+			for _, c := range []int{col, grid - 1 - col} {
+				rect := image.Rect(offset+c*cell, offset+row*cell, offset+(c+1)*cell, offset+(row+1)*cell)
+				draw.Draw(img, rect, &image.Uniform{C: fg}, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode identicon: %w", err)
+	}
+	return buf.Bytes(), nil
+}
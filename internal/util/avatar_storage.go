@@ -0,0 +1,269 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AvatarStorage 头像存储接口，按部署环境使用本地磁盘或S3兼容的对象存储
+type AvatarStorage interface {
+	// Put 保存头像，key为"/"分隔的相对路径，返回可公开访问的URL
+	Put(ctx context.Context, key, contentType string, data []byte) (string, error)
+
+	// Delete 删除头像，key不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// NewAvatarStorageFromEnv 根据AVATAR_STORAGE创建头像存储：local（默认）保存在AVATAR_STORAGE_DIR下，
+// s3使用S3_ENDPOINT、S3_REGION、S3_BUCKET、S3_ACCESS_KEY_ID、S3_SECRET_ACCESS_KEY和S3_PUBLIC_URL
+func NewAvatarStorageFromEnv() (AvatarStorage, error) {
+	switch backend := getEnv("AVATAR_STORAGE", "local"); backend {
+	case "local":
+		return NewLocalAvatarStorage(AvatarStorageDir(), getEnv("AVATAR_BASE_URL", ConfiguredIssuer()+LocalAvatarPath))
+	case "s3":
+		return NewS3AvatarStorage(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		})
+	default:
+		return nil, fmt.Errorf("unsupported avatar storage: %s", backend)
+	}
+}
+
+// LocalAvatarPath 本地存储的头像由服务自身在该路径下提供
+const LocalAvatarPath = "/uploads/avatars"
+
+// AvatarStorageDir 本地头像存储目录，由AVATAR_STORAGE_DIR配置
+func AvatarStorageDir() string {
+	return getEnv("AVATAR_STORAGE_DIR", filepath.Join("uploads", "avatars"))
+}
+
+// cleanAvatarKey 校验key只包含相对路径，防止写出存储目录
+func cleanAvatarKey(key string) (string, error) {
+	cleaned := strings.TrimPrefix(filepath.ToSlash(filepath.Clean("/"+key)), "/")
+	if cleaned == "" || cleaned != key {
+		return "", fmt.Errorf("invalid avatar key: %s", key)
+	}
+	return cleaned, nil
+}
+
+// localAvatarStorage 保存在本地磁盘的头像存储
+type localAvatarStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalAvatarStorage 创建本地磁盘头像存储，baseURL为对外提供dir下文件的URL前缀
+func NewLocalAvatarStorage(dir, baseURL string) (AvatarStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create avatar directory: %w", err)
+	}
+	return &localAvatarStorage{
+		dir:     dir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}, nil
+}
+
+// Put 写入临时文件后重命名，避免读取到写了一半的文件
+func (s *localAvatarStorage) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	key, err := cleanAvatarKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create avatar directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".avatar-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create avatar file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write avatar file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write avatar file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write avatar file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to save avatar file: %w", err)
+	}
+
+	return s.baseURL + "/" + key, nil
+}
+
+// Delete 删除本地头像文件
+func (s *localAvatarStorage) Delete(ctx context.Context, key string) error {
+	key, err := cleanAvatarKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key))); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete avatar file: %w", err)
+	}
+	return nil
+}
+
+// S3Config S3兼容对象存储配置
+type S3Config struct {
+	Endpoint        string // 如https://s3.us-east-1.amazonaws.com或MinIO地址，使用路径风格访问存储桶
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicURL       string // 对外访问头像的URL前缀（如CDN地址），为空时使用{Endpoint}/{Bucket}
+	HTTPClient      *http.Client
+}
+
+// s3AvatarStorage 使用AWS Signature Version 4签名请求的S3兼容对象存储
+type s3AvatarStorage struct {
+	config     S3Config
+	endpoint   *url.URL
+	publicURL  string
+	httpClient *http.Client
+}
+
+// NewS3AvatarStorage 创建S3兼容的头像存储，头像对象需通过存储桶策略或CDN公开读取
+func NewS3AvatarStorage(config S3Config) (AvatarStorage, error) {
+	if config.Endpoint == "" || config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("S3 endpoint, bucket and credentials are required")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint: %s", config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	publicURL := strings.TrimRight(config.PublicURL, "/")
+	if publicURL == "" {
+		publicURL = endpoint.String() + "/" + config.Bucket
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &s3AvatarStorage{
+		config:     config,
+		endpoint:   endpoint,
+		publicURL:  publicURL,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Put 上传头像对象
+func (s *s3AvatarStorage) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	key, err := cleanAvatarKey(key)
+	if err != nil {
+		return "", err
+	}
+	headers := map[string]string{
+		"Content-Type":  contentType,
+		"Cache-Control": "public, max-age=31536000, immutable",
+	}
+	if err := s.do(ctx, http.MethodPut, key, data, headers); err != nil {
+		return "", err
+	}
+	return s.publicURL + "/" + key, nil
+}
+
+// Delete 删除头像对象，S3对不存在的对象同样返回成功
+func (s *s3AvatarStorage) Delete(ctx context.Context, key string) error {
+	key, err := cleanAvatarKey(key)
+	if err != nil {
+		return err
+	}
+	return s.do(ctx, http.MethodDelete, key, nil, nil)
+}
+
+// do 发送签名后的对象请求
+func (s *s3AvatarStorage) do(ctx context.Context, method, key string, body []byte, headers map[string]string) error {
+	objectURL := *s.endpoint
+	objectURL.Path = s.endpoint.Path + "/" + s.config.Bucket + "/" + key
+
+	req, err := http.NewRequestWithContext(ctx, method, objectURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	s.sign(req, body, time.Now().UTC())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 %s %s returned %d: %s", method, key, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// sign 使用AWS Signature Version 4为请求添加签名，签名覆盖host、x-amz-content-sha256和x-amz-date
+func (s *s3AvatarStorage) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	payloadHex := hex.EncodeToString(payloadHash[:])
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Content-Sha256", payloadHex)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHex + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHex,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, s.config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
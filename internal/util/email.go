@@ -4,13 +4,34 @@ import (
 	"fmt"
 	"log"
 	"net/smtp"
+	"net/url"
 	"os"
+	"sync"
+	"time"
 )
 
 // EmailService 邮件服务接口
 type EmailService interface {
-	// SendVerificationEmail 发送验证邮件
-	SendVerificationEmail(email, token string) error
+	// SendVerificationEmail 发送验证邮件，basePath为用户所属realm的路由前缀
+	SendVerificationEmail(email, token, basePath string) error
+	
+	// SendBackchannelAuthEmail 发送CIBA认证批准邮件，用户通过链接进入批准页面
+	SendBackchannelAuthEmail(email, authReqID, basePath, clientName, bindingMessage string) error
+	
+	// SendPasswordResetEmail 发送重置密码邮件，用户通过链接进入重置密码页面
+	SendPasswordResetEmail(email, token, basePath string) error
+	
+	// SendEmailChangeConfirmationEmail 向新邮箱发送确认邮件，用户通过链接确认邮箱变更
+	SendEmailChangeConfirmationEmail(email, token, basePath string) error
+	
+	// SendEmailChangeNoticeEmail 向原邮箱发送邮箱变更通知，附带撤销变更的链接
+	SendEmailChangeNoticeEmail(email, newEmail, revertToken, basePath string) error
+	
+	// SendAccountLockedEmail 通知用户账户因多次登录失败被临时锁定
+	SendAccountLockedEmail(email string, lockedUntil time.Time) error
+	
+	// SendAccountDeletionEmail 通知用户账户已计划删除，以及宽限期内取消删除的方式
+	SendAccountDeletionEmail(email string, deletionScheduledAt time.Time) error
 }
 
 // emailService 邮件服务实现
@@ -41,12 +62,12 @@ func getEnv(key, defaultValue string) string {
 }
 
 // SendVerificationEmail 发送验证邮件
-func (e *emailService) SendVerificationEmail(email, token string) error {
+func (e *emailService) SendVerificationEmail(email, token, basePath string) error {
 	// 邮件主题
 	subject := "请验证您的邮箱地址"
 	
 	// 邮件内容
-	verificationURL := fmt.Sprintf("http://localhost:8080/api/v1/verify?token=%s", token)
+	verificationURL := fmt.Sprintf("%s%s/api/v1/verify?token=%s", ConfiguredIssuer(), basePath, token)
 	
 	// 构造邮件内容
 	message := fmt.Sprintf(
@@ -78,10 +99,265 @@ func (e *emailService) SendVerificationEmail(email, token string) error {
 	return nil
 }
 
+// SendBackchannelAuthEmail 发送CIBA认证批准邮件
+func (e *emailService) SendBackchannelAuthEmail(email, authReqID, basePath, clientName, bindingMessage string) error {
+	// 邮件主题
+	subject := "登录请求待您确认"
+	
+	// 批准页面链接，页面通过issuer调用对应realm的批准接口
+	approvalURL := fmt.Sprintf("%s?auth_req_id=%s&issuer=%s",
+		getEnv("CIBA_APPROVAL_PAGE_URL", "http://localhost:3000/approve"),
+		url.QueryEscape(authReqID),
+		url.QueryEscape(ConfiguredIssuer()+basePath),
+	)
+	
+	// 构造邮件内容
+	message := fmt.Sprintf(
+		"%s 正在请求以您的身份登录。\n\n"+
+		"请确认对方设备上显示的绑定消息与下方一致：\n%s\n\n"+
+		"点击以下链接批准或拒绝该请求：\n%s\n\n"+
+		"如果这不是您本人发起的操作，请拒绝该请求。",
+		clientName, bindingMessage, approvalURL,
+	)
+	
+	// 构造完整的邮件
+	fullMessage := fmt.Sprintf(
+		"To: %s\r\n"+
+		"Subject: %s\r\n"+
+		"\r\n"+
+		"%s",
+		email, subject, message,
+	)
+	
+	// 发送邮件
+	auth := smtp.PlainAuth("", e.senderEmail, e.senderPassword, e.smtpHost)
+	err := smtp.SendMail(e.smtpHost+":"+e.smtpPort, auth, e.senderEmail, []string{email}, []byte(fullMessage))
+	if err != nil {
+		log.Printf("发送认证批准邮件失败: %v", err)
+		return err
+	}
+	
+	log.Printf("认证批准邮件已发送到: %s", email)
+	return nil
+}
+
+// SendPasswordResetEmail 发送重置密码邮件
+func (e *emailService) SendPasswordResetEmail(email, token, basePath string) error {
+	// 邮件主题
+	subject := "重置您的密码"
+	
+	// 重置密码页面链接，页面通过issuer调用对应realm的重置密码接口
+	resetURL := fmt.Sprintf("%s?token=%s&issuer=%s",
+		getEnv("PASSWORD_RESET_PAGE_URL", "http://localhost:3000/reset-password"),
+		url.QueryEscape(token),
+		url.QueryEscape(ConfiguredIssuer()+basePath),
+	)
+	
+	// 构造邮件内容
+	message := fmt.Sprintf(
+		"我们收到了重置您账户密码的请求。\n\n"+
+		"请点击以下链接设置新密码，链接只能使用一次：\n%s\n\n"+
+		"如果这不是您本人发起的操作，请忽略这封邮件，您的密码不会改变。",
+		resetURL,
+	)
+	
+	// 构造完整的邮件
+	fullMessage := fmt.Sprintf(
+		"To: %s\r\n"+
+		"Subject: %s\r\n"+
+		"\r\n"+
+		"%s",
+		email, subject, message,
+	)
+	
+	// 发送邮件
+	auth := smtp.PlainAuth("", e.senderEmail, e.senderPassword, e.smtpHost)
+	err := smtp.SendMail(e.smtpHost+":"+e.smtpPort, auth, e.senderEmail, []string{email}, []byte(fullMessage))
+	if err != nil {
+		log.Printf("发送重置密码邮件失败: %v", err)
+		return err
+	}
+	
+	log.Printf("重置密码邮件已发送到: %s", email)
+	return nil
+}
+
+// emailChangePageURL 邮箱变更页面链接，页面通过issuer调用对应realm的确认或撤销接口
+func emailChangePageURL(action, token, basePath string) string {
+	return fmt.Sprintf("%s?action=%s&token=%s&issuer=%s",
+		getEnv("EMAIL_CHANGE_PAGE_URL", "http://localhost:3000/email-change"),
+		action,
+		url.QueryEscape(token),
+		url.QueryEscape(ConfiguredIssuer()+basePath),
+	)
+}
+
+// SendEmailChangeConfirmationEmail 发送邮箱变更确认邮件
+func (e *emailService) SendEmailChangeConfirmationEmail(email, token, basePath string) error {
+	// 邮件主题
+	subject := "请确认您的新邮箱地址"
+	
+	// 构造邮件内容
+	message := fmt.Sprintf(
+		"您申请将账户邮箱更改为此地址。\n\n"+
+		"请点击以下链接确认变更，链接只能使用一次：\n%s\n\n"+
+		"如果这不是您本人发起的操作，请忽略这封邮件。",
+		emailChangePageURL("confirm", token, basePath),
+	)
+	
+	// 构造完整的邮件
+	fullMessage := fmt.Sprintf(
+		"To: %s\r\n"+
+		"Subject: %s\r\n"+
+		"\r\n"+
+		"%s",
+		email, subject, message,
+	)
+	
+	// 发送邮件
+	auth := smtp.PlainAuth("", e.senderEmail, e.senderPassword, e.smtpHost)
+	err := smtp.SendMail(e.smtpHost+":"+e.smtpPort, auth, e.senderEmail, []string{email}, []byte(fullMessage))
+	if err != nil {
+		log.Printf("发送邮箱变更确认邮件失败: %v", err)
+		return err
+	}
+	
+	log.Printf("邮箱变更确认邮件已发送到: %s", email)
+	return nil
+}
+
+// SendEmailChangeNoticeEmail 发送邮箱变更通知邮件
+func (e *emailService) SendEmailChangeNoticeEmail(email, newEmail, revertToken, basePath string) error {
+	// 邮件主题
+	subject := "您的账户邮箱正在被更改"
+	
+	// 构造邮件内容
+	message := fmt.Sprintf(
+		"有人申请将您的账户邮箱更改为 %s，新邮箱确认后变更生效。\n\n"+
+		"如果这不是您本人发起的操作，请点击以下链接撤销变更，原邮箱将被恢复，所有设备上的登录都会失效：\n%s\n\n"+
+		"如果是您本人的操作，请忽略这封邮件。",
+		newEmail, emailChangePageURL("revert", revertToken, basePath),
+	)
+	
+	// 构造完整的邮件
+	fullMessage := fmt.Sprintf(
+		"To: %s\r\n"+
+		"Subject: %s\r\n"+
+		"\r\n"+
+		"%s",
+		email, subject, message,
+	)
+	
+	// 发送邮件
+	auth := smtp.PlainAuth("", e.senderEmail, e.senderPassword, e.smtpHost)
+	err := smtp.SendMail(e.smtpHost+":"+e.smtpPort, auth, e.senderEmail, []string{email}, []byte(fullMessage))
+	if err != nil {
+		log.Printf("发送邮箱变更通知邮件失败: %v", err)
+		return err
+	}
+	
+	log.Printf("邮箱变更通知邮件已发送到: %s", email)
+	return nil
+}
+
+// SendAccountLockedEmail 发送账户锁定通知邮件
+func (e *emailService) SendAccountLockedEmail(email string, lockedUntil time.Time) error {
+	// 邮件主题
+	subject := "您的账户已被临时锁定"
+	
+	// 构造邮件内容
+	message := fmt.Sprintf(
+		"您的账户连续多次登录失败，为保护账户安全，已被锁定至 %s。\n\n"+
+		"锁定期间无法使用密码登录，到期后自动解除，也可以联系管理员提前解除。\n\n"+
+		"如果这不是您本人的操作，说明有人正在尝试登录您的账户，建议在解除锁定后使用忘记密码功能设置新密码，并启用两步验证。",
+		lockedUntil.Format("2006-01-02 15:04:05 MST"),
+	)
+	
+	// 构造完整的邮件
+	fullMessage := fmt.Sprintf(
+		"To: %s\r\n"+
+		"Subject: %s\r\n"+
+		"\r\n"+
+		"%s",
+		email, subject, message,
+	)
+	
+	// 发送邮件
+	auth := smtp.PlainAuth("", e.senderEmail, e.senderPassword, e.smtpHost)
+	err := smtp.SendMail(e.smtpHost+":"+e.smtpPort, auth, e.senderEmail, []string{email}, []byte(fullMessage))
+	if err != nil {
+		log.Printf("发送账户锁定通知邮件失败: %v", err)
+		return err
+	}
+	
+	log.Printf("账户锁定通知邮件已发送到: %s", email)
+	return nil
+}
+
+// SendAccountDeletionEmail 发送账户删除通知邮件
+func (e *emailService) SendAccountDeletionEmail(email string, deletionScheduledAt time.Time) error {
+	// 邮件主题
+	subject := "您的账户将被删除"
+	
+	// 构造邮件内容
+	message := fmt.Sprintf(
+		"我们收到了删除您账户的申请，账户及其全部数据（资料、收藏、Bangumi绑定和应用授权）将于 %s 被永久删除。\n\n"+
+		"所有设备上的登录已经失效。在此之前重新登录，即可在账户设置中取消删除。\n\n"+
+		"如果这不是您本人发起的操作，请立即登录取消删除，并修改密码。",
+		deletionScheduledAt.Format("2006-01-02 15:04:05 MST"),
+	)
+	
+	// 构造完整的邮件
+	fullMessage := fmt.Sprintf(
+		"To: %s\r\n"+
+		"Subject: %s\r\n"+
+		"\r\n"+
+		"%s",
+		email, subject, message,
+	)
+	
+	// 发送邮件
+	auth := smtp.PlainAuth("", e.senderEmail, e.senderPassword, e.smtpHost)
+	err := smtp.SendMail(e.smtpHost+":"+e.smtpPort, auth, e.senderEmail, []string{email}, []byte(fullMessage))
+	if err != nil {
+		log.Printf("发送账户删除通知邮件失败: %v", err)
+		return err
+	}
+	
+	log.Printf("账户删除通知邮件已发送到: %s", email)
+	return nil
+}
+
+// 邮件类型
+const (
+	EmailTypeVerification      = ""                    // 邮箱验证邮件
+	EmailTypeBackchannelAuth   = "backchannel_auth"    // CIBA认证批准邮件
+	EmailTypePasswordReset     = "password_reset"      // 重置密码邮件
+	EmailTypeEmailChange       = "email_change"        // 发往新邮箱的变更确认邮件
+	EmailTypeEmailChangeNotice = "email_change_notice" // 发往原邮箱的变更通知邮件
+	EmailTypeAccountLocked     = "account_locked"      // 账户锁定通知邮件
+	EmailTypeAccountDeletion   = "account_deletion"    // 账户计划删除通知邮件
+)
+
 // EmailQueueItem 邮件队列项
 type EmailQueueItem struct {
-	Email string `json:"email"`
-	Token string `json:"token"`
+	Email    string `json:"email"`
+	Token    string `json:"token"`
+	BasePath string `json:"base_path,omitempty"` // 用户所属realm的路由前缀
+	Type     string `json:"type,omitempty"`      // 邮件类型，为空表示邮箱验证邮件
+	
+	// CIBA认证批准邮件使用，Token为auth_req_id
+	ClientName     string `json:"client_name,omitempty"`
+	BindingMessage string `json:"binding_message,omitempty"`
+	
+	// 邮箱变更通知邮件使用，Token为撤销令牌
+	NewEmail string `json:"new_email,omitempty"`
+	
+	// 账户锁定通知邮件使用
+	LockedUntil time.Time `json:"locked_until,omitempty"`
+	
+	// 账户删除通知邮件使用
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at,omitempty"`
 }
 
 // EmailQueue 邮件队列接口
@@ -94,6 +370,7 @@ type EmailQueue interface {
 
 // SimpleEmailQueue 简单邮件队列实现（使用内存队列模拟）
 type SimpleEmailQueue struct {
+	mu    sync.Mutex
 	queue []EmailQueueItem
 }
 
@@ -106,6 +383,8 @@ func NewSimpleEmailQueue() EmailQueue {
 
 // Enqueue 将邮件任务加入队列
 func (q *SimpleEmailQueue) Enqueue(item EmailQueueItem) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	q.queue = append(q.queue, item)
 	fmt.Printf("邮件任务已加入队列: %s\n", item.Email)
 	return nil
@@ -113,6 +392,8 @@ func (q *SimpleEmailQueue) Enqueue(item EmailQueueItem) error {
 
 // Dequeue 从队列中取出邮件任务
 func (q *SimpleEmailQueue) Dequeue() (*EmailQueueItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	if len(q.queue) == 0 {
 		return nil, fmt.Errorf("队列为空")
 	}
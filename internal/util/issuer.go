@@ -0,0 +1,62 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// issuerContextKey 上下文中存放issuer的键
+type issuerContextKey struct{}
+
+// ConfiguredIssuer 获取配置的issuer URL
+// 优先使用ISSUER_URL，未配置时回退到本地监听地址（仅用于本地开发和测试，服务启动时要求配置ISSUER_URL）
+func ConfiguredIssuer() string {
+	if issuer := strings.TrimSpace(getEnv("ISSUER_URL", "")); issuer != "" {
+		return strings.TrimRight(issuer, "/")
+	}
+	return "http://localhost:" + getEnv("PORT", "8080")
+}
+
+// ValidateIssuerConfig 校验ISSUER_URL已配置且为不含查询参数和片段的绝对http(s)地址
+func ValidateIssuerConfig() error {
+	raw := strings.TrimSpace(getEnv("ISSUER_URL", ""))
+	if raw == "" {
+		return errors.New("ISSUER_URL is required")
+	}
+	issuer, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid ISSUER_URL: %w", err)
+	}
+	if (issuer.Scheme != "https" && issuer.Scheme != "http") || issuer.Host == "" {
+		return errors.New("ISSUER_URL must be an absolute http(s) URL")
+	}
+	if issuer.User != nil || issuer.RawQuery != "" || issuer.Fragment != "" || strings.HasSuffix(raw, "?") || strings.HasSuffix(raw, "#") {
+		return errors.New("ISSUER_URL must not contain userinfo, query or fragment")
+	}
+	return nil
+}
+
+// ResolveIssuer 获取请求对外的issuer URL，始终为配置的issuer
+// Host和X-Forwarded-*头由客户端控制，不参与推导，否则伪造的请求头会改变Discovery文档、令牌中的iss和登录回跳地址
+func ResolveIssuer(r *http.Request) string {
+	return ConfiguredIssuer()
+}
+
+// WithIssuer 将issuer存入上下文
+func WithIssuer(ctx context.Context, issuer string) context.Context {
+	return context.WithValue(ctx, issuerContextKey{}, issuer)
+}
+
+// IssuerFromContext 从上下文中获取issuer，不存在时返回配置的issuer
+func IssuerFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if issuer, ok := ctx.Value(issuerContextKey{}).(string); ok && issuer != "" {
+			return issuer
+		}
+	}
+	return ConfiguredIssuer()
+}
@@ -1,13 +1,18 @@
 package util
 
 import (
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"fmt"
+	"math/big"
 	"os"
 	"time"
 
+	"github.com/Full-finger/OIDC/internal/model"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -22,8 +27,17 @@ type JWTUtil interface {
 	// ParseIDToken 解析ID Token
 	ParseIDToken(tokenString string) (*IDTokenClaims, error)
 	
+	// ParseIDTokenHint 解析id_token_hint，只校验签名，允许令牌已过期
+	ParseIDTokenHint(tokenString string) (*IDTokenClaims, error)
+	
 	// ParseAccessToken 解析Access Token
 	ParseAccessToken(tokenString string) (*AccessTokenClaims, error)
+	
+	// SignClaims 使用当前签名密钥签名任意声明
+	SignClaims(claims jwt.Claims) (string, error)
+	
+	// PublicJWK 获取签名公钥的JWK表示
+	PublicJWK() JWK
 }
 
 // jwtUtil JWT工具实现
@@ -31,30 +45,65 @@ type jwtUtil struct {
 	privateKey *rsa.PrivateKey
 	publicKey  *rsa.PublicKey
 	issuer     string
+	keyID      string
+}
+
+// JWK JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
 }
 
 // IDTokenClaims ID Token声明
 type IDTokenClaims struct {
 	jwt.RegisteredClaims
-	Nonce   string `json:"nonce,omitempty"`
-	Profile string `json:"profile,omitempty"`
-	Email   string `json:"email,omitempty"`
-	Name    string `json:"name,omitempty"`
+	Nonce         string   `json:"nonce,omitempty"`
+	AuthTime      int64    `json:"auth_time,omitempty"`
+	AMR           []string `json:"amr,omitempty"` // 认证方式引用 (RFC 8176)
+	Profile       string   `json:"profile,omitempty"`
+	Picture       string   `json:"picture,omitempty"`
+	Email         string   `json:"email,omitempty"`
+	EmailVerified *bool    `json:"email_verified,omitempty"` // 使用指针，未验证的邮箱输出false而不是省略
+	Name          string   `json:"name,omitempty"`
+	Nickname      string   `json:"nickname,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
 }
 
 // AccessTokenClaims Access Token声明
 type AccessTokenClaims struct {
 	jwt.RegisteredClaims
-	Scope string `json:"scope,omitempty"`
+	Scope  string   `json:"scope,omitempty"`
+	Roles  []string `json:"roles,omitempty"`  // 用户的有效角色，供资源服务器授权
+	Groups []string `json:"groups,omitempty"` // 用户所属用户组
+
+	// AuthorizationDetails 用户批准的细粒度授权 (RFC 9396 §9.1)
+	AuthorizationDetails []model.AuthorizationDetail `json:"authorization_details,omitempty"`
 }
 
-// NewJWTUtil 创建JWT工具实例
+// NewJWTUtil 创建JWT工具实例，使用JWT_PRIVATE_KEY_PATH和JWT_PUBLIC_KEY_PATH配置的密钥
 func NewJWTUtil() (JWTUtil, error) {
-	// 从环境变量获取JWT配置
-	issuer := getEnv("JWT_ISSUER", "OIDC")
+	return NewJWTUtilWithKeyFiles(
+		getEnv("JWT_PRIVATE_KEY_PATH", "config/private_key.pem"),
+		getEnv("JWT_PUBLIC_KEY_PATH", "config/public_key.pem"),
+	)
+}
+
+// NewJWTUtilWithKeyFiles 使用指定的密钥文件创建JWT工具实例
+func NewJWTUtilWithKeyFiles(privateKeyPath, publicKeyPath string) (JWTUtil, error) {
+	// issuer与Discovery文档使用同一配置
+	issuer := ConfiguredIssuer()
 	
 	// 读取私钥文件
-	privateKeyPath := getEnv("JWT_PRIVATE_KEY_PATH", "config/private_key.pem")
 	privateKeyData, err := os.ReadFile(privateKeyPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read private key: %w", err)
@@ -66,26 +115,71 @@ func NewJWTUtil() (JWTUtil, error) {
 		return nil, fmt.Errorf("failed to parse private key: %w", err)
 	}
 	
-	// 读取公钥文件
-	publicKeyPath := getEnv("JWT_PUBLIC_KEY_PATH", "config/public_key.pem")
-	publicKeyData, err := os.ReadFile(publicKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read public key: %w", err)
+	// 未指定公钥文件时直接使用私钥对应的公钥
+	publicKey := &privateKey.PublicKey
+	if publicKeyPath != "" {
+		// 读取公钥文件
+		publicKeyData, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+		
+		// 解析公钥
+		publicKey, err = parsePublicKey(publicKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
 	}
 	
-	// 解析公钥
-	publicKey, err := parsePublicKey(publicKeyData)
+	return &jwtUtil{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		issuer:     issuer,
+		keyID:      rsaThumbprint(publicKey),
+	}, nil
+}
+
+// NewEphemeralJWTUtil 使用临时生成的RSA密钥创建JWT工具实例
+// 密钥只存在于内存中，服务重启后之前签发的令牌将无法验证，仅用于开发环境
+func NewEphemeralJWTUtil() (JWTUtil, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse public key: %w", err)
+		return nil, fmt.Errorf("failed to generate private key: %w", err)
 	}
 	
 	return &jwtUtil{
 		privateKey: privateKey,
-		publicKey:  publicKey,
-		issuer:     issuer,
+		publicKey:  &privateKey.PublicKey,
+		issuer:     ConfiguredIssuer(),
+		keyID:      rsaThumbprint(&privateKey.PublicKey),
 	}, nil
 }
 
+// SignClaims 使用当前签名密钥签名任意声明
+func (j *jwtUtil) SignClaims(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = j.keyID
+	
+	tokenString, err := token.SignedString(j.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	
+	return tokenString, nil
+}
+
+// PublicJWK 获取签名公钥的JWK表示
+func (j *jwtUtil) PublicJWK() JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: j.keyID,
+		N:   base64.RawURLEncoding.EncodeToString(j.publicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(j.publicKey.E)).Bytes()),
+	}
+}
+
 // GenerateIDToken 生成ID Token
 func (j *jwtUtil) GenerateIDToken(claims *IDTokenClaims) (string, error) {
 	// 设置标准声明
@@ -107,6 +201,7 @@ func (j *jwtUtil) GenerateIDToken(claims *IDTokenClaims) (string, error) {
 	
 	// 创建token
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = j.keyID
 	
 	// 签名并生成token字符串
 	tokenString, err := token.SignedString(j.privateKey)
@@ -138,6 +233,7 @@ func (j *jwtUtil) GenerateAccessToken(claims *AccessTokenClaims) (string, error)
 	
 	// 创建token
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = j.keyID
 	
 	// 签名并生成token字符串
 	tokenString, err := token.SignedString(j.privateKey)
@@ -171,6 +267,25 @@ func (j *jwtUtil) ParseIDToken(tokenString string) (*IDTokenClaims, error) {
 	return nil, fmt.Errorf("invalid ID token")
 }
 
+// ParseIDTokenHint 解析id_token_hint
+// OIDC Core §3.1.2.1 允许使用已过期的ID Token作为提示，因此跳过时间相关声明的校验
+func (j *jwtUtil) ParseIDTokenHint(tokenString string) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// 验证签名方法
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return j.publicKey, nil
+	}, jwt.WithoutClaimsValidation())
+	
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse id_token_hint: %w", err)
+	}
+	
+	return claims, nil
+}
+
 // ParseAccessToken 解析Access Token
 func (j *jwtUtil) ParseAccessToken(tokenString string) (*AccessTokenClaims, error) {
 	// 解析token
@@ -244,4 +359,13 @@ func parsePublicKey(data []byte) (*rsa.PublicKey, error) {
 	}
 	
 	return publicKey, nil
+}
+
+// rsaThumbprint 计算RSA公钥的JWK指纹 (RFC 7638)，用作kid
+func rsaThumbprint(key *rsa.PublicKey) string {
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	canonical := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, e, n)
+	hash := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
 }
\ No newline at end of file
@@ -0,0 +1,75 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// BackchannelNotification CIBA认证请求通知，提示用户在其他设备上批准登录
+type BackchannelNotification struct {
+	AuthReqID      string
+	UserID         uint
+	Email          string
+	ClientID       string
+	ClientName     string
+	BindingMessage string
+	Scopes         []string
+	BasePath       string // 用户所属realm的路由前缀
+	ExpiresAt      time.Time
+}
+
+// Notifier CIBA认证请求通知接口，可按部署环境替换为邮件、推送或聊天机器人等实现
+type Notifier interface {
+	// NotifyBackchannelAuthRequest 将认证请求投递给用户
+	NotifyBackchannelAuthRequest(notification *BackchannelNotification) error
+}
+
+// emailNotifier 通过邮件队列投递认证请求
+type emailNotifier struct {
+	emailQueue EmailQueue
+}
+
+// NewEmailNotifier 创建基于邮件队列的Notifier实例
+func NewEmailNotifier(emailQueue EmailQueue) Notifier {
+	return &emailNotifier{
+		emailQueue: emailQueue,
+	}
+}
+
+// NotifyBackchannelAuthRequest 将认证批准邮件加入邮件队列
+func (n *emailNotifier) NotifyBackchannelAuthRequest(notification *BackchannelNotification) error {
+	return n.emailQueue.Enqueue(EmailQueueItem{
+		Email:          notification.Email,
+		Token:          notification.AuthReqID,
+		BasePath:       notification.BasePath,
+		Type:           EmailTypeBackchannelAuth,
+		ClientName:     notification.ClientName,
+		BindingMessage: notification.BindingMessage,
+	})
+}
+
+// MemoryNotifier 将通知保存在内存中，用于测试和本地开发
+type MemoryNotifier struct {
+	mu            sync.Mutex
+	notifications []BackchannelNotification
+}
+
+// NewMemoryNotifier 创建MemoryNotifier实例
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+// NotifyBackchannelAuthRequest 记录认证请求通知
+func (n *MemoryNotifier) NotifyBackchannelAuthRequest(notification *BackchannelNotification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, *notification)
+	return nil
+}
+
+// Notifications 获取已记录的通知
+func (n *MemoryNotifier) Notifications() []BackchannelNotification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]BackchannelNotification{}, n.notifications...)
+}
@@ -0,0 +1,151 @@
+package util
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// commonPasswords 内置的常见密码，比较时不区分大小写
+var commonPasswords = []string{
+	"123456", "123456789", "12345678", "1234567890", "1234567", "12345", "123123", "111111", "000000", "654321",
+	"666666", "888888", "112233", "121212", "123321", "147258369", "159753", "987654321", "11111111", "88888888",
+	"password", "password1", "password123", "passw0rd", "p@ssw0rd", "p@ssword", "qwerty", "qwerty123", "qwertyuiop", "1q2w3e4r",
+	"1qaz2wsx", "qazwsx", "zaq12wsx", "asdfghjkl", "asdf1234", "zxcvbnm", "abc123", "abc12345", "abcd1234", "a123456",
+	"a12345678", "aa123456", "iloveyou", "woaini", "woaini1314", "5201314", "1314520", "admin", "admin123", "administrator",
+	"root", "toor", "welcome", "welcome1", "letmein", "monkey", "dragon", "master", "sunshine", "princess",
+	"football", "baseball", "superman", "batman", "trustno1", "shadow", "michael", "charlie", "starwars", "whatever",
+	"changeme", "secret", "default", "guest", "test", "test123", "login", "hello123", "computer", "internet",
+	"naruto", "pokemon", "doraemon", "sakura", "onepiece", "qwe123", "123qwe", "123abc", "aaaaaa", "abcdef",
+}
+
+// LoadPasswordBlocklist 加载常见密码黑名单，包含内置列表和path指定文件中的密码（每行一个），path为空时只使用内置列表
+func LoadPasswordBlocklist(path string) (map[string]struct{}, error) {
+	blocklist := make(map[string]struct{}, len(commonPasswords))
+	for _, password := range commonPasswords {
+		blocklist[password] = struct{}{}
+	}
+	if path == "" {
+		return blocklist, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open password blocklist: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			blocklist[strings.ToLower(password)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read password blocklist: %w", err)
+	}
+
+	return blocklist, nil
+}
+
+// BreachedPasswordChecker 泄露密码检查接口
+type BreachedPasswordChecker interface {
+	// IsBreached 判断密码是否出现在泄露密码库中
+	IsBreached(password string) (bool, error)
+}
+
+// NewBreachedPasswordChecker 加载Pwned Passwords格式的本地泄露密码库，按SHA-1哈希的k-anonymity分段方式查找：
+// path为目录时，每个文件以哈希的前5位十六进制命名，内容为"后35位:出现次数"，检查时只读取对应前缀的文件；
+// path为文件时，每行为"完整哈希:出现次数"，启动时按前缀分组加载到内存
+func NewBreachedPasswordChecker(path string) (BreachedPasswordChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password database: %w", err)
+	}
+	if info.IsDir() {
+		return &breachedPasswordRangeDir{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password database: %w", err)
+	}
+	defer file.Close()
+
+	checker := &breachedPasswordRangeFile{ranges: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash := strings.ToUpper(strings.TrimSpace(strings.SplitN(scanner.Text(), ":", 2)[0]))
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+		prefix, suffix := hash[:5], hash[5:]
+		if checker.ranges[prefix] == nil {
+			checker.ranges[prefix] = make(map[string]struct{})
+		}
+		checker.ranges[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password database: %w", err)
+	}
+
+	return checker, nil
+}
+
+// passwordHashRange 计算密码的SHA-1哈希，返回前5位前缀和其余后缀（大写十六进制）
+func passwordHashRange(password string) (string, string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:5], hash[5:]
+}
+
+// breachedPasswordRangeDir 按前缀分段存储在目录中的泄露密码库
+type breachedPasswordRangeDir struct {
+	dir string
+}
+
+// IsBreached 只读取密码哈希前缀对应的分段文件
+func (c *breachedPasswordRangeDir) IsBreached(password string) (bool, error) {
+	prefix, suffix := passwordHashRange(password)
+
+	file, err := os.Open(filepath.Join(c.dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(filepath.Join(c.dir, prefix+".txt"))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open breached password range: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.EqualFold(strings.TrimSpace(strings.SplitN(scanner.Text(), ":", 2)[0]), suffix) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached password range: %w", err)
+	}
+
+	return false, nil
+}
+
+// breachedPasswordRangeFile 加载到内存并按前缀分组的泄露密码库
+type breachedPasswordRangeFile struct {
+	ranges map[string]map[string]struct{}
+}
+
+// IsBreached 在密码哈希前缀对应的分组中查找后缀
+func (c *breachedPasswordRangeFile) IsBreached(password string) (bool, error) {
+	prefix, suffix := passwordHashRange(password)
+	_, breached := c.ranges[prefix][suffix]
+	return breached, nil
+}
@@ -0,0 +1,225 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 支持的密码哈希算法
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// ErrUnsupportedPasswordHash 无法识别的密码哈希格式
+var ErrUnsupportedPasswordHash = errors.New("unsupported password hash format")
+
+// 默认哈希参数，argon2id参考OWASP建议，bcrypt沿用bcrypt.DefaultCost
+const (
+	defaultArgon2MemoryKiB   = 64 * 1024
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+// PasswordHasher 密码哈希接口，哈希结果为PHC格式字符串（bcrypt为$2a$等兼容格式），自带算法和参数
+type PasswordHasher interface {
+	// Hash 使用当前配置的算法和参数计算密码哈希
+	Hash(password string) (string, error)
+
+	// Verify 校验密码与哈希是否匹配，按哈希自带的算法和参数计算，不要求与当前配置一致
+	Verify(encodedHash, password string) (bool, error)
+
+	// NeedsRehash 判断哈希的算法不是当前配置的算法，或参数弱于当前配置，需要在下次登录成功时重新计算
+	NeedsRehash(encodedHash string) bool
+}
+
+// Argon2Params argon2id参数
+type Argon2Params struct {
+	MemoryKiB   uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params 默认的argon2id参数
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		MemoryKiB:   defaultArgon2MemoryKiB,
+		Iterations:  defaultArgon2Iterations,
+		Parallelism: defaultArgon2Parallelism,
+		SaltLength:  argon2SaltLength,
+		KeyLength:   argon2KeyLength,
+	}
+}
+
+// passwordHasher 按配置选择算法的PasswordHasher实现
+type passwordHasher struct {
+	algorithm  string
+	argon2     Argon2Params
+	bcryptCost int
+}
+
+// NewPasswordHasher 创建PasswordHasher实例，algorithm为argon2id或bcrypt
+func NewPasswordHasher(algorithm string, argon2Params Argon2Params, bcryptCost int) (PasswordHasher, error) {
+	switch algorithm {
+	case PasswordHashArgon2id:
+		if argon2Params.MemoryKiB == 0 || argon2Params.Iterations == 0 || argon2Params.Parallelism == 0 {
+			return nil, errors.New("argon2id memory, iterations and parallelism must be positive")
+		}
+		if argon2Params.SaltLength <= 0 {
+			argon2Params.SaltLength = argon2SaltLength
+		}
+		if argon2Params.KeyLength == 0 {
+			argon2Params.KeyLength = argon2KeyLength
+		}
+	case PasswordHashBcrypt:
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm: %s", algorithm)
+	}
+
+	return &passwordHasher{
+		algorithm:  algorithm,
+		argon2:     argon2Params,
+		bcryptCost: bcryptCost,
+	}, nil
+}
+
+// NewPasswordHasherFromEnv 根据环境变量创建PasswordHasher：PASSWORD_HASH_ALGORITHM（默认argon2id），
+// ARGON2_MEMORY_KIB、ARGON2_ITERATIONS、ARGON2_PARALLELISM，以及BCRYPT_COST
+func NewPasswordHasherFromEnv() (PasswordHasher, error) {
+	memory, err := strconv.ParseUint(getEnv("ARGON2_MEMORY_KIB", strconv.Itoa(defaultArgon2MemoryKiB)), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ARGON2_MEMORY_KIB: %w", err)
+	}
+	iterations, err := strconv.ParseUint(getEnv("ARGON2_ITERATIONS", strconv.Itoa(defaultArgon2Iterations)), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ARGON2_ITERATIONS: %w", err)
+	}
+	parallelism, err := strconv.ParseUint(getEnv("ARGON2_PARALLELISM", strconv.Itoa(defaultArgon2Parallelism)), 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ARGON2_PARALLELISM: %w", err)
+	}
+	bcryptCost, err := strconv.Atoi(getEnv("BCRYPT_COST", strconv.Itoa(bcrypt.DefaultCost)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BCRYPT_COST: %w", err)
+	}
+
+	return NewPasswordHasher(getEnv("PASSWORD_HASH_ALGORITHM", PasswordHashArgon2id), Argon2Params{
+		MemoryKiB:   uint32(memory),
+		Iterations:  uint32(iterations),
+		Parallelism: uint8(parallelism),
+	}, bcryptCost)
+}
+
+// Hash 使用当前配置的算法和参数计算密码哈希
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.algorithm == PasswordHashBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, h.argon2.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2.Iterations, h.argon2.MemoryKiB, h.argon2.Parallelism, h.argon2.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.argon2.MemoryKiB, h.argon2.Iterations, h.argon2.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 校验密码与哈希是否匹配
+func (h *passwordHasher) Verify(encodedHash, password string) (bool, error) {
+	if isBcryptHash(encodedHash) {
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	params, salt, key, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.MemoryKiB, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+// NeedsRehash 判断哈希是否需要按当前配置重新计算
+func (h *passwordHasher) NeedsRehash(encodedHash string) bool {
+	if h.algorithm == PasswordHashBcrypt {
+		if !isBcryptHash(encodedHash) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+		return err != nil || cost < h.bcryptCost
+	}
+
+	params, _, _, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.MemoryKiB < h.argon2.MemoryKiB ||
+		params.Iterations < h.argon2.Iterations ||
+		params.Parallelism < h.argon2.Parallelism ||
+		params.SaltLength < h.argon2.SaltLength ||
+		params.KeyLength < h.argon2.KeyLength
+}
+
+// isBcryptHash 判断是否为bcrypt哈希（$2a$、$2b$、$2y$）
+func isBcryptHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") || strings.HasPrefix(encodedHash, "$2b$") || strings.HasPrefix(encodedHash, "$2y$")
+}
+
+// decodeArgon2idHash 解析PHC格式的argon2id哈希：$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func decodeArgon2idHash(encodedHash string) (*Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != PasswordHashArgon2id {
+		return nil, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	params := &Argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.MemoryKiB, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, ErrUnsupportedPasswordHash
+	}
+	if params.MemoryKiB == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return nil, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrUnsupportedPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrUnsupportedPasswordHash
+	}
+	params.SaltLength = len(salt)
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
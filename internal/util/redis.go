@@ -0,0 +1,17 @@
+package util
+
+import (
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// NewRedisClient 使用REDIS_ADDR、REDIS_PASSWORD和REDIS_DB配置创建Redis客户端
+func NewRedisClient() *redis.Client {
+	redisDB, _ := strconv.Atoi(getEnv("REDIS_DB", "0"))
+	return redis.NewClient(&redis.Options{
+		Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
+		Password: getEnv("REDIS_PASSWORD", ""),
+		DB:       redisDB,
+	})
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"context"
+)
+
+// requestMetadataContextKey 上下文中存放请求来源信息的键
+type requestMetadataContextKey struct{}
+
+// RequestMetadata 请求来源信息，用于安全审计和登录防护
+type RequestMetadata struct {
+	IP        string
+	UserAgent string
+}
+
+// WithRequestMetadata 将请求来源信息存入上下文
+func WithRequestMetadata(ctx context.Context, metadata RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataContextKey{}, metadata)
+}
+
+// RequestMetadataFromContext 从上下文中获取请求来源信息，后台任务等不存在时返回零值
+func RequestMetadataFromContext(ctx context.Context) RequestMetadata {
+	if ctx != nil {
+		if metadata, ok := ctx.Value(requestMetadataContextKey{}).(RequestMetadata); ok {
+			return metadata
+		}
+	}
+	return RequestMetadata{}
+}
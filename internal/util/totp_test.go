@@ -0,0 +1,120 @@
+package util_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Full-finger/OIDC/internal/util"
+)
+
+// rfc6238Secret RFC 6238 附录B中SHA1测试向量的共享密钥 "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// 附录B给出8位验证码，6位验证码取其后6位
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		now := time.Unix(tt.unix, 0)
+		code, err := util.TOTPCode(rfc6238Secret, util.TOTPStep(now))
+		if err != nil {
+			t.Fatalf("T=%d: %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Fatalf("T=%d: expected %s, got %s", tt.unix, tt.code, code)
+		}
+		if _, ok := util.ValidateTOTP(rfc6238Secret, tt.code, now, 0); !ok {
+			t.Fatalf("T=%d: expected %s to validate", tt.unix, tt.code)
+		}
+	}
+
+	// 小写密钥同样可用
+	if code, err := util.TOTPCode(strings.ToLower(rfc6238Secret), 1); err != nil || code != "287082" {
+		t.Fatalf("expected lowercase secret to be accepted, got %q %v", code, err)
+	}
+	if _, err := util.TOTPCode("not base32!", 1); err == nil {
+		t.Fatal("expected an invalid secret to be rejected")
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := util.TOTPStep(now)
+
+	tests := []struct {
+		name   string
+		offset int64
+		valid  bool
+	}{
+		{"two steps behind", -2, false},
+		{"one step behind", -1, true},
+		{"current step", 0, true},
+		{"one step ahead", 1, true},
+		{"two steps ahead", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := util.TOTPCode(rfc6238Secret, current+tt.offset)
+			if err != nil {
+				t.Fatalf("generate code: %v", err)
+			}
+			step, ok := util.ValidateTOTP(rfc6238Secret, code, now, 0)
+			if ok != tt.valid {
+				t.Fatalf("expected valid=%v, got %v", tt.valid, ok)
+			}
+			if ok && step != current+tt.offset {
+				t.Fatalf("expected matched step %d, got %d", current+tt.offset, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejectsReusedCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := util.TOTPCode(rfc6238Secret, util.TOTPStep(now))
+	if err != nil {
+		t.Fatalf("generate code: %v", err)
+	}
+
+	step, ok := util.ValidateTOTP(rfc6238Secret, code, now, 0)
+	if !ok {
+		t.Fatal("expected the code to validate")
+	}
+	// 同一时间步及更早时间步的验证码不能再使用，即使仍在偏差范围内
+	if _, ok := util.ValidateTOTP(rfc6238Secret, code, now, step); ok {
+		t.Fatal("expected a reused code to be rejected")
+	}
+	if _, ok := util.ValidateTOTP(rfc6238Secret, code, now.Add(util.TOTPPeriod), step); ok {
+		t.Fatal("expected a reused code to be rejected in the next step")
+	}
+
+	previous, _ := util.TOTPCode(rfc6238Secret, step-1)
+	if _, ok := util.ValidateTOTP(rfc6238Secret, previous, now, step); ok {
+		t.Fatal("expected a code older than the last used step to be rejected")
+	}
+	next, _ := util.TOTPCode(rfc6238Secret, step+1)
+	if matched, ok := util.ValidateTOTP(rfc6238Secret, next, now, step); !ok || matched != step+1 {
+		t.Fatalf("expected the next step to be accepted, got %d %v", matched, ok)
+	}
+}
+
+func TestValidateTOTPRejectsMalformedCode(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "94287082", "abcdef"} {
+		if _, ok := util.ValidateTOTP(rfc6238Secret, code, now, 0); ok {
+			t.Fatalf("expected %q to be rejected", code)
+		}
+	}
+}
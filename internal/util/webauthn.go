@@ -0,0 +1,252 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// 认证器数据标志位 (WebAuthn Level 2 §6.1)
+const (
+	WebAuthnFlagUserPresent    byte = 0x01
+	WebAuthnFlagUserVerified   byte = 0x04
+	WebAuthnFlagBackupEligible byte = 0x08
+	WebAuthnFlagBackedUp       byte = 0x10
+	WebAuthnFlagAttestedData   byte = 0x40
+	WebAuthnFlagExtensionData  byte = 0x80
+)
+
+// 支持的COSE签名算法 (RFC 9053)
+const (
+	COSEAlgES256 int64 = -7
+	COSEAlgEdDSA int64 = -8
+	COSEAlgRS256 int64 = -257
+)
+
+// COSE密钥参数标签
+const (
+	coseKeyType  int64 = 1
+	coseKeyAlg   int64 = 3
+	coseKeyCurve int64 = -1 // EC2/OKP的曲线，RSA的模数n
+	coseKeyX     int64 = -2 // EC2/OKP的x坐标，RSA的指数e
+	coseKeyY     int64 = -3
+
+	coseKeyTypeOKP int64 = 1
+	coseKeyTypeEC2 int64 = 2
+	coseKeyTypeRSA int64 = 3
+
+	coseCurveP256    int64 = 1
+	coseCurveEd25519 int64 = 6
+)
+
+// WebAuthnClientData 浏览器生成的clientDataJSON
+type WebAuthnClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// WebAuthnAuthenticatorData 认证器返回的authenticatorData
+type WebAuthnAuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte // 以下字段仅在注册时存在
+	CredentialID []byte
+	PublicKey    []byte // COSE格式的凭据公钥
+}
+
+// HasFlag 判断认证器数据是否设置了指定标志位
+func (d *WebAuthnAuthenticatorData) HasFlag(flag byte) bool {
+	return d.Flags&flag != 0
+}
+
+// DecodeBase64URL 解码WebAuthn使用的Base64URL数据，兼容带填充的输入
+func DecodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// ParseWebAuthnClientData 解析clientDataJSON
+func ParseWebAuthnClientData(raw []byte) (*WebAuthnClientData, error) {
+	var clientData WebAuthnClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, fmt.Errorf("invalid client data: %w", err)
+	}
+	return &clientData, nil
+}
+
+// ParseWebAuthnAttestationObject 解析注册时返回的attestationObject，返回证明格式和认证器数据
+func ParseWebAuthnAttestationObject(raw []byte) (string, []byte, error) {
+	decoded, rest, err := DecodeCBOR(raw)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	if len(rest) != 0 {
+		return "", nil, errors.New("invalid attestation object: trailing data")
+	}
+
+	object, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return "", nil, errors.New("invalid attestation object: not a map")
+	}
+	format, _ := object["fmt"].(string)
+	authData, ok := object["authData"].([]byte)
+	if format == "" || !ok {
+		return "", nil, errors.New("invalid attestation object: missing fmt or authData")
+	}
+
+	return format, authData, nil
+}
+
+// ParseWebAuthnAuthenticatorData 解析认证器数据，包含凭据数据时一并解析凭据ID和公钥
+func ParseWebAuthnAuthenticatorData(raw []byte) (*WebAuthnAuthenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+
+	data := &WebAuthnAuthenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[37:]
+
+	if data.HasFlag(WebAuthnFlagAttestedData) {
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data is too short")
+		}
+		data.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > 1023 || len(rest) < idLength {
+			return nil, errors.New("invalid credential id length")
+		}
+		data.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, remaining, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		data.PublicKey = rest[:len(rest)-len(remaining)]
+		rest = remaining
+	}
+
+	if data.HasFlag(WebAuthnFlagExtensionData) {
+		extensions, remaining, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extension data: %w", err)
+		}
+		if _, ok := extensions.(map[interface{}]interface{}); !ok {
+			return nil, errors.New("invalid extension data: not a map")
+		}
+		rest = remaining
+	}
+
+	if len(rest) != 0 {
+		return nil, errors.New("authenticator data has trailing bytes")
+	}
+
+	return data, nil
+}
+
+// ParseCOSEPublicKey 解析COSE格式的公钥，支持ES256、EdDSA (Ed25519) 和RS256
+func ParseCOSEPublicKey(raw []byte) (crypto.PublicKey, int64, error) {
+	decoded, rest, err := DecodeCBOR(raw)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid cose key: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, 0, errors.New("invalid cose key: trailing data")
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, errors.New("invalid cose key: not a map")
+	}
+
+	keyType, _ := key[coseKeyType].(int64)
+	alg, _ := key[coseKeyAlg].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && alg == COSEAlgES256:
+		curve, _ := key[coseKeyCurve].(int64)
+		x, _ := key[coseKeyX].([]byte)
+		y, _ := key[coseKeyY].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errors.New("invalid cose key: unsupported ec2 parameters")
+		}
+		// 借助crypto/ecdh校验坐标位于曲线上
+		point := append(append([]byte{0x04}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, 0, fmt.Errorf("invalid cose key: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, alg, nil
+	case keyType == coseKeyTypeOKP && alg == COSEAlgEdDSA:
+		curve, _ := key[coseKeyCurve].(int64)
+		x, _ := key[coseKeyX].([]byte)
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("invalid cose key: unsupported okp parameters")
+		}
+		return ed25519.PublicKey(x), alg, nil
+	case keyType == coseKeyTypeRSA && alg == COSEAlgRS256:
+		n, _ := key[coseKeyCurve].([]byte)
+		e, _ := key[coseKeyX].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, errors.New("invalid cose key: unsupported rsa parameters")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, alg, nil
+	default:
+		return nil, 0, fmt.Errorf("invalid cose key: unsupported key type %d with algorithm %d", keyType, alg)
+	}
+}
+
+// VerifyWebAuthnAssertion 用COSE公钥校验断言签名，签名内容为authenticatorData与clientDataJSON哈希的拼接
+func VerifyWebAuthnAssertion(coseKey, authenticatorData, clientDataJSON, signature []byte) error {
+	publicKey, _, err := ParseCOSEPublicKey(coseKey)
+	if err != nil {
+		return err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, signed, signature) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+	default:
+		return errors.New("unsupported public key")
+	}
+
+	return nil
+}
@@ -56,6 +56,27 @@ func (w *EmailWorker) Stop() {
 
 // processEmail 处理邮件任务
 func (w *EmailWorker) processEmail(item *util.EmailQueueItem) error {
-	// 调用邮件服务发送验证邮件
-	return w.emailService.SendVerificationEmail(item.Email, item.Token)
+	switch item.Type {
+	case util.EmailTypeBackchannelAuth:
+		// 发送CIBA认证批准邮件
+		return w.emailService.SendBackchannelAuthEmail(item.Email, item.Token, item.BasePath, item.ClientName, item.BindingMessage)
+	case util.EmailTypePasswordReset:
+		// 发送重置密码邮件
+		return w.emailService.SendPasswordResetEmail(item.Email, item.Token, item.BasePath)
+	case util.EmailTypeEmailChange:
+		// 发送邮箱变更确认邮件
+		return w.emailService.SendEmailChangeConfirmationEmail(item.Email, item.Token, item.BasePath)
+	case util.EmailTypeEmailChangeNotice:
+		// 发送邮箱变更通知邮件
+		return w.emailService.SendEmailChangeNoticeEmail(item.Email, item.NewEmail, item.Token, item.BasePath)
+	case util.EmailTypeAccountLocked:
+		// 发送账户锁定通知邮件
+		return w.emailService.SendAccountLockedEmail(item.Email, item.LockedUntil)
+	case util.EmailTypeAccountDeletion:
+		// 发送账户删除通知邮件
+		return w.emailService.SendAccountDeletionEmail(item.Email, item.DeletionScheduledAt)
+	default:
+		// 调用邮件服务发送验证邮件
+		return w.emailService.SendVerificationEmail(item.Email, item.Token, item.BasePath)
+	}
 }
\ No newline at end of file
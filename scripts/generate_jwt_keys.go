@@ -7,11 +7,20 @@ import (
 	"encoding/pem"
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 func main() {
-	// 创建config目录（如果不存在）
-	if err := os.MkdirAll("config", 0755); err != nil {
+	// 默认生成到config目录；指定realm名称时生成到config/realms/{name}
+	keyDir := "config"
+	if len(os.Args) > 1 && os.Args[1] != "" {
+		keyDir = filepath.Join("config", "realms", os.Args[1])
+	}
+	privateKeyPath := filepath.Join(keyDir, "private_key.pem")
+	publicKeyPath := filepath.Join(keyDir, "public_key.pem")
+
+	// 创建密钥目录（如果不存在）
+	if err := os.MkdirAll(keyDir, 0755); err != nil {
 		fmt.Printf("Failed to create config directory: %v\n", err)
 		return
 	}
@@ -24,7 +33,7 @@ func main() {
 	}
 
 	// 创建私钥文件
-	privateKeyFile, err := os.Create("config/private_key.pem")
+	privateKeyFile, err := os.Create(privateKeyPath)
 	if err != nil {
 		fmt.Printf("Failed to create private key file: %v\n", err)
 		return
@@ -47,7 +56,7 @@ func main() {
 	publicKey := &privateKey.PublicKey
 
 	// 创建公钥文件
-	publicKeyFile, err := os.Create("config/public_key.pem")
+	publicKeyFile, err := os.Create(publicKeyPath)
 	if err != nil {
 		fmt.Printf("Failed to create public key file: %v\n", err)
 		return
@@ -69,6 +78,6 @@ func main() {
 	}
 
 	fmt.Println("JWT RSA key pair generated successfully!")
-	fmt.Println("Private key saved to: config/private_key.pem")
-	fmt.Println("Public key saved to: config/public_key.pem")
+	fmt.Println("Private key saved to: " + privateKeyPath)
+	fmt.Println("Public key saved to: " + publicKeyPath)
 }
\ No newline at end of file
@@ -1,17 +1,25 @@
--- 创建用户表
+-- 创建用户表（realm_id对应realm配置文件中加载的realm）
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
-    username VARCHAR(50) UNIQUE NOT NULL,
+    realm_id INTEGER NOT NULL,
+    username VARCHAR(50) NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
-    email VARCHAR(100) UNIQUE NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    email_verified BOOLEAN DEFAULT FALSE,
     nickname VARCHAR(100),
     avatar_url TEXT,
+    avatar_key TEXT,
     bio TEXT,
     is_active BOOLEAN DEFAULT FALSE,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deletion_scheduled_at TIMESTAMP,
+    UNIQUE(realm_id, username),
+    UNIQUE(realm_id, email)
 );
 
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users(deletion_scheduled_at);
+
 -- 创建验证令牌表
 CREATE TABLE IF NOT EXISTS verification_tokens (
     id SERIAL PRIMARY KEY,
@@ -21,30 +29,241 @@ CREATE TABLE IF NOT EXISTS verification_tokens (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 创建重置密码令牌表（只保存令牌的哈希）
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(255) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+
+-- 创建邮箱变更请求表（只保存确认令牌和撤销令牌的哈希）
+CREATE TABLE IF NOT EXISTS email_change_requests (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email VARCHAR(100) NOT NULL,
+    old_email_verified BOOLEAN DEFAULT FALSE,
+    new_email VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(255) UNIQUE NOT NULL,
+    revert_token_hash VARCHAR(255) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revert_expires_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_change_requests_user_id ON email_change_requests(user_id);
+
 -- 创建OAuth客户端表
 CREATE TABLE IF NOT EXISTS oauth_clients (
     id SERIAL PRIMARY KEY,
-    client_id VARCHAR(100) UNIQUE NOT NULL,
+    realm_id INTEGER NOT NULL,
+    client_id VARCHAR(100) NOT NULL,
     client_secret_hash VARCHAR(255) NOT NULL,
     redirect_uri TEXT NOT NULL,
+    redirect_uris TEXT,
+    backchannel_token_delivery_mode VARCHAR(16),
+    backchannel_client_notification_endpoint TEXT,
+    authorization_details_types TEXT,
+    token_endpoint_auth_method VARCHAR(32),
+    require_pkce BOOLEAN,
+    token_authorization_code_lifetime INTEGER,
+    token_access_token_lifetime INTEGER,
+    token_id_token_lifetime INTEGER,
+    token_refresh_token_absolute_lifetime INTEGER,
+    token_refresh_token_idle_timeout INTEGER,
+    token_refresh_token_sliding BOOLEAN,
+    token_always_issue_refresh_token BOOLEAN,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(realm_id, client_id)
+);
+
+-- 创建scope注册表
+CREATE TABLE IF NOT EXISTS scopes (
+    id SERIAL PRIMARY KEY,
+    realm_id INTEGER NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    description TEXT,
+    claims TEXT,
+    requires_consent BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(realm_id, name)
+);
+
+-- 创建角色表
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    realm_id INTEGER NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(realm_id, name)
+);
+
+-- 创建用户组表
+CREATE TABLE IF NOT EXISTS groups (
+    id SERIAL PRIMARY KEY,
+    realm_id INTEGER NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    description TEXT,
+    roles TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(realm_id, name)
+);
+
+-- 创建用户角色/用户组分配表
+CREATE TABLE IF NOT EXISTS user_assignments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, kind, name)
 );
 
 -- 创建授权码表
 CREATE TABLE IF NOT EXISTS authorization_codes (
     id SERIAL PRIMARY KEY,
     code VARCHAR(255) UNIQUE NOT NULL,
-    client_id VARCHAR(100) NOT NULL REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+    realm_id INTEGER NOT NULL,
+    client_id VARCHAR(100) NOT NULL,
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     redirect_uri TEXT NOT NULL,
     scopes TEXT[],
     code_challenge VARCHAR(128),
     code_challenge_method VARCHAR(10),
+    nonce TEXT,
+    auth_time TIMESTAMP,
+    amr TEXT,
+    authorization_details TEXT,
     expires_at TIMESTAMP NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 创建推送授权请求表 (PAR)
+CREATE TABLE IF NOT EXISTS pushed_authorization_requests (
+    id SERIAL PRIMARY KEY,
+    request_uri VARCHAR(255) UNIQUE NOT NULL,
+    client_id VARCHAR(100) NOT NULL,
+    parameters TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (realm_id, client_id) REFERENCES oauth_clients(realm_id, client_id) ON DELETE CASCADE
+);
+
+-- 创建登录会话表
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    realm_id INTEGER NOT NULL DEFAULT 1,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    auth_time TIMESTAMP NOT NULL,
+    amr TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建TOTP认证器表
+CREATE TABLE IF NOT EXISTS totp_credentials (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    last_used_step BIGINT DEFAULT 0,
+    confirmed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建两步验证恢复码表
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id);
+
+-- 创建WebAuthn凭据（通行密钥）表
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id VARCHAR(1400) UNIQUE NOT NULL,
+    public_key BYTEA NOT NULL,
+    algorithm INTEGER NOT NULL,
+    sign_count BIGINT DEFAULT 0,
+    transports TEXT,
+    aaguid VARCHAR(36),
+    name VARCHAR(255) NOT NULL,
+    backup_eligible BOOLEAN DEFAULT FALSE,
+    backed_up BOOLEAN DEFAULT FALSE,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+-- 创建WebAuthn仪式表
+CREATE TABLE IF NOT EXISTS webauthn_sessions (
+    id SERIAL PRIMARY KEY,
+    challenge VARCHAR(255) UNIQUE NOT NULL,
+    ceremony VARCHAR(50) NOT NULL,
+    user_id INTEGER DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建两步验证挑战表
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id SERIAL PRIMARY KEY,
+    token VARCHAR(255) UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amr TEXT,
+    attempts INTEGER DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建用户授权同意表
+CREATE TABLE IF NOT EXISTS consents (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id VARCHAR(100) NOT NULL,
+    scopes TEXT,
+    authorization_details TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, client_id)
+);
+
+-- 创建CIBA后端通道认证请求表
+CREATE TABLE IF NOT EXISTS backchannel_auth_requests (
+    id SERIAL PRIMARY KEY,
+    auth_req_id VARCHAR(255) UNIQUE NOT NULL,
+    client_id VARCHAR(100) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scopes TEXT NOT NULL,
+    binding_message TEXT,
+    delivery_mode VARCHAR(16) NOT NULL,
+    client_notification_token TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    interval INTEGER NOT NULL,
+    last_polled_at TIMESTAMP,
+    auth_time TIMESTAMP,
+    amr TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- 创建刷新令牌表
 CREATE TABLE IF NOT EXISTS refresh_tokens (
     id SERIAL PRIMARY KEY,
@@ -52,6 +271,62 @@ CREATE TABLE IF NOT EXISTS refresh_tokens (
     client_id VARCHAR(100) NOT NULL,
     token VARCHAR(255) UNIQUE NOT NULL,
     scopes TEXT,
+    authorization_details TEXT,
+    auth_time TIMESTAMP,
+    amr TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    absolute_expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建上游身份提供方表
+CREATE TABLE IF NOT EXISTS identity_providers (
+    id SERIAL PRIMARY KEY,
+    realm_id INTEGER NOT NULL DEFAULT 1,
+    name VARCHAR(64) NOT NULL,
+    display_name VARCHAR(255),
+    issuer TEXT NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    client_secret TEXT,
+    scopes TEXT,
+    link_by_email BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (realm_id, name)
+);
+
+-- 创建上游身份关联表
+CREATE TABLE IF NOT EXISTS external_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    username VARCHAR(255),
+    display_name VARCHAR(255),
+    avatar_url TEXT,
+    profile TEXT,
+    access_token TEXT,
+    refresh_token TEXT,
+    token_expires_at TIMESTAMP,
+    scope TEXT,
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject)
+);
+
+-- 创建上游登录状态表
+CREATE TABLE IF NOT EXISTS federated_login_states (
+    id SERIAL PRIMARY KEY,
+    state VARCHAR(255) UNIQUE NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    nonce VARCHAR(255) NOT NULL,
+    code_verifier VARCHAR(255) NOT NULL,
+    return_to TEXT,
+    link_user_id INTEGER DEFAULT 0,
+    binding_hash VARCHAR(64),
     expires_at TIMESTAMP NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -96,5 +371,47 @@ CREATE TABLE IF NOT EXISTS bangumi_bindings (
     expires_at TIMESTAMP NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(user_id)
-);
\ No newline at end of file
+    UNIQUE(user_id),
+    UNIQUE(bangumi_user_id)
+);
+
+-- 创建待完成的Bangumi登录表
+CREATE TABLE IF NOT EXISTS bangumi_pending_logins (
+    id SERIAL PRIMARY KEY,
+    token VARCHAR(255) UNIQUE NOT NULL,
+    bangumi_user_id INTEGER NOT NULL,
+    bangumi_username VARCHAR(255),
+    nickname VARCHAR(255),
+    avatar_url TEXT,
+    access_token VARCHAR(255) NOT NULL,
+    refresh_token VARCHAR(255) NOT NULL,
+    token_expires_at TIMESTAMP NOT NULL,
+    scope TEXT,
+    return_to TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建安全审计事件表，事件只追加：不引用users表，账户删除后事件仍保留
+CREATE TABLE IF NOT EXISTS audit_events (
+    id SERIAL PRIMARY KEY,
+    realm_id INTEGER NOT NULL DEFAULT 1,
+    type VARCHAR(64) NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    user_id INTEGER,
+    actor VARCHAR(255),
+    client_id VARCHAR(255),
+    ip VARCHAR(64),
+    user_agent TEXT,
+    details TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_realm_created_at ON audit_events(realm_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_client_id ON audit_events(client_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_type ON audit_events(type);
+
+-- 审计事件不可修改或删除
+CREATE OR REPLACE RULE audit_events_no_update AS ON UPDATE TO audit_events DO INSTEAD NOTHING;
+CREATE OR REPLACE RULE audit_events_no_delete AS ON DELETE TO audit_events DO INSTEAD NOTHING;